@@ -33,3 +33,26 @@ func BenchmarkNearest_Linear_Clustered_100k_4D(b *testing.B) {
 func BenchmarkNearest_Gonum_Clustered_100k_4D(b *testing.B) {
 	benchNearestBackend(b, 100_000, 4, BackendGonum, false, 3)
 }
+
+// Construction of large trees, where partitions are built in parallel.
+func BenchmarkBuild_Gonum_Uniform_1M_4D(b *testing.B) {
+	benchBuildBackend(b, 1_000_000, 4, BackendGonum)
+}
+
+// Leaf bucket size (WithLeafSize) in low and higher dimensions.
+func benchKNearestLeafSize(b *testing.B, dim, leaf int) {
+	tr, _ := NewKDTree(makeUniformPoints(100_000, dim), WithBackend(BackendGonum), WithLeafSize(leaf))
+	q := make([]float64, dim)
+	for i := range q {
+		q[i] = 0.5
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = tr.KNearest(q, 10)
+	}
+}
+
+func BenchmarkKNearest10_Gonum_Leaf1_100k_2D(b *testing.B)  { benchKNearestLeafSize(b, 2, 1) }
+func BenchmarkKNearest10_Gonum_Leaf16_100k_2D(b *testing.B) { benchKNearestLeafSize(b, 2, 16) }
+func BenchmarkKNearest10_Gonum_Leaf1_100k_8D(b *testing.B)  { benchKNearestLeafSize(b, 8, 1) }
+func BenchmarkKNearest10_Gonum_Leaf32_100k_8D(b *testing.B) { benchKNearestLeafSize(b, 8, 32) }
@@ -178,3 +178,18 @@ func BenchmarkRadiusMid_Linear_Clustered_10k_2D(b *testing.B) {
 func BenchmarkRadiusMid_Gonum_Clustered_10k_2D(b *testing.B) {
 	benchRadiusBackend(b, 10_000, 2, 0.5, BackendGonum, false, 3)
 }
+
+func benchBuildBackend(b *testing.B, n, dim int, backend KDBackend) {
+	pts := makeUniformPoints(n, dim)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewKDTree(pts, WithBackend(backend)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Construction cost (Gonum builds the KD-tree; Linear only copies points)
+func BenchmarkBuild_Gonum_Uniform_10k_2D(b *testing.B) { benchBuildBackend(b, 10000, 2, BackendGonum) }
+func BenchmarkBuild_Gonum_Uniform_10k_4D(b *testing.B) { benchBuildBackend(b, 10000, 4, BackendGonum) }
@@ -67,3 +67,21 @@ func BenchmarkKNearest10_10k_2D(b *testing.B) { benchKNearest(b, 10_000, 2, 10)
 
 func BenchmarkRadiusMid_1k_2D(b *testing.B)  { benchRadius(b, 1_000, 2, 0.5) }
 func BenchmarkRadiusMid_10k_2D(b *testing.B) { benchRadius(b, 10_000, 2, 0.5) }
+
+func benchRadiusAppend(b *testing.B, n, dim int, r float64) {
+	pts := makePoints(n, dim)
+	tr, _ := NewKDTree(pts)
+	q := make([]float64, dim)
+	for i := range q {
+		q[i] = 0.5
+	}
+	var buf []Neighbor[int]
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf = tr.RadiusAppend(q, r, buf[:0])
+	}
+}
+
+func BenchmarkRadiusAppendMid_1k_2D(b *testing.B)  { benchRadiusAppend(b, 1_000, 2, 0.5) }
+func BenchmarkRadiusAppendMid_10k_2D(b *testing.B) { benchRadiusAppend(b, 10_000, 2, 0.5) }
@@ -0,0 +1,75 @@
+package poindexter
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// naiveDistance is EuclideanDistance as a plain scalar loop, the baseline the
+// kernels are measured against.
+type naiveDistance struct{}
+
+func (naiveDistance) Distance(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
+
+func naiveSqEuclidean(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return sum
+}
+
+func benchKernel(b *testing.B, dim int, f func(a, b []float64) float64) {
+	pts := makePoints(2, dim)
+	x, y := pts[0].Coords, pts[1].Coords
+	var sink float64
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sink += f(x, y)
+	}
+	_ = sink
+}
+
+func BenchmarkSqEuclidean(b *testing.B) {
+	for _, dim := range []int{4, 16, 128, 768} {
+		b.Run(fmt.Sprintf("naive/dim%d", dim), func(b *testing.B) { benchKernel(b, dim, naiveSqEuclidean) })
+		b.Run(fmt.Sprintf("generic/dim%d", dim), func(b *testing.B) { benchKernel(b, dim, sqEuclideanGeneric) })
+		b.Run(fmt.Sprintf("kernel/dim%d", dim), func(b *testing.B) { benchKernel(b, dim, sqEuclidean) })
+	}
+}
+
+// benchLinearScan runs KNearest on the linear backend; the naive variant
+// swaps in an equivalent metric the kernels do not recognise.
+func benchLinearScan(b *testing.B, n, dim int, metric DistanceMetric) {
+	tr, _ := NewKDTree(makePoints(n, dim), WithMetric(metric))
+	q := make([]float64, dim)
+	for i := range q {
+		q[i] = 0.5
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = tr.KNearest(q, 10)
+	}
+}
+
+func BenchmarkLinearKNearest10_10k_16D_Naive(b *testing.B) {
+	benchLinearScan(b, 10_000, 16, naiveDistance{})
+}
+func BenchmarkLinearKNearest10_10k_16D_Kernel(b *testing.B) {
+	benchLinearScan(b, 10_000, 16, EuclideanDistance{})
+}
+func BenchmarkLinearKNearest10_10k_128D_Naive(b *testing.B) {
+	benchLinearScan(b, 10_000, 128, naiveDistance{})
+}
+func BenchmarkLinearKNearest10_10k_128D_Kernel(b *testing.B) {
+	benchLinearScan(b, 10_000, 128, EuclideanDistance{})
+}
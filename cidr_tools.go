@@ -0,0 +1,153 @@
+package poindexter
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// ============================================================================
+// CIDR Sets
+// ============================================================================
+
+// CIDRSet is an immutable set of IP prefixes, e.g. an allow/deny list or the
+// networks a peer operator controls. IPv4 and IPv6 prefixes may be mixed.
+type CIDRSet struct {
+	prefixes []netip.Prefix // masked, sorted, with covered prefixes removed
+}
+
+// ParseCIDRSet parses CIDR strings ("10.0.0.0/8", "2001:db8::/32"). Bare
+// addresses are treated as single-host prefixes. Host bits are masked off, and
+// prefixes contained in another entry are dropped.
+func ParseCIDRSet(cidrs ...string) (*CIDRSet, error) {
+	ps := make([]netip.Prefix, 0, len(cidrs))
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		var p netip.Prefix
+		if strings.Contains(c, "/") {
+			var err error
+			if p, err = netip.ParsePrefix(c); err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q: %w", c, err)
+			}
+		} else {
+			a, err := netip.ParseAddr(c)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q: %w", c, err)
+			}
+			p = netip.PrefixFrom(a, a.BitLen())
+		}
+		ps = append(ps, p.Masked())
+	}
+	// Sorting by address then length puts every covering prefix before the
+	// prefixes it contains.
+	slices.SortFunc(ps, func(a, b netip.Prefix) int {
+		return cmp.Or(a.Addr().Compare(b.Addr()), cmp.Compare(a.Bits(), b.Bits()))
+	})
+	out := ps[:0]
+	for _, p := range ps {
+		if n := len(out); n > 0 && out[n-1].Bits() <= p.Bits() && out[n-1].Contains(p.Addr()) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return &CIDRSet{prefixes: out}, nil
+}
+
+// Contains reports whether ip (textual form) falls in any prefix of the set.
+// Invalid addresses are not contained.
+func (s *CIDRSet) Contains(ip string) bool {
+	a, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	return s.ContainsAddr(a)
+}
+
+// ContainsAddr reports whether a falls in any prefix of the set. IPv4-mapped
+// IPv6 addresses match IPv4 prefixes.
+func (s *CIDRSet) ContainsAddr(a netip.Addr) bool {
+	if s == nil {
+		return false
+	}
+	a = a.Unmap()
+	for _, p := range s.prefixes {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// Prefixes returns a copy of the normalised prefixes in the set.
+func (s *CIDRSet) Prefixes() []netip.Prefix {
+	if s == nil {
+		return nil
+	}
+	return slices.Clone(s.prefixes)
+}
+
+// String returns the set as comma-separated CIDRs.
+func (s *CIDRSet) String() string {
+	ps := s.Prefixes()
+	parts := make([]string, len(ps))
+	for i, p := range ps {
+		parts[i] = p.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// ============================================================================
+// Peer Grouping
+// ============================================================================
+
+// PeerPrefix returns the network of ip at prefixLen bits, e.g. the /24 of an
+// IPv4 peer. prefixLen is clamped to the address size, so one length (say 24)
+// can be applied to mixed IPv4/IPv6 inputs. IPv4-mapped IPv6 addresses are
+// treated as IPv4.
+func PeerPrefix(ip netip.Addr, prefixLen int) (netip.Prefix, error) {
+	ip = ip.Unmap()
+	if !ip.IsValid() {
+		return netip.Prefix{}, errors.New("invalid IP address")
+	}
+	prefixLen = max(0, min(prefixLen, ip.BitLen()))
+	return ip.Prefix(prefixLen)
+}
+
+// GroupPeersByPrefix groups peers by the prefixLen-bit network of their
+// address, as returned by addrOf (e.g. parsed from KDPoint.ID or the Value
+// payload). Peers whose address is invalid are grouped under the zero Prefix.
+// Within a group peers keep their input order.
+func GroupPeersByPrefix[P any](peers []P, prefixLen int, addrOf func(P) netip.Addr) map[netip.Prefix][]P {
+	groups := make(map[netip.Prefix][]P)
+	for _, p := range peers {
+		pfx, err := PeerPrefix(addrOf(p), prefixLen)
+		if err != nil {
+			pfx = netip.Prefix{}
+		}
+		groups[pfx] = append(groups[pfx], p)
+	}
+	return groups
+}
+
+// LimitPeersPerPrefix keeps at most maxPerPrefix peers from each prefixLen-bit
+// network, preserving input order. Applied to a ranked candidate list (e.g.
+// KNearest results) it expresses diversity constraints such as "max 2 peers
+// per /24". Peers with invalid addresses share one group.
+func LimitPeersPerPrefix[P any](peers []P, prefixLen, maxPerPrefix int, addrOf func(P) netip.Addr) []P {
+	counts := make(map[netip.Prefix]int)
+	out := make([]P, 0, len(peers))
+	for _, p := range peers {
+		pfx, err := PeerPrefix(addrOf(p), prefixLen)
+		if err != nil {
+			pfx = netip.Prefix{}
+		}
+		if counts[pfx] < maxPerPrefix {
+			counts[pfx]++
+			out = append(out, p)
+		}
+	}
+	return out
+}
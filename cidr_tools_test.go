@@ -0,0 +1,88 @@
+package poindexter
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestParseCIDRSet(t *testing.T) {
+	s, err := ParseCIDRSet("10.1.2.3/8", "10.20.0.0/16", " 192.0.2.7 ", "2001:db8::/32", "2001:db8:1::/48")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.String(), "10.0.0.0/8,192.0.2.7/32,2001:db8::/32"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+	for ip, want := range map[string]bool{
+		"10.255.0.1":       true,
+		"11.0.0.1":         false,
+		"192.0.2.7":        true,
+		"192.0.2.8":        false,
+		"::ffff:10.0.0.1":  true,
+		"2001:db8:ffff::1": true,
+		"2001:db9::1":      false,
+		"not-an-ip":        false,
+	} {
+		if got := s.Contains(ip); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", ip, got, want)
+		}
+	}
+	if _, err := ParseCIDRSet("10.0.0.0/33"); err == nil {
+		t.Fatal("expected error for invalid prefix")
+	}
+	if _, err := ParseCIDRSet("example.com"); err == nil {
+		t.Fatal("expected error for non-address")
+	}
+	var nilSet *CIDRSet
+	if nilSet.Contains("10.0.0.1") || nilSet.Prefixes() != nil {
+		t.Fatal("nil set should be empty")
+	}
+}
+
+func TestGroupPeersByPrefix(t *testing.T) {
+	peers := []KDPoint[string]{
+		{ID: "198.51.100.1"},
+		{ID: "198.51.100.200"},
+		{ID: "198.51.101.1"},
+		{ID: "2001:db8::1"},
+		{ID: "bogus"},
+	}
+	addr := func(p KDPoint[string]) netip.Addr {
+		a, _ := netip.ParseAddr(p.ID)
+		return a
+	}
+	groups := GroupPeersByPrefix(peers, 24, addr)
+	if len(groups) != 4 {
+		t.Fatalf("expected 4 groups, got %d: %v", len(groups), groups)
+	}
+	g := groups[netip.MustParsePrefix("198.51.100.0/24")]
+	if len(g) != 2 || g[0].ID != "198.51.100.1" || g[1].ID != "198.51.100.200" {
+		t.Fatalf("unexpected /24 group %+v", g)
+	}
+	if g := groups[netip.MustParsePrefix("2001:d00::/24")]; len(g) != 1 {
+		t.Fatalf("expected IPv6 peer grouped at /24, got %v", groups)
+	}
+	if g := groups[netip.Prefix{}]; len(g) != 1 || g[0].ID != "bogus" {
+		t.Fatalf("expected invalid address in zero-prefix group, got %+v", g)
+	}
+	if g := GroupPeersByPrefix(peers[:3], 64, addr); len(g) != 3 {
+		t.Fatalf("expected prefix clamped to /32 for IPv4, got %d groups", len(g))
+	}
+}
+
+func TestLimitPeersPerPrefix(t *testing.T) {
+	ranked := []string{"203.0.113.1", "203.0.113.2", "198.51.100.9", "203.0.113.3", "198.51.100.10"}
+	got := LimitPeersPerPrefix(ranked, 24, 2, func(s string) netip.Addr {
+		a, _ := netip.ParseAddr(s)
+		return a
+	})
+	want := []string{"203.0.113.1", "203.0.113.2", "198.51.100.9", "198.51.100.10"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
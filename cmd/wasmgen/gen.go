@@ -0,0 +1,445 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// directivePrefix marks the comment describing an export's client method:
+//
+//	export("pxInsert", insert) //wasmgen:KDTreeClient.insert(this, point: PxPoint): boolean
+//
+// The directive names the client class and method, the TypeScript parameters
+// and the type of the result's data. A bare `this` parameter passes the
+// instance handle of a bound class (one constructed from another export's
+// result), and a return type of the form Class(field) constructs that class
+// with the result's field as its handle.
+const directivePrefix = "//wasmgen:"
+
+var (
+	directiveRe = regexp.MustCompile(`^(\w+)\.(\w+)\((.*)\):\s*(.+)$`)
+	wrapRe      = regexp.MustCompile(`^(\w+)\((\w+)\)$`)
+	typeNameRe  = regexp.MustCompile(`\b[A-Z]\w*\b`)
+)
+
+// tsBuiltins are type names the generated definitions need not import.
+var tsBuiltins = map[string]bool{
+	"Array": true, "ArrayBuffer": true, "Date": true, "Map": true, "Partial": true,
+	"Promise": true, "ReadonlyArray": true, "Record": true, "Set": true, "Uint8Array": true,
+}
+
+type param struct {
+	Name     string
+	Optional bool
+	Type     string
+}
+
+// export is one px* global together with its parsed directive.
+type export struct {
+	Global    string
+	Class     string
+	Method    string
+	Bound     bool // first JS argument is the instance handle
+	Params    []param
+	Returns   string
+	WrapClass string // non-empty if the result constructs a bound class
+	WrapField string
+}
+
+// api is the client surface described by a wasm main package.
+type api struct {
+	Root    string
+	Exports []export
+	Handles map[string]string // bound class -> handle field
+}
+
+// parseExports reads every export("px...", fn) call in src and its directive.
+func parseExports(filename string, src []byte, root string) (*api, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	directives := make(map[int]string) // line -> directive text
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			if strings.HasPrefix(c.Text, directivePrefix) {
+				directives[fset.Position(c.Pos()).Line] = strings.TrimPrefix(c.Text, directivePrefix)
+			}
+		}
+	}
+
+	a := &api{Root: root, Handles: make(map[string]string)}
+	var errs []error
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		if id, ok := call.Fun.(*ast.Ident); !ok || id.Name != "export" {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		global, _ := strconv.Unquote(lit.Value)
+		pos := fset.Position(call.Pos())
+		d, ok := directives[pos.Line]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: export %q has no %s directive", pos, global, directivePrefix))
+			return true
+		}
+		e, err := parseDirective(global, d)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", pos, err))
+			return true
+		}
+		a.Exports = append(a.Exports, e)
+		return true
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if len(a.Exports) == 0 {
+		return nil, fmt.Errorf("%s: no exports found", filename)
+	}
+	return a, a.check()
+}
+
+// parseDirective parses `Class.method(params): returns`.
+func parseDirective(global, d string) (export, error) {
+	m := directiveRe.FindStringSubmatch(strings.TrimSpace(d))
+	if m == nil {
+		return export{}, fmt.Errorf("export %q: malformed directive %q", global, d)
+	}
+	e := export{Global: global, Class: m[1], Method: m[2], Returns: strings.TrimSpace(m[4])}
+	if w := wrapRe.FindStringSubmatch(e.Returns); w != nil {
+		e.WrapClass, e.WrapField = w[1], w[2]
+	}
+	for i, p := range splitParams(m[3]) {
+		if p == "this" {
+			if i != 0 {
+				return export{}, fmt.Errorf("export %q: this must be the first parameter", global)
+			}
+			e.Bound = true
+			continue
+		}
+		name, typ, ok := strings.Cut(p, ":")
+		if !ok {
+			return export{}, fmt.Errorf("export %q: parameter %q has no type", global, p)
+		}
+		name = strings.TrimSpace(name)
+		opt := strings.HasSuffix(name, "?")
+		e.Params = append(e.Params, param{Name: strings.TrimSuffix(name, "?"), Optional: opt, Type: strings.TrimSpace(typ)})
+	}
+	return e, nil
+}
+
+// splitParams splits a parameter list on commas outside brackets.
+func splitParams(s string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(', '[', '{', '<':
+			depth++
+		case ')', ']', '}', '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		out = append(out, last)
+	}
+	return out
+}
+
+// check resolves handle fields and rejects inconsistent directives.
+func (a *api) check() error {
+	seen := make(map[string]string)
+	for _, e := range a.Exports {
+		key := e.Class + "." + e.Method
+		if prev, ok := seen[key]; ok {
+			return fmt.Errorf("%s and %s both map to %s", prev, e.Global, key)
+		}
+		seen[key] = e.Global
+		if e.WrapClass == "" {
+			continue
+		}
+		if e.WrapClass == a.Root {
+			return fmt.Errorf("export %q: root class %s cannot be constructed from a result", e.Global, a.Root)
+		}
+		if f, ok := a.Handles[e.WrapClass]; ok && f != e.WrapField {
+			return fmt.Errorf("export %q: %s handle field %q conflicts with %q", e.Global, e.WrapClass, e.WrapField, f)
+		}
+		a.Handles[e.WrapClass] = e.WrapField
+	}
+	for _, e := range a.Exports {
+		_, bound := a.Handles[e.Class]
+		if e.Bound != bound {
+			if bound {
+				return fmt.Errorf("export %q: methods of %s must take this first", e.Global, e.Class)
+			}
+			return fmt.Errorf("export %q: %s is not constructed by any export, so it has no handle for this", e.Global, e.Class)
+		}
+	}
+	return nil
+}
+
+// classes returns the client classes: bound and service classes sorted by
+// name, then the root class.
+func (a *api) classes() []string {
+	set := make(map[string]bool)
+	for _, e := range a.Exports {
+		if e.Class != a.Root {
+			set[e.Class] = true
+		}
+	}
+	out := make([]string, 0, len(set)+1)
+	for c := range set {
+		out = append(out, c)
+	}
+	sort.Strings(out)
+	return append(out, a.Root)
+}
+
+// services returns the unbound, non-root classes exposed as root properties.
+func (a *api) services() []string {
+	var out []string
+	for _, c := range a.classes() {
+		if _, bound := a.Handles[c]; !bound && c != a.Root {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (a *api) methods(class string) []export {
+	var out []export
+	for _, e := range a.Exports {
+		if e.Class == class {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// importedTypes lists the type names the definitions reference but do not
+// declare themselves.
+func (a *api) importedTypes() []string {
+	own := make(map[string]bool)
+	for _, c := range a.classes() {
+		own[c] = true
+	}
+	set := make(map[string]bool)
+	for _, e := range a.Exports {
+		types := []string{e.Returns}
+		for _, p := range e.Params {
+			types = append(types, p.Type)
+		}
+		for _, t := range types {
+			for _, n := range typeNameRe.FindAllString(t, -1) {
+				if !own[n] && !tsBuiltins[n] {
+					set[n] = true
+				}
+			}
+		}
+	}
+	out := make([]string, 0, len(set))
+	for n := range set {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// propertyName derives the root property for a service class: DNSClient → dns.
+func propertyName(class string) string {
+	s := []rune(strings.TrimSuffix(class, "Client"))
+	if len(s) == 0 {
+		s = []rune(class)
+	}
+	// lower-case the leading capitals, keeping the last one of a run that
+	// starts the next word (RDAPTools → rdapTools)
+	i := 0
+	for i < len(s) && unicode.IsUpper(s[i]) {
+		i++
+	}
+	if i > 1 && i < len(s) {
+		i--
+	}
+	for j := 0; j < max(i, 1); j++ {
+		s[j] = unicode.ToLower(s[j])
+	}
+	return string(s)
+}
+
+func (e export) jsArgs(handle string) string {
+	args := []string{"'" + e.Global + "'"}
+	if e.Bound {
+		args = append(args, "this."+handle)
+	}
+	for _, p := range e.Params {
+		args = append(args, p.Name)
+	}
+	return strings.Join(args, ", ")
+}
+
+func (e export) paramNames() string {
+	names := make([]string, len(e.Params))
+	for i, p := range e.Params {
+		names[i] = p.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func (e export) tsParams() string {
+	ps := make([]string, len(e.Params))
+	for i, p := range e.Params {
+		opt := ""
+		if p.Optional {
+			opt = "?"
+		}
+		ps[i] = p.Name + opt + ": " + p.Type
+	}
+	return strings.Join(ps, ", ")
+}
+
+func (e export) tsReturns() string {
+	if e.WrapClass != "" {
+		return e.WrapClass
+	}
+	return e.Returns
+}
+
+// renderJS renders the ESM client module.
+func (a *api) renderJS(source, wasmName string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by wasmgen from %s. DO NOT EDIT.\n\n", source)
+	b.WriteString(jsRuntime)
+	for _, c := range a.classes() {
+		fmt.Fprintf(&b, "\nexport class %s {\n", c)
+		if f, bound := a.Handles[c]; bound {
+			fmt.Fprintf(&b, "  constructor(%s) { this.%s = %s; }\n", f, f, f)
+		} else if c == a.Root {
+			if svcs := a.services(); len(svcs) > 0 {
+				b.WriteString("  constructor() {\n")
+				for _, s := range svcs {
+					fmt.Fprintf(&b, "    this.%s = new %s();\n", propertyName(s), s)
+				}
+				b.WriteString("  }\n")
+			}
+		}
+		for _, e := range a.methods(c) {
+			expr := fmt.Sprintf("call(%s)", e.jsArgs(a.Handles[c]))
+			if e.WrapClass != "" {
+				expr = fmt.Sprintf("new %s(%s.%s)", e.WrapClass, expr, e.WrapField)
+			}
+			fmt.Fprintf(&b, "  async %s(%s) { return %s; }\n", e.Method, e.paramNames(), expr)
+		}
+		b.WriteString("}\n")
+	}
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(jsLoad, "{{WASM}}", wasmName), "{{ROOT}}", a.Root))
+	return b.String()
+}
+
+// renderDTS renders TypeScript definitions for the client module. Shared
+// payload types are imported from typesModule.
+func (a *api) renderDTS(source, typesModule string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by wasmgen from %s. DO NOT EDIT.\n\n", source)
+	if ts := a.importedTypes(); len(ts) > 0 && typesModule != "" {
+		fmt.Fprintf(&b, "import type {\n  %s,\n} from '%s';\n\n", strings.Join(ts, ",\n  "), typesModule)
+	}
+	b.WriteString(dtsLoadOptions)
+	for _, c := range a.classes() {
+		fmt.Fprintf(&b, "\nexport declare class %s {\n", c)
+		if f, bound := a.Handles[c]; bound {
+			fmt.Fprintf(&b, "  readonly %s: number;\n", f)
+		} else if c == a.Root {
+			for _, s := range a.services() {
+				fmt.Fprintf(&b, "  readonly %s: %s;\n", propertyName(s), s)
+			}
+		}
+		for _, e := range a.methods(c) {
+			fmt.Fprintf(&b, "  /** Calls %s. */\n  %s(%s): Promise<%s>;\n", e.Global, e.Method, e.tsParams(), e.tsReturns())
+		}
+		b.WriteString("}\n")
+	}
+	fmt.Fprintf(&b, "\nexport declare function load(options?: LoadOptions): Promise<%s>;\n", a.Root)
+	return b.String()
+}
+
+const jsRuntime = `function unwrap(result) {
+  if (!result || typeof result !== 'object') throw new Error('bad result');
+  if (result.ok) return result.data;
+  throw new Error(result.error || 'unknown error');
+}
+
+function call(name, ...args) {
+  const fn = globalThis[name];
+  if (typeof fn !== 'function') throw new Error(` + "`WASM function ${name} not found`" + `);
+  return unwrap(fn(...args));
+}
+`
+
+const jsLoad = `
+async function ensureGo(wasmExecURL) {
+  if (typeof globalThis.Go === 'function') return;
+  if (typeof document !== 'undefined') {
+    await new Promise((resolve, reject) => {
+      const s = document.createElement('script');
+      s.src = String(wasmExecURL);
+      s.onload = () => resolve();
+      s.onerror = () => reject(new Error(` + "`Failed to load ${wasmExecURL}`" + `));
+      document.head.appendChild(s);
+    });
+  } else {
+    await import(String(wasmExecURL));
+  }
+  if (typeof globalThis.Go !== 'function') throw new Error('wasm_exec.js did not define Go');
+}
+
+async function fetchWasm(wasmURL) {
+  if (typeof document === 'undefined' && typeof process !== 'undefined' && process.versions?.node) {
+    const { readFile } = await import('node:fs/promises');
+    return readFile(wasmURL);
+  }
+  const resp = await fetch(wasmURL);
+  return resp.arrayBuffer();
+}
+
+export async function load(options = {}) {
+  const {
+    wasmURL = new URL('./{{WASM}}', import.meta.url),
+    wasmExecURL = new URL('./wasm_exec.js', import.meta.url),
+  } = options;
+  await ensureGo(wasmExecURL);
+  const go = new globalThis.Go();
+  const { instance } = await WebAssembly.instantiate(await fetchWasm(wasmURL), go.importObject);
+  // Not awaited: the Go main blocks forever to keep its exports alive.
+  go.run(instance);
+  return new {{ROOT}}();
+}
+`
+
+const dtsLoadOptions = `export interface LoadOptions {
+  /** URL (browser) or path/file URL (Node) of the .wasm binary; defaults to the copy next to this module. */
+  wasmURL?: string | URL;
+  /** URL of Go's wasm_exec.js; defaults to the copy next to this module. */
+  wasmExecURL?: string | URL;
+}
+`
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const sample = `package main
+
+func main() {
+	export("pxVersion", version) //wasmgen:Client.version(): string
+	export("pxNewTree", newTree) //wasmgen:Client.newTree(dim: number, opts?: Record<string, number>): TreeClient(treeId)
+	export("pxInsert", insert)   //wasmgen:TreeClient.insert(this, point: PxPoint): boolean
+	export("pxLinks", links)     //wasmgen:RDAPToolsClient.links(domain: string): ExternalToolLinks
+}
+`
+
+func TestParseAndRender(t *testing.T) {
+	a, err := parseExports("main.go", []byte(sample), "Client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Exports) != 4 || a.Handles["TreeClient"] != "treeId" {
+		t.Fatalf("unexpected api: %+v", a)
+	}
+	if ps := a.Exports[1].Params; len(ps) != 2 || !ps[1].Optional || ps[1].Type != "Record<string, number>" {
+		t.Fatalf("params not split at top-level commas: %+v", ps)
+	}
+
+	js := a.renderJS("main.go", "px.wasm")
+	for _, want := range []string{
+		"export class TreeClient {\n  constructor(treeId) { this.treeId = treeId; }",
+		"async insert(point) { return call('pxInsert', this.treeId, point); }",
+		"async newTree(dim, opts) { return new TreeClient(call('pxNewTree', dim, opts).treeId); }",
+		"this.rdapTools = new RDAPToolsClient();",
+		"new URL('./px.wasm', import.meta.url)",
+		"return new Client();",
+	} {
+		if !strings.Contains(js, want) {
+			t.Errorf("client.js missing %q\n%s", want, js)
+		}
+	}
+
+	dts := a.renderDTS("main.go", "../index.js")
+	for _, want := range []string{
+		"import type {\n  ExternalToolLinks,\n  PxPoint,\n} from '../index.js';",
+		"  readonly treeId: number;",
+		"  insert(point: PxPoint): Promise<boolean>;",
+		"  newTree(dim: number, opts?: Record<string, number>): Promise<TreeClient>;",
+		"  readonly rdapTools: RDAPToolsClient;",
+		"export declare function load(options?: LoadOptions): Promise<Client>;",
+	} {
+		if !strings.Contains(dts, want) {
+			t.Errorf("client.d.ts missing %q\n%s", want, dts)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, src := range map[string]string{
+		"missing directive": `package main
+func main() { export("pxA", a) }`,
+		"malformed": `package main
+func main() { export("pxA", a) //wasmgen:nonsense
+}`,
+		"unbound this": `package main
+func main() { export("pxA", a) //wasmgen:T.a(this): number
+}`,
+		"bound without this": `package main
+func main() {
+	export("pxNew", n) //wasmgen:Client.make(): T(id)
+	export("pxA", a)   //wasmgen:T.a(): number
+}`,
+		"duplicate method": `package main
+func main() {
+	export("pxA", a) //wasmgen:Client.a(): number
+	export("pxB", b) //wasmgen:Client.a(): number
+}`,
+	} {
+		if _, err := parseExports("main.go", []byte(src), "Client"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// TestRepoExports keeps the directives in wasm/main.go complete and valid.
+func TestRepoExports(t *testing.T) {
+	src, err := os.ReadFile("../../wasm/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := parseExports("wasm/main.go", src, "PoindexterClient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Handles["KDTreeClient"] != "treeId" || len(a.services()) == 0 || a.services()[0] != "DNSClient" {
+		t.Fatalf("unexpected client layout: handles=%v services=%v", a.Handles, a.services())
+	}
+}
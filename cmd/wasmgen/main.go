@@ -0,0 +1,115 @@
+// Command wasmgen builds the Poindexter WebAssembly module and generates a
+// typed JavaScript client for it.
+//
+// It reads the px* exports registered in the wasm main package, each annotated
+// with a //wasmgen directive (see wasm/main.go), and writes to the output
+// directory:
+//
+//	poindexter.wasm  the module, built with GOOS=js GOARCH=wasm
+//	wasm_exec.js     Go's JS runtime support, copied from GOROOT
+//	client.js        an ESM module wrapping the globals in typed classes
+//	                 (PoindexterClient, KDTreeClient, DNSClient) and a load()
+//	client.d.ts      TypeScript definitions generated from the directives
+//
+// Usage (from the repository root):
+//
+//	go run ./cmd/wasmgen -out dist
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		pkg   = flag.String("pkg", "./wasm", "wasm main package to build")
+		src   = flag.String("src", "", "Go file holding the exports (default <pkg>/main.go)")
+		out   = flag.String("out", "dist", "output directory")
+		name  = flag.String("name", "poindexter.wasm", "file name of the built module")
+		types = flag.String("types", "../index.js", "module the definitions import shared payload types from (relative to -out)")
+		root  = flag.String("root", "PoindexterClient", "class returned by load()")
+		build = flag.Bool("build", true, "build the wasm module and copy wasm_exec.js; false only regenerates the client")
+	)
+	flag.Parse()
+	if *src == "" {
+		*src = filepath.Join(*pkg, "main.go")
+	}
+	if err := run(*pkg, *src, *out, *name, *types, *root, *build); err != nil {
+		fmt.Fprintln(os.Stderr, "wasmgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(pkg, src, out, name, types, root string, build bool) error {
+	code, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	a, err := parseExports(src, code, root)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return err
+	}
+	if build {
+		if err := buildWasm(pkg, filepath.Join(out, name)); err != nil {
+			return err
+		}
+		if err := copyWasmExec(filepath.Join(out, "wasm_exec.js")); err != nil {
+			return err
+		}
+	}
+	source := filepath.ToSlash(src)
+	if err := os.WriteFile(filepath.Join(out, "client.js"), []byte(a.renderJS(source, name)), 0o644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(out, "client.d.ts"), []byte(a.renderDTS(source, types)), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("wasmgen: %d exports -> %s\n", len(a.Exports), out)
+	return nil
+}
+
+func buildWasm(pkg, dst string) error {
+	cmd := exec.Command("go", "build", "-o", dst, pkg)
+	cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("building %s: %w", pkg, err)
+	}
+	return nil
+}
+
+// copyWasmExec copies wasm_exec.js from GOROOT (lib/wasm since Go 1.24,
+// misc/wasm before).
+func copyWasmExec(dst string) error {
+	gr, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		return fmt.Errorf("go env GOROOT: %w", err)
+	}
+	goroot := strings.TrimSpace(string(gr))
+	for _, dir := range []string{"lib/wasm", "misc/wasm"} {
+		in, err := os.Open(filepath.Join(goroot, dir, "wasm_exec.js"))
+		if err != nil {
+			continue
+		}
+		defer in.Close()
+		o, err := os.Create(dst)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(o, in); err != nil {
+			o.Close()
+			return err
+		}
+		return o.Close()
+	}
+	return fmt.Errorf("wasm_exec.js not found under %s", goroot)
+}
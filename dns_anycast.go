@@ -0,0 +1,335 @@
+package poindexter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// DNS-over-HTTPS Vantage Points
+// ============================================================================
+
+// DoHProvider is a DNS-over-HTTPS resolver speaking the JSON API
+// (application/dns-json) used as a vantage point. ClientSubnet, if set, is sent
+// as edns_client_subnet so resolvers that honour it (e.g. Google) answer as if
+// queried from that network.
+type DoHProvider struct {
+	Name         string `json:"name"`
+	URL          string `json:"url"`
+	ClientSubnet string `json:"clientSubnet,omitempty"`
+}
+
+// DefaultDoHProviders returns the vantage points used by DetectAnycast: three
+// public resolvers plus Google queried with client subnets in four regions.
+func DefaultDoHProviders() []DoHProvider {
+	const google = "https://dns.google/resolve"
+	return []DoHProvider{
+		{Name: "Google", URL: google},
+		{Name: "Cloudflare", URL: "https://cloudflare-dns.com/dns-query"},
+		{Name: "Quad9", URL: "https://dns.quad9.net:5053/dns-query"},
+		{Name: "Google (NA)", URL: google, ClientSubnet: "24.0.0.0/24"},
+		{Name: "Google (EU)", URL: google, ClientSubnet: "5.0.0.0/24"},
+		{Name: "Google (APAC)", URL: google, ClientSubnet: "27.0.0.0/24"},
+		{Name: "Google (LATAM)", URL: google, ClientSubnet: "200.0.0.0/24"},
+	}
+}
+
+// WithDoHProviders sets the vantage points used by DetectAnycastWithOptions.
+func WithDoHProviders(providers ...DoHProvider) DNSLookupOption {
+	return func(o *dnsLookupOptions) { o.dohProviders = providers }
+}
+
+// WithASNLookup sets how DetectAnycastWithOptions maps an IP address to its
+// origin ASN (e.g. "13335"). The default queries Team Cymru's IP-to-ASN DNS
+// service through the system resolver.
+func WithASNLookup(fn func(ctx context.Context, ip string) (string, error)) DNSLookupOption {
+	return func(o *dnsLookupOptions) { o.asnLookup = fn }
+}
+
+// ============================================================================
+// Anycast / GeoDNS Detection
+// ============================================================================
+
+// AnycastVerdict summarises how a domain's addresses behave across vantages.
+type AnycastVerdict string
+
+const (
+	// AnycastLikely: every vantage sees the same addresses, announced by a
+	// network known to use anycast.
+	AnycastLikely AnycastVerdict = "anycast-likely"
+	// AnycastGeoDNS: vantages receive different addresses (DNS-based steering
+	// to multiple POPs).
+	AnycastGeoDNS AnycastVerdict = "geodns"
+	// AnycastConsistent: every vantage sees the same addresses, but nothing
+	// suggests anycast; the service may be unicast. Latency from several
+	// locations is needed to tell.
+	AnycastConsistent AnycastVerdict = "consistent"
+	// AnycastUnknown: no vantage returned an answer.
+	AnycastUnknown AnycastVerdict = "unknown"
+)
+
+// knownAnycastASNs are networks that serve most of their address space via
+// anycast.
+var knownAnycastASNs = map[string]string{
+	"13335": "Cloudflare",
+	"54113": "Fastly",
+	"19281": "Quad9",
+	"36692": "Cisco OpenDNS",
+}
+
+// AnycastVantage holds the answers seen from one vantage point.
+type AnycastVantage struct {
+	Provider     string   `json:"provider"`
+	ClientSubnet string   `json:"clientSubnet,omitempty"`
+	Addresses    []string `json:"addresses"`
+	Error        string   `json:"error,omitempty"`
+	LookupTimeMs int64    `json:"lookupTimeMs"`
+}
+
+// AnycastReport is the result of DetectAnycast.
+type AnycastReport struct {
+	Domain            string            `json:"domain"`
+	Verdict           AnycastVerdict    `json:"verdict"`
+	Reasons           []string          `json:"reasons,omitempty"`
+	Vantages          []AnycastVantage  `json:"vantages"`
+	DistinctAddresses []string          `json:"distinctAddresses,omitempty"`
+	ASNs              map[string]string `json:"asns,omitempty"` // address -> origin ASN
+	LookupTimeMs      int64             `json:"lookupTimeMs"`
+	Timestamp         time.Time         `json:"timestamp"`
+}
+
+// DetectAnycast resolves domain (A and AAAA) from several DoH vantage points and
+// compares the answers and their origin ASNs, reporting likely anycast or
+// GeoDNS behaviour. Use it as context before trusting a single latency
+// measurement: an anycast or GeoDNS address measures the nearest POP, not "the"
+// server. The verdict is a heuristic: large round-robin pools that hand out
+// rotating subsets also register as GeoDNS.
+func DetectAnycast(domain string) AnycastReport {
+	return DetectAnycastWithOptions(domain)
+}
+
+// DetectAnycastWithOptions is DetectAnycast with a custom timeout
+// (WithDNSTimeout), vantage points (WithDoHProviders) or ASN source
+// (WithASNLookup).
+func DetectAnycastWithOptions(domain string, opts ...DNSLookupOption) AnycastReport {
+	start := time.Now()
+	o := newDNSLookupOptions(opts)
+	providers := o.dohProviders
+	if providers == nil {
+		providers = DefaultDoHProviders()
+	}
+	asnLookup := o.asnLookup
+	if asnLookup == nil {
+		resolver := dnsResolver(o.resolver)
+		asnLookup = func(ctx context.Context, ip string) (string, error) {
+			return cymruASNLookup(ctx, resolver, ip)
+		}
+	}
+	report := AnycastReport{Domain: domain, Timestamp: start}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+	client := &http.Client{Timeout: o.timeout}
+
+	report.Vantages = make([]AnycastVantage, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Vantages[i] = queryDoHVantage(ctx, client, p, domain)
+		}()
+	}
+	wg.Wait()
+
+	// Compare the answer sets of the vantages that responded.
+	var answered []AnycastVantage
+	seen := map[string]bool{}
+	for _, v := range report.Vantages {
+		if v.Error != "" || len(v.Addresses) == 0 {
+			continue
+		}
+		answered = append(answered, v)
+		for _, a := range v.Addresses {
+			if !seen[a] {
+				seen[a] = true
+				report.DistinctAddresses = append(report.DistinctAddresses, a)
+			}
+		}
+	}
+	slices.SortFunc(report.DistinctAddresses, compareDNSValues)
+	if len(answered) == 0 {
+		report.Verdict = AnycastUnknown
+		report.Reasons = append(report.Reasons, "no vantage returned an answer")
+		report.LookupTimeMs = time.Since(start).Milliseconds()
+		return report
+	}
+
+	report.ASNs = map[string]string{}
+	asnSet := map[string]bool{}
+	for _, a := range report.DistinctAddresses {
+		if asn, err := asnLookup(ctx, a); err == nil && asn != "" {
+			report.ASNs[a] = asn
+			asnSet[asn] = true
+		}
+	}
+
+	identical := true
+	for _, v := range answered[1:] {
+		if !slices.Equal(v.Addresses, answered[0].Addresses) {
+			identical = false
+			break
+		}
+	}
+	var anycastNets []string
+	for asn := range asnSet {
+		if name, ok := knownAnycastASNs[asn]; ok {
+			anycastNets = append(anycastNets, fmt.Sprintf("AS%s (%s)", asn, name))
+		}
+	}
+	slices.Sort(anycastNets)
+
+	switch {
+	case !identical:
+		report.Verdict = AnycastGeoDNS
+		report.Reasons = append(report.Reasons, fmt.Sprintf("%d vantages returned %d distinct addresses", len(answered), len(report.DistinctAddresses)))
+	case len(anycastNets) > 0:
+		report.Verdict = AnycastLikely
+		report.Reasons = append(report.Reasons, fmt.Sprintf("all %d vantages returned the same addresses", len(answered)))
+	default:
+		report.Verdict = AnycastConsistent
+		report.Reasons = append(report.Reasons, fmt.Sprintf("all %d vantages returned the same addresses", len(answered)))
+	}
+	if len(anycastNets) > 0 {
+		report.Reasons = append(report.Reasons, "announced by anycast network "+strings.Join(anycastNets, ", "))
+	}
+	if len(asnSet) > 1 {
+		report.Reasons = append(report.Reasons, fmt.Sprintf("addresses span %d origin ASNs", len(asnSet)))
+	}
+	report.LookupTimeMs = time.Since(start).Milliseconds()
+	return report
+}
+
+// dohJSONResponse is the subset of the DoH JSON API response we use.
+type dohJSONResponse struct {
+	Status int `json:"Status"`
+	Answer []struct {
+		Type int    `json:"type"`
+		Data string `json:"data"`
+	} `json:"Answer"`
+}
+
+// queryDoHVantage resolves A and AAAA records for domain through p. Addresses
+// are returned normalised and sorted.
+func queryDoHVantage(ctx context.Context, client *http.Client, p DoHProvider, domain string) AnycastVantage {
+	start := time.Now()
+	v := AnycastVantage{Provider: p.Name, ClientSubnet: p.ClientSubnet}
+	var errs []string
+	for _, qtype := range []int{1, 28} { // A, AAAA
+		addrs, err := queryDoH(ctx, client, p, domain, qtype)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		v.Addresses = append(v.Addresses, addrs...)
+	}
+	if len(errs) == 2 {
+		v.Error = strings.Join(errs, "; ")
+	}
+	slices.SortFunc(v.Addresses, compareDNSValues)
+	v.Addresses = slices.Compact(v.Addresses)
+	v.LookupTimeMs = time.Since(start).Milliseconds()
+	return v
+}
+
+func queryDoH(ctx context.Context, client *http.Client, p DoHProvider, domain string, qtype int) ([]string, error) {
+	q := url.Values{}
+	q.Set("name", domain)
+	q.Set("type", fmt.Sprint(qtype))
+	if p.ClientSubnet != "" {
+		q.Set("edns_client_subnet", p.ClientSubnet)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/dns-json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DoH request failed: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %s", err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server returned status %d", resp.StatusCode)
+	}
+	var r dohJSONResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse DoH response: %s", err.Error())
+	}
+	if r.Status != 0 {
+		return nil, fmt.Errorf("DoH query returned rcode %d", r.Status)
+	}
+	var addrs []string
+	for _, ans := range r.Answer {
+		if ans.Type != qtype { // skip CNAME chain entries
+			continue
+		}
+		if a, err := netip.ParseAddr(ans.Data); err == nil {
+			addrs = append(addrs, a.String())
+		}
+	}
+	return addrs, nil
+}
+
+// cymruASNLookup maps ip to its origin ASN using Team Cymru's DNS interface
+// (TXT <reversed-ip>.origin[6].asn.cymru.com → "13335 | 104.16.0.0/13 | ...").
+func cymruASNLookup(ctx context.Context, resolver Resolver, ip string) (string, error) {
+	name, err := cymruQueryName(ip)
+	if err != nil {
+		return "", err
+	}
+	txts, err := resolver.LookupTXT(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	for _, txt := range txts {
+		if asn := strings.Fields(strings.SplitN(txt, "|", 2)[0]); len(asn) > 0 {
+			return asn[0], nil
+		}
+	}
+	return "", fmt.Errorf("no ASN for %s", ip)
+}
+
+// cymruQueryName builds the Team Cymru origin query name for ip.
+func cymruQueryName(ip string) (string, error) {
+	a, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", err
+	}
+	a = a.Unmap()
+	var b strings.Builder
+	if a.Is4() {
+		o := a.As4()
+		fmt.Fprintf(&b, "%d.%d.%d.%d.origin.asn.cymru.com", o[3], o[2], o[1], o[0])
+		return b.String(), nil
+	}
+	o := a.As16()
+	for i := len(o) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "%x.%x.", o[i]&0xf, o[i]>>4)
+	}
+	b.WriteString("origin6.asn.cymru.com")
+	return b.String(), nil
+}
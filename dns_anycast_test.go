@@ -0,0 +1,134 @@
+package poindexter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDoH answers A/AAAA queries from per-subnet tables in the DoH JSON format.
+func fakeDoH(t *testing.T, answers map[string][]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/dns-json" {
+			t.Errorf("missing Accept header")
+		}
+		qtype := r.URL.Query().Get("type")
+		var resp dohJSONResponse
+		resp.Answer = append(resp.Answer, struct {
+			Type int    `json:"type"`
+			Data string `json:"data"`
+		}{Type: 5, Data: "alias.example."})
+		for _, a := range answers[r.URL.Query().Get("edns_client_subnet")] {
+			if (qtype == "28") == strings.Contains(a, ":") {
+				resp.Answer = append(resp.Answer, struct {
+					Type int    `json:"type"`
+					Data string `json:"data"`
+				}{Type: map[string]int{"1": 1, "28": 28}[qtype], Data: a})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func asnTable(m map[string]string) DNSLookupOption {
+	return WithASNLookup(func(_ context.Context, ip string) (string, error) {
+		if asn, ok := m[ip]; ok {
+			return asn, nil
+		}
+		return "", errors.New("unknown")
+	})
+}
+
+func TestDetectAnycast_GeoDNS(t *testing.T) {
+	srv := fakeDoH(t, map[string][]string{
+		"":            {"192.0.2.1"},
+		"5.0.0.0/24":  {"198.51.100.1", "2001:db8::1"},
+		"27.0.0.0/24": {"203.0.113.1"},
+	})
+	defer srv.Close()
+	r := DetectAnycastWithOptions("example.com",
+		WithDoHProviders(
+			DoHProvider{Name: "plain", URL: srv.URL},
+			DoHProvider{Name: "eu", URL: srv.URL, ClientSubnet: "5.0.0.0/24"},
+			DoHProvider{Name: "apac", URL: srv.URL, ClientSubnet: "27.0.0.0/24"},
+		),
+		asnTable(map[string]string{"192.0.2.1": "64500", "198.51.100.1": "64501"}),
+		WithDNSTimeout(5*time.Second),
+	)
+	if r.Verdict != AnycastGeoDNS {
+		t.Fatalf("expected geodns, got %s (%v)", r.Verdict, r.Reasons)
+	}
+	if got := strings.Join(r.DistinctAddresses, ","); got != "192.0.2.1,198.51.100.1,203.0.113.1,2001:db8::1" {
+		t.Fatalf("unexpected distinct addresses %s", got)
+	}
+	if r.Vantages[1].Provider != "eu" || len(r.Vantages[1].Addresses) != 2 {
+		t.Fatalf("unexpected eu vantage %+v", r.Vantages[1])
+	}
+	if r.ASNs["198.51.100.1"] != "64501" || len(r.ASNs) != 2 {
+		t.Fatalf("unexpected ASNs %v", r.ASNs)
+	}
+	if !strings.Contains(strings.Join(r.Reasons, ";"), "2 origin ASNs") {
+		t.Fatalf("expected multi-ASN reason, got %v", r.Reasons)
+	}
+}
+
+func TestDetectAnycast_SameAnswers(t *testing.T) {
+	srv := fakeDoH(t, map[string][]string{
+		"":           {"104.16.0.1", "104.16.0.2"},
+		"5.0.0.0/24": {"104.16.0.2", "104.16.0.1"},
+	})
+	defer srv.Close()
+	providers := WithDoHProviders(
+		DoHProvider{Name: "a", URL: srv.URL},
+		DoHProvider{Name: "b", URL: srv.URL, ClientSubnet: "5.0.0.0/24"},
+	)
+
+	r := DetectAnycastWithOptions("example.com", providers, asnTable(map[string]string{"104.16.0.1": "13335", "104.16.0.2": "13335"}))
+	if r.Verdict != AnycastLikely {
+		t.Fatalf("expected anycast-likely, got %s (%v)", r.Verdict, r.Reasons)
+	}
+	if !strings.Contains(strings.Join(r.Reasons, ";"), "AS13335 (Cloudflare)") {
+		t.Fatalf("expected anycast network reason, got %v", r.Reasons)
+	}
+
+	r = DetectAnycastWithOptions("example.com", providers, asnTable(map[string]string{"104.16.0.1": "64500"}))
+	if r.Verdict != AnycastConsistent {
+		t.Fatalf("expected consistent, got %s (%v)", r.Verdict, r.Reasons)
+	}
+}
+
+func TestDetectAnycast_NoAnswers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "nope", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	r := DetectAnycastWithOptions("example.com", WithDoHProviders(DoHProvider{Name: "down", URL: srv.URL}), asnTable(nil))
+	if r.Verdict != AnycastUnknown {
+		t.Fatalf("expected unknown, got %s", r.Verdict)
+	}
+	if !strings.Contains(r.Vantages[0].Error, "status 503") {
+		t.Fatalf("expected status error, got %q", r.Vantages[0].Error)
+	}
+}
+
+func TestCymruQueryName(t *testing.T) {
+	cases := map[string]string{
+		"104.16.132.229":  "229.132.16.104.origin.asn.cymru.com",
+		"::ffff:10.0.0.1": "1.0.0.10.origin.asn.cymru.com",
+		"2001:db8::1":     "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.origin6.asn.cymru.com",
+	}
+	for ip, want := range cases {
+		if got, err := cymruQueryName(ip); err != nil || got != want {
+			t.Errorf("cymruQueryName(%q) = %q, %v; want %q", ip, got, err, want)
+		}
+	}
+	if _, err := cymruQueryName("nope"); err == nil {
+		t.Error("expected error for invalid IP")
+	}
+}
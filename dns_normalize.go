@@ -0,0 +1,170 @@
+package poindexter
+
+import (
+	"cmp"
+	"context"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// DNS Lookup Options
+// ============================================================================
+
+// DNSLookupOption configures DNSLookupWithOptions, DNSLookupAllWithOptions and
+// DetectAnycastWithOptions.
+type DNSLookupOption func(*dnsLookupOptions)
+
+type dnsLookupOptions struct {
+	timeout   time.Duration
+	retries   int
+	resolver  Resolver
+	normalize bool
+
+	// DetectAnycastWithOptions only
+	dohProviders []DoHProvider
+	asnLookup    func(ctx context.Context, ip string) (string, error)
+}
+
+// WithDNSTimeout sets the overall lookup timeout (default 10s, or
+// DNSOptions.Timeout if set with SetDefaultDNSOptions).
+func WithDNSTimeout(timeout time.Duration) DNSLookupOption {
+	return func(o *dnsLookupOptions) { o.timeout = timeout }
+}
+
+// WithDNSRetries sets how many more times a query failing with a timeout or
+// temporary error is tried within the timeout (see DNSOptions.Retries).
+func WithDNSRetries(retries int) DNSLookupOption {
+	return func(o *dnsLookupOptions) { o.retries = retries }
+}
+
+// WithDNSResolver performs the lookup with r instead of the default resolver,
+// e.g. a DNS-over-HTTPS client or a fake in tests.
+func WithDNSResolver(r Resolver) DNSLookupOption {
+	return func(o *dnsLookupOptions) { o.resolver = r }
+}
+
+// WithDNSNormalize normalises results before returning them (see
+// DNSLookupResult.Normalize), so resolvers that shuffle answers still produce
+// identical output for diffing, caching and test fixtures.
+func WithDNSNormalize() DNSLookupOption {
+	return func(o *dnsLookupOptions) { o.normalize = true }
+}
+
+func newDNSLookupOptions(opts []DNSLookupOption) dnsLookupOptions {
+	d := DefaultDNSOptions()
+	o := dnsLookupOptions{timeout: d.dnsTimeout(), retries: d.Retries, resolver: d.Resolver}
+	for _, fn := range opts {
+		if fn != nil {
+			fn(&o)
+		}
+	}
+	return o
+}
+
+// DNSLookupWithOptions performs a DNS lookup for the specified record type.
+func DNSLookupWithOptions(domain string, recordType DNSRecordType, opts ...DNSLookupOption) DNSLookupResult {
+	o := newDNSLookupOptions(opts)
+	result := dnsLookup(domain, recordType, o)
+	if o.normalize {
+		result.Normalize()
+	}
+	return result
+}
+
+// DNSLookupAllWithOptions performs lookups for all common record types.
+func DNSLookupAllWithOptions(domain string, opts ...DNSLookupOption) CompleteDNSLookup {
+	o := newDNSLookupOptions(opts)
+	result := dnsLookupAll(domain, o)
+	if o.normalize {
+		result.Normalize()
+	}
+	return result
+}
+
+// ============================================================================
+// Result Normalisation
+// ============================================================================
+
+// Normalize puts the result into a canonical form: host names are lower-cased
+// (DNS is case-insensitive and some resolvers randomise case), and records are
+// sorted deterministically — by type, then value, with IP addresses compared
+// numerically and numeric fields (MX preference, SRV priority/weight/port)
+// compared as numbers. MXRecords are ordered by priority then host; SRVRecords
+// by priority, weight, port then target. TXT values are sorted but not
+// case-folded.
+func (r *DNSLookupResult) Normalize() {
+	for i := range r.Records {
+		if r.Records[i].Type != DNSRecordTXT {
+			r.Records[i].Value = strings.ToLower(r.Records[i].Value)
+		}
+	}
+	slices.SortStableFunc(r.Records, func(a, b DNSRecord) int {
+		return cmp.Or(cmp.Compare(a.Type, b.Type), compareDNSValues(a.Value, b.Value))
+	})
+	sortMXRecords(r.MXRecords)
+	for i := range r.SRVRecords {
+		r.SRVRecords[i].Target = strings.ToLower(r.SRVRecords[i].Target)
+	}
+	slices.SortStableFunc(r.SRVRecords, func(a, b SRVRecord) int {
+		return cmp.Or(
+			cmp.Compare(a.Priority, b.Priority),
+			cmp.Compare(a.Weight, b.Weight),
+			cmp.Compare(a.Port, b.Port),
+			strings.Compare(a.Target, b.Target),
+		)
+	})
+}
+
+// Normalize puts the result into a canonical form: A/AAAA addresses sorted
+// numerically, NS host names lower-cased and sorted, TXT values sorted, MX
+// records ordered by priority then host, and the CNAME lower-cased.
+func (c *CompleteDNSLookup) Normalize() {
+	slices.SortFunc(c.A, compareDNSValues)
+	slices.SortFunc(c.AAAA, compareDNSValues)
+	for i := range c.NS {
+		c.NS[i] = strings.ToLower(c.NS[i])
+	}
+	slices.Sort(c.NS)
+	slices.Sort(c.TXT)
+	sortMXRecords(c.MX)
+	c.CNAME = strings.ToLower(c.CNAME)
+}
+
+func sortMXRecords(mx []MXRecord) {
+	for i := range mx {
+		mx[i].Host = strings.ToLower(mx[i].Host)
+	}
+	slices.SortStableFunc(mx, func(a, b MXRecord) int {
+		return cmp.Or(cmp.Compare(a.Priority, b.Priority), strings.Compare(a.Host, b.Host))
+	})
+}
+
+// compareDNSValues orders record values: IP addresses numerically, otherwise
+// field by field (space separated) with numeric fields compared as numbers, so
+// "5 mx2" sorts before "10 mx1".
+func compareDNSValues(a, b string) int {
+	if ia, err := netip.ParseAddr(a); err == nil {
+		if ib, err := netip.ParseAddr(b); err == nil {
+			return ia.Compare(ib)
+		}
+	}
+	fa, fb := strings.Fields(a), strings.Fields(b)
+	for i := 0; i < len(fa) && i < len(fb); i++ {
+		na, errA := strconv.ParseUint(fa[i], 10, 64)
+		nb, errB := strconv.ParseUint(fb[i], 10, 64)
+		c := 0
+		if errA == nil && errB == nil {
+			c = cmp.Compare(na, nb)
+		} else {
+			c = strings.Compare(fa[i], fb[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Or(cmp.Compare(len(fa), len(fb)), strings.Compare(a, b))
+}
@@ -0,0 +1,109 @@
+package poindexter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDNSLookupResultNormalize(t *testing.T) {
+	r := DNSLookupResult{
+		Records: []DNSRecord{
+			{Type: DNSRecordMX, Value: "10 MX1.example.com."},
+			{Type: DNSRecordA, Value: "10.0.0.2"},
+			{Type: DNSRecordTXT, Value: "v=spf1 -all"},
+			{Type: DNSRecordA, Value: "9.255.0.1"},
+			{Type: DNSRecordMX, Value: "5 mx2.example.com."},
+			{Type: DNSRecordTXT, Value: "Google-Site-Verification=x"},
+		},
+		MXRecords: []MXRecord{
+			{Host: "b.example.com", Priority: 10},
+			{Host: "A.example.com", Priority: 10},
+			{Host: "z.example.com", Priority: 5},
+		},
+		SRVRecords: []SRVRecord{
+			{Target: "b", Priority: 1, Weight: 5, Port: 80},
+			{Target: "a", Priority: 1, Weight: 5, Port: 80},
+			{Target: "c", Priority: 0, Weight: 9, Port: 443},
+		},
+	}
+	r.Normalize()
+
+	var got []string
+	for _, rec := range r.Records {
+		got = append(got, string(rec.Type)+" "+rec.Value)
+	}
+	want := []string{
+		"A 9.255.0.1",
+		"A 10.0.0.2",
+		"MX 5 mx2.example.com.",
+		"MX 10 mx1.example.com.",
+		"TXT Google-Site-Verification=x",
+		"TXT v=spf1 -all",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("records:\n got %q\nwant %q", got, want)
+	}
+	if r.MXRecords[0].Host != "z.example.com" || r.MXRecords[1].Host != "a.example.com" || r.MXRecords[2].Host != "b.example.com" {
+		t.Fatalf("unexpected MX order %+v", r.MXRecords)
+	}
+	if r.SRVRecords[0].Target != "c" || r.SRVRecords[1].Target != "a" || r.SRVRecords[2].Target != "b" {
+		t.Fatalf("unexpected SRV order %+v", r.SRVRecords)
+	}
+}
+
+func TestCompleteDNSLookupNormalize(t *testing.T) {
+	a := CompleteDNSLookup{
+		A:     []string{"192.0.2.10", "192.0.2.9"},
+		AAAA:  []string{"2001:db8::10", "2001:db8::9"},
+		NS:    []string{"NS2.example.com", "ns1.example.com"},
+		TXT:   []string{"b", "a"},
+		MX:    []MXRecord{{Host: "mx2", Priority: 10}, {Host: "MX1", Priority: 10}},
+		CNAME: "Edge.Example.NET",
+	}
+	b := CompleteDNSLookup{
+		A:     []string{"192.0.2.9", "192.0.2.10"},
+		AAAA:  []string{"2001:db8::9", "2001:db8::10"},
+		NS:    []string{"ns1.example.com", "ns2.example.com"},
+		TXT:   []string{"a", "b"},
+		MX:    []MXRecord{{Host: "mx1", Priority: 10}, {Host: "mx2", Priority: 10}},
+		CNAME: "edge.example.net",
+	}
+	a.Normalize()
+	b.Normalize()
+	if !reflect.DeepEqual(a, b) {
+		t.Fatalf("shuffled answers normalised differently:\n%+v\n%+v", a, b)
+	}
+	if a.A[0] != "192.0.2.9" || a.AAAA[0] != "2001:db8::9" {
+		t.Fatalf("addresses not sorted numerically: %v %v", a.A, a.AAAA)
+	}
+}
+
+func TestDNSLookupOptions(t *testing.T) {
+	o := newDNSLookupOptions(nil)
+	if o.timeout != 10*time.Second || o.normalize {
+		t.Fatalf("unexpected defaults %+v", o)
+	}
+	o = newDNSLookupOptions([]DNSLookupOption{WithDNSTimeout(time.Second), WithDNSNormalize()})
+	if o.timeout != time.Second || !o.normalize {
+		t.Fatalf("options not applied %+v", o)
+	}
+}
+
+func TestCompareDNSValues(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"10.0.0.1", "9.0.0.1", 1},
+		{"::1", "10.0.0.1", 1}, // IPv4 sorts before IPv6
+		{"5 mx.example.", "10 mx.example.", -1},
+		{"1 1 80 a.", "1 1 443 a.", -1},
+		{"a.example", "a.example", 0},
+	}
+	for _, c := range cases {
+		if got := compareDNSValues(c.a, c.b); got != c.want {
+			t.Errorf("compareDNSValues(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package poindexter
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Built-in timeouts used when DNSOptions.Timeout is zero.
+const (
+	defaultDNSTimeout  = 10 * time.Second
+	defaultRDAPTimeout = 15 * time.Second
+)
+
+// Resolver is the subset of *net.Resolver the DNS functions use. Supply one
+// with SetDefaultDNSOptions or WithDNSResolver to route lookups through another
+// transport (DNS-over-HTTPS, DNS-over-TLS, a cache) or a fake in tests.
+// *net.Resolver implements it.
+type Resolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupNS(ctx context.Context, name string) ([]*net.NS, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+var _ Resolver = (*net.Resolver)(nil)
+
+// DNSOptions are the package-wide defaults for DNS and RDAP lookups. Functions
+// without an explicit timeout (DNSLookup, DNSLookupAll, ReverseDNSLookup,
+// RDAPLookupDomain, ...) and the *WithOptions variants start from them; the
+// *WithTimeout variants and DNSLookupOption values override them per call.
+type DNSOptions struct {
+	// Timeout bounds a DNS lookup including its retries, and each RDAP
+	// request. Zero uses the built-in defaults: 10s for DNS, 15s for RDAP.
+	Timeout time.Duration
+	// Retries is how many more times a query is tried after a timeout or
+	// temporary failure (never after a definitive answer such as NXDOMAIN),
+	// and for RDAP after a transport error or a 429/5xx status. RDAP retries
+	// back off exponentially, or as long as the server's Retry-After asks.
+	Retries int
+	// Resolver performs DNS queries, and resolves RDAP server names. Nil uses
+	// the system resolver.
+	Resolver Resolver
+}
+
+var defaultDNSOptions atomic.Pointer[DNSOptions]
+
+// SetDefaultDNSOptions replaces the package-wide DNS and RDAP lookup defaults.
+// It is safe to call concurrently with lookups; lookups already running keep
+// the options they started with.
+func SetDefaultDNSOptions(o DNSOptions) {
+	defaultDNSOptions.Store(&o)
+}
+
+// DefaultDNSOptions returns the package-wide DNS and RDAP lookup defaults.
+func DefaultDNSOptions() DNSOptions {
+	if o := defaultDNSOptions.Load(); o != nil {
+		return *o
+	}
+	return DNSOptions{}
+}
+
+func (o DNSOptions) dnsTimeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return defaultDNSTimeout
+}
+
+func (o DNSOptions) rdapTimeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return defaultRDAPTimeout
+}
+
+// dnsResolver returns r, or the system resolver if r is nil.
+func dnsResolver(r Resolver) Resolver {
+	if r == nil {
+		return net.DefaultResolver
+	}
+	return r
+}
+
+// dnsRetry calls lookup, retrying up to retries more times while it fails with
+// a timeout or temporary error and ctx has not expired.
+func dnsRetry[T any](ctx context.Context, retries int, lookup func() (T, error)) (T, error) {
+	v, err := lookup()
+	for i := 0; i < retries && err != nil && ctx.Err() == nil && retryableDNSError(err); i++ {
+		v, err = lookup()
+	}
+	return v, err
+}
+
+func retryableDNSError(err error) bool {
+	var de *net.DNSError
+	if !errors.As(err, &de) || de.IsNotFound {
+		return false
+	}
+	return de.IsTimeout || de.IsTemporary
+}
+
+// RDAP retry backoff: the wait before retry n is rdapBackoffBase<<n, capped at
+// rdapBackoffMax. A server's Retry-After takes precedence, under the same cap.
+// Variables so tests can shorten them.
+var (
+	rdapBackoffBase = 250 * time.Millisecond
+	rdapBackoffMax  = 10 * time.Second
+)
+
+// rdapGet fetches url using the package defaults' resolver, retrying transport
+// errors and 429/5xx responses up to the defaults' Retries with exponential
+// backoff between attempts. Each attempt is bounded by timeout; ctx bounds the
+// whole call, including the waits.
+func rdapGet(ctx context.Context, url string, timeout time.Duration) (*http.Response, error) {
+	d := DefaultDNSOptions()
+	client := &http.Client{Timeout: timeout}
+	if d.Resolver != nil {
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		tr.DialContext = resolvingDialer(d.Resolver)
+		client.Transport = tr
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		retry := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !retry || attempt >= d.Retries || ctx.Err() != nil {
+			return resp, err
+		}
+		wait := rdapBackoff(attempt)
+		if resp != nil {
+			if ra, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				wait = min(ra, rdapBackoffMax)
+			}
+			resp.Body.Close()
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// rdapBackoff returns the wait before retrying after the given failed attempt.
+func rdapBackoff(attempt int) time.Duration {
+	if attempt >= 30 {
+		return rdapBackoffMax
+	}
+	return min(rdapBackoffBase<<attempt, rdapBackoffMax)
+}
+
+// retryAfter parses a Retry-After header value, either delay-seconds or an
+// HTTP-date, into a wait relative to now.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(min(secs, 1<<30)) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// resolvingDialer returns a DialContext that resolves host names with r.
+func resolvingDialer(r Resolver) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if nr, ok := r.(*net.Resolver); ok {
+		return (&net.Dialer{Resolver: nr}).DialContext
+	}
+	var d net.Dialer
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) != nil {
+			return d.DialContext(ctx, network, addr)
+		}
+		ips, err := r.LookupIP(ctx, "ip", host)
+		if err != nil {
+			return nil, err
+		}
+		if len(ips) == 0 {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		for _, ip := range ips {
+			var c net.Conn
+			if c, err = d.DialContext(ctx, network, net.JoinHostPort(ip.String(), port)); err == nil {
+				return c, nil
+			}
+		}
+		return nil, err
+	}
+}
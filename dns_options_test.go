@@ -0,0 +1,339 @@
+package poindexter
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDefaultDNSOptions(t *testing.T) {
+	t.Cleanup(func() { SetDefaultDNSOptions(DNSOptions{}) })
+	if o := newDNSLookupOptions(nil); o.timeout != 10*time.Second || o.retries != 0 || o.resolver != nil {
+		t.Fatalf("unexpected built-in defaults %+v", o)
+	}
+	if got := DefaultDNSOptions().rdapTimeout(); got != 15*time.Second {
+		t.Fatalf("rdap timeout %v", got)
+	}
+
+	r := &net.Resolver{PreferGo: true}
+	SetDefaultDNSOptions(DNSOptions{Timeout: 3 * time.Second, Retries: 2, Resolver: r})
+	o := newDNSLookupOptions(nil)
+	if o.timeout != 3*time.Second || o.retries != 2 || o.resolver != r {
+		t.Fatalf("defaults not applied: %+v", o)
+	}
+	if got := DefaultDNSOptions().rdapTimeout(); got != 3*time.Second {
+		t.Fatalf("rdap timeout %v", got)
+	}
+	o = newDNSLookupOptions([]DNSLookupOption{WithDNSTimeout(time.Second), WithDNSRetries(0), WithDNSResolver(nil)})
+	if o.timeout != time.Second || o.retries != 0 || o.resolver != nil {
+		t.Fatalf("per-call overrides not applied: %+v", o)
+	}
+}
+
+func TestDNSRetry(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	failing := func(err error) func() (string, error) {
+		return func() (string, error) {
+			calls++
+			return "", err
+		}
+	}
+	if _, err := dnsRetry(ctx, 2, failing(&net.DNSError{Err: "timeout", IsTimeout: true})); err == nil || calls != 3 {
+		t.Fatalf("timeout: %d calls, err %v", calls, err)
+	}
+	calls = 0
+	if _, err := dnsRetry(ctx, 2, failing(&net.DNSError{Err: "no such host", IsNotFound: true, IsTemporary: true})); err == nil || calls != 1 {
+		t.Fatalf("NXDOMAIN must not be retried: %d calls", calls)
+	}
+	calls = 0
+	v, err := dnsRetry(ctx, 3, func() (string, error) {
+		if calls++; calls < 2 {
+			return "", &net.DNSError{Err: "server misbehaving", IsTemporary: true}
+		}
+		return "ok", nil
+	})
+	if err != nil || v != "ok" || calls != 2 {
+		t.Fatalf("temporary: %q %v after %d calls", v, err, calls)
+	}
+}
+
+// shortRDAPBackoff shrinks the RDAP retry waits for the duration of a test.
+func shortRDAPBackoff(t *testing.T, base, max time.Duration) {
+	oldBase, oldMax := rdapBackoffBase, rdapBackoffMax
+	rdapBackoffBase, rdapBackoffMax = base, max
+	t.Cleanup(func() { rdapBackoffBase, rdapBackoffMax = oldBase, oldMax })
+}
+
+func TestRDAPGetRetries(t *testing.T) {
+	t.Cleanup(func() { SetDefaultDNSOptions(DNSOptions{}) })
+	shortRDAPBackoff(t, time.Millisecond, 10*time.Millisecond)
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits++; hits < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	resp, err := rdapGet(context.Background(), srv.URL, time.Second)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable || hits != 1 {
+		t.Fatalf("no retries by default: status %v, %d hits, err %v", resp.StatusCode, hits, err)
+	}
+	resp.Body.Close()
+
+	hits = 0
+	SetDefaultDNSOptions(DNSOptions{Retries: 2})
+	resp, err = rdapGet(context.Background(), srv.URL, time.Second)
+	if err != nil || resp.StatusCode != http.StatusOK || hits != 3 {
+		t.Fatalf("expected success on the third attempt: %d hits, err %v", hits, err)
+	}
+	resp.Body.Close()
+}
+
+func TestRDAPGetBackoff(t *testing.T) {
+	t.Cleanup(func() { SetDefaultDNSOptions(DNSOptions{}) })
+	shortRDAPBackoff(t, 20*time.Millisecond, time.Second)
+	var stamps []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stamps = append(stamps, time.Now())
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	SetDefaultDNSOptions(DNSOptions{Retries: 2})
+	resp, err := rdapGet(context.Background(), srv.URL, time.Second)
+	if err != nil || resp.StatusCode != http.StatusBadGateway || len(stamps) != 3 {
+		t.Fatalf("status %v, %d hits, err %v", resp.StatusCode, len(stamps), err)
+	}
+	resp.Body.Close()
+	if d := stamps[1].Sub(stamps[0]); d < 20*time.Millisecond {
+		t.Errorf("first retry after %v, want >= 20ms", d)
+	}
+	if d := stamps[2].Sub(stamps[1]); d < 40*time.Millisecond {
+		t.Errorf("second retry after %v, want >= 40ms", d)
+	}
+}
+
+func TestRDAPGetRetryAfter(t *testing.T) {
+	t.Cleanup(func() { SetDefaultDNSOptions(DNSOptions{}) })
+	shortRDAPBackoff(t, time.Millisecond, 5*time.Second)
+	var stamps []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if stamps = append(stamps, time.Now()); len(stamps) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	SetDefaultDNSOptions(DNSOptions{Retries: 1})
+	resp, err := rdapGet(context.Background(), srv.URL, time.Second)
+	if err != nil || resp.StatusCode != http.StatusOK || len(stamps) != 2 {
+		t.Fatalf("status %v, %d hits, err %v", resp.StatusCode, len(stamps), err)
+	}
+	resp.Body.Close()
+	if d := stamps[1].Sub(stamps[0]); d < time.Second {
+		t.Errorf("retried after %v, want Retry-After's 1s", d)
+	}
+
+	// The cap bounds how long a server can make us wait.
+	shortRDAPBackoff(t, time.Millisecond, 10*time.Millisecond)
+	stamps = nil
+	start := time.Now()
+	resp, err = rdapGet(context.Background(), srv.URL, time.Second)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %v, err %v", resp.StatusCode, err)
+	}
+	resp.Body.Close()
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("capped Retry-After still waited %v", d)
+	}
+}
+
+func TestRDAPGetCancelStopsRetries(t *testing.T) {
+	t.Cleanup(func() { SetDefaultDNSOptions(DNSOptions{}) })
+	shortRDAPBackoff(t, time.Hour, time.Hour)
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	SetDefaultDNSOptions(DNSOptions{Retries: 5})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	resp, err := rdapGet(ctx, srv.URL, time.Second)
+	if !errors.Is(err, context.DeadlineExceeded) || resp != nil {
+		t.Fatalf("got %v, %v; want context.DeadlineExceeded", resp, err)
+	}
+	if hits != 1 || time.Since(start) > time.Second {
+		t.Fatalf("%d hits in %v; the wait should end on cancellation", hits, time.Since(start))
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+	} {
+		if got, ok := retryAfter(tc.in, now); got != tc.want || ok != tc.ok {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// fakeResolver answers from fixed tables; names it has no entry for are
+// NXDOMAIN.
+type fakeResolver struct {
+	ips   map[string][]net.IP
+	mx    map[string][]*net.MX
+	txt   map[string][]string
+	ptr   map[string][]string
+	calls int
+}
+
+func (f *fakeResolver) notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (f *fakeResolver) LookupIP(_ context.Context, network, host string) ([]net.IP, error) {
+	f.calls++
+	var out []net.IP
+	for _, ip := range f.ips[host] {
+		if (network == "ip4") == (ip.To4() != nil) || network == "ip" {
+			out = append(out, ip)
+		}
+	}
+	if len(out) == 0 {
+		return nil, f.notFound(host)
+	}
+	return out, nil
+}
+
+func (f *fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	f.calls++
+	if mx, ok := f.mx[name]; ok {
+		return mx, nil
+	}
+	return nil, f.notFound(name)
+}
+
+func (f *fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	f.calls++
+	if txt, ok := f.txt[name]; ok {
+		return txt, nil
+	}
+	return nil, f.notFound(name)
+}
+
+func (f *fakeResolver) LookupNS(_ context.Context, name string) ([]*net.NS, error) {
+	f.calls++
+	return nil, f.notFound(name)
+}
+
+func (f *fakeResolver) LookupCNAME(_ context.Context, host string) (string, error) {
+	f.calls++
+	return host + ".", nil
+}
+
+func (f *fakeResolver) LookupSRV(_ context.Context, _, _, name string) (string, []*net.SRV, error) {
+	f.calls++
+	return "", nil, f.notFound(name)
+}
+
+func (f *fakeResolver) LookupAddr(_ context.Context, addr string) ([]string, error) {
+	f.calls++
+	if names, ok := f.ptr[addr]; ok {
+		return names, nil
+	}
+	return nil, f.notFound(addr)
+}
+
+func TestDNSLookupWithResolver(t *testing.T) {
+	t.Cleanup(func() { SetDefaultDNSOptions(DNSOptions{}) })
+	fake := &fakeResolver{
+		ips: map[string][]net.IP{"example.test": {net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")}},
+		mx: map[string][]*net.MX{"example.test": {
+			{Host: "mx2.example.test.", Pref: 20},
+			{Host: "mx1.example.test.", Pref: 10},
+		}},
+		ptr: map[string][]string{"192.0.2.1": {"host.example.test."}},
+	}
+
+	a := DNSLookupWithOptions("example.test", DNSRecordA, WithDNSResolver(fake))
+	if a.Error != "" || len(a.Records) != 1 || a.Records[0].Value != "192.0.2.1" {
+		t.Fatalf("A lookup: %+v", a)
+	}
+	mx := DNSLookupWithOptions("example.test", DNSRecordMX, WithDNSResolver(fake))
+	if mx.Error != "" || len(mx.MXRecords) != 2 || mx.MXRecords[0].Host != "mx1.example.test" {
+		t.Fatalf("MX lookup: %+v", mx)
+	}
+	if r := DNSLookupWithOptions("missing.test", DNSRecordA, WithDNSResolver(fake)); r.Error == "" {
+		t.Fatal("expected an error for an unknown name")
+	}
+
+	all := DNSLookupAllWithOptions("example.test", WithDNSResolver(fake))
+	if len(all.Errors) != 0 || len(all.A) != 1 || len(all.AAAA) != 1 || len(all.MX) != 2 || all.CNAME != "" {
+		t.Fatalf("DNSLookupAll: %+v", all)
+	}
+
+	// package defaults reach functions without options
+	SetDefaultDNSOptions(DNSOptions{Resolver: fake})
+	ptr := ReverseDNSLookup("192.0.2.1")
+	if ptr.Error != "" || len(ptr.Records) != 1 || ptr.Records[0].Value != "host.example.test" {
+		t.Fatalf("PTR lookup: %+v", ptr)
+	}
+}
+
+func TestCymruASNLookupWithResolver(t *testing.T) {
+	fake := &fakeResolver{txt: map[string][]string{
+		"1.2.0.192.origin.asn.cymru.com": {"64500 | 192.0.2.0/24 | ZZ | test | 2020-01-01"},
+	}}
+	asn, err := cymruASNLookup(context.Background(), fake, "192.0.2.1")
+	if err != nil || asn != "64500" {
+		t.Fatalf("got %q, %v", asn, err)
+	}
+	if _, err := cymruASNLookup(context.Background(), fake, "198.51.100.1"); err == nil {
+		t.Fatal("expected an error for an unknown address")
+	}
+}
+
+func TestRDAPGetUsesResolver(t *testing.T) {
+	t.Cleanup(func() { SetDefaultDNSOptions(DNSOptions{}) })
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	fake := &fakeResolver{ips: map[string][]net.IP{"rdap.test": {net.ParseIP("127.0.0.1")}}}
+	SetDefaultDNSOptions(DNSOptions{Resolver: fake})
+	resp, err := rdapGet(context.Background(), "http://rdap.test:"+port+"/", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || fake.calls != 1 {
+		t.Fatalf("status %d after %d resolver calls", resp.StatusCode, fake.calls)
+	}
+}
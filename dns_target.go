@@ -0,0 +1,348 @@
+package poindexter
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrInvalidTarget indicates a string ParseTarget cannot classify.
+var ErrInvalidTarget = errors.New("invalid target")
+
+// TargetKind classifies what a Target refers to.
+type TargetKind string
+
+const (
+	TargetDomain TargetKind = "domain"
+	TargetIPv4   TargetKind = "ipv4"
+	TargetIPv6   TargetKind = "ipv6"
+	TargetEmail  TargetKind = "email"
+	TargetASN    TargetKind = "asn"
+	TargetURL    TargetKind = "url"
+)
+
+// Target is a classified lookup subject, as typed into a search box: a
+// domain, an IP address, an email address, an ASN or a URL. Links and Lookup
+// route it to the tools and queries that apply to its kind.
+type Target struct {
+	// Raw is the input as given to ParseTarget.
+	Raw  string     `json:"raw"`
+	Kind TargetKind `json:"kind"`
+	// Value is the normalized target: a lower-case domain without a trailing
+	// dot, a canonical IP address, an email address with a lower-case domain,
+	// "AS" followed by the AS number, or a URL.
+	Value string `json:"value"`
+	// Host is the domain or IP address DNS and RDAP queries are about: the
+	// domain of an email address or the host of a URL. It is empty for ASNs.
+	Host string `json:"host,omitempty"`
+}
+
+// ParseTarget classifies s. It recognizes, in order:
+//
+//   - URLs with a scheme ("https://example.com/path"), classified by host
+//   - IPv4 and IPv6 addresses, including bracketed IPv6 ("[2001:db8::1]")
+//   - ASNs: "AS15169", "as15169" or a bare number
+//   - email addresses ("user@example.com")
+//   - domain names
+//
+// It returns ErrInvalidTarget for anything else.
+func ParseTarget(s string) (Target, error) {
+	raw := s
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Target{}, ErrInvalidTarget
+	}
+
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil || u.Hostname() == "" {
+			return Target{}, fmt.Errorf("%w: %q", ErrInvalidTarget, raw)
+		}
+		host, ok := targetHost(u.Hostname())
+		if !ok {
+			return Target{}, fmt.Errorf("%w: %q", ErrInvalidTarget, raw)
+		}
+		return Target{Raw: raw, Kind: TargetURL, Value: u.String(), Host: host}, nil
+	}
+
+	if a, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")); err == nil {
+		a = a.Unmap()
+		kind := TargetIPv6
+		if a.Is4() {
+			kind = TargetIPv4
+		}
+		return Target{Raw: raw, Kind: kind, Value: a.String(), Host: a.String()}, nil
+	}
+
+	if asn, ok := parseASN(s); ok {
+		return Target{Raw: raw, Kind: TargetASN, Value: asn}, nil
+	}
+
+	if local, domain, ok := strings.Cut(s, "@"); ok {
+		domain, valid := normalizeTargetDomain(domain)
+		if local == "" || strings.ContainsAny(local, "@ \t") || !valid {
+			return Target{}, fmt.Errorf("%w: %q", ErrInvalidTarget, raw)
+		}
+		return Target{Raw: raw, Kind: TargetEmail, Value: local + "@" + domain, Host: domain}, nil
+	}
+
+	if domain, ok := normalizeTargetDomain(s); ok {
+		return Target{Raw: raw, Kind: TargetDomain, Value: domain, Host: domain}, nil
+	}
+	return Target{}, fmt.Errorf("%w: %q", ErrInvalidTarget, raw)
+}
+
+// IsIP reports whether the target's Host is an IP address.
+func (t Target) IsIP() bool {
+	_, err := netip.ParseAddr(t.Host)
+	return err == nil
+}
+
+// targetHost normalizes a URL host: a canonical IP address or a domain.
+func targetHost(h string) (string, bool) {
+	if a, err := netip.ParseAddr(h); err == nil {
+		return a.Unmap().String(), true
+	}
+	return normalizeTargetDomain(h)
+}
+
+// parseASN accepts "AS<n>" (any case) or a bare AS number and returns "AS<n>".
+func parseASN(s string) (string, bool) {
+	if len(s) > 2 && strings.EqualFold(s[:2], "AS") {
+		s = s[2:]
+	}
+	if s == "" || len(s) > 10 {
+		return "", false
+	}
+	var n uint64
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return "", false
+		}
+		n = n*10 + uint64(c-'0')
+	}
+	if n > 1<<32-1 {
+		return "", false
+	}
+	return fmt.Sprintf("AS%d", n), true
+}
+
+// normalizeTargetDomain lower-cases d and strips a trailing dot, reporting
+// whether the result is a plausible host name: dot-separated labels of
+// letters, digits, '-' and '_' (internationalized names allowed), no label
+// longer than 63 bytes, at most 253 bytes in all and a last label that is not
+// all digits (so malformed IPs and AS numbers are not taken for domains).
+func normalizeTargetDomain(d string) (string, bool) {
+	d = strings.ToLower(strings.TrimSuffix(d, "."))
+	if d == "" || len(d) > 253 || !utf8.ValidString(d) {
+		return "", false
+	}
+	labels := strings.Split(d, ".")
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return "", false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", false
+		}
+		for _, c := range label {
+			switch {
+			case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c >= utf8.RuneSelf:
+			default:
+				return "", false
+			}
+		}
+	}
+	return d, true
+}
+
+// hostTarget returns the domain or IP target for the target's Host.
+func (t Target) hostTarget() Target {
+	kind := TargetDomain
+	if a, err := netip.ParseAddr(t.Host); err == nil {
+		kind = TargetIPv6
+		if a.Is4() {
+			kind = TargetIPv4
+		}
+	}
+	return Target{Raw: t.Host, Kind: kind, Value: t.Host, Host: t.Host}
+}
+
+// Links returns the external analysis tools that apply to the target: DNS,
+// web and mail checks for domains, reputation and routing tools for IP
+// addresses, mail checks for email addresses and routing registries for ASNs.
+// A URL gets the links for its host.
+func (t Target) Links() ExternalToolLinks {
+	switch t.Kind {
+	case TargetDomain:
+		return domainToolLinks(t.Value)
+	case TargetIPv4, TargetIPv6:
+		return ipToolLinks(t.Value)
+	case TargetEmail:
+		return emailToolLinks(t.Value, t.Host)
+	case TargetASN:
+		return asnToolLinks(t.Value)
+	case TargetURL:
+		return t.hostTarget().Links()
+	}
+	return ExternalToolLinks{Target: t.Raw}
+}
+
+// mxtoolboxLink returns the MXToolbox SuperTool URL running action on the
+// query-escaped subject q.
+func mxtoolboxLink(action, q string) string {
+	return fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=%s%%3a%s&run=toolpage", action, q)
+}
+
+func domainToolLinks(domain string) ExternalToolLinks {
+	encoded := url.QueryEscape(domain)
+
+	return ExternalToolLinks{
+		Target: domain,
+		Type:   "domain",
+
+		// MXToolbox
+		MXToolboxDNS:       mxtoolboxLink("dns", encoded),
+		MXToolboxMX:        mxtoolboxLink("mx", encoded),
+		MXToolboxBlacklist: mxtoolboxLink("blacklist", encoded),
+		MXToolboxSMTP:      mxtoolboxLink("smtp", encoded),
+		MXToolboxSPF:       mxtoolboxLink("spf", encoded),
+		MXToolboxDMARC:     mxtoolboxLink("dmarc", encoded),
+		MXToolboxDKIM:      mxtoolboxLink("dkim", encoded),
+		MXToolboxHTTP:      mxtoolboxLink("http", encoded),
+		MXToolboxHTTPS:     mxtoolboxLink("https", encoded),
+		MXToolboxPing:      mxtoolboxLink("ping", encoded),
+		MXToolboxTrace:     mxtoolboxLink("trace", encoded),
+		MXToolboxWhois:     mxtoolboxLink("whois", encoded),
+
+		// DNSChecker
+		DNSCheckerDNS:         fmt.Sprintf("https://dnschecker.org/#A/%s", encoded),
+		DNSCheckerPropagation: fmt.Sprintf("https://dnschecker.org/dns-propagation.php?domain=%s", encoded),
+
+		// Other tools
+		WhoIs:          fmt.Sprintf("https://who.is/whois/%s", encoded),
+		ViewDNS:        fmt.Sprintf("https://viewdns.info/dnsrecord/?domain=%s", encoded),
+		IntoDNS:        fmt.Sprintf("https://intodns.com/%s", encoded),
+		DNSViz:         fmt.Sprintf("https://dnsviz.net/d/%s/analyze/", encoded),
+		SecurityTrails: fmt.Sprintf("https://securitytrails.com/domain/%s", encoded),
+		BuiltWith:      fmt.Sprintf("https://builtwith.com/%s", encoded),
+		SSLLabs:        fmt.Sprintf("https://www.ssllabs.com/ssltest/analyze.html?d=%s", encoded),
+		HSTSPreload:    fmt.Sprintf("https://hstspreload.org/?domain=%s", encoded),
+		Hardenize:      fmt.Sprintf("https://www.hardenize.com/report/%s", encoded),
+		VirusTotal:     fmt.Sprintf("https://www.virustotal.com/gui/domain/%s", encoded),
+	}
+}
+
+func ipToolLinks(ip string) ExternalToolLinks {
+	encoded := url.QueryEscape(ip)
+
+	return ExternalToolLinks{
+		Target: ip,
+		Type:   "ip",
+
+		// MXToolbox
+		MXToolboxBlacklist: mxtoolboxLink("blacklist", encoded),
+		MXToolboxPing:      mxtoolboxLink("ping", encoded),
+		MXToolboxTrace:     mxtoolboxLink("trace", encoded),
+		MXToolboxWhois:     mxtoolboxLink("whois", encoded),
+		MXToolboxASN:       mxtoolboxLink("asn", encoded),
+
+		// IP-specific tools
+		IPInfo:      fmt.Sprintf("https://ipinfo.io/%s", encoded),
+		AbuseIPDB:   fmt.Sprintf("https://www.abuseipdb.com/check/%s", encoded),
+		VirusTotal:  fmt.Sprintf("https://www.virustotal.com/gui/ip-address/%s", encoded),
+		Shodan:      fmt.Sprintf("https://www.shodan.io/host/%s", encoded),
+		Censys:      fmt.Sprintf("https://search.censys.io/hosts/%s", encoded),
+		ThreatCrowd: fmt.Sprintf("https://www.threatcrowd.org/ip.php?ip=%s", encoded),
+	}
+}
+
+// emailToolLinks builds mail checks for target, an email address or a bare
+// domain, run against domain.
+func emailToolLinks(target, domain string) ExternalToolLinks {
+	encoded := url.QueryEscape(domain)
+	emailEncoded := url.QueryEscape(target)
+
+	return ExternalToolLinks{
+		Target: target,
+		Type:   "email",
+
+		// MXToolbox email checks
+		MXToolboxMX:    mxtoolboxLink("mx", encoded),
+		MXToolboxSMTP:  mxtoolboxLink("smtp", encoded),
+		MXToolboxSPF:   mxtoolboxLink("spf", encoded),
+		MXToolboxDMARC: mxtoolboxLink("dmarc", encoded),
+		MXToolboxDKIM:  mxtoolboxLink("dkim", encoded),
+
+		// Email-specific tools
+		MailTester: fmt.Sprintf("https://www.mail-tester.com/test-%s", emailEncoded),
+		LearnDMARC: fmt.Sprintf("https://www.learndmarc.com/?domain=%s", encoded),
+	}
+}
+
+// asnToolLinks builds routing-registry links for asn ("AS<n>").
+func asnToolLinks(asn string) ExternalToolLinks {
+	num := strings.TrimPrefix(asn, "AS")
+
+	return ExternalToolLinks{
+		Target: asn,
+		Type:   "asn",
+
+		MXToolboxASN: mxtoolboxLink("asn", asn),
+		IPInfo:       fmt.Sprintf("https://ipinfo.io/%s", asn),
+		Shodan:       fmt.Sprintf("https://www.shodan.io/search?query=asn%%3A%s", asn),
+
+		// ASN-specific tools
+		BGPHE:     fmt.Sprintf("https://bgp.he.net/%s", asn),
+		PeeringDB: fmt.Sprintf("https://www.peeringdb.com/asn/%s", num),
+	}
+}
+
+// TargetLookup is the combined result of Lookup. Only the parts that apply
+// to the target's kind are set.
+type TargetLookup struct {
+	Target Target `json:"target"`
+	// DNS holds the records of a domain, an email domain or a URL's host name.
+	DNS *CompleteDNSLookup `json:"dns,omitempty"`
+	// ReverseDNS holds the PTR records of an IP address or a URL's IP host.
+	ReverseDNS *DNSLookupResult `json:"reverseDns,omitempty"`
+	// RDAP is the registration record for the domain, IP network or ASN.
+	RDAP         *RDAPResponse     `json:"rdap,omitempty"`
+	Links        ExternalToolLinks `json:"links"`
+	LookupTimeMs int64             `json:"lookupTimeMs"`
+	Timestamp    time.Time         `json:"timestamp"`
+}
+
+// Lookup runs the DNS and RDAP queries that apply to target: all common
+// records and the registration of a domain (for email addresses and URLs,
+// of their domain or host), reverse DNS and the network registration of an
+// IP address, and the registration of an ASN. opts apply to the DNS queries;
+// RDAP uses the package defaults (SetDefaultDNSOptions). Errors are reported
+// in the individual results.
+func Lookup(target Target, opts ...DNSLookupOption) TargetLookup {
+	start := time.Now()
+	result := TargetLookup{Target: target, Links: target.Links(), Timestamp: start}
+
+	switch target.Kind {
+	case TargetDomain, TargetEmail, TargetIPv4, TargetIPv6, TargetURL:
+		if target.IsIP() {
+			rev := DNSLookupWithOptions(target.Host, DNSRecordPTR, opts...)
+			rdap := RDAPLookupIP(target.Host)
+			result.ReverseDNS, result.RDAP = &rev, &rdap
+		} else if target.Host != "" {
+			dns := DNSLookupAllWithOptions(target.Host, opts...)
+			rdap := RDAPLookupDomain(target.Host)
+			result.DNS, result.RDAP = &dns, &rdap
+		}
+	case TargetASN:
+		rdap := RDAPLookupASN(target.Value)
+		result.RDAP = &rdap
+	}
+
+	result.LookupTimeMs = time.Since(start).Milliseconds()
+	return result
+}
@@ -0,0 +1,107 @@
+package poindexter
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		in          string
+		kind        TargetKind
+		value, host string
+	}{
+		{"Example.COM.", TargetDomain, "example.com", "example.com"},
+		{" _dmarc.example.com ", TargetDomain, "_dmarc.example.com", "_dmarc.example.com"},
+		{"bücher.example", TargetDomain, "bücher.example", "bücher.example"},
+		{"8.8.8.8", TargetIPv4, "8.8.8.8", "8.8.8.8"},
+		{"::ffff:192.0.2.1", TargetIPv4, "192.0.2.1", "192.0.2.1"},
+		{"2001:DB8::1", TargetIPv6, "2001:db8::1", "2001:db8::1"},
+		{"[2001:db8::1]", TargetIPv6, "2001:db8::1", "2001:db8::1"},
+		{"AS15169", TargetASN, "AS15169", ""},
+		{"as015169", TargetASN, "AS15169", ""},
+		{"13335", TargetASN, "AS13335", ""},
+		{"User@Example.com", TargetEmail, "User@example.com", "example.com"},
+		{"https://Example.com:8443/path?q=1", TargetURL, "https://Example.com:8443/path?q=1", "example.com"},
+		{"http://[2001:db8::1]/", TargetURL, "http://[2001:db8::1]/", "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		got, err := ParseTarget(tt.in)
+		if err != nil {
+			t.Errorf("ParseTarget(%q): %v", tt.in, err)
+			continue
+		}
+		if got.Raw != tt.in || got.Kind != tt.kind || got.Value != tt.value || got.Host != tt.host {
+			t.Errorf("ParseTarget(%q) = %+v", tt.in, got)
+		}
+	}
+
+	for _, in := range []string{"", "  ", "exa mple.com", "-bad.example", "a..b", "user@", "@example.com", "a@b@c", "https://", "4294967296", "1.2.3.999", "8.8.8.8:53"} {
+		if _, err := ParseTarget(in); !errors.Is(err, ErrInvalidTarget) {
+			t.Errorf("ParseTarget(%q): expected ErrInvalidTarget, got %v", in, err)
+		}
+	}
+}
+
+func TestTargetLinks(t *testing.T) {
+	parse := func(s string) Target {
+		tg, err := ParseTarget(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tg
+	}
+
+	// the deprecated entry points build the same links
+	if got, want := parse("example.com").Links(), GetExternalToolLinks("example.com"); !reflect.DeepEqual(got, want) {
+		t.Errorf("domain links differ:\n%+v\n%+v", got, want)
+	}
+	if got, want := parse("8.8.8.8").Links(), GetExternalToolLinksIP("8.8.8.8"); !reflect.DeepEqual(got, want) {
+		t.Errorf("IP links differ:\n%+v\n%+v", got, want)
+	}
+	if got, want := parse("test@example.com").Links(), GetExternalToolLinksEmail("test@example.com"); !reflect.DeepEqual(got, want) {
+		t.Errorf("email links differ:\n%+v\n%+v", got, want)
+	}
+
+	if l := parse("2001:db8::1").Links(); l.Type != "ip" || l.IPInfo == "" {
+		t.Errorf("IPv6 links: %+v", l)
+	}
+	if l := parse("https://example.com/x").Links(); l.Type != "domain" || l.Target != "example.com" {
+		t.Errorf("URL links should be those of its host: %+v", l)
+	}
+	l := parse("as15169").Links()
+	if l.Type != "asn" || l.Target != "AS15169" || !strings.HasSuffix(l.BGPHE, "/AS15169") || !strings.HasSuffix(l.PeeringDB, "/15169") {
+		t.Errorf("ASN links: %+v", l)
+	}
+}
+
+func TestLookupRoutesByKind(t *testing.T) {
+	fake := &fakeResolver{
+		ips: map[string][]net.IP{"example.test": {net.ParseIP("192.0.2.1")}},
+		ptr: map[string][]string{"192.0.2.1": {"host.example.test."}},
+	}
+	// RDAP servers do not resolve through the fake, so RDAP fails fast offline
+	SetDefaultDNSOptions(DNSOptions{Resolver: fake})
+	t.Cleanup(func() { SetDefaultDNSOptions(DNSOptions{}) })
+
+	tg, _ := ParseTarget("ops@example.test")
+	r := Lookup(tg)
+	if r.DNS == nil || len(r.DNS.A) != 1 || r.ReverseDNS != nil || r.RDAP == nil || r.Links.Type != "email" {
+		t.Fatalf("email lookup: %+v", r)
+	}
+
+	tg, _ = ParseTarget("http://192.0.2.1/status")
+	r = Lookup(tg)
+	if r.ReverseDNS == nil || len(r.ReverseDNS.Records) != 1 || r.DNS != nil || r.RDAP == nil || r.RDAP.Error == "" {
+		t.Fatalf("URL lookup: %+v", r)
+	}
+
+	tg, _ = ParseTarget("AS64500")
+	r = Lookup(tg)
+	if r.DNS != nil || r.ReverseDNS != nil || r.RDAP == nil || r.RDAP.Handle != "AS64500" {
+		t.Fatalf("ASN lookup: %+v", r)
+	}
+}
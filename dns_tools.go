@@ -7,7 +7,6 @@ import (
 	"io"
 	"net"
 	"net/http"
-	"net/url"
 	"sort"
 	"strings"
 	"time"
@@ -254,13 +253,18 @@ type CompleteDNSLookup struct {
 // DNS Lookup Functions
 // ============================================================================
 
-// DNSLookup performs a DNS lookup for the specified record type
+// DNSLookup performs a DNS lookup for the specified record type, using the
+// package defaults (SetDefaultDNSOptions)
 func DNSLookup(domain string, recordType DNSRecordType) DNSLookupResult {
-	return DNSLookupWithTimeout(domain, recordType, 10*time.Second)
+	return dnsLookup(domain, recordType, newDNSLookupOptions(nil))
 }
 
 // DNSLookupWithTimeout performs a DNS lookup with a custom timeout
 func DNSLookupWithTimeout(domain string, recordType DNSRecordType, timeout time.Duration) DNSLookupResult {
+	return dnsLookup(domain, recordType, newDNSLookupOptions([]DNSLookupOption{WithDNSTimeout(timeout)}))
+}
+
+func dnsLookup(domain string, recordType DNSRecordType, o dnsLookupOptions) DNSLookupResult {
 	start := time.Now()
 	result := DNSLookupResult{
 		Domain:    domain,
@@ -268,14 +272,14 @@ func DNSLookupWithTimeout(domain string, recordType DNSRecordType, timeout time.
 		Timestamp: start,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
 	defer cancel()
 
-	resolver := net.Resolver{}
+	resolver := dnsResolver(o.resolver)
 
 	switch recordType {
 	case DNSRecordA:
-		ips, err := resolver.LookupIP(ctx, "ip4", domain)
+		ips, err := dnsRetry(ctx, o.retries, func() ([]net.IP, error) { return resolver.LookupIP(ctx, "ip4", domain) })
 		if err != nil {
 			result.Error = err.Error()
 		} else {
@@ -289,7 +293,7 @@ func DNSLookupWithTimeout(domain string, recordType DNSRecordType, timeout time.
 		}
 
 	case DNSRecordAAAA:
-		ips, err := resolver.LookupIP(ctx, "ip6", domain)
+		ips, err := dnsRetry(ctx, o.retries, func() ([]net.IP, error) { return resolver.LookupIP(ctx, "ip6", domain) })
 		if err != nil {
 			result.Error = err.Error()
 		} else {
@@ -303,7 +307,7 @@ func DNSLookupWithTimeout(domain string, recordType DNSRecordType, timeout time.
 		}
 
 	case DNSRecordMX:
-		mxs, err := resolver.LookupMX(ctx, domain)
+		mxs, err := dnsRetry(ctx, o.retries, func() ([]*net.MX, error) { return resolver.LookupMX(ctx, domain) })
 		if err != nil {
 			result.Error = err.Error()
 		} else {
@@ -325,7 +329,7 @@ func DNSLookupWithTimeout(domain string, recordType DNSRecordType, timeout time.
 		}
 
 	case DNSRecordTXT:
-		txts, err := resolver.LookupTXT(ctx, domain)
+		txts, err := dnsRetry(ctx, o.retries, func() ([]string, error) { return resolver.LookupTXT(ctx, domain) })
 		if err != nil {
 			result.Error = err.Error()
 		} else {
@@ -339,7 +343,7 @@ func DNSLookupWithTimeout(domain string, recordType DNSRecordType, timeout time.
 		}
 
 	case DNSRecordNS:
-		nss, err := resolver.LookupNS(ctx, domain)
+		nss, err := dnsRetry(ctx, o.retries, func() ([]*net.NS, error) { return resolver.LookupNS(ctx, domain) })
 		if err != nil {
 			result.Error = err.Error()
 		} else {
@@ -353,7 +357,7 @@ func DNSLookupWithTimeout(domain string, recordType DNSRecordType, timeout time.
 		}
 
 	case DNSRecordCNAME:
-		cname, err := resolver.LookupCNAME(ctx, domain)
+		cname, err := dnsRetry(ctx, o.retries, func() (string, error) { return resolver.LookupCNAME(ctx, domain) })
 		if err != nil {
 			result.Error = err.Error()
 		} else {
@@ -366,7 +370,10 @@ func DNSLookupWithTimeout(domain string, recordType DNSRecordType, timeout time.
 
 	case DNSRecordSRV:
 		// SRV records require a service and protocol prefix, e.g., _http._tcp.example.com
-		_, srvs, err := resolver.LookupSRV(ctx, "", "", domain)
+		srvs, err := dnsRetry(ctx, o.retries, func() ([]*net.SRV, error) {
+			_, srvs, err := resolver.LookupSRV(ctx, "", "", domain)
+			return srvs, err
+		})
 		if err != nil {
 			result.Error = err.Error()
 		} else {
@@ -386,7 +393,7 @@ func DNSLookupWithTimeout(domain string, recordType DNSRecordType, timeout time.
 		}
 
 	case DNSRecordPTR:
-		names, err := resolver.LookupAddr(ctx, domain)
+		names, err := dnsRetry(ctx, o.retries, func() ([]string, error) { return resolver.LookupAddr(ctx, domain) })
 		if err != nil {
 			result.Error = err.Error()
 		} else {
@@ -407,26 +414,31 @@ func DNSLookupWithTimeout(domain string, recordType DNSRecordType, timeout time.
 	return result
 }
 
-// DNSLookupAll performs lookups for all common record types
+// DNSLookupAll performs lookups for all common record types, using the package
+// defaults (SetDefaultDNSOptions)
 func DNSLookupAll(domain string) CompleteDNSLookup {
-	return DNSLookupAllWithTimeout(domain, 10*time.Second)
+	return dnsLookupAll(domain, newDNSLookupOptions(nil))
 }
 
 // DNSLookupAllWithTimeout performs lookups for all common record types with timeout
 func DNSLookupAllWithTimeout(domain string, timeout time.Duration) CompleteDNSLookup {
+	return dnsLookupAll(domain, newDNSLookupOptions([]DNSLookupOption{WithDNSTimeout(timeout)}))
+}
+
+func dnsLookupAll(domain string, o dnsLookupOptions) CompleteDNSLookup {
 	start := time.Now()
 	result := CompleteDNSLookup{
 		Domain:    domain,
 		Timestamp: start,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
 	defer cancel()
 
-	resolver := net.Resolver{}
+	resolver := dnsResolver(o.resolver)
 
 	// A records
-	if ips, err := resolver.LookupIP(ctx, "ip4", domain); err == nil {
+	if ips, err := dnsRetry(ctx, o.retries, func() ([]net.IP, error) { return resolver.LookupIP(ctx, "ip4", domain) }); err == nil {
 		for _, ip := range ips {
 			result.A = append(result.A, ip.String())
 		}
@@ -435,7 +447,7 @@ func DNSLookupAllWithTimeout(domain string, timeout time.Duration) CompleteDNSLo
 	}
 
 	// AAAA records
-	if ips, err := resolver.LookupIP(ctx, "ip6", domain); err == nil {
+	if ips, err := dnsRetry(ctx, o.retries, func() ([]net.IP, error) { return resolver.LookupIP(ctx, "ip6", domain) }); err == nil {
 		for _, ip := range ips {
 			result.AAAA = append(result.AAAA, ip.String())
 		}
@@ -444,7 +456,7 @@ func DNSLookupAllWithTimeout(domain string, timeout time.Duration) CompleteDNSLo
 	}
 
 	// MX records
-	if mxs, err := resolver.LookupMX(ctx, domain); err == nil {
+	if mxs, err := dnsRetry(ctx, o.retries, func() ([]*net.MX, error) { return resolver.LookupMX(ctx, domain) }); err == nil {
 		for _, mx := range mxs {
 			result.MX = append(result.MX, MXRecord{
 				Host:     strings.TrimSuffix(mx.Host, "."),
@@ -459,7 +471,7 @@ func DNSLookupAllWithTimeout(domain string, timeout time.Duration) CompleteDNSLo
 	}
 
 	// NS records
-	if nss, err := resolver.LookupNS(ctx, domain); err == nil {
+	if nss, err := dnsRetry(ctx, o.retries, func() ([]*net.NS, error) { return resolver.LookupNS(ctx, domain) }); err == nil {
 		for _, ns := range nss {
 			result.NS = append(result.NS, strings.TrimSuffix(ns.Host, "."))
 		}
@@ -468,14 +480,14 @@ func DNSLookupAllWithTimeout(domain string, timeout time.Duration) CompleteDNSLo
 	}
 
 	// TXT records
-	if txts, err := resolver.LookupTXT(ctx, domain); err == nil {
+	if txts, err := dnsRetry(ctx, o.retries, func() ([]string, error) { return resolver.LookupTXT(ctx, domain) }); err == nil {
 		result.TXT = txts
 	} else if !isNoSuchHostError(err) {
 		result.Errors = append(result.Errors, fmt.Sprintf("TXT: %s", err.Error()))
 	}
 
 	// CNAME record
-	if cname, err := resolver.LookupCNAME(ctx, domain); err == nil {
+	if cname, err := dnsRetry(ctx, o.retries, func() (string, error) { return resolver.LookupCNAME(ctx, domain) }); err == nil {
 		result.CNAME = strings.TrimSuffix(cname, ".")
 		// If CNAME equals domain, it's not really a CNAME
 		if result.CNAME == domain {
@@ -489,7 +501,7 @@ func DNSLookupAllWithTimeout(domain string, timeout time.Duration) CompleteDNSLo
 
 // ReverseDNSLookup performs a reverse DNS lookup for an IP address
 func ReverseDNSLookup(ip string) DNSLookupResult {
-	return DNSLookupWithTimeout(ip, DNSRecordPTR, 10*time.Second)
+	return DNSLookup(ip, DNSRecordPTR)
 }
 
 func isNoSuchHostError(err error) bool {
@@ -627,7 +639,7 @@ var rdapServers = map[string]string{
 
 // RDAPLookupDomain performs an RDAP lookup for a domain
 func RDAPLookupDomain(domain string) RDAPResponse {
-	return RDAPLookupDomainWithTimeout(domain, 15*time.Second)
+	return RDAPLookupDomainWithTimeout(domain, DefaultDNSOptions().rdapTimeout())
 }
 
 // RDAPLookupDomainWithTimeout performs an RDAP lookup with custom timeout
@@ -656,8 +668,7 @@ func RDAPLookupDomainWithTimeout(domain string, timeout time.Duration) RDAPRespo
 		serverURL = serverURL + "domain/" + domain
 	}
 
-	client := &http.Client{Timeout: timeout}
-	resp, err := client.Get(serverURL)
+	resp, err := rdapGet(context.Background(), serverURL, timeout)
 	if err != nil {
 		result.Error = fmt.Sprintf("RDAP request failed: %s", err.Error())
 		result.LookupTimeMs = time.Since(start).Milliseconds()
@@ -690,7 +701,7 @@ func RDAPLookupDomainWithTimeout(domain string, timeout time.Duration) RDAPRespo
 
 // RDAPLookupIP performs an RDAP lookup for an IP address
 func RDAPLookupIP(ip string) RDAPResponse {
-	return RDAPLookupIPWithTimeout(ip, 15*time.Second)
+	return RDAPLookupIPWithTimeout(ip, DefaultDNSOptions().rdapTimeout())
 }
 
 // RDAPLookupIPWithTimeout performs an RDAP lookup for an IP with custom timeout
@@ -711,8 +722,7 @@ func RDAPLookupIPWithTimeout(ip string, timeout time.Duration) RDAPResponse {
 	// Use rdap.org as a universal redirector
 	serverURL := fmt.Sprintf("https://rdap.org/ip/%s", ip)
 
-	client := &http.Client{Timeout: timeout}
-	resp, err := client.Get(serverURL)
+	resp, err := rdapGet(context.Background(), serverURL, timeout)
 	if err != nil {
 		result.Error = fmt.Sprintf("RDAP request failed: %s", err.Error())
 		result.LookupTimeMs = time.Since(start).Milliseconds()
@@ -745,7 +755,7 @@ func RDAPLookupIPWithTimeout(ip string, timeout time.Duration) RDAPResponse {
 
 // RDAPLookupASN performs an RDAP lookup for an ASN
 func RDAPLookupASN(asn string) RDAPResponse {
-	return RDAPLookupASNWithTimeout(asn, 15*time.Second)
+	return RDAPLookupASNWithTimeout(asn, DefaultDNSOptions().rdapTimeout())
 }
 
 // RDAPLookupASNWithTimeout performs an RDAP lookup for an ASN with timeout
@@ -762,8 +772,7 @@ func RDAPLookupASNWithTimeout(asn string, timeout time.Duration) RDAPResponse {
 	// Use rdap.org as a universal redirector
 	serverURL := fmt.Sprintf("https://rdap.org/autnum/%s", asnNum)
 
-	client := &http.Client{Timeout: timeout}
-	resp, err := client.Get(serverURL)
+	resp, err := rdapGet(context.Background(), serverURL, timeout)
 	if err != nil {
 		result.Error = fmt.Sprintf("RDAP request failed: %s", err.Error())
 		result.LookupTimeMs = time.Since(start).Milliseconds()
@@ -802,7 +811,7 @@ func RDAPLookupASNWithTimeout(asn string, timeout time.Duration) RDAPResponse {
 type ExternalToolLinks struct {
 	// Target being analyzed
 	Target string `json:"target"`
-	Type   string `json:"type"` // "domain", "ip", "email"
+	Type   string `json:"type"` // "domain", "ip", "email", "asn"
 
 	// MXToolbox links
 	MXToolboxDNS         string `json:"mxtoolboxDns,omitempty"`
@@ -845,74 +854,29 @@ type ExternalToolLinks struct {
 	// Email-specific tools
 	MailTester           string `json:"mailtester,omitempty"`
 	LearnDMARC           string `json:"learndmarc,omitempty"`
+
+	// ASN-specific tools
+	BGPHE                string `json:"bgphe,omitempty"`
+	PeeringDB            string `json:"peeringdb,omitempty"`
 }
 
 // GetExternalToolLinks generates links to external analysis tools for a domain
+//
+// Deprecated: use ParseTarget and Target.Links, which classify the input.
 func GetExternalToolLinks(domain string) ExternalToolLinks {
-	encoded := url.QueryEscape(domain)
-
-	return ExternalToolLinks{
-		Target: domain,
-		Type:   "domain",
-
-		// MXToolbox
-		MXToolboxDNS:       fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=dns%%3a%s&run=toolpage", encoded),
-		MXToolboxMX:        fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=mx%%3a%s&run=toolpage", encoded),
-		MXToolboxBlacklist: fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=blacklist%%3a%s&run=toolpage", encoded),
-		MXToolboxSMTP:      fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=smtp%%3a%s&run=toolpage", encoded),
-		MXToolboxSPF:       fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=spf%%3a%s&run=toolpage", encoded),
-		MXToolboxDMARC:     fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=dmarc%%3a%s&run=toolpage", encoded),
-		MXToolboxDKIM:      fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=dkim%%3a%s&run=toolpage", encoded),
-		MXToolboxHTTP:      fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=http%%3a%s&run=toolpage", encoded),
-		MXToolboxHTTPS:     fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=https%%3a%s&run=toolpage", encoded),
-		MXToolboxPing:      fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=ping%%3a%s&run=toolpage", encoded),
-		MXToolboxTrace:     fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=trace%%3a%s&run=toolpage", encoded),
-		MXToolboxWhois:     fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=whois%%3a%s&run=toolpage", encoded),
-
-		// DNSChecker
-		DNSCheckerDNS:        fmt.Sprintf("https://dnschecker.org/#A/%s", encoded),
-		DNSCheckerPropagation: fmt.Sprintf("https://dnschecker.org/dns-propagation.php?domain=%s", encoded),
-
-		// Other tools
-		WhoIs:          fmt.Sprintf("https://who.is/whois/%s", encoded),
-		ViewDNS:        fmt.Sprintf("https://viewdns.info/dnsrecord/?domain=%s", encoded),
-		IntoDNS:        fmt.Sprintf("https://intodns.com/%s", encoded),
-		DNSViz:         fmt.Sprintf("https://dnsviz.net/d/%s/analyze/", encoded),
-		SecurityTrails: fmt.Sprintf("https://securitytrails.com/domain/%s", encoded),
-		BuiltWith:      fmt.Sprintf("https://builtwith.com/%s", encoded),
-		SSLLabs:        fmt.Sprintf("https://www.ssllabs.com/ssltest/analyze.html?d=%s", encoded),
-		HSTSPreload:    fmt.Sprintf("https://hstspreload.org/?domain=%s", encoded),
-		Hardenize:      fmt.Sprintf("https://www.hardenize.com/report/%s", encoded),
-		VirusTotal:     fmt.Sprintf("https://www.virustotal.com/gui/domain/%s", encoded),
-	}
+	return domainToolLinks(domain)
 }
 
 // GetExternalToolLinksIP generates links to external analysis tools for an IP
+//
+// Deprecated: use ParseTarget and Target.Links, which classify the input.
 func GetExternalToolLinksIP(ip string) ExternalToolLinks {
-	encoded := url.QueryEscape(ip)
-
-	return ExternalToolLinks{
-		Target: ip,
-		Type:   "ip",
-
-		// MXToolbox
-		MXToolboxBlacklist: fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=blacklist%%3a%s&run=toolpage", encoded),
-		MXToolboxPing:      fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=ping%%3a%s&run=toolpage", encoded),
-		MXToolboxTrace:     fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=trace%%3a%s&run=toolpage", encoded),
-		MXToolboxWhois:     fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=whois%%3a%s&run=toolpage", encoded),
-		MXToolboxASN:       fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=asn%%3a%s&run=toolpage", encoded),
-
-		// IP-specific tools
-		IPInfo:      fmt.Sprintf("https://ipinfo.io/%s", encoded),
-		AbuseIPDB:   fmt.Sprintf("https://www.abuseipdb.com/check/%s", encoded),
-		VirusTotal:  fmt.Sprintf("https://www.virustotal.com/gui/ip-address/%s", encoded),
-		Shodan:      fmt.Sprintf("https://www.shodan.io/host/%s", encoded),
-		Censys:      fmt.Sprintf("https://search.censys.io/hosts/%s", encoded),
-		ThreatCrowd: fmt.Sprintf("https://www.threatcrowd.org/ip.php?ip=%s", encoded),
-	}
+	return ipToolLinks(ip)
 }
 
 // GetExternalToolLinksEmail generates links for email-related checks
+//
+// Deprecated: use ParseTarget and Target.Links, which classify the input.
 func GetExternalToolLinksEmail(emailOrDomain string) ExternalToolLinks {
 	// Extract domain from email if needed
 	domain := emailOrDomain
@@ -922,25 +886,7 @@ func GetExternalToolLinksEmail(emailOrDomain string) ExternalToolLinks {
 			domain = parts[1]
 		}
 	}
-
-	encoded := url.QueryEscape(domain)
-	emailEncoded := url.QueryEscape(emailOrDomain)
-
-	return ExternalToolLinks{
-		Target: emailOrDomain,
-		Type:   "email",
-
-		// MXToolbox email checks
-		MXToolboxMX:    fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=mx%%3a%s&run=toolpage", encoded),
-		MXToolboxSMTP:  fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=smtp%%3a%s&run=toolpage", encoded),
-		MXToolboxSPF:   fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=spf%%3a%s&run=toolpage", encoded),
-		MXToolboxDMARC: fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=dmarc%%3a%s&run=toolpage", encoded),
-		MXToolboxDKIM:  fmt.Sprintf("https://mxtoolbox.com/SuperTool.aspx?action=dkim%%3a%s&run=toolpage", encoded),
-
-		// Email-specific tools
-		MailTester: fmt.Sprintf("https://www.mail-tester.com/test-%s", emailEncoded),
-		LearnDMARC: fmt.Sprintf("https://www.learndmarc.com/?domain=%s", encoded),
-	}
+	return emailToolLinks(emailOrDomain, domain)
 }
 
 // ============================================================================
@@ -0,0 +1,197 @@
+// Package kdarrow builds Poindexter KD-tree points from Apache Arrow record
+// batches and Parquet files, so large analytical datasets can be indexed
+// without bespoke conversion code. It is a separate module so the core
+// poindexter package keeps zero external dependencies.
+//
+// Each point's Value is its row number in the source, which callers can use to
+// look up the row's other columns.
+package kdarrow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	poindexter "github.com/Snider/Poindexter"
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/apache/arrow/go/v17/parquet"
+	"github.com/apache/arrow/go/v17/parquet/file"
+	"github.com/apache/arrow/go/v17/parquet/pqarrow"
+)
+
+var (
+	// ErrNoColumn indicates a named column is missing from the data.
+	ErrNoColumn = errors.New("kdarrow: no such column")
+	// ErrColumnType indicates a column's type cannot be used: coordinates must
+	// be integer or floating point, IDs must be strings.
+	ErrColumnType = errors.New("kdarrow: unsupported column type")
+)
+
+// Columns names the columns points are built from.
+type Columns struct {
+	// ID names a string column holding point IDs; empty leaves IDs unset.
+	// Null IDs become "".
+	ID string
+	// Coords names the numeric columns holding the coordinates, one per axis
+	// in axis order. Nulls become NaN, the package's marker for a missing
+	// value.
+	Coords []string
+}
+
+// PointsFromRecord builds one point per row of rec. Each point's Value is its
+// row index in rec.
+func PointsFromRecord(rec arrow.Record, cols Columns) ([]poindexter.KDPoint[int64], error) {
+	return pointsFromRecord(rec, cols, 0)
+}
+
+// PointsFromReader builds points from every record r yields. Each point's
+// Value is its row index across the whole stream.
+func PointsFromReader(r array.RecordReader, cols Columns) ([]poindexter.KDPoint[int64], error) {
+	var pts []poindexter.KDPoint[int64]
+	for r.Next() {
+		rec := r.Record()
+		batch, err := pointsFromRecord(rec, cols, int64(len(pts)))
+		if err != nil {
+			return nil, err
+		}
+		pts = append(pts, batch...)
+	}
+	// some readers, such as Parquet's, report the end of the stream as io.EOF
+	if err := r.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return pts, nil
+}
+
+// PointsFromParquet reads the named columns of the Parquet file in r, which
+// must hold flat (non-nested) columns. Each point's Value is its row index in
+// the file.
+func PointsFromParquet(ctx context.Context, r parquet.ReaderAtSeeker, cols Columns) ([]poindexter.KDPoint[int64], error) {
+	pf, err := file.NewParquetReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer pf.Close()
+	names := cols.Coords
+	if cols.ID != "" {
+		names = append([]string{cols.ID}, names...)
+	}
+	schema := pf.MetaData().Schema
+	indices := make([]int, len(names))
+	for i, name := range names {
+		if indices[i] = schema.ColumnIndexByName(name); indices[i] < 0 {
+			return nil, fmt.Errorf("%w: %q", ErrNoColumn, name)
+		}
+	}
+	fr, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{BatchSize: 64 << 10}, memory.DefaultAllocator)
+	if err != nil {
+		return nil, err
+	}
+	rr, err := fr.GetRecordReader(ctx, indices, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rr.Release()
+	return PointsFromReader(rr, cols)
+}
+
+// pointsFromRecord is PointsFromRecord with values numbered from base.
+func pointsFromRecord(rec arrow.Record, cols Columns, base int64) ([]poindexter.KDPoint[int64], error) {
+	n := int(rec.NumRows())
+	coords := make([][]float64, len(cols.Coords))
+	for d, name := range cols.Coords {
+		col, err := column(rec, name)
+		if err != nil {
+			return nil, err
+		}
+		if coords[d], err = floats(col); err != nil {
+			return nil, fmt.Errorf("%w: %q is %s", err, name, col.DataType())
+		}
+	}
+	var ids []string
+	if cols.ID != "" {
+		col, err := column(rec, cols.ID)
+		if err != nil {
+			return nil, err
+		}
+		if ids, err = strings(col); err != nil {
+			return nil, fmt.Errorf("%w: %q is %s", err, cols.ID, col.DataType())
+		}
+	}
+	values := make([]int64, n)
+	for i := range values {
+		values[i] = base + int64(i)
+	}
+	return poindexter.PointsFromColumns(ids, values, coords...)
+}
+
+// column returns the column of rec named name.
+func column(rec arrow.Record, name string) (arrow.Array, error) {
+	idx := rec.Schema().FieldIndices(name)
+	if len(idx) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrNoColumn, name)
+	}
+	return rec.Column(idx[0]), nil
+}
+
+// floats converts a numeric column to float64s, with nulls as NaN.
+func floats(col arrow.Array) ([]float64, error) {
+	var at func(i int) float64
+	switch a := col.(type) {
+	case *array.Float64:
+		at = func(i int) float64 { return a.Value(i) }
+	case *array.Float32:
+		at = func(i int) float64 { return float64(a.Value(i)) }
+	case *array.Int64:
+		at = func(i int) float64 { return float64(a.Value(i)) }
+	case *array.Int32:
+		at = func(i int) float64 { return float64(a.Value(i)) }
+	case *array.Int16:
+		at = func(i int) float64 { return float64(a.Value(i)) }
+	case *array.Int8:
+		at = func(i int) float64 { return float64(a.Value(i)) }
+	case *array.Uint64:
+		at = func(i int) float64 { return float64(a.Value(i)) }
+	case *array.Uint32:
+		at = func(i int) float64 { return float64(a.Value(i)) }
+	case *array.Uint16:
+		at = func(i int) float64 { return float64(a.Value(i)) }
+	case *array.Uint8:
+		at = func(i int) float64 { return float64(a.Value(i)) }
+	default:
+		return nil, ErrColumnType
+	}
+	out := make([]float64, col.Len())
+	for i := range out {
+		if col.IsNull(i) {
+			out[i] = math.NaN()
+			continue
+		}
+		out[i] = at(i)
+	}
+	return out, nil
+}
+
+// strings converts a string column, with nulls as "".
+func strings(col arrow.Array) ([]string, error) {
+	var at func(i int) string
+	switch a := col.(type) {
+	case *array.String:
+		at = a.Value
+	case *array.LargeString:
+		at = a.Value
+	default:
+		return nil, ErrColumnType
+	}
+	out := make([]string, col.Len())
+	for i := range out {
+		if !col.IsNull(i) {
+			out[i] = at(i)
+		}
+	}
+	return out, nil
+}
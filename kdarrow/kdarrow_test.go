@@ -0,0 +1,116 @@
+package kdarrow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	poindexter "github.com/Snider/Poindexter"
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/apache/arrow/go/v17/parquet/pqarrow"
+)
+
+var peerSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "peer", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "ping", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	{Name: "hops", Type: arrow.PrimitiveTypes.Int32},
+}, nil)
+
+// peerRecord builds a record with one row per id; a negative ping is null.
+func peerRecord(t *testing.T, ids []string, pings []float64, hops []int32) arrow.Record {
+	t.Helper()
+	b := array.NewRecordBuilder(memory.DefaultAllocator, peerSchema)
+	defer b.Release()
+	for i, id := range ids {
+		b.Field(0).(*array.StringBuilder).Append(id)
+		if pings[i] < 0 {
+			b.Field(1).AppendNull()
+		} else {
+			b.Field(1).(*array.Float64Builder).Append(pings[i])
+		}
+		b.Field(2).(*array.Int32Builder).Append(hops[i])
+	}
+	rec := b.NewRecord()
+	t.Cleanup(rec.Release)
+	return rec
+}
+
+func TestPointsFromRecord(t *testing.T) {
+	rec := peerRecord(t, []string{"a", "b", "c"}, []float64{10, 55, -1}, []int32{1, 4, 2})
+	pts, err := PointsFromRecord(rec, Columns{ID: "peer", Coords: []string{"ping", "hops"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 3 || pts[1].ID != "b" || pts[1].Coords[0] != 55 || pts[1].Coords[1] != 4 || pts[1].Value != 1 {
+		t.Fatalf("unexpected points %+v", pts)
+	}
+	if !math.IsNaN(pts[2].Coords[0]) {
+		t.Fatalf("null ping should be NaN, got %v", pts[2].Coords[0])
+	}
+	tr, err := poindexter.NewKDTree(pts[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _, _ := tr.Nearest([]float64{12, 1}); p.ID != "a" {
+		t.Fatalf("nearest %q, want a", p.ID)
+	}
+
+	if _, err := PointsFromRecord(rec, Columns{Coords: []string{"ping", "jitter"}}); !errors.Is(err, ErrNoColumn) {
+		t.Fatalf("missing column: got %v", err)
+	}
+	if _, err := PointsFromRecord(rec, Columns{Coords: []string{"peer"}}); !errors.Is(err, ErrColumnType) {
+		t.Fatalf("string coordinate column: got %v", err)
+	}
+	if _, err := PointsFromRecord(rec, Columns{ID: "hops", Coords: []string{"ping"}}); !errors.Is(err, ErrColumnType) {
+		t.Fatalf("numeric ID column: got %v", err)
+	}
+}
+
+func TestPointsFromReader(t *testing.T) {
+	r1 := peerRecord(t, []string{"a", "b"}, []float64{1, 2}, []int32{1, 1})
+	r2 := peerRecord(t, []string{"c"}, []float64{3}, []int32{2})
+	rr, err := array.NewRecordReader(peerSchema, []arrow.Record{r1, r2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rr.Release()
+	pts, err := PointsFromReader(rr, Columns{ID: "peer", Coords: []string{"ping"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 3 || pts[2].ID != "c" || pts[2].Value != 2 {
+		t.Fatalf("rows should be numbered across batches: %+v", pts)
+	}
+}
+
+func TestPointsFromParquet(t *testing.T) {
+	rec := peerRecord(t, []string{"a", "b", "c"}, []float64{10, 55, 30}, []int32{1, 4, 2})
+	var buf bytes.Buffer
+	w, err := pqarrow.NewFileWriter(peerSchema, &buf, nil, pqarrow.DefaultWriterProps())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cols := Columns{ID: "peer", Coords: []string{"hops", "ping"}}
+	pts, err := PointsFromParquet(context.Background(), bytes.NewReader(buf.Bytes()), cols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 3 || pts[2].ID != "c" || pts[2].Coords[0] != 2 || pts[2].Coords[1] != 30 || pts[2].Value != 2 {
+		t.Fatalf("unexpected points %+v", pts)
+	}
+	cols.Coords = []string{"jitter"}
+	if _, err := PointsFromParquet(context.Background(), bytes.NewReader(buf.Bytes()), cols); !errors.Is(err, ErrNoColumn) {
+		t.Fatalf("missing column: got %v", err)
+	}
+}
@@ -1,9 +1,14 @@
 package poindexter
 
 import (
+	"cmp"
 	"errors"
+	"fmt"
 	"math"
+	"slices"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,15 +23,34 @@ var (
 	ErrDuplicateID = errors.New("kdtree: duplicate point ID")
 	// ErrBackendUnavailable indicates that a requested backend cannot be used (e.g., not built/tagged).
 	ErrBackendUnavailable = errors.New("kdtree: requested backend unavailable")
+	// ErrInvalidCoords indicates a point failed the tree's coordinate validator.
+	ErrInvalidCoords = errors.New("kdtree: invalid coordinates")
+	// ErrPeerIDFuncType indicates WithPeerIDFunc was given a function for a different payload type than the tree.
+	ErrPeerIDFuncType = errors.New("kdtree: peer ID func payload type does not match tree")
+	// ErrUnknownMetric indicates a metric that cannot be named for serialization or resolved by name.
+	ErrUnknownMetric = errors.New("kdtree: unknown distance metric")
+	// ErrConcurrentUse is wrapped by the panic raised when WithConcurrencyChecks
+	// detects overlapping mutations, or a mutation overlapping a query.
+	ErrConcurrentUse = errors.New("kdtree: concurrent use of non-thread-safe tree")
 )
 
 // KDPoint represents a point with coordinates and an attached payload/value.
 // ID should be unique within a tree to enable O(1) deletes by ID.
 // Coords must all have the same dimensionality within a given KDTree.
+// Labels are optional metadata that queries can filter on without knowing the
+// payload type (see WithLabelSelector); the tree shares the map, so treat it
+// as read-only once the point is inserted.
 type KDPoint[T any] struct {
 	ID     string
 	Coords []float64
 	Value  T
+	Labels map[string]string
+}
+
+// Neighbor pairs a query result point with its distance from the query.
+type Neighbor[T any] struct {
+	Point    KDPoint[T]
+	Distance float64
 }
 
 // DistanceMetric defines a metric over R^n.
@@ -38,12 +62,7 @@ type DistanceMetric interface {
 type EuclideanDistance struct{}
 
 func (EuclideanDistance) Distance(a, b []float64) float64 {
-	var sum float64
-	for i := range a {
-		d := a[i] - b[i]
-		sum += d * d
-	}
-	return math.Sqrt(sum)
+	return math.Sqrt(sqEuclidean(a, b))
 }
 
 // ManhattanDistance implements the L1 metric.
@@ -88,14 +107,11 @@ func (ChebyshevDistance) Distance(a, b []float64) float64 {
 type CosineDistance struct{}
 
 func (CosineDistance) Distance(a, b []float64) float64 {
-	var dot, na2, nb2 float64
-	for i := range a {
-		ai := a[i]
-		bi := b[i]
-		dot += ai * bi
-		na2 += ai * ai
-		nb2 += bi * bi
-	}
+	return cosineFromSums(cosineSums(a, b))
+}
+
+// cosineFromSums is CosineDistance given a·b, a·a and b·b.
+func cosineFromSums(dot, na2, nb2 float64) float64 {
 	if na2 == 0 && nb2 == 0 {
 		return 0
 	}
@@ -169,12 +185,129 @@ func (wcd WeightedCosineDistance) Distance(a, b []float64) float64 {
 	return d
 }
 
+// Metric names used when serializing a tree.
+const (
+	MetricEuclidean      = "euclidean"
+	MetricManhattan      = "manhattan"
+	MetricChebyshev      = "chebyshev"
+	MetricCosine         = "cosine"
+	MetricWeightedCosine = "weighted_cosine"
+)
+
+// metricName returns the serialization name of a built-in metric and, for
+// WeightedCosineDistance, its weights. Custom metrics yield ErrUnknownMetric.
+func metricName(m DistanceMetric) (string, []float64, error) {
+	switch mm := m.(type) {
+	case EuclideanDistance:
+		return MetricEuclidean, nil, nil
+	case ManhattanDistance:
+		return MetricManhattan, nil, nil
+	case ChebyshevDistance:
+		return MetricChebyshev, nil, nil
+	case CosineDistance:
+		return MetricCosine, nil, nil
+	case WeightedCosineDistance:
+		return MetricWeightedCosine, append([]float64(nil), mm.Weights...), nil
+	default:
+		return "", nil, ErrUnknownMetric
+	}
+}
+
+// serialMetric splits m into what snapshots store: the name and weights of the
+// built-in base metric (see metricName) and, for a PeriodicMetric, its axis
+// periods.
+func serialMetric(m DistanceMetric) (name string, weights, periods []float64, err error) {
+	if pm, ok := m.(PeriodicMetric); ok {
+		m, periods = pm.Base, append([]float64(nil), pm.Periods...)
+		if m == nil {
+			m = EuclideanDistance{}
+		}
+	}
+	name, weights, err = metricName(m)
+	return name, weights, periods, err
+}
+
+// periodicOptions returns the WithPeriodicAxis options restoring periods read
+// from a snapshot; zero entries are axes that do not wrap.
+func periodicOptions(periods []float64) []KDOption {
+	var opts []KDOption
+	for axis, p := range periods {
+		if p != 0 {
+			opts = append(opts, WithPeriodicAxis(axis, p))
+		}
+	}
+	return opts
+}
+
+// metricByName resolves a serialization name back to a metric. An empty name
+// selects EuclideanDistance, the constructor default.
+func metricByName(name string, weights []float64) (DistanceMetric, error) {
+	switch name {
+	case "", MetricEuclidean:
+		return EuclideanDistance{}, nil
+	case MetricManhattan:
+		return ManhattanDistance{}, nil
+	case MetricChebyshev:
+		return ChebyshevDistance{}, nil
+	case MetricCosine:
+		return CosineDistance{}, nil
+	case MetricWeightedCosine:
+		return WeightedCosineDistance{Weights: append([]float64(nil), weights...)}, nil
+	default:
+		return nil, ErrUnknownMetric
+	}
+}
+
 // KDOption configures KDTree construction (non-generic to allow inference).
 type KDOption func(*kdOptions)
 
 type kdOptions struct {
 	metric  DistanceMetric
 	backend KDBackend
+	// noAnalytics and noPeerAnalytics turn off the trackers that are on by
+	// default (WithAnalytics, WithPeerAnalytics).
+	noAnalytics     bool
+	noPeerAnalytics bool
+	peerOpts        []PeerAnalyticsOption
+	// resultDistSample > 0 enables streaming result-distance tracking.
+	resultDistSample int
+	// resultDigest > 0 enables a result-distance t-digest with that compression.
+	resultDigest float64
+	// queryDistSample > 0 enables per-axis query coordinate tracking.
+	queryDistSample int
+	// queryHistory > 0 records that many recent queries (WithQueryHistory).
+	queryHistory int
+	// peerIDFunc holds a func(KDPoint[T]) string; typed at construction.
+	peerIDFunc        any
+	coordValidator    func(coords []float64) error
+	copyOnWrite       bool
+	rebuildPolicy     RebuildPolicy
+	tombstoneRatio    float64
+	leafSize          int
+	concurrencyChecks bool
+	maxPoints         int
+	evictPolicy       EvictionPolicy
+	// pointWeight holds a func(KDPoint[T]) float64; typed at construction.
+	pointWeight any
+	stableTies  bool
+
+	hnswM              int
+	hnswEfConstruction int
+	hnswEfSearch       int
+
+	lshTables int
+	lshBits   int
+	lshProbes int
+
+	periodic []periodicAxis
+
+	tracer QueryTracer
+	hooks  Hooks
+
+	// anomalyAlpha and anomalyK override the latency anomaly detector
+	// defaults when positive.
+	anomalyAlpha float64
+	anomalyK     float64
 }
 
 // defaultBackend returns the implicit backend depending on build tags.
@@ -193,40 +326,293 @@ type KDBackend string
 const (
 	BackendLinear KDBackend = "linear"
 	BackendGonum  KDBackend = "gonum"
+	// BackendVPTree is a vantage-point tree: sub-linear queries under any
+	// metric satisfying the triangle inequality (custom metrics included) and
+	// under CosineDistance/WeightedCosineDistance, which the gonum backend
+	// cannot index. It needs no build tag.
+	BackendVPTree KDBackend = "vptree"
+	// BackendHNSW is an approximate nearest-neighbour graph for large,
+	// high-dimensional workloads (see WithHNSWEfSearch). Nearest and KNearest
+	// may miss true neighbours; Radius stays exact. It needs no build tag.
+	// Building the graph is costly, so pair it with WithRebuildPolicy for
+	// trees that change often.
+	BackendHNSW KDBackend = "hnsw"
+	// BackendLSH is approximate nearest-neighbour search by random-hyperplane
+	// hashing for CosineDistance and WeightedCosineDistance on high-dimensional
+	// (64+) vectors (see WithLSHProbes). Like BackendHNSW, Nearest and
+	// KNearest may miss true neighbours and Radius stays exact; building is
+	// cheap. Other metrics fall back to the linear backend.
+	BackendLSH KDBackend = "lsh"
+	// BackendRTree is an STR-packed R-tree for workloads dominated by
+	// WithinBox queries, e.g. bounding boxes over latitude/longitude. Nearest,
+	// KNearest and Radius stay exact and indexed under Euclidean, Manhattan and
+	// Chebyshev metrics. It needs no build tag.
+	BackendRTree KDBackend = "rtree"
+	// BackendCoverTree is a cover tree: exact, sub-linear queries under the
+	// same metrics as BackendVPTree, with query cost bounded by the data's
+	// intrinsic dimension rather than its coordinate count. It needs no build
+	// tag.
+	BackendCoverTree KDBackend = "covertree"
 )
 
 // WithMetric sets the distance metric for the KDTree.
 func WithMetric(m DistanceMetric) KDOption { return func(o *kdOptions) { o.metric = m } }
 
-// WithBackend selects the internal KDTree backend ("linear" or "gonum").
+// WithBackend selects the internal KDTree backend ("linear", "gonum", "vptree", "hnsw", "lsh", "rtree" or "covertree").
 // Default is linear. If the requested backend is unavailable (e.g., gonum build tag not enabled),
 // the constructor will silently fall back to the linear backend.
 func WithBackend(b KDBackend) KDOption { return func(o *kdOptions) { o.backend = b } }
 
+// WithAnalytics turns the tree's query, insert, delete and rebuild analytics
+// on (the default) or off. Without them queries skip the clock reads and
+// atomic updates, Analytics returns nil, GetAnalyticsSnapshot a zero snapshot,
+// and radius queries no longer pre-size their result buffers.
+func WithAnalytics(enabled bool) KDOption {
+	return func(o *kdOptions) { o.noAnalytics = !enabled }
+}
+
+// WithPeerAnalytics turns per-peer selection tracking on (the default) or off.
+// Without it queries skip the per-result map updates, PeerAnalytics returns
+// nil, GetPeerStats and GetTopPeers return nothing, and the EvictLRU and
+// EvictLeastSelected policies, which see every point as never selected,
+// degrade to EvictOldest.
+func WithPeerAnalytics(enabled bool) KDOption {
+	return func(o *kdOptions) { o.noPeerAnalytics = !enabled }
+}
+
+// WithPeerAnalyticsOptions configures the tree's peer analytics, e.g.
+// WithSelectionHalfLife to favour recent selections.
+func WithPeerAnalyticsOptions(opts ...PeerAnalyticsOption) KDOption {
+	return func(o *kdOptions) { o.peerOpts = append(o.peerOpts, opts...) }
+}
+
+// WithResultDistanceTracking feeds the distances returned by every query
+// (Nearest, KNearest, Radius, RadiusAppend) into a streaming distribution on
+// the tree, available via GetResultDistanceDistribution. sampleSize bounds the
+// memory used for percentile estimates (<= 0 uses DefaultDistributionSampleSize).
+func WithResultDistanceTracking(sampleSize int) KDOption {
+	return func(o *kdOptions) {
+		if sampleSize <= 0 {
+			sampleSize = DefaultDistributionSampleSize
+		}
+		o.resultDistSample = sampleSize
+	}
+}
+
+// WithPeerIDFunc sets how peer analytics derive a peer key from a result
+// point. By default KDPoint.ID is used, which is empty for many builder-generated
+// points; fn can instead derive a stable key from the Value payload. Results for
+// which fn returns "" are not recorded. The payload type must match the tree's,
+// otherwise the constructor returns ErrPeerIDFuncType.
+func WithPeerIDFunc[T any](fn func(KDPoint[T]) string) KDOption {
+	return func(o *kdOptions) { o.peerIDFunc = fn }
+}
+
+// WithCoordValidator installs a check run on the coordinates of every point
+// passed to NewKDTree or Insert, so domain invariants (e.g., all coordinates
+// in [0, weight]) are enforced in one place. NewKDTree returns the first
+// failure wrapped in ErrInvalidCoords together with the point ID; Insert
+// rejects the point, and ValidateCoords reports why.
+func WithCoordValidator(fn func(coords []float64) error) KDOption {
+	return func(o *kdOptions) { o.coordValidator = fn }
+}
+
+// WithCopyOnWrite makes every Insert/DeleteByID/DeleteWhere publish a new
+// frozen version of the point set instead of mutating the one queries read.
+// Nearest, KNearest, Radius, RadiusAppend, Len, Points and Snapshot then never
+// block and are safe to call concurrently with mutations, and mutations are
+// serialized internally, so read-heavy services need no external lock.
+// Each mutation costs an O(n) copy; batch removals with DeleteWhere.
+func WithCopyOnWrite() KDOption {
+	return func(o *kdOptions) { o.copyOnWrite = true }
+}
+
 // KDTree is a lightweight wrapper providing nearest-neighbor operations.
 //
-// Complexity: queries are O(n) linear scans in the current implementation.
-// Inserts are O(1) amortized; deletes by ID are O(1) using swap-delete (order not preserved).
+// Complexity: query cost depends on the backend (see KDBackend). The linear
+// backend scans all n points; the gonum, vptree, covertree and rtree backends
+// answer exact queries in roughly O(log n) on well-spread, low-dimensional
+// data, degrading towards O(n) as the dimension grows; hnsw and lsh trade
+// exactness of Nearest and KNearest for sub-linear cost on large,
+// high-dimensional sets. Methods a backend cannot index (e.g. an unsupported
+// metric) fall back to a linear scan. Inserts are O(1) amortized plus any
+// index maintenance; deletes by ID are O(1) using swap-delete (order not
+// preserved).
 // Concurrency: KDTree is not safe for concurrent mutation. Guard with a mutex or
-// share immutable snapshots for read-mostly workloads.
+// share immutable snapshots (see Snapshot) for read-mostly workloads, or build
+// the tree WithCopyOnWrite so queries never block on writers;
+// WithConcurrencyChecks makes misuse panic instead of corrupting results. Backend
+// index rebuilds are double-buffered: queries keep using the previous index
+// until the rebuilt one is atomically swapped in. By default inserts extend
+// the index in place (rebalancing with an occasional full rebuild), DeleteByID
+// tombstones the point in it (compacting once WithTombstoneRatio is exceeded)
+// and DeleteWhere rebuilds it; see WithRebuildPolicy to batch rebuilds.
 //
 // This type is designed to be easily swappable with gonum.org/v1/gonum/spatial/kdtree
 // in the future without breaking the public API.
 type KDTree[T any] struct {
-	points      []KDPoint[T]
-	dim         int
-	metric      DistanceMetric
-	idIndex     map[string]int
-	backend     KDBackend
-	backendData any // opaque handle for backend-specific structures (e.g., gonum tree)
+	points  []KDPoint[T]
+	coords  coordArena // owns points' coordinates (see kdtree_coords.go)
+	dim     int
+	metric  DistanceMetric
+	idIndex map[string]int
+	backend KDBackend
+
+	// index is the built backend structure queries run against. Rebuilds
+	// construct a fresh index and swap it in atomically, so queries keep using
+	// the previous one until the new one is complete.
+	index     atomic.Pointer[kdIndex[T]]
+	version   atomic.Uint64 // bumped on every mutation of points
+	rebuildMu sync.Mutex    // serializes index rebuilds
+	// rebuildPolicy decides when mutations rebuild the index (WithRebuildPolicy);
+	// unless it rebuilds on every mutation, queries skip a stale index.
+	rebuildPolicy RebuildPolicy
+	pendingMuts   int         // mutations since the last rebuild
+	rebuildTimer  *time.Timer // armed by RebuildAfter, guarded by writeMu
+	// tombstoneRatio bounds the share of deleted points the index may keep
+	// before a delete compacts it (WithTombstoneRatio).
+	tombstoneRatio float64
+	leafSize       int         // gonum backend leaf bucket size (WithLeafSize)
+	hnsw           *hnswParams // BackendHNSW settings, nil for other backends
+	lsh            lshParams   // BackendLSH settings
+
+	// Copy-on-write mode (WithCopyOnWrite): queries read the published view;
+	// mutations edit points under writeMu and then publish a new view. writeMu
+	// also serializes mutations with RebuildAfter's background rebuilds.
+	cow       bool
+	writeMu   sync.Mutex
+	published atomic.Pointer[KDTreeView[T]]
+
+	// Ownership checks (WithConcurrencyChecks): in-flight queries and mutations.
+	concurrencyChecks bool
+	activeReaders     atomic.Int32
+	activeWriters     atomic.Int32
 
 	// Analytics tracking (optional, enabled by default)
 	analytics     *TreeAnalytics
 	peerAnalytics *PeerAnalytics
+	resultDist    *StreamingDistribution   // nil unless WithResultDistanceTracking
+	resultDigest  *TDigest                 // nil unless WithResultDistanceDigest
+	queryDist     []*StreamingDistribution // per axis; nil unless WithQueryDistributionTracking
+	history       *queryHistory            // nil unless WithQueryHistory
+	peerIDFunc    func(KDPoint[T]) string  // nil → KDPoint.ID
+
+	// Importance weights (WithPointWeight); maxWeight bounds them over points.
+	weightOf  func(KDPoint[T]) float64
+	maxWeight float64
+
+	stableTies bool        // break distance ties by ID (WithStableTies)
+	tracer     QueryTracer // nil unless WithQueryTracer
+	hooks      Hooks       // WithHooks
+
+	coordValidator func(coords []float64) error // nil unless WithCoordValidator
+	evict          *evictionState               // nil unless WithMaxPoints
+	ttl            *ttlState                    // nil until InsertWithTTL
+}
+
+// kdIndex is an immutable backend index together with the point set it was
+// built from; backend results are positions into points.
+type kdIndex[T any] struct {
+	data    any // opaque handle for backend-specific structures (e.g., gonum tree)
+	points  []KDPoint[T]
+	version uint64 // tree version the index reflects
+	// tombstones counts points deleted from the tree but still present in
+	// points; the backend never returns them.
+	tombstones int
+}
+
+// indexed reports whether the tree's backend maintains an index.
+func (t *KDTree[T]) indexed() bool {
+	switch t.backend {
+	case BackendGonum, BackendVPTree, BackendHNSW, BackendLSH, BackendRTree, BackendCoverTree:
+		return true
+	}
+	return false
+}
+
+// indexNearest, indexKNearest, indexRadius and indexRadiusEach run a query
+// against whichever backend built the index data.
+func indexNearest[T any](data any, query []float64) (int, float64, bool) {
+	switch b := data.(type) {
+	case *vpBackend:
+		return b.nearest(query)
+	case *hnswBackend:
+		return b.nearest(query)
+	case *lshBackend:
+		return b.nearest(query)
+	case *rtBackend:
+		return b.nearest(query)
+	case *ctBackend:
+		return b.nearest(query)
+	}
+	return gonumNearest[T](data, query)
+}
+
+// indexKNearest leaves out the points for which skip, if not nil, is true.
+func indexKNearest[T any](data any, query []float64, k int, skip func(idx int) bool) ([]int, []float64) {
+	switch b := data.(type) {
+	case *vpBackend:
+		return b.kNearest(query, k, skip)
+	case *hnswBackend:
+		return b.kNearest(query, k, skip)
+	case *lshBackend:
+		return b.kNearest(query, k, skip)
+	case *rtBackend:
+		return b.kNearest(query, k, skip)
+	case *ctBackend:
+		return b.kNearest(query, k, skip)
+	}
+	return gonumKNearest[T](data, query, k, skip)
+}
+
+// indexRadius and indexRadiusEach report no results for HNSW and LSH indexes, so
+// radius queries fall back to an exact linear scan.
+func indexRadius[T any](data any, query []float64, r float64, capHint int) ([]int, []float64) {
+	switch data.(type) {
+	case *vpBackend, *rtBackend, *ctBackend:
+	default:
+		return gonumRadius[T](data, query, r, capHint)
+	}
+	res := make([]knnItem, 0, max(capHint, 0))
+	if !indexRadiusEach[T](data, query, r, func(idx int, dist float64) {
+		res = append(res, knnItem{idx: idx, dist: dist})
+	}) {
+		return nil, nil
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].dist < res[j].dist })
+	idxs := make([]int, len(res))
+	dists := make([]float64, len(res))
+	for i := range res {
+		idxs[i] = res[i].idx
+		dists[i] = res[i].dist
+	}
+	return idxs, dists
+}
+
+func indexRadiusEach[T any](data any, query []float64, r float64, visit func(idx int, dist float64)) bool {
+	switch b := data.(type) {
+	case *vpBackend:
+		return b.radiusEach(query, r, visit)
+	case *rtBackend:
+		return b.radiusEach(query, r, visit)
+	case *ctBackend:
+		return b.radiusEach(query, r, visit)
+	}
+	return gonumRadiusEach[T](data, query, r, visit)
+}
+
+// indexBox runs a WithinBox query against the index data. Only R-tree indexes
+// serve it; it reports false for the others.
+func indexBox(data any, lo, hi []float64, visit func(idx int)) bool {
+	if b, ok := data.(*rtBackend); ok {
+		return b.box(lo, hi, visit)
+	}
+	return false
 }
 
 // NewKDTree builds a KDTree from the given points.
-// All points must have the same dimensionality (>0).
+// All points must have the same dimensionality (>0). Coordinates are copied
+// into one contiguous block the tree owns, so the caller may reuse pts.
 func NewKDTree[T any](pts []KDPoint[T], opts ...KDOption) (*KDTree[T], error) {
 	if len(pts) == 0 {
 		return nil, ErrEmptyPoints
@@ -247,31 +633,87 @@ func NewKDTree[T any](pts []KDPoint[T], opts ...KDOption) (*KDTree[T], error) {
 			idIndex[p.ID] = i
 		}
 	}
-	cfg := kdOptions{metric: EuclideanDistance{}, backend: defaultBackend()}
+	cfg := kdOptions{metric: EuclideanDistance{}, backend: defaultBackend(), tombstoneRatio: defaultTombstoneRatio, lshProbes: defaultLSHProbes, concurrencyChecks: defaultConcurrencyChecks}
 	for _, o := range opts {
 		o(&cfg)
 	}
-	backend := cfg.backend
-	var backendData any
-	// Attempt to build gonum backend if requested and available.
-	if backend == BackendGonum && hasGonum() {
-		if bd, err := buildGonumBackend(pts, cfg.metric); err == nil {
-			backendData = bd
-		} else {
-			backend = BackendLinear // fallback gracefully
+	peerIDFunc, err := resolvePeerIDFunc[T](cfg)
+	if err != nil {
+		return nil, err
+	}
+	weightOf, err := resolvePointWeight[T](cfg)
+	if err != nil {
+		return nil, err
+	}
+	metric, err := periodicMetric(cfg, dim)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEvictPolicy(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.coordValidator != nil {
+		for _, p := range pts {
+			if err := cfg.coordValidator(p.Coords); err != nil {
+				return nil, fmt.Errorf("%w: point %q: %w", ErrInvalidCoords, p.ID, err)
+			}
+		}
+	}
+	if cfg.maxPoints > 0 && len(pts) > cfg.maxPoints {
+		pts = pts[len(pts)-cfg.maxPoints:]
+		clear(idIndex)
+		for i, p := range pts {
+			if p.ID != "" {
+				idIndex[p.ID] = i
+			}
 		}
-	} else if backend == BackendGonum && !hasGonum() {
+	}
+	backend := cfg.backend
+	if backend == BackendGonum && !hasGonum() {
 		backend = BackendLinear // tag not enabled → fallback
 	}
 	t := &KDTree[T]{
 		points:        append([]KDPoint[T](nil), pts...),
 		dim:           dim,
-		metric:        cfg.metric,
+		metric:        metric,
 		idIndex:       idIndex,
 		backend:       backend,
-		backendData:   backendData,
-		analytics:     NewTreeAnalytics(),
-		peerAnalytics: NewPeerAnalytics(),
+		analytics:     newAnalytics(cfg),
+		peerAnalytics: newPeerAnalytics(cfg),
+		resultDist:    newResultDist(cfg),
+		resultDigest:  newResultDigest(cfg),
+		queryDist:     newQueryDist(cfg, dim),
+		history:       newQueryHistory(cfg),
+		peerIDFunc:    peerIDFunc,
+		weightOf:      weightOf,
+		stableTies:    cfg.stableTies,
+		tracer:        cfg.tracer,
+		hooks:         cfg.hooks,
+
+		coordValidator: cfg.coordValidator,
+		evict:          newEvictionState(cfg, pts),
+		rebuildPolicy:  cfg.rebuildPolicy,
+		tombstoneRatio: cfg.tombstoneRatio,
+		leafSize:       cfg.leafSize,
+		hnsw:           hnswParamsFor(backend, cfg),
+		lsh:            newLSHParams(cfg),
+
+		concurrencyChecks: cfg.concurrencyChecks,
+	}
+	t.coords = packCoords(t.points, dim)
+	t.updateMaxWeight()
+	// Attempt to build the index if the backend has one; falls back to linear
+	// gracefully on failure.
+	if t.indexed() {
+		if ix, _, err := t.buildIndex(); err == nil {
+			t.index.Store(ix)
+		} else {
+			t.backend = BackendLinear
+		}
+	}
+	if cfg.copyOnWrite {
+		t.cow = true
+		t.publish()
 	}
 	return t, nil
 }
@@ -282,167 +724,349 @@ func NewKDTreeFromDim[T any](dim int, opts ...KDOption) (*KDTree[T], error) {
 	if dim <= 0 {
 		return nil, ErrZeroDim
 	}
-	cfg := kdOptions{metric: EuclideanDistance{}, backend: defaultBackend()}
+	cfg := kdOptions{metric: EuclideanDistance{}, backend: defaultBackend(), tombstoneRatio: defaultTombstoneRatio, lshProbes: defaultLSHProbes, concurrencyChecks: defaultConcurrencyChecks}
 	for _, o := range opts {
 		o(&cfg)
 	}
+	peerIDFunc, err := resolvePeerIDFunc[T](cfg)
+	if err != nil {
+		return nil, err
+	}
+	weightOf, err := resolvePointWeight[T](cfg)
+	if err != nil {
+		return nil, err
+	}
+	metric, err := periodicMetric(cfg, dim)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEvictPolicy(cfg); err != nil {
+		return nil, err
+	}
 	backend := cfg.backend
 	if backend == BackendGonum && !hasGonum() {
 		backend = BackendLinear
 	}
-	return &KDTree[T]{
+	t := &KDTree[T]{
 		points:        nil,
 		dim:           dim,
-		metric:        cfg.metric,
+		metric:        metric,
 		idIndex:       make(map[string]int),
 		backend:       backend,
-		backendData:   nil,
-		analytics:     NewTreeAnalytics(),
-		peerAnalytics: NewPeerAnalytics(),
-	}, nil
+		analytics:     newAnalytics(cfg),
+		peerAnalytics: newPeerAnalytics(cfg),
+		resultDist:    newResultDist(cfg),
+		resultDigest:  newResultDigest(cfg),
+		queryDist:     newQueryDist(cfg, dim),
+		history:       newQueryHistory(cfg),
+		peerIDFunc:    peerIDFunc,
+		weightOf:      weightOf,
+		stableTies:    cfg.stableTies,
+		tracer:        cfg.tracer,
+		hooks:         cfg.hooks,
+
+		coordValidator: cfg.coordValidator,
+		evict:          newEvictionState[T](cfg, nil),
+		rebuildPolicy:  cfg.rebuildPolicy,
+		tombstoneRatio: cfg.tombstoneRatio,
+		leafSize:       cfg.leafSize,
+		hnsw:           hnswParamsFor(backend, cfg),
+		lsh:            newLSHParams(cfg),
+
+		concurrencyChecks: cfg.concurrencyChecks,
+	}
+	if cfg.copyOnWrite {
+		t.cow = true
+		t.publish()
+	}
+	return t, nil
+}
+
+// newAnalytics returns the tree analytics tracker, unless cfg turns it off.
+func newAnalytics(cfg kdOptions) *TreeAnalytics {
+	if cfg.noAnalytics {
+		return nil
+	}
+	a := NewTreeAnalytics()
+	if cfg.anomalyAlpha > 0 {
+		a.anomaly.alpha = cfg.anomalyAlpha
+	}
+	if cfg.anomalyK > 0 {
+		a.anomaly.k = cfg.anomalyK
+	}
+	a.anomaly.onAnomaly = cfg.hooks.OnLatencyAnomaly
+	return a
+}
+
+// newPeerAnalytics returns the peer analytics tracker, unless cfg turns it off.
+func newPeerAnalytics(cfg kdOptions) *PeerAnalytics {
+	if cfg.noPeerAnalytics {
+		return nil
+	}
+	return NewPeerAnalytics(cfg.peerOpts...)
+}
+
+// newResultDist returns the result-distance tracker requested by cfg, if any.
+func newResultDist(cfg kdOptions) *StreamingDistribution {
+	if cfg.resultDistSample <= 0 {
+		return nil
+	}
+	return NewStreamingDistribution(cfg.resultDistSample)
+}
+
+// resolvePeerIDFunc asserts the WithPeerIDFunc function against the tree's payload type.
+func resolvePeerIDFunc[T any](cfg kdOptions) (func(KDPoint[T]) string, error) {
+	if cfg.peerIDFunc == nil {
+		return nil, nil
+	}
+	fn, ok := cfg.peerIDFunc.(func(KDPoint[T]) string)
+	if !ok {
+		return nil, ErrPeerIDFuncType
+	}
+	return fn, nil
+}
+
+// peerKey returns the analytics key for p.
+func (t *KDTree[T]) peerKey(p KDPoint[T]) string {
+	if t.peerIDFunc != nil {
+		return t.peerIDFunc(p)
+	}
+	return p.ID
 }
 
 // Dim returns the number of dimensions.
 func (t *KDTree[T]) Dim() int { return t.dim }
 
 // Len returns the number of points in the tree.
-func (t *KDTree[T]) Len() int { return len(t.points) }
+func (t *KDTree[T]) Len() int {
+	if v := t.cowView(); v != nil {
+		return v.Len()
+	}
+	return len(t.points)
+}
 
 // Nearest returns the closest point to the query, along with its distance.
-// ok is false if the tree is empty or the query dimensionality does not match Dim().
-func (t *KDTree[T]) Nearest(query []float64) (KDPoint[T], float64, bool) {
+// ok is false if the tree is empty, no point passes the query options (such as
+// WithLabelSelector), or the query dimensionality does not match Dim().
+func (t *KDTree[T]) Nearest(query []float64, opts ...QueryOption) (KDPoint[T], float64, bool) {
+	if v := t.cowView(); v != nil {
+		return v.Nearest(query, opts...)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
 	if len(query) != t.dim || t.Len() == 0 {
 		return KDPoint[T]{}, 0, false
 	}
-	start := time.Now()
+	t.trackQuery(query)
+	start := t.queryStart()
+	end := t.startQuery("Nearest", 1, 0, opts)
+	var found int
 	defer func() {
 		if t.analytics != nil {
 			t.analytics.RecordQuery(time.Since(start).Nanoseconds())
 		}
+		if end != nil {
+			end(found)
+		}
 	}()
 
+	if skip := querySkip[T](opts); skip != nil || t.weightOf != nil || t.stableTies {
+		var h neighborHeap[T]
+		t.search(query, 1, math.Inf(1), skip, &h)
+		if len(h.pts) == 0 {
+			return KDPoint[T]{}, 0, false
+		}
+		t.recordResults(query, h.pts, h.dists)
+		found = 1
+		return h.pts[0], h.dists[0], true
+	}
+
 	// Gonum backend (if available and built)
-	if t.backend == BackendGonum && t.backendData != nil {
-		if idx, dist, ok := gonumNearest[T](t.backendData, query); ok && idx >= 0 && idx < len(t.points) {
-			p := t.points[idx]
+	if ix := t.queryIndex(); ix != nil {
+		if idx, dist, ok := indexNearest[T](ix.data, query); ok && idx >= 0 && idx < len(ix.points) {
+			p := ix.points[idx]
 			if t.peerAnalytics != nil {
-				t.peerAnalytics.RecordSelection(p.ID, dist)
+				t.peerAnalytics.RecordSelection(t.peerKey(p), dist)
 			}
+			t.recordResultDist(dist)
+			t.recordBest(dist)
+			t.recordQuery(query, 1, func(int) KDPoint[T] { return p })
+			found = 1
 			return p, dist, true
 		}
 		// fall through to linear scan if backend didn't return a result
 	}
 	bestIdx := -1
 	bestDist := math.MaxFloat64
-	for i := range t.points {
-		d := t.metric.Distance(query, t.points[i].Coords)
+	scanDistances(t.metric, query, t.points, func(i int, d float64) {
 		if d < bestDist {
 			bestDist = d
 			bestIdx = i
 		}
-	}
+	})
 	if bestIdx < 0 {
 		return KDPoint[T]{}, 0, false
 	}
 	p := t.points[bestIdx]
 	if t.peerAnalytics != nil {
-		t.peerAnalytics.RecordSelection(p.ID, bestDist)
+		t.peerAnalytics.RecordSelection(t.peerKey(p), bestDist)
 	}
+	t.recordResultDist(bestDist)
+	t.recordBest(bestDist)
+	t.recordQuery(query, 1, func(int) KDPoint[T] { return p })
+	found = 1
 	return p, bestDist, true
 }
 
 // KNearest returns up to k nearest neighbors to the query in ascending distance order.
-// If multiple points are at the same distance, tie ordering is arbitrary and not stable between calls.
-func (t *KDTree[T]) KNearest(query []float64, k int) ([]KDPoint[T], []float64) {
+// If multiple points are at the same distance, tie ordering is arbitrary and not stable between calls,
+// unless the tree was built WithStableTies. Query options such as
+// WithLabelSelector restrict the points considered.
+func (t *KDTree[T]) KNearest(query []float64, k int, opts ...QueryOption) ([]KDPoint[T], []float64) {
+	if v := t.cowView(); v != nil {
+		return v.KNearest(query, k, opts...)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
 	if k <= 0 || len(query) != t.dim || t.Len() == 0 {
 		return nil, nil
 	}
-	start := time.Now()
+	t.trackQuery(query)
+	start := t.queryStart()
+	end := t.startQuery("KNearest", k, 0, opts)
+	var found int
 	defer func() {
 		if t.analytics != nil {
 			t.analytics.RecordQuery(time.Since(start).Nanoseconds())
 		}
+		if end != nil {
+			end(found)
+		}
 	}()
 
+	if skip := querySkip[T](opts); skip != nil || t.weightOf != nil || t.stableTies {
+		h := neighborHeap[T]{pts: make([]KDPoint[T], 0, min(k, len(t.points))), dists: make([]float64, 0, min(k, len(t.points)))}
+		t.search(query, k, math.Inf(1), skip, &h)
+		t.recordResults(query, h.pts, h.dists)
+		found = len(h.pts)
+		return h.pts, h.dists
+	}
+
 	// Gonum backend path
-	if t.backend == BackendGonum && t.backendData != nil {
-		idxs, dists := gonumKNearest[T](t.backendData, query, k)
+	if ix := t.queryIndex(); ix != nil {
+		idxs, dists := indexKNearest[T](ix.data, query, k, nil)
 		if len(idxs) > 0 {
 			neighbors := make([]KDPoint[T], len(idxs))
 			for i := range idxs {
-				neighbors[i] = t.points[idxs[i]]
+				neighbors[i] = ix.points[idxs[i]]
 				if t.peerAnalytics != nil {
-					t.peerAnalytics.RecordSelection(neighbors[i].ID, dists[i])
+					t.peerAnalytics.RecordSelection(t.peerKey(neighbors[i]), dists[i])
 				}
 			}
+			t.recordResultDists(dists)
+			t.recordBest(dists[0])
+			t.recordQuery(query, len(neighbors), func(i int) KDPoint[T] { return neighbors[i] })
+			found = len(neighbors)
 			return neighbors, dists
 		}
 		// fall back on unexpected empty
 	}
-	tmp := make([]struct {
-		idx  int
-		dist float64
-	}, len(t.points))
-	for i := range t.points {
-		tmp[i].idx = i
-		tmp[i].dist = t.metric.Distance(query, t.points[i].Coords)
-	}
-	sort.Slice(tmp, func(i, j int) bool { return tmp[i].dist < tmp[j].dist })
-	if k > len(tmp) {
-		k = len(tmp)
-	}
+	h := make(knnHeap, 0, min(k, len(t.points)))
+	scanDistances(t.metric, query, t.points, func(i int, d float64) {
+		if len(h) < k {
+			h.push(knnItem{idx: i, dist: d})
+		} else if d < h.peek().dist {
+			h[0] = knnItem{idx: i, dist: d}
+			h.down(0)
+		}
+	})
+	k = len(h)
 	neighbors := make([]KDPoint[T], k)
 	dists := make([]float64, k)
-	for i := 0; i < k; i++ {
-		neighbors[i] = t.points[tmp[i].idx]
-		dists[i] = tmp[i].dist
+	for i := k - 1; i >= 0; i-- {
+		it := h.pop()
+		neighbors[i] = t.points[it.idx]
+		dists[i] = it.dist
 		if t.peerAnalytics != nil {
-			t.peerAnalytics.RecordSelection(neighbors[i].ID, dists[i])
+			t.peerAnalytics.RecordSelection(t.peerKey(neighbors[i]), dists[i])
 		}
 	}
+	t.recordResultDists(dists)
+	if len(dists) > 0 {
+		t.recordBest(dists[0])
+	}
+	t.recordQuery(query, len(neighbors), func(i int) KDPoint[T] { return neighbors[i] })
+	found = len(neighbors)
 	return neighbors, dists
 }
 
 // Radius returns points within radius r (inclusive) from the query, sorted by distance.
-func (t *KDTree[T]) Radius(query []float64, r float64) ([]KDPoint[T], []float64) {
+// Query options such as WithLabelSelector restrict the points considered.
+func (t *KDTree[T]) Radius(query []float64, r float64, opts ...QueryOption) ([]KDPoint[T], []float64) {
+	if v := t.cowView(); v != nil {
+		return v.Radius(query, r, opts...)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
 	if r < 0 || len(query) != t.dim || t.Len() == 0 {
 		return nil, nil
 	}
-	start := time.Now()
+	t.trackQuery(query)
+	start := t.queryStart()
+	end := t.startQuery("Radius", 0, r, opts)
+	var found int
 	defer func() {
 		if t.analytics != nil {
 			t.analytics.RecordQuery(time.Since(start).Nanoseconds())
+			t.analytics.RecordRadiusResults(found)
+		}
+		if end != nil {
+			end(found)
 		}
 	}()
 
+	if skip := querySkip[T](opts); skip != nil || t.weightOf != nil || t.stableTies {
+		var h neighborHeap[T]
+		t.search(query, math.MaxInt, r, skip, &h)
+		t.recordResults(query, h.pts, h.dists)
+		found = len(h.pts)
+		return h.pts, h.dists
+	}
+
 	// Gonum backend path
-	if t.backend == BackendGonum && t.backendData != nil {
-		idxs, dists := gonumRadius[T](t.backendData, query, r)
+	if ix := t.queryIndex(); ix != nil {
+		idxs, dists := indexRadius[T](ix.data, query, r, t.radiusCapHint())
 		if len(idxs) > 0 {
 			neighbors := make([]KDPoint[T], len(idxs))
 			for i := range idxs {
-				neighbors[i] = t.points[idxs[i]]
+				neighbors[i] = ix.points[idxs[i]]
 				if t.peerAnalytics != nil {
-					t.peerAnalytics.RecordSelection(neighbors[i].ID, dists[i])
+					t.peerAnalytics.RecordSelection(t.peerKey(neighbors[i]), dists[i])
 				}
 			}
+			t.recordResultDists(dists)
+			t.recordBest(dists[0])
+			t.recordQuery(query, len(neighbors), func(i int) KDPoint[T] { return neighbors[i] })
+			found = len(idxs)
 			return neighbors, dists
 		}
 		// fall back if no results
 	}
-	var sel []struct {
+	sel := make([]struct {
 		idx  int
 		dist float64
-	}
-	for i := range t.points {
-		d := t.metric.Distance(query, t.points[i].Coords)
+	}, 0, t.radiusCapHint())
+	scanDistances(t.metric, query, t.points, func(i int, d float64) {
 		if d <= r {
 			sel = append(sel, struct {
 				idx  int
 				dist float64
 			}{i, d})
 		}
-	}
+	})
 	sort.Slice(sel, func(i, j int) bool { return sel[i].dist < sel[j].dist })
 	neighbors := make([]KDPoint[T], len(sel))
 	dists := make([]float64, len(sel))
@@ -450,23 +1074,188 @@ func (t *KDTree[T]) Radius(query []float64, r float64) ([]KDPoint[T], []float64)
 		neighbors[i] = t.points[sel[i].idx]
 		dists[i] = sel[i].dist
 		if t.peerAnalytics != nil {
-			t.peerAnalytics.RecordSelection(neighbors[i].ID, dists[i])
+			t.peerAnalytics.RecordSelection(t.peerKey(neighbors[i]), dists[i])
 		}
 	}
+	t.recordResultDists(dists)
+	if len(dists) > 0 {
+		t.recordBest(dists[0])
+	}
+	t.recordQuery(query, len(neighbors), func(i int) KDPoint[T] { return neighbors[i] })
+	found = len(sel)
 	return neighbors, dists
 }
 
-// Insert adds a point. Returns false if dimensionality mismatch or duplicate ID exists.
+// RadiusAppend is like Radius but appends results to dst and returns the
+// extended slice, so callers can reuse a buffer across queries and avoid
+// allocating in steady state. Only the appended tail is sorted by distance;
+// existing elements of dst are left untouched. Pass dst[:0] to reuse storage.
+func (t *KDTree[T]) RadiusAppend(query []float64, r float64, dst []Neighbor[T]) []Neighbor[T] {
+	if v := t.cowView(); v != nil {
+		return v.RadiusAppend(query, r, dst)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	if r < 0 || len(query) != t.dim || t.Len() == 0 {
+		return dst
+	}
+	t.trackQuery(query)
+	start := t.queryStart()
+	base := len(dst)
+	defer func() {
+		if t.analytics != nil {
+			t.analytics.RecordQuery(time.Since(start).Nanoseconds())
+			t.analytics.RecordRadiusResults(len(dst) - base)
+		}
+	}()
+
+	if t.weightOf != nil || t.stableTies {
+		var h neighborHeap[T]
+		t.search(query, math.MaxInt, r, nil, &h)
+		for i := range h.pts {
+			dst = append(dst, Neighbor[T]{Point: h.pts[i], Distance: h.dists[i]})
+		}
+		t.recordResults(query, h.pts, h.dists)
+		return dst
+	}
+
+	served := false
+	if ix := t.queryIndex(); ix != nil {
+		served = indexRadiusEach[T](ix.data, query, r, func(idx int, dist float64) {
+			dst = append(dst, Neighbor[T]{Point: ix.points[idx], Distance: dist})
+		})
+	}
+	if !served {
+		scanDistances(t.metric, query, t.points, func(i int, d float64) {
+			if d <= r {
+				dst = append(dst, Neighbor[T]{Point: t.points[i], Distance: d})
+			}
+		})
+	}
+	slices.SortFunc(dst[base:], func(a, b Neighbor[T]) int { return cmp.Compare(a.Distance, b.Distance) })
+	if t.peerAnalytics != nil || t.resultDist != nil || t.resultDigest != nil {
+		for _, n := range dst[base:] {
+			if t.peerAnalytics != nil {
+				t.peerAnalytics.RecordSelection(t.peerKey(n.Point), n.Distance)
+			}
+			t.recordResultDist(n.Distance)
+		}
+	}
+	if len(dst) > base {
+		t.recordBest(dst[base].Distance)
+	}
+	t.recordQuery(query, len(dst)-base, func(i int) KDPoint[T] { return dst[base+i].Point })
+	return dst
+}
+
+// WithinBox returns the points inside the axis-aligned box [lo, hi] (bounds
+// inclusive), in the order Points returns them. If lo[i] > hi[i] the box wraps
+// on axis i and covers coordinates >= lo[i] or <= hi[i], e.g. a longitude
+// range crossing the antimeridian. It returns nil if lo or hi does not match
+// Dim(). BackendRTree indexes these queries; other backends scan every point.
+func (t *KDTree[T]) WithinBox(lo, hi []float64) []KDPoint[T] {
+	if v := t.cowView(); v != nil {
+		return v.WithinBox(lo, hi)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	if len(lo) != t.dim || len(hi) != t.dim || t.Len() == 0 {
+		return nil
+	}
+	start := t.queryStart()
+	defer func() {
+		if t.analytics != nil {
+			t.analytics.RecordQuery(time.Since(start).Nanoseconds())
+		}
+	}()
+
+	if ix := t.queryIndex(); ix != nil {
+		var idxs []int
+		if indexBox(ix.data, lo, hi, func(idx int) { idxs = append(idxs, idx) }) {
+			sort.Ints(idxs)
+			var out []KDPoint[T]
+			for _, idx := range idxs {
+				out = append(out, ix.points[idx])
+			}
+			return out
+		}
+	}
+	var out []KDPoint[T]
+	for _, p := range t.points {
+		if inBox(p.Coords, lo, hi) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// queryStart returns the time a query starts, for analytics, or the zero
+// time if the tree has none.
+func (t *KDTree[T]) queryStart() time.Time {
+	if t.analytics == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// radiusCapHint returns an initial capacity for radius result buffers based on
+// the average result size observed so far, bounded by the number of points.
+func (t *KDTree[T]) radiusCapHint() int {
+	if t.analytics == nil {
+		return 0
+	}
+	avg := int(math.Ceil(t.analytics.AvgRadiusResults()))
+	if avg > len(t.points) {
+		avg = len(t.points)
+	}
+	return avg
+}
+
+// Insert adds a point. Returns false if dimensionality mismatch, duplicate ID exists,
+// or the coordinates fail the tree's validator (see ValidateCoords). The
+// coordinates are copied into the tree's own storage.
 func (t *KDTree[T]) Insert(p KDPoint[T]) bool {
-	if len(p.Coords) != t.dim {
+	return t.insert(p, time.Time{})
+}
+
+// insert is Insert for a point that expires at deadline, if non-zero.
+func (t *KDTree[T]) insert(p KDPoint[T], deadline time.Time) bool {
+	if t.ValidateCoords(p.Coords) != nil {
 		return false
 	}
+	if t.serialWrites() {
+		t.writeMu.Lock()
+		defer t.writeMu.Unlock()
+	}
+	if t.checked() {
+		t.beginWrite()
+		defer t.endWrite()
+	}
+	swept := t.sweepExpired(time.Now()) > 0
 	if p.ID != "" {
 		if _, exists := t.idIndex[p.ID]; exists {
+			if swept && t.cow {
+				t.publish() // the sweep changed the tree even though the insert did not
+			}
 			return false
 		}
 		// will set after append
 	}
+	if t.evict != nil {
+		if len(t.points) >= t.evict.max {
+			t.evictVictim()
+		}
+		t.evict.added(p.ID)
+	}
+	if !deadline.IsZero() {
+		t.setDeadline(p.ID, deadline)
+	}
+	if t.weightOf != nil {
+		t.maxWeight = max(t.maxWeight, t.weight(p))
+	}
+	p.Coords = t.coords.store(p.Coords, len(t.points))
 	t.points = append(t.points, p)
 	if p.ID != "" {
 		t.idIndex[p.ID] = len(t.points) - 1
@@ -475,31 +1264,60 @@ func (t *KDTree[T]) Insert(p KDPoint[T]) bool {
 	if t.analytics != nil {
 		t.analytics.RecordInsert()
 	}
-	// Rebuild backend if using Gonum
-	if t.backend == BackendGonum && hasGonum() {
-		if bd, err := buildGonumBackend(t.points, t.metric); err == nil {
-			t.backendData = bd
-			if t.analytics != nil {
-				t.analytics.RecordRebuild()
-			}
-		} else {
-			// fallback to linear if rebuild fails
-			t.backend = BackendLinear
-			t.backendData = nil
-		}
+	if t.hooks.OnInsert != nil {
+		t.hooks.OnInsert(p.ID)
+	}
+	t.version.Add(1)
+	t.indexAfterInsert()
+	if t.cow {
+		t.publish()
 	}
 	return true
 }
 
+// ValidateCoords reports whether coords could be inserted into the tree:
+// ErrDimMismatch if the dimensionality differs from Dim(), or the validator's
+// error wrapped in ErrInvalidCoords (see WithCoordValidator).
+func (t *KDTree[T]) ValidateCoords(coords []float64) error {
+	if len(coords) != t.dim {
+		return ErrDimMismatch
+	}
+	if t.coordValidator != nil {
+		if err := t.coordValidator(coords); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidCoords, err)
+		}
+	}
+	return nil
+}
+
 // DeleteByID removes a point by its ID. Returns false if not found or ID empty.
 func (t *KDTree[T]) DeleteByID(id string) bool {
 	if id == "" {
 		return false
 	}
+	if t.serialWrites() {
+		t.writeMu.Lock()
+		defer t.writeMu.Unlock()
+	}
+	if t.checked() {
+		t.beginWrite()
+		defer t.endWrite()
+	}
 	idx, ok := t.idIndex[id]
 	if !ok {
 		return false
 	}
+	t.removeAt(idx)
+	if t.cow {
+		t.publish()
+	}
+	return true
+}
+
+// removeAt swap-deletes the point at idx and updates the index. The caller
+// holds whatever locks the mutation needs and publishes afterwards.
+func (t *KDTree[T]) removeAt(idx int) {
+	removed := t.points[idx]
 	last := len(t.points) - 1
 	// swap delete
 	t.points[idx] = t.points[last]
@@ -507,25 +1325,216 @@ func (t *KDTree[T]) DeleteByID(id string) bool {
 		t.idIndex[t.points[idx].ID] = idx
 	}
 	t.points = t.points[:last]
-	delete(t.idIndex, id)
+	if removed.ID != "" {
+		delete(t.idIndex, removed.ID)
+	}
+	if t.evict != nil {
+		t.evict.removed(removed.ID)
+	}
+	t.forgetDeadline(removed.ID)
+	t.releaseCoords(1)
 	// Record delete in analytics
 	if t.analytics != nil {
 		t.analytics.RecordDelete()
 	}
-	// Rebuild backend if using Gonum
-	if t.backend == BackendGonum && hasGonum() {
-		if bd, err := buildGonumBackend(t.points, t.metric); err == nil {
-			t.backendData = bd
-			if t.analytics != nil {
-				t.analytics.RecordRebuild()
+	if t.hooks.OnDelete != nil {
+		t.hooks.OnDelete(removed.ID)
+	}
+	t.version.Add(1)
+	t.indexAfterDelete(removed)
+}
+
+// DeleteWhere removes every point for which pred returns true and reports how
+// many were removed. Unlike repeated DeleteByID calls, the backend index is
+// rebuilt at most once for the whole batch. Remaining points keep their relative order.
+func (t *KDTree[T]) DeleteWhere(pred func(KDPoint[T]) bool) int {
+	return t.deleteBatch(func(_ int, p KDPoint[T]) bool { return pred(p) })
+}
+
+// deleteBatch compacts t.points, dropping those for which remove(i, p) is
+// true (i is the position before compaction), then rebuilds at most once.
+func (t *KDTree[T]) deleteBatch(remove func(i int, p KDPoint[T]) bool) int {
+	return t.deleteChosen(func([]KDPoint[T]) func(int, KDPoint[T]) bool { return remove })
+}
+
+// deleteChosen is deleteBatch for callers whose choice depends on the whole
+// point set: choose runs under the write locks, sees the points positions
+// refer to, and returns the remove predicate (nil removes nothing).
+func (t *KDTree[T]) deleteChosen(choose func(points []KDPoint[T]) func(i int, p KDPoint[T]) bool) int {
+	if t.serialWrites() {
+		t.writeMu.Lock()
+		defer t.writeMu.Unlock()
+	}
+	if t.checked() {
+		t.beginWrite()
+		defer t.endWrite()
+	}
+	remove := choose(t.points)
+	if remove == nil {
+		return 0
+	}
+	removed := t.compact(remove)
+	if removed > 0 && t.cow {
+		t.publish()
+	}
+	return removed
+}
+
+// compact is deleteBatch for a caller that holds the write locks and
+// publishes afterwards.
+func (t *KDTree[T]) compact(remove func(i int, p KDPoint[T]) bool) int {
+	kept := t.points[:0]
+	removed := 0
+	for i, p := range t.points {
+		if remove(i, p) {
+			if p.ID != "" {
+				delete(t.idIndex, p.ID)
 			}
-		} else {
-			// fallback to linear if rebuild fails
-			t.backend = BackendLinear
-			t.backendData = nil
+			if t.hooks.OnDelete != nil {
+				t.hooks.OnDelete(p.ID)
+			}
+			if t.evict != nil {
+				t.evict.removed(p.ID)
+			}
+			t.forgetDeadline(p.ID)
+			removed++
+			continue
+		}
+		if p.ID != "" {
+			t.idIndex[p.ID] = len(kept)
 		}
+		kept = append(kept, p)
+	}
+	if removed == 0 {
+		return 0
+	}
+	clear(t.points[len(kept):]) // release payloads held by the tail
+	t.points = kept
+	t.releaseCoords(removed)
+	t.updateMaxWeight()
+	if t.analytics != nil {
+		for i := 0; i < removed; i++ {
+			t.analytics.RecordDelete()
+		}
+	}
+	t.version.Add(1)
+	t.indexAfterMutation(removed)
+	return removed
+}
+
+// adopt replaces t's state with nt's (used when decoding into an existing
+// tree). Fields are copied individually because KDTree holds atomics and a
+// mutex that must not be copied. A pending RebuildAfter rebuild is dropped,
+// since nt's index is current, and a copy-on-write tree republishes its view.
+func (t *KDTree[T]) adopt(nt *KDTree[T]) {
+	if t.serialWrites() {
+		t.writeMu.Lock()
+		defer t.writeMu.Unlock()
+	}
+	if t.rebuildTimer != nil {
+		t.rebuildTimer.Stop()
+		t.rebuildTimer = nil
+	}
+	t.pendingMuts = 0
+	t.adoptState(nt)
+	if t.cow {
+		t.publish()
+	}
+}
+
+// adoptState copies nt's fields into t under rebuildMu.
+func (t *KDTree[T]) adoptState(nt *KDTree[T]) {
+	t.rebuildMu.Lock()
+	defer t.rebuildMu.Unlock()
+	t.points = nt.points
+	t.coords = nt.coords
+	t.dim = nt.dim
+	t.metric = nt.metric
+	t.idIndex = nt.idIndex
+	t.backend = nt.backend
+	t.index.Store(nt.index.Load())
+	t.version.Store(nt.version.Load())
+	t.analytics = nt.analytics
+	t.peerAnalytics = nt.peerAnalytics
+	t.resultDist = nt.resultDist
+	t.resultDigest = nt.resultDigest
+	t.queryDist = nt.queryDist
+	t.history = nt.history
+	t.peerIDFunc = nt.peerIDFunc
+	t.weightOf = nt.weightOf
+	t.maxWeight = nt.maxWeight
+	t.stableTies = nt.stableTies
+	t.tracer = nt.tracer
+	t.hooks = nt.hooks
+	t.coordValidator = nt.coordValidator
+	t.evict = nt.evict
+	t.ttl = nt.ttl
+	t.rebuildPolicy = nt.rebuildPolicy
+	t.tombstoneRatio = nt.tombstoneRatio
+	t.leafSize = nt.leafSize
+	t.hnsw = nt.hnsw
+	t.lsh = nt.lsh
+	t.concurrencyChecks = nt.concurrencyChecks
+}
+
+// buildIndex builds a backend index over a private copy of the current points.
+// It does not publish the index; the copy keeps the index valid while later
+// mutations rearrange t.points. Returns the build duration.
+func (t *KDTree[T]) buildIndex() (*kdIndex[T], time.Duration, error) {
+	start := time.Now()
+	pts := append([]KDPoint[T](nil), t.points...)
+	ver := t.version.Load()
+	build := func(pts []KDPoint[T], metric DistanceMetric) (any, error) {
+		return buildGonumBackend(pts, metric, t.leafSize)
+	}
+	switch t.backend {
+	case BackendVPTree:
+		build = buildVPBackend[T]
+	case BackendHNSW:
+		build = func(pts []KDPoint[T], metric DistanceMetric) (any, error) {
+			return buildHNSWBackend(pts, metric, t.hnsw)
+		}
+	case BackendLSH:
+		build = func(pts []KDPoint[T], metric DistanceMetric) (any, error) {
+			return buildLSHBackend(pts, metric, t.lsh)
+		}
+	case BackendRTree:
+		build = buildRTreeBackend[T]
+	case BackendCoverTree:
+		build = buildCoverTreeBackend[T]
+	}
+	bd, err := build(pts, t.metric)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &kdIndex[T]{data: bd, points: pts, version: ver}, time.Since(start), nil
+}
+
+// rebuildIndex rebuilds the backend index and atomically swaps it in. Queries
+// running concurrently keep using the previous index until the swap. An index
+// older than the one already published is discarded. If the build fails the
+// tree falls back to the linear backend.
+func (t *KDTree[T]) rebuildIndex() {
+	t.rebuildMu.Lock()
+	defer t.rebuildMu.Unlock()
+	t.pendingMuts = 0
+	ix, took, err := t.buildIndex()
+	if err != nil {
+		// fallback to linear if rebuild fails
+		t.backend = BackendLinear
+		t.index.Store(nil)
+		return
+	}
+	if cur := t.index.Load(); cur != nil && cur.version > ix.version {
+		return
+	}
+	t.index.Store(ix)
+	if t.analytics != nil {
+		t.analytics.RecordRebuildDuration(took.Nanoseconds())
+	}
+	if t.hooks.OnRebuild != nil {
+		t.hooks.OnRebuild(len(ix.points), took)
 	}
-	return true
 }
 
 // Analytics returns the tree analytics tracker.
@@ -569,6 +1578,17 @@ func (t *KDTree[T]) ComputeDistanceDistribution(axisNames []string) []AxisDistri
 	return ComputeAxisDistributions(t.points, axisNames)
 }
 
+// GetResultDistanceDistribution returns statistics over the distances returned
+// by queries since construction (or the last ResetAnalytics). Unlike
+// ComputeDistanceDistribution, this reflects real query behaviour. Returns a
+// zero DistributionStats unless WithResultDistanceTracking was set.
+func (t *KDTree[T]) GetResultDistanceDistribution() DistributionStats {
+	if t.resultDist == nil {
+		return DistributionStats{}
+	}
+	return t.resultDist.Stats()
+}
+
 // ResetAnalytics clears all analytics data.
 func (t *KDTree[T]) ResetAnalytics() {
 	if t.analytics != nil {
@@ -577,16 +1597,127 @@ func (t *KDTree[T]) ResetAnalytics() {
 	if t.peerAnalytics != nil {
 		t.peerAnalytics.Reset()
 	}
+	if t.resultDist != nil {
+		t.resultDist.Reset()
+	}
+	if t.resultDigest != nil {
+		t.resultDigest.Reset()
+	}
+	for _, d := range t.queryDist {
+		d.Reset()
+	}
+	if t.history != nil {
+		t.history.reset()
+	}
 }
 
 // Points returns a copy of all points in the tree.
 // This is useful for analytics and export operations.
 func (t *KDTree[T]) Points() []KDPoint[T] {
+	if v := t.cowView(); v != nil {
+		return v.Points()
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
 	result := make([]KDPoint[T], len(t.points))
 	copy(result, t.points)
 	return result
 }
 
+// Clone returns an independent copy of the tree: points (including their
+// coordinate slices), the ID index and configuration are copied, so a worker
+// can query a stable copy while the original keeps mutating. Point values are
+// copied shallowly. With the gonum backend the clone builds its own index over
+// the copied points. If resetAnalytics is true the clone starts with fresh
+// analytics; otherwise it inherits a copy of the current counters.
+func (t *KDTree[T]) Clone(resetAnalytics bool) *KDTree[T] {
+	if t.serialWrites() {
+		t.writeMu.Lock()
+		defer t.writeMu.Unlock()
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	pts := make([]KDPoint[T], len(t.points))
+	flat := make([]float64, len(t.points)*t.dim)
+	for i, p := range t.points {
+		p.Coords = append(flat[i*t.dim:i*t.dim:(i+1)*t.dim], p.Coords...)
+		pts[i] = p
+	}
+	idIndex := make(map[string]int, len(t.idIndex))
+	for id, i := range t.idIndex {
+		idIndex[id] = i
+	}
+	c := &KDTree[T]{
+		points:         pts,
+		coords:         coordArena{block: flat},
+		dim:            t.dim,
+		metric:         t.metric,
+		idIndex:        idIndex,
+		backend:        t.backend,
+		peerIDFunc:     t.peerIDFunc,
+		weightOf:       t.weightOf,
+		maxWeight:      t.maxWeight,
+		stableTies:     t.stableTies,
+		tracer:         t.tracer,
+		hooks:          t.hooks,
+		coordValidator: t.coordValidator,
+		evict:          t.evict.clone(),
+		ttl:            t.ttl.clone(),
+		rebuildPolicy:  t.rebuildPolicy,
+		tombstoneRatio: t.tombstoneRatio,
+		leafSize:       t.leafSize,
+		hnsw:           t.hnsw.clone(),
+		lsh:            t.lsh,
+
+		concurrencyChecks: t.concurrencyChecks,
+	}
+	c.version.Store(t.version.Load())
+	if c.indexed() {
+		if ix, _, err := c.buildIndex(); err == nil {
+			c.index.Store(ix)
+		} else {
+			c.backend = BackendLinear
+		}
+	}
+	switch {
+	case t.analytics == nil:
+	case resetAnalytics:
+		c.analytics = t.analytics.fresh()
+	default:
+		c.analytics = t.analytics.clone()
+	}
+	switch {
+	case t.peerAnalytics == nil:
+	case resetAnalytics:
+		c.peerAnalytics = t.peerAnalytics.fresh()
+	default:
+		c.peerAnalytics = t.peerAnalytics.clone()
+	}
+	if t.resultDist != nil {
+		c.resultDist = t.resultDist.clone(resetAnalytics)
+	}
+	if t.resultDigest != nil {
+		c.resultDigest = t.resultDigest.clone(resetAnalytics)
+	}
+	if t.queryDist != nil {
+		c.queryDist = make([]*StreamingDistribution, len(t.queryDist))
+		for i, d := range t.queryDist {
+			c.queryDist[i] = d.clone(resetAnalytics)
+		}
+	}
+	if t.history != nil {
+		c.history = t.history.clone(resetAnalytics)
+	}
+	if t.cow {
+		c.cow = true
+		c.publish()
+	}
+	c.armTTLSweep()
+	return c
+}
+
 // Backend returns the active backend type.
 func (t *KDTree[T]) Backend() KDBackend {
 	return t.backend
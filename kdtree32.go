@@ -0,0 +1,239 @@
+package poindexter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// KDTree32 is a memory-lean sibling of KDTree that stores coordinates as
+// float32 in one contiguous block, halving coordinate memory for large trees:
+// a 5M-point, 768-dimension embedding set holds about 31 GB of coordinates in
+// a KDTree and 15 GB in a KDTree32. The API still speaks float64: KDPoint
+// coordinates are rounded to float32 on the way in, and queries, distances
+// and returned points are float64, widened at the boundary. Coordinates keep
+// about seven significant digits; values beyond the float32 range become ±Inf.
+//
+// KDTree32 answers every query with a linear scan, so it suits trees whose
+// size is bound by memory rather than query rate. It honours WithMetric,
+// WithPeriodicAxis and WithCoordValidator; backend, analytics and the other
+// KDTree options are ignored. Like KDTree, it is safe for concurrent queries
+// but mutations must not run concurrently with anything else.
+type KDTree32[T any] struct {
+	dim       int
+	metric    DistanceMetric
+	validator func(coords []float64) error
+	coords    []float32 // point i occupies coords[i*dim : (i+1)*dim]
+	ids       []string
+	values    []T
+	idIndex   map[string]int
+}
+
+// NewKDTree32 builds a KDTree32 from pts. Errors match NewKDTree's.
+func NewKDTree32[T any](pts []KDPoint[T], opts ...KDOption) (*KDTree32[T], error) {
+	if len(pts) == 0 {
+		return nil, ErrEmptyPoints
+	}
+	t, err := NewKDTree32FromDim[T](len(pts[0].Coords), opts...)
+	if err != nil {
+		return nil, err
+	}
+	t.coords = make([]float32, 0, len(pts)*t.dim)
+	t.ids = make([]string, 0, len(pts))
+	t.values = make([]T, 0, len(pts))
+	for _, p := range pts {
+		if len(p.Coords) != t.dim {
+			return nil, ErrDimMismatch
+		}
+		if p.ID != "" {
+			if _, exists := t.idIndex[p.ID]; exists {
+				return nil, ErrDuplicateID
+			}
+		}
+		if t.validator != nil {
+			if err := t.validator(p.Coords); err != nil {
+				return nil, fmt.Errorf("%w: point %q: %w", ErrInvalidCoords, p.ID, err)
+			}
+		}
+		t.add(p)
+	}
+	return t, nil
+}
+
+// NewKDTree32FromDim constructs an empty KDTree32 with the specified
+// dimension. Call Insert to add points after construction.
+func NewKDTree32FromDim[T any](dim int, opts ...KDOption) (*KDTree32[T], error) {
+	if dim <= 0 {
+		return nil, ErrZeroDim
+	}
+	cfg := kdOptions{metric: EuclideanDistance{}}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	metric, err := periodicMetric(cfg, dim)
+	if err != nil {
+		return nil, err
+	}
+	return &KDTree32[T]{
+		dim:       dim,
+		metric:    metric,
+		validator: cfg.coordValidator,
+		idIndex:   make(map[string]int),
+	}, nil
+}
+
+// add appends p without validation.
+func (t *KDTree32[T]) add(p KDPoint[T]) {
+	for _, v := range p.Coords {
+		t.coords = append(t.coords, float32(v))
+	}
+	if p.ID != "" {
+		t.idIndex[p.ID] = len(t.ids)
+	}
+	t.ids = append(t.ids, p.ID)
+	t.values = append(t.values, p.Value)
+}
+
+// Dim returns the number of dimensions.
+func (t *KDTree32[T]) Dim() int { return t.dim }
+
+// Len returns the number of points in the tree.
+func (t *KDTree32[T]) Len() int { return len(t.ids) }
+
+// point widens point i into a KDPoint with freshly allocated coordinates.
+func (t *KDTree32[T]) point(i int) KDPoint[T] {
+	c := make([]float64, t.dim)
+	t.widen(i, c)
+	return KDPoint[T]{ID: t.ids[i], Coords: c, Value: t.values[i]}
+}
+
+// widen copies point i's coordinates into dst (len Dim).
+func (t *KDTree32[T]) widen(i int, dst []float64) {
+	for j, v := range t.coords[i*t.dim : (i+1)*t.dim] {
+		dst[j] = float64(v)
+	}
+}
+
+// Points returns a copy of all points, coordinates widened to float64.
+func (t *KDTree32[T]) Points() []KDPoint[T] {
+	out := make([]KDPoint[T], len(t.ids))
+	for i := range out {
+		out[i] = t.point(i)
+	}
+	return out
+}
+
+// Insert adds a point. It returns false if the point fails the coordinate
+// validator, its dimensionality does not match, or its non-empty ID already
+// exists.
+func (t *KDTree32[T]) Insert(p KDPoint[T]) bool {
+	if len(p.Coords) != t.dim {
+		return false
+	}
+	if t.validator != nil && t.validator(p.Coords) != nil {
+		return false
+	}
+	if p.ID != "" {
+		if _, exists := t.idIndex[p.ID]; exists {
+			return false
+		}
+	}
+	t.add(p)
+	return true
+}
+
+// DeleteByID removes the point with the given ID by swapping the last point
+// into its place. It returns false if the ID is empty or not found.
+func (t *KDTree32[T]) DeleteByID(id string) bool {
+	if id == "" {
+		return false
+	}
+	idx, ok := t.idIndex[id]
+	if !ok {
+		return false
+	}
+	last := len(t.ids) - 1
+	copy(t.coords[idx*t.dim:(idx+1)*t.dim], t.coords[last*t.dim:])
+	t.ids[idx] = t.ids[last]
+	t.values[idx] = t.values[last]
+	if t.ids[idx] != "" {
+		t.idIndex[t.ids[idx]] = idx
+	}
+	var zero T
+	t.values[last] = zero
+	t.coords = t.coords[:last*t.dim]
+	t.ids = t.ids[:last]
+	t.values = t.values[:last]
+	delete(t.idIndex, id)
+	return true
+}
+
+// scan calls visit with the distance from query to every point.
+func (t *KDTree32[T]) scan(query []float64, visit func(i int, dist float64)) {
+	buf := make([]float64, t.dim)
+	for i := range t.ids {
+		t.widen(i, buf)
+		visit(i, t.metric.Distance(query, buf))
+	}
+}
+
+// Nearest returns the closest point to the query, along with its distance.
+// ok is false if the tree is empty or the query dimensionality does not match
+// Dim().
+func (t *KDTree32[T]) Nearest(query []float64) (KDPoint[T], float64, bool) {
+	if len(query) != t.dim || len(t.ids) == 0 {
+		return KDPoint[T]{}, 0, false
+	}
+	best, bestDist := -1, math.Inf(1)
+	t.scan(query, func(i int, d float64) {
+		if best < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	})
+	return t.point(best), bestDist, true
+}
+
+// KNearest returns up to k nearest points to the query in ascending distance
+// order.
+func (t *KDTree32[T]) KNearest(query []float64, k int) ([]KDPoint[T], []float64) {
+	if k <= 0 || len(query) != t.dim || len(t.ids) == 0 {
+		return nil, nil
+	}
+	var h knnHeap
+	t.scan(query, func(i int, d float64) {
+		if h.Len() < k {
+			h.push(knnItem{idx: i, dist: d})
+		} else if d < h.peek().dist {
+			h[0] = knnItem{idx: i, dist: d}
+			h.down(0)
+		}
+	})
+	return t.results(h)
+}
+
+// Radius returns points within radius r (inclusive) from the query, sorted by
+// distance.
+func (t *KDTree32[T]) Radius(query []float64, r float64) ([]KDPoint[T], []float64) {
+	if r < 0 || len(query) != t.dim || len(t.ids) == 0 {
+		return nil, nil
+	}
+	var sel []knnItem
+	t.scan(query, func(i int, d float64) {
+		if d <= r {
+			sel = append(sel, knnItem{idx: i, dist: d})
+		}
+	})
+	return t.results(sel)
+}
+
+// results widens the selected points, sorted by distance.
+func (t *KDTree32[T]) results(sel []knnItem) ([]KDPoint[T], []float64) {
+	sort.Slice(sel, func(i, j int) bool { return sel[i].dist < sel[j].dist })
+	pts := make([]KDPoint[T], len(sel))
+	dists := make([]float64, len(sel))
+	for i, it := range sel {
+		pts[i] = t.point(it.idx)
+		dists[i] = it.dist
+	}
+	return pts, dists
+}
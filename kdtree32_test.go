@@ -0,0 +1,90 @@
+package poindexter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestKDTree32_MatchesKDTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(45))
+	pts := make([]KDPoint[int], 500)
+	for i := range pts {
+		// float32-exact coordinates so both trees see identical points
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{float64(rng.Float32()), float64(rng.Float32()), float64(rng.Float32())}, Value: i}
+	}
+	for _, m := range []DistanceMetric{EuclideanDistance{}, CosineDistance{}} {
+		t32, err := NewKDTree32(pts, WithMetric(m))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t32.DeleteByID("3")
+		t32.Insert(KDPoint[int]{ID: "new", Coords: []float64{0.5, 0.25, 0.125}, Value: -1})
+		ref, _ := NewKDTree(t32.Points(), WithMetric(m), WithBackend(BackendLinear))
+		if t32.Len() != ref.Len() || t32.Dim() != 3 {
+			t.Fatalf("len %d dim %d", t32.Len(), t32.Dim())
+		}
+		for q := 0; q < 20; q++ {
+			query := []float64{rng.Float64(), rng.Float64(), rng.Float64()}
+			p, d, ok := t32.Nearest(query)
+			rp, rd, _ := ref.Nearest(query)
+			if !ok || p.ID != rp.ID || d != rd || p.Value != rp.Value {
+				t.Fatalf("nearest: %v %v vs %v %v", p.ID, d, rp.ID, rd)
+			}
+			_, kd := t32.KNearest(query, 7)
+			_, rkd := ref.KNearest(query, 7)
+			if fmt.Sprint(kd) != fmt.Sprint(rkd) {
+				t.Fatalf("knearest: %v vs %v", kd, rkd)
+			}
+			rr, _ := t32.Radius(query, rkd[3])
+			if len(rr) < 4 {
+				t.Fatalf("radius: %d points", len(rr))
+			}
+		}
+	}
+}
+
+func TestKDTree32_Storage(t *testing.T) {
+	tr, err := NewKDTree32FromDim[string](2, WithPeriodicAxis(1, 360))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tr.Insert(KDPoint[string]{ID: "a", Coords: []float64{0.1, 359}, Value: "x"}) {
+		t.Fatal("insert failed")
+	}
+	if tr.Insert(KDPoint[string]{ID: "a", Coords: []float64{0, 0}}) || tr.Insert(KDPoint[string]{Coords: []float64{0}}) {
+		t.Fatal("duplicate ID or wrong dimension accepted")
+	}
+	p, d, _ := tr.Nearest([]float64{float64(float32(0.1)), 1})
+	if p.Value != "x" || p.Coords[0] != float64(float32(0.1)) || d != 2 {
+		t.Fatalf("coords rounded to float32 and wrapped distance expected: %+v %v", p, d)
+	}
+	if !tr.DeleteByID("a") || tr.Len() != 0 || tr.DeleteByID("a") {
+		t.Fatal("delete")
+	}
+	if _, _, ok := tr.Nearest([]float64{0, 0}); ok {
+		t.Fatal("empty tree")
+	}
+
+	if _, err := NewKDTree32[int](nil); !errors.Is(err, ErrEmptyPoints) {
+		t.Fatalf("empty: %v", err)
+	}
+	if _, err := NewKDTree32([]KDPoint[int]{{ID: "a", Coords: []float64{1}}, {ID: "a", Coords: []float64{2}}}); !errors.Is(err, ErrDuplicateID) {
+		t.Fatalf("duplicate: %v", err)
+	}
+	nonNeg := WithCoordValidator(func(c []float64) error {
+		if c[0] < 0 {
+			return errors.New("negative")
+		}
+		return nil
+	})
+	if _, err := NewKDTree32([]KDPoint[int]{{Coords: []float64{-1}}}, nonNeg); !errors.Is(err, ErrInvalidCoords) {
+		t.Fatalf("validator: %v", err)
+	}
+	big, _ := NewKDTree32([]KDPoint[int]{{Coords: []float64{1e300}}})
+	if c := big.Points()[0].Coords[0]; !math.IsInf(c, 1) {
+		t.Fatalf("out of float32 range: %v", c)
+	}
+}
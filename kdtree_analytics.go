@@ -2,6 +2,7 @@ package poindexter
 
 import (
 	"math"
+	"math/rand/v2"
 	"sort"
 	"sync"
 	"sync/atomic"
@@ -24,6 +25,18 @@ type TreeAnalytics struct {
 	CreatedAt         time.Time
 	LastRebuiltAt     atomic.Int64 // Unix nanoseconds (for gonum backend rebuilds)
 	BackendRebuildCnt atomic.Int64 // Number of backend rebuilds
+
+	// Rebuild timing (nanoseconds)
+	LastRebuildTimeNs  atomic.Int64
+	TotalRebuildTimeNs atomic.Int64
+
+	// Radius result sizes (used to pre-size result buffers)
+	RadiusQueryCount  atomic.Int64 // Total radius queries
+	RadiusResultTotal atomic.Int64 // Total points returned by radius queries
+
+	queryLatency latencyHistogram // query times, for the latency percentiles
+	bestDists    distanceWindow   // best result distance of recent queries
+	anomaly      latencyDetector  // latency baseline, for Anomalous
 }
 
 // NewTreeAnalytics creates a new analytics tracker.
@@ -32,15 +45,25 @@ func NewTreeAnalytics() *TreeAnalytics {
 		CreatedAt: time.Now(),
 	}
 	a.MinQueryTimeNs.Store(math.MaxInt64)
+	a.anomaly.alpha, a.anomaly.k = DefaultAnomalyAlpha, DefaultAnomalyK
 	return a
 }
 
+// fresh returns an empty tracker with a's anomaly detection settings.
+func (a *TreeAnalytics) fresh() *TreeAnalytics {
+	c := NewTreeAnalytics()
+	c.anomaly.alpha, c.anomaly.k, c.anomaly.onAnomaly = a.anomaly.alpha, a.anomaly.k, a.anomaly.onAnomaly
+	return c
+}
+
 // RecordQuery records a query operation with timing.
 func (a *TreeAnalytics) RecordQuery(durationNs int64) {
 	a.QueryCount.Add(1)
 	a.TotalQueryTimeNs.Add(durationNs)
 	a.LastQueryTimeNs.Store(durationNs)
 	a.LastQueryAt.Store(time.Now().UnixNano())
+	a.queryLatency.record(durationNs)
+	a.anomaly.observe(durationNs)
 
 	// Update min/max (best-effort, not strictly atomic)
 	for {
@@ -57,6 +80,50 @@ func (a *TreeAnalytics) RecordQuery(durationNs int64) {
 	}
 }
 
+// QueryTimeQuantile returns the q-quantile (0 <= q <= 1) of recorded query
+// times in nanoseconds, e.g. 0.99 for the 99th percentile, from an HDR-style
+// histogram accurate to about 3%. It returns 0 before any query.
+func (a *TreeAnalytics) QueryTimeQuantile(q float64) int64 {
+	return a.queryLatency.quantile(q)
+}
+
+// RecordRebuildDuration records a backend rebuild that took durationNs.
+func (a *TreeAnalytics) RecordRebuildDuration(durationNs int64) {
+	a.RecordRebuild()
+	a.LastRebuildTimeNs.Store(durationNs)
+	a.TotalRebuildTimeNs.Add(durationNs)
+}
+
+// RecordRadiusResults records the number of points returned by a radius query.
+func (a *TreeAnalytics) RecordRadiusResults(n int) {
+	a.RadiusQueryCount.Add(1)
+	a.RadiusResultTotal.Add(int64(n))
+}
+
+// RecordBestDistance records the distance of a query's best (closest)
+// result. The most recent 1024 feed RecentBestDistances and the snapshot's
+// BestDistance fields.
+func (a *TreeAnalytics) RecordBestDistance(d float64) {
+	a.bestDists.add(d)
+}
+
+// RecentBestDistances returns statistics over the best result distance of
+// the last 1024 queries that returned anything. A rising mean or median means
+// queries are finding worse matches than before, e.g. because good peers
+// left or the workload moved away from the stored points.
+func (a *TreeAnalytics) RecentBestDistances() DistributionStats {
+	return ComputeDistributionStats(a.bestDists.values())
+}
+
+// AvgRadiusResults returns the average number of points returned per radius query.
+func (a *TreeAnalytics) AvgRadiusResults() float64 {
+	qc := a.RadiusQueryCount.Load()
+	if qc == 0 {
+		return 0
+	}
+	return float64(a.RadiusResultTotal.Load()) / float64(qc)
+}
+
 // RecordInsert records a successful insert.
 func (a *TreeAnalytics) RecordInsert() {
 	a.InsertCount.Add(1)
@@ -80,6 +147,11 @@ func (a *TreeAnalytics) Snapshot() TreeAnalyticsSnapshot {
 	if qc > 0 {
 		avgNs = a.TotalQueryTimeNs.Load() / qc
 	}
+	avgRebuildNs := int64(0)
+	if rc := a.BackendRebuildCnt.Load(); rc > 0 {
+		avgRebuildNs = a.TotalRebuildTimeNs.Load() / rc
+	}
+	best := a.RecentBestDistances()
 	minNs := a.MinQueryTimeNs.Load()
 	if minNs == math.MaxInt64 {
 		minNs = 0
@@ -96,6 +168,17 @@ func (a *TreeAnalytics) Snapshot() TreeAnalyticsSnapshot {
 		CreatedAt:         a.CreatedAt,
 		BackendRebuildCnt: a.BackendRebuildCnt.Load(),
 		LastRebuiltAt:     time.Unix(0, a.LastRebuiltAt.Load()),
+		LastRebuildTimeNs: a.LastRebuildTimeNs.Load(),
+		AvgRebuildTimeNs:  avgRebuildNs,
+		AvgRadiusResults:  a.AvgRadiusResults(),
+		P50QueryTimeNs:    a.QueryTimeQuantile(0.5),
+		P95QueryTimeNs:    a.QueryTimeQuantile(0.95),
+		P99QueryTimeNs:    a.QueryTimeQuantile(0.99),
+		P999QueryTimeNs:   a.QueryTimeQuantile(0.999),
+		BestDistanceMean:  best.Mean,
+		BestDistanceP50:   best.Median,
+		BestDistanceP90:   best.P90,
+		Anomalous:         a.Anomalous(),
 	}
 }
 
@@ -111,6 +194,40 @@ func (a *TreeAnalytics) Reset() {
 	a.LastQueryAt.Store(0)
 	a.BackendRebuildCnt.Store(0)
 	a.LastRebuiltAt.Store(0)
+	a.LastRebuildTimeNs.Store(0)
+	a.TotalRebuildTimeNs.Store(0)
+	a.RadiusQueryCount.Store(0)
+	a.RadiusResultTotal.Store(0)
+	a.queryLatency.reset()
+	a.bestDists.reset()
+	a.anomaly.reset()
+}
+
+// clone returns an independent copy of the counters.
+func (a *TreeAnalytics) clone() *TreeAnalytics {
+	c := &TreeAnalytics{CreatedAt: a.CreatedAt}
+	for _, f := range []struct{ dst, src *atomic.Int64 }{
+		{&c.QueryCount, &a.QueryCount},
+		{&c.InsertCount, &a.InsertCount},
+		{&c.DeleteCount, &a.DeleteCount},
+		{&c.TotalQueryTimeNs, &a.TotalQueryTimeNs},
+		{&c.LastQueryTimeNs, &a.LastQueryTimeNs},
+		{&c.MinQueryTimeNs, &a.MinQueryTimeNs},
+		{&c.MaxQueryTimeNs, &a.MaxQueryTimeNs},
+		{&c.LastQueryAt, &a.LastQueryAt},
+		{&c.LastRebuiltAt, &a.LastRebuiltAt},
+		{&c.BackendRebuildCnt, &a.BackendRebuildCnt},
+		{&c.LastRebuildTimeNs, &a.LastRebuildTimeNs},
+		{&c.TotalRebuildTimeNs, &a.TotalRebuildTimeNs},
+		{&c.RadiusQueryCount, &a.RadiusQueryCount},
+		{&c.RadiusResultTotal, &a.RadiusResultTotal},
+	} {
+		f.dst.Store(f.src.Load())
+	}
+	c.queryLatency.copyFrom(&a.queryLatency)
+	c.bestDists.copyFrom(&a.bestDists)
+	c.anomaly.copyFrom(&a.anomaly)
+	return c
 }
 
 // TreeAnalyticsSnapshot is an immutable snapshot for JSON serialization.
@@ -126,116 +243,247 @@ type TreeAnalyticsSnapshot struct {
 	CreatedAt         time.Time `json:"createdAt"`
 	BackendRebuildCnt int64     `json:"backendRebuildCount"`
 	LastRebuiltAt     time.Time `json:"lastRebuiltAt"`
+	LastRebuildTimeNs int64     `json:"lastRebuildTimeNs"`
+	AvgRebuildTimeNs  int64     `json:"avgRebuildTimeNs"`
+	AvgRadiusResults  float64   `json:"avgRadiusResults"`
+
+	// Query time percentiles from an HDR-style histogram, accurate to
+	// about 3%; they show the tail latency min/avg/max hide.
+	P50QueryTimeNs  int64 `json:"p50QueryTimeNs"`
+	P95QueryTimeNs  int64 `json:"p95QueryTimeNs"`
+	P99QueryTimeNs  int64 `json:"p99QueryTimeNs"`
+	P999QueryTimeNs int64 `json:"p999QueryTimeNs"`
+
+	// Best result distance over the last 1024 queries that returned
+	// anything (see RecentBestDistances); a rising trend means worse matches.
+	BestDistanceMean float64 `json:"bestDistanceMean"`
+	BestDistanceP50  float64 `json:"bestDistanceP50"`
+	BestDistanceP90  float64 `json:"bestDistanceP90"`
+
+	// Anomalous is set while query latency runs above its EWMA baseline by
+	// more than k·σ (see WithLatencyAnomalyDetection).
+	Anomalous bool `json:"anomalous"`
 }
 
 // PeerAnalytics tracks per-peer selection statistics for NAT routing optimization.
 type PeerAnalytics struct {
 	mu sync.RWMutex
 
-	// Per-peer hit counters (peer ID -> selection count)
-	hitCounts map[string]*atomic.Int64
-	// Per-peer cumulative distance sums for average calculation
-	distanceSums map[string]*atomic.Uint64 // stored as bits of float64
-	// Last selection time per peer
-	lastSelected map[string]*atomic.Int64 // Unix nano
+	// Per-peer selection records (peer ID -> record)
+	peers map[string]*peerRecord
+
+	// halfLife > 0 decays selection counts and distances (WithSelectionHalfLife)
+	halfLife time.Duration
+	// maxPeers > 0 caps the peers tracked, dropping one chosen by
+	// evictPolicy to make room (WithMaxTrackedPeers)
+	maxPeers    int
+	evictPolicy EvictionPolicy
+	evicted     atomic.Int64
+}
+
+// peerRecord holds one peer's selection statistics.
+type peerRecord struct {
+	hits    atomic.Int64  // selection count
+	distSum atomic.Uint64 // cumulative distance, stored as bits of float64
+	last    atomic.Int64  // last selection, Unix nano
+	created int64         // first selection, Unix nano
+
+	// Decayed selection count and distance sum as of at (Unix nano), kept
+	// only with a half-life.
+	decayMu     sync.Mutex
+	decayed     float64
+	decayedDist float64
+	at          int64
+}
+
+// PeerAnalyticsOption configures NewPeerAnalytics.
+type PeerAnalyticsOption func(*PeerAnalytics)
+
+// WithSelectionHalfLife makes selections fade: each counts half as much after
+// halfLife, a quarter after twice that, and so on. PeerStats.DecayedCount and
+// AvgDistance then reflect recent behaviour, and GetAllPeerStats, GetTopPeers
+// and the EvictLeastSelected policy rank peers by DecayedCount, so a peer that
+// was popular last week no longer dominates. SelectionCount stays cumulative.
+// halfLife <= 0 keeps everything cumulative, the default.
+func WithSelectionHalfLife(halfLife time.Duration) PeerAnalyticsOption {
+	return func(p *PeerAnalytics) { p.halfLife = max(halfLife, 0) }
+}
+
+// WithMaxTrackedPeers caps the number of peers tracked at n, so the maps stay
+// bounded in churny networks. Once n peers are tracked, the first selection of
+// a new peer drops one chosen by policy: EvictLRU the peer selected least
+// recently, EvictLeastSelected the one with the lowest DecayedCount, and
+// EvictOldest the one tracked longest; any other policy counts as EvictLRU.
+// Choosing the peer scans all of them, so it costs O(n). n <= 0 means no
+// limit, the default.
+func WithMaxTrackedPeers(n int, policy EvictionPolicy) PeerAnalyticsOption {
+	return func(p *PeerAnalytics) {
+		p.maxPeers = max(n, 0)
+		p.evictPolicy = policy
+	}
 }
 
 // NewPeerAnalytics creates a new peer analytics tracker.
-func NewPeerAnalytics() *PeerAnalytics {
+func NewPeerAnalytics(opts ...PeerAnalyticsOption) *PeerAnalytics {
+	p := &PeerAnalytics{peers: make(map[string]*peerRecord)}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// fresh returns an empty tracker with p's configuration.
+func (p *PeerAnalytics) fresh() *PeerAnalytics {
 	return &PeerAnalytics{
-		hitCounts:    make(map[string]*atomic.Int64),
-		distanceSums: make(map[string]*atomic.Uint64),
-		lastSelected: make(map[string]*atomic.Int64),
+		peers:       make(map[string]*peerRecord),
+		halfLife:    p.halfLife,
+		maxPeers:    p.maxPeers,
+		evictPolicy: p.evictPolicy,
 	}
 }
 
 // RecordSelection records that a peer was selected/returned in a query result.
 func (p *PeerAnalytics) RecordSelection(peerID string, distance float64) {
+	p.recordAt(peerID, distance, time.Now().UnixNano())
+}
+
+// recordAt is RecordSelection at the given time (Unix nano).
+func (p *PeerAnalytics) recordAt(peerID string, distance float64, now int64) {
 	if peerID == "" {
 		return
 	}
 
 	p.mu.RLock()
-	hc, hok := p.hitCounts[peerID]
-	ds, dok := p.distanceSums[peerID]
-	ls, lok := p.lastSelected[peerID]
+	r, ok := p.peers[peerID]
 	p.mu.RUnlock()
 
-	if !hok || !dok || !lok {
+	if !ok {
 		p.mu.Lock()
-		if _, ok := p.hitCounts[peerID]; !ok {
-			p.hitCounts[peerID] = &atomic.Int64{}
-		}
-		if _, ok := p.distanceSums[peerID]; !ok {
-			p.distanceSums[peerID] = &atomic.Uint64{}
-		}
-		if _, ok := p.lastSelected[peerID]; !ok {
-			p.lastSelected[peerID] = &atomic.Int64{}
+		if r, ok = p.peers[peerID]; !ok {
+			if p.maxPeers > 0 && len(p.peers) >= p.maxPeers {
+				p.evictLocked(now)
+			}
+			r = &peerRecord{created: now}
+			p.peers[peerID] = r
 		}
-		hc = p.hitCounts[peerID]
-		ds = p.distanceSums[peerID]
-		ls = p.lastSelected[peerID]
 		p.mu.Unlock()
 	}
 
-	hc.Add(1)
+	r.hits.Add(1)
 	// Atomic float add via CAS
 	for {
-		old := ds.Load()
+		old := r.distSum.Load()
 		oldF := math.Float64frombits(old)
 		newF := oldF + distance
-		if ds.CompareAndSwap(old, math.Float64bits(newF)) {
+		if r.distSum.CompareAndSwap(old, math.Float64bits(newF)) {
 			break
 		}
 	}
-	ls.Store(time.Now().UnixNano())
+	r.last.Store(now)
+	if p.halfLife > 0 {
+		r.decayMu.Lock()
+		f := decayFactor(now-r.at, p.halfLife)
+		r.decayed = r.decayed*f + 1
+		r.decayedDist = r.decayedDist*f + distance
+		r.at = max(r.at, now)
+		r.decayMu.Unlock()
+	}
+}
+
+// evictLocked drops the peer the eviction policy picks. The caller holds mu
+// for writing.
+func (p *PeerAnalytics) evictLocked(now int64) {
+	victim := ""
+	var bestCount float64
+	var bestKey int64
+	found := false
+	for id, r := range p.peers {
+		var count float64
+		var key int64
+		switch p.evictPolicy {
+		case EvictLeastSelected:
+			count, key = p.stats(id, r, now).DecayedCount, r.last.Load()
+		case EvictOldest:
+			key = r.created
+		default:
+			key = r.last.Load()
+		}
+		if !found || count < bestCount || (count == bestCount && (key < bestKey || (key == bestKey && id < victim))) {
+			victim, bestCount, bestKey, found = id, count, key, true
+		}
+	}
+	if found {
+		delete(p.peers, victim)
+		p.evicted.Add(1)
+	}
 }
 
-// GetPeerStats returns statistics for a specific peer.
-func (p *PeerAnalytics) GetPeerStats(peerID string) PeerStats {
+// Len returns the number of peers tracked.
+func (p *PeerAnalytics) Len() int {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
+	return len(p.peers)
+}
 
-	hc, hok := p.hitCounts[peerID]
-	ds, dok := p.distanceSums[peerID]
-	ls, lok := p.lastSelected[peerID]
+// Evicted returns how many peers WithMaxTrackedPeers has dropped since the
+// tracker was created or last reset.
+func (p *PeerAnalytics) Evicted() int64 {
+	return p.evicted.Load()
+}
 
-	stats := PeerStats{PeerID: peerID}
-	if hok {
-		stats.SelectionCount = hc.Load()
+// decayFactor is the weight left after dt nanoseconds with the given half-life.
+func decayFactor(dt int64, halfLife time.Duration) float64 {
+	if dt <= 0 {
+		return 1
 	}
-	if dok && stats.SelectionCount > 0 {
-		stats.AvgDistance = math.Float64frombits(ds.Load()) / float64(stats.SelectionCount)
+	return math.Exp2(-float64(dt) / float64(halfLife))
+}
+
+// stats returns r's statistics as of now (Unix nano).
+func (p *PeerAnalytics) stats(peerID string, r *peerRecord, now int64) PeerStats {
+	stats := PeerStats{PeerID: peerID, SelectionCount: r.hits.Load()}
+	if stats.SelectionCount > 0 {
+		stats.AvgDistance = math.Float64frombits(r.distSum.Load()) / float64(stats.SelectionCount)
 	}
-	if lok {
-		stats.LastSelectedAt = time.Unix(0, ls.Load())
+	stats.DecayedCount = float64(stats.SelectionCount)
+	stats.LastSelectedAt = time.Unix(0, r.last.Load())
+	stats.FirstSelectedAt = time.Unix(0, r.created)
+	if p.halfLife > 0 {
+		r.decayMu.Lock()
+		stats.DecayedCount = r.decayed * decayFactor(now-r.at, p.halfLife)
+		if r.decayed > 0 {
+			stats.AvgDistance = r.decayedDist / r.decayed
+		}
+		r.decayMu.Unlock()
 	}
 	return stats
 }
 
+// GetPeerStats returns statistics for a specific peer.
+func (p *PeerAnalytics) GetPeerStats(peerID string) PeerStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if r, ok := p.peers[peerID]; ok {
+		return p.stats(peerID, r, time.Now().UnixNano())
+	}
+	return PeerStats{PeerID: peerID}
+}
+
 // GetAllPeerStats returns statistics for all tracked peers.
 func (p *PeerAnalytics) GetAllPeerStats() []PeerStats {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	result := make([]PeerStats, 0, len(p.hitCounts))
-	for id := range p.hitCounts {
-		stats := PeerStats{PeerID: id}
-		if hc := p.hitCounts[id]; hc != nil {
-			stats.SelectionCount = hc.Load()
-		}
-		if ds := p.distanceSums[id]; ds != nil && stats.SelectionCount > 0 {
-			stats.AvgDistance = math.Float64frombits(ds.Load()) / float64(stats.SelectionCount)
-		}
-		if ls := p.lastSelected[id]; ls != nil {
-			stats.LastSelectedAt = time.Unix(0, ls.Load())
-		}
-		result = append(result, stats)
+	now := time.Now().UnixNano()
+	result := make([]PeerStats, 0, len(p.peers))
+	for id, r := range p.peers {
+		result = append(result, p.stats(id, r, now))
 	}
 
-	// Sort by selection count descending
+	// Sort by (decayed) selection count descending
 	sort.Slice(result, func(i, j int) bool {
-		return result[i].SelectionCount > result[j].SelectionCount
+		return result[i].DecayedCount > result[j].DecayedCount
 	})
 	return result
 }
@@ -253,9 +501,43 @@ func (p *PeerAnalytics) GetTopPeers(n int) []PeerStats {
 func (p *PeerAnalytics) Reset() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.hitCounts = make(map[string]*atomic.Int64)
-	p.distanceSums = make(map[string]*atomic.Uint64)
-	p.lastSelected = make(map[string]*atomic.Int64)
+	p.peers = make(map[string]*peerRecord)
+	p.evicted.Store(0)
+}
+
+// clone returns an independent copy of the per-peer statistics.
+func (p *PeerAnalytics) clone() *PeerAnalytics {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c := p.fresh()
+	for id, r := range p.peers {
+		cr := &peerRecord{}
+		cr.hits.Store(r.hits.Load())
+		cr.distSum.Store(r.distSum.Load())
+		cr.last.Store(r.last.Load())
+		cr.created = r.created
+		r.decayMu.Lock()
+		cr.decayed, cr.decayedDist, cr.at = r.decayed, r.decayedDist, r.at
+		r.decayMu.Unlock()
+		c.peers[id] = cr
+	}
+	c.evicted.Store(p.evicted.Load())
+	return c
+}
+
+// selectionOf returns peerID's (decayed) selection count and last selection
+// time in Unix nanoseconds, both 0 if it was never selected or p is nil.
+func (p *PeerAnalytics) selectionOf(peerID string) (count float64, last int64) {
+	if p == nil {
+		return 0, 0
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if r, ok := p.peers[peerID]; ok {
+		s := p.stats(peerID, r, time.Now().UnixNano())
+		return s.DecayedCount, r.last.Load()
+	}
+	return 0, 0
 }
 
 // PeerStats holds statistics for a single peer.
@@ -264,6 +546,11 @@ type PeerStats struct {
 	SelectionCount int64     `json:"selectionCount"`
 	AvgDistance    float64   `json:"avgDistance"`
 	LastSelectedAt time.Time `json:"lastSelectedAt"`
+	// FirstSelectedAt is when the peer was first selected.
+	FirstSelectedAt time.Time `json:"firstSelectedAt"`
+	// DecayedCount is SelectionCount with WithSelectionHalfLife decay
+	// applied; without a half-life the two are equal.
+	DecayedCount float64 `json:"decayedCount"`
 }
 
 // DistributionStats provides statistical analysis of distances in query results.
@@ -360,15 +647,187 @@ func percentile(sorted []float64, p float64) float64 {
 	return sorted[lower]*(1-frac) + sorted[upper]*frac
 }
 
+// DefaultDistributionSampleSize is the reservoir size used by
+// NewStreamingDistribution when a non-positive size is requested.
+const DefaultDistributionSampleSize = 1024
+
+// StreamingDistribution accumulates a stream of values (e.g., query result
+// distances) without retaining all of them. Count, min, max, mean, variance and
+// skewness are exact (computed from running moments); percentiles are estimated
+// from a fixed-size uniform reservoir sample. Safe for concurrent use.
+type StreamingDistribution struct {
+	mu        sync.Mutex
+	count     int64
+	min, max  float64
+	mean      float64
+	m2, m3    float64 // running central moment sums
+	reservoir []float64
+	capacity  int
+	rng       *rand.Rand
+}
+
+// NewStreamingDistribution creates a streaming distribution that keeps at most
+// sampleSize values for percentile estimation.
+func NewStreamingDistribution(sampleSize int) *StreamingDistribution {
+	if sampleSize <= 0 {
+		sampleSize = DefaultDistributionSampleSize
+	}
+	return &StreamingDistribution{
+		capacity: sampleSize,
+		rng:      rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), 0x9e3779b97f4a7c15)),
+	}
+}
+
+// Add records a single value.
+func (s *StreamingDistribution) Add(v float64) {
+	s.mu.Lock()
+	s.add(v)
+	s.mu.Unlock()
+}
+
+// AddAll records every value in vs.
+func (s *StreamingDistribution) AddAll(vs []float64) {
+	if len(vs) == 0 {
+		return
+	}
+	s.mu.Lock()
+	for _, v := range vs {
+		s.add(v)
+	}
+	s.mu.Unlock()
+}
+
+func (s *StreamingDistribution) add(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	if s.count == 0 || v < s.min {
+		s.min = v
+	}
+	if s.count == 0 || v > s.max {
+		s.max = v
+	}
+	// Online update of mean and 2nd/3rd central moments.
+	n1 := float64(s.count)
+	s.count++
+	n := float64(s.count)
+	delta := v - s.mean
+	deltaN := delta / n
+	term1 := delta * deltaN * n1
+	s.mean += deltaN
+	s.m3 += term1*deltaN*(n-2) - 3*deltaN*s.m2
+	s.m2 += term1
+	// Reservoir sampling (Algorithm R).
+	if len(s.reservoir) < s.capacity {
+		s.reservoir = append(s.reservoir, v)
+	} else if j := s.rng.Int64N(s.count); j < int64(s.capacity) {
+		s.reservoir[j] = v
+	}
+}
+
+// sample returns a copy of the retained values.
+func (s *StreamingDistribution) sample() []float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]float64(nil), s.reservoir...)
+}
+
+// Count returns the number of values recorded.
+func (s *StreamingDistribution) Count() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Stats returns the current distribution statistics. Count is the total number
+// of values seen; SampleSize is the number retained for percentile estimates.
+func (s *StreamingDistribution) Stats() DistributionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count == 0 {
+		return DistributionStats{ComputedAt: time.Now()}
+	}
+	sorted := append([]float64(nil), s.reservoir...)
+	sort.Float64s(sorted)
+	n := float64(s.count)
+	variance := s.m2 / n
+	skewness := 0.0
+	if s.m2 > 0 {
+		skewness = math.Sqrt(n) * s.m3 / math.Pow(s.m2, 1.5)
+	}
+	return DistributionStats{
+		Count:      int(s.count),
+		Min:        s.min,
+		Max:        s.max,
+		Mean:       s.mean,
+		Median:     percentile(sorted, 0.5),
+		StdDev:     math.Sqrt(variance),
+		P25:        percentile(sorted, 0.25),
+		P75:        percentile(sorted, 0.75),
+		P90:        percentile(sorted, 0.90),
+		P99:        percentile(sorted, 0.99),
+		Variance:   variance,
+		Skewness:   skewness,
+		SampleSize: len(sorted),
+		ComputedAt: time.Now(),
+	}
+}
+
+// Quantile estimates the p-th quantile (p in [0,1], clamped) of the recorded
+// values from the reservoir sample. Returns 0 if nothing has been recorded.
+func (s *StreamingDistribution) Quantile(p float64) float64 {
+	s.mu.Lock()
+	sorted := append([]float64(nil), s.reservoir...)
+	s.mu.Unlock()
+	sort.Float64s(sorted)
+	return percentile(sorted, math.Max(0, math.Min(1, p)))
+}
+
+// Reset clears all recorded values.
+func (s *StreamingDistribution) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count = 0
+	s.min, s.max = 0, 0
+	s.mean, s.m2, s.m3 = 0, 0, 0
+	s.reservoir = s.reservoir[:0]
+}
+
+// clone returns an independent copy of the distribution. If empty is true
+// only the configuration (sample size) is kept.
+func (s *StreamingDistribution) clone(empty bool) *StreamingDistribution {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := NewStreamingDistribution(s.capacity)
+	if empty {
+		return c
+	}
+	c.count = s.count
+	c.min, c.max = s.min, s.max
+	c.mean, c.m2, c.m3 = s.mean, s.m2, s.m3
+	c.reservoir = append(make([]float64, 0, len(s.reservoir)), s.reservoir...)
+	return c
+}
+
 // AxisDistribution provides per-axis (feature) distribution analysis.
 type AxisDistribution struct {
 	Axis  int               `json:"axis"`
 	Name  string            `json:"name,omitempty"`
 	Stats DistributionStats `json:"stats"`
+	// Histogram holds counts per equal-width bin between Stats.Min and
+	// Stats.Max, for rendering the distribution itself.
+	Histogram Histogram `json:"histogram"`
 }
 
-// ComputeAxisDistributions analyzes the distribution of values along each axis.
+// ComputeAxisDistributions analyzes the distribution of values along each
+// axis, with DefaultHistogramBins histogram bins.
 func ComputeAxisDistributions[T any](points []KDPoint[T], axisNames []string) []AxisDistribution {
+	return ComputeAxisDistributionsWithBins(points, axisNames, DefaultHistogramBins)
+}
+
+// ComputeAxisDistributionsWithBins is ComputeAxisDistributions with bins
+// histogram bins per axis (DefaultHistogramBins if bins <= 0).
+func ComputeAxisDistributionsWithBins[T any](points []KDPoint[T], axisNames []string, bins int) []AxisDistribution {
 	if len(points) == 0 {
 		return nil
 	}
@@ -387,9 +846,10 @@ func ComputeAxisDistributions[T any](points []KDPoint[T], axisNames []string) []
 			name = axisNames[axis]
 		}
 		result[axis] = AxisDistribution{
-			Axis:  axis,
-			Name:  name,
-			Stats: ComputeDistributionStats(values),
+			Axis:      axis,
+			Name:      name,
+			Stats:     ComputeDistributionStats(values),
+			Histogram: ComputeHistogram(values, bins),
 		}
 	}
 	return result
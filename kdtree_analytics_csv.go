@@ -0,0 +1,79 @@
+package poindexter
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// WriteAnalyticsCSV writes the tree's analytics snapshot as CSV: a header row
+// of the snapshot's JSON field names and one row of values, for loading into
+// a spreadsheet or pandas. Times are RFC 3339 in UTC, empty if never set.
+// Without analytics the row holds zeros.
+func (t *KDTree[T]) WriteAnalyticsCSV(w io.Writer) error {
+	s := t.GetAnalyticsSnapshot()
+	cols := []struct {
+		name, value string
+	}{
+		{"queryCount", csvInt(s.QueryCount)},
+		{"insertCount", csvInt(s.InsertCount)},
+		{"deleteCount", csvInt(s.DeleteCount)},
+		{"avgQueryTimeNs", csvInt(s.AvgQueryTimeNs)},
+		{"minQueryTimeNs", csvInt(s.MinQueryTimeNs)},
+		{"maxQueryTimeNs", csvInt(s.MaxQueryTimeNs)},
+		{"lastQueryTimeNs", csvInt(s.LastQueryTimeNs)},
+		{"lastQueryAt", csvTime(s.LastQueryAt)},
+		{"createdAt", csvTime(s.CreatedAt)},
+		{"backendRebuildCount", csvInt(s.BackendRebuildCnt)},
+		{"lastRebuiltAt", csvTime(s.LastRebuiltAt)},
+		{"lastRebuildTimeNs", csvInt(s.LastRebuildTimeNs)},
+		{"avgRebuildTimeNs", csvInt(s.AvgRebuildTimeNs)},
+		{"avgRadiusResults", csvFloat(s.AvgRadiusResults)},
+		{"p50QueryTimeNs", csvInt(s.P50QueryTimeNs)},
+		{"p95QueryTimeNs", csvInt(s.P95QueryTimeNs)},
+		{"p99QueryTimeNs", csvInt(s.P99QueryTimeNs)},
+		{"p999QueryTimeNs", csvInt(s.P999QueryTimeNs)},
+		{"bestDistanceMean", csvFloat(s.BestDistanceMean)},
+		{"bestDistanceP50", csvFloat(s.BestDistanceP50)},
+		{"bestDistanceP90", csvFloat(s.BestDistanceP90)},
+		{"anomalous", strconv.FormatBool(s.Anomalous)},
+	}
+	header := make([]string, len(cols))
+	row := make([]string, len(cols))
+	for i, c := range cols {
+		header[i], row[i] = c.name, c.value
+	}
+	return csv.NewWriter(w).WriteAll([][]string{header, row})
+}
+
+// WritePeerStatsCSV writes per-peer selection statistics as CSV: a header row
+// and one row per tracked peer, most selected first (as GetPeerStats). Times
+// are RFC 3339 in UTC. Without peer analytics only the header is written.
+func (t *KDTree[T]) WritePeerStatsCSV(w io.Writer) error {
+	rows := [][]string{{"peerId", "selectionCount", "decayedCount", "avgDistance", "firstSelectedAt", "lastSelectedAt"}}
+	for _, s := range t.GetPeerStats() {
+		rows = append(rows, []string{
+			s.PeerID,
+			csvInt(s.SelectionCount),
+			csvFloat(s.DecayedCount),
+			csvFloat(s.AvgDistance),
+			csvTime(s.FirstSelectedAt),
+			csvTime(s.LastSelectedAt),
+		})
+	}
+	return csv.NewWriter(w).WriteAll(rows)
+}
+
+func csvInt(v int64) string { return strconv.FormatInt(v, 10) }
+
+func csvFloat(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+
+// csvTime formats tm as RFC 3339 in UTC, or "" for the zero time and the Unix
+// epoch that analytics use for "never".
+func csvTime(tm time.Time) string {
+	if tm.IsZero() || tm.UnixNano() == 0 {
+		return ""
+	}
+	return tm.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,80 @@
+package poindexter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWriteAnalyticsCSV(t *testing.T) {
+	tree, err := NewKDTree([]KDPoint[int]{{ID: "a", Coords: []float64{0}}, {ID: "b", Coords: []float64{3}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Nearest([]float64{1})
+	tree.Nearest([]float64{2})
+
+	var buf bytes.Buffer
+	if err := tree.WriteAnalyticsCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || len(rows[0]) != len(rows[1]) {
+		t.Fatalf("rows %v", rows)
+	}
+	got := map[string]string{}
+	for i, name := range rows[0] {
+		got[name] = rows[1][i]
+	}
+	if got["queryCount"] != "2" || got["anomalous"] != "false" || got["bestDistanceMean"] != "1" {
+		t.Fatalf("values %v", got)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, got["createdAt"]); err != nil {
+		t.Fatalf("createdAt %q: %v", got["createdAt"], err)
+	}
+	if got["lastRebuiltAt"] != "" {
+		t.Fatalf("unset time should be empty, got %q", got["lastRebuiltAt"])
+	}
+}
+
+func TestWritePeerStatsCSV(t *testing.T) {
+	tree, err := NewKDTree([]KDPoint[int]{{ID: "a", Coords: []float64{0}}, {ID: "b", Coords: []float64{10}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Nearest([]float64{1})
+	tree.Nearest([]float64{2})
+	tree.Nearest([]float64{9})
+
+	var buf bytes.Buffer
+	if err := tree.WritePeerStatsCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0][0] != "peerId" {
+		t.Fatalf("rows %v", rows)
+	}
+	if rows[1][0] != "a" || rows[1][1] != "2" || rows[2][0] != "b" || rows[2][1] != "1" {
+		t.Fatalf("peer rows %v", rows[1:])
+	}
+	if d, err := strconv.ParseFloat(rows[1][3], 64); err != nil || d != 1.5 {
+		t.Fatalf("avgDistance %q", rows[1][3])
+	}
+
+	off, _ := NewKDTree([]KDPoint[int]{{ID: "a", Coords: []float64{0}}}, WithPeerAnalytics(false))
+	buf.Reset()
+	if err := off.WritePeerStatsCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if rows, _ := csv.NewReader(&buf).ReadAll(); len(rows) != 1 {
+		t.Fatalf("expected only a header, got %v", rows)
+	}
+}
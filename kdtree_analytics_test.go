@@ -85,6 +85,29 @@ func TestTreeAnalyticsSnapshot(t *testing.T) {
 	}
 }
 
+func TestTreeAnalyticsRebuildDuration(t *testing.T) {
+	a := NewTreeAnalytics()
+
+	a.RecordRebuildDuration(1000)
+	a.RecordRebuildDuration(3000)
+
+	snap := a.Snapshot()
+	if snap.BackendRebuildCnt != 2 {
+		t.Errorf("expected BackendRebuildCnt=2, got %d", snap.BackendRebuildCnt)
+	}
+	if snap.LastRebuildTimeNs != 3000 {
+		t.Errorf("expected LastRebuildTimeNs=3000, got %d", snap.LastRebuildTimeNs)
+	}
+	if snap.AvgRebuildTimeNs != 2000 {
+		t.Errorf("expected AvgRebuildTimeNs=2000, got %d", snap.AvgRebuildTimeNs)
+	}
+
+	a.Reset()
+	if snap := a.Snapshot(); snap.LastRebuildTimeNs != 0 || snap.AvgRebuildTimeNs != 0 {
+		t.Errorf("expected rebuild timings cleared after reset, got %+v", snap)
+	}
+}
+
 func TestTreeAnalyticsReset(t *testing.T) {
 	a := NewTreeAnalytics()
 
@@ -270,6 +293,85 @@ func TestComputeDistributionStatsPercentiles(t *testing.T) {
 // AxisDistribution Tests
 // ============================================================================
 
+func TestStreamingDistributionMatchesBatch(t *testing.T) {
+	values := []float64{3, 1, 4, 1, 5, 9, 2, 6, 5, 3}
+	s := NewStreamingDistribution(0)
+	s.AddAll(values[:4])
+	for _, v := range values[4:] {
+		s.Add(v)
+	}
+	got := s.Stats()
+	want := ComputeDistributionStats(values)
+	pairs := []struct {
+		name      string
+		got, want float64
+	}{
+		{"min", got.Min, want.Min},
+		{"max", got.Max, want.Max},
+		{"mean", got.Mean, want.Mean},
+		{"median", got.Median, want.Median},
+		{"variance", got.Variance, want.Variance},
+		{"skewness", got.Skewness, want.Skewness},
+		{"p90", got.P90, want.P90},
+	}
+	for _, p := range pairs {
+		if math.Abs(p.got-p.want) > 1e-9 {
+			t.Errorf("%s: got %v, want %v", p.name, p.got, p.want)
+		}
+	}
+	if got.Count != len(values) || got.SampleSize != len(values) {
+		t.Errorf("expected count/sample %d, got %d/%d", len(values), got.Count, got.SampleSize)
+	}
+}
+
+func TestStreamingDistributionBoundedSample(t *testing.T) {
+	s := NewStreamingDistribution(16)
+	for i := 0; i < 1000; i++ {
+		s.Add(float64(i))
+	}
+	st := s.Stats()
+	if st.Count != 1000 || st.SampleSize != 16 {
+		t.Fatalf("expected count=1000 sample=16, got %d/%d", st.Count, st.SampleSize)
+	}
+	if st.Min != 0 || st.Max != 999 || math.Abs(st.Mean-499.5) > 1e-9 {
+		t.Fatalf("exact moments wrong: %+v", st)
+	}
+	s.Reset()
+	if s.Count() != 0 || s.Stats().Count != 0 {
+		t.Fatal("expected empty distribution after Reset")
+	}
+}
+
+func TestKDTreeResultDistanceDistribution(t *testing.T) {
+	points := []KDPoint[string]{
+		{ID: "a", Coords: []float64{0}},
+		{ID: "b", Coords: []float64{1}},
+		{ID: "c", Coords: []float64{3}},
+	}
+	plain, _ := NewKDTree(points)
+	plain.Nearest([]float64{0})
+	if plain.GetResultDistanceDistribution().Count != 0 {
+		t.Fatal("tracking should be disabled by default")
+	}
+
+	tree, _ := NewKDTree(points, WithResultDistanceTracking(64))
+	tree.Nearest([]float64{0.5})            // 0.5
+	tree.KNearest([]float64{0}, 2)          // 0, 1
+	tree.Radius([]float64{3}, 2)            // 0, 2
+	tree.RadiusAppend([]float64{3}, 0, nil) // 0
+	st := tree.GetResultDistanceDistribution()
+	if st.Count != 6 {
+		t.Fatalf("expected 6 recorded distances, got %d", st.Count)
+	}
+	if st.Max != 2 || st.Min != 0 {
+		t.Fatalf("unexpected min/max: %v/%v", st.Min, st.Max)
+	}
+	tree.ResetAnalytics()
+	if tree.GetResultDistanceDistribution().Count != 0 {
+		t.Fatal("expected ResetAnalytics to clear result distances")
+	}
+}
+
 func TestComputeAxisDistributions(t *testing.T) {
 	points := []KDPoint[string]{
 		{ID: "a", Coords: []float64{1.0, 10.0}},
@@ -610,6 +712,31 @@ func TestKDTreeAnalyticsIntegration(t *testing.T) {
 	}
 }
 
+func TestKDTreeWithPeerIDFunc(t *testing.T) {
+	type peer struct{ Addr string }
+	points := []KDPoint[peer]{
+		{Coords: []float64{0}, Value: peer{"10.0.0.1"}},
+		{Coords: []float64{5}, Value: peer{"10.0.0.2"}},
+	}
+	tree, err := NewKDTree(points, WithPeerIDFunc(func(p KDPoint[peer]) string { return p.Value.Addr }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Nearest([]float64{0.1})
+	tree.KNearest([]float64{0.1}, 2)
+	stats := tree.PeerAnalytics().GetPeerStats("10.0.0.1")
+	if stats.SelectionCount != 2 {
+		t.Errorf("expected 2 selections keyed by payload, got %d", stats.SelectionCount)
+	}
+	if len(tree.GetPeerStats()) != 2 {
+		t.Errorf("expected 2 tracked peers, got %d", len(tree.GetPeerStats()))
+	}
+
+	if _, err := NewKDTreeFromDim[string](1, WithPeerIDFunc(func(p KDPoint[int]) string { return "" })); err != ErrPeerIDFuncType {
+		t.Errorf("expected ErrPeerIDFuncType, got %v", err)
+	}
+}
+
 func TestKDTreeDistanceDistribution(t *testing.T) {
 	points := []KDPoint[string]{
 		{ID: "a", Coords: []float64{0, 10}, Value: "A"},
@@ -660,3 +787,157 @@ func TestKDTreeBackend(t *testing.T) {
 		t.Errorf("unexpected backend: %s", backend)
 	}
 }
+
+func TestWithAnalyticsDisabled(t *testing.T) {
+	pts := []KDPoint[int]{
+		{ID: "a", Coords: []float64{0}},
+		{ID: "b", Coords: []float64{1}},
+		{ID: "c", Coords: []float64{2}},
+	}
+	tree, err := NewKDTree(pts, WithAnalytics(false), WithPeerAnalytics(false), WithMaxPoints(3, EvictLeastSelected))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Analytics() != nil || tree.PeerAnalytics() != nil {
+		t.Fatal("expected analytics to be disabled")
+	}
+	if _, _, ok := tree.Nearest([]float64{0.1}); !ok {
+		t.Fatal("query failed without analytics")
+	}
+	if got, _ := tree.Radius([]float64{0}, 1); len(got) != 2 {
+		t.Fatalf("expected 2 radius results, got %d", len(got))
+	}
+	if s := tree.GetAnalyticsSnapshot(); s.QueryCount != 0 {
+		t.Fatalf("expected a zero snapshot, got %+v", s)
+	}
+	if tree.GetPeerStats() != nil || tree.GetTopPeers(1) != nil {
+		t.Fatal("expected no peer stats")
+	}
+	// least-selected degrades to oldest without peer analytics
+	tree.Insert(KDPoint[int]{ID: "d", Coords: []float64{3}})
+	if tree.DeleteByID("a") {
+		t.Fatal("expected the oldest point to be evicted")
+	}
+	tree.ResetAnalytics()
+	if c := tree.Clone(false); c.Analytics() != nil || c.PeerAnalytics() != nil {
+		t.Fatal("clone should keep analytics disabled")
+	}
+
+	// peer analytics alone can be turned off
+	tree, err = NewKDTree(pts, WithPeerAnalytics(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Nearest([]float64{0})
+	if tree.PeerAnalytics() != nil || tree.GetAnalyticsSnapshot().QueryCount != 1 {
+		t.Fatal("expected tree analytics without peer analytics")
+	}
+}
+
+func TestPeerAnalyticsSelectionHalfLife(t *testing.T) {
+	const halfLife = time.Hour
+	pa := NewPeerAnalytics(WithSelectionHalfLife(halfLife))
+	now := time.Now().UnixNano()
+	weekAgo := now - int64(7*24*time.Hour)
+	for i := 0; i < 100; i++ {
+		pa.recordAt("veteran", 1, weekAgo)
+	}
+	pa.recordAt("newcomer", 3, now-int64(halfLife))
+	pa.recordAt("newcomer", 5, now)
+
+	top := pa.GetTopPeers(1)
+	if len(top) != 1 || top[0].PeerID != "newcomer" {
+		t.Fatalf("expected the recent peer on top, got %+v", top)
+	}
+	n := pa.GetPeerStats("newcomer")
+	if n.SelectionCount != 2 || math.Abs(n.DecayedCount-1.5) > 0.01 {
+		t.Fatalf("expected 2 selections decaying to ~1.5, got %+v", n)
+	}
+	// the older distance weighs half as much: (3*0.5 + 5) / 1.5
+	if math.Abs(n.AvgDistance-13.0/3) > 0.01 {
+		t.Fatalf("expected decay-weighted avg distance ~4.33, got %v", n.AvgDistance)
+	}
+	v := pa.GetPeerStats("veteran")
+	if v.SelectionCount != 100 || v.DecayedCount > 1e-40 {
+		t.Fatalf("expected the veteran's count to have decayed away, got %+v", v)
+	}
+
+	c := pa.clone()
+	pa.Reset()
+	if got := c.GetPeerStats("newcomer"); math.Abs(got.DecayedCount-n.DecayedCount) > 0.01 {
+		t.Fatalf("clone lost decayed state: %+v", got)
+	}
+	if pa.fresh().halfLife != halfLife {
+		t.Fatal("fresh should keep the half-life")
+	}
+}
+
+func TestPeerAnalyticsNoHalfLife(t *testing.T) {
+	pa := NewPeerAnalytics()
+	pa.RecordSelection("a", 2)
+	pa.RecordSelection("a", 4)
+	s := pa.GetPeerStats("a")
+	if s.SelectionCount != 2 || s.DecayedCount != 2 || s.AvgDistance != 3 {
+		t.Fatalf("expected cumulative stats, got %+v", s)
+	}
+}
+
+func TestEvictLeastSelectedUsesDecay(t *testing.T) {
+	tree, err := NewKDTreeFromDim[int](1,
+		WithMaxPoints(2, EvictLeastSelected),
+		WithPeerAnalyticsOptions(WithSelectionHalfLife(time.Hour)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Insert(KDPoint[int]{ID: "old", Coords: []float64{0}})
+	tree.Insert(KDPoint[int]{ID: "recent", Coords: []float64{10}})
+	pa := tree.PeerAnalytics()
+	now := time.Now().UnixNano()
+	for i := 0; i < 10; i++ {
+		pa.recordAt("old", 0, now-int64(24*time.Hour))
+	}
+	pa.recordAt("recent", 0, now)
+	tree.Insert(KDPoint[int]{ID: "new", Coords: []float64{5}})
+	if tree.DeleteByID("old") {
+		t.Fatal("expected the peer selected long ago to be evicted")
+	}
+	if !tree.DeleteByID("recent") {
+		t.Fatal("the recently selected peer should stay")
+	}
+}
+
+func TestPeerAnalyticsMaxTrackedPeers(t *testing.T) {
+	base := time.Now().UnixNano()
+	for _, c := range []struct {
+		policy EvictionPolicy
+		gone   string
+	}{
+		{EvictLRU, "b"},           // a was reselected last, b is the stalest
+		{EvictLeastSelected, "b"}, // a has 2 selections, b and c 1; b is older
+		{EvictOldest, "a"},        // a was tracked first
+	} {
+		pa := NewPeerAnalytics(WithMaxTrackedPeers(3, c.policy))
+		pa.recordAt("a", 1, base)
+		pa.recordAt("b", 1, base+1)
+		pa.recordAt("c", 1, base+2)
+		pa.recordAt("a", 1, base+3)
+		pa.recordAt("d", 1, base+4)
+		if pa.Len() != 3 || pa.Evicted() != 1 {
+			t.Fatalf("%s: expected 3 peers and 1 eviction, got %d and %d", c.policy, pa.Len(), pa.Evicted())
+		}
+		for _, id := range []string{"a", "b", "c", "d"} {
+			tracked := pa.GetPeerStats(id).SelectionCount > 0
+			if tracked == (id == c.gone) {
+				t.Errorf("%s: peer %s tracked=%v", c.policy, id, tracked)
+			}
+		}
+		if f := pa.fresh(); f.maxPeers != 3 || f.evictPolicy != c.policy {
+			t.Errorf("%s: fresh lost the cap", c.policy)
+		}
+		pa.Reset()
+		if pa.Evicted() != 0 {
+			t.Errorf("%s: Reset should clear the eviction count", c.policy)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package poindexter
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// Latency anomaly detection defaults (see WithLatencyAnomalyDetection).
+const (
+	// DefaultAnomalyAlpha is the EWMA smoothing factor: each query moves the
+	// latency baseline 1% of the way towards its own time.
+	DefaultAnomalyAlpha = 0.01
+	// DefaultAnomalyK is the band width in standard deviations above the
+	// baseline mean.
+	DefaultAnomalyK = 4.0
+)
+
+const (
+	// anomalyRun is how many consecutive queries must land outside (or back
+	// inside) the band to flip the anomalous state, so one slow query, say
+	// across a GC pause, does not.
+	anomalyRun = 5
+	// anomalyMinRelStdDev floors σ at this share of the mean, so a very
+	// steady baseline does not flag harmless jitter.
+	anomalyMinRelStdDev = 0.1
+)
+
+// LatencyAnomaly describes the query that tipped the tree into the anomalous
+// state, for Hooks.OnLatencyAnomaly.
+type LatencyAnomaly struct {
+	LatencyNs  int64   // the query's time
+	BaselineNs float64 // EWMA of query times before it
+	StdDevNs   float64 // exponentially weighted standard deviation
+}
+
+// latencyDetector flags query latency drifting above an EWMA baseline by more
+// than k standard deviations. The zero value is unconfigured; set alpha and k
+// before use.
+type latencyDetector struct {
+	alpha, k  float64
+	onAnomaly func(LatencyAnomaly)
+
+	mu        sync.Mutex
+	n         int64 // queries observed
+	mean      float64
+	variance  float64
+	run       int // consecutive queries disagreeing with the current state
+	anomalous atomic.Bool
+}
+
+// observe feeds one query time to the detector, firing onAnomaly when the
+// state flips to anomalous.
+func (d *latencyDetector) observe(ns int64) {
+	x := float64(ns)
+	var fire *LatencyAnomaly
+	d.mu.Lock()
+	d.n++
+	if d.n == 1 {
+		d.mean = x
+		d.mu.Unlock()
+		return
+	}
+	// Judge against the baseline only once it has seen about 1/alpha queries.
+	if float64(d.n) > 1/d.alpha {
+		sd := math.Max(math.Sqrt(d.variance), d.mean*anomalyMinRelStdDev)
+		upper := d.mean + d.k*sd
+		out := x > upper
+		if out {
+			// Learn from the band edge instead, so a lone spike cannot
+			// inflate σ enough to hide a real slowdown after it.
+			x = upper
+		}
+		if out != d.anomalous.Load() {
+			d.run++
+			if d.run >= anomalyRun {
+				d.anomalous.Store(out)
+				d.run = 0
+				if out {
+					fire = &LatencyAnomaly{LatencyNs: ns, BaselineNs: d.mean, StdDevNs: math.Sqrt(d.variance)}
+				}
+			}
+		} else {
+			d.run = 0
+		}
+	}
+	diff := x - d.mean
+	d.mean += d.alpha * diff
+	d.variance = (1 - d.alpha) * (d.variance + d.alpha*diff*diff)
+	d.mu.Unlock()
+	if fire != nil && d.onAnomaly != nil {
+		d.onAnomaly(*fire)
+	}
+}
+
+// baseline returns the EWMA mean and standard deviation of query times.
+func (d *latencyDetector) baseline() (mean, stdDev float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.mean, math.Sqrt(d.variance)
+}
+
+// reset clears the learned baseline, keeping the configuration.
+func (d *latencyDetector) reset() {
+	d.mu.Lock()
+	d.n, d.mean, d.variance, d.run = 0, 0, 0, 0
+	d.anomalous.Store(false)
+	d.mu.Unlock()
+}
+
+// copyFrom overwrites d's configuration and state with src's.
+func (d *latencyDetector) copyFrom(src *latencyDetector) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	d.alpha, d.k, d.onAnomaly = src.alpha, src.k, src.onAnomaly
+	d.n, d.mean, d.variance, d.run = src.n, src.mean, src.variance, src.run
+	d.anomalous.Store(src.anomalous.Load())
+}
+
+// Anomalous reports whether query latency is currently running above the
+// band around its baseline (see WithLatencyAnomalyDetection).
+func (a *TreeAnalytics) Anomalous() bool {
+	return a.anomaly.anomalous.Load()
+}
+
+// LatencyBaseline returns the exponentially weighted mean and standard
+// deviation of query times in nanoseconds that anomaly detection compares
+// each query against.
+func (a *TreeAnalytics) LatencyBaseline() (meanNs, stdDevNs float64) {
+	return a.anomaly.baseline()
+}
+
+// WithLatencyAnomalyDetection tunes the latency anomaly detector every tree
+// runs alongside its analytics. The detector keeps an exponentially weighted
+// mean and variance of query times, with smoothing factor alpha, and marks
+// the tree anomalous (TreeAnalyticsSnapshot.Anomalous) once several queries
+// in a row take longer than mean + k·σ. It clears after as many queries back
+// inside the band. Only slowdowns count. The baseline keeps adapting, learning
+// slow queries as if they sat on the band edge, so a lasting shift eventually
+// becomes the new normal. Out-of-range values keep DefaultAnomalyAlpha and
+// DefaultAnomalyK.
+func WithLatencyAnomalyDetection(alpha, k float64) KDOption {
+	return func(o *kdOptions) {
+		if alpha > 0 && alpha < 1 {
+			o.anomalyAlpha = alpha
+		}
+		if k > 0 {
+			o.anomalyK = k
+		}
+	}
+}
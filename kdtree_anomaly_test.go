@@ -0,0 +1,122 @@
+package poindexter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyDetector(t *testing.T) {
+	var fired []LatencyAnomaly
+	a := NewTreeAnalytics()
+	a.anomaly.onAnomaly = func(e LatencyAnomaly) { fired = append(fired, e) }
+
+	steady := []int64{1000, 1100, 900, 1050, 950}
+	for i := 0; i < 300; i++ {
+		a.RecordQuery(steady[i%len(steady)])
+	}
+	if a.Anomalous() || len(fired) != 0 {
+		t.Fatal("steady latency flagged as anomalous")
+	}
+	mean, sd := a.LatencyBaseline()
+	if mean < 950 || mean > 1050 || sd <= 0 || sd > 100 {
+		t.Fatalf("baseline %v ± %v", mean, sd)
+	}
+
+	// A single spike does not flip the state.
+	a.RecordQuery(50_000)
+	if a.Anomalous() {
+		t.Fatal("one slow query flagged as anomalous")
+	}
+	a.RecordQuery(1000)
+
+	for i := 0; i < anomalyRun; i++ {
+		a.RecordQuery(20_000)
+	}
+	if !a.Anomalous() || !a.Snapshot().Anomalous {
+		t.Fatal("sustained slowdown not flagged")
+	}
+	if len(fired) != 1 || fired[0].LatencyNs != 20_000 || fired[0].BaselineNs < 1000 {
+		t.Fatalf("hook calls %+v", fired)
+	}
+	for i := 0; i < 3; i++ {
+		a.RecordQuery(20_000)
+	}
+	if len(fired) != 1 {
+		t.Fatal("hook should fire once per episode")
+	}
+
+	for i := 0; i < anomalyRun; i++ {
+		a.RecordQuery(1000)
+	}
+	if a.Anomalous() {
+		t.Fatal("recovery not detected")
+	}
+
+	c := a.clone()
+	a.Reset()
+	if a.Anomalous() || a.anomaly.n != 0 || a.anomaly.k != DefaultAnomalyK {
+		t.Fatal("Reset should clear the baseline and keep the settings")
+	}
+	if c.anomaly.n == 0 || c.anomaly.onAnomaly == nil {
+		t.Fatal("clone should keep the baseline and hook")
+	}
+}
+
+func TestLatencyDetector_Warmup(t *testing.T) {
+	a := NewTreeAnalytics()
+	for i := 0; i < 20; i++ {
+		a.RecordQuery(int64(1000 * (i + 1) * (i + 1)))
+	}
+	if a.Anomalous() {
+		t.Fatal("flagged before the baseline warmed up")
+	}
+}
+
+func TestWithLatencyAnomalyDetection(t *testing.T) {
+	var fired int
+	tree, err := NewKDTree([]KDPoint[int]{{ID: "a", Coords: []float64{0}}},
+		WithLatencyAnomalyDetection(0.2, 2),
+		WithHooks(Hooks{OnLatencyAnomaly: func(LatencyAnomaly) { fired++ }}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := tree.Analytics()
+	if a.anomaly.alpha != 0.2 || a.anomaly.k != 2 || a.anomaly.onAnomaly == nil {
+		t.Fatalf("detector settings alpha=%v k=%v", a.anomaly.alpha, a.anomaly.k)
+	}
+	for i := 0; i < 20; i++ {
+		a.RecordQuery(int64(time.Microsecond))
+	}
+	for i := 0; i < anomalyRun; i++ {
+		a.RecordQuery(int64(time.Millisecond))
+	}
+	if fired != 1 || !tree.GetAnalyticsSnapshot().Anomalous {
+		t.Fatalf("hook fired %d times", fired)
+	}
+
+	c := tree.Clone(true)
+	if ca := c.Analytics(); ca.Anomalous() || ca.anomaly.alpha != 0.2 || ca.anomaly.onAnomaly == nil {
+		t.Fatal("reset clone should keep the detector settings")
+	}
+
+	def, _ := NewKDTree([]KDPoint[int]{{ID: "a", Coords: []float64{0}}}, WithLatencyAnomalyDetection(-1, 0))
+	if da := def.Analytics(); da.anomaly.alpha != DefaultAnomalyAlpha || da.anomaly.k != DefaultAnomalyK {
+		t.Fatal("non-positive settings should keep the defaults")
+	}
+}
+
+func TestTreeAnalyticsSnapshot_AnomalousRoundTrip(t *testing.T) {
+	s := TreeAnalyticsSnapshot{QueryCount: 3, Anomalous: true}
+	got, err := TreeAnalyticsSnapshotFromProto(s.ToProto())
+	if err != nil || !got.Anomalous || got.QueryCount != 3 {
+		t.Fatalf("proto: %+v, %v", got, err)
+	}
+	got, err = TreeAnalyticsSnapshotFromMsgpack(s.ToMsgpack())
+	if err != nil || !got.Anomalous || got.QueryCount != 3 {
+		t.Fatalf("msgpack: %+v, %v", got, err)
+	}
+	got, err = TreeAnalyticsSnapshotFromMsgpack(TreeAnalyticsSnapshot{}.ToMsgpack())
+	if err != nil || got.Anomalous {
+		t.Fatalf("msgpack false: %+v, %v", got, err)
+	}
+}
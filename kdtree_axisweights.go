@@ -0,0 +1,136 @@
+package poindexter
+
+import (
+	"math"
+	"time"
+)
+
+// NearestWeighted is Nearest with per-axis weights applied at query time:
+// distances are measured as if every coordinate on axis i, the query's
+// included, were multiplied by axisWeights[i], which is what rebuilding the
+// points through Build2D/3D/4D with those weights would give. Weights can
+// therefore change from one query to the next without touching the tree. A
+// weight of 0 ignores its axis. Point weights (WithPointWeight) and
+// WithStableTies apply as in Nearest. ok is false if the tree is empty, the
+// query or axisWeights do not match Dim(), or a weight is negative or not
+// finite.
+//
+// Indexed Euclidean, Manhattan and Chebyshev trees search within a radius
+// derived from the smallest weight, so near-zero weights make the query
+// approach a linear scan; other trees always scan.
+func (t *KDTree[T]) NearestWeighted(query, axisWeights []float64) (KDPoint[T], float64, bool) {
+	if v := t.cowView(); v != nil {
+		return v.NearestWeighted(query, axisWeights)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	if len(query) != t.dim || len(axisWeights) != t.dim || t.Len() == 0 {
+		return KDPoint[T]{}, 0, false
+	}
+	minW := math.Inf(1)
+	for _, w := range axisWeights {
+		if !(w >= 0) || math.IsInf(w, 1) {
+			return KDPoint[T]{}, 0, false
+		}
+		minW = min(minW, w)
+	}
+	start := t.queryStart()
+	defer func() {
+		if t.analytics != nil {
+			t.analytics.RecordQuery(time.Since(start).Nanoseconds())
+		}
+	}()
+
+	m := newAxisWeightedMetric(t.metric, axisWeights)
+	var best KDPoint[T]
+	bestDist := math.Inf(1)
+	found := false
+	visit := func(p KDPoint[T]) {
+		e := m.Distance(query, p.Coords) / t.weight(p)
+		if !found || e < bestDist || (t.stableTies && e == bestDist && p.ID < best.ID) {
+			best, bestDist, found = p, e, true
+		}
+	}
+	served := false
+	if ix := t.queryIndex(); ix != nil && minW > 0 && m.norm {
+		// any point bounds the best weighted distance from above; a point
+		// within that bound lies within bound·maxWeight/minW unweighted
+		if idx, _, ok := indexNearest[T](ix.data, query); ok && idx >= 0 && idx < len(ix.points) {
+			p := ix.points[idx]
+			maxW := 1.0
+			if t.weightOf != nil {
+				maxW = t.maxWeight
+			}
+			r := m.Distance(query, p.Coords) / t.weight(p) * maxW / minW * (1 + 1e-9)
+			served = indexRadiusEach[T](ix.data, query, r, func(idx int, _ float64) { visit(ix.points[idx]) })
+		}
+	}
+	if !served {
+		for _, p := range t.points {
+			visit(p)
+		}
+	}
+	if !found {
+		return KDPoint[T]{}, 0, false
+	}
+	t.recordResults(query, []KDPoint[T]{best}, []float64{bestDist})
+	return best, bestDist, true
+}
+
+// axisWeightedMetric measures base distances with every axis difference
+// scaled by its weight. It keeps scratch buffers, so it serves one query.
+type axisWeightedMetric struct {
+	base    DistanceMetric
+	periods PeriodicMetric // zero unless base is periodic
+	weights []float64
+	norm    bool // base is an (optionally periodic) Lp norm
+	sa, sb  []float64
+}
+
+func newAxisWeightedMetric(base DistanceMetric, weights []float64) *axisWeightedMetric {
+	m := &axisWeightedMetric{base: base, weights: weights}
+	if pm, ok := base.(PeriodicMetric); ok {
+		m.periods, m.base = pm, pm.Base
+		if m.base == nil {
+			m.base = EuclideanDistance{}
+		}
+	}
+	switch m.base.(type) {
+	case EuclideanDistance, ManhattanDistance, ChebyshevDistance:
+		m.norm = true
+	default:
+		m.sa, m.sb = make([]float64, len(weights)), make([]float64, len(weights))
+	}
+	return m
+}
+
+func (m *axisWeightedMetric) Distance(a, b []float64) float64 {
+	switch m.base.(type) {
+	case EuclideanDistance:
+		var sum float64
+		for i := range a {
+			d := m.weights[i] * m.periods.offset(a, b, i)
+			sum += d * d
+		}
+		return math.Sqrt(sum)
+	case ManhattanDistance:
+		var sum float64
+		for i := range a {
+			sum += math.Abs(m.weights[i] * m.periods.offset(a, b, i))
+		}
+		return sum
+	case ChebyshevDistance:
+		var mx float64
+		for i := range a {
+			mx = math.Max(mx, math.Abs(m.weights[i]*m.periods.offset(a, b, i)))
+		}
+		return mx
+	}
+	// other metrics see both points scaled, a moved to its closest image
+	for i := range a {
+		m.sa[i] = m.weights[i] * (b[i] + m.periods.offset(a, b, i))
+		m.sb[i] = m.weights[i] * b[i]
+	}
+	return m.base.Distance(m.sa, m.sb)
+}
@@ -0,0 +1,55 @@
+package poindexter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNearestWeightedMatchesScaledTree(t *testing.T) {
+	pts := makePoints(500, 3)
+	w := []float64{2, 0.5, 1}
+	scaled := make([]KDPoint[int], len(pts))
+	for i, p := range pts {
+		c := make([]float64, 3)
+		for j := range c {
+			c[j] = p.Coords[j] * w[j]
+		}
+		scaled[i] = KDPoint[int]{ID: p.ID, Coords: c}
+	}
+	for _, m := range []DistanceMetric{EuclideanDistance{}, ManhattanDistance{}, ChebyshevDistance{}, CosineDistance{}} {
+		ref, _ := NewKDTree(scaled, WithMetric(m), WithBackend(BackendLinear))
+		for _, b := range []KDBackend{BackendLinear, BackendGonum, BackendVPTree} {
+			tr, err := NewKDTree(pts, WithMetric(m), WithBackend(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, q := range [][]float64{{0.5, 0.5, 0.5}, {0.1, 0.9, 0.3}, {1, 0, 0.7}} {
+				sq := []float64{q[0] * w[0], q[1] * w[1], q[2] * w[2]}
+				wp, wd, _ := ref.Nearest(sq)
+				p, d, ok := tr.NearestWeighted(q, w)
+				if !ok || p.ID != wp.ID || math.Abs(d-wd) > 1e-12 {
+					t.Fatalf("%T/%s q=%v: %s at %v, want %s at %v", m, b, q, p.ID, d, wp.ID, wd)
+				}
+			}
+		}
+	}
+}
+
+func TestNearestWeightedArgs(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[int]{
+		{ID: "x", Coords: []float64{1, 0}},
+		{ID: "y", Coords: []float64{0, 2}},
+	}, WithPeriodicAxis(1, 2.5))
+	// y is 0.5 away across the wrap; weighting axis 1 by 3 makes x nearer
+	if p, d, _ := tr.NearestWeighted([]float64{0, 0}, []float64{1, 1}); p.ID != "y" || d != 0.5 {
+		t.Fatalf("unit weights: %s at %v, want y at 0.5", p.ID, d)
+	}
+	if p, d, _ := tr.NearestWeighted([]float64{0, 0}, []float64{1, 3}); p.ID != "x" || d != 1 {
+		t.Fatalf("weighted: %s at %v, want x at 1", p.ID, d)
+	}
+	for _, w := range [][]float64{{1}, {1, -1}, {1, math.NaN()}, {math.Inf(1), 1}} {
+		if _, _, ok := tr.NearestWeighted([]float64{0, 0}, w); ok {
+			t.Fatalf("weights %v accepted", w)
+		}
+	}
+}
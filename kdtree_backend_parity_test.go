@@ -127,3 +127,50 @@ func TestBackendParity_RandomQueries2D(t *testing.T) {
 		}
 	}
 }
+
+func TestBackendParity_KNearestStableTies(t *testing.T) {
+	pts := makeFixedPoints()
+	q := []float64{0.6, 0.6, 0.4, 0.4}
+	ks := []int{1, 2, 5, 10}
+	lin, _ := NewKDTree(pts, WithBackend(BackendLinear), WithMetric(EuclideanDistance{}), WithStableTies())
+	if hasGonum() {
+		gon, _ := NewKDTree(pts, WithBackend(BackendGonum), WithMetric(EuclideanDistance{}), WithStableTies())
+		for _, k := range ks {
+			ln, ld := lin.KNearest(q, k)
+			gn, gd := gon.KNearest(q, k)
+			if len(ln) != len(gn) || len(ld) != len(gd) {
+				t.Fatalf("k=%d length mismatch: linear (%d,%d) vs gonum (%d,%d)", k, len(ln), len(ld), len(gn), len(gd))
+			}
+			// WithStableTies breaks ties by ID, so the order must match exactly.
+			for i := range ln {
+				if ln[i].ID != gn[i].ID {
+					t.Errorf("k=%d index %d ID mismatch: linear=%s gonum=%s (dl=%.6f dg=%.6f)", k, i, ln[i].ID, gn[i].ID, ld[i], gd[i])
+				}
+			}
+		}
+	}
+}
+
+func TestBackendParity_RandomQueries2DStableTies(t *testing.T) {
+	// An integer grid under Manhattan distance, so most queries hit ties.
+	pts := gridPoints(8)
+	lin, _ := NewKDTree(pts, WithBackend(BackendLinear), WithMetric(ManhattanDistance{}), WithStableTies())
+	if hasGonum() {
+		gon, _ := NewKDTree(pts, WithBackend(BackendGonum), WithMetric(ManhattanDistance{}), WithStableTies())
+		rng := rand.New(rand.NewSource(42))
+		for i := 0; i < 50; i++ {
+			q := []float64{float64(rng.Intn(16)) / 2, float64(rng.Intn(16)) / 2}
+			pl, dl, okl := lin.Nearest(q)
+			pg, dg, okg := gon.Nearest(q)
+			if okl != okg {
+				t.Fatalf("ok mismatch (2D rand)")
+			}
+			if !okl {
+				continue
+			}
+			if pl.ID != pg.ID {
+				t.Errorf("2D rand nearest mismatch: linear %s(%.6f) gonum %s(%.6f)", pl.ID, dl, pg.ID, dg)
+			}
+		}
+	}
+}
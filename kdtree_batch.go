@@ -0,0 +1,46 @@
+package poindexter
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// KNearestBatch runs KNearest for every query on a pool of workers goroutines
+// and returns results aligned with queries: pts[i] and dists[i] answer
+// queries[i], and are nil where KNearest would return nil (for example a
+// query of the wrong dimension). workers <= 0 uses GOMAXPROCS. Queries are
+// handed out one at a time, so a slow query does not hold up a whole share.
+//
+// Each query is recorded in analytics as a KNearest call. With
+// WithCopyOnWrite the whole batch runs against the view published when it
+// started; otherwise, as for any query, the tree must not be mutated until
+// KNearestBatch returns.
+func (t *KDTree[T]) KNearestBatch(queries [][]float64, k, workers int) ([][]KDPoint[T], [][]float64) {
+	if v := t.cowView(); v != nil {
+		return v.KNearestBatch(queries, k, workers)
+	}
+	pts := make([][]KDPoint[T], len(queries))
+	dists := make([][]float64, len(queries))
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(queries))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(queries) {
+					return
+				}
+				pts[i], dists[i] = t.KNearest(queries[i], k)
+			}
+		}()
+	}
+	wg.Wait()
+	return pts, dists
+}
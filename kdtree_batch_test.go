@@ -0,0 +1,86 @@
+package poindexter
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestKNearestBatchMatchesKNearest(t *testing.T) {
+	rng := rand.New(rand.NewSource(49))
+	pts := make([]KDPoint[int], 500)
+	for i := range pts {
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{rng.Float64(), rng.Float64(), rng.Float64()}, Value: i}
+	}
+	queries := make([][]float64, 200)
+	for i := range queries {
+		queries[i] = []float64{rng.Float64(), rng.Float64(), rng.Float64()}
+	}
+	queries[7] = []float64{1, 2} // wrong dimension
+	for _, b := range []KDBackend{BackendLinear, BackendVPTree} {
+		tr, err := NewKDTree(pts, WithBackend(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, workers := range []int{0, 1, 3, 1000} {
+			got, dists := tr.KNearestBatch(queries, 5, workers)
+			if len(got) != len(queries) || len(dists) != len(queries) {
+				t.Fatalf("%s/%d: got %d/%d results for %d queries", b, workers, len(got), len(dists), len(queries))
+			}
+			for i, q := range queries {
+				want, wantD := tr.KNearest(q, 5)
+				if !reflect.DeepEqual(dists[i], wantD) || len(got[i]) != len(want) {
+					t.Fatalf("%s/%d: query %d: dists %v, want %v", b, workers, i, dists[i], wantD)
+				}
+			}
+			if got[7] != nil || dists[7] != nil {
+				t.Fatalf("%s/%d: mismatched query returned results", b, workers)
+			}
+		}
+	}
+}
+
+func TestKNearestBatchAnalyticsAndEmpty(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[int]{{ID: "a", Coords: []float64{0}}, {ID: "b", Coords: []float64{1}}})
+	if pts, dists := tr.KNearestBatch(nil, 1, 4); len(pts) != 0 || len(dists) != 0 {
+		t.Fatalf("empty batch returned %v %v", pts, dists)
+	}
+	queries := [][]float64{{0.1}, {0.9}, {0.4}}
+	pts, _ := tr.KNearestBatch(queries, 1, 2)
+	if pts[0][0].ID != "a" || pts[1][0].ID != "b" || pts[2][0].ID != "a" {
+		t.Fatalf("misaligned results: %v", pts)
+	}
+	if n := tr.GetAnalyticsSnapshot().QueryCount; n != 3 {
+		t.Fatalf("QueryCount = %d, want 3", n)
+	}
+}
+
+func TestKNearestBatchCopyOnWrite(t *testing.T) {
+	tr, _ := NewKDTreeFromDim[int](2, WithCopyOnWrite())
+	for i := range 100 {
+		tr.Insert(KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{float64(i), 0}})
+	}
+	queries := make([][]float64, 64)
+	for i := range queries {
+		queries[i] = []float64{float64(i), 0}
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 100; i < 300; i++ {
+			tr.Insert(KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{float64(i), 1}})
+		}
+	}()
+	for range 20 {
+		pts, _ := tr.KNearestBatch(queries, 3, 4)
+		for i := range queries {
+			if len(pts[i]) != 3 || pts[i][0].ID != fmt.Sprint(i) {
+				t.Fatalf("query %d: %v", i, pts[i])
+			}
+		}
+	}
+	wg.Wait()
+}
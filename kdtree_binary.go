@@ -0,0 +1,312 @@
+package poindexter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Binary snapshot layout (all integers little-endian):
+//
+//	magic   [4]byte  "PDXB"
+//	version uint16
+//	flags   uint16   bit 0: values present; bit 1: labels present (version 2); bit 2: periods present (version 3)
+//	dim     uint32
+//	count   uint64
+//	backend uvarint length + bytes
+//	metric  uvarint length + bytes
+//	weights uvarint count + float64s (WeightedCosineDistance only)
+//	periods uvarint count + float64s, one per axis, 0 if it does not wrap (if flagged)
+//	points  count × { id: uvarint length + bytes; coords: dim × float64; value: uvarint length + bytes (if flagged);
+//	                  labels: uvarint count + count × key/value uvarint length + bytes, sorted by key (if flagged) }
+//
+// Readers must reject versions newer than they understand; new fields should be
+// appended behind a version bump so older snapshots stay readable. Writers emit
+// the lowest version covering the fields present (2 when some point has
+// labels, 3 when the tree has periodic axes), so snapshots without them remain
+// readable by version 1 readers. Readers also reject headers declaring more
+// than maxSnapshotDim dimensions or more than maxSnapshotCoords coordinates in
+// total, so a corrupt header cannot force an allocation large enough to
+// exhaust memory.
+const (
+	binaryMagic               = "PDXB"
+	binarySnapshotVer         = 1
+	binarySnapshotVerLabelled = 2
+	binarySnapshotVerPeriodic = 3
+	binaryFlagHasValues       = 1 << 0
+	binaryFlagHasLabels       = 1 << 1
+	binaryFlagHasPeriods      = 1 << 2
+)
+
+// ErrInvalidSnapshot indicates binary snapshot data is malformed or truncated.
+var ErrInvalidSnapshot = errors.New("kdtree: invalid binary snapshot")
+
+// ValueCodec converts point payloads to and from bytes for binary snapshots.
+type ValueCodec[T any] struct {
+	Encode func(T) ([]byte, error)
+	Decode func([]byte) (T, error)
+}
+
+// StringValueCodec stores string payloads as raw bytes.
+func StringValueCodec() *ValueCodec[string] {
+	return &ValueCodec[string]{
+		Encode: func(s string) ([]byte, error) { return []byte(s), nil },
+		Decode: func(b []byte) (string, error) { return string(b), nil },
+	}
+}
+
+// WriteBinary writes a compact binary snapshot of the tree to w. It is intended
+// for very large trees where JSON is too slow and too large. If codec is nil,
+// payload values are omitted and decode as the zero value of T. Point labels
+// are always stored. Trees using a custom DistanceMetric return
+// ErrUnknownMetric.
+func (t *KDTree[T]) WriteBinary(w io.Writer, codec *ValueCodec[T]) error {
+	name, weights, periods, err := serialMetric(t.metric)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	var flags uint16
+	if codec != nil && codec.Encode != nil {
+		flags |= binaryFlagHasValues
+	}
+	ver := uint16(binarySnapshotVer)
+	for _, p := range t.points {
+		if len(p.Labels) > 0 {
+			flags |= binaryFlagHasLabels
+			ver = binarySnapshotVerLabelled
+			break
+		}
+	}
+	if periods != nil {
+		flags |= binaryFlagHasPeriods
+		ver = binarySnapshotVerPeriodic
+	}
+	buf := make([]byte, 0, 64+8*t.dim)
+	buf = append(buf, binaryMagic...)
+	buf = binary.LittleEndian.AppendUint16(buf, ver)
+	buf = binary.LittleEndian.AppendUint16(buf, flags)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(t.dim))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(t.points)))
+	buf = appendBytes(buf, []byte(t.backend))
+	buf = appendBytes(buf, []byte(name))
+	buf = appendFloats(buf, weights)
+	if flags&binaryFlagHasPeriods != 0 {
+		buf = appendFloats(buf, periods)
+	}
+	if _, err := bw.Write(buf); err != nil {
+		return err
+	}
+	for _, p := range t.points {
+		buf = appendBytes(buf[:0], []byte(p.ID))
+		for _, c := range p.Coords {
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(c))
+		}
+		if flags&binaryFlagHasValues != 0 {
+			vb, err := codec.Encode(p.Value)
+			if err != nil {
+				return fmt.Errorf("kdtree: encode value for %q: %w", p.ID, err)
+			}
+			buf = appendBytes(buf, vb)
+		}
+		if flags&binaryFlagHasLabels != 0 {
+			buf = binary.AppendUvarint(buf, uint64(len(p.Labels)))
+			for _, k := range sortedLabelKeys(p.Labels) {
+				buf = appendBytes(buf, []byte(k))
+				buf = appendBytes(buf, []byte(p.Labels[k]))
+			}
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadKDTreeBinary reads a snapshot written by WriteBinary. codec must be able
+// to decode the stored values; if the snapshot carries values and codec is nil,
+// the values are skipped and left as the zero value of T.
+func ReadKDTreeBinary[T any](r io.Reader, codec *ValueCodec[T]) (*KDTree[T], error) {
+	br := bufio.NewReader(r)
+	var hdr [4 + 2 + 2 + 4 + 8]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, snapshotErr(err)
+	}
+	if string(hdr[:4]) != binaryMagic {
+		return nil, ErrInvalidSnapshot
+	}
+	ver := binary.LittleEndian.Uint16(hdr[4:])
+	if ver > binarySnapshotVerPeriodic {
+		return nil, ErrUnsupportedVersion
+	}
+	flags := binary.LittleEndian.Uint16(hdr[6:])
+	if flags&binaryFlagHasLabels != 0 && ver < binarySnapshotVerLabelled ||
+		flags&binaryFlagHasPeriods != 0 && ver < binarySnapshotVerPeriodic {
+		return nil, ErrInvalidSnapshot
+	}
+	dim := int(binary.LittleEndian.Uint32(hdr[8:]))
+	count := binary.LittleEndian.Uint64(hdr[12:])
+	if dim <= 0 {
+		return nil, ErrZeroDim
+	}
+	if dim > maxSnapshotDim || count > maxSnapshotCoords/uint64(dim) {
+		return nil, ErrInvalidSnapshot
+	}
+	backend, err := readBytes(br)
+	if err != nil {
+		return nil, err
+	}
+	metric, err := readBytes(br)
+	if err != nil {
+		return nil, err
+	}
+	weights, err := readFloats(br, dim)
+	if err != nil {
+		return nil, err
+	}
+	var periods []float64
+	if flags&binaryFlagHasPeriods != 0 {
+		if periods, err = readFloats(br, dim); err != nil {
+			return nil, err
+		}
+	}
+
+	// Guard against absurd counts from corrupt headers before allocating.
+	const maxPrealloc = 1 << 20
+	pts := make([]KDPoint[T], 0, min(count, maxPrealloc))
+	coordBuf := make([]byte, 8*dim)
+	for i := uint64(0); i < count; i++ {
+		id, err := readBytes(br)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(br, coordBuf); err != nil {
+			return nil, snapshotErr(err)
+		}
+		coords := make([]float64, dim)
+		for d := range coords {
+			coords[d] = math.Float64frombits(binary.LittleEndian.Uint64(coordBuf[8*d:]))
+		}
+		p := KDPoint[T]{ID: string(id), Coords: coords}
+		if flags&binaryFlagHasValues != 0 {
+			vb, err := readBytes(br)
+			if err != nil {
+				return nil, err
+			}
+			if codec != nil && codec.Decode != nil {
+				if p.Value, err = codec.Decode(vb); err != nil {
+					return nil, fmt.Errorf("kdtree: decode value for %q: %w", p.ID, err)
+				}
+			}
+		}
+		if flags&binaryFlagHasLabels != 0 {
+			if p.Labels, err = readLabels(br); err != nil {
+				return nil, err
+			}
+		}
+		pts = append(pts, p)
+	}
+	return newKDTreeFromSnapshot(dim, pts, string(metric), weights, KDBackend(backend), periodicOptions(periods)...)
+}
+
+// appendBytes appends a uvarint length prefix followed by b.
+func appendBytes(dst, b []byte) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(b)))
+	return append(dst, b...)
+}
+
+// appendFloats appends a uvarint count followed by the float64s in vs.
+func appendFloats(dst []byte, vs []float64) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(vs)))
+	for _, v := range vs {
+		dst = binary.LittleEndian.AppendUint64(dst, math.Float64bits(v))
+	}
+	return dst
+}
+
+// readFloats reads a uvarint count, at most max, followed by that many float64s.
+func readFloats(br *bufio.Reader, max int) ([]float64, error) {
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, snapshotErr(err)
+	}
+	if n > uint64(max) {
+		return nil, ErrInvalidSnapshot
+	}
+	vs := make([]float64, n)
+	var f [8]byte
+	for i := range vs {
+		if _, err := io.ReadFull(br, f[:]); err != nil {
+			return nil, snapshotErr(err)
+		}
+		vs[i] = math.Float64frombits(binary.LittleEndian.Uint64(f[:]))
+	}
+	return vs, nil
+}
+
+// maxSnapshotField bounds a single length-prefixed field (ID or value) so a
+// corrupt length cannot trigger a huge allocation.
+const maxSnapshotField = 64 << 20
+
+// maxSnapshotDim bounds the dimension a binary snapshot may declare: one
+// point's coordinates may take at most maxSnapshotField bytes.
+const maxSnapshotDim = maxSnapshotField / 8
+
+// maxSnapshotCoords bounds count × dim in a binary snapshot header (32 GiB of
+// coordinates).
+const maxSnapshotCoords = 1 << 32
+
+// readBytes reads a uvarint length-prefixed byte string.
+func readBytes(br *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, snapshotErr(err)
+	}
+	if n > maxSnapshotField {
+		return nil, ErrInvalidSnapshot
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(br, b); err != nil {
+		return nil, snapshotErr(err)
+	}
+	return b, nil
+}
+
+// readLabels reads a uvarint count of key/value byte-string pairs. A count of
+// zero yields nil labels.
+func readLabels(br *bufio.Reader) (map[string]string, error) {
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, snapshotErr(err)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	if n > maxSnapshotField {
+		return nil, ErrInvalidSnapshot
+	}
+	labels := make(map[string]string, min(n, 64))
+	for i := uint64(0); i < n; i++ {
+		k, err := readBytes(br)
+		if err != nil {
+			return nil, err
+		}
+		v, err := readBytes(br)
+		if err != nil {
+			return nil, err
+		}
+		labels[string(k)] = string(v)
+	}
+	return labels, nil
+}
+
+// snapshotErr maps truncation errors to ErrInvalidSnapshot and passes others through.
+func snapshotErr(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrInvalidSnapshot
+	}
+	return err
+}
@@ -0,0 +1,150 @@
+package poindexter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestKDTreeBinary_RoundTrip(t *testing.T) {
+	pts := makeUniformPoints(500, 3)
+	for i := range pts {
+		pts[i].Value = i * 7
+	}
+	tr, _ := NewKDTree(pts, WithMetric(ChebyshevDistance{}))
+	codec := &ValueCodec[int]{
+		Encode: func(v int) ([]byte, error) { return []byte{byte(v), byte(v >> 8)}, nil },
+		Decode: func(b []byte) (int, error) { return int(b[0]) | int(b[1])<<8, nil },
+	}
+	var buf bytes.Buffer
+	if err := tr.WriteBinary(&buf, codec); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got, err := ReadKDTreeBinary(&buf, codec)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if got.Len() != tr.Len() || got.Dim() != 3 || got.Backend() != tr.Backend() {
+		t.Fatalf("mismatch: len %d/%d dim %d backend %s", got.Len(), tr.Len(), got.Dim(), got.Backend())
+	}
+	if _, ok := got.metric.(ChebyshevDistance); !ok {
+		t.Fatalf("metric not restored: %T", got.metric)
+	}
+	for i, p := range got.Points() {
+		want := tr.points[i]
+		if p.ID != want.ID || p.Value != want.Value || p.Coords[2] != want.Coords[2] {
+			t.Fatalf("point %d mismatch: %+v vs %+v", i, p, want)
+		}
+	}
+}
+
+func TestKDTreeBinary_NoValuesAndStrings(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[string]{
+		{ID: "a", Coords: []float64{1, 0}, Value: "alpha"},
+		{ID: "b", Coords: []float64{0, 1}, Value: "beta"},
+	}, WithMetric(WeightedCosineDistance{Weights: []float64{2, 1}}))
+	var withVals, noVals bytes.Buffer
+	if err := tr.WriteBinary(&withVals, StringValueCodec()); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.WriteBinary(&noVals, nil); err != nil {
+		t.Fatal(err)
+	}
+	if noVals.Len() >= withVals.Len() {
+		t.Fatalf("expected snapshot without values to be smaller")
+	}
+	got, err := ReadKDTreeBinary(&withVals, StringValueCodec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _, _ := got.Nearest([]float64{0, 2}); p.Value != "beta" {
+		t.Fatalf("want beta, got %q", p.Value)
+	}
+	got, err = ReadKDTreeBinary[string](&noVals, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _, _ := got.Nearest([]float64{0, 2}); p.Value != "" || p.ID != "b" {
+		t.Fatalf("want empty value for b, got %+v", p)
+	}
+}
+
+func TestKDTreeBinary_Labels(t *testing.T) {
+	pts := labelledPoints(30)
+	pts[1].Labels = nil
+	tr, _ := NewKDTree(pts)
+	var buf bytes.Buffer
+	if err := tr.WriteBinary(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if v := binary.LittleEndian.Uint16(buf.Bytes()[4:]); v != binarySnapshotVerLabelled {
+		t.Fatalf("labelled snapshot written as version %d", v)
+	}
+	got, err := ReadKDTreeBinary[int](&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range got.Points() {
+		if !reflect.DeepEqual(p.Labels, pts[i].Labels) {
+			t.Fatalf("point %s labels %v, want %v", p.ID, p.Labels, pts[i].Labels)
+		}
+	}
+	if ps, _ := got.KNearest([]float64{0, 0}, 30, WithLabelSelector("region=eu", "relay")); len(ps) != 3 {
+		t.Fatalf("selector after round trip matched %d points, want 3", len(ps))
+	}
+
+	// Unlabelled trees keep writing version 1.
+	plain, _ := NewKDTree(makeUniformPoints(5, 2))
+	buf.Reset()
+	_ = plain.WriteBinary(&buf, nil)
+	if v := binary.LittleEndian.Uint16(buf.Bytes()[4:]); v != binarySnapshotVer {
+		t.Fatalf("unlabelled snapshot written as version %d", v)
+	}
+
+	// A labels flag on a version 1 header is corrupt.
+	raw := buf.Bytes()
+	raw[6] |= binaryFlagHasLabels
+	if _, err := ReadKDTreeBinary[int](bytes.NewReader(raw), nil); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatalf("want ErrInvalidSnapshot for labels flag on v1, got %v", err)
+	}
+}
+
+func TestKDTreeBinary_Errors(t *testing.T) {
+	if _, err := ReadKDTreeBinary[int](bytes.NewReader([]byte("NOPE0000000000000000")), nil); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatalf("want ErrInvalidSnapshot for bad magic, got %v", err)
+	}
+	tr, _ := NewKDTree(makeUniformPoints(10, 2))
+	var buf bytes.Buffer
+	_ = tr.WriteBinary(&buf, nil)
+	raw := buf.Bytes()
+	if _, err := ReadKDTreeBinary[int](bytes.NewReader(raw[:len(raw)-3]), nil); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatalf("want ErrInvalidSnapshot for truncation, got %v", err)
+	}
+	future := append([]byte(nil), raw...)
+	future[4] = 0xFF
+	if _, err := ReadKDTreeBinary[int](bytes.NewReader(future), nil); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("want ErrUnsupportedVersion, got %v", err)
+	}
+	// Corrupt headers must not allocate by the declared sizes.
+	for name, c := range map[string]struct {
+		dim   uint32
+		count uint64
+	}{
+		"huge dim":   {0xFFFFFFF0, 1},
+		"huge total": {1024, 1 << 40},
+	} {
+		hdr := append([]byte(binaryMagic), 1, 0, 0, 0)
+		hdr = binary.LittleEndian.AppendUint32(hdr, c.dim)
+		hdr = binary.LittleEndian.AppendUint64(hdr, c.count)
+		hdr = append(hdr, 0)
+		if _, err := ReadKDTreeBinary[int](bytes.NewReader(hdr), nil); !errors.Is(err, ErrInvalidSnapshot) {
+			t.Fatalf("%s: want ErrInvalidSnapshot, got %v", name, err)
+		}
+	}
+	custom, _ := NewKDTreeFromDim[int](1, WithMetric(customMetric{}))
+	if err := custom.WriteBinary(&buf, nil); !errors.Is(err, ErrUnknownMetric) {
+		t.Fatalf("want ErrUnknownMetric, got %v", err)
+	}
+}
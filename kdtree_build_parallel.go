@@ -0,0 +1,96 @@
+package poindexter
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// buildParallelChunk is the number of items a BuildNDParallel worker takes at
+// a time.
+const buildParallelChunk = 256
+
+// BuildNDParallel is BuildND with feature extraction spread over a pool of
+// workers goroutines, for datasets of millions of items where calling the
+// extractors dominates load time. Every numeric and categorical extractor
+// runs exactly once per item (BuildND calls each twice: once for the stats,
+// once for the coordinates), so extractors must be safe to call
+// concurrently. The points, their order and any error are identical to
+// BuildND's. workers <= 0 uses GOMAXPROCS.
+//
+// The extracted values are held in memory until the points are built: 8
+// bytes per item per numeric feature, plus one string per item per
+// categorical feature.
+func BuildNDParallel[T any](items []T, id func(T) string, features []func(T) float64, weights []float64, invert []bool, workers int, opts ...BuildOption) ([]KDPoint[T], error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	if len(features) == 0 {
+		return nil, ErrInvalidFeatures
+	}
+	if len(weights) != len(features) {
+		return nil, ErrInvalidWeights
+	}
+	if len(invert) != len(features) {
+		return nil, ErrInvalidInvert
+	}
+	cfg := applyBuildOptions(opts)
+	cats, err := resolveCategorical[T](cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range features {
+		if f == nil {
+			return nil, ErrInvalidFeatures
+		}
+	}
+	if _, err := cfg.axisTransforms(len(features)); err != nil {
+		return nil, err
+	}
+	src := extractParallel(items, features, cats, workers)
+	stats, err := cfg.normStats(len(items), len(features), len(cats), src)
+	if err != nil {
+		return nil, err
+	}
+	return buildNDFrom(items, id, weights, invert, stats, cfg, len(cats), src)
+}
+
+// extractParallel calls every extractor once per item on a pool of workers
+// goroutines and returns a source serving the stored values.
+func extractParallel[T any](items []T, features []func(T) float64, cats []func(T) string, workers int) ndSource {
+	nf, nc := len(features), len(cats)
+	nums := make([]float64, len(items)*nf)
+	labels := make([]string, len(items)*nc)
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, (len(items)+buildParallelChunk-1)/buildParallelChunk)
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				lo := int(next.Add(1)-1) * buildParallelChunk
+				if lo >= len(items) {
+					return
+				}
+				for j := lo; j < min(lo+buildParallelChunk, len(items)); j++ {
+					it := items[j]
+					for d, f := range features {
+						nums[j*nf+d] = f(it)
+					}
+					for c, fn := range cats {
+						labels[j*nc+c] = fn(it)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return ndSource{
+		num: func(j, d int) float64 { return nums[j*nf+d] },
+		cat: func(j, c int) string { return labels[j*nc+c] },
+	}
+}
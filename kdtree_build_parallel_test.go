@@ -0,0 +1,65 @@
+package poindexter
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBuildNDParallelMatchesBuildND(t *testing.T) {
+	type rec struct {
+		id         string
+		ping, loss float64
+		region     string
+	}
+	var items []rec
+	for i := range 5000 {
+		r := rec{fmt.Sprint(i), float64(i%977) * 1.5, float64(i%13) / 13, []string{"eu", "us", "ap"}[i%3]}
+		if i%101 == 0 {
+			r.loss = math.NaN()
+		}
+		items = append(items, r)
+	}
+	var calls atomic.Int64
+	ping := func(r rec) float64 { calls.Add(1); return r.ping }
+	loss := func(r rec) float64 { calls.Add(1); return r.loss }
+	id := func(r rec) string { return r.id }
+	features := []func(rec) float64{ping, loss}
+	opts := []BuildOption{
+		WithNormalization(Robust),
+		WithAxisTransform(0, Log1p),
+		WithMissingValuePolicy(MissingDrop),
+		WithCategoricalFeature(func(r rec) string { return r.region }, 0.5),
+	}
+	want, err := BuildND(items, id, features, []float64{1, 2}, []bool{false, true}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{1, 4, 0} {
+		calls.Store(0)
+		got, err := BuildNDParallel(items, id, features, []float64{1, 2}, []bool{false, true}, workers, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("workers=%d: points differ from BuildND", workers)
+		}
+		if n := calls.Load(); n != int64(2*len(items)) {
+			t.Fatalf("workers=%d: %d extractor calls, want %d", workers, n, 2*len(items))
+		}
+	}
+
+	_, wantErr := BuildND(items, id, features, []float64{1, 1}, []bool{false, false}, WithMissingValuePolicy(MissingError))
+	_, gotErr := BuildNDParallel(items, id, features, []float64{1, 1}, []bool{false, false}, 3, WithMissingValuePolicy(MissingError))
+	if gotErr == nil || gotErr.Error() != wantErr.Error() {
+		t.Fatalf("err = %v, want %v", gotErr, wantErr)
+	}
+	if _, err := BuildNDParallel(items, id, features, []float64{1}, []bool{false, false}, 2); err != ErrInvalidWeights {
+		t.Fatalf("short weights: err = %v", err)
+	}
+	if pts, err := BuildNDParallel(nil, id, features, []float64{1, 1}, []bool{false, false}, 2); pts != nil || err != nil {
+		t.Fatalf("no items: %v, %v", pts, err)
+	}
+}
@@ -0,0 +1,84 @@
+package poindexter
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrCategoricalType indicates a WithCategoricalFeature function does not
+// take the builder's item type.
+var ErrCategoricalType = errors.New("kdtree: categorical feature type does not match items")
+
+// EncodeCategorical one-hot encodes values: labels holds the distinct values
+// in sorted order, and coords[i] has a 1 in the column of values[i]'s label
+// and 0 elsewhere. Two different categories are √2 apart under the Euclidean
+// metric and 2 under Manhattan; identical ones coincide.
+func EncodeCategorical(values []string) (coords [][]float64, labels []string) {
+	labels = categoryLabels(values)
+	col := categoryColumns(labels)
+	coords = make([][]float64, len(values))
+	for i, v := range values {
+		coords[i] = make([]float64, len(labels))
+		coords[i][col[v]] = 1
+	}
+	return coords, labels
+}
+
+// categoryLabels returns the distinct values, sorted.
+func categoryLabels(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	labels := make([]string, 0)
+	for _, v := range values {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			labels = append(labels, v)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+func categoryColumns(labels []string) map[string]int {
+	col := make(map[string]int, len(labels))
+	for i, l := range labels {
+		col[l] = i
+	}
+	return col
+}
+
+type categoricalFeature struct {
+	fn     any // func(T) string
+	weight float64
+}
+
+// WithCategoricalFeature adds a categorical feature, such as NAT type or
+// region, to BuildND, BuildNDNoErr, BuildNDWithStats and ComputeNormStatsND.
+// Each one-hot encodes its values as in EncodeCategorical and appends the
+// columns, scaled by weight, after the numeric features, in the order the
+// options are given. ComputeNormStatsND records each feature's labels in
+// NormStats.Categories; BuildNDWithStats needs the same options again and
+// encodes a value missing from the recorded labels as all zeros. Functions
+// for another item type yield ErrCategoricalType. The fixed-dimension
+// Build2D/3D/4D helpers ignore it.
+func WithCategoricalFeature[T any](category func(T) string, weight float64) BuildOption {
+	return func(o *buildOptions) {
+		o.categorical = append(o.categorical, categoricalFeature{fn: category, weight: weight})
+	}
+}
+
+// resolveCategorical asserts the WithCategoricalFeature functions against the
+// builder's item type.
+func resolveCategorical[T any](cfg buildOptions) ([]func(T) string, error) {
+	if len(cfg.categorical) == 0 {
+		return nil, nil
+	}
+	fns := make([]func(T) string, len(cfg.categorical))
+	for i, c := range cfg.categorical {
+		fn, ok := c.fn.(func(T) string)
+		if !ok || fn == nil {
+			return nil, ErrCategoricalType
+		}
+		fns[i] = fn
+	}
+	return fns, nil
+}
@@ -0,0 +1,72 @@
+package poindexter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeCategorical(t *testing.T) {
+	coords, labels := EncodeCategorical([]string{"symmetric", "full-cone", "symmetric", "none"})
+	if !reflect.DeepEqual(labels, []string{"full-cone", "none", "symmetric"}) {
+		t.Fatalf("labels = %v", labels)
+	}
+	want := [][]float64{{0, 0, 1}, {1, 0, 0}, {0, 0, 1}, {0, 1, 0}}
+	if !reflect.DeepEqual(coords, want) {
+		t.Fatalf("coords = %v, want %v", coords, want)
+	}
+	if coords, labels := EncodeCategorical(nil); len(coords) != 0 || len(labels) != 0 {
+		t.Fatalf("empty input: %v %v", coords, labels)
+	}
+}
+
+type catPeer struct {
+	id, nat, region string
+	ping            float64
+}
+
+func TestBuildNDCategorical(t *testing.T) {
+	peers := []catPeer{
+		{"a", "open", "eu", 10},
+		{"b", "symmetric", "us", 30},
+		{"c", "open", "us", 20},
+	}
+	id := func(p catPeer) string { return p.id }
+	features := []func(catPeer) float64{func(p catPeer) float64 { return p.ping }}
+	opts := []BuildOption{
+		WithCategoricalFeature(func(p catPeer) string { return p.nat }, 0.5),
+		WithCategoricalFeature(func(p catPeer) string { return p.region }, 2),
+	}
+	pts, err := BuildND(peers, id, features, []float64{1}, []bool{false}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ping, then nat (open, symmetric), then region (eu, us)
+	want := [][]float64{{0, 0.5, 0, 2, 0}, {1, 0, 0.5, 0, 2}, {0.5, 0.5, 0, 0, 2}}
+	for i, p := range pts {
+		if !reflect.DeepEqual(p.Coords, want[i]) {
+			t.Fatalf("point %s coords = %v, want %v", p.ID, p.Coords, want[i])
+		}
+	}
+
+	stats, err := ComputeNormStatsND(peers, features, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stats.Categories, [][]string{{"open", "symmetric"}, {"eu", "us"}}) {
+		t.Fatalf("categories = %v", stats.Categories)
+	}
+	// a later peer with an unseen region gets no region column set
+	later, err := BuildNDWithStats([]catPeer{{"d", "symmetric", "ap", 20}}, id, features, []float64{1}, []bool{false}, stats, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(later[0].Coords, []float64{0.5, 0, 0.5, 0, 0}) {
+		t.Fatalf("later coords = %v", later[0].Coords)
+	}
+	if _, err := BuildNDWithStats(peers, id, features, []float64{1}, []bool{false}, stats); err != ErrStatsDimMismatch {
+		t.Fatalf("missing categorical options: err = %v, want ErrStatsDimMismatch", err)
+	}
+	if _, err := BuildND(peers, id, features, []float64{1}, []bool{false}, WithCategoricalFeature(func(s string) string { return s }, 1)); err != ErrCategoricalType {
+		t.Fatalf("wrong item type: err = %v, want ErrCategoricalType", err)
+	}
+}
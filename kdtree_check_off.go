@@ -0,0 +1,6 @@
+//go:build !pxcheck
+
+package poindexter
+
+// defaultConcurrencyChecks is false unless built with the "pxcheck" tag.
+const defaultConcurrencyChecks = false
@@ -0,0 +1,7 @@
+//go:build pxcheck
+
+package poindexter
+
+// defaultConcurrencyChecks enables WithConcurrencyChecks for every tree when
+// built with the "pxcheck" tag.
+const defaultConcurrencyChecks = true
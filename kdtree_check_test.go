@@ -0,0 +1,62 @@
+package poindexter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func expectConcurrentUse(t *testing.T, want string, fn func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		r := recover()
+		err, ok := r.(error)
+		if !ok || !errors.Is(err, ErrConcurrentUse) || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected ErrConcurrentUse panic containing %q, got %v", want, r)
+		}
+	}()
+	fn()
+}
+
+func TestConcurrencyChecks(t *testing.T) {
+	newTree := func(opts ...KDOption) *KDTree[int] {
+		tr, err := NewKDTree([]KDPoint[int]{
+			{ID: "a", Coords: []float64{0}},
+			{ID: "b", Coords: []float64{1}},
+		}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tr
+	}
+	insertC := func(tr *KDTree[int]) { tr.Insert(KDPoint[int]{ID: "c", Coords: []float64{2}}) }
+
+	tr := newTree(WithConcurrencyChecks())
+	// callbacks run inside an operation, so they overlap it deterministically
+	expectConcurrentUse(t, "mutation started during a query", func() {
+		tr.RankByCombined([]float64{0}, 1, 0.5, func(KDPoint[int]) float64 { insertC(tr); return 0 })
+	})
+	tr = newTree(WithConcurrencyChecks())
+	expectConcurrentUse(t, "query started during a mutation", func() {
+		tr.DeleteWhere(func(KDPoint[int]) bool { tr.Nearest([]float64{0}); return false })
+	})
+	tr = newTree(WithConcurrencyChecks())
+	expectConcurrentUse(t, "concurrent mutations", func() {
+		tr.DeleteWhere(func(KDPoint[int]) bool { insertC(tr); return false })
+	})
+
+	// sequential use is unaffected
+	tr = newTree(WithConcurrencyChecks())
+	insertC(tr)
+	if p, _, _ := tr.Nearest([]float64{2}); p.ID != "c" || !tr.DeleteByID("c") {
+		t.Fatal("sequential use failed under checks")
+	}
+
+	// copy-on-write trees serialize writers themselves and are not checked
+	cow := newTree(WithConcurrencyChecks(), WithCopyOnWrite())
+	cow.RankByCombined([]float64{0}, 1, 0.5, func(KDPoint[int]) float64 { insertC(cow); return 0 })
+	if cow.Len() != 3 {
+		t.Fatalf("expected copy-on-write insert to succeed, got %d points", cow.Len())
+	}
+}
@@ -0,0 +1,82 @@
+package poindexter
+
+import (
+	"slices"
+	"time"
+)
+
+// ChurnStats describes peer turnover, derived from when each tracked peer was
+// first and last selected. A peer counts as gone once it has not been
+// selected for a whole Window.
+type ChurnStats struct {
+	Window time.Duration `json:"window"`
+	// Peers is the number of tracked peers.
+	Peers int `json:"peers"`
+	// Active is the number of peers selected within the last Window.
+	Active int `json:"active"`
+	// Joined is the number of peers first selected within the last Window.
+	Joined int `json:"joined"`
+	// Departed is the number of peers that disappeared during the last
+	// Window: last selected in the Window before it, and not since.
+	Departed int `json:"departed"`
+	// ChurnRate is Departed as a share of the peers present at the start of
+	// the last Window (active then, and first selected before it).
+	ChurnRate float64 `json:"churnRate"`
+	// MedianLifetime is the median time between a peer's first and last
+	// selection, over all tracked peers. Peers still active keep growing it;
+	// peers dropped by WithMaxTrackedPeers no longer count.
+	MedianLifetime time.Duration `json:"medianLifetime"`
+}
+
+// Churn returns peer turnover over window, or a zero ChurnStats if window is
+// not positive.
+func (p *PeerAnalytics) Churn(window time.Duration) ChurnStats {
+	return p.churnAt(window, time.Now().UnixNano())
+}
+
+// churnAt is Churn as of now (Unix nano).
+func (p *PeerAnalytics) churnAt(window time.Duration, now int64) ChurnStats {
+	if window <= 0 {
+		return ChurnStats{}
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c := ChurnStats{Window: window, Peers: len(p.peers)}
+	start, prev := now-int64(window), now-2*int64(window)
+	lifetimes := make([]int64, 0, len(p.peers))
+	for _, r := range p.peers {
+		last := r.last.Load()
+		lifetimes = append(lifetimes, max(0, last-r.created))
+		switch {
+		case last >= start:
+			c.Active++
+			if r.created >= start {
+				c.Joined++
+			}
+		case last >= prev:
+			c.Departed++
+		}
+	}
+	if present := c.Active - c.Joined + c.Departed; present > 0 {
+		c.ChurnRate = float64(c.Departed) / float64(present)
+	}
+	if n := len(lifetimes); n > 0 {
+		slices.Sort(lifetimes)
+		mid := lifetimes[n/2]
+		if n%2 == 0 {
+			mid = lifetimes[n/2-1] + (lifetimes[n/2]-lifetimes[n/2-1])/2
+		}
+		c.MedianLifetime = time.Duration(mid)
+	}
+	return c
+}
+
+// GetPeerChurn returns peer turnover over window, or a zero ChurnStats if
+// peer analytics are off.
+func (t *KDTree[T]) GetPeerChurn(window time.Duration) ChurnStats {
+	if t.peerAnalytics == nil {
+		return ChurnStats{}
+	}
+	return t.peerAnalytics.Churn(window)
+}
@@ -0,0 +1,57 @@
+package poindexter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeerAnalyticsChurn(t *testing.T) {
+	const minute = int64(time.Minute)
+	now := 100 * minute
+	p := NewPeerAnalytics()
+	// stable: seen throughout, still active
+	p.recordAt("stable", 1, now-50*minute)
+	p.recordAt("stable", 1, now-minute)
+	// gone: active at the start of the window, silent during it
+	p.recordAt("gone", 1, now-40*minute)
+	p.recordAt("gone", 1, now-15*minute)
+	// new: first seen within the window
+	p.recordAt("new", 1, now-5*minute)
+	// ancient: disappeared long before the previous window
+	p.recordAt("ancient", 1, now-90*minute)
+	p.recordAt("ancient", 1, now-80*minute)
+
+	c := p.churnAt(10*time.Minute, now)
+	if c.Peers != 4 || c.Active != 2 || c.Joined != 1 || c.Departed != 1 {
+		t.Fatalf("counts %+v", c)
+	}
+	// present at the window start: stable and gone
+	if c.ChurnRate != 0.5 {
+		t.Fatalf("churn rate %v, want 0.5", c.ChurnRate)
+	}
+	// lifetimes 0, 10, 25, 49 minutes
+	if want := 17*time.Minute + 30*time.Second; c.MedianLifetime != want {
+		t.Fatalf("median lifetime %v, want %v", c.MedianLifetime, want)
+	}
+
+	if p.churnAt(0, now) != (ChurnStats{}) {
+		t.Fatal("expected zero stats for a non-positive window")
+	}
+	if c := NewPeerAnalytics().Churn(time.Minute); c.Peers != 0 || c.ChurnRate != 0 || c.MedianLifetime != 0 {
+		t.Fatalf("empty tracker: %+v", c)
+	}
+}
+
+func TestKDTreePeerChurn(t *testing.T) {
+	tree, err := NewKDTree([]KDPoint[int]{{ID: "a", Coords: []float64{0}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Nearest([]float64{0})
+	if c := tree.GetPeerChurn(time.Hour); c.Active != 1 || c.Joined != 1 || c.Departed != 0 {
+		t.Fatalf("churn %+v", c)
+	}
+	if got := tree.GetPeerStats()[0]; got.FirstSelectedAt.IsZero() || got.FirstSelectedAt.After(got.LastSelectedAt) {
+		t.Fatalf("first/last selected %v / %v", got.FirstSelectedAt, got.LastSelectedAt)
+	}
+}
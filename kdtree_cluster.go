@@ -0,0 +1,245 @@
+package poindexter
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// ErrInvalidLinkage indicates an unknown linkage criterion was requested.
+var ErrInvalidLinkage = errors.New("kdtree: invalid linkage; use single or complete")
+
+// Linkage selects how the distance between two clusters is derived from the
+// pairwise distances of their members.
+type Linkage string
+
+const (
+	// LinkageSingle uses the minimum pairwise distance (nearest members).
+	LinkageSingle Linkage = "single"
+	// LinkageComplete uses the maximum pairwise distance (farthest members).
+	LinkageComplete Linkage = "complete"
+)
+
+// DendrogramNode is a node in a hierarchical clustering tree.
+// Leaves reference a single point by Index and have Height 0; internal nodes
+// have Index -1 and record the linkage distance at which Left and Right merged.
+type DendrogramNode struct {
+	Left   *DendrogramNode `json:"left,omitempty"`
+	Right  *DendrogramNode `json:"right,omitempty"`
+	Index  int             `json:"index"`
+	Height float64         `json:"height"`
+	Size   int             `json:"size"`
+}
+
+// Leaves returns the point indices under this node in left-to-right order.
+func (n *DendrogramNode) Leaves() []int {
+	if n == nil {
+		return nil
+	}
+	var out []int
+	stack := []*DendrogramNode{n}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if cur.Left == nil && cur.Right == nil {
+			out = append(out, cur.Index)
+			continue
+		}
+		if cur.Right != nil {
+			stack = append(stack, cur.Right)
+		}
+		if cur.Left != nil {
+			stack = append(stack, cur.Left)
+		}
+	}
+	return out
+}
+
+// Dendrogram is the result of agglomerative clustering. Points holds the
+// clustered points (leaf indices refer into it) and Clusters holds the flat
+// clustering obtained by cutting the tree at CutHeight.
+type Dendrogram[T any] struct {
+	Root      *DendrogramNode
+	Points    []KDPoint[T]
+	Linkage   Linkage
+	CutHeight float64
+	Clusters  [][]KDPoint[T]
+}
+
+// Cut returns the flat clusters obtained by cutting the dendrogram at height:
+// every maximal subtree whose merge height is <= height becomes one cluster.
+// Clusters are returned largest first.
+func (d *Dendrogram[T]) Cut(height float64) [][]KDPoint[T] {
+	if d == nil || d.Root == nil {
+		return nil
+	}
+	var groups [][]KDPoint[T]
+	stack := []*DendrogramNode{d.Root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if n.Height <= height || (n.Left == nil && n.Right == nil) {
+			leaves := n.Leaves()
+			g := make([]KDPoint[T], len(leaves))
+			for i, idx := range leaves {
+				g[i] = d.Points[idx]
+			}
+			groups = append(groups, g)
+			continue
+		}
+		stack = append(stack, n.Right, n.Left)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i]) > len(groups[j]) })
+	return groups
+}
+
+// AgglomerativeCluster performs bottom-up hierarchical clustering of pts using
+// the given linkage and distance metric (Euclidean if nil), then cuts the
+// resulting dendrogram at cutHeight to produce flat clusters.
+//
+// It uses the nearest-neighbour-chain algorithm over a full distance matrix:
+// O(n^2) time and memory. Intended for exploratory analysis of peer feature
+// distributions rather than very large point sets.
+func AgglomerativeCluster[T any](pts []KDPoint[T], linkage Linkage, cutHeight float64, metric DistanceMetric) (*Dendrogram[T], error) {
+	if len(pts) == 0 {
+		return nil, ErrEmptyPoints
+	}
+	if linkage != LinkageSingle && linkage != LinkageComplete {
+		return nil, ErrInvalidLinkage
+	}
+	dim := len(pts[0].Coords)
+	if dim == 0 {
+		return nil, ErrZeroDim
+	}
+	for _, p := range pts {
+		if len(p.Coords) != dim {
+			return nil, ErrDimMismatch
+		}
+	}
+	if metric == nil {
+		metric = EuclideanDistance{}
+	}
+	n := len(pts)
+	dist := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			d := metric.Distance(pts[i].Coords, pts[j].Coords)
+			dist[i*n+j] = d
+			dist[j*n+i] = d
+		}
+	}
+
+	type merge struct {
+		a, b   int // representative point index of each cluster
+		height float64
+	}
+	merges := make([]merge, 0, n-1)
+	active := make([]bool, n)
+	for i := range active {
+		active[i] = true
+	}
+	var chain []int
+	for remaining := n; remaining > 1; remaining-- {
+		if len(chain) == 0 {
+			for i := range active {
+				if active[i] {
+					chain = append(chain, i)
+					break
+				}
+			}
+		}
+		// Grow the chain until two clusters are reciprocal nearest neighbours.
+		var a, b int
+		for {
+			a = chain[len(chain)-1]
+			prev := -1
+			bestD := math.Inf(1)
+			if len(chain) > 1 {
+				prev = chain[len(chain)-2]
+				bestD = dist[a*n+prev]
+			}
+			best := prev
+			for j := 0; j < n; j++ {
+				if !active[j] || j == a {
+					continue
+				}
+				if d := dist[a*n+j]; d < bestD {
+					bestD = d
+					best = j
+				}
+			}
+			if best < 0 {
+				// no finite distance (e.g., NaN coords): pair with any active cluster
+				for j := 0; j < n; j++ {
+					if active[j] && j != a {
+						best = j
+						break
+					}
+				}
+			}
+			if best == prev && prev >= 0 {
+				b = prev
+				break
+			}
+			chain = append(chain, best)
+		}
+		chain = chain[:len(chain)-2]
+		merges = append(merges, merge{a: a, b: b, height: dist[a*n+b]})
+		// Lance–Williams update: keep the merged cluster in slot a.
+		for k := 0; k < n; k++ {
+			if !active[k] || k == a || k == b {
+				continue
+			}
+			da, db := dist[a*n+k], dist[b*n+k]
+			d := math.Min(da, db)
+			if linkage == LinkageComplete {
+				d = math.Max(da, db)
+			}
+			dist[a*n+k] = d
+			dist[k*n+a] = d
+		}
+		active[b] = false
+	}
+
+	// NN-chain emits merges out of height order; replay them sorted so the
+	// dendrogram is monotone. Slots are member points, so union-find on point
+	// indices recovers which subtrees each merge joins.
+	sort.SliceStable(merges, func(i, j int) bool { return merges[i].height < merges[j].height })
+	parent := make([]int, n)
+	nodes := make([]*DendrogramNode, n)
+	for i := range parent {
+		parent[i] = i
+		nodes[i] = &DendrogramNode{Index: i, Size: 1}
+	}
+	find := func(x int) int {
+		for parent[x] != x {
+			parent[x] = parent[parent[x]]
+			x = parent[x]
+		}
+		return x
+	}
+	root := nodes[0]
+	for _, m := range merges {
+		ra, rb := find(m.a), find(m.b)
+		left, right := nodes[ra], nodes[rb]
+		node := &DendrogramNode{Left: left, Right: right, Index: -1, Height: m.height, Size: left.Size + right.Size}
+		parent[rb] = ra
+		nodes[ra] = node
+		root = node
+	}
+
+	d := &Dendrogram[T]{
+		Root:      root,
+		Points:    append([]KDPoint[T](nil), pts...),
+		Linkage:   linkage,
+		CutHeight: cutHeight,
+	}
+	d.Clusters = d.Cut(cutHeight)
+	return d, nil
+}
+
+// AgglomerativeCluster clusters the tree's points using the tree's metric.
+// See the package-level AgglomerativeCluster for details.
+func (t *KDTree[T]) AgglomerativeCluster(linkage Linkage, cutHeight float64) (*Dendrogram[T], error) {
+	return AgglomerativeCluster(t.points, linkage, cutHeight, t.metric)
+}
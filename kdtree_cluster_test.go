@@ -0,0 +1,96 @@
+package poindexter
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func clusterTestPoints() []KDPoint[int] {
+	return []KDPoint[int]{
+		{ID: "a1", Coords: []float64{0, 0}},
+		{ID: "a2", Coords: []float64{0.5, 0}},
+		{ID: "a3", Coords: []float64{0, 0.5}},
+		{ID: "b1", Coords: []float64{10, 10}},
+		{ID: "b2", Coords: []float64{10.5, 10}},
+		{ID: "c1", Coords: []float64{-20, 5}},
+	}
+}
+
+func TestAgglomerativeCluster_Single(t *testing.T) {
+	d, err := AgglomerativeCluster(clusterTestPoints(), LinkageSingle, 1, nil)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if d.Root.Size != 6 {
+		t.Fatalf("root size want 6, got %d", d.Root.Size)
+	}
+	if len(d.Clusters) != 3 {
+		t.Fatalf("want 3 clusters, got %d", len(d.Clusters))
+	}
+	if len(d.Clusters[0]) != 3 || len(d.Clusters[1]) != 2 || len(d.Clusters[2]) != 1 {
+		t.Fatalf("unexpected cluster sizes: %d %d %d", len(d.Clusters[0]), len(d.Clusters[1]), len(d.Clusters[2]))
+	}
+	if got := d.Cut(math.Inf(1)); len(got) != 1 {
+		t.Fatalf("cut at +Inf want 1 cluster, got %d", len(got))
+	}
+	if got := d.Cut(0); len(got) != 6 {
+		t.Fatalf("cut at 0 want 6 clusters, got %d", len(got))
+	}
+}
+
+func TestAgglomerativeCluster_CompleteHeights(t *testing.T) {
+	pts := []KDPoint[int]{
+		{Coords: []float64{0}},
+		{Coords: []float64{1}},
+		{Coords: []float64{2.5}},
+	}
+	single, _ := AgglomerativeCluster(pts, LinkageSingle, 0, nil)
+	complete, _ := AgglomerativeCluster(pts, LinkageComplete, 0, nil)
+	if single.Root.Height != 1.5 {
+		t.Fatalf("single root height want 1.5, got %v", single.Root.Height)
+	}
+	if complete.Root.Height != 2.5 {
+		t.Fatalf("complete root height want 2.5, got %v", complete.Root.Height)
+	}
+	// heights must be monotone from leaves to root
+	var check func(n *DendrogramNode)
+	check = func(n *DendrogramNode) {
+		for _, c := range []*DendrogramNode{n.Left, n.Right} {
+			if c != nil {
+				if c.Height > n.Height {
+					t.Fatalf("non-monotone dendrogram: child %v > parent %v", c.Height, n.Height)
+				}
+				check(c)
+			}
+		}
+	}
+	check(complete.Root)
+}
+
+func TestAgglomerativeCluster_Errors(t *testing.T) {
+	if _, err := AgglomerativeCluster[int](nil, LinkageSingle, 1, nil); !errors.Is(err, ErrEmptyPoints) {
+		t.Fatalf("want ErrEmptyPoints, got %v", err)
+	}
+	if _, err := AgglomerativeCluster(clusterTestPoints(), Linkage("average"), 1, nil); !errors.Is(err, ErrInvalidLinkage) {
+		t.Fatalf("want ErrInvalidLinkage, got %v", err)
+	}
+	bad := []KDPoint[int]{{Coords: []float64{0}}, {Coords: []float64{0, 1}}}
+	if _, err := AgglomerativeCluster(bad, LinkageSingle, 1, nil); !errors.Is(err, ErrDimMismatch) {
+		t.Fatalf("want ErrDimMismatch, got %v", err)
+	}
+}
+
+func TestKDTree_AgglomerativeCluster(t *testing.T) {
+	tr, _ := NewKDTree(clusterTestPoints(), WithMetric(ChebyshevDistance{}))
+	d, err := tr.AgglomerativeCluster(LinkageComplete, 1)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(d.Clusters) != 3 {
+		t.Fatalf("want 3 clusters, got %d", len(d.Clusters))
+	}
+	if len(d.Root.Leaves()) != tr.Len() {
+		t.Fatalf("leaves want %d, got %d", tr.Len(), len(d.Root.Leaves()))
+	}
+}
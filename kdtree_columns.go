@@ -0,0 +1,44 @@
+package poindexter
+
+import "fmt"
+
+// PointsFromColumns assembles points from column-oriented data, the layout used
+// by Arrow record batches, Parquet row groups and most dataframe libraries.
+// Each element of cols is one coordinate axis; all columns must have the same
+// length. ids and values are optional (nil) but, when given, must match that
+// length. Coordinates are copied, so the source buffers may be reused.
+func PointsFromColumns[T any](ids []string, values []T, cols ...[]float64) ([]KDPoint[T], error) {
+	if len(cols) == 0 {
+		return nil, ErrZeroDim
+	}
+	n := len(cols[0])
+	for d, c := range cols {
+		if len(c) != n {
+			return nil, fmt.Errorf("%w: column %d has %d rows, want %d", ErrDimMismatch, d, len(c), n)
+		}
+	}
+	if ids != nil && len(ids) != n {
+		return nil, fmt.Errorf("%w: %d ids for %d rows", ErrDimMismatch, len(ids), n)
+	}
+	if values != nil && len(values) != n {
+		return nil, fmt.Errorf("%w: %d values for %d rows", ErrDimMismatch, len(values), n)
+	}
+	dim := len(cols)
+	// one backing array for all coordinates keeps large ingests to two allocations
+	flat := make([]float64, n*dim)
+	pts := make([]KDPoint[T], n)
+	for i := range pts {
+		coords := flat[i*dim : (i+1)*dim : (i+1)*dim]
+		for d, c := range cols {
+			coords[d] = c[i]
+		}
+		pts[i].Coords = coords
+		if ids != nil {
+			pts[i].ID = ids[i]
+		}
+		if values != nil {
+			pts[i].Value = values[i]
+		}
+	}
+	return pts, nil
+}
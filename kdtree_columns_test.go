@@ -0,0 +1,52 @@
+package poindexter
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPointsFromColumns(t *testing.T) {
+	x := []float64{0, 3, 10}
+	y := []float64{0, 4, 10}
+	pts, err := PointsFromColumns([]string{"a", "b", "c"}, []int{1, 2, 3}, x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 3 || pts[1].ID != "b" || pts[1].Value != 2 || pts[1].Coords[0] != 3 || pts[1].Coords[1] != 4 {
+		t.Fatalf("unexpected points %+v", pts)
+	}
+	// coordinates are copied and appends do not spill into the next point
+	x[1] = 99
+	pts[0].Coords = append(pts[0].Coords, 7)
+	if pts[1].Coords[0] != 3 {
+		t.Fatalf("points alias source or neighbour storage: %v", pts[1].Coords)
+	}
+	tr, err := NewKDTree(pts[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _, _ := tr.Nearest([]float64{9, 9}); p.ID != "c" {
+		t.Fatalf("expected c, got %q", p.ID)
+	}
+
+	// ids and values are optional
+	pts2, err := PointsFromColumns[string](nil, nil, []float64{1, 2})
+	if err != nil || len(pts2) != 2 || pts2[0].ID != "" || len(pts2[0].Coords) != 1 {
+		t.Fatalf("unexpected %+v err=%v", pts2, err)
+	}
+}
+
+func TestPointsFromColumns_Errors(t *testing.T) {
+	if _, err := PointsFromColumns[string](nil, nil); !errors.Is(err, ErrZeroDim) {
+		t.Fatalf("want ErrZeroDim, got %v", err)
+	}
+	if _, err := PointsFromColumns[string](nil, nil, []float64{1, 2}, []float64{1}); !errors.Is(err, ErrDimMismatch) {
+		t.Fatalf("want ErrDimMismatch for ragged columns, got %v", err)
+	}
+	if _, err := PointsFromColumns[string]([]string{"a"}, nil, []float64{1, 2}); !errors.Is(err, ErrDimMismatch) {
+		t.Fatalf("want ErrDimMismatch for short ids, got %v", err)
+	}
+	if _, err := PointsFromColumns([]string{"a", "b"}, []int{1}, []float64{1, 2}); !errors.Is(err, ErrDimMismatch) {
+		t.Fatalf("want ErrDimMismatch for short values, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package poindexter
+
+// CombinedDistance ranks points by a weighted sum of two metrics over the same
+// coordinates, WeightA·A + WeightB·B, for multi-objective selection such as
+// 0.7·Euclidean (latency, hops) + 0.3·Cosine (profile similarity). The
+// metrics' scales differ (Euclidean is unbounded, cosine lies in [0, 2]), so
+// normalise coordinates or pick weights with that in mind.
+//
+// A combination of norms with non-negative weights is itself a metric, which
+// the vptree and covertree backends index; they fall back to the linear
+// backend for other combinations, as the gonum and lsh backends always do.
+// The hnsw backend accepts any combination and the rtree backend scans. Trees
+// using it cannot be serialized (ErrUnknownMetric).
+type CombinedDistance struct {
+	A, B             DistanceMetric
+	WeightA, WeightB float64
+}
+
+// CombineMetrics returns the metric wa·a + wb·b, e.g.
+//
+//	CombineMetrics(EuclideanDistance{}, 0.7, CosineDistance{}, 0.3)
+func CombineMetrics(a DistanceMetric, wa float64, b DistanceMetric, wb float64) CombinedDistance {
+	return CombinedDistance{A: a, B: b, WeightA: wa, WeightB: wb}
+}
+
+func (m CombinedDistance) Distance(a, b []float64) float64 {
+	return m.WeightA*m.A.Distance(a, b) + m.WeightB*m.B.Distance(a, b)
+}
+
+// isNormCombination reports whether m is a non-negative combination of the
+// Euclidean, Manhattan and Chebyshev norms, and so a true metric.
+func isNormCombination(m DistanceMetric) bool {
+	switch m := m.(type) {
+	case EuclideanDistance, ManhattanDistance, ChebyshevDistance:
+		return true
+	case CombinedDistance:
+		return m.WeightA >= 0 && m.WeightB >= 0 && isNormCombination(m.A) && isNormCombination(m.B)
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package poindexter
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestCombinedDistance(t *testing.T) {
+	m := CombineMetrics(EuclideanDistance{}, 0.7, CosineDistance{}, 0.3)
+	a, b := []float64{1, 0}, []float64{0, 1}
+	if got, want := m.Distance(a, b), 0.7*math.Sqrt2+0.3; math.Abs(got-want) > 1e-12 {
+		t.Fatalf("Distance = %v, want %v", got, want)
+	}
+	// the cosine term favours the aligned point even though it is farther
+	pts := []KDPoint[int]{
+		{ID: "aligned", Coords: []float64{2, 2}},
+		{ID: "close", Coords: []float64{1.2, 0.2}},
+	}
+	tr, _ := NewKDTree(pts, WithMetric(CombineMetrics(EuclideanDistance{}, 0.2, CosineDistance{}, 1)))
+	if p, _, _ := tr.Nearest([]float64{1, 1}); p.ID != "aligned" {
+		t.Fatalf("Nearest = %s, want aligned", p.ID)
+	}
+}
+
+func TestCombinedDistanceBackends(t *testing.T) {
+	pts := makePoints(500, 3)
+	norms := CombineMetrics(EuclideanDistance{}, 0.5, ManhattanDistance{}, 0.5)
+	ref, _ := NewKDTree(pts, WithMetric(norms), WithBackend(BackendLinear))
+	for _, b := range []KDBackend{BackendVPTree, BackendCoverTree} {
+		tr, err := NewKDTree(pts, WithMetric(norms), WithBackend(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tr.Backend() != b {
+			t.Fatalf("%s fell back to %s for a combination of norms", b, tr.Backend())
+		}
+		q := []float64{0.3, 0.6, 0.9}
+		_, got := tr.KNearest(q, 8)
+		_, want := ref.KNearest(q, 8)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("%s: KNearest %v, want %v", b, got, want)
+		}
+	}
+	mixed := CombineMetrics(EuclideanDistance{}, 0.7, CosineDistance{}, 0.3)
+	if tr, _ := NewKDTree(pts, WithMetric(mixed), WithBackend(BackendVPTree)); tr.Backend() != BackendLinear {
+		t.Fatalf("Euclidean+cosine on vptree = %s, want linear fallback", tr.Backend())
+	}
+}
@@ -0,0 +1,56 @@
+package poindexter
+
+// A tree keeps its points' coordinates in contiguous blocks it owns rather
+// than in the slices callers passed in: NewKDTree packs every point into one
+// block in point order, and inserts fill a chunk allocated ahead of them. A
+// linear scan or backend build then walks memory sequentially instead of
+// chasing one small allocation per point. Each KDPoint.Coords is a slice of a
+// block capped at Dim, so the public API is unchanged.
+//
+// Blocks are append-only: a slot is never rewritten once a point holds it,
+// because snapshots, indexes and query history share coordinate slices with
+// the tree. Deleted points leave their slots behind; once those outnumber the
+// live points, the survivors are repacked into a fresh block.
+
+// minCoordChunk is the fewest points an insert chunk holds.
+const minCoordChunk = 64
+
+// coordArena allocates coordinate slots for a tree.
+type coordArena struct {
+	block   []float64 // current block; slots are taken from its spare capacity
+	garbage int       // slots of deleted points across all blocks
+}
+
+// store copies c into a fresh slot and returns it. When the current block is
+// full a new chunk sized for about n/8 more points (at least minCoordChunk)
+// is allocated, n being the tree's current size.
+func (a *coordArena) store(c []float64, n int) []float64 {
+	dim := len(c)
+	if cap(a.block)-len(a.block) < dim {
+		a.block = make([]float64, 0, max(n/8, minCoordChunk)*dim)
+	}
+	at := len(a.block)
+	a.block = append(a.block, c...)
+	return a.block[at : at+dim : at+dim]
+}
+
+// packCoords copies the coordinates of pts, in order, into a single block
+// and points each KDPoint at its slot.
+func packCoords[T any](pts []KDPoint[T], dim int) coordArena {
+	block := make([]float64, len(pts)*dim)
+	for i := range pts {
+		s := block[i*dim : (i+1)*dim : (i+1)*dim]
+		copy(s, pts[i].Coords)
+		pts[i].Coords = s
+	}
+	return coordArena{block: block}
+}
+
+// releaseCoords records that n points were deleted and repacks the remaining
+// points once deleted slots outnumber them.
+func (t *KDTree[T]) releaseCoords(n int) {
+	t.coords.garbage += n
+	if t.coords.garbage > len(t.points) {
+		t.coords = packCoords(t.points, t.dim)
+	}
+}
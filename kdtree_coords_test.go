@@ -0,0 +1,76 @@
+package poindexter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCoords_Contiguous(t *testing.T) {
+	src := []KDPoint[int]{
+		{ID: "a", Coords: []float64{1, 2}},
+		{ID: "b", Coords: []float64{3, 4}},
+		{ID: "c", Coords: []float64{5, 6}},
+	}
+	tr, err := NewKDTree(src, WithBackend(BackendLinear))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range tr.points {
+		if &p.Coords[0] != &tr.coords.block[i*2] || cap(p.Coords) != 2 {
+			t.Fatalf("point %d not packed in the block", i)
+		}
+	}
+	src[0].Coords[0] = 100
+	if p, d, _ := tr.Nearest([]float64{1, 2}); p.ID != "a" || d != 0 {
+		t.Fatal("tree shares coordinates with the caller")
+	}
+
+	in := []float64{7, 8}
+	tr.Insert(KDPoint[int]{ID: "d", Coords: in})
+	in[0] = -1
+	d := tr.points[3].Coords
+	if d[0] != 7 || cap(d) != 2 {
+		t.Fatalf("inserted coords %v cap %d", d, cap(d))
+	}
+	tr.Insert(KDPoint[int]{ID: "e", Coords: []float64{9, 10}})
+	if b := tr.coords.block; &b[0] != &d[0] || &b[2] != &tr.points[4].Coords[0] {
+		t.Fatal("inserts should fill one chunk in order")
+	}
+	_ = append(d, 99) // capped: must not clobber "e"
+	if tr.points[4].Coords[0] != 9 {
+		t.Fatal("append to a point's coords overwrote its neighbour")
+	}
+}
+
+func TestCoords_RepackAfterDeletes(t *testing.T) {
+	pts := make([]KDPoint[int], 100)
+	for i := range pts {
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{float64(i), float64(-i)}}
+	}
+	tr, _ := NewKDTree(pts)
+	view := tr.Snapshot()
+	old := tr.coords.block
+	for i := 0; i < 60; i++ {
+		if i%2 == 0 {
+			tr.DeleteByID(fmt.Sprint(i))
+		}
+	}
+	tr.DeleteWhere(func(p KDPoint[int]) bool { return p.Coords[0] > 60 })
+	if &tr.coords.block[0] == &old[0] || tr.coords.garbage != 0 {
+		t.Fatalf("expected a repack, garbage=%d", tr.coords.garbage)
+	}
+	for i, p := range tr.points {
+		if &p.Coords[0] != &tr.coords.block[i*2] || p.Coords[1] != -p.Coords[0] {
+			t.Fatalf("point %d (%s) not repacked intact: %v", i, p.ID, p.Coords)
+		}
+	}
+	if p, d, _ := tr.Nearest([]float64{31, -31}); p.ID != "31" || d != 0 {
+		t.Fatalf("nearest after repack: %s %v", p.ID, d)
+	}
+	// the snapshot still sees every original point unchanged
+	for _, p := range view.Points() {
+		if p.ID != fmt.Sprint(p.Coords[0]) || p.Coords[1] != -p.Coords[0] {
+			t.Fatalf("snapshot coords changed: %s %v", p.ID, p.Coords)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package poindexter
+
+import (
+	"math"
+	"time"
+)
+
+// QueryKind identifies a query operation for EstimateQueryCost.
+type QueryKind string
+
+const (
+	QueryNearest  QueryKind = "nearest"
+	QueryKNearest QueryKind = "knearest"
+	QueryRadius   QueryKind = "radius"
+)
+
+// QueryCostEstimate is the predicted cost of a query, computed without running it.
+type QueryCostEstimate struct {
+	Kind    QueryKind `json:"kind"`
+	Backend KDBackend `json:"backend"`
+	Points  int       `json:"points"`
+	Dim     int       `json:"dim"`
+	// DistanceEvals is the expected number of point distance computations.
+	DistanceEvals float64 `json:"distanceEvals"`
+	// ExpectedResults is the expected number of points returned.
+	ExpectedResults float64 `json:"expectedResults"`
+	// Latency is the predicted wall time of the query.
+	Latency time.Duration `json:"latencyNs"`
+	// Calibrated reports whether Latency was scaled by observed query timings
+	// from the tree's analytics rather than the built-in per-coordinate cost.
+	Calibrated bool `json:"calibrated"`
+}
+
+// costNsPerCoord is the uncalibrated cost of one coordinate in a distance
+// evaluation, including loop and comparison overhead.
+const costNsPerCoord = 1.5
+
+// costSampleSize bounds the points sampled to estimate radius result counts.
+const costSampleSize = 32
+
+// EstimateQueryCost predicts the cost of a query from the tree size, dimension,
+// backend and recent analytics, so callers can choose between an exact query
+// and a cheaper approximation (a smaller k, a coarser radius, a Snapshot of a
+// pre-filtered tree) before paying for it. param is k for QueryKNearest, the
+// radius for QueryRadius, and ignored for QueryNearest.
+//
+// The model is deliberately coarse: a linear scan evaluates every point (and
+// KNearest/Radius sort their candidates), while the gonum backend visits about
+// log2(n) + 2^dim nodes plus a few per extra neighbor, capped at n. Radius
+// result counts are estimated from pairwise distances of a small deterministic
+// sample of points.
+// When analytics hold timed queries, the per-evaluation cost is calibrated so
+// the model reproduces the observed average latency.
+func (t *KDTree[T]) EstimateQueryCost(kind QueryKind, param float64) QueryCostEstimate {
+	if v := t.cowView(); v != nil {
+		return v.t.EstimateQueryCost(kind, param)
+	}
+	n := len(t.points)
+	est := QueryCostEstimate{Kind: kind, Backend: t.backend, Points: n, Dim: t.dim}
+	if n == 0 {
+		return est
+	}
+	switch kind {
+	case QueryNearest:
+		est.ExpectedResults = 1
+	case QueryKNearest:
+		est.ExpectedResults = math.Min(math.Max(math.Floor(param), 0), float64(n))
+	case QueryRadius:
+		if param >= 0 {
+			est.ExpectedResults = t.estimateRadiusResults(param)
+		}
+	default:
+		return est
+	}
+	est.DistanceEvals = t.modelEvals(kind, est.ExpectedResults)
+
+	nsPerEval := costNsPerCoord * float64(t.dim)
+	if t.analytics != nil {
+		if s := t.analytics.Snapshot(); s.QueryCount > 0 && s.AvgQueryTimeNs > 0 {
+			// Calibrate against a typical query: nearest, or a radius query
+			// returning the observed average if radius queries dominate.
+			ref := t.modelEvals(QueryNearest, 1)
+			if t.analytics.RadiusQueryCount.Load()*2 > s.QueryCount {
+				ref = t.modelEvals(QueryRadius, s.AvgRadiusResults)
+			}
+			nsPerEval = float64(s.AvgQueryTimeNs) / ref
+			est.Calibrated = true
+		}
+	}
+	est.Latency = time.Duration(est.DistanceEvals * nsPerEval)
+	return est
+}
+
+// modelEvals returns the modelled work of a query returning m results, in
+// distance-evaluation units (sorting m items counts as m·log2(m) units).
+func (t *KDTree[T]) modelEvals(kind QueryKind, m float64) float64 {
+	n := float64(len(t.points))
+	sortCost := func(x float64) float64 {
+		if x < 2 {
+			return 0
+		}
+		return x * math.Log2(x)
+	}
+	if t.queryIndex() != nil {
+		perNeighbor := math.Log2(n+1) + math.Pow(2, float64(t.dim))
+		switch kind {
+		case QueryNearest:
+			return math.Min(n, perNeighbor)
+		default:
+			return math.Min(n, perNeighbor+m*math.Pow(2, float64(t.dim)/2)) + sortCost(m)
+		}
+	}
+	switch kind {
+	case QueryKNearest:
+		return n + sortCost(n)
+	case QueryRadius:
+		return n + sortCost(m)
+	default:
+		return n
+	}
+}
+
+// estimateRadiusResults estimates how many points lie within r of a typical
+// point, from the pairwise distances of an evenly spaced sample.
+func (t *KDTree[T]) estimateRadiusResults(r float64) float64 {
+	n := len(t.points)
+	s := min(n, costSampleSize)
+	if s < 2 {
+		return float64(n)
+	}
+	within, pairs := 0, 0
+	t.samplePairDistances(func(d float64) {
+		pairs++
+		if d <= r {
+			within++
+		}
+	})
+	// the sampled fraction of the other n-1 points, plus the point itself
+	return 1 + float64(within)/float64(pairs)*float64(n-1)
+}
+
+// samplePairDistances calls visit with the pairwise distances of up to
+// costSampleSize evenly spaced points.
+func (t *KDTree[T]) samplePairDistances(visit func(d float64)) {
+	n := len(t.points)
+	s := min(n, costSampleSize)
+	sample := make([][]float64, s)
+	for i := range sample {
+		sample[i] = t.points[i*n/s].Coords
+	}
+	for i := 0; i < s; i++ {
+		for j := i + 1; j < s; j++ {
+			visit(t.metric.Distance(sample[i], sample[j]))
+		}
+	}
+}
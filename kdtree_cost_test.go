@@ -0,0 +1,48 @@
+package poindexter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimateQueryCost(t *testing.T) {
+	pts := make([]KDPoint[int], 100)
+	for i := range pts {
+		pts[i] = KDPoint[int]{Coords: []float64{float64(i), 0}}
+	}
+	tr, err := NewKDTree(pts, WithBackend(BackendLinear))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := tr.EstimateQueryCost(QueryNearest, 0)
+	if n.Points != 100 || n.Dim != 2 || n.ExpectedResults != 1 || n.DistanceEvals != 100 || n.Calibrated {
+		t.Fatalf("unexpected nearest estimate %+v", n)
+	}
+	if n.Latency <= 0 {
+		t.Fatalf("expected positive latency, got %v", n.Latency)
+	}
+	k := tr.EstimateQueryCost(QueryKNearest, 500)
+	if k.ExpectedResults != 100 || k.Latency <= n.Latency {
+		t.Fatalf("kNearest should be clamped to n and cost more than nearest: %+v", k)
+	}
+	small := tr.EstimateQueryCost(QueryRadius, 1)
+	big := tr.EstimateQueryCost(QueryRadius, 1000)
+	if big.ExpectedResults != 100 || small.ExpectedResults >= big.ExpectedResults {
+		t.Fatalf("radius results should grow with r: small=%v big=%v", small.ExpectedResults, big.ExpectedResults)
+	}
+	if e := tr.EstimateQueryCost("bogus", 1); e.DistanceEvals != 0 || e.Latency != 0 {
+		t.Fatalf("unknown kind should estimate nothing: %+v", e)
+	}
+
+	tr.Analytics().RecordQuery(int64(time.Millisecond))
+	c := tr.EstimateQueryCost(QueryNearest, 0)
+	if !c.Calibrated || c.Latency != time.Millisecond {
+		t.Fatalf("expected calibrated 1ms nearest estimate, got %+v", c)
+	}
+
+	empty, _ := NewKDTreeFromDim[int](3)
+	if e := empty.EstimateQueryCost(QueryNearest, 0); e.Latency != 0 || e.Dim != 3 {
+		t.Fatalf("empty tree estimate: %+v", e)
+	}
+}
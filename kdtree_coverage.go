@@ -0,0 +1,57 @@
+package poindexter
+
+import (
+	"math"
+	"sort"
+)
+
+// KForCoverage returns a data-driven k for KNearest around query: the number of
+// points within a threshold distance of query, at least 1 and at most Len. The
+// threshold is the targetCoverage quantile (in [0,1], clamped) of the tree's
+// distance distribution — the distances returned by earlier queries when
+// WithResultDistanceDigest or WithResultDistanceTracking is enabled and has
+// data (the digest preferred), otherwise the pairwise distances of an evenly
+// spaced sample of the stored points. A query in a dense
+// region therefore gets a larger k than one in a sparse region, instead of a
+// fixed k=10 regardless of density. Returns 0 for an empty tree or a query of
+// the wrong dimension.
+func (t *KDTree[T]) KForCoverage(query []float64, targetCoverage float64) int {
+	if v := t.cowView(); v != nil {
+		return v.t.KForCoverage(query, targetCoverage)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	n := len(t.points)
+	if n == 0 || len(query) != t.dim {
+		return 0
+	}
+	threshold := t.coverageThreshold(math.Max(0, math.Min(1, targetCoverage)))
+
+	k := 0
+	count := func(_ int, _ float64) { k++ }
+	if ix := t.queryIndex(); ix == nil || !indexRadiusEach[T](ix.data, query, threshold, count) {
+		k = 0
+		for _, p := range t.points {
+			if t.metric.Distance(query, p.Coords) <= threshold {
+				k++
+			}
+		}
+	}
+	return max(1, min(k, n))
+}
+
+// coverageThreshold returns the p-quantile of the distance distribution used
+// by KForCoverage.
+func (t *KDTree[T]) coverageThreshold(p float64) float64 {
+	if t.resultDigest != nil && t.resultDigest.Count() > 0 {
+		return t.resultDigest.Quantile(p)
+	}
+	if t.resultDist != nil && t.resultDist.Count() > 0 {
+		return t.resultDist.Quantile(p)
+	}
+	var dists []float64
+	t.samplePairDistances(func(d float64) { dists = append(dists, d) })
+	sort.Float64s(dists)
+	return percentile(dists, p)
+}
@@ -0,0 +1,49 @@
+package poindexter
+
+import "testing"
+
+func TestKForCoverage_Density(t *testing.T) {
+	var pts []KDPoint[int]
+	for i := 0; i < 50; i++ { // dense cluster around the origin
+		pts = append(pts, KDPoint[int]{Coords: []float64{float64(i%10) * 0.01, float64(i/10) * 0.01}})
+	}
+	for i := 0; i < 10; i++ { // sparse points far apart
+		pts = append(pts, KDPoint[int]{Coords: []float64{10 + float64(i)*10, 10}})
+	}
+	tr, err := NewKDTree(pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dense := tr.KForCoverage([]float64{0.05, 0.02}, 0.3)
+	sparse := tr.KForCoverage([]float64{55, 10}, 0.3)
+	if dense <= sparse {
+		t.Fatalf("expected larger k in dense region: dense=%d sparse=%d", dense, sparse)
+	}
+	if sparse < 1 {
+		t.Fatalf("k must be at least 1, got %d", sparse)
+	}
+	if k := tr.KForCoverage([]float64{0.05, 0.02}, 5); k < dense || k > tr.Len() {
+		t.Fatalf("clamped full coverage should be in [%d, %d], got %d", dense, tr.Len(), k)
+	}
+	if k := tr.KForCoverage([]float64{0}, 0.5); k != 0 {
+		t.Fatalf("dimension mismatch should return 0, got %d", k)
+	}
+}
+
+func TestKForCoverage_ResultDistribution(t *testing.T) {
+	pts := make([]KDPoint[int], 20)
+	for i := range pts {
+		pts[i] = KDPoint[int]{Coords: []float64{float64(i)}}
+	}
+	tr, err := NewKDTree(pts, WithResultDistanceTracking(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// observed result distances are all within 2 units
+	for i := 0; i < 10; i++ {
+		tr.KNearest([]float64{float64(i)}, 3)
+	}
+	if k := tr.KForCoverage([]float64{10}, 1); k < 3 || k > 5 {
+		t.Fatalf("expected k within the observed 2-unit scale (3..5), got %d", k)
+	}
+}
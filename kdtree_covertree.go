@@ -0,0 +1,215 @@
+package poindexter
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// The cover-tree backend (BackendCoverTree) indexes points for any metric
+// the vantage-point tree accepts: L1, L2 and L∞, custom metrics satisfying
+// the triangle inequality, and CosineDistance/WeightedCosineDistance through
+// the same chord transform (see vpScale). Each node sits at an integer level
+// and covers its children: a child of a level-l node lies within 2^l of it,
+// so every level halves the scale. On data with expansion constant c a query
+// touches O(c^O(1)·log n) nodes, independent of the dimension of the
+// coordinates, which keeps cosine search on embeddings sub-linear where the
+// kd backend cannot prune at all.
+//
+// This is the simplified cover tree of Izbicki and Shelton (2015): nodes
+// record the largest distance to any of their descendants, which a query
+// uses to skip whole subtrees. Results are exact.
+
+// ctNode is a cover-tree node. Distances are in the pruning space.
+type ctNode struct {
+	idx      int
+	level    int
+	maxDist  float64 // to the farthest descendant
+	children []*ctNode
+	dups     []int32 // points at distance 0 from idx
+}
+
+// ctMinLevel is the level of a lone root; the first insert that needs a
+// larger cover raises it.
+const ctMinLevel = math.MinInt32
+
+// ctCover returns the covering radius of a level-l node.
+func ctCover(l int) float64 {
+	if l == ctMinLevel {
+		return 0
+	}
+	return math.Ldexp(1, l)
+}
+
+// ctBackend is an immutable cover tree over a point set.
+type ctBackend struct {
+	root   *ctNode
+	dim    int
+	metric DistanceMetric
+	coords func(i int) []float64
+	len    int
+	// scale maps metric distances into the metric space the tree prunes in.
+	scale func(d float64) float64
+}
+
+// buildCoverTreeBackend builds a cover tree over points by inserting them in
+// a fixed-seed random order, so builds are deterministic. It returns
+// ErrBackendUnavailable for metrics it cannot prune under.
+func buildCoverTreeBackend[T any](points []KDPoint[T], metric DistanceMetric) (any, error) {
+	scale, ok := vpScale(metric)
+	if !ok {
+		return nil, ErrBackendUnavailable
+	}
+	b := &ctBackend{
+		metric: metric,
+		coords: func(i int) []float64 { return points[i].Coords },
+		len:    len(points),
+		scale:  scale,
+	}
+	if len(points) == 0 {
+		return b, nil
+	}
+	b.dim = len(points[0].Coords)
+	rng := rand.New(rand.NewPCG(uint64(len(points)), 0xc7))
+	for _, i := range rng.Perm(len(points)) {
+		b.insert(i)
+	}
+	return b, nil
+}
+
+// dist returns the pruning-space distance between points i and j.
+func (b *ctBackend) dist(i, j int) float64 {
+	return b.scale(b.metric.Distance(b.coords(i), b.coords(j)))
+}
+
+// insert adds point x below the deepest chain of nodes covering it, raising
+// the root's level first if x lies outside its cover.
+func (b *ctBackend) insert(x int) {
+	if b.root == nil {
+		b.root = &ctNode{idx: x, level: ctMinLevel}
+		return
+	}
+	p, d := b.root, b.dist(b.root.idx, x)
+	if d > ctCover(p.level) {
+		// the smallest level whose cover reaches x
+		_, exp := math.Frexp(d)
+		p.level = exp
+	}
+	for {
+		if d == 0 {
+			p.dups = append(p.dups, int32(x))
+			return
+		}
+		p.maxDist = math.Max(p.maxDist, d)
+		var next *ctNode
+		var nd float64
+		for _, q := range p.children {
+			if qd := b.dist(q.idx, x); qd <= ctCover(q.level) && (next == nil || qd < nd) {
+				next, nd = q, qd
+			}
+		}
+		if next == nil {
+			p.children = append(p.children, &ctNode{idx: x, level: p.level - 1})
+			return
+		}
+		p, d = next, nd
+	}
+}
+
+// ctSearchItem is a node pending visit with the query's metric distance to
+// it and that distance in the pruning space.
+type ctSearchItem struct {
+	n    *ctNode
+	d, s float64
+}
+
+// pruned reports whether no point under it can be within metric distance
+// limit of the query.
+func (b *ctBackend) pruned(it ctSearchItem, limit float64) bool {
+	if math.IsInf(limit, 1) {
+		return false
+	}
+	return it.s-it.n.maxDist > b.scale(limit)*(1+vpSlack)+vpSlack
+}
+
+// search walks the tree depth-first, nearest children first, skipping
+// subtrees that cannot reach within limit() of the query and calling visit
+// for every point it reaches.
+func (b *ctBackend) search(query []float64, limit func() float64, visit func(idx int, dist float64)) {
+	item := func(n *ctNode) ctSearchItem {
+		d := b.metric.Distance(query, b.coords(n.idx))
+		return ctSearchItem{n, d, b.scale(d)}
+	}
+	stack := []ctSearchItem{item(b.root)}
+	var kids []ctSearchItem
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if b.pruned(it, limit()) {
+			continue
+		}
+		visit(it.n.idx, it.d)
+		for _, i := range it.n.dups {
+			visit(int(i), b.metric.Distance(query, b.coords(int(i))))
+		}
+		kids = kids[:0]
+		for _, c := range it.n.children {
+			kids = append(kids, item(c))
+		}
+		// push farthest first so the nearest child is explored next
+		sort.Slice(kids, func(i, j int) bool { return kids[i].d > kids[j].d })
+		stack = append(stack, kids...)
+	}
+}
+
+func (b *ctBackend) kNearest(query []float64, k int, skip func(idx int) bool) ([]int, []float64) {
+	if b.root == nil || len(query) != b.dim || k <= 0 {
+		return nil, nil
+	}
+	var h knnHeap
+	limit := func() float64 {
+		if h.Len() < k {
+			return math.Inf(1)
+		}
+		return h.peek().dist
+	}
+	b.search(query, limit, func(idx int, d float64) {
+		if skip != nil && skip(idx) {
+			return
+		}
+		if h.Len() < k {
+			h.push(knnItem{idx: idx, dist: d})
+		} else if d < h.peek().dist {
+			h[0] = knnItem{idx: idx, dist: d}
+			h.down(0)
+		}
+	})
+	sort.Slice(h, func(i, j int) bool { return h[i].dist < h[j].dist })
+	idxs := make([]int, len(h))
+	dists := make([]float64, len(h))
+	for i := range h {
+		idxs[i] = h[i].idx
+		dists[i] = h[i].dist
+	}
+	return idxs, dists
+}
+
+func (b *ctBackend) nearest(query []float64) (int, float64, bool) {
+	idxs, dists := b.kNearest(query, 1, nil)
+	if len(idxs) == 0 {
+		return -1, 0, false
+	}
+	return idxs[0], dists[0], true
+}
+
+func (b *ctBackend) radiusEach(query []float64, r float64, visit func(idx int, dist float64)) bool {
+	if b.root == nil || len(query) != b.dim || r < 0 {
+		return false
+	}
+	b.search(query, func() float64 { return r }, func(idx int, d float64) {
+		if d <= r {
+			visit(idx, d)
+		}
+	})
+	return true
+}
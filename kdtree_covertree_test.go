@@ -0,0 +1,103 @@
+package poindexter
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestCoverTree_MatchesLinear(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	pts := make([]KDPoint[int], 600)
+	for i := range pts {
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{rng.Float64(), rng.Float64() * 2, rng.Float64() - 0.5, rng.Float64()}}
+	}
+	pts[7].Coords = []float64{0, 0, 0, 0}
+	pts[8].Coords = append([]float64(nil), pts[9].Coords...) // duplicate
+	metrics := []DistanceMetric{
+		CosineDistance{},
+		WeightedCosineDistance{Weights: []float64{1, 0.5, 2, 0}},
+		EuclideanDistance{},
+		ManhattanDistance{},
+		ChebyshevDistance{},
+		countingMetric{n: new(int)},
+	}
+	for _, m := range metrics {
+		t.Run(fmt.Sprintf("%T", m), func(t *testing.T) {
+			ct, err := NewKDTree(pts, WithMetric(m), WithBackend(BackendCoverTree))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ct.Backend() != BackendCoverTree {
+				t.Fatalf("backend %s", ct.Backend())
+			}
+			ct.Insert(KDPoint[int]{ID: "extra", Coords: []float64{0.3, 0.3, 0.3, 0.3}})
+			ct.DeleteByID("11")
+			lin, _ := NewKDTree(ct.Points(), WithMetric(m), WithBackend(BackendLinear))
+			for q := 0; q < 40; q++ {
+				query := []float64{rng.Float64(), rng.Float64() * 2, rng.Float64() - 0.5, rng.Float64()}
+				if q == 0 {
+					query = pts[9].Coords
+				}
+				_, dc, _ := ct.Nearest(query)
+				_, dl, _ := lin.Nearest(query)
+				if dc != dl {
+					t.Fatalf("nearest: %v vs %v", dc, dl)
+				}
+				_, kc := ct.KNearest(query, 9)
+				_, kl := lin.KNearest(query, 9)
+				if fmt.Sprint(kc) != fmt.Sprint(kl) {
+					t.Fatalf("kNearest: %v vs %v", kc, kl)
+				}
+				r := kl[4]
+				rc, _ := ct.Radius(query, r)
+				rl, _ := lin.Radius(query, r)
+				if len(rc) != len(rl) {
+					t.Fatalf("radius %v: %d vs %d", r, len(rc), len(rl))
+				}
+			}
+		})
+	}
+}
+
+func TestCoverTree_Prunes(t *testing.T) {
+	rng := rand.New(rand.NewSource(43))
+	// 2-D data embedded in 32 dimensions: low intrinsic dimension
+	pts := make([]KDPoint[int], 5000)
+	for i := range pts {
+		c := make([]float64, 32)
+		c[3], c[17] = rng.Float64()*100, rng.Float64()*100
+		pts[i] = KDPoint[int]{Coords: c}
+	}
+	evals := 0
+	tr, err := NewKDTree(pts, WithMetric(countingMetric{n: &evals}), WithBackend(BackendCoverTree))
+	if err != nil {
+		t.Fatal(err)
+	}
+	evals = 0
+	for q := 0; q < 20; q++ {
+		c := make([]float64, 32)
+		c[3], c[17] = rng.Float64()*100, rng.Float64()*100
+		tr.Nearest(c)
+	}
+	if avg := evals / 20; avg > len(pts)/10 {
+		t.Fatalf("expected sub-linear search, got %d distance evaluations per query", avg)
+	}
+}
+
+func TestCoverTree_Fallback(t *testing.T) {
+	pts := []KDPoint[int]{{Coords: []float64{1, 1}}, {Coords: []float64{2, -1}}}
+	tr, _ := NewKDTree(pts, WithMetric(WeightedCosineDistance{Weights: []float64{1, -1}}), WithBackend(BackendCoverTree))
+	if tr.Backend() != BackendLinear {
+		t.Fatalf("expected linear fallback for negative weights, got %s", tr.Backend())
+	}
+	e, _ := NewKDTreeFromDim[int](2, WithBackend(BackendCoverTree))
+	e.Insert(KDPoint[int]{ID: "a", Coords: []float64{1, 0}})
+	e.Insert(KDPoint[int]{ID: "b", Coords: []float64{5, 5}})
+	if p, d, ok := e.Nearest([]float64{1, 0.5}); !ok || p.ID != "a" || d != 0.5 {
+		t.Fatalf("nearest: %v %v %v", p, d, ok)
+	}
+	if e.Backend() != BackendCoverTree || e.queryIndex() == nil {
+		t.Fatal("expected a cover-tree index")
+	}
+}
@@ -0,0 +1,189 @@
+package poindexter
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ErrInvalidCSVColumns indicates the configured ID/value columns do not fit
+// the row layout (out of range or colliding).
+var ErrInvalidCSVColumns = errors.New("kdtree: invalid csv column layout")
+
+// csvOptions configures CSV import/export of points.
+type csvOptions struct {
+	idCol    int // -1 disables the ID column
+	valueCol int // -1 disables the value column
+	header   bool
+	comma    rune
+}
+
+// CSVOption configures LoadPointsCSV and WritePointsCSV.
+type CSVOption func(*csvOptions)
+
+// WithCSVIDColumn sets the zero-based column holding point IDs (default 0).
+// Pass -1 for files without an ID column; points then get empty IDs.
+func WithCSVIDColumn(col int) CSVOption {
+	return func(o *csvOptions) { o.idCol = col }
+}
+
+// WithCSVValueColumn sets the zero-based column holding point values. By
+// default there is no value column.
+func WithCSVValueColumn(col int) CSVOption {
+	return func(o *csvOptions) { o.valueCol = col }
+}
+
+// WithCSVHeader makes LoadPointsCSV skip the first row and WritePointsCSV emit
+// a header row ("id", "x0".."xN", "value").
+func WithCSVHeader(header bool) CSVOption {
+	return func(o *csvOptions) { o.header = header }
+}
+
+// WithCSVComma sets the field delimiter (default ',').
+func WithCSVComma(r rune) CSVOption {
+	return func(o *csvOptions) { o.comma = r }
+}
+
+func newCSVOptions(opts []CSVOption) csvOptions {
+	o := csvOptions{idCol: 0, valueCol: -1, comma: ','}
+	for _, fn := range opts {
+		if fn != nil {
+			fn(&o)
+		}
+	}
+	return o
+}
+
+// layout returns the row width and, for each column, the coordinate index it
+// holds (or -1 for the ID/value columns).
+func (o csvOptions) layout(dim int) (int, []int, error) {
+	width := dim
+	for _, c := range []int{o.idCol, o.valueCol} {
+		if c >= 0 {
+			width++
+		} else if c != -1 {
+			return 0, nil, ErrInvalidCSVColumns
+		}
+	}
+	if o.idCol >= width || o.valueCol >= width || (o.idCol >= 0 && o.idCol == o.valueCol) {
+		return 0, nil, ErrInvalidCSVColumns
+	}
+	coordOf := make([]int, width)
+	d := 0
+	for c := range coordOf {
+		if c == o.idCol || c == o.valueCol {
+			coordOf[c] = -1
+			continue
+		}
+		coordOf[c] = d
+		d++
+	}
+	return width, coordOf, nil
+}
+
+// LoadPointsCSV reads points with dim coordinates from CSV. By default each
+// row is an ID followed by the coordinates; use WithCSVIDColumn and
+// WithCSVValueColumn to place the ID and value columns elsewhere. Coordinates
+// fill the remaining columns in order. Errors report the offending line.
+func LoadPointsCSV(r io.Reader, dim int, opts ...CSVOption) ([]KDPoint[string], error) {
+	if dim <= 0 {
+		return nil, ErrZeroDim
+	}
+	o := newCSVOptions(opts)
+	width, coordOf, err := o.layout(dim)
+	if err != nil {
+		return nil, err
+	}
+	cr := csv.NewReader(r)
+	cr.Comma = o.comma
+	cr.FieldsPerRecord = width
+	cr.ReuseRecord = true
+	var pts []KDPoint[string]
+	for first := true; ; first = false {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return pts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first && o.header {
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		p := KDPoint[string]{Coords: make([]float64, dim)}
+		for c, field := range rec {
+			switch {
+			case c == o.idCol:
+				p.ID = field
+			case c == o.valueCol:
+				p.Value = field
+			default:
+				v, err := strconv.ParseFloat(field, 64)
+				if err != nil {
+					return nil, fmt.Errorf("kdtree: csv line %d, column %d: %w", line, c+1, err)
+				}
+				p.Coords[coordOf[c]] = v
+			}
+		}
+		pts = append(pts, p)
+	}
+}
+
+// WritePointsCSV writes pts as CSV using the same column layout as
+// LoadPointsCSV, so the output round-trips. The dimension is taken from the
+// first point; points of a different dimension return ErrDimMismatch.
+func WritePointsCSV(w io.Writer, pts []KDPoint[string], opts ...CSVOption) error {
+	if len(pts) == 0 {
+		return nil
+	}
+	dim := len(pts[0].Coords)
+	if dim == 0 {
+		return ErrZeroDim
+	}
+	o := newCSVOptions(opts)
+	width, coordOf, err := o.layout(dim)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = o.comma
+	row := make([]string, width)
+	if o.header {
+		for c := range row {
+			switch {
+			case c == o.idCol:
+				row[c] = "id"
+			case c == o.valueCol:
+				row[c] = "value"
+			default:
+				row[c] = "x" + strconv.Itoa(coordOf[c])
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	for _, p := range pts {
+		if len(p.Coords) != dim {
+			return ErrDimMismatch
+		}
+		for c := range row {
+			switch {
+			case c == o.idCol:
+				row[c] = p.ID
+			case c == o.valueCol:
+				row[c] = p.Value
+			default:
+				row[c] = strconv.FormatFloat(p.Coords[coordOf[c]], 'g', -1, 64)
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,99 @@
+package poindexter
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLoadPointsCSV_Default(t *testing.T) {
+	pts, err := LoadPointsCSV(strings.NewReader("a,0,0\nb,3,4\n"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 2 || pts[1].ID != "b" || pts[1].Coords[0] != 3 || pts[1].Coords[1] != 4 {
+		t.Fatalf("unexpected points %+v", pts)
+	}
+	tr, err := NewKDTree(pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _, _ := tr.Nearest([]float64{2.5, 4}); p.ID != "b" {
+		t.Fatalf("expected b, got %q", p.ID)
+	}
+}
+
+func TestLoadPointsCSV_Columns(t *testing.T) {
+	in := "x;label;y;peer\n1.5;alpha;-2;p1\n"
+	pts, err := LoadPointsCSV(strings.NewReader(in), 2,
+		WithCSVIDColumn(3), WithCSVValueColumn(1), WithCSVHeader(true), WithCSVComma(';'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 1 {
+		t.Fatalf("expected 1 point, got %d", len(pts))
+	}
+	p := pts[0]
+	if p.ID != "p1" || p.Value != "alpha" || p.Coords[0] != 1.5 || p.Coords[1] != -2 {
+		t.Fatalf("unexpected point %+v", p)
+	}
+
+	pts, err = LoadPointsCSV(strings.NewReader("1,2,3\n"), 3, WithCSVIDColumn(-1))
+	if err != nil || len(pts) != 1 || pts[0].ID != "" || pts[0].Coords[2] != 3 {
+		t.Fatalf("no-ID layout: %+v err=%v", pts, err)
+	}
+}
+
+func TestLoadPointsCSV_Errors(t *testing.T) {
+	_, err := LoadPointsCSV(strings.NewReader("a,1,2\nb,1,oops\n"), 2)
+	if err == nil || !strings.Contains(err.Error(), "line 2, column 3") || !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatalf("expected line/column parse error, got %v", err)
+	}
+	if _, err := LoadPointsCSV(strings.NewReader("a,1,2\nb,1\n"), 2); err == nil {
+		t.Fatal("expected error for short row")
+	}
+	if _, err := LoadPointsCSV(strings.NewReader(""), 0); !errors.Is(err, ErrZeroDim) {
+		t.Fatalf("expected ErrZeroDim, got %v", err)
+	}
+	for _, opts := range [][]CSVOption{
+		{WithCSVIDColumn(3)},
+		{WithCSVValueColumn(0)},
+		{WithCSVIDColumn(-2)},
+	} {
+		if _, err := LoadPointsCSV(strings.NewReader("a,1,2\n"), 2, opts...); !errors.Is(err, ErrInvalidCSVColumns) {
+			t.Fatalf("expected ErrInvalidCSVColumns, got %v", err)
+		}
+	}
+}
+
+func TestWritePointsCSV_RoundTrip(t *testing.T) {
+	pts := []KDPoint[string]{
+		{ID: "a", Coords: []float64{0.1, -2}, Value: "x,y"},
+		{ID: "b", Coords: []float64{1e-9, 3}, Value: ""},
+	}
+	opts := []CSVOption{WithCSVValueColumn(1), WithCSVHeader(true)}
+	var buf bytes.Buffer
+	if err := WritePointsCSV(&buf, pts, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,value,x0,x1\na,\"x,y\",0.1,-2\nb,,1e-09,3\n"; buf.String() != want {
+		t.Fatalf("unexpected csv:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	got, err := LoadPointsCSV(&buf, 2, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range pts {
+		if got[i].ID != pts[i].ID || got[i].Value != pts[i].Value || !slices.Equal(got[i].Coords, pts[i].Coords) {
+			t.Fatalf("round-trip mismatch at %d: %+v vs %+v", i, got[i], pts[i])
+		}
+	}
+
+	bad := []KDPoint[string]{{ID: "a", Coords: []float64{1, 2}}, {ID: "b", Coords: []float64{1}}}
+	if err := WritePointsCSV(&buf, bad); !errors.Is(err, ErrDimMismatch) {
+		t.Fatalf("expected ErrDimMismatch, got %v", err)
+	}
+}
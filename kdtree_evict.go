@@ -0,0 +1,123 @@
+package poindexter
+
+import "errors"
+
+// ErrUnknownEvictionPolicy is returned by the constructors when WithMaxPoints
+// names a policy other than the EvictionPolicy constants.
+var ErrUnknownEvictionPolicy = errors.New("kdtree: unknown eviction policy")
+
+// EvictionPolicy chooses the point a full tree drops to make room for an
+// insert (see WithMaxPoints). Ties go to the earliest inserted point.
+type EvictionPolicy string
+
+const (
+	// EvictLRU drops the point a query selected least recently; points never
+	// selected go first. Selection times come from the tree's peer analytics,
+	// keyed like RecordSelection (WithPeerIDFunc), so ResetAnalytics makes
+	// every point count as never selected.
+	EvictLRU EvictionPolicy = "lru"
+	// EvictLeastSelected drops the point queries selected the fewest times,
+	// also from peer analytics.
+	EvictLeastSelected EvictionPolicy = "least-selected"
+	// EvictOldest drops the earliest inserted point (FIFO).
+	EvictOldest EvictionPolicy = "oldest"
+)
+
+// WithMaxPoints caps the tree at n points. Once it is full, each Insert first
+// evicts one point chosen by policy, so long-running peer trees stay bounded
+// without an external cleanup job. Choosing the victim scans every point, so
+// a capped Insert costs O(n). Evictions count as deletes in analytics.
+// NewKDTree keeps only the last n of the points it is given. n <= 0 means no
+// limit, the default.
+func WithMaxPoints(n int, policy EvictionPolicy) KDOption {
+	return func(o *kdOptions) {
+		o.maxPoints = n
+		o.evictPolicy = policy
+	}
+}
+
+// checkEvictPolicy validates the WithMaxPoints policy in cfg.
+func checkEvictPolicy(cfg kdOptions) error {
+	if cfg.maxPoints <= 0 {
+		return nil
+	}
+	switch cfg.evictPolicy {
+	case EvictLRU, EvictLeastSelected, EvictOldest:
+		return nil
+	}
+	return ErrUnknownEvictionPolicy
+}
+
+// evictionState records insertion order for a capped tree.
+type evictionState struct {
+	max    int
+	policy EvictionPolicy
+	seq    uint64            // sequence number of the last insert
+	born   map[string]uint64 // insert sequence by point ID
+}
+
+// newEvictionState returns the eviction state requested by cfg, or nil, with
+// pts recorded as inserted in order.
+func newEvictionState[T any](cfg kdOptions, pts []KDPoint[T]) *evictionState {
+	if cfg.maxPoints <= 0 {
+		return nil
+	}
+	e := &evictionState{max: cfg.maxPoints, policy: cfg.evictPolicy, born: make(map[string]uint64, len(pts))}
+	for _, p := range pts {
+		e.added(p.ID)
+	}
+	return e
+}
+
+// added records an insert of the point with the given ID.
+func (e *evictionState) added(id string) {
+	e.seq++
+	if id != "" {
+		e.born[id] = e.seq
+	}
+}
+
+// removed forgets the point with the given ID.
+func (e *evictionState) removed(id string) {
+	if id != "" {
+		delete(e.born, id)
+	}
+}
+
+func (e *evictionState) clone() *evictionState {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.born = make(map[string]uint64, len(e.born))
+	for id, s := range e.born {
+		c.born[id] = s
+	}
+	return &c
+}
+
+// evictVictim removes the point the eviction policy picks. Points without an
+// ID have no recorded insert and count as the oldest.
+func (t *KDTree[T]) evictVictim() {
+	e := t.evict
+	victim := -1
+	var bestCount float64
+	var bestLast, bestBorn int64
+	for i, p := range t.points {
+		born := int64(e.born[p.ID])
+		var count float64
+		var last int64
+		switch e.policy {
+		case EvictLRU:
+			_, last = t.peerAnalytics.selectionOf(t.peerKey(p))
+		case EvictLeastSelected:
+			count, _ = t.peerAnalytics.selectionOf(t.peerKey(p))
+		}
+		if victim < 0 || count < bestCount || (count == bestCount && (last < bestLast || (last == bestLast && born < bestBorn))) {
+			victim, bestCount, bestLast, bestBorn = i, count, last, born
+		}
+	}
+	if victim >= 0 {
+		t.removeAt(victim)
+	}
+}
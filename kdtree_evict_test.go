@@ -0,0 +1,103 @@
+package poindexter
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func evictIDs(tr *KDTree[int]) []string {
+	var ids []string
+	for _, p := range tr.Points() {
+		ids = append(ids, p.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func pt1(id string, x float64) KDPoint[int] { return KDPoint[int]{ID: id, Coords: []float64{x}} }
+
+func TestMaxPointsEvictOldest(t *testing.T) {
+	tr, err := NewKDTreeFromDim[int](1, WithMaxPoints(3, EvictOldest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range []string{"a", "b", "c", "d"} {
+		if !tr.Insert(pt1(id, float64(i))) {
+			t.Fatalf("insert %s failed", id)
+		}
+	}
+	if got := evictIDs(tr); !slices.Equal(got, []string{"b", "c", "d"}) {
+		t.Fatalf("points %v, want [b c d]", got)
+	}
+	// a duplicate insert must not evict anything
+	if tr.Insert(pt1("c", 9)) || tr.Len() != 3 {
+		t.Fatalf("duplicate insert changed the tree: %v", evictIDs(tr))
+	}
+	// deleting frees a slot
+	tr.DeleteByID("c")
+	tr.Insert(pt1("e", 4))
+	if got := evictIDs(tr); !slices.Equal(got, []string{"b", "d", "e"}) {
+		t.Fatalf("points %v, want [b d e]", got)
+	}
+	if n := tr.GetAnalyticsSnapshot().DeleteCount; n != 2 {
+		t.Fatalf("DeleteCount = %d, want 2 (one eviction, one delete)", n)
+	}
+}
+
+func TestMaxPointsEvictLRU(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[int]{pt1("a", 0), pt1("b", 10), pt1("c", 20)}, WithMaxPoints(3, EvictLRU))
+	tr.Nearest([]float64{0})
+	time.Sleep(2 * time.Millisecond)
+	tr.Nearest([]float64{20})
+	tr.Insert(pt1("d", 30)) // b was never selected
+	if got := evictIDs(tr); !slices.Equal(got, []string{"a", "c", "d"}) {
+		t.Fatalf("points %v, want [a c d]", got)
+	}
+	time.Sleep(2 * time.Millisecond)
+	tr.Nearest([]float64{30})
+	tr.Insert(pt1("e", 40)) // a was selected longest ago
+	if got := evictIDs(tr); !slices.Equal(got, []string{"c", "d", "e"}) {
+		t.Fatalf("points %v, want [c d e]", got)
+	}
+}
+
+func TestMaxPointsEvictLeastSelected(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[int]{pt1("a", 0), pt1("b", 10), pt1("c", 20)}, WithMaxPoints(3, EvictLeastSelected))
+	tr.KNearest([]float64{0}, 2)  // a, b
+	tr.KNearest([]float64{-1}, 1) // a
+	tr.KNearest([]float64{20}, 1) // c
+	tr.Insert(pt1("d", 30))       // b and c tie on one selection; b is older
+	if got := evictIDs(tr); !slices.Equal(got, []string{"a", "c", "d"}) {
+		t.Fatalf("points %v, want [a c d]", got)
+	}
+	if p, _, _ := tr.Nearest([]float64{11}); p.ID != "c" {
+		t.Fatalf("Nearest after eviction = %s, want c", p.ID)
+	}
+}
+
+func TestMaxPointsConstruction(t *testing.T) {
+	pts := []KDPoint[int]{pt1("a", 0), pt1("b", 1), pt1("c", 2), pt1("d", 3)}
+	tr, err := NewKDTree(pts, WithMaxPoints(2, EvictOldest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := evictIDs(tr); !slices.Equal(got, []string{"c", "d"}) {
+		t.Fatalf("points %v, want [c d]", got)
+	}
+	c := tr.Clone(false)
+	c.Insert(pt1("e", 4))
+	if got := evictIDs(c); !slices.Equal(got, []string{"d", "e"}) {
+		t.Fatalf("clone points %v, want [d e]", got)
+	}
+	if got := evictIDs(tr); !slices.Equal(got, []string{"c", "d"}) {
+		t.Fatalf("original changed by clone insert: %v", got)
+	}
+	if _, err := NewKDTree(pts, WithMaxPoints(2, "random")); !errors.Is(err, ErrUnknownEvictionPolicy) {
+		t.Fatalf("err = %v, want ErrUnknownEvictionPolicy", err)
+	}
+	if _, err := NewKDTreeFromDim[int](1, WithMaxPoints(0, "random")); err != nil {
+		t.Fatalf("uncapped tree rejected policy: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package poindexter
+
+import (
+	"math"
+	"time"
+)
+
+// KNearestExcluding is KNearest leaving out the points whose IDs are in
+// excludeIDs, such as recently failed peers or the querying node itself. The
+// exclusions are applied inside the search, so it still returns up to k
+// points without the caller over-fetching. Points without an ID are never
+// excluded. An empty excludeIDs behaves exactly like KNearest.
+func (t *KDTree[T]) KNearestExcluding(query []float64, k int, excludeIDs map[string]struct{}) ([]KDPoint[T], []float64) {
+	if len(excludeIDs) == 0 {
+		return t.KNearest(query, k)
+	}
+	if v := t.cowView(); v != nil {
+		return v.KNearestExcluding(query, k, excludeIDs)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	if k <= 0 || len(query) != t.dim || t.Len() == 0 {
+		return nil, nil
+	}
+	start := t.queryStart()
+	defer func() {
+		if t.analytics != nil {
+			t.analytics.RecordQuery(time.Since(start).Nanoseconds())
+		}
+	}()
+
+	skip := func(p KDPoint[T]) bool {
+		_, ok := excludeIDs[p.ID]
+		return ok && p.ID != ""
+	}
+	var h neighborHeap[T]
+	t.search(query, k, math.Inf(1), skip, &h)
+	t.recordResults(query, h.pts, h.dists)
+	return h.pts, h.dists
+}
+
+// knnSearch fills h, in ascending order, with the k nearest points for which
+// skip, if not nil, is false.
+func (t *KDTree[T]) knnSearch(query []float64, k int, skip func(KDPoint[T]) bool, h *neighborHeap[T]) {
+	if ix := t.queryIndex(); ix != nil {
+		var skipIdx func(int) bool
+		if skip != nil {
+			skipIdx = func(idx int) bool { return skip(ix.points[idx]) }
+		}
+		idxs, dists := indexKNearest[T](ix.data, query, k, skipIdx)
+		// approximate backends may come up short; count what they could find
+		avail := len(t.points)
+		if len(idxs) < k && skip != nil {
+			for _, p := range t.points {
+				if skip(p) {
+					avail--
+				}
+			}
+		}
+		if len(idxs) >= min(k, avail) {
+			for i, idx := range idxs {
+				h.pts = append(h.pts, ix.points[idx])
+				h.dists = append(h.dists, dists[i])
+			}
+			return
+		}
+	}
+	scanDistances(t.metric, query, t.points, func(i int, d float64) {
+		if skip == nil || !skip(t.points[i]) {
+			h.offer(t.points[i], d, k)
+		}
+	})
+	h.sort()
+}
+
+// NearestToID returns up to k neighbours of the point with the given ID,
+// nearest first, using its coordinates as the query and leaving the point
+// itself out: "which peers are closest to this one". It returns nil if no
+// point has the ID. Snapshots and copy-on-write reads find the point by
+// scanning, so there the lookup costs O(n).
+func (t *KDTree[T]) NearestToID(id string, k int) ([]KDPoint[T], []float64) {
+	if v := t.cowView(); v != nil {
+		return v.NearestToID(id, k)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	p, ok := t.pointByID(id)
+	if !ok || k <= 0 {
+		return nil, nil
+	}
+	start := t.queryStart()
+	defer func() {
+		if t.analytics != nil {
+			t.analytics.RecordQuery(time.Since(start).Nanoseconds())
+		}
+	}()
+
+	var h neighborHeap[T]
+	t.search(p.Coords, k, math.Inf(1), func(q KDPoint[T]) bool { return q.ID == id }, &h)
+	t.recordResults(p.Coords, h.pts, h.dists)
+	return h.pts, h.dists
+}
+
+// pointByID finds the point with the given ID. Frozen views carry no ID
+// index and scan their points.
+func (t *KDTree[T]) pointByID(id string) (KDPoint[T], bool) {
+	if id == "" {
+		return KDPoint[T]{}, false
+	}
+	if t.idIndex == nil {
+		for _, p := range t.points {
+			if p.ID == id {
+				return p, true
+			}
+		}
+		return KDPoint[T]{}, false
+	}
+	i, ok := t.idIndex[id]
+	if !ok {
+		return KDPoint[T]{}, false
+	}
+	return t.points[i], true
+}
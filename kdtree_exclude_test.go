@@ -0,0 +1,85 @@
+package poindexter
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func TestKNearestExcludingMatchesBruteForce(t *testing.T) {
+	pts := makePoints(500, 3)
+	exclude := map[string]struct{}{}
+	for i := 0; i < len(pts); i += 3 {
+		exclude[fmt.Sprint(i)] = struct{}{}
+	}
+	for _, b := range []KDBackend{BackendLinear, BackendGonum, BackendVPTree, BackendCoverTree, BackendRTree, BackendHNSW} {
+		tr, err := NewKDTree(pts, WithBackend(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, q := range [][]float64{{0.5, 0.5, 0.5}, {0, 0, 0}, {1, 0.2, 0.9}} {
+			var want []float64
+			for _, p := range pts {
+				if _, ok := exclude[p.ID]; !ok {
+					want = append(want, EuclideanDistance{}.Distance(q, p.Coords))
+				}
+			}
+			sort.Float64s(want)
+			for _, k := range []int{1, 10, 400} {
+				got, dists := tr.KNearestExcluding(q, k, exclude)
+				if len(got) != min(k, len(want)) {
+					t.Fatalf("%s k=%d: got %d neighbours, want %d", b, k, len(got), min(k, len(want)))
+				}
+				for i, p := range got {
+					if _, ok := exclude[p.ID]; ok {
+						t.Fatalf("%s k=%d: returned excluded point %s", b, k, p.ID)
+					}
+					// HNSW is approximate; only check that it filtered
+					if b != BackendHNSW && dists[i] != want[i] {
+						t.Fatalf("%s k=%d: dist %d = %v, want %v", b, k, i, dists[i], want[i])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestKNearestExcludingSelf(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[int]{pt1("a", 0), pt1("b", 1), pt1("c", 3)}, WithPointWeight(func(p KDPoint[int]) float64 {
+		if p.ID == "c" {
+			return 4
+		}
+		return 1
+	}))
+	got, dists := tr.KNearestExcluding([]float64{0}, 2, map[string]struct{}{"a": {}})
+	if len(got) != 2 || got[0].ID != "c" || dists[0] != 0.75 || got[1].ID != "b" {
+		t.Fatalf("KNearestExcluding = %v %v, want c then b", got, dists)
+	}
+	if got, _ := tr.KNearestExcluding([]float64{0}, 1, nil); got[0].ID != "a" {
+		t.Fatalf("no exclusions: got %s, want a", got[0].ID)
+	}
+}
+
+func TestNearestToID(t *testing.T) {
+	pts := []KDPoint[int]{pt1("a", 0), pt1("b", 1), pt1("c", 3), pt1("d", 7)}
+	for _, cow := range []bool{false, true} {
+		opts := []KDOption{WithBackend(BackendVPTree)}
+		if cow {
+			opts = append(opts, WithCopyOnWrite())
+		}
+		tr, err := NewKDTree(pts, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, dists := tr.NearestToID("b", 2)
+		if len(got) != 2 || got[0].ID != "a" || dists[0] != 1 || got[1].ID != "c" || dists[1] != 2 {
+			t.Fatalf("cow=%v: NearestToID(b) = %v %v, want a, c", cow, got, dists)
+		}
+		if got, _ := tr.NearestToID("d", 10); len(got) != 3 {
+			t.Fatalf("cow=%v: NearestToID(d, 10) returned %d points, want 3", cow, len(got))
+		}
+		if got, _ := tr.NearestToID("missing", 2); got != nil {
+			t.Fatalf("cow=%v: unknown ID returned %v", cow, got)
+		}
+	}
+}
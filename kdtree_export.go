@@ -0,0 +1,50 @@
+package poindexter
+
+import "time"
+
+// TreeExport is a point-in-time export of a tree's points together with its
+// analytics, taken by ExportSnapshot.
+type TreeExport[T any] struct {
+	// Epoch is the tree's mutation counter when the export was taken; two
+	// exports with the same Epoch hold the same points.
+	Epoch   uint64       `json:"epoch"`
+	TakenAt time.Time    `json:"takenAt"`
+	Dim     int          `json:"dim"`
+	Backend KDBackend    `json:"backend"`
+	Points  []KDPoint[T] `json:"points"`
+	// Analytics, PeerStats and ResultDistances are as GetAnalyticsSnapshot,
+	// GetPeerStats and GetResultDistanceDistribution would report them.
+	Analytics       TreeAnalyticsSnapshot `json:"analytics"`
+	PeerStats       []PeerStats           `json:"peerStats"`
+	ResultDistances DistributionStats     `json:"resultDistances"`
+}
+
+// ExportSnapshot captures the tree's points, analytics and peer stats at a
+// single epoch, so an export endpoint can serve them together without the
+// torn state that composing Points and GetAnalyticsSnapshot can observe when
+// a mutation lands in between: Points, Epoch and the insert and delete counts
+// always agree.
+//
+// Mutations are held off while the export is taken when the tree serializes
+// its writers (WithCopyOnWrite or a RebuildAfter policy); otherwise, as with
+// every other method, callers must not mutate the tree concurrently. Queries
+// are never blocked, so query-driven counters and peer stats reflect the
+// queries that completed by the time each was read. Point coordinates are
+// shared with the tree, which never modifies them in place.
+func (t *KDTree[T]) ExportSnapshot() TreeExport[T] {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	return TreeExport[T]{
+		Epoch:           t.version.Load(),
+		TakenAt:         time.Now(),
+		Dim:             t.dim,
+		Backend:         t.backend,
+		Points:          append([]KDPoint[T](nil), t.points...),
+		Analytics:       t.GetAnalyticsSnapshot(),
+		PeerStats:       t.GetPeerStats(),
+		ResultDistances: t.GetResultDistanceDistribution(),
+	}
+}
@@ -0,0 +1,69 @@
+package poindexter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestExportSnapshot_Consistent(t *testing.T) {
+	tr, err := NewKDTreeFromDim[int](2, WithCopyOnWrite())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				id := fmt.Sprintf("%d-%d", w, i)
+				tr.Insert(KDPoint[int]{ID: id, Coords: []float64{float64(w), float64(i)}})
+				if i%3 == 0 {
+					tr.DeleteByID(id)
+				}
+				tr.Nearest([]float64{0, 0})
+			}
+		}(w)
+	}
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	for exports := 0; ; exports++ {
+		e := tr.ExportSnapshot()
+		if n := e.Analytics.InsertCount - e.Analytics.DeleteCount; int64(len(e.Points)) != n {
+			t.Fatalf("torn export: %d points, %d inserts - %d deletes", len(e.Points), e.Analytics.InsertCount, e.Analytics.DeleteCount)
+		}
+		if e.Epoch != uint64(e.Analytics.InsertCount+e.Analytics.DeleteCount) {
+			t.Fatalf("epoch %d after %d mutations", e.Epoch, e.Analytics.InsertCount+e.Analytics.DeleteCount)
+		}
+		select {
+		case <-done:
+			if exports == 0 {
+				t.Log("writers finished before the first export")
+			}
+			return
+		default:
+		}
+	}
+}
+
+func TestExportSnapshot_Fields(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[string]{{ID: "a", Coords: []float64{0}, Value: "x"}}, WithResultDistanceTracking(0))
+	tr.Nearest([]float64{1})
+	e := tr.ExportSnapshot()
+	if e.Dim != 1 || e.Backend != tr.Backend() || len(e.Points) != 1 || e.TakenAt.IsZero() {
+		t.Fatalf("export: %+v", e)
+	}
+	if e.Analytics.QueryCount != 1 || len(e.PeerStats) != 1 || e.PeerStats[0].PeerID != "a" || e.ResultDistances.Count != 1 {
+		t.Fatalf("analytics: %+v", e)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back TreeExport[string]
+	if err := json.Unmarshal(b, &back); err != nil || back.Points[0].Value != "x" || back.Epoch != e.Epoch {
+		t.Fatalf("json round trip: %v %+v", err, back)
+	}
+}
@@ -0,0 +1,16 @@
+package poindexter
+
+import "expvar"
+
+// PublishExpvar publishes tree's analytics snapshot as the expvar variable
+// name, so services that already serve /debug/vars (via net/http's default
+// mux) expose query counts, latency percentiles and rebuild times without a
+// metrics library. The snapshot is taken afresh on each read and marshals
+// with the same JSON field names as TreeAnalyticsSnapshot; a tree built
+// without analytics publishes zeros. Like expvar.Publish, it panics if name
+// is already published.
+func PublishExpvar[T any](name string, tree *KDTree[T]) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return tree.GetAnalyticsSnapshot()
+	}))
+}
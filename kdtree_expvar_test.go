@@ -0,0 +1,48 @@
+package poindexter
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	tree, err := NewKDTree([]KDPoint[int]{
+		{ID: "a", Coords: []float64{0, 0}},
+		{ID: "b", Coords: []float64{1, 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	PublishExpvar("poindexter_test_tree", tree)
+	tree.Nearest([]float64{0.1, 0.1})
+	tree.KNearest([]float64{0.9, 0.9}, 2)
+
+	v := expvar.Get("poindexter_test_tree")
+	if v == nil {
+		t.Fatal("variable not published")
+	}
+	var got TreeAnalyticsSnapshot
+	if err := json.Unmarshal([]byte(v.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.QueryCount != 2 || got.P50QueryTimeNs > got.P999QueryTimeNs {
+		t.Fatalf("unexpected snapshot %+v", got)
+	}
+
+	// each read reflects the live tree
+	tree.Nearest([]float64{0, 0})
+	if err := json.Unmarshal([]byte(v.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.QueryCount != 3 {
+		t.Fatalf("expected 3 queries, got %d", got.QueryCount)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic on reuse of the name")
+		}
+	}()
+	PublishExpvar("poindexter_test_tree", tree)
+}
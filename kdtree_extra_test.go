@@ -114,3 +114,167 @@ func TestNearest_QueryDimMismatch(t *testing.T) {
 		t.Fatalf("expected ok=false for query dim mismatch")
 	}
 }
+
+func TestRadiusAppend_MatchesRadius(t *testing.T) {
+	for _, be := range []KDBackend{BackendLinear, BackendGonum} {
+		tr, _ := NewKDTree(makePoints(200, 2), WithBackend(be))
+		q := []float64{0.5, 0.5}
+		want, wantD := tr.Radius(q, 0.2)
+		prefix := []Neighbor[int]{{Distance: -1}}
+		got := tr.RadiusAppend(q, 0.2, prefix)
+		if got[0].Distance != -1 {
+			t.Fatalf("%s: existing dst element overwritten", be)
+		}
+		got = got[1:]
+		if len(got) != len(want) {
+			t.Fatalf("%s: want %d results, got %d", be, len(want), len(got))
+		}
+		for i := range got {
+			if got[i].Distance != wantD[i] {
+				t.Fatalf("%s: distance mismatch at %d: %v vs %v", be, i, got[i].Distance, wantD[i])
+			}
+		}
+		if tr.Analytics().AvgRadiusResults() != float64(len(want)) {
+			t.Fatalf("%s: avg radius results want %d, got %v", be, len(want), tr.Analytics().AvgRadiusResults())
+		}
+	}
+}
+
+func TestRadiusAppend_ReusesBuffer(t *testing.T) {
+	tr, _ := NewKDTree(makePoints(100, 2), WithBackend(BackendLinear))
+	tr.peerAnalytics = nil // isolate buffer reuse from per-peer map growth
+	q := []float64{0.5, 0.5}
+	buf := tr.RadiusAppend(q, 0.3, nil)
+	allocs := testing.AllocsPerRun(20, func() {
+		buf = tr.RadiusAppend(q, 0.3, buf[:0])
+	})
+	if allocs > 0 {
+		t.Fatalf("expected no allocations with a reused buffer, got %v", allocs)
+	}
+	if out := tr.RadiusAppend([]float64{0}, 1, buf[:0]); len(out) != 0 {
+		t.Fatalf("expected no results for dim mismatch")
+	}
+}
+
+func TestWithCoordValidator(t *testing.T) {
+	errRange := errors.New("coordinate out of [0,1]")
+	unit := WithCoordValidator(func(c []float64) error {
+		for _, v := range c {
+			if v < 0 || v > 1 {
+				return errRange
+			}
+		}
+		return nil
+	})
+
+	_, err := NewKDTree([]KDPoint[int]{
+		{ID: "ok", Coords: []float64{0.5, 0.5}},
+		{ID: "bad", Coords: []float64{0.5, 2}},
+	}, unit)
+	if !errors.Is(err, ErrInvalidCoords) || !errors.Is(err, errRange) {
+		t.Fatalf("want ErrInvalidCoords wrapping validator error, got %v", err)
+	}
+	if want := `kdtree: invalid coordinates: point "bad": coordinate out of [0,1]`; err.Error() != want {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+
+	tr, err := NewKDTreeFromDim[int](2, unit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Insert(KDPoint[int]{ID: "x", Coords: []float64{-1, 0}}) {
+		t.Fatal("expected Insert to reject invalid coordinates")
+	}
+	if err := tr.ValidateCoords([]float64{-1, 0}); !errors.Is(err, errRange) {
+		t.Fatalf("want validator error, got %v", err)
+	}
+	if err := tr.ValidateCoords([]float64{0}); !errors.Is(err, ErrDimMismatch) {
+		t.Fatalf("want ErrDimMismatch, got %v", err)
+	}
+	if !tr.Insert(KDPoint[int]{ID: "y", Coords: []float64{1, 0}}) || tr.Len() != 1 {
+		t.Fatal("expected valid point to be inserted")
+	}
+}
+
+func TestDeleteWhere(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[int]{
+		{ID: "a", Coords: []float64{0}, Value: 1},
+		{ID: "b", Coords: []float64{1}, Value: 2},
+		{ID: "c", Coords: []float64{2}, Value: 3},
+		{Coords: []float64{3}, Value: 4},
+	})
+	n := tr.DeleteWhere(func(p KDPoint[int]) bool { return p.Value%2 == 0 })
+	if n != 2 || tr.Len() != 2 {
+		t.Fatalf("expected 2 removed and 2 left, got %d/%d", n, tr.Len())
+	}
+	if pts := tr.Points(); pts[0].ID != "a" || pts[1].ID != "c" {
+		t.Fatalf("expected order preserved, got %+v", pts)
+	}
+	if !tr.DeleteByID("c") || tr.DeleteByID("b") {
+		t.Fatal("idIndex not updated after DeleteWhere")
+	}
+	if tr.DeleteWhere(func(KDPoint[int]) bool { return false }) != 0 {
+		t.Fatal("expected no-op")
+	}
+}
+
+func TestClone_Independent(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[string]{
+		{ID: "a", Coords: []float64{0, 0}, Value: "A"},
+		{ID: "b", Coords: []float64{5, 5}, Value: "B"},
+	}, WithResultDistanceTracking(8))
+	tr.Nearest([]float64{1, 1})
+
+	c := tr.Clone(false)
+	if c.Len() != 2 || c.Dim() != 2 || c.Backend() != tr.Backend() {
+		t.Fatalf("clone mismatch: len=%d dim=%d", c.Len(), c.Dim())
+	}
+	if got := c.GetAnalyticsSnapshot().QueryCount; got != 1 {
+		t.Fatalf("expected inherited QueryCount=1, got %d", got)
+	}
+	if got := c.GetResultDistanceDistribution().Count; got != 1 {
+		t.Fatalf("expected inherited result distances, got %d", got)
+	}
+
+	// Mutating the original must not affect the clone, and vice versa.
+	tr.Points()[0].Coords[0] = 100 // Points copies the slice but shares coords
+	tr.DeleteByID("a")
+	tr.Insert(KDPoint[string]{ID: "c", Coords: []float64{0, 0}})
+	tr.Nearest([]float64{0, 0})
+	if p, d, _ := c.Nearest([]float64{0, 0}); p.ID != "a" || d != 0 {
+		t.Fatalf("clone affected by original: nearest %q d=%v", p.ID, d)
+	}
+	if got := c.GetPeerStats(); len(got) != 1 || got[0].PeerID != "a" || got[0].SelectionCount != 2 {
+		t.Fatalf("unexpected clone peer stats %+v", got)
+	}
+	if !c.DeleteByID("b") || tr.Len() != 2 {
+		t.Fatal("clone ID index not independent")
+	}
+
+	fresh := tr.Clone(true)
+	if s := fresh.GetAnalyticsSnapshot(); s.QueryCount != 0 || s.InsertCount != 0 {
+		t.Fatalf("expected reset analytics, got %+v", s)
+	}
+	if len(fresh.GetPeerStats()) != 0 {
+		t.Fatal("expected reset peer analytics")
+	}
+	if fresh.GetResultDistanceDistribution().Count != 0 {
+		t.Fatal("expected reset result distribution")
+	}
+}
+
+func TestRebuild_LinearNoop(t *testing.T) {
+	tr, err := NewKDTree([]KDPoint[int]{{ID: "a", Coords: []float64{0}}},
+		WithBackend(BackendLinear), WithAutoRebuild(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.Insert(KDPoint[int]{ID: "b", Coords: []float64{1}})
+	tr.Rebuild()
+	if got := tr.GetAnalyticsSnapshot().BackendRebuildCnt; got != 0 {
+		t.Fatalf("linear backend should not rebuild, got %d", got)
+	}
+	if p, _, _ := tr.Nearest([]float64{0.9}); p.ID != "b" {
+		t.Fatalf("expected b, got %q", p.ID)
+	}
+}
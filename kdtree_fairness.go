@@ -0,0 +1,76 @@
+package poindexter
+
+import (
+	"math"
+	"slices"
+)
+
+// SelectionFairness summarises how evenly selections are spread across
+// tracked peers. Load concentrating on a handful of peers shows up as
+// NormalizedEntropy falling towards 0 and Gini rising towards 1.
+type SelectionFairness struct {
+	// Peers is the number of tracked peers.
+	Peers int `json:"peers"`
+	// Entropy is the Shannon entropy of the selection shares, in bits.
+	Entropy float64 `json:"entropy"`
+	// NormalizedEntropy is Entropy divided by its maximum, log2(Peers): 1
+	// when every peer is selected equally, 0 when one peer takes everything.
+	NormalizedEntropy float64 `json:"normalizedEntropy"`
+	// Gini is the Gini coefficient of the selection counts: 0 for perfect
+	// equality, approaching 1 as selections concentrate on one peer.
+	Gini float64 `json:"gini"`
+}
+
+// ComputeSelectionFairness computes the fairness of the DecayedCount values in
+// stats, so decay set with WithSelectionHalfLife applies. With a single peer
+// selection is trivially even (NormalizedEntropy 1, Gini 0); with no
+// selections at all it returns a zero SelectionFairness apart from Peers.
+func ComputeSelectionFairness(stats []PeerStats) SelectionFairness {
+	f := SelectionFairness{Peers: len(stats)}
+	counts := make([]float64, 0, len(stats))
+	total := 0.0
+	for _, s := range stats {
+		c := math.Max(0, s.DecayedCount)
+		counts = append(counts, c)
+		total += c
+	}
+	if total == 0 {
+		return f
+	}
+	if len(counts) == 1 {
+		f.NormalizedEntropy = 1
+		return f
+	}
+
+	for _, c := range counts {
+		if c > 0 {
+			p := c / total
+			f.Entropy -= p * math.Log2(p)
+		}
+	}
+	f.NormalizedEntropy = f.Entropy / math.Log2(float64(len(counts)))
+
+	// G = 2·Σ i·x_i / (n·Σ x) − (n+1)/n over counts sorted ascending, i from 1.
+	slices.Sort(counts)
+	n := float64(len(counts))
+	weighted := 0.0
+	for i, c := range counts {
+		weighted += float64(i+1) * c
+	}
+	f.Gini = math.Max(0, 2*weighted/(n*total)-(n+1)/n)
+	return f
+}
+
+// Fairness returns the SelectionFairness of the tracked peers.
+func (p *PeerAnalytics) Fairness() SelectionFairness {
+	return ComputeSelectionFairness(p.GetAllPeerStats())
+}
+
+// GetSelectionFairness returns how evenly queries have selected peers, or a
+// zero SelectionFairness if peer analytics are off.
+func (t *KDTree[T]) GetSelectionFairness() SelectionFairness {
+	if t.peerAnalytics == nil {
+		return SelectionFairness{}
+	}
+	return t.peerAnalytics.Fairness()
+}
@@ -0,0 +1,64 @@
+package poindexter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeSelectionFairness(t *testing.T) {
+	peers := func(counts ...float64) []PeerStats {
+		out := make([]PeerStats, len(counts))
+		for i, c := range counts {
+			out[i] = PeerStats{DecayedCount: c}
+		}
+		return out
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	even := ComputeSelectionFairness(peers(5, 5, 5, 5))
+	if even.Peers != 4 || !near(even.Entropy, 2) || !near(even.NormalizedEntropy, 1) || !near(even.Gini, 0) {
+		t.Fatalf("even: %+v", even)
+	}
+
+	skewed := ComputeSelectionFairness(peers(0, 0, 0, 12))
+	if !near(skewed.NormalizedEntropy, 0) || !near(skewed.Gini, 0.75) {
+		t.Fatalf("concentrated: %+v", skewed)
+	}
+
+	mid := ComputeSelectionFairness(peers(1, 3))
+	if mid.NormalizedEntropy <= 0 || mid.NormalizedEntropy >= 1 || !near(mid.Gini, 0.25) {
+		t.Fatalf("uneven: %+v", mid)
+	}
+
+	if f := ComputeSelectionFairness(peers(7)); f.NormalizedEntropy != 1 || f.Gini != 0 {
+		t.Fatalf("single peer: %+v", f)
+	}
+	if f := ComputeSelectionFairness(nil); f != (SelectionFairness{}) {
+		t.Fatalf("no peers: %+v", f)
+	}
+}
+
+func TestKDTreeSelectionFairness(t *testing.T) {
+	pts := []KDPoint[int]{
+		{ID: "a", Coords: []float64{0}},
+		{ID: "b", Coords: []float64{10}},
+	}
+	tree, err := NewKDTree(pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 9; i++ {
+		tree.Nearest([]float64{1})
+	}
+	tree.Nearest([]float64{9})
+	f := tree.GetSelectionFairness()
+	if f.Peers != 2 || !(f.Gini > 0.3) || !(f.NormalizedEntropy < 0.5) {
+		t.Fatalf("fairness %+v", f)
+	}
+
+	off, _ := NewKDTree(pts, WithPeerAnalytics(false))
+	off.Nearest([]float64{1})
+	if off.GetSelectionFairness() != (SelectionFairness{}) {
+		t.Fatal("expected zero fairness without peer analytics")
+	}
+}
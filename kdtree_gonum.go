@@ -4,15 +4,20 @@ package poindexter
 
 import (
 	"math"
+	"math/bits"
+	"runtime"
 	"sort"
+	"sync"
 )
 
 // Note: This file is compiled when built with the "gonum" tag. For now, we
 // provide an internal KD-tree backend that performs balanced median-split
-// construction and branch-and-bound queries. This gives sub-linear behavior on
-// suitable datasets without introducing an external dependency. The public API
-// and option names remain the same; a future change can swap this implementation
-// to use gonum.org/v1/gonum/spatial/kdtree without altering callers.
+// construction, path-copying incremental inserts, tombstoned deletes and
+// branch-and-bound queries.
+// This gives sub-linear behavior on suitable datasets without introducing an
+// external dependency. The public API and option names remain the same; a
+// future change can swap this implementation to use
+// gonum.org/v1/gonum/spatial/kdtree without altering callers.
 
 // hasGonum reports whether the optimized backend is compiled in.
 func hasGonum() bool { return true }
@@ -24,6 +29,9 @@ type kdNode struct {
 	val   float64
 	left  *kdNode
 	right *kdNode
+	// bucket, when non-nil, makes the node a leaf holding these point
+	// indices (WithLeafSize); axis, idx and val are then unused.
+	bucket []int
 }
 
 // kdBackend holds the KD-tree root and metadata.
@@ -34,22 +42,66 @@ type kdBackend struct {
 	// Access to original coords by index is done via a closure we capture at build
 	coords func(i int) []float64
 	len    int
+	// depth is the height of the tree and inserts the number of points added
+	// by gonumInsert since it was built; together they trigger rebalancing.
+	depth   int
+	inserts int
+	// tomb marks points deleted by gonumDelete (a bitset over indices);
+	// queries skip them. It is never modified in place, only replaced.
+	tomb  []uint64
+	tombs int
+	// periods holds the axis periods of a PeriodicMetric (its Base unset);
+	// empty otherwise. Splitting values and routed queries use coordinates
+	// mapped into [0, period) on periodic axes.
+	periods PeriodicMetric
+	// leafSize is the most points a partition may hold before it is split
+	// (WithLeafSize); 1 gives every point a node of its own.
+	leafSize int
+}
+
+// points yields the indices of the points stored at n: its bucket for a
+// leaf, otherwise its single point.
+func (n *kdNode) points(yield func(int) bool) {
+	if n.bucket == nil {
+		yield(n.idx)
+		return
+	}
+	for _, i := range n.bucket {
+		if !yield(i) {
+			return
+		}
+	}
+}
+
+// dead reports whether point i has been tombstoned.
+func (b *kdBackend) dead(i int) bool {
+	w := i >> 6
+	return w < len(b.tomb) && b.tomb[w]&(1<<(uint(i)&63)) != 0
 }
 
 // buildGonumBackend builds a balanced KD-tree using variance-based axis choice
-// and median splits. It does not reorder the external points slice; it keeps
-// indices and accesses the original data via closures, preserving caller order.
-func buildGonumBackend[T any](points []KDPoint[T], metric DistanceMetric) (any, error) {
+// and median splits, down to partitions of at most leafSize points. It does
+// not reorder the external points slice; it keeps indices and accesses the
+// original data via closures, preserving caller order.
+func buildGonumBackend[T any](points []KDPoint[T], metric DistanceMetric, leafSize int) (any, error) {
 	// Only enable this backend for metrics where the axis-slab bound is valid
 	// for pruning: L2/L1/L∞. For other metrics (e.g., Cosine), fall back.
-	switch metric.(type) {
+	// Periodic variants of these keep the bound: the wrapped offset along
+	// one axis still never exceeds the distance.
+	var periods PeriodicMetric
+	base := metric
+	if pm, ok := metric.(PeriodicMetric); ok {
+		periods, base = PeriodicMetric{Periods: pm.Periods}, pm.Base
+	}
+	switch base.(type) {
 	case EuclideanDistance, ManhattanDistance, ChebyshevDistance:
 		// supported
 	default:
 		return nil, ErrBackendUnavailable
 	}
+	leafSize = max(leafSize, 1)
 	if len(points) == 0 {
-		return &kdBackend{root: nil, dim: 0, metric: metric, coords: func(int) []float64 { return nil }}, nil
+		return &kdBackend{root: nil, dim: 0, metric: metric, coords: func(int) []float64 { return nil }, periods: periods, leafSize: leafSize}, nil
 	}
 	dim := len(points[0].Coords)
 	coords := func(i int) []float64 { return points[i].Coords }
@@ -57,8 +109,106 @@ func buildGonumBackend[T any](points []KDPoint[T], metric DistanceMetric) (any,
 	for i := range idxs {
 		idxs[i] = i
 	}
-	root := buildKDRecursive(idxs, coords, dim, 0)
-	return &kdBackend{root: root, dim: dim, metric: metric, coords: coords, len: len(points)}, nil
+	split := coords
+	if periods.Periods != nil {
+		canon := make([][]float64, len(points))
+		for i := range points {
+			canon[i] = periods.canonical(points[i].Coords)
+		}
+		split = func(i int) []float64 { return canon[i] }
+	}
+	root := buildKD(idxs, split, dim, leafSize, runtime.GOMAXPROCS(0))
+	// median splits give a tree of height ceil(log2(n+1)), less with buckets
+	return &kdBackend{root: root, dim: dim, metric: metric, coords: coords, len: len(points), depth: bits.Len(uint(len(points))), periods: periods, leafSize: leafSize}, nil
+}
+
+// gonumInsert adds the last element of points to the backend, where points is
+// the backend's point set plus one appended point. Only the nodes on the new
+// point's root-to-leaf path are copied; the rest are shared, so the original
+// backend stays valid for queries already running against it. It reports
+// false when the backend cannot take the insert or the tree has drifted far
+// enough from balanced (height beyond twice the optimum, more points added
+// incrementally than it was built with, or a leaf bucket grown past twice the
+// leaf size) that a full rebuild is due.
+func gonumInsert[T any](backend any, points []KDPoint[T]) (any, bool) {
+	b, ok := backend.(*kdBackend)
+	if !ok || b.root == nil || len(points) != b.len+1 {
+		return nil, false
+	}
+	idx := len(points) - 1
+	c := points[idx].Coords
+	if len(c) != b.dim {
+		return nil, false
+	}
+	c = b.periods.canonical(c)
+	nb := &kdBackend{
+		dim:      b.dim,
+		metric:   b.metric,
+		coords:   func(i int) []float64 { return points[i].Coords },
+		len:      len(points),
+		depth:    b.depth,
+		inserts:  b.inserts + 1,
+		tomb:     b.tomb,
+		tombs:    b.tombs,
+		periods:  b.periods,
+		leafSize: b.leafSize,
+	}
+	root := *b.root
+	nb.root = &root
+	n, depth := nb.root, 1
+	for n.bucket == nil {
+		depth++
+		// same side convention as pushChildren: ties go right
+		next := &n.left
+		if c[n.axis] >= n.val {
+			next = &n.right
+		}
+		if *next == nil {
+			axis := (n.axis + 1) % b.dim
+			*next = &kdNode{axis: axis, idx: idx, val: c[axis]}
+			if b.leafSize > 1 {
+				*next = &kdNode{idx: -1, bucket: []int{idx}}
+			}
+			n = nil
+			break
+		}
+		cp := **next
+		*next = &cp
+		n = &cp
+	}
+	if n != nil {
+		// n is the copied leaf; its bucket may be shared, so never append in place
+		if len(n.bucket) >= 2*b.leafSize {
+			return nil, false
+		}
+		n.bucket = append(n.bucket[:len(n.bucket):len(n.bucket)], idx)
+	}
+	nb.depth = max(nb.depth, depth)
+	if nb.depth > 2*bits.Len(uint(nb.len))+2 || nb.inserts*2 > nb.len {
+		return nil, false
+	}
+	return nb, true
+}
+
+// gonumDelete returns a backend in which point idx is tombstoned: it stays in
+// the tree structure but queries no longer return it. The original backend is
+// unchanged. It reports false when idx is out of range or already deleted, or
+// when tombstones would exceed maxRatio of the indexed points, at which point
+// the tree should be compacted by a full rebuild.
+func gonumDelete(backend any, idx int, maxRatio float64) (any, bool) {
+	b, ok := backend.(*kdBackend)
+	if !ok || b.root == nil || idx < 0 || idx >= b.len || b.dead(idx) {
+		return nil, false
+	}
+	if float64(b.tombs+1) > maxRatio*float64(b.len) {
+		return nil, false
+	}
+	nb := *b
+	nb.tomb = make([]uint64, (b.len+63)/64)
+	copy(nb.tomb, b.tomb)
+	nb.tomb[idx>>6] |= 1 << (uint(idx) & 63)
+	nb.tombs++
+	return &nb, true
 }
 
 // compute per-axis standard deviation (used for axis selection)
@@ -91,170 +241,281 @@ func axisStd(idxs []int, coords func(int) []float64, dim int) []float64 {
 	return vars
 }
 
-func buildKDRecursive(idxs []int, coords func(int) []float64, dim int, depth int) *kdNode {
-	if len(idxs) == 0 {
-		return nil
-	}
-	// choose axis with max stddev
-	stds := axisStd(idxs, coords, dim)
-	axis := 0
-	maxv := stds[0]
-	for d := 1; d < dim; d++ {
-		if stds[d] > maxv {
-			maxv = stds[d]
-			axis = d
-		}
-	}
-	// nth-element (partial sort) by axis using sort.Slice for simplicity
-	sort.Slice(idxs, func(i, j int) bool { return coords(idxs[i])[axis] < coords(idxs[j])[axis] })
-	mid := len(idxs) / 2
-	medianIdx := idxs[mid]
-	n := &kdNode{axis: axis, idx: medianIdx, val: coords(medianIdx)[axis]}
-	n.left = buildKDRecursive(append([]int(nil), idxs[:mid]...), coords, dim, depth+1)
-	n.right = buildKDRecursive(append([]int(nil), idxs[mid+1:]...), coords, dim, depth+1)
-	return n
+// kdBuildTask is a pending partition in the iterative construction.
+type kdBuildTask struct {
+	idxs   []int
+	parent *kdNode
+	left   bool
 }
 
-// gonumNearest performs 1-NN search using the KD backend.
-func gonumNearest[T any](backend any, query []float64) (int, float64, bool) {
-	b, ok := backend.(*kdBackend)
-	if !ok || b.root == nil || len(query) != b.dim {
-		return -1, 0, false
+// kdParallelMin is the smallest partition construction hands to a goroutine
+// of its own; trees under twice this size are built on the calling goroutine.
+const kdParallelMin = 1 << 15
+
+// kdBuilder carries the state shared by the goroutines building one tree.
+type kdBuilder struct {
+	coords   func(int) []float64
+	dim      int
+	leafSize int
+	sem      chan struct{} // one token per extra goroutine; nil to build serially
+	wg       sync.WaitGroup
+}
+
+// buildKDIterative builds the tree with an explicit work stack instead of
+// recursion so degenerate (e.g., heavily duplicated) data cannot blow up the
+// call depth. Each task partitions its own sub-slice of idxs in place; sibling
+// tasks never overlap, so no copies are needed. Large trees split their
+// partitions across up to GOMAXPROCS goroutines.
+func buildKDIterative(idxs []int, coords func(int) []float64, dim int) *kdNode {
+	return buildKD(idxs, coords, dim, 1, runtime.GOMAXPROCS(0))
+}
+
+// buildKD is buildKDIterative with partitions of up to leafSize points kept
+// as leaf buckets, using at most workers goroutines. The tree it builds does
+// not depend on workers.
+func buildKD(idxs []int, coords func(int) []float64, dim, leafSize, workers int) *kdNode {
+	b := &kdBuilder{coords: coords, dim: dim, leafSize: leafSize}
+	if workers > 1 && len(idxs) >= 2*kdParallelMin {
+		b.sem = make(chan struct{}, workers-1)
 	}
-	bestIdx := -1
-	bestDist := math.MaxFloat64
-	var search func(*kdNode)
-	search = func(n *kdNode) {
-		if n == nil {
-			return
-		}
-		c := b.coords(n.idx)
-		d := b.metric.Distance(query, c)
-		if d < bestDist {
-			bestDist = d
-			bestIdx = n.idx
+	root := b.run(kdBuildTask{idxs: idxs})
+	b.wg.Wait()
+	return root
+}
+
+// run builds the subtree for task, links it under task.parent and returns its
+// root. Child partitions of at least kdParallelMin points are handed to new
+// goroutines while tokens are free.
+func (b *kdBuilder) run(task kdBuildTask) *kdNode {
+	var root *kdNode
+	stack := []kdBuildTask{task}
+	for len(stack) > 0 {
+		task := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if len(task.idxs) == 0 {
+			continue
 		}
-		axis := n.axis
-		qv := query[axis]
-		// choose side
-		near, far := n.left, n.right
-		if qv >= n.val {
-			near, far = n.right, n.left
+		if b.leafSize > 1 && len(task.idxs) <= b.leafSize {
+			b.link(&root, task, &kdNode{idx: -1, bucket: task.idxs})
+			continue
 		}
-		search(near)
-		// prune if hyperslab distance is >= bestDist
-		diff := qv - n.val
-		if diff < 0 {
-			diff = -diff
+		// choose axis with max stddev
+		stds := axisStd(task.idxs, b.coords, b.dim)
+		axis := 0
+		maxv := stds[0]
+		for d := 1; d < b.dim; d++ {
+			if stds[d] > maxv {
+				maxv = stds[d]
+				axis = d
+			}
 		}
-		if diff <= bestDist {
-			search(far)
+		// nth-element partition around the median along axis
+		sub := task.idxs
+		mid := len(sub) / 2
+		nthElement(sub, mid, func(i int) float64 { return b.coords(i)[axis] })
+		medianIdx := sub[mid]
+		n := &kdNode{axis: axis, idx: medianIdx, val: b.coords(medianIdx)[axis]}
+		b.link(&root, task, n)
+		for _, child := range []kdBuildTask{
+			{idxs: sub[mid+1:], parent: n},
+			{idxs: sub[:mid], parent: n, left: true},
+		} {
+			if !b.spawn(child) {
+				stack = append(stack, child)
+			}
 		}
 	}
-	search(b.root)
-	if bestIdx < 0 {
-		return -1, 0, false
+	return root
+}
+
+// link hangs n under task's parent; the first node run builds becomes *root.
+func (b *kdBuilder) link(root **kdNode, task kdBuildTask, n *kdNode) {
+	if *root == nil {
+		*root = n
+	}
+	switch {
+	case task.parent == nil:
+	case task.left:
+		task.parent.left = n
+	default:
+		task.parent.right = n
 	}
-	return bestIdx, bestDist, true
 }
 
-// small max-heap for (distance, index)
-// We’ll use a slice maintaining the largest distance at [0] via container/heap-like ops.
-type knnItem struct {
-	idx  int
-	dist float64
+// spawn builds task on a new goroutine if it is large enough and a token is
+// free, reporting whether it did.
+func (b *kdBuilder) spawn(task kdBuildTask) bool {
+	if b.sem == nil || len(task.idxs) < kdParallelMin {
+		return false
+	}
+	select {
+	case b.sem <- struct{}{}:
+	default:
+		return false
+	}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.run(task)
+		<-b.sem
+	}()
+	return true
 }
 
-type knnHeap []knnItem
+// nthElement partially orders idxs so that idxs[n] holds the element that
+// would be there if idxs were sorted by key, with every element before it
+// having key <= and every element after it key >=. It uses quickselect with a
+// median-of-three pivot and three-way partitioning, so runs of duplicate keys
+// do not degrade to quadratic time. Expected cost is O(len(idxs)).
+func nthElement(idxs []int, n int, key func(int) float64) {
+	lo, hi := 0, len(idxs)-1
+	for lo < hi {
+		a, b, c := key(idxs[lo]), key(idxs[lo+(hi-lo)/2]), key(idxs[hi])
+		pivot := medianOf3(a, b, c)
+		// three-way partition: [lo,lt) < pivot, [lt,gt] == pivot, (gt,hi] > pivot
+		lt, i, gt := lo, lo, hi
+		for i <= gt {
+			v := key(idxs[i])
+			switch {
+			case v < pivot:
+				idxs[lt], idxs[i] = idxs[i], idxs[lt]
+				lt++
+				i++
+			case v > pivot:
+				idxs[i], idxs[gt] = idxs[gt], idxs[i]
+				gt--
+			default:
+				i++
+			}
+		}
+		switch {
+		case n < lt:
+			hi = lt - 1
+		case n > gt:
+			lo = gt + 1
+		default:
+			return
+		}
+	}
+}
 
-func (h knnHeap) Len() int           { return len(h) }
-func (h knnHeap) less(i, j int) bool { return h[i].dist > h[j].dist } // max-heap by dist
-func (h *knnHeap) push(x knnItem)    { *h = append(*h, x); h.up(len(*h) - 1) }
-func (h *knnHeap) pop() knnItem {
-	n := len(*h) - 1
-	h.swap(0, n)
-	v := (*h)[n]
-	*h = (*h)[:n]
-	h.down(0)
-	return v
+func medianOf3(a, b, c float64) float64 {
+	if a > b {
+		a, b = b, a
+	}
+	if b > c {
+		b = c
+	}
+	if a > b {
+		return a
+	}
+	return b
 }
-func (h *knnHeap) peek() knnItem { return (*h)[0] }
-func (h knnHeap) swap(i, j int)  { h[i], h[j] = h[j], h[i] }
-func (h *knnHeap) up(i int) {
-	for i > 0 {
-		p := (i - 1) / 2
-		if !h.less(i, p) {
-			break
+
+// kdSearchItem is a node pending visit during iterative search. bound is the
+// distance from the query to the splitting hyperplane that separates the node
+// from the query; the node is skipped if bound already exceeds the current
+// pruning threshold when it is popped.
+type kdSearchItem struct {
+	n     *kdNode
+	bound float64
+}
+
+// pushChildren pushes the far child (with its hyperplane bound) and then the
+// near child so the near side is explored first, mirroring depth-first order.
+// On a periodic axis the far side is also reachable by wrapping around, so
+// its bound is the shorter of the two ways there; query must be canonical.
+func (b *kdBackend) pushChildren(stack []kdSearchItem, n *kdNode, query []float64) []kdSearchItem {
+	qv := query[n.axis]
+	near, far := n.left, n.right
+	if qv >= n.val {
+		near, far = n.right, n.left
+	}
+	diff := qv - n.val
+	if diff < 0 {
+		diff = -diff
+	}
+	if p := b.periods.period(n.axis); p > 0 {
+		if qv >= n.val {
+			diff = math.Min(diff, p-qv) // left side [0, val) via the top
+		} else {
+			diff = math.Min(diff, qv) // right side [val, p) via zero
 		}
-		h.swap(i, p)
-		i = p
 	}
+	if far != nil {
+		stack = append(stack, kdSearchItem{n: far, bound: diff})
+	}
+	if near != nil {
+		stack = append(stack, kdSearchItem{n: near})
+	}
+	return stack
 }
-func (h *knnHeap) down(i int) {
-	for {
-		l := 2*i + 1
-		r := l + 1
-		largest := i
-		if l < h.Len() && h.less(l, largest) {
-			largest = l
-		}
-		if r < h.Len() && h.less(r, largest) {
-			largest = r
+
+// gonumNearest performs 1-NN search using the KD backend.
+func gonumNearest[T any](backend any, query []float64) (int, float64, bool) {
+	b, ok := backend.(*kdBackend)
+	if !ok || b.root == nil || len(query) != b.dim {
+		return -1, 0, false
+	}
+	split := b.periods.canonical(query)
+	bestIdx := -1
+	bestDist := math.MaxFloat64
+	stack := []kdSearchItem{{n: b.root}}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		// prune if hyperslab distance is > bestDist
+		if it.bound > bestDist {
+			continue
 		}
-		if largest == i {
-			break
+		for i := range it.n.points {
+			d := b.metric.Distance(query, b.coords(i))
+			if d < bestDist && !b.dead(i) {
+				bestDist = d
+				bestIdx = i
+			}
 		}
-		h.swap(i, largest)
-		i = largest
+		stack = b.pushChildren(stack, it.n, split)
 	}
+	if bestIdx < 0 {
+		return -1, 0, false
+	}
+	return bestIdx, bestDist, true
 }
 
 // gonumKNearest returns indices in ascending distance order.
-func gonumKNearest[T any](backend any, query []float64, k int) ([]int, []float64) {
+func gonumKNearest[T any](backend any, query []float64, k int, skip func(idx int) bool) ([]int, []float64) {
 	b, ok := backend.(*kdBackend)
 	if !ok || b.root == nil || len(query) != b.dim || k <= 0 {
 		return nil, nil
 	}
+	split := b.periods.canonical(query)
 	var h knnHeap
 	bestCap := k
-	var search func(*kdNode)
-	search = func(n *kdNode) {
-		if n == nil {
-			return
-		}
-		c := b.coords(n.idx)
-		d := b.metric.Distance(query, c)
-		if h.Len() < bestCap {
-			h.push(knnItem{idx: n.idx, dist: d})
-		} else if d < h.peek().dist {
-			// replace max
-			h[0] = knnItem{idx: n.idx, dist: d}
-			h.down(0)
-		}
-		axis := n.axis
-		qv := query[axis]
-		near, far := n.left, n.right
-		if qv >= n.val {
-			near, far = n.right, n.left
+	stack := []kdSearchItem{{n: b.root}}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		// prune against current worst in heap once the heap is full
+		if h.Len() == bestCap && it.bound > h.peek().dist {
+			continue
 		}
-		search(near)
-		// prune against current worst in heap if heap is full; otherwise use bestDist
-		threshold := math.MaxFloat64
-		if h.Len() == bestCap {
-			threshold = h.peek().dist
-		} else if h.Len() > 0 {
-			// use best known (not strictly necessary)
-			threshold = h.peek().dist
-		}
-		diff := qv - n.val
-		if diff < 0 {
-			diff = -diff
-		}
-		if diff <= threshold {
-			search(far)
+		for i := range it.n.points {
+			// tombstoned nodes still route the search but are never results
+			if b.dead(i) || (skip != nil && skip(i)) {
+				continue
+			}
+			d := b.metric.Distance(query, b.coords(i))
+			if h.Len() < bestCap {
+				h.push(knnItem{idx: i, dist: d})
+			} else if d < h.peek().dist {
+				// replace max
+				h[0] = knnItem{idx: i, dist: d}
+				h.down(0)
+			}
 		}
+		stack = b.pushChildren(stack, it.n, split)
 	}
-	search(b.root)
 	// Extract to slices and sort ascending by distance
 	res := make([]knnItem, len(h))
 	copy(res, h)
@@ -268,38 +529,16 @@ func gonumKNearest[T any](backend any, query []float64, k int) ([]int, []float64
 	return idxs, dists
 }
 
-func gonumRadius[T any](backend any, query []float64, r float64) ([]int, []float64) {
-	b, ok := backend.(*kdBackend)
-	if !ok || b.root == nil || len(query) != b.dim || r < 0 {
-		return nil, nil
+func gonumRadius[T any](backend any, query []float64, r float64, capHint int) ([]int, []float64) {
+	if capHint < 0 {
+		capHint = 0
 	}
-	var res []knnItem
-	var search func(*kdNode)
-	search = func(n *kdNode) {
-		if n == nil {
-			return
-		}
-		c := b.coords(n.idx)
-		d := b.metric.Distance(query, c)
-		if d <= r {
-			res = append(res, knnItem{idx: n.idx, dist: d})
-		}
-		axis := n.axis
-		qv := query[axis]
-		near, far := n.left, n.right
-		if qv >= n.val {
-			near, far = n.right, n.left
-		}
-		search(near)
-		diff := qv - n.val
-		if diff < 0 {
-			diff = -diff
-		}
-		if diff <= r {
-			search(far)
-		}
+	res := make([]knnItem, 0, capHint)
+	if !gonumRadiusEach[T](backend, query, r, func(idx int, dist float64) {
+		res = append(res, knnItem{idx: idx, dist: dist})
+	}) {
+		return nil, nil
 	}
-	search(b.root)
 	sort.Slice(res, func(i, j int) bool { return res[i].dist < res[j].dist })
 	idxs := make([]int, len(res))
 	dists := make([]float64, len(res))
@@ -309,3 +548,28 @@ func gonumRadius[T any](backend any, query []float64, r float64) ([]int, []float
 	}
 	return idxs, dists
 }
+
+// gonumRadiusEach calls visit for every point within r of query, in tree
+// order (unsorted). It reports false if the backend cannot serve the query.
+func gonumRadiusEach[T any](backend any, query []float64, r float64, visit func(idx int, dist float64)) bool {
+	b, ok := backend.(*kdBackend)
+	if !ok || b.root == nil || len(query) != b.dim || r < 0 {
+		return false
+	}
+	split := b.periods.canonical(query)
+	stack := []kdSearchItem{{n: b.root}}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if it.bound > r {
+			continue
+		}
+		for i := range it.n.points {
+			if d := b.metric.Distance(query, b.coords(i)); d <= r && !b.dead(i) {
+				visit(i, d)
+			}
+		}
+		stack = b.pushChildren(stack, it.n, split)
+	}
+	return true
+}
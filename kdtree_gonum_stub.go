@@ -6,18 +6,30 @@ package poindexter
 func hasGonum() bool { return false }
 
 // buildGonumBackend is unavailable without the 'gonum' build tag.
-func buildGonumBackend[T any](pts []KDPoint[T], metric DistanceMetric) (any, error) {
+func buildGonumBackend[T any](pts []KDPoint[T], metric DistanceMetric, leafSize int) (any, error) {
 	return nil, ErrEmptyPoints // sentinel non-nil error to force fallback
 }
 
+func gonumInsert[T any](backend any, points []KDPoint[T]) (any, bool) {
+	return nil, false
+}
+
+func gonumDelete(backend any, idx int, maxRatio float64) (any, bool) {
+	return nil, false
+}
+
 func gonumNearest[T any](backend any, query []float64) (int, float64, bool) {
 	return -1, 0, false
 }
 
-func gonumKNearest[T any](backend any, query []float64, k int) ([]int, []float64) {
+func gonumKNearest[T any](backend any, query []float64, k int, skip func(idx int) bool) ([]int, []float64) {
 	return nil, nil
 }
 
-func gonumRadius[T any](backend any, query []float64, r float64) ([]int, []float64) {
+func gonumRadius[T any](backend any, query []float64, r float64, capHint int) ([]int, []float64) {
 	return nil, nil
 }
+
+func gonumRadiusEach[T any](backend any, query []float64, r float64, visit func(idx int, dist float64)) bool {
+	return false
+}
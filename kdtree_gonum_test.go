@@ -3,8 +3,13 @@
 package poindexter
 
 import (
+	"fmt"
 	"math"
+	"math/bits"
+	"math/rand"
+	"sort"
 	"testing"
+	"time"
 )
 
 func equalish(a, b []float64, tol float64) bool {
@@ -262,10 +267,10 @@ func TestGonumNearestReturnsFalseForNoPoints(t *testing.T) {
 		t.Errorf("expected ok to be false, but it was true")
 	}
 }
-func TestBuildKDRecursiveWithSinglePoint(t *testing.T) {
+func TestBuildKDIterativeWithSinglePoint(t *testing.T) {
 	idxs := []int{0}
 	coords := func(i int) []float64 { return []float64{1, 1} }
-	node := buildKDRecursive(idxs, coords, 2, 0)
+	node := buildKDIterative(idxs, coords, 2)
 	if node == nil {
 		t.Fatal("expected a node, got nil")
 	}
@@ -274,6 +279,148 @@ func TestBuildKDRecursiveWithSinglePoint(t *testing.T) {
 	}
 }
 
+func TestBuildKDParallelMatchesSerial(t *testing.T) {
+	const n = 5*kdParallelMin + 123
+	rng := rand.New(rand.NewSource(50))
+	pts := make([][]float64, n)
+	for i := range pts {
+		pts[i] = []float64{rng.Float64(), rng.Float64(), float64(rng.Intn(4))}
+	}
+	coords := func(i int) []float64 { return pts[i] }
+	build := func(workers int) *kdNode {
+		idxs := make([]int, n)
+		for i := range idxs {
+			idxs[i] = i
+		}
+		return buildKD(idxs, coords, 3, 1, workers)
+	}
+	var walk func(a, b *kdNode) int
+	walk = func(a, b *kdNode) int {
+		if a == nil || b == nil {
+			if a != b {
+				t.Fatal("tree shapes differ")
+			}
+			return 0
+		}
+		if a.axis != b.axis || a.idx != b.idx || a.val != b.val {
+			t.Fatalf("nodes differ: %+v vs %+v", *a, *b)
+		}
+		return 1 + walk(a.left, b.left) + walk(a.right, b.right)
+	}
+	serial := build(1)
+	for _, workers := range []int{2, 8} {
+		if got := walk(serial, build(workers)); got != n {
+			t.Fatalf("workers=%d: tree holds %d nodes, want %d", workers, got, n)
+		}
+	}
+}
+
+func TestGonumLeafSizeMatchesLinear(t *testing.T) {
+	rng := rand.New(rand.NewSource(52))
+	for _, dim := range []int{2, 8} {
+		pts := make([]KDPoint[int], 3000)
+		for i := range pts {
+			c := make([]float64, dim)
+			for j := range c {
+				c[j] = rng.Float64()
+			}
+			pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: c, Value: i}
+		}
+		for _, leaf := range []int{0, 1, 8, 64} {
+			lin, _ := NewKDTree(pts, WithBackend(BackendLinear))
+			tr, err := NewKDTree(pts, WithBackend(BackendGonum), WithLeafSize(leaf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tr.Backend() != BackendGonum {
+				t.Fatalf("leaf %d: backend %s", leaf, tr.Backend())
+			}
+			if leaf > 1 {
+				var walk func(n *kdNode)
+				buckets := 0
+				walk = func(n *kdNode) {
+					if n == nil {
+						return
+					}
+					if n.bucket != nil {
+						buckets++
+						if len(n.bucket) > leaf || n.left != nil || n.right != nil {
+							t.Fatalf("leaf %d: bad bucket of %d points", leaf, len(n.bucket))
+						}
+					}
+					walk(n.left)
+					walk(n.right)
+				}
+				walk(tr.index.Load().data.(*kdBackend).root)
+				if buckets == 0 {
+					t.Fatalf("leaf %d: no buckets built", leaf)
+				}
+			}
+			// mutate both trees: inserts land in buckets, deletes tombstone
+			for i := 0; i < 300; i++ {
+				c := make([]float64, dim)
+				for j := range c {
+					c[j] = rng.Float64()
+				}
+				p := KDPoint[int]{ID: fmt.Sprint("n", i), Coords: c}
+				tr.Insert(p)
+				lin.Insert(p)
+				id := fmt.Sprint(rng.Intn(len(pts)))
+				tr.DeleteByID(id)
+				lin.DeleteByID(id)
+			}
+			for q := 0; q < 50; q++ {
+				query := make([]float64, dim)
+				for j := range query {
+					query[j] = rng.Float64()
+				}
+				_, wantD := lin.KNearest(query, 7)
+				_, gotD := tr.KNearest(query, 7)
+				if !equalish(gotD, wantD, 1e-12) {
+					t.Fatalf("dim %d leaf %d: KNearest %v, want %v", dim, leaf, gotD, wantD)
+				}
+				_, wantN, _ := lin.Nearest(query)
+				if _, gotN, _ := tr.Nearest(query); gotN != wantN {
+					t.Fatalf("dim %d leaf %d: Nearest %v, want %v", dim, leaf, gotN, wantN)
+				}
+				r := 0.1 * float64(dim)
+				wantR, _ := lin.Radius(query, r)
+				if gotR, _ := tr.Radius(query, r); len(gotR) != len(wantR) {
+					t.Fatalf("dim %d leaf %d: Radius found %d, want %d", dim, leaf, len(gotR), len(wantR))
+				}
+			}
+			if c := tr.Clone(false); c.leafSize != tr.leafSize {
+				t.Fatalf("Clone dropped leaf size %d", tr.leafSize)
+			}
+		}
+	}
+}
+
+func TestBuildKDIterativeDegenerateData(t *testing.T) {
+	// All points identical except one: every split lands on duplicate values.
+	const n = 5000
+	points := make([]KDPoint[int], n)
+	for i := range points {
+		points[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{1, 1}}
+	}
+	points[n-1].Coords = []float64{5, 5}
+	tree, err := NewKDTree(points, WithBackend(BackendGonum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, d, ok := tree.Nearest([]float64{4.9, 4.9})
+	if !ok || p.ID != fmt.Sprint(n-1) {
+		t.Fatalf("expected outlier point, got %v (ok=%v)", p.ID, ok)
+	}
+	if math.Abs(d-math.Hypot(0.1, 0.1)) > 1e-9 {
+		t.Errorf("unexpected distance %v", d)
+	}
+	ps, _ := tree.Radius([]float64{1, 1}, 0)
+	if len(ps) != n-1 {
+		t.Errorf("expected %d duplicates within r=0, got %d", n-1, len(ps))
+	}
+}
+
 func TestGonumKNearestWithLargeK(t *testing.T) {
 	points := []KDPoint[int]{
 		{ID: "1", Coords: []float64{1, 1}},
@@ -623,3 +770,377 @@ func TestGonumNearestWithMultipleDimensions(t *testing.T) {
 		t.Errorf("expected point 1, got %v", p)
 	}
 }
+
+func TestNthElementPartitions(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	for trial := 0; trial < 50; trial++ {
+		n := 1 + r.Intn(200)
+		vals := make([]float64, n)
+		for i := range vals {
+			vals[i] = float64(r.Intn(10)) // plenty of duplicates
+		}
+		idxs := make([]int, n)
+		for i := range idxs {
+			idxs[i] = i
+		}
+		k := r.Intn(n)
+		nthElement(idxs, k, func(i int) float64 { return vals[i] })
+		sorted := append([]float64(nil), vals...)
+		sort.Float64s(sorted)
+		if vals[idxs[k]] != sorted[k] {
+			t.Fatalf("trial %d: nth value %v, want %v", trial, vals[idxs[k]], sorted[k])
+		}
+		for i := 0; i < k; i++ {
+			if vals[idxs[i]] > vals[idxs[k]] {
+				t.Fatalf("trial %d: left element %v > pivot %v", trial, vals[idxs[i]], vals[idxs[k]])
+			}
+		}
+		for i := k + 1; i < n; i++ {
+			if vals[idxs[i]] < vals[idxs[k]] {
+				t.Fatalf("trial %d: right element %v < pivot %v", trial, vals[idxs[i]], vals[idxs[k]])
+			}
+		}
+	}
+}
+
+func TestGonumRebuildDoubleBuffered(t *testing.T) {
+	pts := make([]KDPoint[int], 0, 512)
+	for i := 0; i < 512; i++ {
+		pts = append(pts, KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{float64(i % 32), float64(i / 32)}})
+	}
+	tr, err := NewKDTree(pts, WithBackend(BackendGonum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := tr.index.Load()
+	if old == nil {
+		t.Fatal("expected gonum index to be built")
+	}
+
+	done := make(chan struct{})
+	errs := make(chan string, 4)
+	for g := 0; g < 4; g++ {
+		go func() {
+			for {
+				select {
+				case <-done:
+					errs <- ""
+					return
+				default:
+				}
+				if p, d, ok := tr.Nearest([]float64{3, 4}); !ok || p.ID != "131" || d != 0 {
+					errs <- fmt.Sprintf("Nearest during rebuild: %v %v %v", p.ID, d, ok)
+					return
+				}
+				if ns, _ := tr.KNearest([]float64{0, 0}, 3); len(ns) != 3 {
+					errs <- fmt.Sprintf("KNearest during rebuild: got %d", len(ns))
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		tr.rebuildIndex()
+	}
+	close(done)
+	for g := 0; g < 4; g++ {
+		if msg := <-errs; msg != "" {
+			t.Fatal(msg)
+		}
+	}
+	if tr.index.Load() == old {
+		t.Fatal("expected rebuilt index to be swapped in")
+	}
+}
+
+func TestGonumRebuildDiscardsStaleIndex(t *testing.T) {
+	tr, err := NewKDTree([]KDPoint[int]{{ID: "a", Coords: []float64{0}}}, WithBackend(BackendGonum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, _, err := tr.buildIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tr.Insert(KDPoint[int]{ID: "b", Coords: []float64{1}}) {
+		t.Fatal("insert failed")
+	}
+	cur := tr.index.Load()
+	if cur.version <= stale.version {
+		t.Fatalf("expected insert to publish a newer index: %d <= %d", cur.version, stale.version)
+	}
+	if len(cur.points) != 2 {
+		t.Fatalf("expected published index to hold 2 points, got %d", len(cur.points))
+	}
+	// Queries against the published index see the new point.
+	if p, _, ok := tr.Nearest([]float64{1}); !ok || p.ID != "b" {
+		t.Fatalf("expected b, got %v ok=%v", p.ID, ok)
+	}
+}
+
+func TestGonumRebuildRecordsDuration(t *testing.T) {
+	tr, err := NewKDTree([]KDPoint[int]{
+		{ID: "a", Coords: []float64{0, 0}},
+		{ID: "b", Coords: []float64{1, 1}},
+		{ID: "c", Coords: []float64{2, 2}},
+	}, WithBackend(BackendGonum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.DeleteByID("a")
+	tr.DeleteByID("b")
+	snap := tr.GetAnalyticsSnapshot()
+	if snap.BackendRebuildCnt != 2 {
+		t.Fatalf("expected 2 rebuilds, got %d", snap.BackendRebuildCnt)
+	}
+	if snap.LastRebuildTimeNs <= 0 || snap.AvgRebuildTimeNs <= 0 {
+		t.Fatalf("expected rebuild timings, got last=%d avg=%d", snap.LastRebuildTimeNs, snap.AvgRebuildTimeNs)
+	}
+}
+
+func TestGonumDeleteWhereRebuildsOnce(t *testing.T) {
+	pts := make([]KDPoint[int], 100)
+	for i := range pts {
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{float64(i), 0}, Value: i}
+	}
+	tr, err := NewKDTree(pts, WithBackend(BackendGonum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := tr.DeleteWhere(func(p KDPoint[int]) bool { return p.Value%2 == 1 }); n != 50 {
+		t.Fatalf("expected 50 removed, got %d", n)
+	}
+	if got := tr.GetAnalyticsSnapshot().BackendRebuildCnt; got != 1 {
+		t.Fatalf("expected a single rebuild, got %d", got)
+	}
+	if p, _, _ := tr.Nearest([]float64{51, 0}); p.ID != "50" && p.ID != "52" {
+		t.Fatalf("index not rebuilt: nearest %q", p.ID)
+	}
+}
+
+func TestGonumManualRebuild(t *testing.T) {
+	tr, err := NewKDTree([]KDPoint[int]{{ID: "a", Coords: []float64{0, 0}}},
+		WithBackend(BackendGonum), WithAutoRebuild(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 10; i++ {
+		tr.Insert(KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{float64(i), 0}})
+	}
+	tr.DeleteByID("a")
+	if got := tr.GetAnalyticsSnapshot().BackendRebuildCnt; got != 0 {
+		t.Fatalf("expected no automatic rebuilds, got %d", got)
+	}
+	// the stale index is bypassed, so queries already see the mutations
+	if p, _, _ := tr.Nearest([]float64{0, 0}); p.ID != "1" {
+		t.Fatalf("stale index served: nearest %q", p.ID)
+	}
+	if tr.queryIndex() != nil {
+		t.Fatal("expected stale index to be skipped")
+	}
+	tr.Rebuild()
+	if got := tr.GetAnalyticsSnapshot().BackendRebuildCnt; got != 1 {
+		t.Fatalf("expected one explicit rebuild, got %d", got)
+	}
+	if tr.queryIndex() == nil {
+		t.Fatal("expected fresh index after Rebuild")
+	}
+	if p, _, _ := tr.Nearest([]float64{9.2, 0}); p.ID != "9" {
+		t.Fatalf("expected 9, got %q", p.ID)
+	}
+}
+
+func TestGonumRebuildEveryN(t *testing.T) {
+	tr, err := NewKDTree([]KDPoint[int]{{ID: "a", Coords: []float64{0, 0}}},
+		WithBackend(BackendGonum), WithRebuildPolicy(RebuildEveryN(5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 12; i++ {
+		tr.Insert(KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{float64(i), 0}})
+	}
+	if got := tr.GetAnalyticsSnapshot().BackendRebuildCnt; got != 2 {
+		t.Fatalf("expected 2 rebuilds for 12 inserts, got %d", got)
+	}
+	if p, _, _ := tr.Nearest([]float64{12, 0}); p.ID != "12" {
+		t.Fatalf("pending inserts not visible: nearest %q", p.ID)
+	}
+	// a batch delete counts every removed point toward the threshold
+	tr.DeleteWhere(func(p KDPoint[int]) bool { return p.ID != "a" && p.Coords[0] > 9 })
+	if got := tr.GetAnalyticsSnapshot().BackendRebuildCnt; got != 3 {
+		t.Fatalf("expected batch delete to trigger a rebuild, got %d", got)
+	}
+	if tr.queryIndex() == nil {
+		t.Fatal("expected fresh index after threshold rebuild")
+	}
+}
+
+func TestGonumRebuildAfter(t *testing.T) {
+	tr, err := NewKDTree([]KDPoint[int]{{ID: "a", Coords: []float64{0, 0}}},
+		WithBackend(BackendGonum), WithRebuildPolicy(RebuildAfter(20*time.Millisecond)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 10; i++ {
+		tr.Insert(KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{float64(i), 0}})
+	}
+	if got := tr.GetAnalyticsSnapshot().BackendRebuildCnt; got != 0 {
+		t.Fatalf("expected rebuild to be deferred, got %d", got)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for tr.GetAnalyticsSnapshot().BackendRebuildCnt == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("deferred rebuild never ran")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	tr.writeMu.Lock()
+	fresh := tr.queryIndex() != nil
+	tr.writeMu.Unlock()
+	if got := tr.GetAnalyticsSnapshot().BackendRebuildCnt; got != 1 || !fresh {
+		t.Fatalf("expected one coalesced rebuild and a fresh index, got %d fresh=%v", got, fresh)
+	}
+}
+
+func TestGonumIncrementalInsert(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	randPt := func(id string) KDPoint[int] {
+		return KDPoint[int]{ID: id, Coords: []float64{rng.Float64() * 100, rng.Float64() * 100}}
+	}
+	pts := make([]KDPoint[int], 200)
+	for i := range pts {
+		pts[i] = randPt(fmt.Sprint(i))
+	}
+	tr, err := NewKDTree(pts, WithBackend(BackendGonum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := tr.index.Load()
+	for i := 0; i < 50; i++ {
+		if !tr.Insert(randPt(fmt.Sprint("n", i))) {
+			t.Fatal("insert failed")
+		}
+	}
+	if got := tr.GetAnalyticsSnapshot().BackendRebuildCnt; got != 0 {
+		t.Fatalf("expected inserts to extend the index without rebuilding, got %d rebuilds", got)
+	}
+	ix := tr.queryIndex()
+	if ix == nil || len(ix.points) != 250 {
+		t.Fatal("expected a current index covering every point")
+	}
+	if len(before.points) != 200 {
+		t.Fatalf("earlier index changed: %d points", len(before.points))
+	}
+	// results must match a linear scan exactly
+	lin, _ := NewKDTree(tr.Points(), WithBackend(BackendLinear))
+	for q := 0; q < 50; q++ {
+		query := []float64{rng.Float64() * 100, rng.Float64() * 100}
+		_, dg, _ := tr.Nearest(query)
+		_, dl, _ := lin.Nearest(query)
+		if dg != dl {
+			t.Fatalf("nearest mismatch: %v vs %v", dg, dl)
+		}
+		_, kg := tr.KNearest(query, 7)
+		_, kl := lin.KNearest(query, 7)
+		for i := range kl {
+			if kg[i] != kl[i] {
+				t.Fatalf("kNearest mismatch at %d: %v vs %v", i, kg, kl)
+			}
+		}
+		rg, _ := tr.Radius(query, 15)
+		rl, _ := lin.Radius(query, 15)
+		if len(rg) != len(rl) {
+			t.Fatalf("radius mismatch: %d vs %d", len(rg), len(rl))
+		}
+	}
+
+	// sorted inserts unbalance the tree and eventually force a full rebuild
+	for i := 0; i < 300; i++ {
+		tr.Insert(KDPoint[int]{ID: fmt.Sprint("s", i), Coords: []float64{float64(i), 0}})
+	}
+	if got := tr.GetAnalyticsSnapshot().BackendRebuildCnt; got == 0 {
+		t.Fatal("expected rebalancing rebuilds after sorted inserts")
+	}
+	b := tr.index.Load().data.(*kdBackend)
+	if b.depth > 2*bits.Len(uint(b.len))+2 {
+		t.Fatalf("tree left unbalanced: depth %d for %d points", b.depth, b.len)
+	}
+}
+
+func TestGonumTombstoneDelete(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	pts := make([]KDPoint[int], 100)
+	for i := range pts {
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{rng.Float64() * 100, rng.Float64() * 100}}
+	}
+	tr, err := NewKDTree(pts, WithBackend(BackendGonum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		if !tr.DeleteByID(fmt.Sprint(i)) {
+			t.Fatal("delete failed")
+		}
+		deleted[fmt.Sprint(i)] = true
+	}
+	tr.Insert(KDPoint[int]{ID: "new", Coords: []float64{50, 50}})
+	if got := tr.GetAnalyticsSnapshot().BackendRebuildCnt; got != 0 {
+		t.Fatalf("expected deletes to tombstone without rebuilding, got %d rebuilds", got)
+	}
+	ix := tr.queryIndex()
+	if ix == nil || ix.tombstones != 20 || len(ix.points) != 101 {
+		t.Fatal("expected a current index holding 20 tombstones")
+	}
+	lin, _ := NewKDTree(tr.Points(), WithBackend(BackendLinear))
+	for q := 0; q < 50; q++ {
+		query := []float64{rng.Float64() * 100, rng.Float64() * 100}
+		pg, dg, _ := tr.Nearest(query)
+		_, dl, _ := lin.Nearest(query)
+		if dg != dl {
+			t.Fatalf("nearest mismatch: %v vs %v", dg, dl)
+		}
+		if deleted[pg.ID] {
+			t.Fatalf("deleted point %s returned", pg.ID)
+		}
+		_, kg := tr.KNearest(query, 7)
+		_, kl := lin.KNearest(query, 7)
+		for i := range kl {
+			if kg[i] != kl[i] {
+				t.Fatalf("kNearest mismatch at %d: %v vs %v", i, kg, kl)
+			}
+		}
+		rg, _ := tr.Radius(query, 15)
+		rl, _ := lin.Radius(query, 15)
+		if len(rg) != len(rl) {
+			t.Fatalf("radius mismatch: %d vs %d", len(rg), len(rl))
+		}
+	}
+	// index positions no longer match tree order, yet k-th neighbour
+	// distances must still be per point
+	got, want := tr.kthNeighborDistances(3), lin.kthNeighborDistances(3)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("kth neighbour distance %d: %v vs %v", i, got[i], want[i])
+		}
+	}
+
+	// the delete pushing tombstones past 25% of the indexed points compacts
+	for i := 20; i < 26; i++ {
+		tr.DeleteByID(fmt.Sprint(i))
+	}
+	if got := tr.GetAnalyticsSnapshot().BackendRebuildCnt; got != 1 {
+		t.Fatalf("expected one compaction, got %d rebuilds", got)
+	}
+	if ix := tr.queryIndex(); ix == nil || ix.tombstones != 0 || len(ix.points) != tr.Len() {
+		t.Fatal("expected a compacted index")
+	}
+
+	// ratio 0 rebuilds on every delete
+	eager, _ := NewKDTree(pts, WithBackend(BackendGonum), WithTombstoneRatio(0))
+	eager.DeleteByID("0")
+	eager.DeleteByID("1")
+	if got := eager.GetAnalyticsSnapshot().BackendRebuildCnt; got != 2 {
+		t.Fatalf("expected 2 rebuilds with ratio 0, got %d", got)
+	}
+}
@@ -0,0 +1,52 @@
+package poindexter
+
+// small max-heap for (distance, index)
+// We’ll use a slice maintaining the largest distance at [0] via container/heap-like ops.
+type knnItem struct {
+	idx  int
+	dist float64
+}
+
+type knnHeap []knnItem
+
+func (h knnHeap) Len() int           { return len(h) }
+func (h knnHeap) less(i, j int) bool { return h[i].dist > h[j].dist } // max-heap by dist
+func (h *knnHeap) push(x knnItem)    { *h = append(*h, x); h.up(len(*h) - 1) }
+func (h *knnHeap) pop() knnItem {
+	n := len(*h) - 1
+	h.swap(0, n)
+	v := (*h)[n]
+	*h = (*h)[:n]
+	h.down(0)
+	return v
+}
+func (h *knnHeap) peek() knnItem { return (*h)[0] }
+func (h knnHeap) swap(i, j int)  { h[i], h[j] = h[j], h[i] }
+func (h *knnHeap) up(i int) {
+	for i > 0 {
+		p := (i - 1) / 2
+		if !h.less(i, p) {
+			break
+		}
+		h.swap(i, p)
+		i = p
+	}
+}
+func (h *knnHeap) down(i int) {
+	for {
+		l := 2*i + 1
+		r := l + 1
+		largest := i
+		if l < h.Len() && h.less(l, largest) {
+			largest = l
+		}
+		if r < h.Len() && h.less(r, largest) {
+			largest = r
+		}
+		if largest == i {
+			break
+		}
+		h.swap(i, largest)
+		i = largest
+	}
+}
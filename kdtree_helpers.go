@@ -1,8 +1,12 @@
 package poindexter
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
-// Helper builders for KDTree points with min-max normalisation, optional inversion per-axis,
+// Helper builders for KDTree points with per-axis normalisation (min-max unless
+// WithNormalization picks another strategy), optional inversion per-axis,
 // and per-axis weights. These are convenience utilities to make it easy to map domain
 // records into KD space for 2D/3D/4D use-cases.
 
@@ -16,59 +20,165 @@ var (
 	ErrInvalidInvert = errors.New("kdtree: invalid invert length; must match number of features")
 	// ErrStatsDimMismatch indicates NormStats dimensions do not match features length.
 	ErrStatsDimMismatch = errors.New("kdtree: stats dimensionality mismatch")
+	// ErrInvalidAxisTransform indicates WithAxisTransform, WithAxisSigmoid or
+	// WithTargetRange named an axis outside the features, WithAxisSigmoid was
+	// given a non-positive width, or WithTargetRange an empty range.
+	ErrInvalidAxisTransform = errors.New("kdtree: invalid axis transform")
+	// ErrInvalidAxisMask indicates a WithAxisMask whose length does not match
+	// the features, or which excludes every feature.
+	ErrInvalidAxisMask = errors.New("kdtree: invalid axis mask")
+	// ErrInvalidWinsorize indicates WithWinsorize quantiles outside
+	// 0 <= lower < upper <= 1.
+	ErrInvalidWinsorize = errors.New("kdtree: invalid winsorize quantiles")
 )
 
-// AxisStats holds the min/max observed for a single axis.
+// AxisStats holds the min/max observed for a single axis. Mean and StdDev are
+// filled in for ZScore normalisation, Median and IQR for Robust. A positive
+// SigmoidWidth makes the axis use WithAxisSigmoid's curve instead. When
+// TargetLo and TargetHi differ, the normalised axis is mapped onto that
+// WithTargetRange. Fill is the value imputed for missing values under the
+// impute policies.
 type AxisStats struct {
-	Min float64
-	Max float64
+	Min             float64
+	Max             float64
+	Mean            float64 `json:",omitempty"`
+	StdDev          float64 `json:",omitempty"`
+	Median          float64 `json:",omitempty"`
+	IQR             float64 `json:",omitempty"`
+	SigmoidMidpoint float64 `json:",omitempty"`
+	SigmoidWidth    float64 `json:",omitempty"`
+	TargetLo        float64 `json:",omitempty"`
+	TargetHi        float64 `json:",omitempty"`
+	Fill            float64 `json:",omitempty"`
 }
 
 // NormStats holds per-axis normalisation statistics.
-// For D dimensions, Stats has length D.
+// For D dimensions, Stats has length D. Method is the strategy the stats were
+// computed for; the zero value is MinMax. Transforms, if set, holds the
+// per-axis transform applied to raw values before Stats were computed,
+// Categories the sorted labels of each WithCategoricalFeature, and Missing
+// the WithMissingValuePolicy they were computed under, and OutOfRange the
+// WithOutOfRange policy the *WithStats builders apply. Count is the number of
+// items the stats cover, which MergeNormStats uses to weight shards.
 type NormStats struct {
-	Stats []AxisStats
+	Stats      []AxisStats
+	Method     Normalization
+	Transforms []AxisTransform
+	Categories [][]string
+	Missing    MissingValuePolicy
+	OutOfRange RangePolicy
+	Count      int
 }
 
-// ComputeNormStatsND computes per-axis min/max for an arbitrary number of features.
-func ComputeNormStatsND[T any](items []T, features []func(T) float64) (NormStats, error) {
+// ComputeNormStatsND computes per-axis statistics for an arbitrary number of
+// features: min/max, plus whatever the WithNormalization strategy needs.
+func ComputeNormStatsND[T any](items []T, features []func(T) float64, opts ...BuildOption) (NormStats, error) {
 	if len(features) == 0 {
 		return NormStats{}, ErrInvalidFeatures
 	}
-	// Initialise mins/maxes on first item where possible
-	stats := make([]AxisStats, len(features))
-	if len(items) == 0 {
-		// empty items → zero stats slice of correct dim
-		return NormStats{Stats: stats}, nil
+	cfg := applyBuildOptions(opts)
+	cats, err := resolveCategorical[T](cfg)
+	if err != nil {
+		return NormStats{}, err
 	}
-	// Seed with first item values
-	first := items[0]
-	for i, f := range features {
+	for _, f := range features {
 		if f == nil {
 			return NormStats{}, ErrInvalidFeatures
 		}
-		v := f(first)
-		stats[i] = AxisStats{Min: v, Max: v}
 	}
-	// Process remaining items
-	for _, it := range items[1:] {
-		for i, f := range features {
-			v := f(it)
-			if v < stats[i].Min {
-				stats[i].Min = v
+	return cfg.normStats(len(items), len(features), len(cats), itemSource(items, features, cats))
+}
+
+// ndSource supplies the raw values the N-D helpers work from: num(j, d) is
+// feature d of item j and cat(j, c) its label for categorical feature c.
+type ndSource struct {
+	num func(j, d int) float64
+	cat func(j, c int) string
+}
+
+// itemSource reads values by calling the extractors on the items.
+func itemSource[T any](items []T, features []func(T) float64, cats []func(T) string) ndSource {
+	return ndSource{
+		num: func(j, d int) float64 { return features[d](items[j]) },
+		cat: func(j, c int) string { return cats[c](items[j]) },
+	}
+}
+
+// normStats computes the stats of n items with the given numbers of numeric
+// and categorical features, reading their values from src.
+func (o buildOptions) normStats(n, features, categoricals int, src ndSource) (NormStats, error) {
+	norm := o.norm
+	ts, err := o.axisTransforms(features)
+	if err != nil {
+		return NormStats{}, err
+	}
+	var categories [][]string
+	if categoricals > 0 {
+		categories = make([][]string, categoricals)
+	}
+	stats := make([]AxisStats, features)
+	if n == 0 {
+		// empty items → zero stats slice of correct dim (keeping any sigmoids)
+		for i := range stats {
+			stats[i] = o.axisStats(i, nil)
+		}
+		return NormStats{Stats: stats, Method: norm, Transforms: ts, OutOfRange: o.statsRange(), Categories: categories, Missing: o.missing}, nil
+	}
+	cols := make([][]float64, features)
+	keep := make([]bool, n) // false for items MissingDrop leaves out
+	for j := range keep {
+		keep[j] = true
+	}
+	for i := range cols {
+		cols[i] = make([]float64, n)
+		for j := range n {
+			v := transformAt(ts, i, src.num(j, i))
+			cols[i][j] = v
+			if o.missing != MissingPropagate && isMissing(v) {
+				if o.missing == MissingError {
+					return NormStats{}, fmt.Errorf("%w: item %d, feature %d", ErrMissingValue, j, i)
+				}
+				if o.missing == MissingDrop {
+					keep[j] = false
+				}
+			}
+		}
+	}
+	vals := make([]float64, 0, n)
+	for i := range cols {
+		vals = vals[:0]
+		for j, v := range cols[i] {
+			if keep[j] && (o.missing == MissingPropagate || !isMissing(v)) {
+				vals = append(vals, v)
 			}
-			if v > stats[i].Max {
-				stats[i].Max = v
+		}
+		w := o.winsorize(vals)
+		stats[i] = o.axisStats(i, w)
+		stats[i].Fill = o.fill(w)
+	}
+	for c := range categories {
+		values := make([]string, 0, n)
+		for j := range n {
+			if keep[j] {
+				values = append(values, src.cat(j, c))
 			}
 		}
+		categories[c] = categoryLabels(values)
 	}
-	return NormStats{Stats: stats}, nil
+	count := 0
+	for _, k := range keep {
+		if k {
+			count++
+		}
+	}
+	return NormStats{Stats: stats, Method: norm, Transforms: ts, OutOfRange: o.statsRange(), Categories: categories, Missing: o.missing, Count: count}, nil
 }
 
 // BuildND constructs normalised-and-weighted KD points from arbitrary amount features.
-// Features are min-max normalised per axis over the provided items, optionally inverted,
-// then multiplied by per-axis weights.
-func BuildND[T any](items []T, id func(T) string, features []func(T) float64, weights []float64, invert []bool) ([]KDPoint[T], error) {
+// Features are normalised per axis over the provided items (min-max unless
+// WithNormalization says otherwise), optionally inverted, then multiplied by per-axis weights.
+// NaN and ±Inf values pass through unless WithMissingValuePolicy handles them.
+func BuildND[T any](items []T, id func(T) string, features []func(T) float64, weights []float64, invert []bool, opts ...BuildOption) ([]KDPoint[T], error) {
 	if len(items) == 0 {
 		return nil, nil
 	}
@@ -81,18 +191,43 @@ func BuildND[T any](items []T, id func(T) string, features []func(T) float64, we
 	if len(invert) != len(features) {
 		return nil, ErrInvalidInvert
 	}
-	stats, err := ComputeNormStatsND(items, features)
+	stats, err := ComputeNormStatsND(items, features, opts...)
 	if err != nil {
 		return nil, err
 	}
-	return BuildNDWithStats(items, id, features, weights, invert, stats)
+	return BuildNDWithStats(items, id, features, weights, invert, stats, opts...)
+}
+
+// BuildNDWithComputedStats is BuildND that also returns the normalisation
+// stats it computed, so they can be persisted (see NormStats.MarshalJSON)
+// and handed to BuildNDWithStats for later items in one call. With no items
+// it returns no points and the stats ComputeNormStatsND gives for none.
+func BuildNDWithComputedStats[T any](items []T, id func(T) string, features []func(T) float64, weights []float64, invert []bool, opts ...BuildOption) ([]KDPoint[T], NormStats, error) {
+	if len(features) == 0 {
+		return nil, NormStats{}, ErrInvalidFeatures
+	}
+	if len(weights) != len(features) {
+		return nil, NormStats{}, ErrInvalidWeights
+	}
+	if len(invert) != len(features) {
+		return nil, NormStats{}, ErrInvalidInvert
+	}
+	stats, err := ComputeNormStatsND(items, features, opts...)
+	if err != nil {
+		return nil, NormStats{}, err
+	}
+	pts, err := BuildNDWithStats(items, id, features, weights, invert, stats, opts...)
+	if err != nil {
+		return nil, NormStats{}, err
+	}
+	return pts, stats, nil
 }
 
 // BuildNDNoErr constructs normalized-and-weighted KD points like BuildND but never returns an error.
 // It performs no input validation beyond basic length checks and will propagate NaN/Inf values
-// from feature extractors into the resulting coordinates. Use when you control inputs and want a
-// simpler call signature.
-func BuildNDNoErr[T any](items []T, id func(T) string, features []func(T) float64, weights []float64, invert []bool) []KDPoint[T] {
+// from feature extractors into the resulting coordinates unless WithMissingValuePolicy says
+// otherwise. Use when you control inputs and want a simpler call signature.
+func BuildNDNoErr[T any](items []T, id func(T) string, features []func(T) float64, weights []float64, invert []bool, opts ...BuildOption) []KDPoint[T] {
 	if len(items) == 0 || len(features) == 0 {
 		return nil
 	}
@@ -100,13 +235,18 @@ func BuildNDNoErr[T any](items []T, id func(T) string, features []func(T) float6
 	if len(weights) != len(features) || len(invert) != len(features) {
 		return nil
 	}
-	stats, _ := ComputeNormStatsND(items, features)
-	pts, _ := BuildNDWithStats(items, id, features, weights, invert, stats)
+	stats, _ := ComputeNormStatsND(items, features, opts...)
+	pts, _ := BuildNDWithStats(items, id, features, weights, invert, stats, opts...)
 	return pts
 }
 
-// BuildNDWithStats builds points using provided normalisation stats.
-func BuildNDWithStats[T any](items []T, id func(T) string, features []func(T) float64, weights []float64, invert []bool, stats NormStats) ([]KDPoint[T], error) {
+// BuildNDWithStats builds points using provided normalisation stats, scaled
+// with stats.Method. Of opts, only WithCategoricalFeature,
+// WithMissingValuePolicy, WithOutOfRange and WithAxisMask apply here; the
+// stats already carry the normalisation and transforms. Imputed values come from AxisStats.Fill
+// (or WithMissingValueConstant), so compute the stats under the same
+// impute policy.
+func BuildNDWithStats[T any](items []T, id func(T) string, features []func(T) float64, weights []float64, invert []bool, stats NormStats, opts ...BuildOption) ([]KDPoint[T], error) {
 	if len(items) == 0 {
 		return nil, nil
 	}
@@ -122,24 +262,80 @@ func BuildNDWithStats[T any](items []T, id func(T) string, features []func(T) fl
 	if len(stats.Stats) != len(features) {
 		return nil, ErrStatsDimMismatch
 	}
-	pts := make([]KDPoint[T], len(items))
+	cfg := applyBuildOptions(opts)
+	cats, err := resolveCategorical[T](cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range features {
+		if f == nil {
+			return nil, ErrInvalidFeatures
+		}
+	}
+	return buildNDFrom(items, id, weights, invert, stats, cfg, len(cats), itemSource(items, features, cats))
+}
+
+// buildNDFrom is BuildNDWithStats reading raw values from src, with one
+// numeric feature per weight and the given number of categorical features.
+func buildNDFrom[T any](items []T, id func(T) string, weights []float64, invert []bool, stats NormStats, cfg buildOptions, categoricals int, src ndSource) ([]KDPoint[T], error) {
+	features := len(weights)
+	if err := cfg.checkMask(features); err != nil {
+		return nil, err
+	}
+	if len(stats.Categories) != categoricals {
+		return nil, ErrStatsDimMismatch
+	}
+	dim := features
+	cols := make([]map[string]int, categoricals)
+	for c, labels := range stats.Categories {
+		cols[c] = categoryColumns(labels)
+		dim += len(labels)
+	}
+	missing := stats.Missing
+	if cfg.missingSet {
+		missing = cfg.missing
+	}
+	rp := cfg.rangePolicy(stats)
+	pts := make([]KDPoint[T], 0, len(items))
+nextItem:
 	for i, it := range items {
-		coords := make([]float64, len(features))
-		for d, f := range features {
-			if f == nil {
-				return nil, ErrInvalidFeatures
+		coords := make([]float64, dim)
+		for d := range features {
+			v := transformAt(stats.Transforms, d, src.num(i, d))
+			if missing != MissingPropagate && isMissing(v) {
+				switch {
+				case missing == MissingError:
+					return nil, fmt.Errorf("%w: item %d, feature %d", ErrMissingValue, i, d)
+				case missing == MissingDrop:
+					continue nextItem
+				case missing == MissingImputeConstant && cfg.missingSet:
+					v = cfg.missingConst
+				default:
+					v = stats.Stats[d].Fill
+				}
+			}
+			v, err := stats.boundAxis(i, d, v, rp)
+			if err != nil {
+				return nil, err
 			}
-			n := scale01(f(it), stats.Stats[d].Min, stats.Stats[d].Max)
+			n := stats.Method.scale(v, stats.Stats[d])
 			if invert[d] {
-				n = 1 - n
+				n = stats.Method.invert(n)
 			}
-			coords[d] = weights[d] * n
+			coords[d] = weights[d] * stats.Stats[d].target(n)
+		}
+		off := features
+		for c := range categoricals {
+			if col, ok := cols[c][src.cat(i, c)]; ok {
+				coords[off+col] = cfg.categorical[c].weight
+			}
+			off += len(stats.Categories[c])
 		}
 		var pid string
 		if id != nil {
 			pid = id(it)
 		}
-		pts[i] = KDPoint[T]{ID: pid, Value: it, Coords: coords}
+		pts = append(pts, KDPoint[T]{ID: pid, Value: it, Coords: cfg.masked(coords)})
 	}
 	return pts, nil
 }
@@ -170,51 +366,51 @@ func scale01(v, min, max float64) float64 {
 }
 
 // ComputeNormStats2D computes per-axis min/max for two features.
-func ComputeNormStats2D[T any](items []T, f1, f2 func(T) float64) NormStats {
+func ComputeNormStats2D[T any](items []T, f1, f2 func(T) float64, opts ...BuildOption) NormStats {
+	cfg := applyBuildOptions(opts)
+	norm := cfg.norm
+	ts, _ := cfg.axisTransforms(2)
 	vals1 := make([]float64, len(items))
 	vals2 := make([]float64, len(items))
 	for i, it := range items {
-		vals1[i] = f1(it)
-		vals2[i] = f2(it)
+		vals1[i] = transformAt(ts, 0, f1(it))
+		vals2[i] = transformAt(ts, 1, f2(it))
 	}
-	mn1, mx1 := minMax(vals1)
-	mn2, mx2 := minMax(vals2)
-	return NormStats{Stats: []AxisStats{{mn1, mx1}, {mn2, mx2}}}
+	return NormStats{Stats: []AxisStats{cfg.axisStats(0, cfg.winsorize(vals1)), cfg.axisStats(1, cfg.winsorize(vals2))}, Method: norm, Transforms: ts, OutOfRange: cfg.statsRange(), Count: len(items)}
 }
 
 // ComputeNormStats3D computes per-axis min/max for three features.
-func ComputeNormStats3D[T any](items []T, f1, f2, f3 func(T) float64) NormStats {
+func ComputeNormStats3D[T any](items []T, f1, f2, f3 func(T) float64, opts ...BuildOption) NormStats {
+	cfg := applyBuildOptions(opts)
+	norm := cfg.norm
+	ts, _ := cfg.axisTransforms(3)
 	vals1 := make([]float64, len(items))
 	vals2 := make([]float64, len(items))
 	vals3 := make([]float64, len(items))
 	for i, it := range items {
-		vals1[i] = f1(it)
-		vals2[i] = f2(it)
-		vals3[i] = f3(it)
-	}
-	mn1, mx1 := minMax(vals1)
-	mn2, mx2 := minMax(vals2)
-	mn3, mx3 := minMax(vals3)
-	return NormStats{Stats: []AxisStats{{mn1, mx1}, {mn2, mx2}, {mn3, mx3}}}
+		vals1[i] = transformAt(ts, 0, f1(it))
+		vals2[i] = transformAt(ts, 1, f2(it))
+		vals3[i] = transformAt(ts, 2, f3(it))
+	}
+	return NormStats{Stats: []AxisStats{cfg.axisStats(0, cfg.winsorize(vals1)), cfg.axisStats(1, cfg.winsorize(vals2)), cfg.axisStats(2, cfg.winsorize(vals3))}, Method: norm, Transforms: ts, OutOfRange: cfg.statsRange(), Count: len(items)}
 }
 
 // ComputeNormStats4D computes per-axis min/max for four features.
-func ComputeNormStats4D[T any](items []T, f1, f2, f3, f4 func(T) float64) NormStats {
+func ComputeNormStats4D[T any](items []T, f1, f2, f3, f4 func(T) float64, opts ...BuildOption) NormStats {
+	cfg := applyBuildOptions(opts)
+	norm := cfg.norm
+	ts, _ := cfg.axisTransforms(4)
 	vals1 := make([]float64, len(items))
 	vals2 := make([]float64, len(items))
 	vals3 := make([]float64, len(items))
 	vals4 := make([]float64, len(items))
 	for i, it := range items {
-		vals1[i] = f1(it)
-		vals2[i] = f2(it)
-		vals3[i] = f3(it)
-		vals4[i] = f4(it)
-	}
-	mn1, mx1 := minMax(vals1)
-	mn2, mx2 := minMax(vals2)
-	mn3, mx3 := minMax(vals3)
-	mn4, mx4 := minMax(vals4)
-	return NormStats{Stats: []AxisStats{{mn1, mx1}, {mn2, mx2}, {mn3, mx3}, {mn4, mx4}}}
+		vals1[i] = transformAt(ts, 0, f1(it))
+		vals2[i] = transformAt(ts, 1, f2(it))
+		vals3[i] = transformAt(ts, 2, f3(it))
+		vals4[i] = transformAt(ts, 3, f4(it))
+	}
+	return NormStats{Stats: []AxisStats{cfg.axisStats(0, cfg.winsorize(vals1)), cfg.axisStats(1, cfg.winsorize(vals2)), cfg.axisStats(2, cfg.winsorize(vals3)), cfg.axisStats(3, cfg.winsorize(vals4))}, Method: norm, Transforms: ts, OutOfRange: cfg.statsRange(), Count: len(items)}
 }
 
 // Build2D constructs normalised-and-weighted KD points from items using two feature extractors.
@@ -222,225 +418,307 @@ func ComputeNormStats4D[T any](items []T, f1, f2, f3, f4 func(T) float64) NormSt
 // - f1,f2: feature extractors (raw values)
 // - weights: per-axis weights applied after normalization
 // - invert: per-axis flags; if true, the axis is inverted (1-norm) so that higher raw values become lower cost
-func Build2D[T any](items []T, id func(T) string, f1, f2 func(T) float64, weights [2]float64, invert [2]bool) ([]KDPoint[T], error) {
+// - opts: e.g. WithNormalization(ZScore) or WithAxisTransform(1, Log1p); min-max normalisation by default
+func Build2D[T any](items []T, id func(T) string, f1, f2 func(T) float64, weights [2]float64, invert [2]bool, opts ...BuildOption) ([]KDPoint[T], error) {
 	if len(items) == 0 {
 		return nil, nil
 	}
+	cfg := applyBuildOptions(opts)
+	if err := cfg.checkMask(2); err != nil {
+		return nil, err
+	}
+	ts, err := cfg.axisTransforms(2)
+	if err != nil {
+		return nil, err
+	}
 	vals1 := make([]float64, len(items))
 	vals2 := make([]float64, len(items))
 	for i, it := range items {
-		vals1[i] = f1(it)
-		vals2[i] = f2(it)
-	}
-	mn1, mx1 := minMax(vals1)
-	mn2, mx2 := minMax(vals2)
+		vals1[i] = transformAt(ts, 0, f1(it))
+		vals2[i] = transformAt(ts, 1, f2(it))
+	}
+	norm := cfg.norm
+	vals1 = cfg.winsorize(vals1)
+	s1 := cfg.axisStats(0, vals1)
+	vals2 = cfg.winsorize(vals2)
+	s2 := cfg.axisStats(1, vals2)
 
 	pts := make([]KDPoint[T], len(items))
 	for i, it := range items {
-		n1 := scale01(vals1[i], mn1, mx1)
-		n2 := scale01(vals2[i], mn2, mx2)
+		n1 := norm.scale(vals1[i], s1)
+		n2 := norm.scale(vals2[i], s2)
 		if invert[0] {
-			n1 = 1 - n1
+			n1 = norm.invert(n1)
 		}
 		if invert[1] {
-			n2 = 1 - n2
+			n2 = norm.invert(n2)
 		}
 		pts[i] = KDPoint[T]{
 			ID:    id(it),
 			Value: it,
-			Coords: []float64{
-				weights[0] * n1,
-				weights[1] * n2,
-			},
+			Coords: cfg.masked([]float64{
+				weights[0] * s1.target(n1),
+				weights[1] * s2.target(n2),
+			}),
 		}
 	}
 	return pts, nil
 }
 
 // Build2DWithStats builds points using provided normalisation stats.
-func Build2DWithStats[T any](items []T, id func(T) string, f1, f2 func(T) float64, weights [2]float64, invert [2]bool, stats NormStats) ([]KDPoint[T], error) {
+func Build2DWithStats[T any](items []T, id func(T) string, f1, f2 func(T) float64, weights [2]float64, invert [2]bool, stats NormStats, opts ...BuildOption) ([]KDPoint[T], error) {
 	if len(items) == 0 {
 		return nil, nil
 	}
 	if len(stats.Stats) != 2 {
 		return nil, ErrStatsDimMismatch
 	}
+	norm := stats.Method
+	cfg := applyBuildOptions(opts)
+	if err := cfg.checkMask(2); err != nil {
+		return nil, err
+	}
+	rp := cfg.rangePolicy(stats)
 	pts := make([]KDPoint[T], len(items))
 	for i, it := range items {
-		n1 := scale01(f1(it), stats.Stats[0].Min, stats.Stats[0].Max)
-		n2 := scale01(f2(it), stats.Stats[1].Min, stats.Stats[1].Max)
+		n1, err := stats.scaleAxis(i, 0, f1(it), rp)
+		if err != nil {
+			return nil, err
+		}
+		n2, err := stats.scaleAxis(i, 1, f2(it), rp)
+		if err != nil {
+			return nil, err
+		}
 		if invert[0] {
-			n1 = 1 - n1
+			n1 = norm.invert(n1)
 		}
 		if invert[1] {
-			n2 = 1 - n2
+			n2 = norm.invert(n2)
 		}
 		pts[i] = KDPoint[T]{
 			ID:     id(it),
 			Value:  it,
-			Coords: []float64{weights[0] * n1, weights[1] * n2},
+			Coords: cfg.masked([]float64{weights[0] * stats.Stats[0].target(n1), weights[1] * stats.Stats[1].target(n2)}),
 		}
 	}
 	return pts, nil
 }
 
 // Build3D constructs normalised-and-weighted KD points using three feature extractors.
-func Build3D[T any](items []T, id func(T) string, f1, f2, f3 func(T) float64, weights [3]float64, invert [3]bool) ([]KDPoint[T], error) {
+func Build3D[T any](items []T, id func(T) string, f1, f2, f3 func(T) float64, weights [3]float64, invert [3]bool, opts ...BuildOption) ([]KDPoint[T], error) {
 	if len(items) == 0 {
 		return nil, nil
 	}
+	cfg := applyBuildOptions(opts)
+	if err := cfg.checkMask(3); err != nil {
+		return nil, err
+	}
+	ts, err := cfg.axisTransforms(3)
+	if err != nil {
+		return nil, err
+	}
 	vals1 := make([]float64, len(items))
 	vals2 := make([]float64, len(items))
 	vals3 := make([]float64, len(items))
 	for i, it := range items {
-		vals1[i] = f1(it)
-		vals2[i] = f2(it)
-		vals3[i] = f3(it)
-	}
-	mn1, mx1 := minMax(vals1)
-	mn2, mx2 := minMax(vals2)
-	mn3, mx3 := minMax(vals3)
+		vals1[i] = transformAt(ts, 0, f1(it))
+		vals2[i] = transformAt(ts, 1, f2(it))
+		vals3[i] = transformAt(ts, 2, f3(it))
+	}
+	norm := cfg.norm
+	vals1 = cfg.winsorize(vals1)
+	s1 := cfg.axisStats(0, vals1)
+	vals2 = cfg.winsorize(vals2)
+	s2 := cfg.axisStats(1, vals2)
+	vals3 = cfg.winsorize(vals3)
+	s3 := cfg.axisStats(2, vals3)
 
 	pts := make([]KDPoint[T], len(items))
 	for i, it := range items {
-		n1 := scale01(vals1[i], mn1, mx1)
-		n2 := scale01(vals2[i], mn2, mx2)
-		n3 := scale01(vals3[i], mn3, mx3)
+		n1 := norm.scale(vals1[i], s1)
+		n2 := norm.scale(vals2[i], s2)
+		n3 := norm.scale(vals3[i], s3)
 		if invert[0] {
-			n1 = 1 - n1
+			n1 = norm.invert(n1)
 		}
 		if invert[1] {
-			n2 = 1 - n2
+			n2 = norm.invert(n2)
 		}
 		if invert[2] {
-			n3 = 1 - n3
+			n3 = norm.invert(n3)
 		}
 		pts[i] = KDPoint[T]{
 			ID:    id(it),
 			Value: it,
-			Coords: []float64{
-				weights[0] * n1,
-				weights[1] * n2,
-				weights[2] * n3,
-			},
+			Coords: cfg.masked([]float64{
+				weights[0] * s1.target(n1),
+				weights[1] * s2.target(n2),
+				weights[2] * s3.target(n3),
+			}),
 		}
 	}
 	return pts, nil
 }
 
 // Build3DWithStats builds points using provided normalisation stats.
-func Build3DWithStats[T any](items []T, id func(T) string, f1, f2, f3 func(T) float64, weights [3]float64, invert [3]bool, stats NormStats) ([]KDPoint[T], error) {
+func Build3DWithStats[T any](items []T, id func(T) string, f1, f2, f3 func(T) float64, weights [3]float64, invert [3]bool, stats NormStats, opts ...BuildOption) ([]KDPoint[T], error) {
 	if len(items) == 0 {
 		return nil, nil
 	}
 	if len(stats.Stats) != 3 {
 		return nil, ErrStatsDimMismatch
 	}
+	norm := stats.Method
+	cfg := applyBuildOptions(opts)
+	if err := cfg.checkMask(3); err != nil {
+		return nil, err
+	}
+	rp := cfg.rangePolicy(stats)
 	pts := make([]KDPoint[T], len(items))
 	for i, it := range items {
-		n1 := scale01(f1(it), stats.Stats[0].Min, stats.Stats[0].Max)
-		n2 := scale01(f2(it), stats.Stats[1].Min, stats.Stats[1].Max)
-		n3 := scale01(f3(it), stats.Stats[2].Min, stats.Stats[2].Max)
+		n1, err := stats.scaleAxis(i, 0, f1(it), rp)
+		if err != nil {
+			return nil, err
+		}
+		n2, err := stats.scaleAxis(i, 1, f2(it), rp)
+		if err != nil {
+			return nil, err
+		}
+		n3, err := stats.scaleAxis(i, 2, f3(it), rp)
+		if err != nil {
+			return nil, err
+		}
 		if invert[0] {
-			n1 = 1 - n1
+			n1 = norm.invert(n1)
 		}
 		if invert[1] {
-			n2 = 1 - n2
+			n2 = norm.invert(n2)
 		}
 		if invert[2] {
-			n3 = 1 - n3
+			n3 = norm.invert(n3)
 		}
 		pts[i] = KDPoint[T]{
 			ID:     id(it),
 			Value:  it,
-			Coords: []float64{weights[0] * n1, weights[1] * n2, weights[2] * n3},
+			Coords: cfg.masked([]float64{weights[0] * stats.Stats[0].target(n1), weights[1] * stats.Stats[1].target(n2), weights[2] * stats.Stats[2].target(n3)}),
 		}
 	}
 	return pts, nil
 }
 
 // Build4D constructs normalised-and-weighted KD points using four feature extractors.
-func Build4D[T any](items []T, id func(T) string, f1, f2, f3, f4 func(T) float64, weights [4]float64, invert [4]bool) ([]KDPoint[T], error) {
+func Build4D[T any](items []T, id func(T) string, f1, f2, f3, f4 func(T) float64, weights [4]float64, invert [4]bool, opts ...BuildOption) ([]KDPoint[T], error) {
 	if len(items) == 0 {
 		return nil, nil
 	}
+	cfg := applyBuildOptions(opts)
+	if err := cfg.checkMask(4); err != nil {
+		return nil, err
+	}
+	ts, err := cfg.axisTransforms(4)
+	if err != nil {
+		return nil, err
+	}
 	vals1 := make([]float64, len(items))
 	vals2 := make([]float64, len(items))
 	vals3 := make([]float64, len(items))
 	vals4 := make([]float64, len(items))
 	for i, it := range items {
-		vals1[i] = f1(it)
-		vals2[i] = f2(it)
-		vals3[i] = f3(it)
-		vals4[i] = f4(it)
-	}
-	mn1, mx1 := minMax(vals1)
-	mn2, mx2 := minMax(vals2)
-	mn3, mx3 := minMax(vals3)
-	mn4, mx4 := minMax(vals4)
+		vals1[i] = transformAt(ts, 0, f1(it))
+		vals2[i] = transformAt(ts, 1, f2(it))
+		vals3[i] = transformAt(ts, 2, f3(it))
+		vals4[i] = transformAt(ts, 3, f4(it))
+	}
+	norm := cfg.norm
+	vals1 = cfg.winsorize(vals1)
+	s1 := cfg.axisStats(0, vals1)
+	vals2 = cfg.winsorize(vals2)
+	s2 := cfg.axisStats(1, vals2)
+	vals3 = cfg.winsorize(vals3)
+	s3 := cfg.axisStats(2, vals3)
+	vals4 = cfg.winsorize(vals4)
+	s4 := cfg.axisStats(3, vals4)
 
 	pts := make([]KDPoint[T], len(items))
 	for i, it := range items {
-		n1 := scale01(vals1[i], mn1, mx1)
-		n2 := scale01(vals2[i], mn2, mx2)
-		n3 := scale01(vals3[i], mn3, mx3)
-		n4 := scale01(vals4[i], mn4, mx4)
+		n1 := norm.scale(vals1[i], s1)
+		n2 := norm.scale(vals2[i], s2)
+		n3 := norm.scale(vals3[i], s3)
+		n4 := norm.scale(vals4[i], s4)
 		if invert[0] {
-			n1 = 1 - n1
+			n1 = norm.invert(n1)
 		}
 		if invert[1] {
-			n2 = 1 - n2
+			n2 = norm.invert(n2)
 		}
 		if invert[2] {
-			n3 = 1 - n3
+			n3 = norm.invert(n3)
 		}
 		if invert[3] {
-			n4 = 1 - n4
+			n4 = norm.invert(n4)
 		}
 		pts[i] = KDPoint[T]{
 			ID:    id(it),
 			Value: it,
-			Coords: []float64{
-				weights[0] * n1,
-				weights[1] * n2,
-				weights[2] * n3,
-				weights[3] * n4,
-			},
+			Coords: cfg.masked([]float64{
+				weights[0] * s1.target(n1),
+				weights[1] * s2.target(n2),
+				weights[2] * s3.target(n3),
+				weights[3] * s4.target(n4),
+			}),
 		}
 	}
 	return pts, nil
 }
 
 // Build4DWithStats builds points using provided normalisation stats.
-func Build4DWithStats[T any](items []T, id func(T) string, f1, f2, f3, f4 func(T) float64, weights [4]float64, invert [4]bool, stats NormStats) ([]KDPoint[T], error) {
+func Build4DWithStats[T any](items []T, id func(T) string, f1, f2, f3, f4 func(T) float64, weights [4]float64, invert [4]bool, stats NormStats, opts ...BuildOption) ([]KDPoint[T], error) {
 	if len(items) == 0 {
 		return nil, nil
 	}
 	if len(stats.Stats) != 4 {
 		return nil, ErrStatsDimMismatch
 	}
+	norm := stats.Method
+	cfg := applyBuildOptions(opts)
+	if err := cfg.checkMask(4); err != nil {
+		return nil, err
+	}
+	rp := cfg.rangePolicy(stats)
 	pts := make([]KDPoint[T], len(items))
 	for i, it := range items {
-		n1 := scale01(f1(it), stats.Stats[0].Min, stats.Stats[0].Max)
-		n2 := scale01(f2(it), stats.Stats[1].Min, stats.Stats[1].Max)
-		n3 := scale01(f3(it), stats.Stats[2].Min, stats.Stats[2].Max)
-		n4 := scale01(f4(it), stats.Stats[3].Min, stats.Stats[3].Max)
+		n1, err := stats.scaleAxis(i, 0, f1(it), rp)
+		if err != nil {
+			return nil, err
+		}
+		n2, err := stats.scaleAxis(i, 1, f2(it), rp)
+		if err != nil {
+			return nil, err
+		}
+		n3, err := stats.scaleAxis(i, 2, f3(it), rp)
+		if err != nil {
+			return nil, err
+		}
+		n4, err := stats.scaleAxis(i, 3, f4(it), rp)
+		if err != nil {
+			return nil, err
+		}
 		if invert[0] {
-			n1 = 1 - n1
+			n1 = norm.invert(n1)
 		}
 		if invert[1] {
-			n2 = 1 - n2
+			n2 = norm.invert(n2)
 		}
 		if invert[2] {
-			n3 = 1 - n3
+			n3 = norm.invert(n3)
 		}
 		if invert[3] {
-			n4 = 1 - n4
+			n4 = norm.invert(n4)
 		}
 		pts[i] = KDPoint[T]{
 			ID:     id(it),
 			Value:  it,
-			Coords: []float64{weights[0] * n1, weights[1] * n2, weights[2] * n3, weights[3] * n4},
+			Coords: cfg.masked([]float64{weights[0] * stats.Stats[0].target(n1), weights[1] * stats.Stats[1].target(n2), weights[2] * stats.Stats[2].target(n3), weights[3] * stats.Stats[3].target(n4)}),
 		}
 	}
 	return pts, nil
@@ -281,3 +281,89 @@ func TestBuildNDError(t *testing.T) {
 		t.Fatal("expected an error, but got nil")
 	}
 }
+
+func TestWithAxisMask(t *testing.T) {
+	recs := normRecs()[1:] // pings 20..100, hops 0..2
+	id := func(r normRec) string { return r.id }
+	ping := func(r normRec) float64 { return r.ping }
+	hops := func(r normRec) float64 { return r.hops }
+	features := []func(normRec) float64{ping, hops, func(r normRec) float64 { return -r.ping }}
+
+	full, err := BuildND(recs, id, features, []float64{1, 2, 1}, []bool{false, false, false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	masked, err := BuildND(recs, id, features, []float64{1, 2, 1}, []bool{false, false, false}, WithAxisMask([]bool{true, false, true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range masked {
+		want := []float64{full[i].Coords[0], full[i].Coords[2]}
+		if fmt.Sprint(masked[i].Coords) != fmt.Sprint(want) {
+			t.Fatalf("masked coords = %v, want %v", masked[i].Coords, want)
+		}
+	}
+
+	// categorical columns survive the mask
+	pts, err := BuildND(recs[:1], id, features[:2], []float64{1, 1}, []bool{false, false},
+		WithAxisMask([]bool{false, true}), WithCategoricalFeature(func(normRec) string { return "x" }, 3))
+	if err != nil || fmt.Sprint(pts[0].Coords) != "[0 3]" {
+		t.Fatalf("with categorical: %v, %v", pts, err)
+	}
+
+	// the fixed-dimension builders drop masked axes too
+	stats := ComputeNormStats2D(recs, ping, hops)
+	p2, err := Build2DWithStats(recs[:1], id, ping, hops, [2]float64{1, 1}, [2]bool{}, stats, WithAxisMask([]bool{false, true}))
+	if err != nil || len(p2[0].Coords) != 1 || p2[0].Coords[0] != 0 {
+		t.Fatalf("Build2DWithStats: %v, %v", p2, err)
+	}
+	p3, err := Build3D(recs, id, ping, hops, ping, [3]float64{1, 1, 1}, [3]bool{}, WithAxisMask([]bool{true, true, false}))
+	if err != nil || len(p3[0].Coords) != 2 {
+		t.Fatalf("Build3D: %v, %v", p3, err)
+	}
+
+	for _, mask := range [][]bool{{true}, {false, false, false}} {
+		if _, err := BuildND(recs, id, features, []float64{1, 1, 1}, []bool{false, false, false}, WithAxisMask(mask)); err != ErrInvalidAxisMask {
+			t.Fatalf("mask %v: err = %v", mask, err)
+		}
+	}
+	if _, err := Build2D(recs, id, ping, hops, [2]float64{1, 1}, [2]bool{}, WithAxisMask([]bool{true, true, true})); err != ErrInvalidAxisMask {
+		t.Fatalf("Build2D long mask: err = %v", err)
+	}
+}
+
+func TestBuildNDWithComputedStats(t *testing.T) {
+	recs := normRecs()
+	id := func(r normRec) string { return r.id }
+	features := []func(normRec) float64{
+		func(r normRec) float64 { return r.ping },
+		func(r normRec) float64 { return r.hops },
+	}
+	opts := []BuildOption{WithNormalization(ZScore), WithAxisTransform(0, Log1p)}
+	pts, stats, err := BuildNDWithComputedStats(recs, id, features, []float64{1, 2}, []bool{true, false}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantStats, _ := ComputeNormStatsND(recs, features, opts...)
+	if fmt.Sprint(stats) != fmt.Sprint(wantStats) {
+		t.Fatalf("stats = %+v, want %+v", stats, wantStats)
+	}
+	wantPts, _ := BuildND(recs, id, features, []float64{1, 2}, []bool{true, false}, opts...)
+	if fmt.Sprint(pts) != fmt.Sprint(wantPts) {
+		t.Fatalf("points differ from BuildND")
+	}
+
+	// the returned stats rebuild later items identically
+	again, err := BuildNDWithStats(recs[:3], id, features, []float64{1, 2}, []bool{true, false}, stats)
+	if err != nil || fmt.Sprint(again) != fmt.Sprint(pts[:3]) {
+		t.Fatalf("rebuild = %v, %v", again, err)
+	}
+
+	if _, _, err := BuildNDWithComputedStats(recs, id, features, []float64{1}, []bool{false, false}); err != ErrInvalidWeights {
+		t.Fatalf("short weights: err = %v", err)
+	}
+	pts, stats, err = BuildNDWithComputedStats(nil, id, features, []float64{1, 1}, []bool{false, false})
+	if err != nil || pts != nil || len(stats.Stats) != 2 {
+		t.Fatalf("no items: %v, %+v, %v", pts, stats, err)
+	}
+}
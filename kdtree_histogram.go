@@ -0,0 +1,55 @@
+package poindexter
+
+import "math"
+
+// DefaultHistogramBins is the bin count ComputeHistogram uses when a
+// non-positive one is requested.
+const DefaultHistogramBins = 10
+
+// Histogram counts values in equal-width bins, for rendering an actual
+// distribution rather than summary statistics.
+type Histogram struct {
+	// Edges holds the len(Counts)+1 bin boundaries in ascending order; bin i
+	// covers [Edges[i], Edges[i+1]), and the last bin includes its upper edge.
+	Edges  []float64 `json:"edges"`
+	Counts []int64   `json:"counts"`
+}
+
+// ComputeHistogram bins values into bins equal-width bins spanning their
+// minimum to maximum (DefaultHistogramBins if bins <= 0). NaNs are skipped.
+// If every value is the same, there is a single bin; with no values the
+// histogram is empty.
+func ComputeHistogram(values []float64, bins int) Histogram {
+	if bins <= 0 {
+		bins = DefaultHistogramBins
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	if lo > hi {
+		return Histogram{}
+	}
+	if lo == hi {
+		bins = 1
+	}
+	h := Histogram{Edges: make([]float64, bins+1), Counts: make([]int64, bins)}
+	width := (hi - lo) / float64(bins)
+	for i := range h.Edges {
+		h.Edges[i] = lo + float64(i)*width
+	}
+	h.Edges[bins] = hi
+	for _, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		i := bins - 1
+		if width > 0 {
+			i = min(int((v-lo)/width), bins-1)
+		}
+		h.Counts[i]++
+	}
+	return h
+}
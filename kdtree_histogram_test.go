@@ -0,0 +1,51 @@
+package poindexter
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestComputeHistogram(t *testing.T) {
+	h := ComputeHistogram([]float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 10, math.NaN()}, 5)
+	if !slices.Equal(h.Edges, []float64{0, 2, 4, 6, 8, 10}) {
+		t.Fatalf("edges %v", h.Edges)
+	}
+	if !slices.Equal(h.Counts, []int64{2, 2, 2, 2, 2}) {
+		t.Fatalf("counts %v (the maximum belongs to the last bin)", h.Counts)
+	}
+
+	if h := ComputeHistogram([]float64{3, 3, 3}, 4); len(h.Counts) != 1 || h.Counts[0] != 3 || !slices.Equal(h.Edges, []float64{3, 3}) {
+		t.Fatalf("constant values: %+v", h)
+	}
+	if h := ComputeHistogram(nil, 4); h.Edges != nil || h.Counts != nil {
+		t.Fatalf("expected an empty histogram, got %+v", h)
+	}
+	if h := ComputeHistogram([]float64{0, 1}, 0); len(h.Counts) != DefaultHistogramBins {
+		t.Fatalf("expected %d default bins, got %d", DefaultHistogramBins, len(h.Counts))
+	}
+}
+
+func TestComputeAxisDistributionsWithBins(t *testing.T) {
+	points := []KDPoint[string]{
+		{ID: "a", Coords: []float64{0, 5}},
+		{ID: "b", Coords: []float64{1, 5}},
+		{ID: "c", Coords: []float64{2, 5}},
+		{ID: "d", Coords: []float64{4, 5}},
+	}
+	dists := ComputeAxisDistributionsWithBins(points, nil, 4)
+	if want := []int64{1, 1, 1, 1}; !slices.Equal(dists[0].Histogram.Counts, want) {
+		t.Fatalf("axis 0 counts %v, want %v", dists[0].Histogram.Counts, want)
+	}
+	if want := []float64{0, 1, 2, 3, 4}; !slices.Equal(dists[0].Histogram.Edges, want) {
+		t.Fatalf("axis 0 edges %v, want %v", dists[0].Histogram.Edges, want)
+	}
+	if got := dists[1].Histogram.Counts; len(got) != 1 || got[0] != 4 {
+		t.Fatalf("constant axis counts %v, want one bin of 4", got)
+	}
+
+	def := ComputeAxisDistributions(points, nil)
+	if len(def[0].Histogram.Counts) != DefaultHistogramBins {
+		t.Fatalf("default bins %d, want %d", len(def[0].Histogram.Counts), DefaultHistogramBins)
+	}
+}
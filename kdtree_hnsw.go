@@ -0,0 +1,386 @@
+package poindexter
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// The HNSW backend (BackendHNSW) is a hierarchical navigable small world graph
+// (Malkov & Yashunin): every point is linked to its approximate nearest
+// neighbours on layer 0 and, with exponentially decreasing probability, on
+// sparser upper layers. A query descends greedily through the upper layers and
+// then runs a beam search of width efSearch on layer 0. Results are
+// approximate — a true neighbour can be missed — in exchange for roughly
+// logarithmic query cost in high dimensions, where kd and vp trees degrade to
+// a linear scan. It works with any DistanceMetric.
+//
+// Radius queries are answered exactly by the linear scan: a beam search cannot
+// bound how many points lie within r.
+
+// Default HNSW parameters.
+const (
+	defaultHNSWM              = 16
+	defaultHNSWEfConstruction = 200
+	defaultHNSWEfSearch       = 50
+)
+
+// WithHNSWM sets the number of links per point on the HNSW upper layers
+// (twice as many on layer 0). Larger values raise recall and memory use and
+// slow construction; 12–48 is typical. Default 16.
+func WithHNSWM(m int) KDOption {
+	return func(o *kdOptions) { o.hnswM = m }
+}
+
+// WithHNSWEfConstruction sets the beam width used while building the HNSW
+// graph. Larger values build a better graph (higher recall at the same
+// efSearch) more slowly. Default 200.
+func WithHNSWEfConstruction(ef int) KDOption {
+	return func(o *kdOptions) { o.hnswEfConstruction = ef }
+}
+
+// WithHNSWEfSearch sets the initial HNSW query beam width, the recall-vs-speed
+// knob: larger values visit more of the graph and miss fewer true neighbours.
+// KNearest always uses at least k. Default 50; see KDTree.SetHNSWEfSearch.
+func WithHNSWEfSearch(ef int) KDOption {
+	return func(o *kdOptions) { o.hnswEfSearch = ef }
+}
+
+// SetHNSWEfSearch changes the HNSW query beam width (see WithHNSWEfSearch)
+// without rebuilding the index; it applies to queries that start afterwards,
+// including those on Snapshots sharing the index. It has no effect on other
+// backends.
+func (t *KDTree[T]) SetHNSWEfSearch(ef int) {
+	if t.hnsw != nil {
+		t.hnsw.efSearch.Store(int64(max(ef, 1)))
+	}
+}
+
+// hnswParams are a tree's HNSW settings, shared by every index it builds.
+type hnswParams struct {
+	m              int
+	efConstruction int
+	efSearch       atomic.Int64
+}
+
+func newHNSWParams(o kdOptions) *hnswParams {
+	p := &hnswParams{m: o.hnswM, efConstruction: o.hnswEfConstruction}
+	if p.m < 2 {
+		p.m = defaultHNSWM
+	}
+	if p.efConstruction < 1 {
+		p.efConstruction = defaultHNSWEfConstruction
+	}
+	ef := o.hnswEfSearch
+	if ef < 1 {
+		ef = defaultHNSWEfSearch
+	}
+	p.efSearch.Store(int64(ef))
+	return p
+}
+
+// hnswParamsFor returns the HNSW settings for a tree using backend.
+func hnswParamsFor(backend KDBackend, o kdOptions) *hnswParams {
+	if backend != BackendHNSW {
+		return nil
+	}
+	return newHNSWParams(o)
+}
+
+// clone returns an independent copy of p.
+func (p *hnswParams) clone() *hnswParams {
+	if p == nil {
+		return nil
+	}
+	c := &hnswParams{m: p.m, efConstruction: p.efConstruction}
+	c.efSearch.Store(p.efSearch.Load())
+	return c
+}
+
+// hnswBackend is an HNSW graph over a point set. It is not modified after
+// construction.
+type hnswBackend struct {
+	metric   DistanceMetric
+	coords   func(i int) []float64
+	dim      int
+	len      int
+	params   *hnswParams
+	entry    int // entry point on the top layer, -1 when empty
+	maxLevel int
+	links    [][][]int32 // links[i][l] lists the neighbours of point i on layer l
+	visited  sync.Pool   // *hnswVisited sized for len
+}
+
+// hnswCand is a point and its distance to the point being searched for.
+type hnswCand struct {
+	idx  int
+	dist float64
+}
+
+// hnswVisited marks the points a search has reached.
+type hnswVisited []uint64
+
+func (v hnswVisited) visit(i int) bool {
+	w, bit := i>>6, uint64(1)<<(uint(i)&63)
+	seen := v[w]&bit != 0
+	v[w] |= bit
+	return seen
+}
+
+// buildHNSWBackend builds an HNSW graph by inserting points in order, with
+// levels drawn from a fixed-seed generator so builds are deterministic.
+func buildHNSWBackend[T any](points []KDPoint[T], metric DistanceMetric, params *hnswParams) (any, error) {
+	if metric == nil || params == nil {
+		return nil, ErrBackendUnavailable
+	}
+	b := &hnswBackend{
+		metric: metric,
+		coords: func(i int) []float64 { return points[i].Coords },
+		len:    len(points),
+		params: params,
+		entry:  -1,
+		links:  make([][][]int32, len(points)),
+	}
+	words := (len(points) + 63) / 64
+	b.visited.New = func() any {
+		v := make(hnswVisited, words)
+		return &v
+	}
+	if len(points) == 0 {
+		return b, nil
+	}
+	b.dim = len(points[0].Coords)
+	rng := rand.New(rand.NewPCG(uint64(len(points)), 0x4e5357))
+	mL := 1 / math.Log(float64(params.m))
+	for i := range points {
+		b.insert(i, int(-math.Log(1-rng.Float64())*mL))
+	}
+	return b, nil
+}
+
+// maxLinks is the link budget of a point on layer l.
+func (b *hnswBackend) maxLinks(l int) int {
+	if l == 0 {
+		return 2 * b.params.m
+	}
+	return b.params.m
+}
+
+func (b *hnswBackend) insert(q, level int) {
+	b.links[q] = make([][]int32, level+1)
+	if b.entry < 0 {
+		b.entry, b.maxLevel = q, level
+		return
+	}
+	qc := b.coords(q)
+	ep := hnswCand{b.entry, b.metric.Distance(qc, b.coords(b.entry))}
+	for l := b.maxLevel; l > level; l-- {
+		ep = b.greedy(qc, ep, l)
+	}
+	for l := min(level, b.maxLevel); l >= 0; l-- {
+		cands := b.searchLayer(qc, ep, b.params.efConstruction, l)
+		neigh := b.selectNeighbors(cands, b.params.m)
+		b.links[q][l] = make([]int32, len(neigh))
+		for i, n := range neigh {
+			b.links[q][l][i] = int32(n.idx)
+			b.link(n.idx, q, l)
+		}
+		ep = cands[0]
+	}
+	if level > b.maxLevel {
+		b.entry, b.maxLevel = q, level
+	}
+}
+
+// link adds q to n's neighbours on layer l, re-selecting them if that
+// exceeds the layer's budget.
+func (b *hnswBackend) link(n, q, l int) {
+	ns := append(b.links[n][l], int32(q))
+	if len(ns) > b.maxLinks(l) {
+		nc := b.coords(n)
+		cands := make([]hnswCand, len(ns))
+		for i, x := range ns {
+			cands[i] = hnswCand{int(x), b.metric.Distance(nc, b.coords(int(x)))}
+		}
+		sort.Slice(cands, func(i, j int) bool { return cands[i].dist < cands[j].dist })
+		keep := b.selectNeighbors(cands, b.maxLinks(l))
+		ns = ns[:len(keep)]
+		for i, c := range keep {
+			ns[i] = int32(c.idx)
+		}
+	}
+	b.links[n][l] = ns
+}
+
+// selectNeighbors picks up to m of cands (sorted by distance) using the HNSW
+// heuristic: a candidate is skipped if it is closer to an already chosen
+// neighbour than to the base point, which keeps links spread across
+// directions. Skipped candidates fill any remaining slots.
+func (b *hnswBackend) selectNeighbors(cands []hnswCand, m int) []hnswCand {
+	if len(cands) <= m {
+		return cands
+	}
+	out := make([]hnswCand, 0, m)
+	var skipped []hnswCand
+	for _, c := range cands {
+		if len(out) == m {
+			break
+		}
+		cc := b.coords(c.idx)
+		good := true
+		for _, r := range out {
+			if b.metric.Distance(cc, b.coords(r.idx)) < c.dist {
+				good = false
+				break
+			}
+		}
+		if good {
+			out = append(out, c)
+		} else {
+			skipped = append(skipped, c)
+		}
+	}
+	for _, c := range skipped {
+		if len(out) == m {
+			break
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// greedy walks layer l from ep towards q, returning the closest point found.
+func (b *hnswBackend) greedy(q []float64, ep hnswCand, l int) hnswCand {
+	for changed := true; changed; {
+		changed = false
+		for _, n := range b.links[ep.idx][l] {
+			if d := b.metric.Distance(q, b.coords(int(n))); d < ep.dist {
+				ep, changed = hnswCand{int(n), d}, true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer runs a beam search of width ef on layer l from ep and returns
+// the closest points found, nearest first.
+func (b *hnswBackend) searchLayer(q []float64, ep hnswCand, ef, l int) []hnswCand {
+	vp := b.visited.Get().(*hnswVisited)
+	visited := *vp
+	defer func() {
+		clear(visited)
+		b.visited.Put(vp)
+	}()
+	visited.visit(ep.idx)
+	cands := hnswMinHeap{ep} // to expand, nearest first
+	var res knnHeap          // best found, farthest on top
+	res.push(knnItem{idx: ep.idx, dist: ep.dist})
+	for len(cands) > 0 {
+		c := cands.pop()
+		if res.Len() >= ef && c.dist > res.peek().dist {
+			break
+		}
+		for _, n := range b.links[c.idx][l] {
+			if visited.visit(int(n)) {
+				continue
+			}
+			d := b.metric.Distance(q, b.coords(int(n)))
+			if res.Len() < ef || d < res.peek().dist {
+				cands.push(hnswCand{int(n), d})
+				res.push(knnItem{idx: int(n), dist: d})
+				if res.Len() > ef {
+					res.pop()
+				}
+			}
+		}
+	}
+	out := make([]hnswCand, len(res))
+	for i, it := range res {
+		out[i] = hnswCand{it.idx, it.dist}
+	}
+	slices.SortFunc(out, func(a, c hnswCand) int {
+		switch {
+		case a.dist < c.dist:
+			return -1
+		case a.dist > c.dist:
+			return 1
+		}
+		return a.idx - c.idx
+	})
+	return out
+}
+
+// kNearest drops skipped points from the efSearch candidates, so it may
+// return fewer than k results when many of them are skipped.
+func (b *hnswBackend) kNearest(query []float64, k int, skip func(idx int) bool) ([]int, []float64) {
+	if b.entry < 0 || len(query) != b.dim || k <= 0 {
+		return nil, nil
+	}
+	ep := hnswCand{b.entry, b.metric.Distance(query, b.coords(b.entry))}
+	for l := b.maxLevel; l > 0; l-- {
+		ep = b.greedy(query, ep, l)
+	}
+	found := b.searchLayer(query, ep, max(int(b.params.efSearch.Load()), k), 0)
+	if skip != nil {
+		found = slices.DeleteFunc(found, func(c hnswCand) bool { return skip(c.idx) })
+	}
+	found = found[:min(k, len(found))]
+	idxs := make([]int, len(found))
+	dists := make([]float64, len(found))
+	for i, c := range found {
+		idxs[i] = c.idx
+		dists[i] = c.dist
+	}
+	return idxs, dists
+}
+
+func (b *hnswBackend) nearest(query []float64) (int, float64, bool) {
+	idxs, dists := b.kNearest(query, 1, nil)
+	if len(idxs) == 0 {
+		return -1, 0, false
+	}
+	return idxs[0], dists[0], true
+}
+
+// hnswMinHeap is a min-heap of candidates by distance.
+type hnswMinHeap []hnswCand
+
+func (h *hnswMinHeap) push(c hnswCand) {
+	*h = append(*h, c)
+	s := *h
+	for i := len(s) - 1; i > 0; {
+		p := (i - 1) / 2
+		if s[p].dist <= s[i].dist {
+			break
+		}
+		s[i], s[p] = s[p], s[i]
+		i = p
+	}
+}
+
+func (h *hnswMinHeap) pop() hnswCand {
+	s := *h
+	top := s[0]
+	n := len(s) - 1
+	s[0] = s[n]
+	s = s[:n]
+	for i := 0; ; {
+		l, r, small := 2*i+1, 2*i+2, i
+		if l < n && s[l].dist < s[small].dist {
+			small = l
+		}
+		if r < n && s[r].dist < s[small].dist {
+			small = r
+		}
+		if small == i {
+			break
+		}
+		s[i], s[small] = s[small], s[i]
+		i = small
+	}
+	*h = s
+	return top
+}
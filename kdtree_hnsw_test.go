@@ -0,0 +1,109 @@
+package poindexter
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func hnswTestPoints(rng *rand.Rand, n, dim int) []KDPoint[int] {
+	pts := make([]KDPoint[int], n)
+	for i := range pts {
+		c := make([]float64, dim)
+		for j := range c {
+			c[j] = rng.Float64()
+		}
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: c, Value: i}
+	}
+	return pts
+}
+
+// hnswRecall returns the fraction of true k-nearest neighbours tr finds.
+func hnswRecall(tr, lin *KDTree[int], queries [][]float64, k int) float64 {
+	hit, total := 0, 0
+	for _, q := range queries {
+		want := map[string]bool{}
+		ps, _ := lin.KNearest(q, k)
+		for _, p := range ps {
+			want[p.ID] = true
+		}
+		got, _ := tr.KNearest(q, k)
+		for _, p := range got {
+			if want[p.ID] {
+				hit++
+			}
+		}
+		total += len(ps)
+	}
+	return float64(hit) / float64(total)
+}
+
+func TestHNSW_Recall(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	pts := hnswTestPoints(rng, 2000, 16)
+	tr, err := NewKDTree(pts, WithBackend(BackendHNSW), WithHNSWM(8), WithHNSWEfConstruction(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Backend() != BackendHNSW {
+		t.Fatalf("backend %s", tr.Backend())
+	}
+	lin, _ := NewKDTree(pts, WithBackend(BackendLinear))
+	queries := make([][]float64, 50)
+	for i := range queries {
+		queries[i] = hnswTestPoints(rng, 1, 16)[0].Coords
+	}
+
+	tr.SetHNSWEfSearch(1)
+	low := hnswRecall(tr, lin, queries, 10)
+	tr.SetHNSWEfSearch(200)
+	high := hnswRecall(tr, lin, queries, 10)
+	if high < 0.95 || high < low {
+		t.Fatalf("recall: ef=1 %.3f, ef=200 %.3f", low, high)
+	}
+
+	// an indexed point is its own nearest neighbour
+	for i := 0; i < 100; i++ {
+		p, d, ok := tr.Nearest(pts[i].Coords)
+		if !ok || d != 0 || p.Value != i {
+			t.Fatalf("nearest of point %d: %v %v %v", i, p.ID, d, ok)
+		}
+	}
+	// distances are exact and ascending even when neighbours are approximate
+	ps, ds := tr.KNearest(queries[0], 10)
+	for i := range ps {
+		if ds[i] != (EuclideanDistance{}).Distance(queries[0], ps[i].Coords) || (i > 0 && ds[i] < ds[i-1]) {
+			t.Fatalf("bad distances %v", ds)
+		}
+	}
+	// radius queries stay exact
+	rg, _ := tr.Radius(queries[0], 0.9)
+	rl, _ := lin.Radius(queries[0], 0.9)
+	if len(rg) != len(rl) {
+		t.Fatalf("radius: %d vs %d", len(rg), len(rl))
+	}
+}
+
+func TestHNSW_Mutations(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	tr, err := NewKDTreeFromDim[int](4, WithBackend(BackendHNSW), WithMetric(CosineDistance{}), WithHNSWEfSearch(64), WithRebuildPolicy(RebuildEveryN(100)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range hnswTestPoints(rng, 300, 4) {
+		tr.Insert(p)
+	}
+	tr.DeleteByID("0")
+	tr.Rebuild()
+	if ix := tr.queryIndex(); ix == nil || len(ix.points) != 299 {
+		t.Fatal("expected an HNSW index over the live points")
+	}
+	if p, _, _ := tr.Nearest([]float64{1, 1, 1, 1}); p.ID == "0" {
+		t.Fatal("deleted point returned")
+	}
+	c := tr.Clone(false)
+	c.SetHNSWEfSearch(1)
+	if tr.hnsw.efSearch.Load() != 64 || c.Backend() != BackendHNSW {
+		t.Fatal("clone must have independent HNSW settings")
+	}
+}
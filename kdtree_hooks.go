@@ -0,0 +1,41 @@
+package poindexter
+
+import "time"
+
+// Hooks are callbacks on tree activity, for streaming it to an application's
+// own logging or metrics instead of polling analytics snapshots. Any of them
+// may be nil. They run synchronously on the goroutine doing the work (for
+// OnRebuild, possibly a background rebuild started by RebuildAfter), so they
+// should be quick, safe for concurrent use, and must not call back into the
+// tree's mutating methods.
+type Hooks struct {
+	// OnQuery is called as each Nearest, KNearest and Radius call returns,
+	// including those on snapshots and copy-on-write views.
+	OnQuery func(QueryEvent)
+	// OnInsert is called with the ID of each point added by Insert or
+	// InsertWithTTL.
+	OnInsert func(id string)
+	// OnDelete is called with the ID of each point removed, whether by
+	// DeleteByID, DeleteWhere, eviction or TTL expiry.
+	OnDelete func(id string)
+	// OnRebuild is called after each full rebuild of the backend index, with
+	// the number of points indexed and the time the build took.
+	OnRebuild func(points int, took time.Duration)
+	// OnLatencyAnomaly is called when query latency becomes anomalous (see
+	// WithLatencyAnomalyDetection), from the query that tipped it. It fires
+	// once per episode, not again until latency has recovered.
+	OnLatencyAnomaly func(LatencyAnomaly)
+}
+
+// QueryEvent describes a completed query for Hooks.OnQuery.
+type QueryEvent struct {
+	QueryInfo
+	Results  int           // number of points returned
+	Duration time.Duration // time spent in the query
+}
+
+// WithHooks installs callbacks on queries, inserts, deletes and index
+// rebuilds (see Hooks).
+func WithHooks(h Hooks) KDOption {
+	return func(o *kdOptions) { o.hooks = h }
+}
@@ -0,0 +1,82 @@
+package poindexter
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithHooks(t *testing.T) {
+	var (
+		queries           []QueryEvent
+		inserted, deleted []string
+		rebuilds          []int
+	)
+	hooks := Hooks{
+		OnQuery:   func(e QueryEvent) { queries = append(queries, e) },
+		OnInsert:  func(id string) { inserted = append(inserted, id) },
+		OnDelete:  func(id string) { deleted = append(deleted, id) },
+		OnRebuild: func(points int, took time.Duration) { rebuilds = append(rebuilds, points) },
+	}
+	tree, err := NewKDTree([]KDPoint[int]{
+		{ID: "a", Coords: []float64{0, 0}},
+		{ID: "b", Coords: []float64{1, 1}},
+	}, WithHooks(hooks), WithBackend(BackendVPTree))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Insert(KDPoint[int]{ID: "c", Coords: []float64{2, 2}})
+	tree.Insert(KDPoint[int]{ID: "c", Coords: []float64{3, 3}}) // duplicate: rejected
+	tree.KNearest([]float64{0, 0}, 2)
+	tree.Radius([]float64{0, 0}, 1.5)
+	tree.Snapshot().Nearest([]float64{2, 2})
+	tree.DeleteByID("a")
+	tree.DeleteWhere(func(p KDPoint[int]) bool { return p.ID == "c" })
+	tree.Rebuild()
+
+	if !slices.Equal(inserted, []string{"c"}) {
+		t.Errorf("inserts %v", inserted)
+	}
+	if !slices.Equal(deleted, []string{"a", "c"}) {
+		t.Errorf("deletes %v", deleted)
+	}
+	if len(queries) != 3 {
+		t.Fatalf("expected 3 query events, got %+v", queries)
+	}
+	for i, want := range []struct {
+		op      string
+		results int
+	}{{"KNearest", 2}, {"Radius", 2}, {"Nearest", 1}} {
+		q := queries[i]
+		if q.Op != want.op || q.Results != want.results || q.Dim != 2 || q.Backend != BackendVPTree || q.Duration < 0 {
+			t.Errorf("query %d: %+v", i, q)
+		}
+	}
+	if len(rebuilds) == 0 || rebuilds[len(rebuilds)-1] != 1 {
+		t.Errorf("rebuilds %v: expected the last to index 1 point", rebuilds)
+	}
+}
+
+func TestWithHooks_EvictionAndTracer(t *testing.T) {
+	var deleted []string
+	tr := &recordingTracer{}
+	var queries int
+	tree, err := NewKDTreeFromDim[int](1,
+		WithMaxPoints(1, EvictOldest),
+		WithQueryTracer(tr),
+		WithHooks(Hooks{OnDelete: func(id string) { deleted = append(deleted, id) }, OnQuery: func(QueryEvent) { queries++ }}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Insert(KDPoint[int]{ID: "old", Coords: []float64{0}})
+	tree.Insert(KDPoint[int]{ID: "new", Coords: []float64{1}})
+	if !slices.Equal(deleted, []string{"old"}) {
+		t.Fatalf("expected the evicted point to be reported, got %v", deleted)
+	}
+	tree.Nearest([]float64{0})
+	if queries != 1 || len(tr.spans) != 1 || !tr.spans[0].ended {
+		t.Fatalf("hooks and tracer should both see the query: %d %+v", queries, tr.spans)
+	}
+}
@@ -0,0 +1,183 @@
+package poindexter
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+)
+
+// DefaultQueryHistorySize is the number of queries WithQueryHistory keeps when
+// a non-positive size is requested.
+const DefaultQueryHistorySize = 1024
+
+// axisImportancePopulation caps the number of points AxisImportance compares
+// each recorded query against; larger trees are sampled.
+const axisImportancePopulation = 1024
+
+// WithQueryHistory records the most recent size queries (Nearest, KNearest,
+// Radius, RadiusAppend) together with the coordinates of the points they
+// returned, for AxisImportance. Memory grows with size times the result count
+// times Dim. size <= 0 uses DefaultQueryHistorySize.
+func WithQueryHistory(size int) KDOption {
+	return func(o *kdOptions) {
+		if size <= 0 {
+			size = DefaultQueryHistorySize
+		}
+		o.queryHistory = size
+	}
+}
+
+// queryRecord is one recorded query and its results' coordinates.
+type queryRecord struct {
+	query    []float64
+	selected [][]float64
+}
+
+// queryHistory is a ring buffer of recent queries. Safe for concurrent use.
+type queryHistory struct {
+	mu   sync.Mutex
+	recs []queryRecord
+	next int // slot the next record goes to once recs is full
+	size int
+}
+
+// newQueryHistory returns the query history requested by cfg, if any.
+func newQueryHistory(cfg kdOptions) *queryHistory {
+	if cfg.queryHistory <= 0 {
+		return nil
+	}
+	return &queryHistory{size: cfg.queryHistory}
+}
+
+func (h *queryHistory) add(r queryRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.recs) < h.size {
+		h.recs = append(h.recs, r)
+		return
+	}
+	h.recs[h.next] = r
+	h.next = (h.next + 1) % h.size
+}
+
+// records returns the recorded queries, oldest first.
+func (h *queryHistory) records() []queryRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]queryRecord, 0, len(h.recs))
+	out = append(out, h.recs[h.next:]...)
+	return append(out, h.recs[:h.next]...)
+}
+
+// reset forgets every recorded query.
+func (h *queryHistory) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.recs, h.next = nil, 0
+}
+
+// clone returns an independent copy of the history. If empty is true only the
+// configuration (size) is kept.
+func (h *queryHistory) clone(empty bool) *queryHistory {
+	c := &queryHistory{size: h.size}
+	if !empty {
+		c.recs = h.records()
+	}
+	return c
+}
+
+// recordQuery adds query and the coordinates of its results to the tree's
+// query history, if enabled. Queries without results are not recorded.
+func (t *KDTree[T]) recordQuery(query []float64, n int, result func(i int) KDPoint[T]) {
+	if t.history == nil || n == 0 {
+		return
+	}
+	r := queryRecord{query: append([]float64(nil), query...), selected: make([][]float64, n)}
+	for i := range r.selected {
+		// the tree never modifies coordinates in place, so sharing is safe
+		r.selected[i] = result(i).Coords
+	}
+	t.history.add(r)
+}
+
+// AxisImportanceStats reports how strongly one axis separated the points
+// recorded queries returned from the rest of the tree.
+type AxisImportanceStats struct {
+	Axis int `json:"axis"`
+	// SelectedSpread is the mean absolute offset along the axis between a
+	// query and the points it returned.
+	SelectedSpread float64 `json:"selectedSpread"`
+	// PopulationSpread is the mean absolute offset along the axis between a
+	// query and the tree's points at large.
+	PopulationSpread float64 `json:"populationSpread"`
+	// Importance is 1 - SelectedSpread/PopulationSpread. Near 1, results sit
+	// much closer to the query on this axis than peers in general: the axis
+	// drives selection. Near 0 it barely matters, and below 0 results are
+	// more spread out on it than the population. 0 for constant axes.
+	Importance float64 `json:"importance"`
+}
+
+// AxisImportance analyses the queries recorded by WithQueryHistory to show
+// which axes actually discriminate between the peers queries select and
+// those they pass over, one entry per axis in axis order. An axis with high
+// Importance is doing the work; one near zero is dead weight whose
+// normalization weight could be dropped, and raising the weight of a useful
+// axis that scores low makes it count for more. Offsets along periodic axes
+// (WithPeriodicAxis) are measured the short way round.
+//
+// Queries are compared against the tree's current points (a random sample of
+// at most 1024 in large trees). Returns nil unless WithQueryHistory was set
+// and at least one query with results has been recorded since construction
+// or the last ResetAnalytics.
+func (t *KDTree[T]) AxisImportance() []AxisImportanceStats {
+	if v := t.cowView(); v != nil {
+		return v.AxisImportance()
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	if t.history == nil || len(t.points) == 0 {
+		return nil
+	}
+	recs := t.history.records()
+	if len(recs) == 0 {
+		return nil
+	}
+	pop := t.points
+	if len(pop) > axisImportancePopulation {
+		pop = make([]KDPoint[T], axisImportancePopulation)
+		for i, j := range rand.Perm(len(t.points))[:len(pop)] {
+			pop[i] = t.points[j]
+		}
+	}
+	offset := func(a, b []float64, i int) float64 { return a[i] - b[i] }
+	if pm, ok := t.metric.(PeriodicMetric); ok {
+		offset = pm.offset
+	}
+
+	out := make([]AxisImportanceStats, t.dim)
+	for axis := range out {
+		var sel, all float64
+		var nSel, nAll int
+		for _, r := range recs {
+			for _, c := range r.selected {
+				sel += math.Abs(offset(c, r.query, axis))
+			}
+			for _, p := range pop {
+				all += math.Abs(offset(p.Coords, r.query, axis))
+			}
+			nSel += len(r.selected)
+			nAll += len(pop)
+		}
+		s := AxisImportanceStats{
+			Axis:             axis,
+			SelectedSpread:   sel / float64(nSel),
+			PopulationSpread: all / float64(nAll),
+		}
+		if s.PopulationSpread > 0 {
+			s.Importance = 1 - s.SelectedSpread/s.PopulationSpread
+		}
+		out[axis] = s
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package poindexter
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// importanceTestTree returns a tree of random points in the unit cube whose
+// last axis is constant.
+func importanceTestTree(t *testing.T, opts ...KDOption) *KDTree[int] {
+	t.Helper()
+	rng := rand.New(rand.NewSource(44))
+	pts := make([]KDPoint[int], 2000)
+	for i := range pts {
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{rng.Float64(), rng.Float64(), rng.Float64(), 1}}
+	}
+	tr, err := NewKDTree(pts, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+// axisOnlyMetric is Euclidean distance over axes 0 and 2, with axis 2 scaled
+// up; it ignores axis 1.
+type axisOnlyMetric struct{}
+
+func (axisOnlyMetric) Distance(a, b []float64) float64 {
+	return EuclideanDistance{}.Distance([]float64{a[0], 2 * a[2]}, []float64{b[0], 2 * b[2]})
+}
+
+func TestAxisImportance(t *testing.T) {
+	tr := importanceTestTree(t, WithMetric(axisOnlyMetric{}), WithQueryHistory(100))
+	if tr.AxisImportance() != nil {
+		t.Fatal("want nil before any query")
+	}
+	rng := rand.New(rand.NewSource(45))
+	for q := 0; q < 150; q++ {
+		query := []float64{rng.Float64(), rng.Float64(), rng.Float64(), 1}
+		switch q % 3 {
+		case 0:
+			tr.KNearest(query, 10)
+		case 1:
+			tr.Radius(query, 0.05)
+		default:
+			tr.Nearest(query)
+		}
+	}
+	imp := tr.AxisImportance()
+	if len(imp) != 4 {
+		t.Fatalf("want 4 axes, got %d", len(imp))
+	}
+	for i, s := range imp {
+		if s.Axis != i {
+			t.Fatalf("axis %d reported as %d", i, s.Axis)
+		}
+	}
+	if imp[0].Importance < 0.7 || imp[2].Importance < 0.7 {
+		t.Fatalf("selecting axes should score high: %+v", imp)
+	}
+	if imp[2].Importance <= imp[0].Importance {
+		t.Fatalf("the weighted axis should score higher: %+v", imp)
+	}
+	if imp[1].Importance > 0.2 || imp[1].Importance < -0.2 {
+		t.Fatalf("ignored axis should score near zero: %+v", imp[1])
+	}
+	if imp[3].Importance != 0 || imp[3].PopulationSpread != 0 {
+		t.Fatalf("constant axis: %+v", imp[3])
+	}
+	if len(tr.history.records()) != 100 {
+		t.Fatalf("history holds %d queries, want the last 100", len(tr.history.records()))
+	}
+
+	c := tr.Clone(false)
+	if got := c.AxisImportance(); len(got) != 4 {
+		t.Fatal("clone should keep the history")
+	}
+	if tr.Clone(true).AxisImportance() != nil {
+		t.Fatal("reset clone should start empty")
+	}
+	tr.ResetAnalytics()
+	if tr.AxisImportance() != nil {
+		t.Fatal("ResetAnalytics should clear the history")
+	}
+	if importanceTestTree(t).AxisImportance() != nil {
+		t.Fatal("want nil without WithQueryHistory")
+	}
+}
+
+func TestAxisImportance_Periodic(t *testing.T) {
+	pts := []KDPoint[int]{
+		{ID: "a", Coords: []float64{359}},
+		{ID: "b", Coords: []float64{180}},
+	}
+	tr, _ := NewKDTree(pts, WithPeriodicAxis(0, 360), WithQueryHistory(0))
+	tr.Nearest([]float64{1})
+	imp := tr.AxisImportance()
+	if len(imp) != 1 || imp[0].SelectedSpread != 2 || imp[0].PopulationSpread != 90.5 {
+		t.Fatalf("periodic offsets: %+v", imp)
+	}
+}
@@ -0,0 +1,176 @@
+package poindexter
+
+import (
+	"math"
+	"time"
+)
+
+// KNearestInto is KNearest writing its results into ptsBuf and distBuf, which
+// it returns resliced to the number of neighbours found. Buffers with enough
+// capacity are reused from the start, so a peer-selection loop that passes
+// the previous call's slices back in allocates nothing per query on the
+// linear backend; indexed backends still allocate inside their search.
+// Results are only valid until the buffers are reused.
+func (t *KDTree[T]) KNearestInto(query []float64, k int, ptsBuf []KDPoint[T], distBuf []float64) ([]KDPoint[T], []float64) {
+	if v := t.cowView(); v != nil {
+		return v.KNearestInto(query, k, ptsBuf, distBuf)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	h := neighborHeap[T]{pts: ptsBuf[:0], dists: distBuf[:0]}
+	if k <= 0 || len(query) != t.dim || t.Len() == 0 {
+		return h.pts, h.dists
+	}
+	start := t.queryStart()
+	defer func() {
+		if t.analytics != nil {
+			t.analytics.RecordQuery(time.Since(start).Nanoseconds())
+		}
+	}()
+
+	if t.weightOf != nil || t.stableTies {
+		t.search(query, k, math.Inf(1), nil, &h)
+		t.recordResults(query, h.pts, h.dists)
+		return h.pts, h.dists
+	}
+	if ix := t.queryIndex(); ix != nil {
+		idxs, dists := indexKNearest[T](ix.data, query, k, nil)
+		if len(idxs) > 0 {
+			for _, idx := range idxs {
+				h.pts = append(h.pts, ix.points[idx])
+			}
+			h.dists = append(h.dists, dists...)
+			t.recordResults(query, h.pts, h.dists)
+			return h.pts, h.dists
+		}
+		// fall back on unexpected empty
+	}
+	scanDistances(t.metric, query, t.points, func(i int, d float64) {
+		h.offer(t.points[i], d, k)
+	})
+	h.sort()
+	t.recordResults(query, h.pts, h.dists)
+	return h.pts, h.dists
+}
+
+// RadiusInto is Radius writing its results into ptsBuf and distBuf, reused
+// from the start as in KNearestInto. Points within r are sorted by distance
+// in place, without allocating.
+func (t *KDTree[T]) RadiusInto(query []float64, r float64, ptsBuf []KDPoint[T], distBuf []float64) ([]KDPoint[T], []float64) {
+	if v := t.cowView(); v != nil {
+		return v.RadiusInto(query, r, ptsBuf, distBuf)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	h := neighborHeap[T]{pts: ptsBuf[:0], dists: distBuf[:0]}
+	if r < 0 || len(query) != t.dim || t.Len() == 0 {
+		return h.pts, h.dists
+	}
+	start := t.queryStart()
+	defer func() {
+		if t.analytics != nil {
+			t.analytics.RecordQuery(time.Since(start).Nanoseconds())
+			t.analytics.RecordRadiusResults(len(h.pts))
+		}
+	}()
+
+	if t.weightOf != nil || t.stableTies {
+		t.search(query, math.MaxInt, r, nil, &h)
+		t.recordResults(query, h.pts, h.dists)
+		return h.pts, h.dists
+	}
+	served := false
+	if ix := t.queryIndex(); ix != nil {
+		served = indexRadiusEach[T](ix.data, query, r, func(idx int, dist float64) {
+			h.offer(ix.points[idx], dist, math.MaxInt)
+		})
+	}
+	if !served {
+		scanDistances(t.metric, query, t.points, func(i int, d float64) {
+			if d <= r {
+				h.offer(t.points[i], d, math.MaxInt)
+			}
+		})
+	}
+	h.sort()
+	t.recordResults(query, h.pts, h.dists)
+	return h.pts, h.dists
+}
+
+// recordResults feeds a query's results to the peer, result-distance and
+// query-history analytics.
+func (t *KDTree[T]) recordResults(query []float64, pts []KDPoint[T], dists []float64) {
+	if t.peerAnalytics != nil {
+		for i := range pts {
+			t.peerAnalytics.RecordSelection(t.peerKey(pts[i]), dists[i])
+		}
+	}
+	t.recordResultDists(dists)
+	if len(dists) > 0 {
+		t.recordBest(dists[0])
+	}
+	t.recordQuery(query, len(pts), func(i int) KDPoint[T] { return pts[i] })
+}
+
+// neighborHeap is a max-heap by distance kept in parallel point and distance
+// slices, so results can be selected and sorted in caller-provided buffers.
+type neighborHeap[T any] struct {
+	pts   []KDPoint[T]
+	dists []float64
+}
+
+// offer adds p at distance d, keeping at most k entries: once full, p
+// replaces the farthest entry if it is closer.
+func (h *neighborHeap[T]) offer(p KDPoint[T], d float64, k int) {
+	if len(h.dists) < k {
+		h.pts = append(h.pts, p)
+		h.dists = append(h.dists, d)
+		for i := len(h.dists) - 1; i > 0; {
+			parent := (i - 1) / 2
+			if h.dists[i] <= h.dists[parent] {
+				break
+			}
+			h.swap(i, parent)
+			i = parent
+		}
+		return
+	}
+	if d >= h.dists[0] {
+		return
+	}
+	h.pts[0], h.dists[0] = p, d
+	h.down(0, len(h.dists))
+}
+
+// sort orders the entries by ascending distance (heapsort).
+func (h *neighborHeap[T]) sort() {
+	for n := len(h.dists) - 1; n > 0; n-- {
+		h.swap(0, n)
+		h.down(0, n)
+	}
+}
+
+func (h *neighborHeap[T]) swap(i, j int) {
+	h.pts[i], h.pts[j] = h.pts[j], h.pts[i]
+	h.dists[i], h.dists[j] = h.dists[j], h.dists[i]
+}
+
+// down restores the heap property below i among the first n entries.
+func (h *neighborHeap[T]) down(i, n int) {
+	for {
+		largest := i
+		if l := 2*i + 1; l < n && h.dists[l] > h.dists[largest] {
+			largest = l
+		}
+		if r := 2*i + 2; r < n && h.dists[r] > h.dists[largest] {
+			largest = r
+		}
+		if largest == i {
+			return
+		}
+		h.swap(i, largest)
+		i = largest
+	}
+}
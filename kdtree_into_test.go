@@ -0,0 +1,57 @@
+package poindexter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKNearestIntoMatchesKNearest(t *testing.T) {
+	pts := makePoints(800, 3)
+	for _, b := range []KDBackend{BackendLinear, BackendVPTree, BackendCoverTree} {
+		tr, err := NewKDTree(pts, WithBackend(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pbuf []KDPoint[int]
+		var dbuf []float64
+		for _, q := range [][]float64{{0.5, 0.5, 0.5}, {0, 0, 0}, {1, 0.2, 0.9}} {
+			for _, k := range []int{1, 7, 1000} {
+				want, wantD := tr.KNearest(q, k)
+				pbuf, dbuf = tr.KNearestInto(q, k, pbuf, dbuf)
+				if !reflect.DeepEqual(dbuf, wantD) || len(pbuf) != len(want) {
+					t.Fatalf("%s k=%d: dists %v, want %v", b, k, dbuf, wantD)
+				}
+				wantR, wantRD := tr.Radius(q, 0.3)
+				pbuf, dbuf = tr.RadiusInto(q, 0.3, pbuf, dbuf)
+				if len(dbuf) != len(wantRD) || len(pbuf) != len(wantR) {
+					t.Fatalf("%s: RadiusInto found %d, want %d", b, len(dbuf), len(wantRD))
+				}
+				for i := range dbuf {
+					if dbuf[i] != wantRD[i] {
+						t.Fatalf("%s: RadiusInto dist %d = %v, want %v", b, i, dbuf[i], wantRD[i])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestIntoReusesBuffersWithoutAllocating(t *testing.T) {
+	tr, _ := NewKDTree(makePoints(2000, 4), WithBackend(BackendLinear))
+	q := []float64{0.5, 0.5, 0.5, 0.5}
+	pbuf := make([]KDPoint[int], 0, 64)
+	dbuf := make([]float64, 0, 64)
+	allocs := testing.AllocsPerRun(100, func() {
+		p, d := tr.KNearestInto(q, 10, pbuf, dbuf)
+		if len(p) != 10 || &p[0] != &pbuf[:1][0] || &d[0] != &dbuf[:1][0] {
+			t.Fatal("KNearestInto did not fill the caller's buffers")
+		}
+		_, _ = tr.RadiusInto(q, 0.15, pbuf, dbuf)
+	})
+	if allocs != 0 {
+		t.Fatalf("KNearestInto+RadiusInto allocated %v times per run, want 0", allocs)
+	}
+	if p, d := tr.KNearestInto([]float64{1}, 3, pbuf, dbuf); len(p) != 0 || len(d) != 0 {
+		t.Fatalf("mismatched query returned %d results", len(p))
+	}
+}
@@ -0,0 +1,128 @@
+package poindexter
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// kdTreeJSONVersion is the current JSON snapshot format version.
+const kdTreeJSONVersion = 1
+
+// ErrUnsupportedVersion indicates a serialized tree uses a format version this
+// build cannot read.
+var ErrUnsupportedVersion = errors.New("kdtree: unsupported serialization format version")
+
+// kdPointJSON is the wire form of a KDPoint.
+type kdPointJSON[T any] struct {
+	ID     string            `json:"id"`
+	Coords []float64         `json:"coords"`
+	Value  T                 `json:"value"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// kdTreeJSON is the wire form of a KDTree. The layout is a superset of the
+// WASM exportJSON output (dim/len/backend/points with id/coords/value).
+type kdTreeJSON[T any] struct {
+	Version       int              `json:"version"`
+	Dim           int              `json:"dim"`
+	Len           int              `json:"len"`
+	Backend       KDBackend        `json:"backend"`
+	Metric        string           `json:"metric"`
+	MetricWeights []float64        `json:"metricWeights,omitempty"`
+	Periods       []float64        `json:"periods,omitempty"`
+	Points        []kdPointJSON[T] `json:"points"`
+}
+
+// MarshalJSON encodes the tree's points, dimension, metric and backend choice,
+// and the periods of any WithPeriodicAxis axes. Analytics are not included. Trees using a custom DistanceMetric cannot be
+// serialized and return ErrUnknownMetric. The Value payload must itself be
+// JSON-encodable.
+func (t *KDTree[T]) MarshalJSON() ([]byte, error) {
+	name, weights, periods, err := serialMetric(t.metric)
+	if err != nil {
+		return nil, err
+	}
+	w := kdTreeJSON[T]{
+		Version:       kdTreeJSONVersion,
+		Dim:           t.dim,
+		Len:           len(t.points),
+		Backend:       t.backend,
+		Metric:        name,
+		MetricWeights: weights,
+		Periods:       periods,
+		Points:        make([]kdPointJSON[T], len(t.points)),
+	}
+	for i, p := range t.points {
+		w.Points[i] = kdPointJSON[T]{ID: p.ID, Coords: p.Coords, Value: p.Value, Labels: p.Labels}
+	}
+	return json.Marshal(w)
+}
+
+// UnmarshalJSON replaces the tree with the one encoded in data, rebuilding the
+// backend. Analytics start fresh. If the encoded backend is unavailable in this
+// build, the tree falls back to the linear backend as NewKDTree does.
+func (t *KDTree[T]) UnmarshalJSON(data []byte) error {
+	var w kdTreeJSON[T]
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	if w.Version > kdTreeJSONVersion {
+		return ErrUnsupportedVersion
+	}
+	pts := make([]KDPoint[T], len(w.Points))
+	for i, p := range w.Points {
+		pts[i] = KDPoint[T]{ID: p.ID, Coords: p.Coords, Value: p.Value, Labels: p.Labels}
+	}
+	nt, err := newKDTreeFromSnapshot(w.Dim, pts, w.Metric, w.MetricWeights, w.Backend, periodicOptions(w.Periods)...)
+	if err != nil {
+		return err
+	}
+	t.adopt(nt)
+	return nil
+}
+
+// Save writes the tree as JSON to w. See MarshalJSON.
+func (t *KDTree[T]) Save(w io.Writer) error {
+	b, err := t.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// LoadKDTree reads a tree previously written by Save (or MarshalJSON) from r.
+func LoadKDTree[T any](r io.Reader) (*KDTree[T], error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	t := &KDTree[T]{}
+	if err := t.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// newKDTreeFromSnapshot rebuilds a tree from decoded snapshot fields. An empty
+// point set yields an empty tree of the given dimension. extra options are
+// applied after the metric and backend.
+func newKDTreeFromSnapshot[T any](dim int, pts []KDPoint[T], metric string, weights []float64, backend KDBackend, extra ...KDOption) (*KDTree[T], error) {
+	m, err := metricByName(metric, weights)
+	if err != nil {
+		return nil, err
+	}
+	opts := []KDOption{WithMetric(m)}
+	if backend != "" {
+		opts = append(opts, WithBackend(backend))
+	}
+	opts = append(opts, extra...)
+	if len(pts) == 0 {
+		return NewKDTreeFromDim[T](dim, opts...)
+	}
+	if len(pts[0].Coords) != dim {
+		return nil, ErrDimMismatch
+	}
+	return NewKDTree(pts, opts...)
+}
@@ -0,0 +1,87 @@
+package poindexter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+type jsonPayload struct {
+	Name string `json:"name"`
+	RTT  int    `json:"rtt"`
+}
+
+func TestKDTreeJSON_RoundTrip(t *testing.T) {
+	pts := []KDPoint[jsonPayload]{
+		{ID: "a", Coords: []float64{0, 0}, Value: jsonPayload{"alpha", 10}},
+		{ID: "b", Coords: []float64{1, 1}, Value: jsonPayload{"beta", 20}},
+		{ID: "", Coords: []float64{2, 0}, Value: jsonPayload{"anon", 30}},
+	}
+	tr, _ := NewKDTree(pts, WithMetric(ManhattanDistance{}), WithBackend(BackendLinear))
+	b, err := json.Marshal(tr)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var got KDTree[jsonPayload]
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Len() != 3 || got.Dim() != 2 || got.Backend() != BackendLinear {
+		t.Fatalf("unexpected tree: len=%d dim=%d backend=%s", got.Len(), got.Dim(), got.Backend())
+	}
+	if _, ok := got.metric.(ManhattanDistance); !ok {
+		t.Fatalf("metric not restored: %T", got.metric)
+	}
+	p, d, ok := got.Nearest([]float64{1, 0.9})
+	if !ok || p.ID != "b" || p.Value.Name != "beta" {
+		t.Fatalf("unexpected nearest %+v", p)
+	}
+	if math.Abs(d-0.1) > 1e-9 {
+		t.Fatalf("unexpected distance %v", d)
+	}
+	if !got.DeleteByID("a") {
+		t.Fatalf("id index not rebuilt")
+	}
+}
+
+func TestKDTreeJSON_WeightedCosineAndEmpty(t *testing.T) {
+	tr, _ := NewKDTreeFromDim[string](3, WithMetric(WeightedCosineDistance{Weights: []float64{1, 2, 3}}))
+	var buf bytes.Buffer
+	if err := tr.Save(&buf); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, err := LoadKDTree[string](&buf)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Dim() != 3 || got.Len() != 0 {
+		t.Fatalf("unexpected dim/len %d/%d", got.Dim(), got.Len())
+	}
+	wc, ok := got.metric.(WeightedCosineDistance)
+	if !ok || len(wc.Weights) != 3 || wc.Weights[2] != 3 {
+		t.Fatalf("weighted cosine not restored: %#v", got.metric)
+	}
+}
+
+type customMetric struct{}
+
+func (customMetric) Distance(a, b []float64) float64 { return 0 }
+
+func TestKDTreeJSON_Errors(t *testing.T) {
+	tr, _ := NewKDTreeFromDim[int](1, WithMetric(customMetric{}))
+	if _, err := json.Marshal(tr); !errors.Is(err, ErrUnknownMetric) {
+		t.Fatalf("want ErrUnknownMetric, got %v", err)
+	}
+	var got KDTree[int]
+	if err := got.UnmarshalJSON([]byte(`{"version":99,"dim":1}`)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("want ErrUnsupportedVersion, got %v", err)
+	}
+	if err := got.UnmarshalJSON([]byte(`{"version":1,"dim":1,"metric":"hamming"}`)); !errors.Is(err, ErrUnknownMetric) {
+		t.Fatalf("want ErrUnknownMetric, got %v", err)
+	}
+	if err := got.UnmarshalJSON([]byte(`{"version":1,"dim":2,"points":[{"id":"x","coords":[1]}]}`)); !errors.Is(err, ErrDimMismatch) {
+		t.Fatalf("want ErrDimMismatch, got %v", err)
+	}
+}
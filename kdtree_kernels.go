@@ -0,0 +1,94 @@
+package poindexter
+
+import "math"
+
+// Distance kernels. EuclideanDistance and CosineDistance spend nearly all of
+// their time summing over coordinates, so the sums are factored out into
+// kernels with an SSE2 implementation on amd64 (kdtree_kernels_amd64.s) and
+// the unrolled Go versions below elsewhere, or when built with the purego
+// tag. Both metrics call the kernels directly, so every backend sees the same
+// distances bit for bit.
+
+// scanBlock is the number of distances a linear scan computes per batch.
+const scanBlock = 256
+
+// sqEuclideanGeneric returns the squared Euclidean distance between a and b,
+// summing into four independent accumulators so the additions pipeline.
+func sqEuclideanGeneric(a, b []float64) float64 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3 float64
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		d0 := a[i] - b[i]
+		d1 := a[i+1] - b[i+1]
+		d2 := a[i+2] - b[i+2]
+		d3 := a[i+3] - b[i+3]
+		s0 += d0 * d0
+		s1 += d1 * d1
+		s2 += d2 * d2
+		s3 += d3 * d3
+	}
+	for ; i < len(a); i++ {
+		d := a[i] - b[i]
+		s0 += d * d
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+// cosineSumsGeneric returns a·b, a·a and b·b in one pass.
+func cosineSumsGeneric(a, b []float64) (dot, na2, nb2 float64) {
+	b = b[:len(a)]
+	var d1, a1, b1 float64
+	i := 0
+	for ; i+2 <= len(a); i += 2 {
+		x0, y0 := a[i], b[i]
+		x1, y1 := a[i+1], b[i+1]
+		dot += x0 * y0
+		na2 += x0 * x0
+		nb2 += y0 * y0
+		d1 += x1 * y1
+		a1 += x1 * x1
+		b1 += y1 * y1
+	}
+	if i < len(a) {
+		x, y := a[i], b[i]
+		dot += x * y
+		na2 += x * x
+		nb2 += y * y
+	}
+	return dot + d1, na2 + a1, nb2 + b1
+}
+
+// batchDistances sets out[i] to metric's distance from query to pts[i],
+// resolving the metric once rather than per point. len(out) must be at least
+// len(pts).
+func batchDistances[T any](metric DistanceMetric, query []float64, pts []KDPoint[T], out []float64) {
+	out = out[:len(pts)]
+	switch metric.(type) {
+	case EuclideanDistance:
+		for i := range pts {
+			out[i] = math.Sqrt(sqEuclidean(query, pts[i].Coords))
+		}
+	case CosineDistance:
+		for i := range pts {
+			out[i] = cosineFromSums(cosineSums(query, pts[i].Coords))
+		}
+	default:
+		for i := range pts {
+			out[i] = metric.Distance(query, pts[i].Coords)
+		}
+	}
+}
+
+// scanDistances calls visit with the distance from query to every point in
+// pts, in order, computing them scanBlock at a time with batchDistances.
+func scanDistances[T any](metric DistanceMetric, query []float64, pts []KDPoint[T], visit func(i int, d float64)) {
+	var buf [scanBlock]float64
+	for lo := 0; lo < len(pts); lo += scanBlock {
+		block := pts[lo:min(lo+scanBlock, len(pts))]
+		batchDistances(metric, query, block, buf[:])
+		for j, d := range buf[:len(block)] {
+			visit(lo+j, d)
+		}
+	}
+}
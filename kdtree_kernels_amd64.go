@@ -0,0 +1,31 @@
+//go:build amd64 && !purego
+
+package poindexter
+
+// kernelMinDim is the shortest vector worth the assembly call; below it the
+// unrolled Go loops, which inline, are faster.
+const kernelMinDim = 8
+
+// sqEuclidean returns the squared Euclidean distance between a and b.
+func sqEuclidean(a, b []float64) float64 {
+	if len(a) < kernelMinDim {
+		return sqEuclideanGeneric(a, b)
+	}
+	return sqEuclideanSSE2(a, b[:len(a)])
+}
+
+// cosineSums returns a·b, a·a and b·b in one pass.
+func cosineSums(a, b []float64) (dot, na2, nb2 float64) {
+	if len(a) < kernelMinDim {
+		return cosineSumsGeneric(a, b)
+	}
+	return cosineSumsSSE2(a, b[:len(a)])
+}
+
+// Implemented in kdtree_kernels_amd64.s; len(b) must equal len(a).
+
+//go:noescape
+func sqEuclideanSSE2(a, b []float64) float64
+
+//go:noescape
+func cosineSumsSSE2(a, b []float64) (dot, na2, nb2 float64)
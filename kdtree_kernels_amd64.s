@@ -0,0 +1,166 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func sqEuclideanSSE2(a, b []float64) float64
+// Sums (a[i]-b[i])² eight at a time into four packed accumulators.
+TEXT ·sqEuclideanSSE2(SB), NOSPLIT, $0-56
+	MOVQ a_base+0(FP), SI
+	MOVQ a_len+8(FP), CX
+	MOVQ b_base+24(FP), DI
+	XORPD X0, X0
+	XORPD X1, X1
+	XORPD X2, X2
+	XORPD X3, X3
+	XORQ AX, AX
+	MOVQ CX, DX
+	ANDQ $-8, DX
+	JZ   pairs
+
+loop8:
+	MOVUPD (SI)(AX*8), X4
+	MOVUPD 16(SI)(AX*8), X5
+	MOVUPD 32(SI)(AX*8), X6
+	MOVUPD 48(SI)(AX*8), X7
+	MOVUPD (DI)(AX*8), X8
+	MOVUPD 16(DI)(AX*8), X9
+	MOVUPD 32(DI)(AX*8), X10
+	MOVUPD 48(DI)(AX*8), X11
+	SUBPD  X8, X4
+	SUBPD  X9, X5
+	SUBPD  X10, X6
+	SUBPD  X11, X7
+	MULPD  X4, X4
+	MULPD  X5, X5
+	MULPD  X6, X6
+	MULPD  X7, X7
+	ADDPD  X4, X0
+	ADDPD  X5, X1
+	ADDPD  X6, X2
+	ADDPD  X7, X3
+	ADDQ   $8, AX
+	CMPQ   AX, DX
+	JLT    loop8
+
+pairs:
+	MOVQ CX, DX
+	ANDQ $-2, DX
+	CMPQ AX, DX
+	JGE  reduce
+
+loop2:
+	MOVUPD (SI)(AX*8), X4
+	MOVUPD (DI)(AX*8), X8
+	SUBPD  X8, X4
+	MULPD  X4, X4
+	ADDPD  X4, X0
+	ADDQ   $2, AX
+	CMPQ   AX, DX
+	JLT    loop2
+
+reduce:
+	ADDPD    X1, X0
+	ADDPD    X3, X2
+	ADDPD    X2, X0
+	MOVAPD   X0, X1
+	UNPCKHPD X1, X1
+	ADDSD    X1, X0
+	CMPQ     AX, CX
+	JGE      done
+	MOVSD    (SI)(AX*8), X4
+	SUBSD    (DI)(AX*8), X4
+	MULSD    X4, X4
+	ADDSD    X4, X0
+
+done:
+	MOVSD X0, ret+48(FP)
+	RET
+
+// func cosineSumsSSE2(a, b []float64) (dot, na2, nb2 float64)
+// Sums a[i]*b[i], a[i]² and b[i]² four at a time, two packed accumulators
+// per sum.
+TEXT ·cosineSumsSSE2(SB), NOSPLIT, $0-72
+	MOVQ a_base+0(FP), SI
+	MOVQ a_len+8(FP), CX
+	MOVQ b_base+24(FP), DI
+	XORPD X0, X0
+	XORPD X1, X1
+	XORPD X2, X2
+	XORPD X3, X3
+	XORPD X4, X4
+	XORPD X5, X5
+	XORQ AX, AX
+	MOVQ CX, DX
+	ANDQ $-4, DX
+	JZ   pairs
+
+loop4:
+	MOVUPD (SI)(AX*8), X6
+	MOVUPD 16(SI)(AX*8), X7
+	MOVUPD (DI)(AX*8), X8
+	MOVUPD 16(DI)(AX*8), X9
+	MOVAPD X6, X10
+	MOVAPD X7, X11
+	MULPD  X8, X10
+	MULPD  X9, X11
+	ADDPD  X10, X0
+	ADDPD  X11, X1
+	MULPD  X6, X6
+	MULPD  X7, X7
+	ADDPD  X6, X2
+	ADDPD  X7, X3
+	MULPD  X8, X8
+	MULPD  X9, X9
+	ADDPD  X8, X4
+	ADDPD  X9, X5
+	ADDQ   $4, AX
+	CMPQ   AX, DX
+	JLT    loop4
+
+pairs:
+	MOVQ CX, DX
+	ANDQ $-2, DX
+	CMPQ AX, DX
+	JGE  reduce
+
+	MOVUPD (SI)(AX*8), X6
+	MOVUPD (DI)(AX*8), X8
+	MOVAPD X6, X10
+	MULPD  X8, X10
+	ADDPD  X10, X0
+	MULPD  X6, X6
+	ADDPD  X6, X2
+	MULPD  X8, X8
+	ADDPD  X8, X4
+	ADDQ   $2, AX
+
+reduce:
+	ADDPD    X1, X0
+	ADDPD    X3, X2
+	ADDPD    X5, X4
+	MOVAPD   X0, X1
+	UNPCKHPD X1, X1
+	ADDSD    X1, X0
+	MOVAPD   X2, X3
+	UNPCKHPD X3, X3
+	ADDSD    X3, X2
+	MOVAPD   X4, X5
+	UNPCKHPD X5, X5
+	ADDSD    X5, X4
+	CMPQ     AX, CX
+	JGE      done
+	MOVSD    (SI)(AX*8), X6
+	MOVSD    (DI)(AX*8), X8
+	MOVSD    X6, X10
+	MULSD    X8, X10
+	ADDSD    X10, X0
+	MULSD    X6, X6
+	ADDSD    X6, X2
+	MULSD    X8, X8
+	ADDSD    X8, X4
+
+done:
+	MOVSD X0, dot+48(FP)
+	MOVSD X2, na2+56(FP)
+	MOVSD X4, nb2+64(FP)
+	RET
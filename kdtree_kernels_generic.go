@@ -0,0 +1,9 @@
+//go:build !amd64 || purego
+
+package poindexter
+
+// sqEuclidean returns the squared Euclidean distance between a and b.
+func sqEuclidean(a, b []float64) float64 { return sqEuclideanGeneric(a, b) }
+
+// cosineSums returns a·b, a·a and b·b in one pass.
+func cosineSums(a, b []float64) (dot, na2, nb2 float64) { return cosineSumsGeneric(a, b) }
@@ -0,0 +1,88 @@
+package poindexter
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDistanceKernelsMatchGeneric(t *testing.T) {
+	rng := rand.New(rand.NewSource(48))
+	for dim := 0; dim <= 37; dim++ {
+		a := make([]float64, dim)
+		b := make([]float64, dim+3) // longer b: kernels only read len(a)
+		for i := range a {
+			a[i] = rng.NormFloat64()
+		}
+		for i := range b {
+			b[i] = rng.NormFloat64()
+		}
+		var want, wd, wa, wb float64
+		for i := range a {
+			d := a[i] - b[i]
+			want += d * d
+			wd += a[i] * b[i]
+			wa += a[i] * a[i]
+			wb += b[i] * b[i]
+		}
+		close := func(got, want float64) bool { return math.Abs(got-want) <= 1e-12*(1+math.Abs(want)) }
+		if got := sqEuclidean(a, b); !close(got, want) {
+			t.Fatalf("dim %d: sqEuclidean = %v, want %v", dim, got, want)
+		}
+		if got := sqEuclideanGeneric(a, b); !close(got, want) {
+			t.Fatalf("dim %d: sqEuclideanGeneric = %v, want %v", dim, got, want)
+		}
+		for _, f := range []func(a, b []float64) (float64, float64, float64){cosineSums, cosineSumsGeneric} {
+			d, na, nb := f(a, b)
+			if !close(d, wd) || !close(na, wa) || !close(nb, wb) {
+				t.Fatalf("dim %d: cosine sums = %v %v %v, want %v %v %v", dim, d, na, nb, wd, wa, wb)
+			}
+		}
+	}
+}
+
+func TestBatchDistancesMatchMetric(t *testing.T) {
+	pts := makePoints(600, 9)
+	q := pts[17].Coords
+	out := make([]float64, len(pts))
+	for _, m := range []DistanceMetric{EuclideanDistance{}, CosineDistance{}, ManhattanDistance{}} {
+		batchDistances(m, q, pts, out)
+		for i, p := range pts {
+			if want := m.Distance(q, p.Coords); out[i] != want {
+				t.Fatalf("%T: point %d: %v, want %v", m, i, out[i], want)
+			}
+		}
+		n := 0
+		scanDistances(m, q, pts, func(i int, d float64) {
+			if i != n || d != out[i] {
+				t.Fatalf("%T: visit(%d, %v), want (%d, %v)", m, i, d, n, out[n])
+			}
+			n++
+		})
+		if n != len(pts) {
+			t.Fatalf("%T: visited %d points, want %d", m, n, len(pts))
+		}
+	}
+}
+
+func TestLinearKNearestHeapOrder(t *testing.T) {
+	pts := makePoints(1000, 5)
+	tr, err := NewKDTree(pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := []float64{0.5, 0.5, 0.5, 0.5, 0.5}
+	got, dists := tr.KNearest(q, 25)
+	if len(got) != 25 {
+		t.Fatalf("got %d neighbours, want 25", len(got))
+	}
+	all, _ := tr.Radius(q, math.Inf(1))
+	for i := range got {
+		if want := (EuclideanDistance{}).Distance(q, all[i].Coords); dists[i] != want {
+			t.Fatalf("rank %d: distance %v, want %v", i, dists[i], want)
+		}
+	}
+	if got, _ := tr.KNearest(q, 5000); len(got) != 1000 {
+		t.Fatalf("k > Len: got %d neighbours, want 1000", len(got))
+	}
+}
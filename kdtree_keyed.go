@@ -0,0 +1,226 @@
+package poindexter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// KeyedPoint is a KDPoint whose ID is any comparable type, e.g. a uint64, a
+// [32]byte node ID or a netip.AddrPort.
+type KeyedPoint[K comparable, T any] struct {
+	ID     K
+	Coords []float64
+	Value  T
+}
+
+// KeyedTree is a sibling of KDTree for points identified by a comparable key
+// instead of a string, so DHT node IDs and addresses are stored and looked up
+// as they are, without a string conversion (and allocation) per Insert or
+// DeleteByID. Every point has a key, the zero value included, and keys are
+// unique.
+//
+// Like KDTree32, KeyedTree answers every query with a linear scan (using the
+// same distance kernels as KDTree), so it suits trees of up to a few hundred
+// thousand points. It honours WithMetric, WithPeriodicAxis and
+// WithCoordValidator; backend, analytics and the other KDTree options are
+// ignored. It is safe for concurrent queries but mutations must not run
+// concurrently with anything else.
+type KeyedTree[K comparable, T any] struct {
+	dim       int
+	metric    DistanceMetric
+	validator func(coords []float64) error
+	points    []KDPoint[T] // IDs unused; coordinates live in coords
+	keys      []K          // keys[i] identifies points[i]
+	index     map[K]int
+	coords    coordArena
+}
+
+// NewKeyedTree builds a KeyedTree from pts. Errors match NewKDTree's, with
+// ErrDuplicateID for a repeated key.
+func NewKeyedTree[K comparable, T any](pts []KeyedPoint[K, T], opts ...KDOption) (*KeyedTree[K, T], error) {
+	if len(pts) == 0 {
+		return nil, ErrEmptyPoints
+	}
+	t, err := NewKeyedTreeFromDim[K, T](len(pts[0].Coords), opts...)
+	if err != nil {
+		return nil, err
+	}
+	t.points = make([]KDPoint[T], 0, len(pts))
+	t.keys = make([]K, 0, len(pts))
+	for _, p := range pts {
+		if len(p.Coords) != t.dim {
+			return nil, ErrDimMismatch
+		}
+		if _, exists := t.index[p.ID]; exists {
+			return nil, ErrDuplicateID
+		}
+		if t.validator != nil {
+			if err := t.validator(p.Coords); err != nil {
+				return nil, fmt.Errorf("%w: point %v: %w", ErrInvalidCoords, p.ID, err)
+			}
+		}
+		t.index[p.ID] = len(t.points)
+		t.points = append(t.points, KDPoint[T]{Coords: p.Coords, Value: p.Value})
+		t.keys = append(t.keys, p.ID)
+	}
+	t.coords = packCoords(t.points, t.dim)
+	return t, nil
+}
+
+// NewKeyedTreeFromDim constructs an empty KeyedTree with the specified
+// dimension. Call Insert to add points after construction.
+func NewKeyedTreeFromDim[K comparable, T any](dim int, opts ...KDOption) (*KeyedTree[K, T], error) {
+	if dim <= 0 {
+		return nil, ErrZeroDim
+	}
+	cfg := kdOptions{metric: EuclideanDistance{}}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	metric, err := periodicMetric(cfg, dim)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyedTree[K, T]{
+		dim:       dim,
+		metric:    metric,
+		validator: cfg.coordValidator,
+		index:     make(map[K]int),
+	}, nil
+}
+
+// Dim returns the number of dimensions.
+func (t *KeyedTree[K, T]) Dim() int { return t.dim }
+
+// Len returns the number of points in the tree.
+func (t *KeyedTree[K, T]) Len() int { return len(t.points) }
+
+// point returns point i. Its coordinates are shared with the tree and must
+// not be modified.
+func (t *KeyedTree[K, T]) point(i int) KeyedPoint[K, T] {
+	return KeyedPoint[K, T]{ID: t.keys[i], Coords: t.points[i].Coords, Value: t.points[i].Value}
+}
+
+// Points returns all points. Their coordinates are shared with the tree and
+// must not be modified.
+func (t *KeyedTree[K, T]) Points() []KeyedPoint[K, T] {
+	out := make([]KeyedPoint[K, T], len(t.points))
+	for i := range out {
+		out[i] = t.point(i)
+	}
+	return out
+}
+
+// Get returns the point with the given key.
+func (t *KeyedTree[K, T]) Get(id K) (KeyedPoint[K, T], bool) {
+	i, ok := t.index[id]
+	if !ok {
+		return KeyedPoint[K, T]{}, false
+	}
+	return t.point(i), true
+}
+
+// Insert adds a point. It returns false if the point fails the coordinate
+// validator, its dimensionality does not match, or its key already exists.
+// Coordinates are copied into storage the tree owns.
+func (t *KeyedTree[K, T]) Insert(p KeyedPoint[K, T]) bool {
+	if len(p.Coords) != t.dim {
+		return false
+	}
+	if t.validator != nil && t.validator(p.Coords) != nil {
+		return false
+	}
+	if _, exists := t.index[p.ID]; exists {
+		return false
+	}
+	t.index[p.ID] = len(t.points)
+	t.points = append(t.points, KDPoint[T]{Coords: t.coords.store(p.Coords, len(t.points)), Value: p.Value})
+	t.keys = append(t.keys, p.ID)
+	return true
+}
+
+// DeleteByID removes the point with the given key by swapping the last point
+// into its place. It returns false if the key is not found.
+func (t *KeyedTree[K, T]) DeleteByID(id K) bool {
+	idx, ok := t.index[id]
+	if !ok {
+		return false
+	}
+	last := len(t.points) - 1
+	t.points[idx] = t.points[last]
+	t.keys[idx] = t.keys[last]
+	t.index[t.keys[idx]] = idx
+	t.points[last] = KDPoint[T]{}
+	var zero K
+	t.keys[last] = zero
+	t.points = t.points[:last]
+	t.keys = t.keys[:last]
+	delete(t.index, id)
+	t.coords.garbage++
+	if t.coords.garbage > len(t.points) {
+		t.coords = packCoords(t.points, t.dim)
+	}
+	return true
+}
+
+// Nearest returns the closest point to the query, along with its distance.
+// ok is false if the tree is empty or the query dimensionality does not match
+// Dim().
+func (t *KeyedTree[K, T]) Nearest(query []float64) (KeyedPoint[K, T], float64, bool) {
+	if len(query) != t.dim || len(t.points) == 0 {
+		return KeyedPoint[K, T]{}, 0, false
+	}
+	best, bestDist := -1, math.Inf(1)
+	scanDistances(t.metric, query, t.points, func(i int, d float64) {
+		if best < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	})
+	return t.point(best), bestDist, true
+}
+
+// KNearest returns up to k nearest points to the query in ascending distance
+// order.
+func (t *KeyedTree[K, T]) KNearest(query []float64, k int) ([]KeyedPoint[K, T], []float64) {
+	if k <= 0 || len(query) != t.dim || len(t.points) == 0 {
+		return nil, nil
+	}
+	var h knnHeap
+	scanDistances(t.metric, query, t.points, func(i int, d float64) {
+		if h.Len() < k {
+			h.push(knnItem{idx: i, dist: d})
+		} else if d < h.peek().dist {
+			h[0] = knnItem{idx: i, dist: d}
+			h.down(0)
+		}
+	})
+	return t.results(h)
+}
+
+// Radius returns points within radius r (inclusive) from the query, sorted by
+// distance.
+func (t *KeyedTree[K, T]) Radius(query []float64, r float64) ([]KeyedPoint[K, T], []float64) {
+	if r < 0 || len(query) != t.dim || len(t.points) == 0 {
+		return nil, nil
+	}
+	var sel []knnItem
+	scanDistances(t.metric, query, t.points, func(i int, d float64) {
+		if d <= r {
+			sel = append(sel, knnItem{idx: i, dist: d})
+		}
+	})
+	return t.results(sel)
+}
+
+// results returns the selected points, sorted by distance.
+func (t *KeyedTree[K, T]) results(sel []knnItem) ([]KeyedPoint[K, T], []float64) {
+	sort.Slice(sel, func(i, j int) bool { return sel[i].dist < sel[j].dist })
+	pts := make([]KeyedPoint[K, T], len(sel))
+	dists := make([]float64, len(sel))
+	for i, it := range sel {
+		pts[i] = t.point(it.idx)
+		dists[i] = it.dist
+	}
+	return pts, dists
+}
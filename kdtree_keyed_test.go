@@ -0,0 +1,91 @@
+package poindexter
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"testing"
+)
+
+func TestKeyedTreeMatchesKDTree(t *testing.T) {
+	src := makePoints(400, 3)
+	pts := make([]KeyedPoint[uint64, int], len(src))
+	for i, p := range src {
+		pts[i] = KeyedPoint[uint64, int]{ID: uint64(i), Coords: p.Coords, Value: p.Value}
+	}
+	kt, err := NewKeyedTree(pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(0); i < 400; i += 5 {
+		kt.DeleteByID(i)
+	}
+	kt.Insert(KeyedPoint[uint64, int]{ID: 1 << 40, Coords: []float64{0.5, 0.5, 0.5}, Value: -1})
+	var ref []KDPoint[int]
+	for _, p := range kt.Points() {
+		ref = append(ref, KDPoint[int]{ID: fmt.Sprint(p.ID), Coords: p.Coords, Value: p.Value})
+	}
+	rt, _ := NewKDTree(ref, WithBackend(BackendLinear))
+	if kt.Len() != rt.Len() || kt.Len() != 321 {
+		t.Fatalf("Len = %d, want 321", kt.Len())
+	}
+	for _, q := range [][]float64{{0.5, 0.5, 0.5}, {0, 1, 0}, {0.3, 0.9, 0.1}} {
+		p, d, _ := kt.Nearest(q)
+		rp, rd, _ := rt.Nearest(q)
+		if fmt.Sprint(p.ID) != rp.ID || d != rd {
+			t.Fatalf("Nearest = %d at %v, want %s at %v", p.ID, d, rp.ID, rd)
+		}
+		_, kd := kt.KNearest(q, 9)
+		_, rkd := rt.KNearest(q, 9)
+		if fmt.Sprint(kd) != fmt.Sprint(rkd) {
+			t.Fatalf("KNearest: %v, want %v", kd, rkd)
+		}
+		kp, _ := kt.Radius(q, 0.25)
+		rp2, _ := rt.Radius(q, 0.25)
+		if len(kp) != len(rp2) {
+			t.Fatalf("Radius found %d, want %d", len(kp), len(rp2))
+		}
+	}
+}
+
+func TestKeyedTreeAddrPortKeys(t *testing.T) {
+	a := netip.MustParseAddrPort("10.0.0.1:4000")
+	b := netip.MustParseAddrPort("[2001:db8::1]:4000")
+	kt, err := NewKeyedTreeFromDim[netip.AddrPort, string](2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !kt.Insert(KeyedPoint[netip.AddrPort, string]{ID: a, Coords: []float64{0, 0}, Value: "a"}) ||
+		!kt.Insert(KeyedPoint[netip.AddrPort, string]{ID: b, Coords: []float64{1, 1}, Value: "b"}) {
+		t.Fatal("Insert failed")
+	}
+	if kt.Insert(KeyedPoint[netip.AddrPort, string]{ID: a, Coords: []float64{2, 2}}) {
+		t.Fatal("duplicate key inserted")
+	}
+	if p, ok := kt.Get(b); !ok || p.Value != "b" {
+		t.Fatalf("Get(b) = %v, %v", p, ok)
+	}
+	if !kt.DeleteByID(a) || kt.DeleteByID(a) || kt.Len() != 1 {
+		t.Fatal("DeleteByID did not remove a exactly once")
+	}
+	if p, _, _ := kt.Nearest([]float64{0, 0}); p.ID != b {
+		t.Fatalf("Nearest = %v, want %v", p.ID, b)
+	}
+	for i := range 200 {
+		kt.Insert(KeyedPoint[netip.AddrPort, string]{ID: netip.AddrPortFrom(a.Addr(), uint16(i)), Coords: []float64{2, 2}})
+	}
+	// coordinate storage is allocated in chunks, so churn averages out to
+	// less than one allocation per insert
+	origin := []float64{0, 0}
+	allocs := testing.AllocsPerRun(100, func() {
+		kt.Insert(KeyedPoint[netip.AddrPort, string]{ID: a, Coords: origin})
+		kt.DeleteByID(a)
+	})
+	if allocs > 0 {
+		t.Fatalf("Insert+DeleteByID allocated %v times per run", allocs)
+	}
+	dup := []KeyedPoint[[32]byte, int]{{Coords: []float64{0}}, {Coords: []float64{1}}}
+	if _, err := NewKeyedTree(dup); !errors.Is(err, ErrDuplicateID) {
+		t.Fatalf("repeated zero key: err = %v, want ErrDuplicateID", err)
+	}
+}
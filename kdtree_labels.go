@@ -0,0 +1,122 @@
+package poindexter
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// QueryOption configures a single Nearest, KNearest or Radius call.
+type QueryOption func(*queryOptions)
+
+type queryOptions struct {
+	selector []labelTerm
+	ctx      context.Context // for the tree's QueryTracer (WithQueryContext)
+}
+
+// labelTerm is one parsed WithLabelSelector term.
+type labelTerm struct {
+	key, value string
+	op         labelOp
+}
+
+type labelOp uint8
+
+const (
+	labelEq     labelOp = iota // key=value
+	labelNe                    // key!=value
+	labelExists                // key
+	labelAbsent                // !key
+	labelBad                   // malformed; matches nothing
+)
+
+// WithLabelSelector restricts a query to points whose KDPoint.Labels match
+// every term: "key=value" (or "key==value") requires the label to have that
+// value, "key!=value" requires it not to (a missing label qualifies), "key"
+// requires the label to be present and "!key" requires it to be absent. A
+// term with an empty key matches no point. Filtering happens inside the
+// search, so KNearest still returns up to k matching points:
+//
+//	tree.KNearest(q, 5, WithLabelSelector("region=eu", "relay!=true"))
+func WithLabelSelector(terms ...string) QueryOption {
+	parsed := make([]labelTerm, len(terms))
+	for i, s := range terms {
+		parsed[i] = parseLabelTerm(s)
+	}
+	return func(o *queryOptions) { o.selector = append(o.selector, parsed...) }
+}
+
+func parseLabelTerm(s string) labelTerm {
+	s = strings.TrimSpace(s)
+	var t labelTerm
+	switch {
+	case strings.Contains(s, "!="):
+		t.key, t.value, _ = strings.Cut(s, "!=")
+		t.op = labelNe
+	case strings.Contains(s, "="):
+		t.key, t.value, _ = strings.Cut(s, "=")
+		t.value = strings.TrimPrefix(t.value, "=")
+		t.op = labelEq
+	case strings.HasPrefix(s, "!"):
+		t.key = s[1:]
+		t.op = labelAbsent
+	default:
+		t.key = s
+		t.op = labelExists
+	}
+	t.key, t.value = strings.TrimSpace(t.key), strings.TrimSpace(t.value)
+	if t.key == "" {
+		t.op = labelBad
+	}
+	return t
+}
+
+// matches reports whether labels satisfy the term.
+func (t labelTerm) matches(labels map[string]string) bool {
+	v, ok := labels[t.key]
+	switch t.op {
+	case labelEq:
+		return ok && v == t.value
+	case labelNe:
+		return !ok || v != t.value
+	case labelExists:
+		return ok
+	case labelAbsent:
+		return !ok
+	}
+	return false
+}
+
+// querySkip returns the predicate excluding points the query options filter
+// out, or nil if they filter nothing.
+func querySkip[T any](opts []QueryOption) func(KDPoint[T]) bool {
+	if len(opts) == 0 {
+		return nil
+	}
+	var o queryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(o.selector) == 0 {
+		return nil
+	}
+	return func(p KDPoint[T]) bool {
+		for _, t := range o.selector {
+			if !t.matches(p.Labels) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// sortedLabelKeys returns the keys of labels in ascending order, so encoders
+// write labels deterministically.
+func sortedLabelKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
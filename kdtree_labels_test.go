@@ -0,0 +1,99 @@
+package poindexter
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func labelledPoints(n int) []KDPoint[int] {
+	pts := makePoints(n, 2)
+	regions := []string{"eu", "us", "ap"}
+	for i := range pts {
+		pts[i].Labels = map[string]string{"region": regions[i%3]}
+		if i%4 == 0 {
+			pts[i].Labels["relay"] = "true"
+		}
+	}
+	return pts
+}
+
+func TestLabelSelectorTerms(t *testing.T) {
+	labels := map[string]string{"region": "eu", "relay": "false"}
+	for _, tc := range []struct {
+		term string
+		want bool
+	}{
+		{"region=eu", true},
+		{"region==eu", true},
+		{" region = us ", false},
+		{"region!=us", true},
+		{"zone!=a", true},
+		{"relay", true},
+		{"zone", false},
+		{"!zone", true},
+		{"!relay", false},
+		{"=eu", false},
+		{"", false},
+	} {
+		if got := parseLabelTerm(tc.term).matches(labels); got != tc.want {
+			t.Errorf("%q matched %v, want %v", tc.term, got, tc.want)
+		}
+	}
+}
+
+func TestLabelFilteredQueries(t *testing.T) {
+	pts := labelledPoints(600)
+	sel := WithLabelSelector("region=eu", "!relay")
+	keep := func(p KDPoint[int]) bool { return p.Labels["region"] == "eu" && p.Labels["relay"] == "" }
+	var want []KDPoint[int]
+	for _, p := range pts {
+		if keep(p) {
+			want = append(want, p)
+		}
+	}
+	ref, _ := NewKDTree(want, WithBackend(BackendLinear))
+	q := []float64{0.5, 0.5}
+	for _, b := range []KDBackend{BackendLinear, BackendGonum, BackendVPTree, BackendRTree} {
+		tr, err := NewKDTree(pts, WithBackend(b), WithCopyOnWrite())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, dists := tr.KNearest(q, 10, sel)
+		_, wantD := ref.KNearest(q, 10)
+		if len(got) != 10 || fmt.Sprint(dists) != fmt.Sprint(wantD) {
+			t.Fatalf("%s: KNearest dists %v, want %v", b, dists, wantD)
+		}
+		for _, p := range got {
+			if !keep(p) {
+				t.Fatalf("%s: KNearest returned %s with labels %v", b, p.ID, p.Labels)
+			}
+		}
+		got, _ = tr.Radius(q, 0.2, sel)
+		wantR, _ := ref.Radius(q, 0.2)
+		if len(got) != len(wantR) {
+			t.Fatalf("%s: Radius found %d, want %d", b, len(got), len(wantR))
+		}
+		if p, _, ok := tr.Nearest(q, sel); !ok || !keep(p) {
+			t.Fatalf("%s: Nearest = %v, %v", b, p, ok)
+		}
+		if _, _, ok := tr.Nearest(q, WithLabelSelector("region=mars")); ok {
+			t.Fatalf("%s: Nearest matched an unknown region", b)
+		}
+	}
+}
+
+func TestLabelsRoundTripJSON(t *testing.T) {
+	tr, _ := NewKDTree(labelledPoints(10))
+	data, err := json.Marshal(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back KDTree[int]
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := back.KNearest([]float64{0, 0}, 10, WithLabelSelector("relay")); len(got) != 3 {
+		t.Fatalf("relay points after round trip = %d, want 3", len(got))
+	}
+}
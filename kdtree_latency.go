@@ -0,0 +1,91 @@
+package poindexter
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+// latencySubBits sets the latency histogram's precision: values below
+// 2^latencySubBits nanoseconds get a bucket each, and every power of two
+// above is split into 2^(latencySubBits-1) buckets, so a bucket spans at most
+// 1/32 of the values in it.
+const latencySubBits = 6
+
+// latencyBuckets covers every non-negative int64.
+const latencyBuckets = 1<<latencySubBits + (64-latencySubBits)<<(latencySubBits-1)
+
+// latencyHistogram is a lock-free HDR-style (log-linear) histogram of
+// durations in nanoseconds with about 3% relative precision over the whole
+// int64 range. The zero value is empty and ready to use.
+type latencyHistogram struct {
+	counts [latencyBuckets]atomic.Int64
+	total  atomic.Int64
+}
+
+// latencyBucket returns the bucket holding v; negative values count as 0.
+func latencyBucket(v int64) int {
+	if v < 1<<latencySubBits {
+		return int(max(v, 0))
+	}
+	shift := bits.Len64(uint64(v)) - latencySubBits
+	mantissa := int(v>>shift) - 1<<(latencySubBits-1)
+	return 1<<latencySubBits + (shift-1)<<(latencySubBits-1) + mantissa
+}
+
+// latencyBucketRange returns the smallest and largest value in bucket i.
+func latencyBucketRange(i int) (lo, hi int64) {
+	if i < 1<<latencySubBits {
+		return int64(i), int64(i)
+	}
+	j := i - 1<<latencySubBits
+	shift := j>>(latencySubBits-1) + 1
+	mantissa := int64(j&(1<<(latencySubBits-1)-1) + 1<<(latencySubBits-1))
+	lo = mantissa << shift
+	return lo, lo + (1<<shift - 1)
+}
+
+func (h *latencyHistogram) record(ns int64) {
+	h.counts[latencyBucket(ns)].Add(1)
+	h.total.Add(1)
+}
+
+// quantile returns the q-quantile (0 <= q <= 1) of the recorded durations,
+// as the midpoint of the bucket holding it, or 0 if nothing was recorded.
+func (h *latencyHistogram) quantile(q float64) int64 {
+	total := h.total.Load()
+	if total == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(min(max(q, 0), 1) * float64(total)))
+	rank = max(rank, 1)
+	var seen int64
+	last := 0
+	for i := range h.counts {
+		c := h.counts[i].Load()
+		if c == 0 {
+			continue
+		}
+		last = i
+		if seen += c; seen >= rank {
+			break
+		}
+	}
+	lo, hi := latencyBucketRange(last)
+	return lo + (hi-lo)/2
+}
+
+func (h *latencyHistogram) reset() {
+	for i := range h.counts {
+		h.counts[i].Store(0)
+	}
+	h.total.Store(0)
+}
+
+// copyFrom overwrites h with the counts of src.
+func (h *latencyHistogram) copyFrom(src *latencyHistogram) {
+	for i := range h.counts {
+		h.counts[i].Store(src.counts[i].Load())
+	}
+	h.total.Store(src.total.Load())
+}
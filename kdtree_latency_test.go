@@ -0,0 +1,86 @@
+package poindexter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLatencyBucket_RoundTrip(t *testing.T) {
+	for _, v := range []int64{0, 1, 63, 64, 65, 127, 128, 1000, 123456, 1 << 40, math.MaxInt64} {
+		i := latencyBucket(v)
+		if i < 0 || i >= latencyBuckets {
+			t.Fatalf("bucket %d for %d out of range", i, v)
+		}
+		lo, hi := latencyBucketRange(i)
+		if v < lo || v > hi {
+			t.Fatalf("%d not in its bucket [%d, %d]", v, lo, hi)
+		}
+		if float64(hi-lo) > float64(lo)/32 {
+			t.Fatalf("bucket [%d, %d] wider than 1/32", lo, hi)
+		}
+	}
+	if latencyBucket(-5) != 0 {
+		t.Fatal("negative durations should count as 0")
+	}
+	// buckets tile the range without gaps
+	for i := 1; i < latencyBuckets; i++ {
+		_, prevHi := latencyBucketRange(i - 1)
+		lo, _ := latencyBucketRange(i)
+		if lo != prevHi+1 {
+			t.Fatalf("gap before bucket %d: %d after %d", i, lo, prevHi)
+		}
+	}
+}
+
+func TestTreeAnalyticsQueryTimeQuantile(t *testing.T) {
+	a := NewTreeAnalytics()
+	if a.QueryTimeQuantile(0.5) != 0 {
+		t.Fatal("expected 0 before any query")
+	}
+	for us := int64(1); us <= 1000; us++ {
+		a.RecordQuery(us * 1000)
+	}
+	for _, c := range []struct {
+		q    float64
+		want float64
+	}{{0.5, 500e3}, {0.95, 950e3}, {0.99, 990e3}, {0.999, 999e3}, {1, 1000e3}} {
+		got := float64(a.QueryTimeQuantile(c.q))
+		if math.Abs(got-c.want)/c.want > 0.03 {
+			t.Errorf("q%.3f = %v, want ~%v", c.q, got, c.want)
+		}
+	}
+	s := a.Snapshot()
+	if s.P50QueryTimeNs != a.QueryTimeQuantile(0.5) || s.P999QueryTimeNs != a.QueryTimeQuantile(0.999) {
+		t.Fatalf("snapshot percentiles %+v", s)
+	}
+	if !(s.P50QueryTimeNs <= s.P95QueryTimeNs && s.P95QueryTimeNs <= s.P99QueryTimeNs && s.P99QueryTimeNs <= s.P999QueryTimeNs) {
+		t.Fatalf("percentiles not ordered: %+v", s)
+	}
+
+	c := a.clone()
+	a.Reset()
+	if a.QueryTimeQuantile(0.99) != 0 {
+		t.Fatal("Reset should clear the histogram")
+	}
+	if c.QueryTimeQuantile(0.5) != s.P50QueryTimeNs {
+		t.Fatal("clone should keep its own histogram")
+	}
+}
+
+func TestTreeAnalyticsSnapshot_PercentilesRoundTrip(t *testing.T) {
+	s := TreeAnalyticsSnapshot{QueryCount: 4, P50QueryTimeNs: 500, P95QueryTimeNs: 950, P99QueryTimeNs: 990, P999QueryTimeNs: 1 << 33}
+	check := func(name string, got TreeAnalyticsSnapshot, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got.QueryCount != 4 || got.P50QueryTimeNs != 500 || got.P95QueryTimeNs != 950 ||
+			got.P99QueryTimeNs != 990 || got.P999QueryTimeNs != 1<<33 {
+			t.Fatalf("%s: percentile mismatch %+v", name, got)
+		}
+	}
+	got, err := TreeAnalyticsSnapshotFromProto(s.ToProto())
+	check("proto", got, err)
+	got, err = TreeAnalyticsSnapshotFromMsgpack(s.ToMsgpack())
+	check("msgpack", got, err)
+}
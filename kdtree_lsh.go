@@ -0,0 +1,261 @@
+package poindexter
+
+import (
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"sort"
+	"sync"
+)
+
+// The LSH backend (BackendLSH) hashes points with random hyperplanes
+// (Charikar's SimHash): each table draws `bits` Gaussian hyperplanes through
+// the origin and keys a point by which side of each it falls on, so vectors a
+// small angle apart usually share a bucket. A query collects the points in its
+// bucket in every table, plus the buckets one bit-flip away across its
+// least-certain hyperplanes (multi-probe), and ranks those candidates by exact
+// distance. Results are approximate, but the cost depends on bucket sizes
+// rather than dimension, which suits 64+ dimensional embeddings where a kd
+// tree ends up visiting every point.
+//
+// Hyperplane hashes estimate angles, so the backend is only available for
+// CosineDistance and WeightedCosineDistance (non-negative weights). Radius
+// queries are answered exactly by the linear scan.
+
+// Default LSH parameters.
+const (
+	defaultLSHTables = 8
+	defaultLSHProbes = 4
+	// lshBucketTarget is the mean bucket size the automatic bit count aims for.
+	lshBucketTarget = 16
+	maxLSHBits      = 32
+)
+
+// WithLSHTables sets the number of LSH hash tables. More tables raise recall
+// and memory use linearly. Default 8.
+func WithLSHTables(n int) KDOption {
+	return func(o *kdOptions) { o.lshTables = n }
+}
+
+// WithLSHBits sets the number of hyperplanes (hash bits) per LSH table. More
+// bits make buckets smaller: queries get faster and recall drops. The default
+// (0) picks about log2(n/16) bits, for buckets of around 16 points.
+func WithLSHBits(n int) KDOption {
+	return func(o *kdOptions) { o.lshBits = n }
+}
+
+// WithLSHProbes sets how many extra buckets per table a query visits, each one
+// bit-flip from its own on the hyperplanes it lies closest to. This is the
+// recall-vs-speed knob: 0 probes only the query's own buckets. Default 4.
+func WithLSHProbes(n int) KDOption {
+	return func(o *kdOptions) { o.lshProbes = n }
+}
+
+// lshParams are a tree's LSH settings; bits <= 0 means automatic.
+type lshParams struct {
+	tables, bits, probes int
+}
+
+func newLSHParams(o kdOptions) lshParams {
+	p := lshParams{tables: o.lshTables, bits: o.lshBits, probes: o.lshProbes}
+	if p.tables < 1 {
+		p.tables = defaultLSHTables
+	}
+	if p.probes < 0 {
+		p.probes = 0
+	}
+	return p
+}
+
+// lshBackend is a set of random-hyperplane hash tables over a point set. It is
+// not modified after construction.
+type lshBackend struct {
+	metric  DistanceMetric
+	coords  func(i int) []float64
+	dim     int
+	len     int
+	scale   []float64   // sqrt of WeightedCosineDistance weights, nil for cosine
+	bits    int         // hash bits per table
+	probes  int         // extra buckets visited per table
+	planes  [][]float64 // tables*bits hyperplane normals, table-major
+	buckets []map[uint64][]int32
+	visited sync.Pool // *hnswVisited sized for len
+}
+
+// lshScale returns the per-axis scaling that turns metric into plain cosine
+// distance, or false if hyperplane hashing does not apply to metric.
+func lshScale(metric DistanceMetric) ([]float64, bool) {
+	switch m := metric.(type) {
+	case CosineDistance:
+		return nil, true
+	case WeightedCosineDistance:
+		s := make([]float64, len(m.Weights))
+		for i, w := range m.Weights {
+			if w < 0 {
+				return nil, false
+			}
+			s[i] = math.Sqrt(w)
+		}
+		return s, true
+	}
+	return nil, false
+}
+
+// buildLSHBackend hashes points into LSH tables, with hyperplanes drawn from a
+// fixed-seed generator so builds are deterministic. It returns
+// ErrBackendUnavailable for metrics other than (weighted) cosine.
+func buildLSHBackend[T any](points []KDPoint[T], metric DistanceMetric, params lshParams) (any, error) {
+	scale, ok := lshScale(metric)
+	if !ok {
+		return nil, ErrBackendUnavailable
+	}
+	b := &lshBackend{
+		metric: metric,
+		coords: func(i int) []float64 { return points[i].Coords },
+		len:    len(points),
+		probes: params.probes,
+		bits:   params.bits,
+	}
+	words := (len(points) + 63) / 64
+	b.visited.New = func() any {
+		v := make(hnswVisited, words)
+		return &v
+	}
+	if len(points) == 0 {
+		return b, nil
+	}
+	b.dim = len(points[0].Coords)
+	if len(scale) == b.dim {
+		b.scale = scale // otherwise the metric falls back to plain cosine
+	}
+	if b.bits <= 0 {
+		b.bits = max(bits.Len(uint(len(points)/lshBucketTarget)), 1)
+	}
+	b.bits = min(b.bits, maxLSHBits)
+
+	rng := rand.New(rand.NewPCG(uint64(len(points)), 0x4c5348))
+	b.planes = make([][]float64, params.tables*b.bits)
+	for i := range b.planes {
+		p := make([]float64, b.dim)
+		for j := range p {
+			p[j] = rng.NormFloat64()
+		}
+		b.planes[i] = p
+	}
+	b.buckets = make([]map[uint64][]int32, params.tables)
+	for t := range b.buckets {
+		m := make(map[uint64][]int32)
+		for i := range points {
+			key := b.hash(t, points[i].Coords, nil)
+			m[key] = append(m[key], int32(i))
+		}
+		b.buckets[t] = m
+	}
+	return b, nil
+}
+
+// hash returns v's bucket key in table t. If margins is non-nil it receives
+// |v·plane| for each bit: how far v is from flipping it.
+func (b *lshBackend) hash(t int, v []float64, margins []float64) uint64 {
+	var key uint64
+	for j, p := range b.planes[t*b.bits : (t+1)*b.bits] {
+		var dot float64
+		if b.scale != nil {
+			for i, x := range v {
+				dot += x * b.scale[i] * p[i]
+			}
+		} else {
+			for i, x := range v {
+				dot += x * p[i]
+			}
+		}
+		if dot >= 0 {
+			key |= 1 << uint(j)
+		}
+		if margins != nil {
+			margins[j] = math.Abs(dot)
+		}
+	}
+	return key
+}
+
+// candidates calls visit once for each point sharing a probed bucket with
+// query.
+func (b *lshBackend) candidates(query []float64, visit func(i int)) {
+	vp := b.visited.Get().(*hnswVisited)
+	visited := *vp
+	defer func() {
+		clear(visited)
+		b.visited.Put(vp)
+	}()
+	probes := min(b.probes, b.bits)
+	margins := make([]float64, b.bits)
+	order := make([]int, b.bits)
+	for t, m := range b.buckets {
+		key := b.hash(t, query, margins)
+		for _, i := range m[key] {
+			if !visited.visit(int(i)) {
+				visit(int(i))
+			}
+		}
+		if probes == 0 {
+			continue
+		}
+		for j := range order {
+			order[j] = j
+		}
+		sort.Slice(order, func(x, y int) bool { return margins[order[x]] < margins[order[y]] })
+		for _, j := range order[:probes] {
+			for _, i := range m[key^(1<<uint(j))] {
+				if !visited.visit(int(i)) {
+					visit(int(i))
+				}
+			}
+		}
+	}
+}
+
+// kNearest ranks the candidates by exact distance. If fewer than k points
+// share a probed bucket with the query it scans every point instead, so a
+// query never returns fewer results than the linear backend.
+func (b *lshBackend) kNearest(query []float64, k int, skip func(idx int) bool) ([]int, []float64) {
+	if b.len == 0 || len(query) != b.dim || k <= 0 {
+		return nil, nil
+	}
+	var h knnHeap
+	offer := func(i int) {
+		if skip != nil && skip(i) {
+			return
+		}
+		d := b.metric.Distance(query, b.coords(i))
+		if h.Len() < k {
+			h.push(knnItem{idx: i, dist: d})
+		} else if d < h.peek().dist {
+			h[0] = knnItem{idx: i, dist: d}
+			h.down(0)
+		}
+	}
+	b.candidates(query, offer)
+	if h.Len() < min(k, b.len) {
+		h = h[:0]
+		for i := 0; i < b.len; i++ {
+			offer(i)
+		}
+	}
+	sort.Slice(h, func(i, j int) bool { return h[i].dist < h[j].dist })
+	idxs := make([]int, len(h))
+	dists := make([]float64, len(h))
+	for i := range h {
+		idxs[i] = h[i].idx
+		dists[i] = h[i].dist
+	}
+	return idxs, dists
+}
+
+func (b *lshBackend) nearest(query []float64) (int, float64, bool) {
+	idxs, dists := b.kNearest(query, 1, nil)
+	if len(idxs) == 0 {
+		return -1, 0, false
+	}
+	return idxs[0], dists[0], true
+}
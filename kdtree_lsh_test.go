@@ -0,0 +1,103 @@
+package poindexter
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// lshTestPoints returns n dim-dimensional points scattered around 32 random
+// directions, like clustered embeddings.
+func lshTestPoints(rng *rand.Rand, n, dim int) []KDPoint[int] {
+	centers := make([][]float64, 32)
+	for i := range centers {
+		centers[i] = make([]float64, dim)
+		for j := range centers[i] {
+			centers[i][j] = rng.NormFloat64()
+		}
+	}
+	pts := make([]KDPoint[int], n)
+	for i := range pts {
+		c := centers[rng.Intn(len(centers))]
+		v := make([]float64, dim)
+		for j := range v {
+			v[j] = c[j] + 0.5*rng.NormFloat64()
+		}
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: v, Value: i}
+	}
+	return pts
+}
+
+func TestLSH_Recall(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	all := lshTestPoints(rng, 3050, 96)
+	pts, queries := all[:3000], make([][]float64, 50)
+	for i := range queries {
+		queries[i] = all[3000+i].Coords
+	}
+	lin, _ := NewKDTree(pts, WithBackend(BackendLinear), WithMetric(CosineDistance{}))
+	exact, _ := NewKDTree(pts, WithBackend(BackendLSH), WithMetric(CosineDistance{}), WithLSHProbes(0))
+	probed, err := NewKDTree(pts, WithBackend(BackendLSH), WithMetric(CosineDistance{}), WithLSHProbes(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if probed.Backend() != BackendLSH {
+		t.Fatalf("backend %s", probed.Backend())
+	}
+	low := hnswRecall(exact, lin, queries, 10)
+	high := hnswRecall(probed, lin, queries, 10)
+	if high < 0.9 || high < low {
+		t.Fatalf("recall: 0 probes %.3f, 8 probes %.3f", low, high)
+	}
+
+	// an indexed point is its own nearest neighbour
+	for i := 0; i < 100; i++ {
+		p, d, ok := probed.Nearest(pts[i].Coords)
+		if !ok || d > 1e-12 || p.Value != i {
+			t.Fatalf("nearest of point %d: %v %v %v", i, p.ID, d, ok)
+		}
+	}
+	// distances are exact and ascending
+	ps, ds := probed.KNearest(queries[0], 10)
+	for i := range ps {
+		if ds[i] != (CosineDistance{}).Distance(queries[0], ps[i].Coords) || (i > 0 && ds[i] < ds[i-1]) {
+			t.Fatalf("bad distances %v", ds)
+		}
+	}
+	// k beyond the probed candidates still returns k results
+	if ps, _ := probed.KNearest(queries[0], 2000); len(ps) != 2000 {
+		t.Fatalf("KNearest(2000) returned %d", len(ps))
+	}
+	// radius queries stay exact
+	rg, _ := probed.Radius(queries[0], 0.5)
+	rl, _ := lin.Radius(queries[0], 0.5)
+	if len(rg) != len(rl) {
+		t.Fatalf("radius: %d vs %d", len(rg), len(rl))
+	}
+}
+
+func TestLSH_Metrics(t *testing.T) {
+	rng := rand.New(rand.NewSource(6))
+	pts := lshTestPoints(rng, 200, 8)
+	if tr, _ := NewKDTree(pts, WithBackend(BackendLSH)); tr.Backend() != BackendLinear {
+		t.Fatalf("Euclidean must fall back to linear, got %s", tr.Backend())
+	}
+	w := make([]float64, 8)
+	for i := range w {
+		w[i] = float64(i + 1)
+	}
+	tr, _ := NewKDTree(pts, WithBackend(BackendLSH), WithMetric(WeightedCosineDistance{Weights: w}), WithLSHTables(4), WithLSHBits(3))
+	if tr.Backend() != BackendLSH {
+		t.Fatalf("weighted cosine: backend %s", tr.Backend())
+	}
+	if p, _, _ := tr.Nearest(pts[7].Coords); p.Value != 7 {
+		t.Fatalf("nearest of point 7: %v", p.ID)
+	}
+	neg := append([]float64{-1}, w[1:]...)
+	if tr, _ := NewKDTree(pts, WithBackend(BackendLSH), WithMetric(WeightedCosineDistance{Weights: neg})); tr.Backend() != BackendLinear {
+		t.Fatal("negative weights must fall back to linear")
+	}
+	if c := tr.Clone(false); c.Backend() != BackendLSH || c.lsh != tr.lsh {
+		t.Fatal("clone must keep the LSH settings")
+	}
+}
@@ -0,0 +1,96 @@
+package poindexter
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// ErrMissingValue indicates a feature value was missing under MissingError.
+var ErrMissingValue = errors.New("kdtree: missing feature value")
+
+// MissingValuePolicy decides what the N-D builders do with a missing feature
+// value: NaN or ±Inf, after any WithAxisTransform (so Log of 0 counts).
+type MissingValuePolicy uint8
+
+const (
+	// MissingPropagate passes missing values through into the coordinates,
+	// where they poison distances. It is the default, for compatibility.
+	MissingPropagate MissingValuePolicy = iota
+	// MissingError fails the build with ErrMissingValue.
+	MissingError
+	// MissingDrop leaves out items with any missing value.
+	MissingDrop
+	// MissingImputeMean replaces a missing value with the mean of the
+	// axis' present values.
+	MissingImputeMean
+	// MissingImputeMedian replaces a missing value with the median of the
+	// axis' present values.
+	MissingImputeMedian
+	// MissingImputeConstant replaces a missing value with the constant given
+	// to WithMissingValueConstant (0 by default).
+	MissingImputeConstant
+)
+
+// String returns the policy name.
+func (p MissingValuePolicy) String() string {
+	switch p {
+	case MissingPropagate:
+		return "propagate"
+	case MissingError:
+		return "error"
+	case MissingDrop:
+		return "drop"
+	case MissingImputeMean:
+		return "impute-mean"
+	case MissingImputeMedian:
+		return "impute-median"
+	case MissingImputeConstant:
+		return "impute-constant"
+	}
+	return "unknown"
+}
+
+// WithMissingValuePolicy sets how BuildND, BuildNDNoErr, ComputeNormStatsND
+// and BuildNDWithStats treat missing feature values. ComputeNormStatsND
+// computes the stats over present values only, records the policy in
+// NormStats.Missing and the value to impute per axis in AxisStats.Fill;
+// BuildNDWithStats follows stats.Missing unless this option overrides it. The
+// fixed-dimension Build2D/3D/4D helpers ignore it.
+func WithMissingValuePolicy(p MissingValuePolicy) BuildOption {
+	return func(o *buildOptions) { o.missing, o.missingSet = p, true }
+}
+
+// WithMissingValueConstant selects MissingImputeConstant with the value v,
+// which is imputed as a raw value after any transform, before normalisation.
+func WithMissingValueConstant(v float64) BuildOption {
+	return func(o *buildOptions) {
+		o.missing, o.missingSet, o.missingConst = MissingImputeConstant, true, v
+	}
+}
+
+// isMissing reports whether a feature value counts as missing.
+func isMissing(v float64) bool { return math.IsNaN(v) || math.IsInf(v, 0) }
+
+// fill returns the value imputed for missing values on an axis whose present
+// values are vals.
+func (o buildOptions) fill(vals []float64) float64 {
+	switch o.missing {
+	case MissingImputeMean:
+		if len(vals) == 0 {
+			return 0
+		}
+		var sum float64
+		for _, v := range vals {
+			sum += v
+		}
+		return sum / float64(len(vals))
+	case MissingImputeMedian:
+		sorted := append([]float64(nil), vals...)
+		sort.Float64s(sorted)
+		return percentile(sorted, 0.5)
+	case MissingImputeConstant:
+		return o.missingConst
+	}
+	return 0
+}
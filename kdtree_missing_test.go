@@ -0,0 +1,106 @@
+package poindexter
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type gappy struct {
+	id         string
+	ping, loss float64
+}
+
+func gappyItems() []gappy {
+	return []gappy{
+		{"a", 10, 0},
+		{"b", math.NaN(), 0.5},
+		{"c", 30, 1},
+		{"d", 50, math.Inf(1)},
+	}
+}
+
+func gappyBuild(opts ...BuildOption) ([]KDPoint[gappy], error) {
+	return BuildND(gappyItems(), func(g gappy) string { return g.id },
+		[]func(gappy) float64{func(g gappy) float64 { return g.ping }, func(g gappy) float64 { return g.loss }},
+		[]float64{1, 1}, []bool{false, false}, opts...)
+}
+
+func TestMissingValuePolicies(t *testing.T) {
+	if _, err := gappyBuild(WithMissingValuePolicy(MissingError)); !errors.Is(err, ErrMissingValue) {
+		t.Fatalf("MissingError: err = %v, want ErrMissingValue", err)
+	}
+
+	pts, err := gappyBuild(WithMissingValuePolicy(MissingDrop))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b and d are dropped and do not stretch the stats of the others
+	if len(pts) != 2 || pts[0].ID != "a" || pts[1].ID != "c" || !reflect.DeepEqual(pts[1].Coords, []float64{1, 1}) {
+		t.Fatalf("MissingDrop = %v", pts)
+	}
+
+	cases := []struct {
+		opt          BuildOption
+		bPing, dLoss float64 // raw values imputed for b and d
+	}{
+		{WithMissingValuePolicy(MissingImputeMean), 30, 0.5},
+		{WithMissingValuePolicy(MissingImputeMedian), 30, 0.5},
+		{WithMissingValueConstant(10), 10, 10},
+	}
+	for _, c := range cases {
+		pts, err := gappyBuild(c.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pts) != 4 {
+			t.Fatalf("got %d points, want 4", len(pts))
+		}
+		for _, p := range pts {
+			for _, x := range p.Coords {
+				if isMissing(x) {
+					t.Fatalf("point %s has missing coordinate %v", p.ID, p.Coords)
+				}
+			}
+		}
+		// ping spans [10,50] over the present values, loss [0,1]
+		if got, want := pts[1].Coords[0], (c.bPing-10)/40; got != want {
+			t.Fatalf("b ping = %v, want %v", got, want)
+		}
+		if got := pts[3].Coords[1]; got != c.dLoss {
+			t.Fatalf("d loss = %v, want %v", got, c.dLoss)
+		}
+	}
+
+	// the default still propagates
+	pts, _ = gappyBuild()
+	if !math.IsNaN(pts[1].Coords[0]) {
+		t.Fatalf("default policy: b ping = %v, want NaN", pts[1].Coords[0])
+	}
+}
+
+func TestMissingValuePolicyWithStats(t *testing.T) {
+	items := gappyItems()
+	id := func(g gappy) string { return g.id }
+	features := []func(gappy) float64{func(g gappy) float64 { return g.ping }, func(g gappy) float64 { return g.loss }}
+	stats, err := ComputeNormStatsND(items, features, WithMissingValuePolicy(MissingImputeMedian))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Missing != MissingImputeMedian || stats.Stats[0].Fill != 30 || stats.Stats[0].Max != 50 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	later := []gappy{{"e", math.NaN(), 1}}
+	pts, err := BuildNDWithStats(later, id, features, []float64{1, 1}, []bool{false, false}, stats)
+	if err != nil || len(pts) != 1 || pts[0].Coords[0] != 0.5 {
+		t.Fatalf("stats policy: %v, %v", pts, err)
+	}
+	pts, err = BuildNDWithStats(later, id, features, []float64{1, 1}, []bool{false, false}, stats, WithMissingValuePolicy(MissingDrop))
+	if err != nil || len(pts) != 0 {
+		t.Fatalf("override to MissingDrop: %v, %v", pts, err)
+	}
+	if _, err := BuildNDWithStats(later, id, features, []float64{1, 1}, []bool{false, false}, stats, WithMissingValuePolicy(MissingError)); !errors.Is(err, ErrMissingValue) {
+		t.Fatalf("override to MissingError: err = %v", err)
+	}
+}
@@ -0,0 +1,667 @@
+package poindexter
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// MessagePack helpers for compact transport to browser/WASM consumers. Like the
+// protobuf helpers they encode directly against the wire format so the package
+// keeps zero external dependencies.
+//
+// A KDPoint is encoded as a map {"id": str, "coords": [float64...], "value": bin,
+// "labels": {str: str}} ("value" only when a codec is supplied, "labels" only
+// when the point has labels) and a TreeAnalyticsSnapshot as a map
+// keyed by its JSON field names, with times as msgpack timestamps (omitted when
+// zero). Decoders accept any numeric type for coordinates and counters, ignore
+// unknown keys, and accept str or bin for values.
+
+// ErrInvalidMsgpack indicates malformed MessagePack data.
+var ErrInvalidMsgpack = errors.New("kdtree: invalid msgpack data")
+
+// msgpack extension type for timestamps
+const mpExtTimestamp = -1
+
+// KDPointsToMsgpack encodes pts as a msgpack array of point maps. If codec is
+// nil the value field is omitted.
+func KDPointsToMsgpack[T any](pts []KDPoint[T], codec *ValueCodec[T]) ([]byte, error) {
+	b := mpAppendArrayHeader(nil, len(pts))
+	for _, p := range pts {
+		fields := 2
+		var vb []byte
+		hasValue := codec != nil && codec.Encode != nil
+		if hasValue {
+			var err error
+			if vb, err = codec.Encode(p.Value); err != nil {
+				return nil, err
+			}
+			fields++
+		}
+		if len(p.Labels) > 0 {
+			fields++
+		}
+		b = mpAppendMapHeader(b, fields)
+		b = mpAppendString(b, "id")
+		b = mpAppendString(b, p.ID)
+		b = mpAppendString(b, "coords")
+		b = mpAppendArrayHeader(b, len(p.Coords))
+		for _, c := range p.Coords {
+			b = mpAppendFloat64(b, c)
+		}
+		if hasValue {
+			b = mpAppendString(b, "value")
+			b = mpAppendBin(b, vb)
+		}
+		if len(p.Labels) > 0 {
+			b = mpAppendString(b, "labels")
+			b = mpAppendMapHeader(b, len(p.Labels))
+			for _, k := range sortedLabelKeys(p.Labels) {
+				b = mpAppendString(b, k)
+				b = mpAppendString(b, p.Labels[k])
+			}
+		}
+	}
+	return b, nil
+}
+
+// KDPointsFromMsgpack decodes a msgpack array of point maps. If codec is nil the
+// values are left as the zero value of T.
+func KDPointsFromMsgpack[T any](b []byte, codec *ValueCodec[T]) ([]KDPoint[T], error) {
+	r := mpReader{b: b}
+	n, err := r.arrayLen()
+	if err != nil {
+		return nil, err
+	}
+	pts := make([]KDPoint[T], 0, min(n, len(b)))
+	for i := 0; i < n; i++ {
+		var p KDPoint[T]
+		err := r.mapEach(func(key string) error {
+			switch key {
+			case "id":
+				s, err := r.bytes()
+				p.ID = string(s)
+				return err
+			case "coords":
+				m, err := r.arrayLen()
+				if err != nil {
+					return err
+				}
+				p.Coords = make([]float64, m)
+				for j := range p.Coords {
+					if p.Coords[j], err = r.float(); err != nil {
+						return err
+					}
+				}
+				return nil
+			case "value":
+				vb, err := r.bytes()
+				if err != nil || codec == nil || codec.Decode == nil {
+					return err
+				}
+				p.Value, err = codec.Decode(vb)
+				return err
+			case "labels":
+				labels := map[string]string{}
+				err := r.mapEach(func(k string) error {
+					v, err := r.bytes()
+					labels[k] = string(v)
+					return err
+				})
+				if len(labels) > 0 {
+					p.Labels = labels
+				}
+				return err
+			default:
+				return r.skip()
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		pts = append(pts, p)
+	}
+	return pts, r.end()
+}
+
+// ToMsgpack encodes the snapshot as a msgpack map keyed by its JSON field names.
+func (s TreeAnalyticsSnapshot) ToMsgpack() []byte {
+	ints := s.msgpackInts()
+	times := s.msgpackTimes()
+	floats := s.msgpackFloats()
+	fields := len(ints) + len(floats) + 1 // + anomalous
+	for _, f := range times {
+		if !f.v.IsZero() {
+			fields++
+		}
+	}
+	b := mpAppendMapHeader(nil, fields)
+	for _, f := range ints {
+		b = mpAppendString(b, f.key)
+		b = mpAppendInt(b, f.v)
+	}
+	for _, f := range times {
+		if !f.v.IsZero() {
+			b = mpAppendString(b, f.key)
+			b = mpAppendTime(b, f.v)
+		}
+	}
+	for _, f := range floats {
+		b = mpAppendString(b, f.key)
+		b = mpAppendFloat64(b, *f.p)
+	}
+	b = mpAppendString(b, "anomalous")
+	return mpAppendBool(b, s.Anomalous)
+}
+
+// TreeAnalyticsSnapshotFromMsgpack decodes a snapshot written by ToMsgpack.
+func TreeAnalyticsSnapshotFromMsgpack(b []byte) (TreeAnalyticsSnapshot, error) {
+	var s TreeAnalyticsSnapshot
+	ints := s.msgpackInts()
+	times := s.msgpackTimes()
+	floats := s.msgpackFloats()
+	r := mpReader{b: b}
+	err := r.mapEach(func(key string) error {
+		for _, f := range ints {
+			if f.key == key {
+				v, err := r.int()
+				*f.p = v
+				return err
+			}
+		}
+		for _, f := range times {
+			if f.key == key {
+				v, err := r.time()
+				*f.p = v
+				return err
+			}
+		}
+		for _, f := range floats {
+			if f.key == key {
+				v, err := r.float()
+				*f.p = v
+				return err
+			}
+		}
+		if key == "anomalous" {
+			v, err := r.bool()
+			s.Anomalous = v
+			return err
+		}
+		return r.skip()
+	})
+	if err != nil {
+		return TreeAnalyticsSnapshot{}, err
+	}
+	if err := r.end(); err != nil {
+		return TreeAnalyticsSnapshot{}, err
+	}
+	return s, nil
+}
+
+type mpIntField struct {
+	key string
+	v   int64
+	p   *int64
+}
+
+type mpTimeField struct {
+	key string
+	v   time.Time
+	p   *time.Time
+}
+
+func (s *TreeAnalyticsSnapshot) msgpackInts() []mpIntField {
+	return []mpIntField{
+		{"queryCount", s.QueryCount, &s.QueryCount},
+		{"insertCount", s.InsertCount, &s.InsertCount},
+		{"deleteCount", s.DeleteCount, &s.DeleteCount},
+		{"avgQueryTimeNs", s.AvgQueryTimeNs, &s.AvgQueryTimeNs},
+		{"minQueryTimeNs", s.MinQueryTimeNs, &s.MinQueryTimeNs},
+		{"maxQueryTimeNs", s.MaxQueryTimeNs, &s.MaxQueryTimeNs},
+		{"lastQueryTimeNs", s.LastQueryTimeNs, &s.LastQueryTimeNs},
+		{"backendRebuildCount", s.BackendRebuildCnt, &s.BackendRebuildCnt},
+		{"lastRebuildTimeNs", s.LastRebuildTimeNs, &s.LastRebuildTimeNs},
+		{"avgRebuildTimeNs", s.AvgRebuildTimeNs, &s.AvgRebuildTimeNs},
+		{"p50QueryTimeNs", s.P50QueryTimeNs, &s.P50QueryTimeNs},
+		{"p95QueryTimeNs", s.P95QueryTimeNs, &s.P95QueryTimeNs},
+		{"p99QueryTimeNs", s.P99QueryTimeNs, &s.P99QueryTimeNs},
+		{"p999QueryTimeNs", s.P999QueryTimeNs, &s.P999QueryTimeNs},
+	}
+}
+
+type mpFloatField struct {
+	key string
+	p   *float64
+}
+
+func (s *TreeAnalyticsSnapshot) msgpackFloats() []mpFloatField {
+	return []mpFloatField{
+		{"avgRadiusResults", &s.AvgRadiusResults},
+		{"bestDistanceMean", &s.BestDistanceMean},
+		{"bestDistanceP50", &s.BestDistanceP50},
+		{"bestDistanceP90", &s.BestDistanceP90},
+	}
+}
+
+func (s *TreeAnalyticsSnapshot) msgpackTimes() []mpTimeField {
+	return []mpTimeField{
+		{"lastQueryAt", s.LastQueryAt, &s.LastQueryAt},
+		{"createdAt", s.CreatedAt, &s.CreatedAt},
+		{"lastRebuiltAt", s.LastRebuiltAt, &s.LastRebuiltAt},
+	}
+}
+
+func mpAppendArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+	}
+}
+
+func mpAppendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+	}
+}
+
+func mpAppendString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func mpAppendBin(b []byte, data []byte) []byte {
+	switch n := len(data); {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(n))
+	}
+	return append(b, data...)
+}
+
+func mpAppendFloat64(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+// mpAppendInt writes v using the smallest integer encoding.
+func mpAppendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v < 128:
+		return append(b, byte(v))
+	case v >= -32 && v < 0:
+		return append(b, byte(v))
+	case v >= 0 && v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v >= 0 && v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(v))
+	case v >= 0 && v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(v))
+	case v >= 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+	}
+}
+
+func mpAppendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+// mpAppendTime writes t as a 96-bit msgpack timestamp extension.
+func mpAppendTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xc7, 12, byte(mpExtTimestamp&0xff))
+	b = binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond()))
+	return binary.BigEndian.AppendUint64(b, uint64(t.Unix()))
+}
+
+// mpReader decodes msgpack values from a byte slice.
+type mpReader struct {
+	b []byte
+}
+
+func (r *mpReader) next(n int) ([]byte, error) {
+	if n < 0 || n > len(r.b) {
+		return nil, ErrInvalidMsgpack
+	}
+	out := r.b[:n]
+	r.b = r.b[n:]
+	return out, nil
+}
+
+func (r *mpReader) byte() (byte, error) {
+	p, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return p[0], nil
+}
+
+func (r *mpReader) uint(size int) (uint64, error) {
+	p, err := r.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(p[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(p)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(p)), nil
+	default:
+		return binary.BigEndian.Uint64(p), nil
+	}
+}
+
+// end reports an error if unread bytes remain.
+func (r *mpReader) end() error {
+	if len(r.b) != 0 {
+		return ErrInvalidMsgpack
+	}
+	return nil
+}
+
+func (r *mpReader) arrayLen() (int, error) {
+	c, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	switch {
+	case c&0xf0 == 0x90:
+		n = uint64(c & 0x0f)
+	case c == 0xdc:
+		n, err = r.uint(2)
+	case c == 0xdd:
+		n, err = r.uint(4)
+	default:
+		return 0, ErrInvalidMsgpack
+	}
+	if err != nil || n > uint64(len(r.b)) {
+		return 0, ErrInvalidMsgpack
+	}
+	return int(n), nil
+}
+
+func (r *mpReader) mapLen() (int, error) {
+	c, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	switch {
+	case c&0xf0 == 0x80:
+		n = uint64(c & 0x0f)
+	case c == 0xde:
+		n, err = r.uint(2)
+	case c == 0xdf:
+		n, err = r.uint(4)
+	default:
+		return 0, ErrInvalidMsgpack
+	}
+	if err != nil || n > uint64(len(r.b)) {
+		return 0, ErrInvalidMsgpack
+	}
+	return int(n), nil
+}
+
+// mapEach reads a map with string keys, calling fn for each key; fn must
+// consume the corresponding value.
+func (r *mpReader) mapEach(fn func(key string) error) error {
+	n, err := r.mapLen()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		key, err := r.bytes()
+		if err != nil {
+			return err
+		}
+		if err := fn(string(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bytes reads a str or bin value.
+func (r *mpReader) bytes() ([]byte, error) {
+	c, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	var n uint64
+	switch c {
+	case 0xd9, 0xc4:
+		n, err = r.uint(1)
+	case 0xda, 0xc5:
+		n, err = r.uint(2)
+	case 0xdb, 0xc6:
+		n, err = r.uint(4)
+	default:
+		if c&0xe0 != 0xa0 {
+			return nil, ErrInvalidMsgpack
+		}
+		n = uint64(c & 0x1f)
+	}
+	if err != nil || n > uint64(len(r.b)) {
+		return nil, ErrInvalidMsgpack
+	}
+	return r.next(int(n))
+}
+
+func (r *mpReader) bool() (bool, error) {
+	c, err := r.byte()
+	if err != nil {
+		return false, err
+	}
+	switch c {
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	}
+	return false, ErrInvalidMsgpack
+}
+
+// int reads any integer value; floats with integral values are accepted too.
+func (r *mpReader) int() (int64, error) {
+	start := r.b
+	c, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case c < 0x80:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	}
+	switch c {
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := r.uint(1 << (c - 0xcc))
+		if err != nil || v > math.MaxInt64 {
+			return 0, ErrInvalidMsgpack
+		}
+		return int64(v), nil
+	case 0xd0:
+		v, err := r.uint(1)
+		return int64(int8(v)), err
+	case 0xd1:
+		v, err := r.uint(2)
+		return int64(int16(v)), err
+	case 0xd2:
+		v, err := r.uint(4)
+		return int64(int32(v)), err
+	case 0xd3:
+		v, err := r.uint(8)
+		return int64(v), err
+	case 0xca, 0xcb:
+		r.b = start
+		f, err := r.float()
+		if err != nil || f != math.Trunc(f) || math.Abs(f) > math.MaxInt64 {
+			return 0, ErrInvalidMsgpack
+		}
+		return int64(f), nil
+	}
+	return 0, ErrInvalidMsgpack
+}
+
+// float reads any numeric value as a float64.
+func (r *mpReader) float() (float64, error) {
+	if len(r.b) == 0 {
+		return 0, ErrInvalidMsgpack
+	}
+	switch r.b[0] {
+	case 0xca:
+		r.b = r.b[1:]
+		v, err := r.uint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		r.b = r.b[1:]
+		v, err := r.uint(8)
+		return math.Float64frombits(v), err
+	case 0xcf:
+		r.b = r.b[1:]
+		v, err := r.uint(8)
+		return float64(v), err
+	}
+	v, err := r.int()
+	return float64(v), err
+}
+
+// time reads a msgpack timestamp extension (32, 64 or 96 bit) or nil.
+func (r *mpReader) time() (time.Time, error) {
+	c, err := r.byte()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var n int
+	switch c {
+	case 0xc0:
+		return time.Time{}, nil
+	case 0xd6:
+		n = 4
+	case 0xd7:
+		n = 8
+	case 0xc7:
+		l, err := r.byte()
+		if err != nil || l != 12 {
+			return time.Time{}, ErrInvalidMsgpack
+		}
+		n = 12
+	default:
+		return time.Time{}, ErrInvalidMsgpack
+	}
+	typ, err := r.byte()
+	if err != nil || int8(typ) != mpExtTimestamp {
+		return time.Time{}, ErrInvalidMsgpack
+	}
+	p, err := r.next(n)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(p)), 0), nil
+	case 8:
+		v := binary.BigEndian.Uint64(p)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)), nil
+	default:
+		return time.Unix(int64(binary.BigEndian.Uint64(p[4:])), int64(binary.BigEndian.Uint32(p))), nil
+	}
+}
+
+// skip discards the next value of any type.
+func (r *mpReader) skip() error {
+	start := r.b
+	c, err := r.byte()
+	if err != nil {
+		return err
+	}
+	var n uint64
+	switch {
+	case c < 0x80 || c >= 0xe0 || c == 0xc0 || c == 0xc2 || c == 0xc3:
+		return nil
+	case c&0xf0 == 0x80, c == 0xde, c == 0xdf:
+		r.b = start
+		m, err := r.mapLen()
+		if err != nil {
+			return err
+		}
+		return r.skipN(2 * m)
+	case c&0xf0 == 0x90, c == 0xdc, c == 0xdd:
+		r.b = start
+		m, err := r.arrayLen()
+		if err != nil {
+			return err
+		}
+		return r.skipN(m)
+	case c&0xe0 == 0xa0, c == 0xd9, c == 0xda, c == 0xdb, c == 0xc4, c == 0xc5, c == 0xc6:
+		r.b = start
+		_, err := r.bytes()
+		return err
+	case c == 0xcc, c == 0xd0:
+		n = 1
+	case c == 0xcd, c == 0xd1:
+		n = 2
+	case c == 0xca, c == 0xce, c == 0xd2:
+		n = 4
+	case c == 0xcb, c == 0xcf, c == 0xd3:
+		n = 8
+	case c >= 0xd4 && c <= 0xd8: // fixext 1/2/4/8/16
+		n = 1 + 1<<(c-0xd4)
+	case c == 0xc7, c == 0xc8, c == 0xc9: // ext 8/16/32
+		if n, err = r.uint(1 << (c - 0xc7)); err != nil {
+			return err
+		}
+		n++ // type byte
+	default:
+		return ErrInvalidMsgpack
+	}
+	if n > uint64(len(r.b)) {
+		return ErrInvalidMsgpack
+	}
+	r.b = r.b[n:]
+	return nil
+}
+
+func (r *mpReader) skipN(n int) error {
+	for i := 0; i < n; i++ {
+		if err := r.skip(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
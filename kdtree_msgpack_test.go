@@ -0,0 +1,166 @@
+package poindexter
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKDPointsMsgpack_WireFormat(t *testing.T) {
+	b, err := KDPointsToMsgpack([]KDPoint[string]{{ID: "a", Coords: []float64{1}, Value: "v"}}, StringValueCodec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x91, 0x83, // array(1), map(3)
+		0xa2, 'i', 'd', 0xa1, 'a',
+		0xa6, 'c', 'o', 'o', 'r', 'd', 's', 0x91, 0xcb, 0x3f, 0xf0, 0, 0, 0, 0, 0, 0,
+		0xa5, 'v', 'a', 'l', 'u', 'e', 0xc4, 0x01, 'v',
+	}
+	if !bytes.Equal(b, want) {
+		t.Fatalf("wire mismatch:\n got %x\nwant %x", b, want)
+	}
+	pts, err := KDPointsFromMsgpack(b, StringValueCodec())
+	if err != nil || len(pts) != 1 || pts[0].ID != "a" || pts[0].Value != "v" || pts[0].Coords[0] != 1 {
+		t.Fatalf("decode mismatch: %+v err=%v", pts, err)
+	}
+}
+
+func TestKDPointsMsgpack_LenientDecode(t *testing.T) {
+	// As a JS encoder would write it: integer and float32 coords, str value,
+	// and an unknown key holding a nested map.
+	b := []byte{
+		0x91, 0x84,
+		0xa6, 'c', 'o', 'o', 'r', 'd', 's', 0x93, 0x02, 0xff, 0xca, 0x3f, 0xc0, 0, 0, // [2, -1, 1.5]
+		0xa5, 'e', 'x', 't', 'r', 'a', 0x81, 0xa1, 'k', 0x92, 0xc0, 0xc3,
+		0xa5, 'v', 'a', 'l', 'u', 'e', 0xa2, 'h', 'i',
+		0xa2, 'i', 'd', 0xa1, 'x',
+	}
+	pts, err := KDPointsFromMsgpack(b, StringValueCodec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pts[0]
+	if p.ID != "x" || p.Value != "hi" || len(p.Coords) != 3 || p.Coords[0] != 2 || p.Coords[1] != -1 || p.Coords[2] != 1.5 {
+		t.Fatalf("unexpected point %+v", p)
+	}
+}
+
+func TestKDPointsMsgpack_RoundTripIntoTree(t *testing.T) {
+	pts := make([]KDPoint[string], 40)
+	for i := range pts {
+		pts[i] = KDPoint[string]{ID: string(rune('A' + i)), Coords: []float64{float64(i), -float64(i) / 3}, Value: "v"}
+	}
+	b, err := KDPointsToMsgpack(pts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := KDPointsFromMsgpack[string](b, nil)
+	if err != nil || len(got) != len(pts) {
+		t.Fatalf("decode: len=%d err=%v", len(got), err)
+	}
+	tr, err := NewKDTree(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _, _ := tr.Nearest([]float64{20, -20.0 / 3}); p.ID != pts[20].ID || p.Value != "" {
+		t.Fatalf("unexpected nearest %+v", p)
+	}
+}
+
+func TestKDPointsMsgpack_Labels(t *testing.T) {
+	b, err := KDPointsToMsgpack([]KDPoint[string]{{ID: "a", Coords: []float64{1}, Labels: map[string]string{"z": "1", "r": "eu"}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x91, 0x83, // array(1), map(3)
+		0xa2, 'i', 'd', 0xa1, 'a',
+		0xa6, 'c', 'o', 'o', 'r', 'd', 's', 0x91, 0xcb, 0x3f, 0xf0, 0, 0, 0, 0, 0, 0,
+		0xa6, 'l', 'a', 'b', 'e', 'l', 's', 0x82, 0xa1, 'r', 0xa2, 'e', 'u', 0xa1, 'z', 0xa1, '1',
+	}
+	if !bytes.Equal(b, want) {
+		t.Fatalf("wire mismatch:\n got %x\nwant %x", b, want)
+	}
+
+	pts := labelledPoints(20)
+	pts[1].Labels = nil
+	b, err = KDPointsToMsgpack(pts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := KDPointsFromMsgpack[int](b, nil)
+	if err != nil || len(got) != len(pts) {
+		t.Fatalf("decode: len=%d err=%v", len(got), err)
+	}
+	for i, p := range got {
+		if !reflect.DeepEqual(p.Labels, pts[i].Labels) {
+			t.Fatalf("point %s labels %v, want %v", p.ID, p.Labels, pts[i].Labels)
+		}
+	}
+	for i := 0; i < len(want); i++ {
+		if _, err := KDPointsFromMsgpack[string](want[:i], nil); !errors.Is(err, ErrInvalidMsgpack) {
+			t.Fatalf("truncated at %d: expected ErrInvalidMsgpack, got %v", i, err)
+		}
+	}
+}
+
+func TestKDPointsMsgpack_Invalid(t *testing.T) {
+	b, _ := KDPointsToMsgpack([]KDPoint[string]{{ID: "a", Coords: []float64{1, 2}}}, nil)
+	for i := 0; i < len(b); i++ {
+		if _, err := KDPointsFromMsgpack[string](b[:i], nil); !errors.Is(err, ErrInvalidMsgpack) {
+			t.Fatalf("truncated at %d: expected ErrInvalidMsgpack, got %v", i, err)
+		}
+	}
+	if _, err := KDPointsFromMsgpack[string](append(b, 0xc0), nil); !errors.Is(err, ErrInvalidMsgpack) {
+		t.Fatalf("trailing bytes: expected ErrInvalidMsgpack, got %v", err)
+	}
+	if _, err := KDPointsFromMsgpack[string]([]byte{0xdd, 0xff, 0xff, 0xff, 0xff}, nil); !errors.Is(err, ErrInvalidMsgpack) {
+		t.Fatalf("huge array length: expected ErrInvalidMsgpack, got %v", err)
+	}
+}
+
+func TestTreeAnalyticsSnapshotMsgpack_RoundTrip(t *testing.T) {
+	now := time.Unix(1700000000, 123456789)
+	s := TreeAnalyticsSnapshot{
+		QueryCount:        10,
+		InsertCount:       300,
+		DeleteCount:       70000,
+		AvgQueryTimeNs:    1 << 40,
+		MinQueryTimeNs:    -5,
+		LastQueryAt:       now,
+		CreatedAt:         now.Add(-time.Hour),
+		BackendRebuildCnt: 2,
+		LastRebuildTimeNs: 700,
+		AvgRadiusResults:  4.5,
+	}
+	got, err := TreeAnalyticsSnapshotFromMsgpack(s.ToMsgpack())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.QueryCount != 10 || got.InsertCount != 300 || got.DeleteCount != 70000 || got.AvgQueryTimeNs != 1<<40 ||
+		got.MinQueryTimeNs != -5 || got.BackendRebuildCnt != 2 || got.LastRebuildTimeNs != 700 || got.AvgRadiusResults != 4.5 {
+		t.Fatalf("counter mismatch: %+v", got)
+	}
+	if !got.LastQueryAt.Equal(now) || !got.CreatedAt.Equal(now.Add(-time.Hour)) || !got.LastRebuiltAt.IsZero() {
+		t.Fatalf("time mismatch: %v %v %v", got.LastQueryAt, got.CreatedAt, got.LastRebuiltAt)
+	}
+}
+
+func TestTreeAnalyticsSnapshotMsgpack_Timestamps(t *testing.T) {
+	// timestamp 32 (seconds only) and timestamp 64 (30-bit nanos, 34-bit seconds)
+	b := []byte{
+		0x82,
+		0xa9, 'c', 'r', 'e', 'a', 't', 'e', 'd', 'A', 't', 0xd6, 0xff, 0, 0, 0, 100,
+		0xab, 'l', 'a', 's', 't', 'Q', 'u', 'e', 'r', 'y', 'A', 't', 0xd7, 0xff, 0, 0, 0, 0x1c, 0, 0, 0, 100, // 7ns
+	}
+	got, err := TreeAnalyticsSnapshotFromMsgpack(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(time.Unix(100, 0)) || !got.LastQueryAt.Equal(time.Unix(100, 7)) {
+		t.Fatalf("unexpected times %v %v", got.CreatedAt, got.LastQueryAt)
+	}
+}
@@ -0,0 +1,656 @@
+package poindexter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Normalization selects how builders scale each feature axis before weights
+// are applied.
+type Normalization uint8
+
+const (
+	// MinMax maps each axis linearly onto [0,1] using its observed min and
+	// max. It is the default. A single extreme value stretches the range and
+	// squeezes every other value towards one end.
+	MinMax Normalization = iota
+	// ZScore centres each axis on its mean and divides by its (population)
+	// standard deviation, so typical values land in roughly [-2,2] and one
+	// outlier shifts the others far less than under MinMax. Inverting a
+	// z-scored axis negates it.
+	ZScore
+	// Robust centres each axis on its median and divides by its interquartile
+	// range (75th minus 25th percentile), so outliers barely move the scale
+	// of everyone else: a 10-second ping lands far out on its own while the
+	// ordinary peers keep their spread. An axis whose IQR is 0 but which is
+	// not constant is divided by its max-min range instead. Inverting a
+	// robust-scaled axis negates it.
+	Robust
+)
+
+// String returns the strategy name.
+func (n Normalization) String() string {
+	switch n {
+	case MinMax:
+		return "minmax"
+	case ZScore:
+		return "zscore"
+	case Robust:
+		return "robust"
+	}
+	return "unknown"
+}
+
+// AxisTransform reshapes raw feature values before they are normalised.
+type AxisTransform uint8
+
+const (
+	// NoTransform leaves values as they are.
+	NoTransform AxisTransform = iota
+	// Log replaces v with ln(v). Values must be positive: 0 becomes -Inf
+	// and negatives NaN, so prefer Log1p for features that can be 0.
+	Log
+	// Log1p replaces v with ln(1+v), which maps 0 to 0 and suits
+	// non-negative heavy-tailed features such as bandwidth or geo distance.
+	Log1p
+)
+
+// String returns the transform name.
+func (x AxisTransform) String() string {
+	switch x {
+	case NoTransform:
+		return "none"
+	case Log:
+		return "log"
+	case Log1p:
+		return "log1p"
+	}
+	return "unknown"
+}
+
+func (x AxisTransform) apply(v float64) float64 {
+	switch x {
+	case Log:
+		return math.Log(v)
+	case Log1p:
+		return math.Log1p(v)
+	}
+	return v
+}
+
+// undo inverts apply.
+func (x AxisTransform) undo(v float64) float64 {
+	switch x {
+	case Log:
+		return math.Exp(v)
+	case Log1p:
+		return math.Expm1(v)
+	}
+	return v
+}
+
+// transformAt applies the transform for axis, if ts has one.
+func transformAt(ts []AxisTransform, axis int, v float64) float64 {
+	if axis < len(ts) {
+		return ts[axis].apply(v)
+	}
+	return v
+}
+
+// BuildOption configures the Build* and ComputeNormStats* helpers.
+type BuildOption func(*buildOptions)
+
+type buildOptions struct {
+	norm        Normalization
+	transforms  map[int]AxisTransform
+	sigmoids    map[int][2]float64 // axis → {midpoint, width}
+	targets     map[int][2]float64 // axis → {lo, hi}
+	categorical []categoricalFeature
+
+	missing      MissingValuePolicy
+	missingSet   bool // missing was given explicitly
+	missingConst float64
+
+	outOfRange    RangePolicy
+	outOfRangeSet bool // outOfRange was given explicitly
+
+	mask []bool // nil keeps every axis
+
+	winsor    [2]float64 // lower and upper quantiles
+	winsorSet bool
+}
+
+// WithNormalization selects the per-axis normalization strategy (MinMax by
+// default). Stats computed with it record the strategy in NormStats.Method, so
+// the *WithStats builders apply the same scaling later.
+func WithNormalization(n Normalization) BuildOption {
+	return func(o *buildOptions) { o.norm = n }
+}
+
+// WithAxisTransform applies x to the raw values of feature axis (0-based)
+// before normalisation, e.g. WithAxisTransform(2, Log1p) to tame a
+// heavy-tailed geo distance so a few far-away peers do not dominate the axis.
+// Stats computed with it record the transforms in NormStats.Transforms, and
+// the *WithStats builders reapply them. The builders that return errors
+// report ErrInvalidAxisTransform for an axis outside the features.
+func WithAxisTransform(axis int, x AxisTransform) BuildOption {
+	return func(o *buildOptions) {
+		if o.transforms == nil {
+			o.transforms = make(map[int]AxisTransform)
+		}
+		o.transforms[axis] = x
+	}
+}
+
+// WithAxisSigmoid scales feature axis (0-based) with the logistic curve
+// 1/(1+exp(-(v-midpoint)/width)) instead of the normalization strategy, so
+// values beyond a soft threshold saturate rather than dominate: with
+// midpoint 250 and width 60, a 500ms latency maps to 0.985 and a 5s one to
+// 1, making both about equally bad. The axis lands in (0,1) and runs after
+// any WithAxisTransform. Stats computed with it record midpoint and width in
+// AxisStats. width must be positive and finite; the builders that return
+// errors report ErrInvalidAxisTransform otherwise, or for an axis outside
+// the features.
+func WithAxisSigmoid(axis int, midpoint, width float64) BuildOption {
+	return func(o *buildOptions) {
+		if o.sigmoids == nil {
+			o.sigmoids = make(map[int][2]float64)
+		}
+		o.sigmoids[axis] = [2]float64{midpoint, width}
+	}
+}
+
+// WithTargetRange maps feature axis (0-based) onto [lo, hi] instead of [0,1]
+// after normalisation and inversion, before the axis weight is applied, e.g.
+// [-1, 1] for cosine workloads where a [0,1] axis would leave every vector in
+// one orthant. The map is affine, taking the normalised 0 to lo and 1 to hi:
+// exact for MinMax and sigmoid axes, which land in [0,1], and a plain
+// stretch and shift for ZScore and Robust. Stats computed with it record the
+// range in AxisStats, so the *WithStats builders reuse it. lo must be below
+// hi and both finite; the builders that return errors report
+// ErrInvalidAxisTransform otherwise, or for an axis outside the features.
+func WithTargetRange(axis int, lo, hi float64) BuildOption {
+	return func(o *buildOptions) {
+		if o.targets == nil {
+			o.targets = make(map[int][2]float64)
+		}
+		o.targets[axis] = [2]float64{lo, hi}
+	}
+}
+
+// validTarget reports whether lo and hi describe a usable target range.
+func validTarget(lo, hi float64) bool {
+	return lo < hi && !math.IsInf(lo, 0) && !math.IsInf(hi, 0)
+}
+
+// target maps a normalised, possibly inverted, value onto the axis' target
+// range, if it has one.
+func (s AxisStats) target(n float64) float64 {
+	if s.TargetLo == s.TargetHi {
+		return n
+	}
+	return s.TargetLo + n*(s.TargetHi-s.TargetLo)
+}
+
+// untarget inverts target.
+func (s AxisStats) untarget(v float64) float64 {
+	if s.TargetLo == s.TargetHi {
+		return v
+	}
+	return (v - s.TargetLo) / (s.TargetHi - s.TargetLo)
+}
+
+// WithWinsorize clamps each axis' raw values, after any transform, to their
+// lower and upper quantiles before the normalisation stats are computed, so
+// a single broken measurement does not define the range for everyone:
+// WithWinsorize(0.01, 0.99) caps every axis at its 1st and 99th percentiles.
+// Quantiles interpolate between neighbouring values as in the analytics
+// percentiles, and missing values are left out.
+//
+// The clamped range becomes the axis' Min and Max, and stats computed with
+// it default to RangeClamp, so the *WithStats builders clamp later values to
+// the same limits unless given WithOutOfRange. The builders that return
+// errors report ErrInvalidWinsorize unless 0 <= lower < upper <= 1.
+func WithWinsorize(lower, upper float64) BuildOption {
+	return func(o *buildOptions) { o.winsor, o.winsorSet = [2]float64{lower, upper}, true }
+}
+
+// winsorize returns vals clamped to their winsorize quantiles, or vals
+// itself without WithWinsorize. Missing values pass through.
+func (o buildOptions) winsorize(vals []float64) []float64 {
+	if !o.winsorSet || len(vals) == 0 {
+		return vals
+	}
+	sorted := make([]float64, 0, len(vals))
+	for _, v := range vals {
+		if !isMissing(v) {
+			sorted = append(sorted, v)
+		}
+	}
+	if len(sorted) == 0 {
+		return vals
+	}
+	sort.Float64s(sorted)
+	lo, hi := percentile(sorted, o.winsor[0]), percentile(sorted, o.winsor[1])
+	out := make([]float64, len(vals))
+	for i, v := range vals {
+		out[i] = v
+		if isMissing(v) {
+			continue
+		}
+		if v < lo {
+			out[i] = lo
+		} else if v > hi {
+			out[i] = hi
+		}
+	}
+	return out
+}
+
+// statsRange returns the range policy stats computed with these options
+// record: the explicit one, else RangeClamp for winsorized stats.
+func (o buildOptions) statsRange() RangePolicy {
+	if o.winsorSet && !o.outOfRangeSet {
+		return RangeClamp
+	}
+	return o.outOfRange
+}
+
+// validSigmoid reports whether a midpoint and width describe a usable curve.
+func validSigmoid(mid, width float64) bool {
+	return width > 0 && !math.IsInf(width, 1) && !math.IsNaN(mid) && !math.IsInf(mid, 0)
+}
+
+// axisStats computes the stats for one axis: the strategy's, plus the
+// sigmoid configured for the axis.
+func (o buildOptions) axisStats(axis int, vals []float64) AxisStats {
+	s := o.norm.axisStats(vals)
+	if sg, ok := o.sigmoids[axis]; ok && validSigmoid(sg[0], sg[1]) {
+		s.SigmoidMidpoint, s.SigmoidWidth = sg[0], sg[1]
+	}
+	if tr, ok := o.targets[axis]; ok && validTarget(tr[0], tr[1]) {
+		s.TargetLo, s.TargetHi = tr[0], tr[1]
+	}
+	return s
+}
+
+// axisTransforms returns the per-axis transforms for dim features, or nil if
+// there are none. Transforms for axes outside [0, dim) are dropped; they, and
+// invalid sigmoids and target ranges, are reported as ErrInvalidAxisTransform;
+// invalid winsorize quantiles as ErrInvalidWinsorize.
+func (o buildOptions) axisTransforms(dim int) ([]AxisTransform, error) {
+	var err error
+	for axis, sg := range o.sigmoids {
+		if axis < 0 || axis >= dim || !validSigmoid(sg[0], sg[1]) {
+			err = ErrInvalidAxisTransform
+		}
+	}
+	for axis, tr := range o.targets {
+		if axis < 0 || axis >= dim || !validTarget(tr[0], tr[1]) {
+			err = ErrInvalidAxisTransform
+		}
+	}
+	if o.winsorSet && !(o.winsor[0] >= 0 && o.winsor[0] < o.winsor[1] && o.winsor[1] <= 1) {
+		err = ErrInvalidWinsorize
+	}
+	if len(o.transforms) == 0 {
+		return nil, err
+	}
+	ts := make([]AxisTransform, dim)
+	for axis, x := range o.transforms {
+		if axis < 0 || axis >= dim {
+			err = ErrInvalidAxisTransform
+			continue
+		}
+		ts[axis] = x
+	}
+	return ts, err
+}
+
+// WithAxisMask leaves the features whose mask entry is false out of the
+// generated coordinates, so a ranking feature can be switched off for an A/B
+// experiment without rewriting the extractor closures or weights. The mask
+// has one entry per feature; points get one coordinate per true entry, in
+// feature order, followed by any categorical columns. Stats are still
+// computed for every feature, so the same NormStats serve every mask. The
+// builders report ErrInvalidAxisMask for a mask of the wrong length or one
+// that excludes every feature.
+func WithAxisMask(mask []bool) BuildOption {
+	return func(o *buildOptions) { o.mask = append([]bool(nil), mask...) }
+}
+
+// checkMask validates the axis mask against the number of features.
+func (o buildOptions) checkMask(features int) error {
+	if o.mask == nil {
+		return nil
+	}
+	if len(o.mask) != features {
+		return ErrInvalidAxisMask
+	}
+	for _, keep := range o.mask {
+		if keep {
+			return nil
+		}
+	}
+	return ErrInvalidAxisMask
+}
+
+// masked drops the coordinates of masked-out features from coords, in
+// place; coordinates past the mask, such as categorical columns, are kept.
+func (o buildOptions) masked(coords []float64) []float64 {
+	if o.mask == nil {
+		return coords
+	}
+	out := coords[:0]
+	for d, v := range coords {
+		if d >= len(o.mask) || o.mask[d] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func applyBuildOptions(opts []BuildOption) buildOptions {
+	var cfg buildOptions
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// axisStats summarises one axis' raw values for this strategy.
+func (n Normalization) axisStats(vals []float64) AxisStats {
+	var s AxisStats
+	s.Min, s.Max = minMax(vals)
+	if n == Robust && len(vals) > 0 {
+		sorted := append([]float64(nil), vals...)
+		sort.Float64s(sorted)
+		s.Median = percentile(sorted, 0.5)
+		s.IQR = percentile(sorted, 0.75) - percentile(sorted, 0.25)
+	}
+	if n == ZScore && len(vals) > 0 {
+		var sum float64
+		for _, v := range vals {
+			sum += v
+		}
+		s.Mean = sum / float64(len(vals))
+		var ss float64
+		for _, v := range vals {
+			d := v - s.Mean
+			ss += d * d
+		}
+		s.StdDev = math.Sqrt(ss / float64(len(vals)))
+	}
+	return s
+}
+
+// scale normalises v using the axis stats. A constant axis maps to 0.
+func (n Normalization) scale(v float64, s AxisStats) float64 {
+	if s.SigmoidWidth > 0 {
+		return 1 / (1 + math.Exp(-(v-s.SigmoidMidpoint)/s.SigmoidWidth))
+	}
+	switch n {
+	case ZScore:
+		if s.StdDev == 0 {
+			return 0
+		}
+		return (v - s.Mean) / s.StdDev
+	case Robust:
+		scale := s.IQR
+		if scale == 0 {
+			scale = s.Max - s.Min
+		}
+		if scale == 0 {
+			return 0
+		}
+		return (v - s.Median) / scale
+	}
+	return scale01(v, s.Min, s.Max)
+}
+
+// invert flips a normalised value so higher raw values become lower cost.
+func (n Normalization) invert(v float64) float64 {
+	if n == ZScore || n == Robust {
+		return -v
+	}
+	return 1 - v
+}
+
+// unscale maps a normalised value back to the (transformed) raw scale; it
+// undoes scale except where scale collapsed a constant axis.
+func (n Normalization) unscale(v float64, s AxisStats) float64 {
+	if s.SigmoidWidth > 0 {
+		return s.SigmoidMidpoint + s.SigmoidWidth*math.Log(v/(1-v))
+	}
+	switch n {
+	case ZScore:
+		return s.Mean + v*s.StdDev
+	case Robust:
+		scale := s.IQR
+		if scale == 0 {
+			scale = s.Max - s.Min
+		}
+		return s.Median + v*scale
+	}
+	return s.Min + v*(s.Max-s.Min)
+}
+
+// DenormalizeCoords maps built coordinates back to raw feature values, so
+// results and cluster centroids can be reported in the units they were
+// measured in (ms, hops, km) rather than as weighted normalised values. It
+// undoes the weights, target ranges, inversion, normalisation and any
+// transform recorded in stats, taking weights and invert as given to the
+// builder. Categorical columns after the numeric axes are ignored.
+//
+// An axis with weight 0, or one that was constant when the stats were
+// computed, carries no information and comes back as NaN or its constant
+// value respectively. A value the out-of-range policy clamped comes back at
+// the edge of the range. It returns nil if coords has fewer entries than
+// stats has axes, or weights or invert do not match the axes. Coordinates
+// built with WithAxisMask lack the masked axes and cannot be mapped back.
+func DenormalizeCoords(coords []float64, stats NormStats, weights []float64, invert []bool) []float64 {
+	dim := len(stats.Stats)
+	if len(coords) < dim || len(weights) != dim || len(invert) != dim {
+		return nil
+	}
+	out := make([]float64, dim)
+	for d, a := range stats.Stats {
+		if weights[d] == 0 {
+			out[d] = math.NaN()
+			continue
+		}
+		n := a.untarget(coords[d] / weights[d])
+		if invert[d] {
+			n = stats.Method.invert(n)
+		}
+		v := stats.Method.unscale(n, a)
+		if d < len(stats.Transforms) {
+			v = stats.Transforms[d].undo(v)
+		}
+		out[d] = v
+	}
+	return out
+}
+
+// MergeNormStats combines stats that workers computed over disjoint
+// partitions of the items, with the same features and options, into stats for
+// all of them, weighting each side by its Count. Min, Max, Mean and StdDev
+// (and mean imputation fills) merge exactly; Median and IQR cannot be merged
+// from summaries, so the result is the count-weighted average of each side's,
+// an approximation that is close when the partitions are similarly
+// distributed. Category labels are unioned. Method, Transforms, Missing,
+// OutOfRange, sigmoid parameters and target ranges are taken from a.
+//
+// A side with no stats, or a Count of 0 while the other's is positive, is
+// ignored. If the sides' dimensions differ, a is returned unchanged.
+func MergeNormStats(a, b NormStats) NormStats {
+	switch {
+	case len(b.Stats) == 0 || (b.Count == 0 && a.Count > 0):
+		return cloneNormStats(a)
+	case len(a.Stats) == 0 || (a.Count == 0 && b.Count > 0):
+		return cloneNormStats(b)
+	case len(a.Stats) != len(b.Stats):
+		return cloneNormStats(a)
+	}
+	wa, wb := float64(a.Count), float64(b.Count)
+	if wa+wb == 0 {
+		wa, wb = 1, 1 // stats built by hand carry no counts
+	}
+	fa, fb := wa/(wa+wb), wb/(wa+wb)
+	out := cloneNormStats(a)
+	out.Count = a.Count + b.Count
+	for i, x := range a.Stats {
+		y := b.Stats[i]
+		m := &out.Stats[i]
+		m.Min, m.Max = min(x.Min, y.Min), max(x.Max, y.Max)
+		m.Mean = fa*x.Mean + fb*y.Mean
+		dx, dy := x.Mean-m.Mean, y.Mean-m.Mean
+		m.StdDev = math.Sqrt(fa*(x.StdDev*x.StdDev+dx*dx) + fb*(y.StdDev*y.StdDev+dy*dy))
+		m.Median = fa*x.Median + fb*y.Median
+		m.IQR = fa*x.IQR + fb*y.IQR
+		if x.Fill != y.Fill {
+			m.Fill = fa*x.Fill + fb*y.Fill
+		}
+	}
+	if len(a.Categories) == len(b.Categories) {
+		for c := range out.Categories {
+			out.Categories[c] = categoryLabels(append(append([]string(nil), a.Categories[c]...), b.Categories[c]...))
+		}
+	}
+	return out
+}
+
+// cloneNormStats deep-copies s so merged stats share no slices with inputs.
+func cloneNormStats(s NormStats) NormStats {
+	s.Stats = append([]AxisStats(nil), s.Stats...)
+	s.Transforms = append([]AxisTransform(nil), s.Transforms...)
+	if s.Categories != nil {
+		cats := make([][]string, len(s.Categories))
+		for i, c := range s.Categories {
+			cats[i] = append([]string(nil), c...)
+		}
+		s.Categories = cats
+	}
+	return s
+}
+
+// ComputeNormStats derives per-axis Min, Max, Mean and StdDev (population)
+// from the coordinates currently in the tree, with Count set to Len(). Run it
+// periodically on a long-lived index to see how far its points have drifted
+// from the stats they were built with, or to re-normalise against them. The
+// stats describe stored coordinates, so for points built with Build* they
+// are in normalised, weighted space. Method is MinMax; set it to ZScore to
+// use the mean and standard deviation. An empty tree yields zero stats of
+// the tree's dimension.
+func (t *KDTree[T]) ComputeNormStats() NormStats {
+	if v := t.cowView(); v != nil {
+		return v.ComputeNormStats()
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	stats := make([]AxisStats, t.dim)
+	if len(t.points) == 0 {
+		return NormStats{Stats: stats}
+	}
+	for d := range stats {
+		stats[d].Min, stats[d].Max = math.Inf(1), math.Inf(-1)
+	}
+	for _, p := range t.points {
+		for d, v := range p.Coords {
+			s := &stats[d]
+			s.Min, s.Max = min(s.Min, v), max(s.Max, v)
+			s.Mean += v
+		}
+	}
+	n := float64(len(t.points))
+	for d := range stats {
+		stats[d].Mean /= n
+	}
+	for _, p := range t.points {
+		for d, v := range p.Coords {
+			dv := v - stats[d].Mean
+			stats[d].StdDev += dv * dv
+		}
+	}
+	for d := range stats {
+		stats[d].StdDev = math.Sqrt(stats[d].StdDev / n)
+	}
+	return NormStats{Stats: stats, Count: len(t.points)}
+}
+
+// RangePolicy decides what the *WithStats builders do with a value outside
+// the [Min, Max] range its axis stats were computed over, as happens when
+// saved stats meet streaming inserts.
+type RangePolicy uint8
+
+const (
+	// RangeExtend normalises the value as is, extrapolating beyond the
+	// fitted range (outside [0,1] for MinMax). It is the default.
+	RangeExtend RangePolicy = iota
+	// RangeClamp clamps the value to [Min, Max] first, so new extremes land
+	// on the edge of the fitted space.
+	RangeClamp
+	// RangeError fails the build with ErrOutOfRange.
+	RangeError
+)
+
+// ErrOutOfRange indicates a value fell outside its axis stats under RangeError.
+var ErrOutOfRange = errors.New("kdtree: feature value outside normalisation range")
+
+// String returns the policy name.
+func (p RangePolicy) String() string {
+	switch p {
+	case RangeExtend:
+		return "extend"
+	case RangeClamp:
+		return "clamp"
+	case RangeError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// WithOutOfRange sets the RangePolicy. The ComputeNormStats* helpers record
+// it in NormStats.OutOfRange, which the *WithStats builders follow unless
+// given this option themselves. The range is checked after any transform;
+// missing values are left to the missing-value policy.
+func WithOutOfRange(p RangePolicy) BuildOption {
+	return func(o *buildOptions) { o.outOfRange, o.outOfRangeSet = p, true }
+}
+
+// rangePolicy returns the policy for building with stats: the option if set,
+// else the one the stats were computed with.
+func (o buildOptions) rangePolicy(stats NormStats) RangePolicy {
+	if o.outOfRangeSet {
+		return o.outOfRange
+	}
+	return stats.OutOfRange
+}
+
+// scaleAxis transforms raw value v of axis d of item i, applies the range
+// policy and normalises it.
+func (s NormStats) scaleAxis(i, d int, v float64, rp RangePolicy) (float64, error) {
+	v, err := s.boundAxis(i, d, transformAt(s.Transforms, d, v), rp)
+	if err != nil {
+		return 0, err
+	}
+	return s.Method.scale(v, s.Stats[d]), nil
+}
+
+// boundAxis applies the range policy to the transformed value v of axis d of
+// item i.
+func (s NormStats) boundAxis(i, d int, v float64, rp RangePolicy) (float64, error) {
+	a := s.Stats[d]
+	if v < a.Min || v > a.Max {
+		switch rp {
+		case RangeClamp:
+			v = math.Max(a.Min, math.Min(a.Max, v))
+		case RangeError:
+			return 0, fmt.Errorf("%w: item %d, feature %d: %v not in [%v, %v]", ErrOutOfRange, i, d, v, a.Min, a.Max)
+		}
+	}
+	return v, nil
+}
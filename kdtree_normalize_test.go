@@ -0,0 +1,518 @@
+package poindexter
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type normRec struct {
+	id         string
+	ping, hops float64
+}
+
+func normRecs() []normRec {
+	// nine ordinary pings and one 10-second outlier
+	recs := []normRec{{"out", 10000, 1}}
+	for i := 0; i < 9; i++ {
+		recs = append(recs, normRec{string(rune('a' + i)), 20 + float64(i)*10, float64(i % 3)})
+	}
+	return recs
+}
+
+func TestBuildNDZScore(t *testing.T) {
+	recs := normRecs()
+	features := []func(normRec) float64{
+		func(r normRec) float64 { return r.ping },
+		func(r normRec) float64 { return r.hops },
+	}
+	id := func(r normRec) string { return r.id }
+	pts, err := BuildND(recs, id, features, []float64{1, 1}, []bool{false, true}, WithNormalization(ZScore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for d := 0; d < 2; d++ {
+		var sum, ss float64
+		for _, p := range pts {
+			sum += p.Coords[d]
+		}
+		mean := sum / float64(len(pts))
+		for _, p := range pts {
+			ss += (p.Coords[d] - mean) * (p.Coords[d] - mean)
+		}
+		if math.Abs(mean) > 1e-12 || math.Abs(ss/float64(len(pts))-1) > 1e-12 {
+			t.Fatalf("axis %d: mean %v variance %v, want 0 and 1", d, mean, ss/float64(len(pts)))
+		}
+	}
+	// inverted hops: the fewest hops get the highest coordinate
+	if pts[1].Coords[1] <= pts[2].Coords[1] {
+		t.Fatalf("inverted axis not negated: %v vs %v", pts[1].Coords, pts[2].Coords)
+	}
+
+	// under min-max the outlier squeezes every other ping below 0.01; z-scores
+	// keep them apart by more than that
+	mm, _ := BuildND(recs, id, features, []float64{1, 1}, []bool{false, false})
+	if spread := mm[9].Coords[0] - mm[1].Coords[0]; spread > 0.01 {
+		t.Fatalf("min-max spread = %v, expected the outlier to compress it", spread)
+	}
+	if spread := pts[9].Coords[0] - pts[1].Coords[0]; spread < 0.02 {
+		t.Fatalf("z-score spread = %v, want more than min-max", spread)
+	}
+}
+
+func TestNormStatsMethodReused(t *testing.T) {
+	recs := normRecs()
+	ping := func(r normRec) float64 { return r.ping }
+	hops := func(r normRec) float64 { return r.hops }
+	id := func(r normRec) string { return r.id }
+
+	stats := ComputeNormStats2D(recs, ping, hops, WithNormalization(ZScore))
+	if stats.Method != ZScore || stats.Stats[1].Mean != 1 || stats.Stats[0].Min != 20 || stats.Stats[0].Max != 10000 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	direct, _ := Build2D(recs, id, ping, hops, [2]float64{1, 2}, [2]bool{true, false}, WithNormalization(ZScore))
+	reused, _ := Build2DWithStats(recs, id, ping, hops, [2]float64{1, 2}, [2]bool{true, false}, stats)
+	nd, _ := ComputeNormStatsND(recs, []func(normRec) float64{ping, hops}, WithNormalization(ZScore))
+	viaND, _ := BuildNDWithStats(recs, id, []func(normRec) float64{ping, hops}, []float64{1, 2}, []bool{true, false}, nd)
+	for i := range direct {
+		for d := 0; d < 2; d++ {
+			if direct[i].Coords[d] != reused[i].Coords[d] || direct[i].Coords[d] != viaND[i].Coords[d] {
+				t.Fatalf("point %d axis %d: Build2D %v, WithStats %v, ND %v", i, d, direct[i].Coords, reused[i].Coords, viaND[i].Coords)
+			}
+		}
+	}
+}
+
+func TestZScoreConstantAxis(t *testing.T) {
+	recs := []normRec{{"a", 5, 1}, {"b", 5, 2}}
+	pts, err := Build2D(recs, func(r normRec) string { return r.id },
+		func(r normRec) float64 { return r.ping }, func(r normRec) float64 { return r.hops },
+		[2]float64{1, 1}, [2]bool{}, WithNormalization(ZScore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pts[0].Coords[0] != 0 || pts[1].Coords[0] != 0 || pts[0].Coords[1] != -1 || pts[1].Coords[1] != 1 {
+		t.Fatalf("coords = %v %v, want [0 -1] [0 1]", pts[0].Coords, pts[1].Coords)
+	}
+}
+
+func TestBuildNDRobust(t *testing.T) {
+	recs := normRecs()
+	ping := func(r normRec) float64 { return r.ping }
+	id := func(r normRec) string { return r.id }
+	stats, _ := ComputeNormStatsND(recs, []func(normRec) float64{ping}, WithNormalization(Robust))
+	if s := stats.Stats[0]; stats.Method != Robust || s.Median != 65 || s.IQR != 45 {
+		t.Fatalf("stats = %+v, want median 65 and IQR 45", stats)
+	}
+	pts, err := BuildND(recs, id, []func(normRec) float64{ping}, []float64{1}, []bool{false}, WithNormalization(Robust))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the outlier sits far out on its own; the ordinary peers keep a spread
+	// of 80/45 instead of being squeezed together
+	if got := pts[9].Coords[0] - pts[1].Coords[0]; math.Abs(got-80.0/45) > 1e-12 {
+		t.Fatalf("spread = %v, want %v", got, 80.0/45)
+	}
+	if pts[0].Coords[0] < 100 {
+		t.Fatalf("outlier at %v, want far from the rest", pts[0].Coords[0])
+	}
+	inv, _ := BuildND(recs, id, []func(normRec) float64{ping}, []float64{1}, []bool{true}, WithNormalization(Robust))
+	if inv[3].Coords[0] != -pts[3].Coords[0] {
+		t.Fatalf("inverted = %v, want %v", inv[3].Coords[0], -pts[3].Coords[0])
+	}
+}
+
+func TestRobustZeroIQRFallsBackToRange(t *testing.T) {
+	recs := []normRec{{"a", 1, 0}, {"b", 1, 0}, {"c", 1, 0}, {"d", 1, 0}, {"e", 5, 0}}
+	pts, _ := BuildND(recs, func(r normRec) string { return r.id },
+		[]func(normRec) float64{func(r normRec) float64 { return r.ping }, func(r normRec) float64 { return r.hops }},
+		[]float64{1, 1}, []bool{false, false}, WithNormalization(Robust))
+	if pts[0].Coords[0] != 0 || pts[4].Coords[0] != 1 {
+		t.Fatalf("coords = %v %v, want 0 and 1 (scaled by max-min)", pts[0].Coords, pts[4].Coords)
+	}
+	if pts[4].Coords[1] != 0 {
+		t.Fatalf("constant axis = %v, want 0", pts[4].Coords[1])
+	}
+}
+
+func TestWithAxisTransform(t *testing.T) {
+	type rec struct {
+		id      string
+		bw, geo float64
+	}
+	recs := []rec{{"a", 0, 1}, {"b", math.E - 1, math.E}, {"c", math.Exp(2) - 1, math.Exp(2)}}
+	id := func(r rec) string { return r.id }
+	bw := func(r rec) float64 { return r.bw }
+	geo := func(r rec) float64 { return r.geo }
+	opts := []BuildOption{WithAxisTransform(0, Log1p), WithAxisTransform(1, Log)}
+
+	pts, err := Build2D(recs, id, bw, geo, [2]float64{1, 1}, [2]bool{}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// both axes become 0, 1, 2 before min-max scaling
+	for i, want := range []float64{0, 0.5, 1} {
+		for d := 0; d < 2; d++ {
+			if math.Abs(pts[i].Coords[d]-want) > 1e-12 {
+				t.Fatalf("point %d axis %d = %v, want %v", i, d, pts[i].Coords[d], want)
+			}
+		}
+	}
+
+	stats := ComputeNormStats2D(recs, bw, geo, opts...)
+	if len(stats.Transforms) != 2 || stats.Transforms[0] != Log1p || stats.Transforms[1] != Log || math.Abs(stats.Stats[0].Max-2) > 1e-12 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	reused, _ := Build2DWithStats(recs, id, bw, geo, [2]float64{1, 1}, [2]bool{}, stats)
+	features := []func(rec) float64{bw, geo}
+	nd, err := BuildND(recs, id, features, []float64{1, 1}, []bool{false, false}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range pts {
+		for d := 0; d < 2; d++ {
+			if reused[i].Coords[d] != pts[i].Coords[d] || nd[i].Coords[d] != pts[i].Coords[d] {
+				t.Fatalf("point %d: Build2D %v, WithStats %v, BuildND %v", i, pts[i].Coords, reused[i].Coords, nd[i].Coords)
+			}
+		}
+	}
+
+	if _, err := BuildND(recs, id, features, []float64{1, 1}, []bool{false, false}, WithAxisTransform(2, Log)); err != ErrInvalidAxisTransform {
+		t.Fatalf("BuildND out-of-range axis: err = %v, want ErrInvalidAxisTransform", err)
+	}
+	if _, err := Build2D(recs, id, bw, geo, [2]float64{1, 1}, [2]bool{}, WithAxisTransform(-1, Log)); err != ErrInvalidAxisTransform {
+		t.Fatalf("Build2D negative axis: err = %v, want ErrInvalidAxisTransform", err)
+	}
+}
+
+func TestWithAxisSigmoid(t *testing.T) {
+	recs := normRecs()
+	id := func(r normRec) string { return r.id }
+	ping := func(r normRec) float64 { return r.ping }
+	hops := func(r normRec) float64 { return r.hops }
+	pts, err := Build2D(recs, id, ping, hops, [2]float64{1, 1}, [2]bool{}, WithAxisSigmoid(0, 250, 60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the 10s outlier saturates at 1 without squeezing the ordinary pings
+	if pts[0].Coords[0] < 0.999999 {
+		t.Fatalf("outlier = %v, want ~1", pts[0].Coords[0])
+	}
+	if want := 1 / (1 + math.Exp(-(20.0-250)/60)); math.Abs(pts[1].Coords[0]-want) > 1e-12 {
+		t.Fatalf("20ms = %v, want %v", pts[1].Coords[0], want)
+	}
+	// the other axis keeps min-max scaling
+	if pts[2].Coords[1] != 0.5 {
+		t.Fatalf("hops axis = %v, want 0.5", pts[2].Coords[1])
+	}
+
+	stats := ComputeNormStats2D(recs, ping, hops, WithAxisSigmoid(0, 250, 60))
+	if stats.Stats[0].SigmoidMidpoint != 250 || stats.Stats[0].SigmoidWidth != 60 || stats.Stats[1].SigmoidWidth != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	reused, _ := Build2DWithStats(recs, id, ping, hops, [2]float64{1, 1}, [2]bool{}, stats)
+	for i := range pts {
+		if reused[i].Coords[0] != pts[i].Coords[0] {
+			t.Fatalf("point %d: WithStats %v, Build2D %v", i, reused[i].Coords, pts[i].Coords)
+		}
+	}
+
+	features := []func(normRec) float64{ping, hops}
+	for _, opt := range []BuildOption{WithAxisSigmoid(0, 250, 0), WithAxisSigmoid(0, 250, math.Inf(1)), WithAxisSigmoid(2, 250, 60)} {
+		if _, err := BuildND(recs, id, features, []float64{1, 1}, []bool{false, false}, opt); err != ErrInvalidAxisTransform {
+			t.Fatalf("err = %v, want ErrInvalidAxisTransform", err)
+		}
+	}
+}
+
+func TestMergeNormStats(t *testing.T) {
+	recs := normRecs()
+	features := []func(normRec) float64{
+		func(r normRec) float64 { return r.ping },
+		func(r normRec) float64 { return r.hops },
+	}
+	opts := []BuildOption{WithNormalization(ZScore), WithMissingValuePolicy(MissingImputeMean)}
+	whole, _ := ComputeNormStatsND(recs, features, opts...)
+	left, _ := ComputeNormStatsND(recs[:3], features, opts...)
+	right, _ := ComputeNormStatsND(recs[3:], features, opts...)
+	merged := MergeNormStats(left, right)
+	if merged.Count != len(recs) || merged.Method != ZScore || merged.Missing != MissingImputeMean {
+		t.Fatalf("merged = %+v", merged)
+	}
+	for d := range whole.Stats {
+		w, m := whole.Stats[d], merged.Stats[d]
+		if w.Min != m.Min || w.Max != m.Max {
+			t.Fatalf("axis %d: min/max %v/%v, want %v/%v", d, m.Min, m.Max, w.Min, w.Max)
+		}
+		for _, pair := range [][2]float64{{w.Mean, m.Mean}, {w.StdDev, m.StdDev}, {w.Fill, m.Fill}} {
+			if math.Abs(pair[0]-pair[1]) > 1e-9 {
+				t.Fatalf("axis %d: merged %+v, want %+v", d, m, w)
+			}
+		}
+	}
+	if left.Stats[0].Min != 20 || len(left.Stats) != 2 {
+		t.Fatalf("merge modified its input: %+v", left)
+	}
+
+	// an empty shard contributes nothing
+	empty, _ := ComputeNormStatsND([]normRec{}, features, opts...)
+	if got := MergeNormStats(empty, whole); !reflect.DeepEqual(got, whole) {
+		t.Fatalf("merge with empty shard = %+v, want %+v", got, whole)
+	}
+	if got := MergeNormStats(whole, NormStats{}); !reflect.DeepEqual(got, whole) {
+		t.Fatalf("merge with zero stats = %+v, want %+v", got, whole)
+	}
+}
+
+func TestMergeNormStatsRobustAndCategories(t *testing.T) {
+	type rec struct {
+		v      float64
+		region string
+	}
+	var a, b []rec
+	for i := 0; i < 100; i++ {
+		a = append(a, rec{float64(i), "eu"})
+		b = append(b, rec{float64(i) + 0.5, "us"})
+	}
+	features := []func(rec) float64{func(r rec) float64 { return r.v }}
+	opts := []BuildOption{WithNormalization(Robust), WithCategoricalFeature(func(r rec) string { return r.region }, 1)}
+	sa, _ := ComputeNormStatsND(a, features, opts...)
+	sb, _ := ComputeNormStatsND(b, features, opts...)
+	whole, _ := ComputeNormStatsND(append(append([]rec(nil), a...), b...), features, opts...)
+	merged := MergeNormStats(sa, sb)
+	// similarly distributed shards: median and IQR land close to the truth
+	if math.Abs(merged.Stats[0].Median-whole.Stats[0].Median) > 0.5 || math.Abs(merged.Stats[0].IQR-whole.Stats[0].IQR) > 1 {
+		t.Fatalf("merged %+v, whole %+v", merged.Stats[0], whole.Stats[0])
+	}
+	if !reflect.DeepEqual(merged.Categories, [][]string{{"eu", "us"}}) {
+		t.Fatalf("categories = %v", merged.Categories)
+	}
+}
+
+func TestKDTreeComputeNormStats(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[int]{
+		{ID: "a", Coords: []float64{0, 10}},
+		{ID: "b", Coords: []float64{2, 10}},
+		{ID: "c", Coords: []float64{4, 40}},
+	})
+	stats := tr.ComputeNormStats()
+	want := []AxisStats{
+		{Min: 0, Max: 4, Mean: 2, StdDev: math.Sqrt(8.0 / 3)},
+		{Min: 10, Max: 40, Mean: 20, StdDev: math.Sqrt(200)},
+	}
+	if stats.Count != 3 || len(stats.Stats) != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	for d, w := range want {
+		g := stats.Stats[d]
+		if g.Min != w.Min || g.Max != w.Max || g.Mean != w.Mean || math.Abs(g.StdDev-w.StdDev) > 1e-12 {
+			t.Fatalf("axis %d = %+v, want %+v", d, g, w)
+		}
+	}
+
+	// it follows the live point set
+	tr.DeleteByID("c")
+	tr.Insert(KDPoint[int]{ID: "d", Coords: []float64{-2, 10}})
+	if s := tr.ComputeNormStats().Stats[0]; s.Min != -2 || s.Max != 2 || s.Mean != 0 {
+		t.Fatalf("after updates axis 0 = %+v", s)
+	}
+	if s := tr.Snapshot().ComputeNormStats(); s.Count != 3 || s.Stats[1].Max != 10 {
+		t.Fatalf("view stats = %+v", s)
+	}
+
+	empty, _ := NewKDTreeFromDim[int](3)
+	if s := empty.ComputeNormStats(); len(s.Stats) != 3 || s.Count != 0 || s.Stats[0] != (AxisStats{}) {
+		t.Fatalf("empty tree stats = %+v", s)
+	}
+}
+
+func TestOutOfRangePolicy(t *testing.T) {
+	recs := normRecs()[1:] // pings 20..100, hops 0..2
+	ping := func(r normRec) float64 { return r.ping }
+	hops := func(r normRec) float64 { return r.hops }
+	id := func(r normRec) string { return r.id }
+	later := []normRec{{"slow", 180, 1}}
+
+	stats := ComputeNormStats2D(recs, ping, hops)
+	pts, err := Build2DWithStats(later, id, ping, hops, [2]float64{1, 1}, [2]bool{}, stats)
+	if err != nil || pts[0].Coords[0] != 2 {
+		t.Fatalf("extend: %v, %v", pts, err)
+	}
+	pts, err = Build2DWithStats(later, id, ping, hops, [2]float64{1, 1}, [2]bool{}, stats, WithOutOfRange(RangeClamp))
+	if err != nil || pts[0].Coords[0] != 1 || pts[0].Coords[1] != 0.5 {
+		t.Fatalf("clamp: %v, %v", pts, err)
+	}
+	if _, err := Build2DWithStats(later, id, ping, hops, [2]float64{1, 1}, [2]bool{}, stats, WithOutOfRange(RangeError)); !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("error: err = %v", err)
+	}
+
+	// the policy recorded in the stats applies unless overridden
+	stats = ComputeNormStats2D(recs, ping, hops, WithOutOfRange(RangeClamp))
+	if stats.OutOfRange != RangeClamp {
+		t.Fatalf("stats.OutOfRange = %v", stats.OutOfRange)
+	}
+	pts, err = Build2DWithStats(later, id, ping, hops, [2]float64{1, 1}, [2]bool{}, stats)
+	if err != nil || pts[0].Coords[0] != 1 {
+		t.Fatalf("stats clamp: %v, %v", pts, err)
+	}
+	pts, err = Build2DWithStats(later, id, ping, hops, [2]float64{1, 1}, [2]bool{}, stats, WithOutOfRange(RangeExtend))
+	if err != nil || pts[0].Coords[0] != 2 {
+		t.Fatalf("override to extend: %v, %v", pts, err)
+	}
+
+	// the N-D builder checks after the transform
+	features := []func(normRec) float64{ping, hops}
+	nd, err := ComputeNormStatsND(recs, features, WithAxisTransform(0, Log1p), WithOutOfRange(RangeError))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BuildNDWithStats(later, id, features, []float64{1, 1}, []bool{false, false}, nd); !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("ND error: err = %v", err)
+	}
+	pts, err = BuildNDWithStats(later, id, features, []float64{1, 1}, []bool{false, false}, nd, WithOutOfRange(RangeClamp))
+	if err != nil || pts[0].Coords[0] != 1 {
+		t.Fatalf("ND clamp: %v, %v", pts, err)
+	}
+}
+
+func TestDenormalizeCoords(t *testing.T) {
+	recs := normRecs()
+	id := func(r normRec) string { return r.id }
+	features := []func(normRec) float64{
+		func(r normRec) float64 { return r.ping },
+		func(r normRec) float64 { return r.hops },
+	}
+	weights, invert := []float64{2, 0.5}, []bool{true, false}
+	for _, opts := range [][]BuildOption{
+		nil,
+		{WithNormalization(ZScore)},
+		{WithNormalization(Robust), WithAxisTransform(0, Log1p)},
+		{WithAxisTransform(0, Log), WithAxisSigmoid(1, 1, 0.5)},
+		{WithCategoricalFeature(func(r normRec) string { return r.id }, 1)},
+	} {
+		pts, stats, err := BuildNDWithComputedStats(recs, id, features, weights, invert, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, p := range pts {
+			raw := DenormalizeCoords(p.Coords, stats, weights, invert)
+			want := []float64{recs[i].ping, recs[i].hops}
+			for d := range want {
+				if math.Abs(raw[d]-want[d]) > 1e-9*math.Max(1, math.Abs(want[d])) {
+					t.Fatalf("%v: point %d axis %d = %v, want %v", stats.Method, i, d, raw[d], want[d])
+				}
+			}
+		}
+	}
+
+	stats := ComputeNormStats2D(recs, features[0], func(normRec) float64 { return 3 })
+	raw := DenormalizeCoords([]float64{0.5, 0}, stats, []float64{0, 1}, []bool{false, false})
+	if !math.IsNaN(raw[0]) || raw[1] != 3 {
+		t.Fatalf("zero weight / constant axis = %v", raw)
+	}
+	if DenormalizeCoords([]float64{1}, stats, []float64{1, 1}, []bool{false, false}) != nil {
+		t.Fatal("short coords should give nil")
+	}
+	if DenormalizeCoords([]float64{1, 1}, stats, []float64{1}, []bool{false, false}) != nil {
+		t.Fatal("short weights should give nil")
+	}
+}
+
+func TestWithTargetRange(t *testing.T) {
+	recs := normRecs()[1:] // pings 20..100, hops 0..2
+	id := func(r normRec) string { return r.id }
+	ping := func(r normRec) float64 { return r.ping }
+	hops := func(r normRec) float64 { return r.hops }
+	features := []func(normRec) float64{ping, hops}
+
+	pts, stats, err := BuildNDWithComputedStats(recs, id, features, []float64{1, 2}, []bool{true, false}, WithTargetRange(0, -1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ping 20 is inverted to 1 and lands on hi; hops keep [0,1] and weight 2
+	if got := pts[0].Coords; got[0] != 1 || got[1] != 0 {
+		t.Fatalf("first point = %v", got)
+	}
+	if got := pts[8].Coords; got[0] != -1 || got[1] != 2 {
+		t.Fatalf("last point = %v", got)
+	}
+	if a := stats.Stats[0]; a.TargetLo != -1 || a.TargetHi != 1 || stats.Stats[1].TargetHi != 0 {
+		t.Fatalf("stats = %+v", stats.Stats)
+	}
+	for i, p := range pts {
+		raw := DenormalizeCoords(p.Coords, stats, []float64{1, 2}, []bool{true, false})
+		if math.Abs(raw[0]-recs[i].ping) > 1e-9 || math.Abs(raw[1]-recs[i].hops) > 1e-9 {
+			t.Fatalf("denormalised %d = %v", i, raw)
+		}
+	}
+
+	// the fixed-dimension builders and their WithStats forms agree
+	p2, err := Build2D(recs, id, ping, hops, [2]float64{1, 1}, [2]bool{}, WithTargetRange(1, -1, 1))
+	if err != nil || p2[0].Coords[1] != -1 || p2[2].Coords[1] != 1 {
+		t.Fatalf("Build2D: %v, %v", p2, err)
+	}
+	s2 := ComputeNormStats2D(recs, ping, hops, WithTargetRange(1, -1, 1))
+	w2, err := Build2DWithStats(recs, id, ping, hops, [2]float64{1, 1}, [2]bool{}, s2)
+	if err != nil || !reflect.DeepEqual(w2, p2) {
+		t.Fatalf("Build2DWithStats: %v, %v", w2, err)
+	}
+
+	pp := NewPipeline[normRec]().Select(ping, hops).TargetRange(0, -1, 1)
+	pps, err := pp.Build(recs)
+	if err != nil || pps[0].Coords[0] != -1 {
+		t.Fatalf("pipeline: %v, %v", pps, err)
+	}
+
+	for _, opt := range []BuildOption{WithTargetRange(0, 1, 1), WithTargetRange(0, 1, -1), WithTargetRange(2, 0, 1), WithTargetRange(0, math.Inf(-1), 1)} {
+		if _, err := BuildND(recs, id, features, []float64{1, 1}, []bool{false, false}, opt); err != ErrInvalidAxisTransform {
+			t.Fatalf("invalid range: err = %v", err)
+		}
+	}
+}
+
+func TestWithWinsorize(t *testing.T) {
+	recs := normRecs() // pings 20..100 and one 10000 outlier
+	id := func(r normRec) string { return r.id }
+	ping := func(r normRec) float64 { return r.ping }
+	hops := func(r normRec) float64 { return r.hops }
+	features := []func(normRec) float64{ping, hops}
+
+	pts, stats, err := BuildNDWithComputedStats(recs, id, features, []float64{1, 1}, []bool{false, false}, WithWinsorize(0.1, 0.8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the 10th and 80th percentiles of the pings are 29 and 92
+	if a := stats.Stats[0]; math.Abs(a.Min-29) > 1e-9 || math.Abs(a.Max-92) > 1e-9 {
+		t.Fatalf("ping stats = %+v", a)
+	}
+	if stats.OutOfRange != RangeClamp {
+		t.Fatalf("OutOfRange = %v, want clamp", stats.OutOfRange)
+	}
+	if pts[0].Coords[0] != 1 || pts[1].Coords[0] != 0 {
+		t.Fatalf("outlier = %v, slowest ordinary = %v", pts[0].Coords, pts[1].Coords)
+	}
+	if got, want := pts[5].Coords[0], (60.0-29)/(92-29); math.Abs(got-want) > 1e-12 {
+		t.Fatalf("ping 60 -> %v, want %v", got, want)
+	}
+
+	// the fixed-dimension builders winsorize the same way
+	p2, err := Build2D(recs, id, ping, hops, [2]float64{1, 1}, [2]bool{}, WithWinsorize(0.1, 0.8))
+	if err != nil || !reflect.DeepEqual(p2, pts) {
+		t.Fatalf("Build2D: %v, %v", p2, err)
+	}
+	s2 := ComputeNormStats2D(recs, ping, hops, WithWinsorize(0.1, 0.8), WithOutOfRange(RangeExtend))
+	if s2.OutOfRange != RangeExtend || math.Abs(s2.Stats[0].Max-92) > 1e-9 {
+		t.Fatalf("explicit policy: %+v", s2)
+	}
+	later, _ := Build2DWithStats(recs[:1], id, ping, hops, [2]float64{1, 1}, [2]bool{}, s2)
+	if !(later[0].Coords[0] > 1) {
+		t.Fatalf("extend policy should extrapolate: %v", later[0].Coords)
+	}
+
+	for _, q := range [][2]float64{{-0.1, 0.9}, {0.5, 0.5}, {0.1, 1.5}} {
+		if _, err := BuildND(recs, id, features, []float64{1, 1}, []bool{false, false}, WithWinsorize(q[0], q[1])); err != ErrInvalidWinsorize {
+			t.Fatalf("quantiles %v: err = %v", q, err)
+		}
+	}
+}
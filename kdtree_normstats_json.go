@@ -0,0 +1,134 @@
+package poindexter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// normStatsJSONVersion is the current NormStats JSON format version.
+const normStatsJSONVersion = 1
+
+// ErrInvalidNormStats indicates encoded normalisation stats are malformed.
+var ErrInvalidNormStats = errors.New("kdtree: invalid normalisation stats")
+
+// axisStatsJSON is the wire form of an AxisStats.
+type axisStatsJSON struct {
+	Min             float64 `json:"min"`
+	Max             float64 `json:"max"`
+	Mean            float64 `json:"mean,omitempty"`
+	StdDev          float64 `json:"stddev,omitempty"`
+	Median          float64 `json:"median,omitempty"`
+	IQR             float64 `json:"iqr,omitempty"`
+	SigmoidMidpoint float64 `json:"sigmoidMidpoint,omitempty"`
+	SigmoidWidth    float64 `json:"sigmoidWidth,omitempty"`
+	TargetLo        float64 `json:"targetLo,omitempty"`
+	TargetHi        float64 `json:"targetHi,omitempty"`
+	Fill            float64 `json:"fill,omitempty"`
+}
+
+// normStatsJSON is the wire form of a NormStats. Enumerations are stored by
+// name so the encoding survives reordered constants.
+type normStatsJSON struct {
+	Version    int             `json:"version"`
+	Method     string          `json:"method"`
+	Axes       []axisStatsJSON `json:"axes"`
+	Transforms []string        `json:"transforms,omitempty"`
+	Categories [][]string      `json:"categories,omitempty"`
+	Missing    string          `json:"missing,omitempty"`
+	OutOfRange string          `json:"outOfRange,omitempty"`
+	Count      int             `json:"count,omitempty"`
+}
+
+// MarshalJSON encodes the stats with a format version, so they can be saved
+// next to a serialized tree and checked when loaded. Stats holding NaN or
+// ±Inf cannot be encoded.
+func (s NormStats) MarshalJSON() ([]byte, error) {
+	w := normStatsJSON{
+		Version:    normStatsJSONVersion,
+		Method:     s.Method.String(),
+		Axes:       make([]axisStatsJSON, len(s.Stats)),
+		Categories: s.Categories,
+		Count:      s.Count,
+	}
+	for i, a := range s.Stats {
+		w.Axes[i] = axisStatsJSON(a)
+	}
+	for _, x := range s.Transforms {
+		w.Transforms = append(w.Transforms, x.String())
+	}
+	if s.Missing != MissingPropagate {
+		w.Missing = s.Missing.String()
+	}
+	if s.OutOfRange != RangeExtend {
+		w.OutOfRange = s.OutOfRange.String()
+	}
+	return json.Marshal(w)
+}
+
+// UnmarshalJSON decodes stats written by MarshalJSON. It returns
+// ErrUnsupportedVersion for a newer format and ErrInvalidNormStats for
+// unknown strategy, transform or policy names, a transform list that does not
+// match the axes, a negative count, or an axis whose min exceeds its max,
+// whose spread or sigmoid width is negative, or whose target range is
+// reversed.
+func (s *NormStats) UnmarshalJSON(data []byte) error {
+	var w normStatsJSON
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	if w.Version > normStatsJSONVersion {
+		return ErrUnsupportedVersion
+	}
+	out := NormStats{Categories: w.Categories, Count: w.Count}
+	var ok bool
+	if out.Method, ok = parseEnum[Normalization](w.Method); !ok {
+		return fmt.Errorf("%w: unknown method %q", ErrInvalidNormStats, w.Method)
+	}
+	if w.Missing != "" {
+		if out.Missing, ok = parseEnum[MissingValuePolicy](w.Missing); !ok {
+			return fmt.Errorf("%w: unknown missing-value policy %q", ErrInvalidNormStats, w.Missing)
+		}
+	}
+	if w.OutOfRange != "" {
+		if out.OutOfRange, ok = parseEnum[RangePolicy](w.OutOfRange); !ok {
+			return fmt.Errorf("%w: unknown range policy %q", ErrInvalidNormStats, w.OutOfRange)
+		}
+	}
+	if len(w.Transforms) > 0 && len(w.Transforms) != len(w.Axes) {
+		return fmt.Errorf("%w: %d transforms for %d axes", ErrInvalidNormStats, len(w.Transforms), len(w.Axes))
+	}
+	for _, name := range w.Transforms {
+		x, ok := parseEnum[AxisTransform](name)
+		if !ok {
+			return fmt.Errorf("%w: unknown transform %q", ErrInvalidNormStats, name)
+		}
+		out.Transforms = append(out.Transforms, x)
+	}
+	if w.Count < 0 {
+		return fmt.Errorf("%w: negative count", ErrInvalidNormStats)
+	}
+	out.Stats = make([]AxisStats, len(w.Axes))
+	for i, a := range w.Axes {
+		if a.Min > a.Max || a.StdDev < 0 || a.IQR < 0 || a.SigmoidWidth < 0 || a.TargetLo > a.TargetHi {
+			return fmt.Errorf("%w: axis %d", ErrInvalidNormStats, i)
+		}
+		out.Stats[i] = AxisStats(a)
+	}
+	*s = out
+	return nil
+}
+
+// parseEnum finds the value of a small enumeration whose String method
+// returns name.
+func parseEnum[E interface {
+	~uint8
+	String() string
+}](name string) (E, bool) {
+	for e := E(0); e.String() != "unknown"; e++ {
+		if e.String() == name {
+			return e, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,79 @@
+package poindexter
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormStatsJSONRoundTrip(t *testing.T) {
+	recs := normRecs()
+	features := []func(normRec) float64{
+		func(r normRec) float64 { return r.ping },
+		func(r normRec) float64 { return r.hops },
+	}
+	stats, err := ComputeNormStatsND(recs, features,
+		WithNormalization(Robust),
+		WithAxisTransform(0, Log1p),
+		WithAxisSigmoid(1, 1, 0.5),
+		WithMissingValuePolicy(MissingImputeMedian),
+		WithOutOfRange(RangeClamp),
+		WithTargetRange(0, -1, 1),
+		WithCategoricalFeature(func(r normRec) string { return r.id[:1] }, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"version":1`, `"method":"robust"`, `"transforms":["log1p","none"]`, `"missing":"impute-median"`, `"outOfRange":"clamp"`} {
+		if !strings.Contains(string(b), want) {
+			t.Fatalf("encoding %s lacks %s", b, want)
+		}
+	}
+	var got NormStats
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, stats) {
+		t.Fatalf("round trip = %+v, want %+v", got, stats)
+	}
+
+	// plain min-max stats stay compact and round-trip too
+	plain := ComputeNormStats2D(recs, features[0], features[1])
+	b, _ = json.Marshal(plain)
+	if strings.Contains(string(b), "transforms") || strings.Contains(string(b), "missing") {
+		t.Fatalf("plain encoding = %s", b)
+	}
+	got = NormStats{}
+	if err := json.Unmarshal(b, &got); err != nil || !reflect.DeepEqual(got, plain) {
+		t.Fatalf("plain round trip = %+v, %v", got, err)
+	}
+}
+
+func TestNormStatsJSONValidation(t *testing.T) {
+	cases := map[string]error{
+		`{"version":2,"method":"minmax","axes":[]}`:                                             ErrUnsupportedVersion,
+		`{"version":1,"method":"quantile","axes":[]}`:                                           ErrInvalidNormStats,
+		`{"version":1,"method":"minmax","axes":[{"min":1,"max":0}]}`:                            ErrInvalidNormStats,
+		`{"version":1,"method":"zscore","axes":[{"min":0,"max":1,"stddev":-1}]}`:                ErrInvalidNormStats,
+		`{"version":1,"method":"minmax","axes":[{"min":0,"max":1}],"transforms":[]}`:            nil,
+		`{"version":1,"method":"minmax","axes":[{"min":0,"max":1}],"transforms":["log","log"]}`: ErrInvalidNormStats,
+		`{"version":1,"method":"minmax","axes":[{"min":0,"max":1}],"transforms":["sqrt"]}`:      ErrInvalidNormStats,
+		`{"version":1,"method":"minmax","axes":[{"min":0,"max":1}],"missing":"guess"}`:          ErrInvalidNormStats,
+		`{"version":1,"method":"minmax","axes":[{"min":0,"max":1}],"count":-1}`:                 ErrInvalidNormStats,
+	}
+	for in, want := range cases {
+		s := NormStats{Count: 7}
+		err := json.Unmarshal([]byte(in), &s)
+		if !errors.Is(err, want) {
+			t.Fatalf("%s: err = %v, want %v", in, err, want)
+		}
+		if err != nil && s.Count != 7 {
+			t.Fatalf("%s: failed decode modified the stats", in)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package poindexter
+
+import (
+	"math"
+	"sort"
+)
+
+// Outliers returns points whose distance to their k-th nearest neighbour
+// (excluding the point itself) is strictly greater than threshold.
+// Results are ordered by descending k-th neighbour distance so the most
+// isolated points come first. This is useful to flag peers reporting absurd
+// metrics that place them far away from everyone else.
+//
+// Returns nil if k <= 0 or the tree holds k or fewer points. Outlier scans do
+// not record query analytics or peer selections.
+func (t *KDTree[T]) Outliers(k int, threshold float64) []KDPoint[T] {
+	kth := t.kthNeighborDistances(k)
+	if kth == nil {
+		return nil
+	}
+	return t.collectOutliers(kth, threshold)
+}
+
+// OutliersPercentile is like Outliers but derives the threshold from the
+// distribution of k-th neighbour distances: points above the p-th percentile
+// (p in [0,1]) are returned. p is clamped to [0,1].
+func (t *KDTree[T]) OutliersPercentile(k int, p float64) []KDPoint[T] {
+	kth := t.kthNeighborDistances(k)
+	if kth == nil {
+		return nil
+	}
+	p = math.Max(0, math.Min(1, p))
+	sorted := append([]float64(nil), kth...)
+	sort.Float64s(sorted)
+	return t.collectOutliers(kth, percentile(sorted, p))
+}
+
+// collectOutliers selects points whose k-th neighbour distance exceeds threshold.
+func (t *KDTree[T]) collectOutliers(kth []float64, threshold float64) []KDPoint[T] {
+	var idxs []int
+	for i, d := range kth {
+		if d > threshold {
+			idxs = append(idxs, i)
+		}
+	}
+	sort.SliceStable(idxs, func(a, b int) bool { return kth[idxs[a]] > kth[idxs[b]] })
+	out := make([]KDPoint[T], len(idxs))
+	for i, idx := range idxs {
+		out[i] = t.points[idx]
+	}
+	return out
+}
+
+// kthNeighborDistances returns, for every point, the distance to its k-th
+// nearest other point. Returns nil if k <= 0 or there are not enough points.
+func (t *KDTree[T]) kthNeighborDistances(k int) []float64 {
+	n := len(t.points)
+	if k <= 0 || n <= k {
+		return nil
+	}
+	res := make([]float64, n)
+	buf := make([]float64, 0, n-1)
+	for i := range t.points {
+		if ix := t.index.Load(); ix != nil && ix.version == t.version.Load() {
+			if d, ok := gonumKthNeighbor(ix, t.points[i].Coords, k); ok {
+				res[i] = d
+				continue
+			}
+		}
+		buf = buf[:0]
+		for j := range t.points {
+			if j == i {
+				continue
+			}
+			buf = append(buf, t.metric.Distance(t.points[i].Coords, t.points[j].Coords))
+		}
+		sort.Float64s(buf)
+		res[i] = buf[k-1]
+	}
+	return res
+}
+
+// gonumKthNeighbor uses the backend index to find the k-th neighbour
+// distance of an indexed point at coords. The point itself is among the k+1
+// nearest at distance 0, so dropping one zero leaves the distances to the
+// other points; this holds even when index positions no longer match
+// t.points (after tombstoned deletes).
+func gonumKthNeighbor[T any](ix *kdIndex[T], coords []float64, k int) (float64, bool) {
+	_, dists := indexKNearest[T](ix.data, coords, k+1, nil)
+	if len(dists) <= k {
+		return 0, false
+	}
+	return dists[k], true
+}
@@ -0,0 +1,60 @@
+package poindexter
+
+import "testing"
+
+func outlierTestTree(t *testing.T, opts ...KDOption) *KDTree[int] {
+	t.Helper()
+	pts := []KDPoint[int]{
+		{ID: "a", Coords: []float64{0, 0}},
+		{ID: "b", Coords: []float64{1, 0}},
+		{ID: "c", Coords: []float64{0, 1}},
+		{ID: "d", Coords: []float64{1, 1}},
+		{ID: "far", Coords: []float64{50, 50}},
+		{ID: "mid", Coords: []float64{10, 10}},
+	}
+	tr, err := NewKDTree(pts, opts...)
+	if err != nil {
+		t.Fatalf("NewKDTree err: %v", err)
+	}
+	return tr
+}
+
+func TestOutliers_Threshold(t *testing.T) {
+	for _, be := range []KDBackend{BackendLinear, BackendGonum} {
+		tr := outlierTestTree(t, WithBackend(be))
+		out := tr.Outliers(1, 5)
+		if len(out) != 2 {
+			t.Fatalf("%s: want 2 outliers, got %d", be, len(out))
+		}
+		if out[0].ID != "far" || out[1].ID != "mid" {
+			t.Fatalf("%s: want [far mid], got [%s %s]", be, out[0].ID, out[1].ID)
+		}
+		if got := tr.Outliers(1, 1000); len(got) != 0 {
+			t.Fatalf("%s: want no outliers at high threshold, got %d", be, len(got))
+		}
+	}
+}
+
+func TestOutliers_Percentile(t *testing.T) {
+	tr := outlierTestTree(t)
+	out := tr.OutliersPercentile(1, 0.8)
+	if len(out) != 1 || out[0].ID != "far" {
+		t.Fatalf("want [far], got %v", out)
+	}
+	if got := tr.OutliersPercentile(1, 1); len(got) != 0 {
+		t.Fatalf("want none above p100, got %d", len(got))
+	}
+}
+
+func TestOutliers_EdgeCases(t *testing.T) {
+	tr := outlierTestTree(t)
+	if got := tr.Outliers(0, 1); got != nil {
+		t.Fatalf("expected nil for k<=0")
+	}
+	if got := tr.Outliers(tr.Len(), 1); got != nil {
+		t.Fatalf("expected nil when k >= Len")
+	}
+	if tr.Analytics().QueryCount.Load() != 0 {
+		t.Fatalf("outlier scan should not record queries")
+	}
+}
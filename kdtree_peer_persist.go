@@ -0,0 +1,96 @@
+package poindexter
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// peerAnalyticsJSONVersion is the current PeerAnalytics.Save format version.
+const peerAnalyticsJSONVersion = 1
+
+// peerAnalyticsJSON is the wire form of PeerAnalytics.
+type peerAnalyticsJSON struct {
+	Version int              `json:"version"`
+	Peers   []peerRecordJSON `json:"peers"`
+}
+
+// peerRecordJSON is the wire form of one peer's record. Times are Unix
+// nanoseconds; the decayed fields are only present with a half-life.
+type peerRecordJSON struct {
+	ID             string  `json:"id"`
+	SelectionCount int64   `json:"selectionCount"`
+	DistanceSum    float64 `json:"distanceSum"`
+	LastSelected   int64   `json:"lastSelected"`
+	FirstSelected  int64   `json:"firstSelected"`
+	Decayed        float64 `json:"decayed,omitempty"`
+	DecayedDist    float64 `json:"decayedDistance,omitempty"`
+	DecayedAt      int64   `json:"decayedAt,omitempty"`
+}
+
+// Save writes the per-peer selection history as JSON to w, so it can be
+// restored with Load after a restart instead of every peer starting out
+// unselected. Configuration (half-life, peer cap) is not included.
+func (p *PeerAnalytics) Save(w io.Writer) error {
+	p.mu.RLock()
+	out := peerAnalyticsJSON{Version: peerAnalyticsJSONVersion, Peers: make([]peerRecordJSON, 0, len(p.peers))}
+	for id, r := range p.peers {
+		rec := peerRecordJSON{
+			ID:             id,
+			SelectionCount: r.hits.Load(),
+			DistanceSum:    math.Float64frombits(r.distSum.Load()),
+			LastSelected:   r.last.Load(),
+			FirstSelected:  r.created,
+		}
+		r.decayMu.Lock()
+		rec.Decayed, rec.DecayedDist, rec.DecayedAt = r.decayed, r.decayedDist, r.at
+		r.decayMu.Unlock()
+		out.Peers = append(out.Peers, rec)
+	}
+	p.mu.RUnlock()
+	sort.Slice(out.Peers, func(i, j int) bool { return out.Peers[i].ID < out.Peers[j].ID })
+	return json.NewEncoder(w).Encode(out)
+}
+
+// Load replaces the tracked peers with the history written by Save, keeping
+// p's own configuration. History saved without a half-life but loaded with
+// one counts every past selection as made at the peer's last selection time.
+// If p caps the peers tracked (WithMaxTrackedPeers), peers beyond the cap are
+// evicted as the policy dictates. A newer format version yields
+// ErrUnsupportedVersion.
+func (p *PeerAnalytics) Load(r io.Reader) error {
+	var in peerAnalyticsJSON
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return err
+	}
+	if in.Version > peerAnalyticsJSONVersion {
+		return ErrUnsupportedVersion
+	}
+	peers := make(map[string]*peerRecord, len(in.Peers))
+	for _, rec := range in.Peers {
+		if rec.ID == "" {
+			continue
+		}
+		pr := &peerRecord{created: rec.FirstSelected}
+		pr.hits.Store(rec.SelectionCount)
+		pr.distSum.Store(math.Float64bits(rec.DistanceSum))
+		pr.last.Store(rec.LastSelected)
+		pr.decayed, pr.decayedDist, pr.at = rec.Decayed, rec.DecayedDist, rec.DecayedAt
+		if p.halfLife > 0 && rec.DecayedAt == 0 {
+			pr.decayed, pr.decayedDist, pr.at = float64(rec.SelectionCount), rec.DistanceSum, rec.LastSelected
+		}
+		peers[rec.ID] = pr
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.peers = peers
+	p.evicted.Store(0)
+	now := time.Now().UnixNano()
+	for p.maxPeers > 0 && len(p.peers) > p.maxPeers {
+		p.evictLocked(now)
+	}
+	return nil
+}
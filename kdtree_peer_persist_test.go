@@ -0,0 +1,72 @@
+package poindexter
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPeerAnalyticsSaveLoad(t *testing.T) {
+	pa := NewPeerAnalytics(WithSelectionHalfLife(time.Hour))
+	now := time.Now().UnixNano()
+	pa.recordAt("a", 2, now-int64(time.Hour))
+	pa.recordAt("a", 4, now)
+	pa.recordAt("b", 1, now)
+
+	var buf bytes.Buffer
+	if err := pa.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := NewPeerAnalytics(WithSelectionHalfLife(time.Hour))
+	got.RecordSelection("stale", 1)
+	if err := got.Load(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if got.Len() != 2 {
+		t.Fatalf("expected Load to replace the peers, got %d", got.Len())
+	}
+	for _, id := range []string{"a", "b"} {
+		want, have := pa.GetPeerStats(id), got.GetPeerStats(id)
+		if have.SelectionCount != want.SelectionCount || !have.LastSelectedAt.Equal(want.LastSelectedAt) ||
+			math.Abs(have.DecayedCount-want.DecayedCount) > 1e-3 || math.Abs(have.AvgDistance-want.AvgDistance) > 1e-9 {
+			t.Errorf("%s: got %+v, want %+v", id, have, want)
+		}
+	}
+}
+
+func TestPeerAnalyticsLoad_CumulativeIntoDecayAndCap(t *testing.T) {
+	pa := NewPeerAnalytics()
+	for i := 0; i < 3; i++ {
+		pa.RecordSelection("busy", 3)
+	}
+	pa.RecordSelection("idle", 1)
+	var buf bytes.Buffer
+	if err := pa.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	got := NewPeerAnalytics(WithSelectionHalfLife(24*time.Hour), WithMaxTrackedPeers(1, EvictLeastSelected))
+	if err := got.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got.Len() != 1 || got.Evicted() != 1 {
+		t.Fatalf("expected the cap to apply on load, got %d peers, %d evicted", got.Len(), got.Evicted())
+	}
+	s := got.GetPeerStats("busy")
+	if s.SelectionCount != 3 || math.Abs(s.DecayedCount-3) > 0.01 || s.AvgDistance != 3 {
+		t.Fatalf("expected cumulative history to seed the decayed count, got %+v", s)
+	}
+}
+
+func TestPeerAnalyticsLoad_Errors(t *testing.T) {
+	pa := NewPeerAnalytics()
+	if err := pa.Load(strings.NewReader(`{"version":99,"peers":[]}`)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+	if err := pa.Load(strings.NewReader(`{`)); err == nil {
+		t.Fatal("expected a decode error")
+	}
+}
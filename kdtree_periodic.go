@@ -0,0 +1,144 @@
+package poindexter
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrInvalidPeriod indicates a WithPeriodicAxis axis outside the tree's
+// dimensions or a period that is not positive and finite.
+var ErrInvalidPeriod = errors.New("kdtree: invalid periodic axis")
+
+// WithPeriodicAxis makes axis wrap around with the given period, so distances
+// along it are measured the short way round: with period 360, longitudes 359
+// and 1 are 2 apart, and with period 24 hours 23 and 1 are 2 apart. It may be
+// given once per axis. Coordinates need not lie in any particular range.
+//
+// The tree's metric is wrapped in a PeriodicMetric. The gonum backend indexes
+// periodic Euclidean, Manhattan and Chebyshev trees, as do the vptree,
+// covertree and hnsw backends; the rtree backend scans linearly for distance
+// queries on them.
+// The periods are stored in JSON, binary and protobuf snapshots.
+func WithPeriodicAxis(axis int, period float64) KDOption {
+	return func(o *kdOptions) {
+		o.periodic = append(o.periodic, periodicAxis{axis, period})
+	}
+}
+
+type periodicAxis struct {
+	axis   int
+	period float64
+}
+
+// periodicMetric returns cfg's metric wrapped for its periodic axes, or the
+// metric unchanged if there are none.
+func periodicMetric(cfg kdOptions, dim int) (DistanceMetric, error) {
+	if len(cfg.periodic) == 0 {
+		return cfg.metric, nil
+	}
+	periods := make([]float64, dim)
+	for _, p := range cfg.periodic {
+		if p.axis < 0 || p.axis >= dim || !(p.period > 0) || math.IsInf(p.period, 1) {
+			return nil, ErrInvalidPeriod
+		}
+		periods[p.axis] = p.period
+	}
+	return PeriodicMetric{Base: cfg.metric, Periods: periods}, nil
+}
+
+// PeriodicMetric measures Base distance on a torus: on every axis i with
+// Periods[i] > 0, the coordinate difference is reduced to the shortest signed
+// offset in [-Periods[i]/2, Periods[i]/2] before Base sees it. Axes without a
+// positive period (or beyond len(Periods)) are compared as usual. A nil Base
+// means EuclideanDistance.
+type PeriodicMetric struct {
+	Base    DistanceMetric
+	Periods []float64
+}
+
+// wrapOffset reduces the difference d to the shortest offset modulo period.
+func wrapOffset(d, period float64) float64 {
+	d = math.Mod(d, period)
+	if d > period/2 {
+		d -= period
+	} else if d < -period/2 {
+		d += period
+	}
+	return d
+}
+
+// wrapCoord maps v into [0, period).
+func wrapCoord(v, period float64) float64 {
+	v = math.Mod(v, period)
+	if v < 0 {
+		v += period
+	}
+	if v >= period { // -tiny + period rounds up to period
+		v = 0
+	}
+	return v
+}
+
+func (m PeriodicMetric) period(i int) float64 {
+	if i < len(m.Periods) && m.Periods[i] > 0 {
+		return m.Periods[i]
+	}
+	return 0
+}
+
+func (m PeriodicMetric) Distance(a, b []float64) float64 {
+	switch m.Base.(type) {
+	case EuclideanDistance, nil:
+		var sum float64
+		for i := range a {
+			d := m.offset(a, b, i)
+			sum += d * d
+		}
+		return math.Sqrt(sum)
+	case ManhattanDistance:
+		var sum float64
+		for i := range a {
+			sum += math.Abs(m.offset(a, b, i))
+		}
+		return sum
+	case ChebyshevDistance:
+		var mx float64
+		for i := range a {
+			mx = math.Max(mx, math.Abs(m.offset(a, b, i)))
+		}
+		return mx
+	}
+	// other metrics see a copy of a moved to its closest image next to b
+	w := make([]float64, len(a))
+	for i := range a {
+		w[i] = b[i] + m.offset(a, b, i)
+	}
+	return m.Base.Distance(w, b)
+}
+
+// offset returns a[i] - b[i], wrapped if axis i is periodic.
+func (m PeriodicMetric) offset(a, b []float64, i int) float64 {
+	d := a[i] - b[i]
+	if p := m.period(i); p > 0 {
+		return wrapOffset(d, p)
+	}
+	return d
+}
+
+// canonical returns c with periodic coordinates mapped into [0, period), or c
+// itself if the metric has no periodic axes.
+func (m PeriodicMetric) canonical(c []float64) []float64 {
+	var out []float64
+	for i := range c {
+		if p := m.period(i); p > 0 {
+			if out == nil {
+				out = append([]float64(nil), c...)
+			}
+			out[i] = wrapCoord(c[i], p)
+		}
+	}
+	if out == nil {
+		return c
+	}
+	return out
+}
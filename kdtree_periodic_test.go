@@ -0,0 +1,197 @@
+package poindexter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// doubledDistance is twice the Euclidean distance: a metric PeriodicMetric
+// has no fast path for.
+type doubledDistance struct{}
+
+func (doubledDistance) Distance(a, b []float64) float64 {
+	return 2 * EuclideanDistance{}.Distance(a, b)
+}
+
+func TestPeriodicMetric(t *testing.T) {
+	m := PeriodicMetric{Base: EuclideanDistance{}, Periods: []float64{0, 360}}
+	if d := m.Distance([]float64{0, 359}, []float64{0, 1}); math.Abs(d-2) > 1e-12 {
+		t.Fatalf("359° to 1°: %v", d)
+	}
+	if d := m.Distance([]float64{3, 720 - 6}, []float64{0, -10}); math.Abs(d-5) > 1e-12 {
+		t.Fatalf("unnormalized coords: %v", d)
+	}
+	hours := PeriodicMetric{Base: ManhattanDistance{}, Periods: []float64{24}}
+	if d := hours.Distance([]float64{23}, []float64{1}); d != 2 {
+		t.Fatalf("23h to 1h: %v", d)
+	}
+	// other bases see the closest image
+	w := PeriodicMetric{Base: doubledDistance{}, Periods: []float64{10}}
+	if d := w.Distance([]float64{9}, []float64{1}); math.Abs(d-4) > 1e-12 {
+		t.Fatalf("weighted: %v", d)
+	}
+}
+
+func TestWithPeriodicAxis_Invalid(t *testing.T) {
+	pts := []KDPoint[int]{{Coords: []float64{0, 0}}}
+	for _, opt := range []KDOption{WithPeriodicAxis(2, 360), WithPeriodicAxis(-1, 360), WithPeriodicAxis(0, 0), WithPeriodicAxis(0, math.Inf(1)), WithPeriodicAxis(0, math.NaN())} {
+		if _, err := NewKDTree(pts, opt); !errors.Is(err, ErrInvalidPeriod) {
+			t.Fatalf("NewKDTree: %v", err)
+		}
+		if _, err := NewKDTreeFromDim[int](2, opt); !errors.Is(err, ErrInvalidPeriod) {
+			t.Fatalf("NewKDTreeFromDim: %v", err)
+		}
+	}
+}
+
+func TestPeriodic_AcrossBoundary(t *testing.T) {
+	pts := []KDPoint[string]{
+		{ID: "east", Coords: []float64{0, 179}},
+		{ID: "west", Coords: []float64{0, -179}},
+		{ID: "mid", Coords: []float64{0, 90}},
+	}
+	tr, err := NewKDTree(pts, WithPeriodicAxis(1, 360))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, d, ok := tr.Nearest([]float64{0, 178})
+	if !ok || p.ID != "east" || d != 1 {
+		t.Fatalf("nearest %v %v", p.ID, d)
+	}
+	p, d, _ = tr.Nearest([]float64{0, -181}) // same place as 179
+	if p.ID != "east" || d != 0 {
+		t.Fatalf("nearest %v %v", p.ID, d)
+	}
+	got, _ := tr.Radius([]float64{0, 180}, 1.5)
+	if len(got) != 2 {
+		t.Fatalf("radius across the antimeridian: %d points", len(got))
+	}
+}
+
+func TestPeriodic_MatchesLinear(t *testing.T) {
+	rng := rand.New(rand.NewSource(41))
+	pts := make([]KDPoint[int], 1500)
+	for i := range pts {
+		// longitude, hour of day, and an ordinary axis
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{rng.Float64()*360 - 180, rng.Float64() * 24, rng.Float64() * 50}}
+	}
+	periodic := []KDOption{WithPeriodicAxis(0, 360), WithPeriodicAxis(1, 24)}
+	for _, m := range []DistanceMetric{EuclideanDistance{}, ManhattanDistance{}, ChebyshevDistance{}} {
+		for _, backend := range []KDBackend{BackendGonum, BackendVPTree, BackendRTree, BackendCoverTree} {
+			t.Run(fmt.Sprintf("%T/%s", m, backend), func(t *testing.T) {
+				tr, err := NewKDTree(pts[:1000], append([]KDOption{WithMetric(m), WithBackend(backend)}, periodic...)...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if (backend != BackendGonum || hasGonum()) && tr.Backend() != backend {
+					t.Fatalf("backend %s", tr.Backend())
+				}
+				for _, p := range pts[1000:] {
+					tr.Insert(p)
+				}
+				lin, _ := NewKDTree(pts, append([]KDOption{WithMetric(m), WithBackend(BackendLinear)}, periodic...)...)
+				for q := 0; q < 50; q++ {
+					query := []float64{rng.Float64()*720 - 360, rng.Float64()*48 - 12, rng.Float64() * 50}
+					_, dt, _ := tr.Nearest(query)
+					_, dl, _ := lin.Nearest(query)
+					if dt != dl {
+						t.Fatalf("nearest %v: %v vs %v", query, dt, dl)
+					}
+					_, kt := tr.KNearest(query, 10)
+					_, kl := lin.KNearest(query, 10)
+					if fmt.Sprint(kt) != fmt.Sprint(kl) {
+						t.Fatalf("knearest %v: %v vs %v", query, kt, kl)
+					}
+					rt, _ := tr.Radius(query, 15)
+					rl, _ := lin.Radius(query, 15)
+					if len(rt) != len(rl) {
+						t.Fatalf("radius %v: %d vs %d", query, len(rt), len(rl))
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestPeriodic_JSONRoundTrip(t *testing.T) {
+	pts := []KDPoint[int]{{ID: "a", Coords: []float64{0, 359}}, {ID: "b", Coords: []float64{0, 90}}}
+	tr, _ := NewKDTree(pts, WithMetric(ManhattanDistance{}), WithPeriodicAxis(1, 360))
+	var buf bytes.Buffer
+	if err := tr.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lt, err := LoadKDTree[int](&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, d, _ := lt.Nearest([]float64{0, 1}); p.ID != "a" || d != 2 {
+		t.Fatalf("loaded tree lost its periodic axis: %v %v", p.ID, d)
+	}
+}
+
+func TestPeriodic_BinaryRoundTrip(t *testing.T) {
+	pts := []KDPoint[int]{{ID: "a", Coords: []float64{0, 359}}, {ID: "b", Coords: []float64{0, 90}}}
+	tr, _ := NewKDTree(pts, WithMetric(ManhattanDistance{}), WithPeriodicAxis(1, 360))
+	var buf bytes.Buffer
+	if err := tr.WriteBinary(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	raw := append([]byte(nil), buf.Bytes()...)
+	if v := binary.LittleEndian.Uint16(raw[4:]); v != binarySnapshotVerPeriodic {
+		t.Fatalf("periodic snapshot written as version %d", v)
+	}
+	lt, err := ReadKDTreeBinary[int](&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm, ok := lt.metric.(PeriodicMetric)
+	if _, base := pm.Base.(ManhattanDistance); !ok || !base || len(pm.Periods) != 2 || pm.Periods[0] != 0 || pm.Periods[1] != 360 {
+		t.Fatalf("metric not restored: %#v", lt.metric)
+	}
+	if p, d, _ := lt.Nearest([]float64{0, 1}); p.ID != "a" || d != 2 {
+		t.Fatalf("loaded tree lost its periodic axis: %v %v", p.ID, d)
+	}
+
+	// A periods flag on an older version is corrupt.
+	binary.LittleEndian.PutUint16(raw[4:], binarySnapshotVerLabelled)
+	if _, err := ReadKDTreeBinary[int](bytes.NewReader(raw), nil); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatalf("want ErrInvalidSnapshot for periods flag on v2, got %v", err)
+	}
+}
+
+func TestPeriodic_ProtoRoundTrip(t *testing.T) {
+	pts := []KDPoint[int]{{ID: "a", Coords: []float64{23, 0}}, {ID: "b", Coords: []float64{12, 0}}}
+	tr, _ := NewKDTree(pts, WithPeriodicAxis(0, 24))
+	b, err := tr.ToProto(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lt, err := KDTreeFromProto[int](b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, d, _ := lt.Nearest([]float64{1, 0}); p.ID != "a" || d != 2 {
+		t.Fatalf("loaded tree lost its periodic axis: %v %v", p.ID, d)
+	}
+
+	// Version 2 marks the periods; a plain tree still writes version 1.
+	var version uint64
+	_ = walkProto(b, func(num, _ int, v uint64, _ []byte) error {
+		if num == 1 {
+			version = v
+		}
+		return nil
+	})
+	if version != kdTreeProtoVersionPeriodic {
+		t.Fatalf("periodic snapshot written as version %d", version)
+	}
+	plain, _ := NewKDTree(pts)
+	if b, _ := plain.ToProto(nil); b[0] != 0x08 || b[1] != kdTreeProtoVersion {
+		t.Fatalf("plain snapshot header %x", b[:2])
+	}
+}
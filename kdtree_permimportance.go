@@ -0,0 +1,140 @@
+package poindexter
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+)
+
+// ErrTooFewOutcomes indicates fewer than two points had a labelled outcome.
+var ErrTooFewOutcomes = errors.New("kdtree: too few labelled outcomes")
+
+// permutationQueries caps the number of labelled points PermutationImportance
+// uses as queries; larger sets are sampled.
+const permutationQueries = 1024
+
+// permutationRounds is the number of shuffles PermutationImportance averages
+// per axis.
+const permutationRounds = 5
+
+// FeatureImportance reports how much one axis drives KNearest rankings
+// towards points with similar outcomes.
+type FeatureImportance struct {
+	Axis int `json:"axis"`
+	// Error is the mean squared error of the outcomes predicted from each
+	// labelled point's neighbours once this axis is shuffled between them.
+	Error float64 `json:"error"`
+	// Importance is Error minus the error with no axis shuffled. Large means
+	// the axis puts points with similar outcomes together; near 0 (or below)
+	// means rankings would be as good, or better, without it.
+	Importance float64 `json:"importance"`
+	// WeightFactor is the suggested multiplier for the axis weight: positive
+	// importances scaled to average 1 across axes, and 0 for axes that do
+	// not help. All factors are 1 if no axis helps.
+	WeightFactor float64 `json:"weightFactor"`
+}
+
+// PermutationImportance estimates which axes actually drive the tree's
+// rankings, given labelled outcomes such as 1 for a successful connection
+// and 0 for a failed one. outcome returns a point's outcome and whether it
+// has one; unlabelled points are ignored. Each labelled point's outcome is
+// predicted as the mean outcome of its k nearest labelled neighbours (itself,
+// matched by ID, excluded), once as is and then with each axis in turn
+// shuffled between the query points; an axis whose shuffling makes the
+// predictions worse is one the rankings depend on. Results are in axis order,
+// with suggested weight factors to multiply into the weights the points were
+// built with.
+//
+// Queries are a random sample of at most 1024 labelled points, and each axis
+// is shuffled 5 times; seed fixes the sampling and shuffles so runs are
+// repeatable. k below 1 is treated as 1. Returns ErrTooFewOutcomes if fewer
+// than two points are labelled.
+func (t *KDTree[T]) PermutationImportance(outcome func(KDPoint[T]) (float64, bool), k int, seed uint64) ([]FeatureImportance, error) {
+	if v := t.cowView(); v != nil {
+		return v.PermutationImportance(outcome, k, seed)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	k = max(k, 1)
+	label := func(p KDPoint[T]) (float64, bool) {
+		y, ok := outcome(p)
+		return y, ok && !math.IsNaN(y) && !math.IsInf(y, 0)
+	}
+	var queries []KDPoint[T]
+	var ys []float64
+	for _, p := range t.points {
+		if y, ok := label(p); ok {
+			queries, ys = append(queries, p), append(ys, y)
+		}
+	}
+	if len(queries) < 2 {
+		return nil, ErrTooFewOutcomes
+	}
+	rng := rand.New(rand.NewPCG(seed, 0x7065726d))
+	if len(queries) > permutationQueries {
+		perm := rng.Perm(len(queries))[:permutationQueries]
+		qs, qys := make([]KDPoint[T], len(perm)), make([]float64, len(perm))
+		for i, j := range perm {
+			qs[i], qys[i] = queries[j], ys[j]
+		}
+		queries, ys = qs, qys
+	}
+
+	// predictionError is the mean squared error of the neighbour-predicted
+	// outcomes with each query's coordinates taken from coords(i).
+	h := neighborHeap[T]{}
+	predictionError := func(coords func(i int) []float64) float64 {
+		var sum float64
+		for i, q := range queries {
+			h.pts, h.dists = h.pts[:0], h.dists[:0]
+			skip := func(p KDPoint[T]) bool {
+				_, ok := label(p)
+				return !ok || p.ID == q.ID
+			}
+			t.search(coords(i), k, math.Inf(1), skip, &h)
+			var pred float64
+			for _, p := range h.pts {
+				y, _ := label(p)
+				pred += y
+			}
+			if len(h.pts) > 0 {
+				pred /= float64(len(h.pts))
+			}
+			sum += (ys[i] - pred) * (ys[i] - pred)
+		}
+		return sum / float64(len(queries))
+	}
+	base := predictionError(func(i int) []float64 { return queries[i].Coords })
+
+	out := make([]FeatureImportance, t.dim)
+	buf := make([]float64, t.dim)
+	var total float64
+	var helpful int
+	for axis := range out {
+		var e float64
+		for range permutationRounds {
+			perm := rng.Perm(len(queries))
+			e += predictionError(func(i int) []float64 {
+				copy(buf, queries[i].Coords)
+				buf[axis] = queries[perm[i]].Coords[axis]
+				return buf
+			})
+		}
+		e /= permutationRounds
+		out[axis] = FeatureImportance{Axis: axis, Error: e, Importance: e - base}
+		if imp := out[axis].Importance; imp > 0 {
+			total += imp
+			helpful++
+		}
+	}
+	for i := range out {
+		switch {
+		case helpful == 0:
+			out[i].WeightFactor = 1
+		case out[i].Importance > 0:
+			out[i].WeightFactor = out[i].Importance / total * float64(t.dim)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,74 @@
+package poindexter
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+func TestPermutationImportance(t *testing.T) {
+	// outcomes follow axis 0; axis 1 is noise
+	rng := rand.New(rand.NewPCG(1, 2))
+	var pts []KDPoint[bool]
+	for i := range 200 {
+		x := rng.Float64()
+		pts = append(pts, KDPoint[bool]{ID: fmt.Sprint(i), Coords: []float64{x, rng.Float64()}, Value: x > 0.5})
+	}
+	// a few unlabelled points sitting on top of labelled ones must be ignored
+	for i := range 20 {
+		pts = append(pts, KDPoint[bool]{ID: fmt.Sprint("u", i), Coords: append([]float64(nil), pts[i].Coords...)})
+	}
+	tree, _ := NewKDTree(pts)
+	outcome := func(p KDPoint[bool]) (float64, bool) {
+		if p.ID[0] == 'u' {
+			return 0, false
+		}
+		if p.Value {
+			return 1, true
+		}
+		return 0, true
+	}
+	imp, err := tree.PermutationImportance(outcome, 5, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imp) != 2 || imp[0].Axis != 0 || imp[1].Axis != 1 {
+		t.Fatalf("importance = %+v", imp)
+	}
+	if !(imp[0].Importance > 0.1) || !(imp[0].Importance > 5*imp[1].Importance) {
+		t.Fatalf("axis 0 should dominate: %+v", imp)
+	}
+	if !(imp[0].WeightFactor > 1) || !(imp[1].WeightFactor < 1) {
+		t.Fatalf("weight factors = %+v", imp)
+	}
+	again, _ := tree.Snapshot().PermutationImportance(outcome, 5, 42)
+	if !reflect.DeepEqual(again, imp) {
+		t.Fatalf("same seed gave %+v, want %+v", again, imp)
+	}
+
+	none := func(KDPoint[bool]) (float64, bool) { return 0, false }
+	if _, err := tree.PermutationImportance(none, 5, 1); err != ErrTooFewOutcomes {
+		t.Fatalf("no outcomes: err = %v", err)
+	}
+}
+
+func TestPermutationImportanceNoSignal(t *testing.T) {
+	// constant outcomes: no axis helps, so every factor stays 1
+	pts := []KDPoint[int]{
+		{ID: "a", Coords: []float64{0, 0}},
+		{ID: "b", Coords: []float64{1, 0}},
+		{ID: "c", Coords: []float64{0, 1}},
+		{ID: "d", Coords: []float64{1, 1}},
+	}
+	tree, _ := NewKDTree(pts)
+	imp, err := tree.PermutationImportance(func(KDPoint[int]) (float64, bool) { return 1, true }, 0, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range imp {
+		if fi.Importance != 0 || fi.WeightFactor != 1 {
+			t.Fatalf("importance = %+v", imp)
+		}
+	}
+}
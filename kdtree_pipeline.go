@@ -0,0 +1,228 @@
+package poindexter
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrPipelineNotFitted indicates Apply or Coords ran before Fit, Build or
+// UseStats gave the pipeline its normalisation stats.
+var ErrPipelineNotFitted = errors.New("kdtree: pipeline not fitted")
+
+// Pipeline declares how items become KDPoints once, so the same
+// preprocessing is applied to the build set and, identically, to new items
+// and queries later:
+//
+//	p := NewPipeline[Peer]().
+//		ID(func(x Peer) string { return x.ID }).
+//		Select(ping, hops, bandwidth).
+//		Clip(0, 0, 2000).
+//		Transform(2, Log1p).
+//		Normalize(Robust).
+//		Weight(1, 0.5, 1.2).
+//		Invert(2)
+//	pts, err := p.Build(peers)   // fit stats on peers and build
+//	q, err := p.Coords(candidate) // later: same clip, transform and scaling
+//
+// Raw values are selected, clipped, transformed, normalised, inverted, mapped
+// to any target range and weighted, in that order, whatever order the steps
+// are declared in. Build, Fit and UseStats fix the normalisation stats;
+// declaring a step afterwards discards them until the next fit. Step errors
+// (such as an axis outside the selected features) surface from Fit or Build.
+// A fitted pipeline is safe for concurrent Apply and Coords calls.
+type Pipeline[T any] struct {
+	id       func(T) string
+	features []func(T) float64
+	clips    map[int][2]float64
+	weights  []float64
+	invert   []int
+	opts     []BuildOption
+	stats    *NormStats
+}
+
+// NewPipeline returns an empty pipeline.
+func NewPipeline[T any]() *Pipeline[T] { return &Pipeline[T]{} }
+
+// ID sets the function supplying point IDs; without it IDs are empty.
+func (p *Pipeline[T]) ID(fn func(T) string) *Pipeline[T] {
+	p.id = fn
+	p.stats = nil
+	return p
+}
+
+// Select appends feature extractors; each becomes the next axis.
+func (p *Pipeline[T]) Select(features ...func(T) float64) *Pipeline[T] {
+	p.features = append(p.features, features...)
+	p.stats = nil
+	return p
+}
+
+// Clip clamps the raw values of axis to [lo, hi] before anything else, so
+// values beyond a hard limit count as the limit. NaN passes through as
+// missing.
+func (p *Pipeline[T]) Clip(axis int, lo, hi float64) *Pipeline[T] {
+	if p.clips == nil {
+		p.clips = make(map[int][2]float64)
+	}
+	p.clips[axis] = [2]float64{lo, hi}
+	p.stats = nil
+	return p
+}
+
+// Transform applies x to axis after clipping (see WithAxisTransform).
+func (p *Pipeline[T]) Transform(axis int, x AxisTransform) *Pipeline[T] {
+	return p.With(WithAxisTransform(axis, x))
+}
+
+// Normalize sets the normalisation strategy (MinMax by default).
+func (p *Pipeline[T]) Normalize(n Normalization) *Pipeline[T] {
+	return p.With(WithNormalization(n))
+}
+
+// Weight sets the per-axis weights, one per selected feature. Without it
+// every axis weighs 1.
+func (p *Pipeline[T]) Weight(weights ...float64) *Pipeline[T] {
+	p.weights = append([]float64(nil), weights...)
+	p.stats = nil
+	return p
+}
+
+// Invert inverts the given axes so higher raw values cost less.
+func (p *Pipeline[T]) Invert(axes ...int) *Pipeline[T] {
+	p.invert = append(p.invert, axes...)
+	p.stats = nil
+	return p
+}
+
+// TargetRange maps axis onto [lo, hi] instead of [0,1] after inversion (see
+// WithTargetRange).
+func (p *Pipeline[T]) TargetRange(axis int, lo, hi float64) *Pipeline[T] {
+	return p.With(WithTargetRange(axis, lo, hi))
+}
+
+// With adds any other build options, such as WithAxisSigmoid,
+// WithMissingValuePolicy or WithCategoricalFeature.
+func (p *Pipeline[T]) With(opts ...BuildOption) *Pipeline[T] {
+	p.opts = append(p.opts, opts...)
+	p.stats = nil
+	return p
+}
+
+// Fit computes the normalisation stats over items and keeps them for Apply
+// and Coords.
+func (p *Pipeline[T]) Fit(items []T) error {
+	features, err := p.clipped()
+	if err != nil {
+		return err
+	}
+	if _, _, err := p.axes(); err != nil {
+		return err
+	}
+	stats, err := ComputeNormStatsND(items, features, p.opts...)
+	if err != nil {
+		return err
+	}
+	p.stats = &stats
+	return nil
+}
+
+// Build fits the pipeline on items and returns their points.
+func (p *Pipeline[T]) Build(items []T) ([]KDPoint[T], error) {
+	if err := p.Fit(items); err != nil {
+		return nil, err
+	}
+	return p.Apply(items)
+}
+
+// Apply turns items into points with the fitted stats, exactly as Build did
+// for the items it was fitted on.
+func (p *Pipeline[T]) Apply(items []T) ([]KDPoint[T], error) {
+	if p.stats == nil {
+		return nil, ErrPipelineNotFitted
+	}
+	features, err := p.clipped()
+	if err != nil {
+		return nil, err
+	}
+	weights, invert, err := p.axes()
+	if err != nil {
+		return nil, err
+	}
+	return BuildNDWithStats(items, p.id, features, weights, invert, *p.stats, p.opts...)
+}
+
+// Coords returns the coordinates of a single item, e.g. to use as a query
+// against a tree built by this pipeline. It returns nil coordinates and no
+// error if the missing-value policy drops the item.
+func (p *Pipeline[T]) Coords(item T) ([]float64, error) {
+	pts, err := p.Apply([]T{item})
+	if err != nil || len(pts) == 0 {
+		return nil, err
+	}
+	return pts[0].Coords, nil
+}
+
+// Stats returns the fitted stats, e.g. to persist them with the tree, and
+// whether the pipeline has been fitted.
+func (p *Pipeline[T]) Stats() (NormStats, bool) {
+	if p.stats == nil {
+		return NormStats{}, false
+	}
+	return *p.stats, true
+}
+
+// UseStats adopts previously fitted stats, such as ones loaded from JSON,
+// instead of calling Fit. They must come from a pipeline with the same
+// steps.
+func (p *Pipeline[T]) UseStats(stats NormStats) *Pipeline[T] {
+	p.stats = &stats
+	return p
+}
+
+// clipped returns the selected features with their clips applied.
+func (p *Pipeline[T]) clipped() ([]func(T) float64, error) {
+	if len(p.features) == 0 {
+		return nil, ErrInvalidFeatures
+	}
+	features := append([]func(T) float64(nil), p.features...)
+	for axis, c := range p.clips {
+		if axis < 0 || axis >= len(features) || !(c[0] <= c[1]) {
+			return nil, ErrInvalidAxisTransform
+		}
+		f, lo, hi := features[axis], c[0], c[1]
+		if f == nil {
+			return nil, ErrInvalidFeatures
+		}
+		features[axis] = func(item T) float64 {
+			v := f(item)
+			if math.IsNaN(v) {
+				return v
+			}
+			return math.Max(lo, math.Min(hi, v))
+		}
+	}
+	return features, nil
+}
+
+// axes returns the per-axis weights and invert flags.
+func (p *Pipeline[T]) axes() ([]float64, []bool, error) {
+	n := len(p.features)
+	weights := p.weights
+	if weights == nil {
+		weights = make([]float64, n)
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	if len(weights) != n {
+		return nil, nil, ErrInvalidWeights
+	}
+	invert := make([]bool, n)
+	for _, axis := range p.invert {
+		if axis < 0 || axis >= n {
+			return nil, nil, ErrInvalidInvert
+		}
+		invert[axis] = true
+	}
+	return weights, invert, nil
+}
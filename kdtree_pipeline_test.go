@@ -0,0 +1,116 @@
+package poindexter
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type pipePeer struct {
+	id             string
+	ping, hops, bw float64
+}
+
+func pipePeers() []pipePeer {
+	return []pipePeer{
+		{"a", 10, 1, 0},
+		{"b", 40, 2, 99},
+		{"c", 5000, 3, 9},
+	}
+}
+
+func newPeerPipeline() *Pipeline[pipePeer] {
+	return NewPipeline[pipePeer]().
+		ID(func(p pipePeer) string { return p.id }).
+		Select(
+			func(p pipePeer) float64 { return p.ping },
+			func(p pipePeer) float64 { return p.hops },
+			func(p pipePeer) float64 { return p.bw },
+		).
+		Transform(2, Log1p).
+		Clip(0, 0, 100).
+		Weight(1, 0.5, 2).
+		Invert(2)
+}
+
+func TestPipelineBuild(t *testing.T) {
+	peers := pipePeers()
+	pts, err := newPeerPipeline().Build(peers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ping clipped to [10,100]; bw log1p'd to [0, ln 100] then inverted
+	want := [][]float64{
+		{0, 0, 2},
+		{30.0 / 90, 0.25, 0},
+		{1, 0.5, 2 * (1 - math.Log(10)/math.Log(100))},
+	}
+	for i, p := range pts {
+		for d := range want[i] {
+			if math.Abs(p.Coords[d]-want[i][d]) > 1e-12 {
+				t.Fatalf("%s coords = %v, want %v", p.ID, p.Coords, want[i])
+			}
+		}
+	}
+}
+
+func TestPipelineReapply(t *testing.T) {
+	p := newPeerPipeline()
+	if _, err := p.Coords(pipePeer{}); !errors.Is(err, ErrPipelineNotFitted) {
+		t.Fatalf("unfitted Coords: err = %v", err)
+	}
+	built, err := p.Build(pipePeers())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// applying again reproduces the build exactly, and a new item is scaled
+	// with the fitted stats rather than its own
+	again, _ := p.Apply(pipePeers())
+	if !reflect.DeepEqual(again, built) {
+		t.Fatalf("Apply = %v, want %v", again, built)
+	}
+	q, err := p.Coords(pipePeer{id: "q", ping: 55, hops: 2, bw: 99})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q[0] != 0.5 || q[1] != 0.25 || q[2] != 0 {
+		t.Fatalf("query coords = %v, want [0.5 0.25 0]", q)
+	}
+
+	// fitted stats transfer to a fresh pipeline with the same steps
+	stats, ok := p.Stats()
+	if !ok {
+		t.Fatal("Stats: not fitted")
+	}
+	q2, _ := newPeerPipeline().UseStats(stats).Coords(pipePeer{id: "q", ping: 55, hops: 2, bw: 99})
+	if !reflect.DeepEqual(q2, q) {
+		t.Fatalf("UseStats coords = %v, want %v", q2, q)
+	}
+
+	// declaring another step discards the fit
+	p.Normalize(ZScore)
+	if _, ok := p.Stats(); ok {
+		t.Fatal("stats survived a new step")
+	}
+}
+
+func TestPipelineErrors(t *testing.T) {
+	peers := pipePeers()
+	cases := map[string]struct {
+		p    *Pipeline[pipePeer]
+		want error
+	}{
+		"no features": {NewPipeline[pipePeer](), ErrInvalidFeatures},
+		"weights":     {newPeerPipeline().Weight(1), ErrInvalidWeights},
+		"invert":      {newPeerPipeline().Invert(3), ErrInvalidInvert},
+		"clip axis":   {newPeerPipeline().Clip(5, 0, 1), ErrInvalidAxisTransform},
+		"clip range":  {newPeerPipeline().Clip(1, 2, 1), ErrInvalidAxisTransform},
+		"transform":   {newPeerPipeline().Transform(4, Log), ErrInvalidAxisTransform},
+	}
+	for name, c := range cases {
+		if _, err := c.p.Build(peers); !errors.Is(err, c.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, c.want)
+		}
+	}
+}
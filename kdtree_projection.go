@@ -0,0 +1,99 @@
+package poindexter
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+)
+
+// ErrInvalidProjection indicates random projection dimensions that are not
+// positive, or a target dimension above the input dimension.
+var ErrInvalidProjection = errors.New("kdtree: invalid random projection dimensions")
+
+// RandomProjection maps coordinates from a high input dimension down to a
+// lower one with a fixed random Gaussian matrix, so indexing and queries run
+// in far fewer dimensions while Euclidean distances are approximately kept:
+// by the Johnson–Lindenstrauss lemma, projecting n points to JLDim(n, eps)
+// dimensions keeps every pairwise distance within a factor 1±eps with high
+// probability. Other metrics are not preserved as such.
+//
+// The matrix depends only on the dimensions and seed, so a projection
+// recreated with the same three values maps coordinates identically; keep
+// them with the tree to project later queries. A RandomProjection is
+// immutable and safe for concurrent use.
+type RandomProjection struct {
+	inDim, outDim int
+	seed          uint64
+	rows          []float64 // outDim rows of inDim entries
+}
+
+// NewRandomProjection returns the projection from inDim to outDim dimensions
+// generated from seed. It returns ErrInvalidProjection unless
+// 0 < outDim <= inDim.
+func NewRandomProjection(inDim, outDim int, seed uint64) (*RandomProjection, error) {
+	if inDim <= 0 || outDim <= 0 || outDim > inDim {
+		return nil, ErrInvalidProjection
+	}
+	rng := rand.New(rand.NewPCG(seed, 0x4a4c))
+	rows := make([]float64, outDim*inDim)
+	scale := 1 / math.Sqrt(float64(outDim))
+	for i := range rows {
+		rows[i] = rng.NormFloat64() * scale
+	}
+	return &RandomProjection{inDim: inDim, outDim: outDim, seed: seed, rows: rows}, nil
+}
+
+// InputDim returns the dimension of the coordinates the projection accepts.
+func (p *RandomProjection) InputDim() int { return p.inDim }
+
+// OutputDim returns the dimension of projected coordinates.
+func (p *RandomProjection) OutputDim() int { return p.outDim }
+
+// Seed returns the seed the projection was generated from.
+func (p *RandomProjection) Seed() uint64 { return p.seed }
+
+// Project returns coords mapped to OutputDim dimensions, e.g. to query a
+// tree built from ProjectPoints. It returns ErrDimMismatch if coords does
+// not have InputDim entries.
+func (p *RandomProjection) Project(coords []float64) ([]float64, error) {
+	if len(coords) != p.inDim {
+		return nil, ErrDimMismatch
+	}
+	out := make([]float64, p.outDim)
+	for i := range out {
+		row := p.rows[i*p.inDim : (i+1)*p.inDim]
+		var s float64
+		for j, v := range coords {
+			s += row[j] * v
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+// ProjectPoints returns copies of pts with their coordinates projected by p,
+// ready for NewKDTree. IDs, values and labels are kept. It returns
+// ErrDimMismatch if a point does not have p.InputDim() coordinates.
+func ProjectPoints[T any](p *RandomProjection, pts []KDPoint[T]) ([]KDPoint[T], error) {
+	out := make([]KDPoint[T], len(pts))
+	for i, pt := range pts {
+		c, err := p.Project(pt.Coords)
+		if err != nil {
+			return nil, err
+		}
+		pt.Coords = c
+		out[i] = pt
+	}
+	return out, nil
+}
+
+// JLDim returns the target dimension the Johnson–Lindenstrauss lemma asks for
+// to keep all pairwise distances among n points within a factor 1±eps,
+// 4·ln(n) / (eps²/2 − eps³/3). It returns 0 unless n >= 2 and 0 < eps < 1.
+// The bound is conservative; in practice fewer dimensions often suffice.
+func JLDim(n int, eps float64) int {
+	if n < 2 || !(eps > 0 && eps < 1) {
+		return 0
+	}
+	return int(math.Ceil(4 * math.Log(float64(n)) / (eps*eps/2 - eps*eps*eps/3)))
+}
@@ -0,0 +1,73 @@
+package poindexter
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+func TestRandomProjectionPreservesDistances(t *testing.T) {
+	const n, in, out = 50, 512, 256
+	rng := rand.New(rand.NewPCG(3, 4))
+	pts := make([]KDPoint[int], n)
+	for i := range pts {
+		c := make([]float64, in)
+		for j := range c {
+			c[j] = rng.Float64()
+		}
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: c, Value: i, Labels: map[string]string{"k": "v"}}
+	}
+	p, err := NewRandomProjection(in, out, 99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proj, err := ProjectPoints(p, pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proj[0].Coords) != out || proj[7].ID != "7" || proj[7].Value != 7 || proj[7].Labels["k"] != "v" {
+		t.Fatalf("projected point = %+v", proj[7])
+	}
+	if len(pts[0].Coords) != in {
+		t.Fatal("input points modified")
+	}
+	var m EuclideanDistance
+	for i := range n {
+		for j := i + 1; j < n; j++ {
+			r := m.Distance(proj[i].Coords, proj[j].Coords) / m.Distance(pts[i].Coords, pts[j].Coords)
+			if math.Abs(r-1) > 0.25 {
+				t.Fatalf("distance ratio %d-%d = %v", i, j, r)
+			}
+		}
+	}
+
+	// the same dimensions and seed give the same projection
+	q, _ := NewRandomProjection(in, out, p.Seed())
+	a, _ := p.Project(pts[3].Coords)
+	b, _ := q.Project(pts[3].Coords)
+	if !reflect.DeepEqual(a, b) || q.InputDim() != in || q.OutputDim() != out {
+		t.Fatal("projection not reproducible from its seed")
+	}
+	if _, err := p.Project([]float64{1, 2}); err != ErrDimMismatch {
+		t.Fatalf("short coords: err = %v", err)
+	}
+}
+
+func TestRandomProjectionErrors(t *testing.T) {
+	for _, c := range [][2]int{{0, 1}, {4, 0}, {4, 5}} {
+		if _, err := NewRandomProjection(c[0], c[1], 1); err != ErrInvalidProjection {
+			t.Fatalf("dims %v: err = %v", c, err)
+		}
+	}
+}
+
+func TestJLDim(t *testing.T) {
+	if got := JLDim(1000, 0.5); got != 332 {
+		t.Fatalf("JLDim(1000, 0.5) = %d", got)
+	}
+	if JLDim(1, 0.5) != 0 || JLDim(100, 0) != 0 || JLDim(100, 1) != 0 {
+		t.Fatal("invalid arguments should give 0")
+	}
+}
@@ -0,0 +1,369 @@
+package poindexter
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// Protobuf helpers for the messages in proto/poindexter.proto. Encoding is done
+// directly against the protobuf wire format so the package keeps zero external
+// dependencies; the bytes interoperate with any generated protobuf bindings.
+
+// KDTreeSnapshot.version values written by ToProto: version 2 marks snapshots
+// carrying periods, so readers that would silently drop them reject the
+// snapshot instead; others keep version 1.
+const (
+	kdTreeProtoVersion         = 1
+	kdTreeProtoVersionPeriodic = 2
+)
+
+// ErrInvalidProto indicates malformed protobuf wire data.
+var ErrInvalidProto = errors.New("kdtree: invalid protobuf data")
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// KDPointToProto encodes p as a poindexter.v1.KDPoint message. If codec is nil
+// the value field is omitted. Labels are written as a map field, sorted by key.
+func KDPointToProto[T any](p KDPoint[T], codec *ValueCodec[T]) ([]byte, error) {
+	return appendProtoPoint(nil, p, codec)
+}
+
+// KDPointFromProto decodes a poindexter.v1.KDPoint message. If codec is nil the
+// value is left as the zero value of T.
+func KDPointFromProto[T any](b []byte, codec *ValueCodec[T]) (KDPoint[T], error) {
+	var p KDPoint[T]
+	err := walkProto(b, func(num int, typ int, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			p.ID = string(data)
+		case 2:
+			coords, err := decodeProtoDoubles(p.Coords, typ, v, data)
+			if err != nil {
+				return err
+			}
+			p.Coords = coords
+		case 3:
+			if codec != nil && codec.Decode != nil {
+				val, err := codec.Decode(data)
+				if err != nil {
+					return err
+				}
+				p.Value = val
+			}
+		case 4:
+			if typ != wireBytes {
+				return ErrInvalidProto
+			}
+			var k, lv string
+			if err := walkProto(data, func(num int, _ int, _ uint64, data []byte) error {
+				switch num {
+				case 1:
+					k = string(data)
+				case 2:
+					lv = string(data)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			if p.Labels == nil {
+				p.Labels = map[string]string{}
+			}
+			p.Labels[k] = lv
+		}
+		return nil
+	})
+	return p, err
+}
+
+// ToProto encodes the tree as a poindexter.v1.KDTreeSnapshot message, including
+// the periods of any WithPeriodicAxis axes. Trees using a custom
+// DistanceMetric return ErrUnknownMetric.
+func (t *KDTree[T]) ToProto(codec *ValueCodec[T]) ([]byte, error) {
+	name, weights, periods, err := serialMetric(t.metric)
+	if err != nil {
+		return nil, err
+	}
+	ver := uint64(kdTreeProtoVersion)
+	if periods != nil {
+		ver = kdTreeProtoVersionPeriodic
+	}
+	var b []byte
+	b = appendProtoVarint(b, 1, ver)
+	b = appendProtoVarint(b, 2, uint64(t.dim))
+	b = appendProtoBytes(b, 3, []byte(t.backend))
+	b = appendProtoBytes(b, 4, []byte(name))
+	b = appendProtoDoubles(b, 5, weights)
+	var pb []byte
+	for _, p := range t.points {
+		if pb, err = appendProtoPoint(pb[:0], p, codec); err != nil {
+			return nil, err
+		}
+		b = appendProtoBytes(b, 6, pb)
+	}
+	return appendProtoDoubles(b, 7, periods), nil
+}
+
+// KDTreeFromProto rebuilds a tree from a poindexter.v1.KDTreeSnapshot message.
+func KDTreeFromProto[T any](b []byte, codec *ValueCodec[T]) (*KDTree[T], error) {
+	var (
+		version, dim    uint64
+		backend, metric string
+		weights         []float64
+		periods         []float64
+		pts             []KDPoint[T]
+	)
+	err := walkProto(b, func(num int, typ int, v uint64, data []byte) error {
+		var err error
+		switch num {
+		case 1:
+			version = v
+		case 2:
+			dim = v
+		case 3:
+			backend = string(data)
+		case 4:
+			metric = string(data)
+		case 5:
+			weights, err = decodeProtoDoubles(weights, typ, v, data)
+		case 6:
+			var p KDPoint[T]
+			if p, err = KDPointFromProto(data, codec); err == nil {
+				pts = append(pts, p)
+			}
+		case 7:
+			periods, err = decodeProtoDoubles(periods, typ, v, data)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if version > kdTreeProtoVersionPeriodic {
+		return nil, ErrUnsupportedVersion
+	}
+	return newKDTreeFromSnapshot(int(dim), pts, metric, weights, KDBackend(backend), periodicOptions(periods)...)
+}
+
+// ToProto encodes the snapshot as a poindexter.v1.TreeAnalyticsSnapshot message.
+func (s TreeAnalyticsSnapshot) ToProto() []byte {
+	var b []byte
+	for i, v := range []int64{
+		s.QueryCount, s.InsertCount, s.DeleteCount,
+		s.AvgQueryTimeNs, s.MinQueryTimeNs, s.MaxQueryTimeNs, s.LastQueryTimeNs,
+		protoUnixNano(s.LastQueryAt), protoUnixNano(s.CreatedAt),
+		s.BackendRebuildCnt, protoUnixNano(s.LastRebuiltAt),
+		0, // field 12 is the double avg_radius_results
+		s.LastRebuildTimeNs, s.AvgRebuildTimeNs,
+		s.P50QueryTimeNs, s.P95QueryTimeNs, s.P99QueryTimeNs, s.P999QueryTimeNs,
+	} {
+		if v != 0 {
+			b = appendProtoVarint(b, i+1, uint64(v))
+		}
+	}
+	for _, f := range s.protoDoubles() {
+		if *f.p != 0 {
+			b = protoTag(b, f.num, wireFixed64)
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(*f.p))
+		}
+	}
+	if s.Anomalous {
+		b = appendProtoVarint(b, 22, 1)
+	}
+	return b
+}
+
+type protoDoubleField struct {
+	num int
+	p   *float64
+}
+
+// protoDoubles lists the snapshot's double fields by field number.
+func (s *TreeAnalyticsSnapshot) protoDoubles() []protoDoubleField {
+	return []protoDoubleField{
+		{12, &s.AvgRadiusResults},
+		{19, &s.BestDistanceMean},
+		{20, &s.BestDistanceP50},
+		{21, &s.BestDistanceP90},
+	}
+}
+
+// TreeAnalyticsSnapshotFromProto decodes a poindexter.v1.TreeAnalyticsSnapshot message.
+func TreeAnalyticsSnapshotFromProto(b []byte) (TreeAnalyticsSnapshot, error) {
+	var s TreeAnalyticsSnapshot
+	ints := make([]int64, 18)
+	doubles := s.protoDoubles()
+	err := walkProto(b, func(num int, typ int, v uint64, _ []byte) error {
+		switch {
+		case num >= 1 && num <= 18 && num != 12 && typ == wireVarint:
+			ints[num-1] = int64(v)
+		case num == 22 && typ == wireVarint:
+			s.Anomalous = v != 0
+		case typ == wireFixed64:
+			for _, f := range doubles {
+				if f.num == num {
+					*f.p = math.Float64frombits(v)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return TreeAnalyticsSnapshot{}, err
+	}
+	s.QueryCount = ints[0]
+	s.InsertCount = ints[1]
+	s.DeleteCount = ints[2]
+	s.AvgQueryTimeNs = ints[3]
+	s.MinQueryTimeNs = ints[4]
+	s.MaxQueryTimeNs = ints[5]
+	s.LastQueryTimeNs = ints[6]
+	s.LastQueryAt = time.Unix(0, ints[7])
+	s.CreatedAt = time.Unix(0, ints[8])
+	s.BackendRebuildCnt = ints[9]
+	s.LastRebuiltAt = time.Unix(0, ints[10])
+	s.LastRebuildTimeNs = ints[12]
+	s.AvgRebuildTimeNs = ints[13]
+	s.P50QueryTimeNs = ints[14]
+	s.P95QueryTimeNs = ints[15]
+	s.P99QueryTimeNs = ints[16]
+	s.P999QueryTimeNs = ints[17]
+	return s, nil
+}
+
+func appendProtoPoint[T any](b []byte, p KDPoint[T], codec *ValueCodec[T]) ([]byte, error) {
+	if p.ID != "" {
+		b = appendProtoBytes(b, 1, []byte(p.ID))
+	}
+	b = appendProtoDoubles(b, 2, p.Coords)
+	if codec != nil && codec.Encode != nil {
+		vb, err := codec.Encode(p.Value)
+		if err != nil {
+			return nil, err
+		}
+		if len(vb) > 0 {
+			b = appendProtoBytes(b, 3, vb)
+		}
+	}
+	var entry []byte
+	for _, k := range sortedLabelKeys(p.Labels) {
+		entry = appendProtoBytes(entry[:0], 1, []byte(k))
+		entry = appendProtoBytes(entry, 2, []byte(p.Labels[k]))
+		b = appendProtoBytes(b, 4, entry)
+	}
+	return b, nil
+}
+
+// protoUnixNano converts t to Unix nanoseconds, mapping the zero time to 0.
+func protoUnixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func protoTag(b []byte, num, typ int) []byte {
+	return binary.AppendUvarint(b, uint64(num)<<3|uint64(typ))
+}
+
+func appendProtoVarint(b []byte, num int, v uint64) []byte {
+	b = protoTag(b, num, wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendProtoBytes(b []byte, num int, data []byte) []byte {
+	b = protoTag(b, num, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// appendProtoDoubles writes a packed repeated double field (omitted if empty).
+func appendProtoDoubles(b []byte, num int, vs []float64) []byte {
+	if len(vs) == 0 {
+		return b
+	}
+	b = protoTag(b, num, wireBytes)
+	b = binary.AppendUvarint(b, uint64(8*len(vs)))
+	for _, v := range vs {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+	}
+	return b
+}
+
+// decodeProtoDoubles appends a repeated double field, accepting both packed
+// and unpacked encodings as protobuf parsers must.
+func decodeProtoDoubles(dst []float64, typ int, v uint64, data []byte) ([]float64, error) {
+	switch typ {
+	case wireFixed64:
+		return append(dst, math.Float64frombits(v)), nil
+	case wireBytes:
+		if len(data)%8 != 0 {
+			return nil, ErrInvalidProto
+		}
+		for i := 0; i < len(data); i += 8 {
+			dst = append(dst, math.Float64frombits(binary.LittleEndian.Uint64(data[i:])))
+		}
+		return dst, nil
+	default:
+		return nil, ErrInvalidProto
+	}
+}
+
+// walkProto iterates the fields of a protobuf message. For varint and fixed
+// fields v holds the value; for length-delimited fields data holds the payload.
+// Unknown fields are passed to fn, which may ignore them.
+func walkProto(b []byte, fn func(num int, typ int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return ErrInvalidProto
+		}
+		b = b[n:]
+		num, typ := int(key>>3), int(key&7)
+		if num == 0 {
+			return ErrInvalidProto
+		}
+		var v uint64
+		var data []byte
+		switch typ {
+		case wireVarint:
+			if v, n = binary.Uvarint(b); n <= 0 {
+				return ErrInvalidProto
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return ErrInvalidProto
+			}
+			v = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return ErrInvalidProto
+			}
+			v = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return ErrInvalidProto
+			}
+			data = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			return ErrInvalidProto
+		}
+		if err := fn(num, typ, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
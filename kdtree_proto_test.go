@@ -0,0 +1,114 @@
+package poindexter
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKDPointProto_WireFormat(t *testing.T) {
+	b, err := KDPointToProto(KDPoint[string]{ID: "a", Coords: []float64{1}, Value: "v"}, StringValueCodec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// field 1 (id), field 2 (packed coords: 8 bytes of 1.0), field 3 (value)
+	want := []byte{0x0a, 0x01, 'a', 0x12, 0x08, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, 0x1a, 0x01, 'v'}
+	if !bytes.Equal(b, want) {
+		t.Fatalf("wire mismatch:\n got %x\nwant %x", b, want)
+	}
+	p, err := KDPointFromProto(b, StringValueCodec())
+	if err != nil || p.ID != "a" || p.Value != "v" || len(p.Coords) != 1 || p.Coords[0] != 1 {
+		t.Fatalf("decode mismatch: %+v err=%v", p, err)
+	}
+	// unpacked coords (field 2, wire type 1) and an unknown field (15, varint) must be accepted
+	unpacked := []byte{0x11, 0, 0, 0, 0, 0, 0, 0, 0x40, 0x78, 0x05}
+	p, err = KDPointFromProto[string](unpacked, nil)
+	if err != nil || len(p.Coords) != 1 || p.Coords[0] != 2 {
+		t.Fatalf("unpacked decode mismatch: %+v err=%v", p, err)
+	}
+}
+
+func TestKDTreeProto_RoundTrip(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[string]{
+		{ID: "a", Coords: []float64{0, 0}, Value: "alpha"},
+		{ID: "b", Coords: []float64{3, 4}, Value: "beta"},
+	}, WithMetric(ManhattanDistance{}))
+	b, err := tr.ToProto(StringValueCodec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := KDTreeFromProto(b, StringValueCodec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Len() != 2 || got.Dim() != 2 {
+		t.Fatalf("unexpected len/dim %d/%d", got.Len(), got.Dim())
+	}
+	p, d, _ := got.Nearest([]float64{3, 3})
+	if p.Value != "beta" || d != 1 {
+		t.Fatalf("unexpected nearest %+v d=%v", p, d)
+	}
+	if _, err := KDTreeFromProto[string]([]byte{0x08}, nil); !errors.Is(err, ErrInvalidProto) {
+		t.Fatalf("want ErrInvalidProto for truncated data, got %v", err)
+	}
+}
+
+func TestKDPointProto_Labels(t *testing.T) {
+	b, err := KDPointToProto(KDPoint[string]{ID: "a", Labels: map[string]string{"z": "1", "r": "eu"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// field 1 (id), then one field 4 map entry per label, sorted by key
+	want := []byte{0x0a, 0x01, 'a', 0x22, 0x07, 0x0a, 0x01, 'r', 0x12, 0x02, 'e', 'u', 0x22, 0x06, 0x0a, 0x01, 'z', 0x12, 0x01, '1'}
+	if !bytes.Equal(b, want) {
+		t.Fatalf("wire mismatch:\n got %x\nwant %x", b, want)
+	}
+
+	pts := labelledPoints(20)
+	pts[1].Labels = nil
+	tr, _ := NewKDTree(pts)
+	b, err = tr.ToProto(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := KDTreeFromProto[int](b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range got.Points() {
+		if !reflect.DeepEqual(p.Labels, pts[i].Labels) {
+			t.Fatalf("point %s labels %v, want %v", p.ID, p.Labels, pts[i].Labels)
+		}
+	}
+	if _, err := KDPointFromProto[string]([]byte{0x20, 0x01}, nil); !errors.Is(err, ErrInvalidProto) {
+		t.Fatalf("want ErrInvalidProto for non-message labels entry, got %v", err)
+	}
+}
+
+func TestTreeAnalyticsSnapshotProto_RoundTrip(t *testing.T) {
+	now := time.Unix(0, time.Now().UnixNano())
+	s := TreeAnalyticsSnapshot{
+		QueryCount:        10,
+		InsertCount:       3,
+		AvgQueryTimeNs:    1500,
+		LastQueryAt:       now,
+		CreatedAt:         now.Add(-time.Hour),
+		BackendRebuildCnt: 2,
+		LastRebuildTimeNs: 700,
+		AvgRebuildTimeNs:  600,
+		AvgRadiusResults:  4.5,
+	}
+	got, err := TreeAnalyticsSnapshotFromProto(s.ToProto())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.QueryCount != 10 || got.InsertCount != 3 || got.AvgQueryTimeNs != 1500 || got.BackendRebuildCnt != 2 || got.AvgRadiusResults != 4.5 ||
+		got.LastRebuildTimeNs != 700 || got.AvgRebuildTimeNs != 600 {
+		t.Fatalf("counter mismatch: %+v", got)
+	}
+	if !got.LastQueryAt.Equal(now) || !got.CreatedAt.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("time mismatch: %v %v", got.LastQueryAt, got.CreatedAt)
+	}
+}
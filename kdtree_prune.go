@@ -0,0 +1,48 @@
+package poindexter
+
+import (
+	"math"
+	"sort"
+)
+
+// PruneWorstPeers removes the lowest-scoring fraction p (in [0,1]) of the
+// tree's points in one batched delete and returns the removed points, worst
+// first. scoreOf rates a point (higher is better), e.g. a peer quality score
+// computed from its Value. floor(p*Len()) points are removed; ties are broken
+// by tree order. p is clamped to [0,1]. Selection and removal happen in one
+// write, so scoreOf must not call back into the tree.
+//
+// Intended for periodic table hygiene: one call replaces a scan plus many
+// DeleteByID calls, rebuilding the backend index only once, and also works for
+// points without IDs.
+func PruneWorstPeers[T any](tree *KDTree[T], scoreOf func(KDPoint[T]) float64, p float64) []KDPoint[T] {
+	if tree == nil || scoreOf == nil {
+		return nil
+	}
+	p = math.Max(0, math.Min(1, p))
+	var pruned []KDPoint[T]
+	// Score and select under the write lock, so a concurrent mutation of a
+	// copy-on-write tree cannot shift the positions being removed.
+	tree.deleteChosen(func(points []KDPoint[T]) func(int, KDPoint[T]) bool {
+		n := len(points)
+		k := int(math.Floor(p * float64(n)))
+		if k == 0 {
+			return nil
+		}
+		scores := make([]float64, n)
+		order := make([]int, n)
+		for i, pt := range points {
+			scores[i] = scoreOf(pt)
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] < scores[order[b]] })
+		remove := make([]bool, n)
+		pruned = make([]KDPoint[T], k)
+		for i, idx := range order[:k] {
+			remove[idx] = true
+			pruned[i] = points[idx]
+		}
+		return func(i int, _ KDPoint[T]) bool { return remove[i] }
+	})
+	return pruned
+}
@@ -0,0 +1,101 @@
+package poindexter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestPruneWorstPeers(t *testing.T) {
+	pts := make([]KDPoint[float64], 10)
+	for i := range pts {
+		// quality score stored in Value; i=3 and i=7 are the worst
+		q := float64(10 + i)
+		if i == 3 || i == 7 {
+			q = float64(i) / 10
+		}
+		pts[i] = KDPoint[float64]{ID: fmt.Sprint("p", i), Coords: []float64{float64(i)}, Value: q}
+	}
+	tr, err := NewKDTree(pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	score := func(p KDPoint[float64]) float64 { return p.Value }
+
+	pruned := PruneWorstPeers(tr, score, 0.25) // floor(2.5) = 2
+	if len(pruned) != 2 || pruned[0].ID != "p3" || pruned[1].ID != "p7" {
+		t.Fatalf("unexpected pruned set %+v", pruned)
+	}
+	if tr.Len() != 8 {
+		t.Fatalf("expected 8 points left, got %d", tr.Len())
+	}
+	if tr.DeleteByID("p3") {
+		t.Fatal("p3 should already be gone")
+	}
+	// surviving IDs still resolve after compaction
+	if !tr.DeleteByID("p9") {
+		t.Fatal("expected p9 to remain deletable")
+	}
+	if p, _, _ := tr.Nearest([]float64{7}); p.ID != "p6" && p.ID != "p8" {
+		t.Fatalf("unexpected nearest %q", p.ID)
+	}
+	if got := tr.GetAnalyticsSnapshot().DeleteCount; got != 3 {
+		t.Fatalf("expected DeleteCount=3, got %d", got)
+	}
+
+	if PruneWorstPeers(tr, score, 0) != nil || PruneWorstPeers(tr, score, 0.1) != nil {
+		t.Fatal("expected nothing pruned below one point")
+	}
+	if got := PruneWorstPeers(tr, score, 2); len(got) != 7 || tr.Len() != 0 {
+		t.Fatalf("expected p clamped to 1 and all pruned, got %d left %d", len(got), tr.Len())
+	}
+}
+
+func TestPruneWorstPeers_NoIDs(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[int]{
+		{Coords: []float64{0}, Value: 5},
+		{Coords: []float64{1}, Value: 1},
+		{Coords: []float64{2}, Value: 3},
+	})
+	pruned := PruneWorstPeers(tr, func(p KDPoint[int]) float64 { return float64(p.Value) }, 0.5)
+	if len(pruned) != 1 || pruned[0].Value != 1 || tr.Len() != 2 {
+		t.Fatalf("unexpected prune result %+v len=%d", pruned, tr.Len())
+	}
+}
+
+func TestPruneWorstPeers_ConcurrentWriters(t *testing.T) {
+	pts := make([]KDPoint[float64], 40)
+	for i := range pts {
+		pts[i] = KDPoint[float64]{ID: fmt.Sprint("p", i), Coords: []float64{float64(i)}, Value: float64(i)}
+	}
+	tr, _ := NewKDTree(pts, WithCopyOnWrite())
+	score := func(p KDPoint[float64]) float64 { return p.Value }
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			id := fmt.Sprint("w", i)
+			tr.Insert(KDPoint[float64]{ID: id, Coords: []float64{float64(100 + i)}, Value: 1000})
+			tr.DeleteByID(id)
+		}
+	}()
+	var pruned []KDPoint[float64]
+	for i := 0; i < 5; i++ {
+		pruned = append(pruned, PruneWorstPeers(tr, score, 0.1)...)
+	}
+	wg.Wait()
+
+	for _, p := range pruned {
+		if p.Value >= 1000 {
+			t.Fatalf("pruned a high-scoring writer point %s", p.ID)
+		}
+		if tr.DeleteByID(p.ID) {
+			t.Fatalf("pruned point %s is still in the tree", p.ID)
+		}
+	}
+	if tr.Len()+len(pruned) != len(pts) {
+		t.Fatalf("%d left + %d pruned != %d", tr.Len(), len(pruned), len(pts))
+	}
+}
@@ -0,0 +1,53 @@
+package poindexter
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// qualityWindow is how many recent queries' best result distances
+// TreeAnalytics keeps for result-quality statistics.
+const qualityWindow = 1024
+
+// distanceWindow is a lock-free ring buffer of the most recent distances.
+// Readers racing a writer may see a slot's previous value, which is fine for
+// statistics. The zero value is empty and ready to use.
+type distanceWindow struct {
+	vals [qualityWindow]atomic.Uint64 // float64 bits
+	n    atomic.Int64                 // distances ever added
+}
+
+func (w *distanceWindow) add(d float64) {
+	i := w.n.Add(1) - 1
+	w.vals[i%qualityWindow].Store(math.Float64bits(d))
+}
+
+// values returns the distances in the window, oldest first.
+func (w *distanceWindow) values() []float64 {
+	n := w.n.Load()
+	size := min(n, qualityWindow)
+	out := make([]float64, size)
+	for i := range out {
+		out[i] = math.Float64frombits(w.vals[(n-size+int64(i))%qualityWindow].Load())
+	}
+	return out
+}
+
+func (w *distanceWindow) reset() {
+	w.n.Store(0)
+}
+
+// copyFrom overwrites w with the contents of src.
+func (w *distanceWindow) copyFrom(src *distanceWindow) {
+	for i := range w.vals {
+		w.vals[i].Store(src.vals[i].Load())
+	}
+	w.n.Store(src.n.Load())
+}
+
+// recordBest feeds the distance of a query's best result to analytics.
+func (t *KDTree[T]) recordBest(d float64) {
+	if t.analytics != nil {
+		t.analytics.RecordBestDistance(d)
+	}
+}
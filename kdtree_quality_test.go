@@ -0,0 +1,68 @@
+package poindexter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceWindow(t *testing.T) {
+	var w distanceWindow
+	if len(w.values()) != 0 {
+		t.Fatal("expected an empty window")
+	}
+	for i := 0; i < qualityWindow+10; i++ {
+		w.add(float64(i))
+	}
+	vals := w.values()
+	if len(vals) != qualityWindow || vals[0] != 10 || vals[len(vals)-1] != qualityWindow+9 {
+		t.Fatalf("expected the last %d values oldest first, got %v..%v (%d)", qualityWindow, vals[0], vals[len(vals)-1], len(vals))
+	}
+	var c distanceWindow
+	c.copyFrom(&w)
+	w.reset()
+	if len(w.values()) != 0 || len(c.values()) != qualityWindow {
+		t.Fatal("reset or copy failed")
+	}
+}
+
+func TestTreeAnalyticsBestDistance(t *testing.T) {
+	tree, err := NewKDTree([]KDPoint[int]{
+		{ID: "a", Coords: []float64{0}},
+		{ID: "b", Coords: []float64{10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Nearest([]float64{1})     // best 1
+	tree.KNearest([]float64{7}, 2) // best 3
+	tree.Radius([]float64{5}, 0.5) // no results: not recorded
+	tree.Radius([]float64{12}, 5)  // best 2
+	best := tree.Analytics().RecentBestDistances()
+	if best.Count != 3 || best.Min != 1 || best.Max != 3 || best.Mean != 2 {
+		t.Fatalf("unexpected best-distance stats %+v", best)
+	}
+	s := tree.GetAnalyticsSnapshot()
+	if s.BestDistanceMean != 2 || s.BestDistanceP50 != 2 || math.Abs(s.BestDistanceP90-2.8) > 1e-9 {
+		t.Fatalf("unexpected snapshot fields %+v", s)
+	}
+	tree.ResetAnalytics()
+	if tree.GetAnalyticsSnapshot().BestDistanceMean != 0 {
+		t.Fatal("ResetAnalytics should clear the window")
+	}
+}
+
+func TestTreeAnalyticsSnapshot_BestDistanceRoundTrip(t *testing.T) {
+	s := TreeAnalyticsSnapshot{QueryCount: 2, AvgRadiusResults: 1.5, BestDistanceMean: 0.25, BestDistanceP50: 0.2, BestDistanceP90: 0.75}
+	for name, enc := range map[string]func() (TreeAnalyticsSnapshot, error){
+		"proto":   func() (TreeAnalyticsSnapshot, error) { return TreeAnalyticsSnapshotFromProto(s.ToProto()) },
+		"msgpack": func() (TreeAnalyticsSnapshot, error) { return TreeAnalyticsSnapshotFromMsgpack(s.ToMsgpack()) },
+	} {
+		got, err := enc()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got.QueryCount != 2 || got.AvgRadiusResults != 1.5 || got.BestDistanceMean != 0.25 || got.BestDistanceP50 != 0.2 || got.BestDistanceP90 != 0.75 {
+			t.Fatalf("%s: mismatch %+v", name, got)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package poindexter
+
+// WithQueryDistributionTracking records the coordinates of every Nearest,
+// KNearest, Radius and RadiusAppend query in a streaming distribution per
+// axis, so operators can see where queries land and notice when the workload
+// drifts away from the stored points (see QueryAxisDistributions,
+// QueryAxisHistograms and QueryOutOfRange). sampleSize bounds the values kept
+// per axis for percentiles and histograms (<= 0 uses
+// DefaultDistributionSampleSize).
+func WithQueryDistributionTracking(sampleSize int) KDOption {
+	return func(o *kdOptions) {
+		if sampleSize <= 0 {
+			sampleSize = DefaultDistributionSampleSize
+		}
+		o.queryDistSample = sampleSize
+	}
+}
+
+// newQueryDist returns the per-axis query trackers requested by cfg, if any.
+func newQueryDist(cfg kdOptions, dim int) []*StreamingDistribution {
+	if cfg.queryDistSample <= 0 {
+		return nil
+	}
+	qd := make([]*StreamingDistribution, dim)
+	for i := range qd {
+		qd[i] = NewStreamingDistribution(cfg.queryDistSample)
+	}
+	return qd
+}
+
+// trackQuery records the query's coordinates when query distributions are
+// tracked.
+func (t *KDTree[T]) trackQuery(query []float64) {
+	for i, d := range t.queryDist {
+		d.Add(query[i])
+	}
+}
+
+// QueryAxisDistributions returns the distribution of query coordinates along
+// each axis since construction (or the last ResetAnalytics), named by
+// axisNames where given; each Histogram counts the retained sample. Comparing
+// it with ComputeDistanceDistribution shows whether queries target the region
+// the stored points cover. It returns nil unless the tree was built
+// WithQueryDistributionTracking.
+func (t *KDTree[T]) QueryAxisDistributions(axisNames []string) []AxisDistribution {
+	if t.queryDist == nil {
+		return nil
+	}
+	out := make([]AxisDistribution, len(t.queryDist))
+	for axis, d := range t.queryDist {
+		out[axis] = AxisDistribution{Axis: axis, Stats: d.Stats(), Histogram: ComputeHistogram(d.sample(), 0)}
+		if axis < len(axisNames) {
+			out[axis].Name = axisNames[axis]
+		}
+	}
+	return out
+}
+
+// QueryAxisHistograms returns a histogram of the sampled query coordinates
+// along each axis (see ComputeHistogram). Counts are of the retained sample,
+// not of every query. It returns nil unless the tree was built
+// WithQueryDistributionTracking.
+func (t *KDTree[T]) QueryAxisHistograms(bins int) []Histogram {
+	if t.queryDist == nil {
+		return nil
+	}
+	out := make([]Histogram, len(t.queryDist))
+	for axis, d := range t.queryDist {
+		out[axis] = ComputeHistogram(d.sample(), bins)
+	}
+	return out
+}
+
+// QueryOutOfRange returns, per axis, the share of sampled query coordinates
+// outside the range the tree's current points span on that axis. Shares
+// creeping up mean the workload is drifting away from the stored points. It
+// returns nil unless the tree was built WithQueryDistributionTracking, and
+// all ones for an empty tree with tracked queries.
+func (t *KDTree[T]) QueryOutOfRange() []float64 {
+	if v := t.cowView(); v != nil {
+		return v.QueryOutOfRange()
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	if t.queryDist == nil {
+		return nil
+	}
+	out := make([]float64, len(t.queryDist))
+	for axis, d := range t.queryDist {
+		sample := d.sample()
+		if len(sample) == 0 {
+			continue
+		}
+		lo, hi, ok := t.axisRange(axis)
+		outside := 0
+		for _, v := range sample {
+			if !ok || v < lo || v > hi {
+				outside++
+			}
+		}
+		out[axis] = float64(outside) / float64(len(sample))
+	}
+	return out
+}
+
+// axisRange returns the smallest and largest coordinate of the points on
+// axis, and false if there are no points.
+func (t *KDTree[T]) axisRange(axis int) (lo, hi float64, ok bool) {
+	for i, p := range t.points {
+		v := p.Coords[axis]
+		if i == 0 || v < lo {
+			lo = v
+		}
+		if i == 0 || v > hi {
+			hi = v
+		}
+	}
+	return lo, hi, len(t.points) > 0
+}
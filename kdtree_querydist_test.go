@@ -0,0 +1,58 @@
+package poindexter
+
+import "testing"
+
+func TestQueryDistributionTracking(t *testing.T) {
+	pts := []KDPoint[int]{
+		{ID: "a", Coords: []float64{0, 0}},
+		{ID: "b", Coords: []float64{1, 1}},
+	}
+	for _, cow := range []bool{false, true} {
+		opts := []KDOption{WithQueryDistributionTracking(0)}
+		if cow {
+			opts = append(opts, WithCopyOnWrite())
+		}
+		tree, err := NewKDTree(pts, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree.Nearest([]float64{0.5, 0.5})
+		tree.KNearest([]float64{0.25, 2}, 1)
+		tree.Radius([]float64{0.75, 3}, 0.1)
+		tree.RadiusAppend([]float64{1, 4}, 0.1, nil)
+		tree.Nearest([]float64{1}) // wrong dimension: ignored
+
+		dists := tree.QueryAxisDistributions([]string{"x"})
+		if len(dists) != 2 || dists[0].Name != "x" || dists[1].Name != "" {
+			t.Fatalf("cow=%v: unexpected distributions %+v", cow, dists)
+		}
+		if dists[0].Stats.Count != 4 || dists[0].Stats.Min != 0.25 || dists[0].Stats.Max != 1 || dists[1].Stats.Max != 4 {
+			t.Fatalf("cow=%v: unexpected query stats %+v", cow, dists)
+		}
+		out := tree.QueryOutOfRange()
+		if len(out) != 2 || out[0] != 0 || out[1] != 0.75 {
+			t.Fatalf("cow=%v: expected all x in range and 3/4 of y outside, got %v", cow, out)
+		}
+		hists := tree.QueryAxisHistograms(3)
+		if len(hists) != 2 || len(hists[1].Counts) != 3 || hists[1].Counts[0]+hists[1].Counts[1]+hists[1].Counts[2] != 4 {
+			t.Fatalf("cow=%v: unexpected histograms %+v", cow, hists)
+		}
+
+		c := tree.Clone(false)
+		tree.ResetAnalytics()
+		if tree.QueryAxisDistributions(nil)[0].Stats.Count != 0 {
+			t.Fatalf("cow=%v: ResetAnalytics should clear query tracking", cow)
+		}
+		if c.QueryAxisDistributions(nil)[0].Stats.Count != 4 {
+			t.Fatalf("cow=%v: clone should keep its own query tracking", cow)
+		}
+	}
+
+	plain, err := NewKDTree(pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.QueryAxisDistributions(nil) != nil || plain.QueryAxisHistograms(0) != nil || plain.QueryOutOfRange() != nil {
+		t.Fatal("expected nil results without tracking")
+	}
+}
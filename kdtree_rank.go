@@ -0,0 +1,77 @@
+package poindexter
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+// RankedNeighbor is a point with its distance from the query and its blended
+// score from RankByCombined.
+type RankedNeighbor[T any] struct {
+	Point    KDPoint[T]
+	Distance float64
+	Score    float64
+}
+
+// RankByCombined ranks every point by alpha·(1−normDist) + (1−alpha)·extScore
+// and returns the k best, highest score first. normDist is the point's
+// distance from query min-max normalised over the tree to [0,1], so the
+// nearest point contributes 1 and the farthest 0; extScore rates a point
+// independently of the query (e.g. uptime or a PeerQualityScore) and is
+// clamped to [0,1]. alpha (clamped to [0,1]) weights proximity against the
+// external score: 1 is pure nearest-neighbour order, 0 ignores distance. Ties
+// are broken by distance. Every point is scored, so the query is O(n) on
+// either backend. Returns nil if k <= 0 or the query dimension is wrong.
+func (t *KDTree[T]) RankByCombined(query []float64, k int, alpha float64, extScore func(KDPoint[T]) float64) []RankedNeighbor[T] {
+	if v := t.cowView(); v != nil {
+		return v.t.RankByCombined(query, k, alpha, extScore)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	if k <= 0 || len(query) != t.dim || len(t.points) == 0 {
+		return nil
+	}
+	start := t.queryStart()
+	defer func() {
+		if t.analytics != nil {
+			t.analytics.RecordQuery(time.Since(start).Nanoseconds())
+		}
+	}()
+
+	alpha = math.Max(0, math.Min(1, alpha))
+	ranked := make([]RankedNeighbor[T], len(t.points))
+	minD, maxD := math.Inf(1), math.Inf(-1)
+	for i, p := range t.points {
+		d := t.metric.Distance(query, p.Coords)
+		ranked[i] = RankedNeighbor[T]{Point: p, Distance: d}
+		minD, maxD = math.Min(minD, d), math.Max(maxD, d)
+	}
+	for i := range ranked {
+		norm := 0.0
+		if maxD > minD {
+			norm = (ranked[i].Distance - minD) / (maxD - minD)
+		}
+		ext := 0.0
+		if extScore != nil {
+			ext = math.Max(0, math.Min(1, extScore(ranked[i].Point)))
+		}
+		ranked[i].Score = alpha*(1-norm) + (1-alpha)*ext
+	}
+	slices.SortFunc(ranked, func(a, b RankedNeighbor[T]) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.Distance, b.Distance))
+	})
+	ranked = ranked[:min(k, len(ranked))]
+	for _, r := range ranked {
+		if t.peerAnalytics != nil {
+			t.peerAnalytics.RecordSelection(t.peerKey(r.Point), r.Distance)
+		}
+		t.recordResultDist(r.Distance)
+	}
+	if len(ranked) > 0 {
+		t.recordBest(ranked[0].Distance)
+	}
+	return ranked
+}
@@ -0,0 +1,40 @@
+package poindexter
+
+import "testing"
+
+func TestRankByCombined(t *testing.T) {
+	pts := []KDPoint[float64]{
+		{ID: "near-bad", Coords: []float64{0}, Value: 0.0},
+		{ID: "mid-good", Coords: []float64{5}, Value: 1.0},
+		{ID: "far-ok", Coords: []float64{10}, Value: 0.5},
+	}
+	tr, err := NewKDTree(pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	score := func(p KDPoint[float64]) float64 { return p.Value }
+	ids := func(rs []RankedNeighbor[float64]) []string {
+		out := make([]string, len(rs))
+		for i, r := range rs {
+			out[i] = r.Point.ID
+		}
+		return out
+	}
+
+	// alpha=1 is plain nearest-neighbour order
+	if got := ids(tr.RankByCombined([]float64{0}, 3, 1, score)); got[0] != "near-bad" || got[2] != "far-ok" {
+		t.Fatalf("alpha=1 order: %v", got)
+	}
+	// alpha=0 is external-score order
+	if got := ids(tr.RankByCombined([]float64{0}, 3, 0, score)); got[0] != "mid-good" || got[1] != "far-ok" {
+		t.Fatalf("alpha=0 order: %v", got)
+	}
+	// blend: near-bad = 0.5, mid-good = 0.5·0.5 + 0.5 = 0.75, far-ok = 0.25
+	rs := tr.RankByCombined([]float64{0}, 2, 0.5, score)
+	if len(rs) != 2 || rs[0].Point.ID != "mid-good" || rs[0].Score != 0.75 || rs[1].Score != 0.5 {
+		t.Fatalf("blended ranking: %+v", rs)
+	}
+	if tr.RankByCombined([]float64{0, 0}, 2, 0.5, score) != nil || tr.RankByCombined([]float64{0}, 0, 0.5, score) != nil {
+		t.Fatal("expected nil for bad dimension or k")
+	}
+}
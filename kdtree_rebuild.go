@@ -0,0 +1,234 @@
+package poindexter
+
+import "time"
+
+// RebuildPolicy decides when Insert/DeleteByID/DeleteWhere rebuild the backend
+// index (gonum, vptree, hnsw, lsh, rtree or covertree). Rebuilding costs O(n log n), so rebuilding after every
+// mutation dominates the cost of dynamic trees; the policies below trade index
+// freshness for fewer rebuilds. While the index is stale, queries still see
+// every mutation but fall back to a linear scan. The linear backend has no
+// index and ignores the policy.
+type RebuildPolicy struct {
+	everyN int
+	after  time.Duration
+	manual bool
+}
+
+// RebuildManual never rebuilds automatically; call KDTree.Rebuild after bulk
+// changes.
+var RebuildManual = RebuildPolicy{manual: true}
+
+// RebuildEveryN rebuilds once n points have been inserted or deleted since the
+// last rebuild. n <= 1 rebuilds on every mutation (the default policy).
+func RebuildEveryN(n int) RebuildPolicy {
+	return RebuildPolicy{everyN: max(n, 1)}
+}
+
+// RebuildAfter rebuilds in the background d after the first mutation since the
+// last rebuild, coalescing all mutations in that window into one rebuild. The
+// index is therefore at most d stale. Mutations are serialized internally with
+// the background rebuild. d <= 0 rebuilds on every mutation.
+func RebuildAfter(d time.Duration) RebuildPolicy {
+	if d <= 0 {
+		return RebuildPolicy{everyN: 1}
+	}
+	return RebuildPolicy{after: d}
+}
+
+// deferred reports whether the policy can leave the index stale.
+func (p RebuildPolicy) deferred() bool {
+	return p.manual || p.after > 0 || p.everyN > 1
+}
+
+// WithRebuildPolicy sets when mutations rebuild the backend index
+// (RebuildEveryN, RebuildAfter or RebuildManual). The default rebuilds after
+// every mutation.
+func WithRebuildPolicy(p RebuildPolicy) KDOption {
+	return func(o *kdOptions) { o.rebuildPolicy = p }
+}
+
+// WithAutoRebuild controls whether the backend index is rebuilt after
+// every Insert/DeleteByID/DeleteWhere (the default). With enabled=false the
+// index is only rebuilt by Rebuild; until then queries see every mutation but
+// fall back to a linear scan, so bulk churn costs one rebuild instead of one
+// per change. It is shorthand for WithRebuildPolicy(RebuildManual).
+func WithAutoRebuild(enabled bool) KDOption {
+	return func(o *kdOptions) {
+		if enabled {
+			o.rebuildPolicy = RebuildPolicy{}
+		} else {
+			o.rebuildPolicy = RebuildManual
+		}
+	}
+}
+
+// WithLeafSize sets how many points a gonum backend partition may hold before
+// it is split further. Partitions of n points or fewer become leaf buckets
+// that queries scan linearly, trading a little extra distance work for a
+// shallower tree with fewer nodes to visit and prune. Small leaves suit
+// low-dimensional data, where pruning is effective; in higher dimensions,
+// where most branches get visited anyway, leaves of 16-64 points cut the
+// per-node overhead. The best value depends on the data, so measure (see the
+// Leaf benchmarks). n <= 1 gives every point a node of its own, the default.
+func WithLeafSize(n int) KDOption {
+	return func(o *kdOptions) { o.leafSize = n }
+}
+
+// defaultTombstoneRatio is the share of deleted points the gonum index keeps
+// before DeleteByID compacts it.
+const defaultTombstoneRatio = 0.25
+
+// WithTombstoneRatio sets how many deleted points the gonum backend index may
+// hold, as a fraction of the points it was built over, before it is compacted.
+// DeleteByID marks the point as a tombstone in the index, which queries skip,
+// instead of rebuilding it; the delete that would exceed ratio rebuilds the
+// index from the live points. Tombstones make queries visit dead nodes, so a
+// smaller ratio keeps queries tighter at the cost of more rebuilds. ratio <= 0
+// rebuilds on every delete. The default is 0.25. Only applies when every
+// mutation is meant to update the index (the default rebuild policy).
+func WithTombstoneRatio(ratio float64) KDOption {
+	return func(o *kdOptions) { o.tombstoneRatio = ratio }
+}
+
+// Rebuild rebuilds the backend index from the current points, e.g. after bulk
+// churn on a tree built WithAutoRebuild(false). It is a no-op for the linear
+// backend. As with automatic rebuilds, a failed build falls back to the linear
+// backend.
+func (t *KDTree[T]) Rebuild() {
+	if t.serialWrites() {
+		t.writeMu.Lock()
+		defer t.writeMu.Unlock()
+	}
+	if t.checked() {
+		t.beginWrite()
+		defer t.endWrite()
+	}
+	if !t.indexed() {
+		return
+	}
+	t.rebuildIndex()
+	if t.cow {
+		t.publish()
+	}
+}
+
+// queryIndex returns the index queries should use, or nil for a linear scan.
+// When every mutation rebuilds, an index being replaced is still served, so
+// readers are not blocked by the rebuild; under a deferred policy a stale
+// index is skipped.
+func (t *KDTree[T]) queryIndex() *kdIndex[T] {
+	ix := t.index.Load()
+	if ix != nil && t.rebuildPolicy.deferred() && ix.version != t.version.Load() {
+		return nil
+	}
+	return ix
+}
+
+// serialWrites reports whether mutations must hold writeMu.
+func (t *KDTree[T]) serialWrites() bool {
+	return t.cow || t.rebuildPolicy.after > 0
+}
+
+// indexAfterMutation applies the rebuild policy after n points changed.
+func (t *KDTree[T]) indexAfterMutation(n int) {
+	if !t.indexed() {
+		return
+	}
+	p := t.rebuildPolicy
+	switch {
+	case p.manual:
+	case p.after > 0:
+		t.pendingMuts += n
+		if t.rebuildTimer == nil {
+			t.rebuildTimer = time.AfterFunc(p.after, t.deferredRebuild)
+		}
+	default:
+		t.pendingMuts += n
+		if t.pendingMuts >= max(p.everyN, 1) {
+			t.rebuildIndex()
+		}
+	}
+}
+
+// indexAfterInsert updates the index after Insert appended a point. When every
+// mutation is meant to rebuild and the index was current before the insert,
+// the point is added to the existing index instead of rebuilding it.
+func (t *KDTree[T]) indexAfterInsert() {
+	if t.backend == BackendGonum && !t.rebuildPolicy.deferred() && t.insertIndex() {
+		return
+	}
+	t.indexAfterMutation(1)
+}
+
+// insertIndex publishes an index extended by the last point of t.points. It
+// reports false if the index is not exactly one insert behind or the backend
+// asks for a full rebuild. The new index's points may share the old one's
+// backing array: slots past the old length are never visible through it.
+func (t *KDTree[T]) insertIndex() bool {
+	t.rebuildMu.Lock()
+	defer t.rebuildMu.Unlock()
+	cur := t.index.Load()
+	ver := t.version.Load()
+	if cur == nil || cur.version != ver-1 || len(cur.points)-cur.tombstones+1 != len(t.points) {
+		return false
+	}
+	pts := append(cur.points, t.points[len(t.points)-1])
+	data, ok := gonumInsert[T](cur.data, pts)
+	if !ok {
+		return false
+	}
+	t.index.Store(&kdIndex[T]{data: data, points: pts, version: ver, tombstones: cur.tombstones})
+	return true
+}
+
+// indexAfterDelete updates the index after DeleteByID removed p. As with
+// inserts, a current index is updated in place, here by tombstoning p,
+// unless the tombstone ratio calls for compaction.
+func (t *KDTree[T]) indexAfterDelete(p KDPoint[T]) {
+	if t.backend == BackendGonum && !t.rebuildPolicy.deferred() && t.tombstoneIndex(p) {
+		return
+	}
+	t.indexAfterMutation(1)
+}
+
+// tombstoneIndex publishes an index in which p, removed from t.points by the
+// last mutation, is tombstoned. p is found by an exact-match search on its
+// coordinates; its ID is unique among the index's live points.
+func (t *KDTree[T]) tombstoneIndex(p KDPoint[T]) bool {
+	t.rebuildMu.Lock()
+	defer t.rebuildMu.Unlock()
+	cur := t.index.Load()
+	ver := t.version.Load()
+	if cur == nil || cur.version != ver-1 || p.ID == "" {
+		return false
+	}
+	pos := -1
+	gonumRadiusEach[T](cur.data, p.Coords, 0, func(idx int, _ float64) {
+		if pos < 0 && cur.points[idx].ID == p.ID {
+			pos = idx
+		}
+	})
+	if pos < 0 {
+		return false
+	}
+	data, ok := gonumDelete(cur.data, pos, t.tombstoneRatio)
+	if !ok {
+		return false
+	}
+	t.index.Store(&kdIndex[T]{data: data, points: cur.points, version: ver, tombstones: cur.tombstones + 1})
+	return true
+}
+
+// deferredRebuild is RebuildAfter's timer callback.
+func (t *KDTree[T]) deferredRebuild() {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	t.rebuildTimer = nil
+	if !t.indexed() || t.pendingMuts == 0 {
+		return
+	}
+	t.rebuildIndex()
+	if t.cow {
+		t.publish()
+	}
+}
@@ -0,0 +1,53 @@
+package poindexter
+
+import (
+	"sort"
+	"time"
+)
+
+// ReverseKNearest returns the stored points that would count the query among
+// their k nearest neighbours if it were inserted, nearest to the query first,
+// with their distances to it. A point qualifies when fewer than k other
+// points are strictly closer to it than the query is, so ties go the query's
+// way and every point qualifies while the tree holds k or fewer points. The
+// length of the result estimates how many peers running a k-nearest selection
+// would pick a candidate at query.
+//
+// Each call runs one k-nearest search per stored point (a full scan per point
+// on the linear backend). It records query analytics but no peer selections.
+// Returns nil if k <= 0, the tree is empty or the query dimensionality does
+// not match Dim().
+func (t *KDTree[T]) ReverseKNearest(query []float64, k int) ([]KDPoint[T], []float64) {
+	if v := t.cowView(); v != nil {
+		return v.ReverseKNearest(query, k)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	if k <= 0 || len(query) != t.dim || len(t.points) == 0 {
+		return nil, nil
+	}
+	start := t.queryStart()
+	defer func() {
+		if t.analytics != nil {
+			t.analytics.RecordQuery(time.Since(start).Nanoseconds())
+		}
+	}()
+
+	kth := t.kthNeighborDistances(k) // nil when every point qualifies
+	var sel []knnItem
+	for i, p := range t.points {
+		d := t.metric.Distance(query, p.Coords)
+		if kth == nil || d <= kth[i] {
+			sel = append(sel, knnItem{idx: i, dist: d})
+		}
+	}
+	sort.SliceStable(sel, func(a, b int) bool { return sel[a].dist < sel[b].dist })
+	pts := make([]KDPoint[T], len(sel))
+	dists := make([]float64, len(sel))
+	for i, it := range sel {
+		pts[i] = t.points[it.idx]
+		dists[i] = it.dist
+	}
+	return pts, dists
+}
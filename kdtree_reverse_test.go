@@ -0,0 +1,72 @@
+package poindexter
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestReverseKNearestMatchesBruteForce(t *testing.T) {
+	pts := makePoints(300, 2)
+	m := EuclideanDistance{}
+	for _, b := range []KDBackend{BackendLinear, BackendGonum, BackendVPTree} {
+		tr, err := NewKDTree(pts, WithBackend(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, q := range [][]float64{{0.5, 0.5}, {0, 0}, {2, 2}} {
+			for _, k := range []int{1, 3, 10} {
+				var want []string
+				for _, p := range pts {
+					d := m.Distance(q, p.Coords)
+					closer := 0
+					for _, o := range pts {
+						if o.ID != p.ID && m.Distance(p.Coords, o.Coords) < d {
+							closer++
+						}
+					}
+					if closer < k {
+						want = append(want, p.ID)
+					}
+				}
+				got, dists := tr.ReverseKNearest(q, k)
+				if !sort.Float64sAreSorted(dists) {
+					t.Fatalf("%s k=%d: distances not sorted: %v", b, k, dists)
+				}
+				gotIDs := ids(got)
+				sort.Strings(gotIDs)
+				sort.Strings(want)
+				if len(gotIDs) != len(want) {
+					t.Fatalf("%s q=%v k=%d: got %v, want %v", b, q, k, gotIDs, want)
+				}
+				for i := range want {
+					if gotIDs[i] != want[i] {
+						t.Fatalf("%s q=%v k=%d: got %v, want %v", b, q, k, gotIDs, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestReverseKNearestSmallTree(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[int]{pt1("a", 0), pt1("b", 1), pt1("c", 10)})
+	// c's nearest other point is b at 9, so a query at 9.5 takes its place;
+	// a and b are each other's nearest and keep it
+	if got, dists := tr.ReverseKNearest([]float64{9.5}, 1); len(got) != 1 || got[0].ID != "c" || dists[0] != 0.5 {
+		t.Fatalf("ReverseKNearest k=1 = %v %v, want [c]", ids(got), dists)
+	}
+	// ties go to the query: b's neighbour a is exactly 1 away
+	if got, _ := tr.ReverseKNearest([]float64{2}, 1); len(got) != 2 || got[0].ID != "b" || got[1].ID != "c" {
+		t.Fatalf("ReverseKNearest tie = %v, want [b c]", ids(got))
+	}
+	// with k >= Len every point would keep the query
+	if got, _ := tr.ReverseKNearest([]float64{100}, 3); len(got) != 3 || got[0].ID != "c" {
+		t.Fatalf("ReverseKNearest k=3 = %v, want all points, c first", ids(got))
+	}
+	if got, _ := tr.ReverseKNearest([]float64{0}, 0); got != nil {
+		t.Fatalf("k=0: got %v, want nil", got)
+	}
+	if got, _ := tr.ReverseKNearest([]float64{0, 0}, 1); got != nil {
+		t.Fatalf("dimension mismatch: got %v, want nil", got)
+	}
+}
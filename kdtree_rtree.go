@@ -0,0 +1,295 @@
+package poindexter
+
+import (
+	"math"
+	"sort"
+)
+
+// The R-tree backend (BackendRTree) groups points into nested axis-aligned
+// bounding boxes, bulk-loaded with Sort-Tile-Recursive packing so sibling
+// boxes barely overlap. It is built for workloads dominated by WithinBox
+// queries — e.g. "all peers inside this latitude/longitude rectangle" —
+// which descend only into boxes intersecting the query box. Nearest,
+// KNearest and Radius are answered exactly with a branch-and-bound search
+// under Euclidean, Manhattan and Chebyshev metrics; under other metrics they
+// fall back to the linear scan, while WithinBox stays indexed.
+
+// rtNodeSize is the maximum number of entries in an R-tree node.
+const rtNodeSize = 16
+
+// rtNode is an R-tree node: a bounding box over either child nodes or, in a
+// leaf, point indices.
+type rtNode struct {
+	min, max []float64
+	children []*rtNode
+	idxs     []int32 // leaf entries
+}
+
+// rtBackend is an immutable STR-packed R-tree over a point set.
+type rtBackend struct {
+	root   *rtNode
+	dim    int
+	metric DistanceMetric
+	coords func(i int) []float64
+	len    int
+	// lpMetric reports whether metric is an Lp norm, for which the distance
+	// to the nearest point of a box bounds the distance to any point in it.
+	lpMetric bool
+}
+
+// buildRTreeBackend bulk-loads an R-tree over points. It works with any
+// metric; see rtBackend.lpMetric.
+func buildRTreeBackend[T any](points []KDPoint[T], metric DistanceMetric) (any, error) {
+	if metric == nil {
+		return nil, ErrBackendUnavailable
+	}
+	b := &rtBackend{
+		metric: metric,
+		coords: func(i int) []float64 { return points[i].Coords },
+		len:    len(points),
+	}
+	switch metric.(type) {
+	case EuclideanDistance, ManhattanDistance, ChebyshevDistance:
+		b.lpMetric = true
+	}
+	if len(points) == 0 {
+		return b, nil
+	}
+	b.dim = len(points[0].Coords)
+
+	// leaves over the points
+	idxs := make([]int, len(points))
+	for i := range idxs {
+		idxs[i] = i
+	}
+	var level []*rtNode
+	for _, g := range strPack(idxs, b.coords, b.dim) {
+		n := &rtNode{idxs: make([]int32, len(g))}
+		for i, j := range g {
+			n.idxs[i] = int32(j)
+		}
+		b.fitPoints(n)
+		level = append(level, n)
+	}
+	// pack each level into parents until a single root remains
+	for len(level) > 1 {
+		centers := make([][]float64, len(level))
+		for i, n := range level {
+			c := make([]float64, b.dim)
+			for a := range c {
+				c[a] = (n.min[a] + n.max[a]) / 2
+			}
+			centers[i] = c
+		}
+		nodes := make([]int, len(level))
+		for i := range nodes {
+			nodes[i] = i
+		}
+		var parents []*rtNode
+		for _, g := range strPack(nodes, func(i int) []float64 { return centers[i] }, b.dim) {
+			p := &rtNode{children: make([]*rtNode, len(g))}
+			for i, j := range g {
+				p.children[i] = level[j]
+			}
+			b.fitChildren(p)
+			parents = append(parents, p)
+		}
+		level = parents
+	}
+	b.root = level[0]
+	return b, nil
+}
+
+// strPack partitions items into groups of at most rtNodeSize by
+// Sort-Tile-Recursive packing on their center coordinates: sort by the first
+// axis, cut into slabs, sort each slab by the next axis, and so on.
+func strPack(items []int, center func(i int) []float64, dim int) [][]int {
+	var groups [][]int
+	var tile func(items []int, axis int)
+	tile = func(items []int, axis int) {
+		sort.Slice(items, func(i, j int) bool { return center(items[i])[axis] < center(items[j])[axis] })
+		if axis == dim-1 || len(items) <= rtNodeSize {
+			for len(items) > 0 {
+				n := min(rtNodeSize, len(items))
+				groups = append(groups, items[:n:n])
+				items = items[n:]
+			}
+			return
+		}
+		leaves := (len(items) + rtNodeSize - 1) / rtNodeSize
+		slabs := int(math.Ceil(math.Pow(float64(leaves), 1/float64(dim-axis))))
+		per := rtNodeSize * ((leaves + slabs - 1) / slabs)
+		for len(items) > 0 {
+			n := min(per, len(items))
+			tile(items[:n:n], axis+1)
+			items = items[n:]
+		}
+	}
+	tile(items, 0)
+	return groups
+}
+
+func (b *rtBackend) fitPoints(n *rtNode) {
+	n.min = append([]float64(nil), b.coords(int(n.idxs[0]))...)
+	n.max = append([]float64(nil), n.min...)
+	for _, i := range n.idxs[1:] {
+		for a, v := range b.coords(int(i)) {
+			n.min[a] = math.Min(n.min[a], v)
+			n.max[a] = math.Max(n.max[a], v)
+		}
+	}
+}
+
+func (b *rtBackend) fitChildren(n *rtNode) {
+	n.min = append([]float64(nil), n.children[0].min...)
+	n.max = append([]float64(nil), n.children[0].max...)
+	for _, c := range n.children[1:] {
+		for a := range n.min {
+			n.min[a] = math.Min(n.min[a], c.min[a])
+			n.max[a] = math.Max(n.max[a], c.max[a])
+		}
+	}
+}
+
+// inBox reports whether c lies in the box [lo, hi]; see KDTree.WithinBox for
+// wrapped axes.
+func inBox(c, lo, hi []float64) bool {
+	for a, v := range c {
+		if lo[a] <= hi[a] {
+			if v < lo[a] || v > hi[a] {
+				return false
+			}
+		} else if v < lo[a] && v > hi[a] {
+			return false
+		}
+	}
+	return true
+}
+
+// intersects reports whether n's bounding box overlaps the box [lo, hi].
+func (n *rtNode) intersects(lo, hi []float64) bool {
+	for a := range n.min {
+		if lo[a] <= hi[a] {
+			if n.max[a] < lo[a] || n.min[a] > hi[a] {
+				return false
+			}
+		} else if n.max[a] < lo[a] && n.min[a] > hi[a] {
+			return false
+		}
+	}
+	return true
+}
+
+// box calls visit for every point in the box [lo, hi].
+func (b *rtBackend) box(lo, hi []float64, visit func(idx int)) bool {
+	if b.root == nil || len(lo) != b.dim || len(hi) != b.dim {
+		return false
+	}
+	stack := []*rtNode{b.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !n.intersects(lo, hi) {
+			continue
+		}
+		for _, i := range n.idxs {
+			if inBox(b.coords(int(i)), lo, hi) {
+				visit(int(i))
+			}
+		}
+		stack = append(stack, n.children...)
+	}
+	return true
+}
+
+// minDist returns the metric distance from q to the closest point of n's box,
+// using scratch (len dim) for that point.
+func (b *rtBackend) minDist(q []float64, n *rtNode, scratch []float64) float64 {
+	for a, v := range q {
+		scratch[a] = math.Min(math.Max(v, n.min[a]), n.max[a])
+	}
+	return b.metric.Distance(q, scratch)
+}
+
+// rtSearchItem is a node pending visit with its box's distance to the query.
+type rtSearchItem struct {
+	n    *rtNode
+	dist float64
+}
+
+// search walks the tree depth-first, nearest boxes first, skipping boxes
+// farther than limit() and calling visit for each point it reaches.
+func (b *rtBackend) search(query []float64, limit func() float64, visit func(idx int, dist float64)) {
+	scratch := make([]float64, b.dim)
+	stack := []rtSearchItem{{b.root, b.minDist(query, b.root, scratch)}}
+	var kids []rtSearchItem
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if it.dist > limit() {
+			continue
+		}
+		for _, i := range it.n.idxs {
+			visit(int(i), b.metric.Distance(query, b.coords(int(i))))
+		}
+		kids = kids[:0]
+		for _, c := range it.n.children {
+			kids = append(kids, rtSearchItem{c, b.minDist(query, c, scratch)})
+		}
+		// push farthest first so the nearest child is explored next
+		sort.Slice(kids, func(i, j int) bool { return kids[i].dist > kids[j].dist })
+		stack = append(stack, kids...)
+	}
+}
+
+func (b *rtBackend) kNearest(query []float64, k int, skip func(idx int) bool) ([]int, []float64) {
+	if b.root == nil || !b.lpMetric || len(query) != b.dim || k <= 0 {
+		return nil, nil
+	}
+	var h knnHeap
+	limit := func() float64 {
+		if h.Len() < k {
+			return math.Inf(1)
+		}
+		return h.peek().dist
+	}
+	b.search(query, limit, func(idx int, d float64) {
+		if skip != nil && skip(idx) {
+			return
+		}
+		if h.Len() < k {
+			h.push(knnItem{idx: idx, dist: d})
+		} else if d < h.peek().dist {
+			h[0] = knnItem{idx: idx, dist: d}
+			h.down(0)
+		}
+	})
+	sort.Slice(h, func(i, j int) bool { return h[i].dist < h[j].dist })
+	idxs := make([]int, len(h))
+	dists := make([]float64, len(h))
+	for i := range h {
+		idxs[i] = h[i].idx
+		dists[i] = h[i].dist
+	}
+	return idxs, dists
+}
+
+func (b *rtBackend) nearest(query []float64) (int, float64, bool) {
+	idxs, dists := b.kNearest(query, 1, nil)
+	if len(idxs) == 0 {
+		return -1, 0, false
+	}
+	return idxs[0], dists[0], true
+}
+
+func (b *rtBackend) radiusEach(query []float64, r float64, visit func(idx int, dist float64)) bool {
+	if b.root == nil || !b.lpMetric || len(query) != b.dim || r < 0 {
+		return false
+	}
+	b.search(query, func() float64 { return r }, func(idx int, d float64) {
+		if d <= r {
+			visit(idx, d)
+		}
+	})
+	return true
+}
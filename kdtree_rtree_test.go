@@ -0,0 +1,93 @@
+package poindexter
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// geoTestPoints returns n random (latitude, longitude) points.
+func geoTestPoints(rng *rand.Rand, n int) []KDPoint[int] {
+	pts := make([]KDPoint[int], n)
+	for i := range pts {
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{rng.Float64()*180 - 90, rng.Float64()*360 - 180}, Value: i}
+	}
+	return pts
+}
+
+func TestRTree_WithinBox(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	rt, err := NewKDTree(geoTestPoints(rng, 3000), WithBackend(BackendRTree))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt.Backend() != BackendRTree {
+		t.Fatalf("backend %s", rt.Backend())
+	}
+	rt.Insert(KDPoint[int]{ID: "edge", Coords: []float64{10, 20}})
+	rt.DeleteByID("5")
+	lin, _ := NewKDTree(rt.Points(), WithBackend(BackendLinear))
+
+	boxes := [][2][]float64{
+		{{-10, -10}, {10, 20}},   // "edge" lies on the boundary
+		{{40, 170}, {60, -170}},  // wraps across the antimeridian
+		{{-90, -180}, {90, 180}}, // everything
+		{{0, 0}, {0, 0}},         // empty
+	}
+	for i := 0; i < 30; i++ {
+		lat, lon := rng.Float64()*160-80, rng.Float64()*340-170
+		boxes = append(boxes, [2][]float64{{lat, lon}, {lat + rng.Float64()*20, lon + rng.Float64()*20}})
+	}
+	for _, b := range boxes {
+		got := rt.WithinBox(b[0], b[1])
+		want := lin.WithinBox(b[0], b[1])
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("box %v: %d points, linear %d", b, len(got), len(want))
+		}
+	}
+	if n := len(rt.WithinBox([]float64{-10, -10}, []float64{10, 20})); n == 0 {
+		t.Fatal("expected points in the box")
+	}
+	if got := rt.WithinBox([]float64{0}, []float64{1}); got != nil {
+		t.Fatal("dimension mismatch must return nil")
+	}
+	if got := rt.Snapshot().WithinBox([]float64{40, 170}, []float64{60, -170}); len(got) != len(lin.WithinBox([]float64{40, 170}, []float64{60, -170})) {
+		t.Fatal("snapshot box query differs")
+	}
+}
+
+func TestRTree_MatchesLinear(t *testing.T) {
+	rng := rand.New(rand.NewSource(12))
+	pts := make([]KDPoint[int], 1000)
+	for i := range pts {
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{rng.Float64(), rng.Float64(), rng.Float64()}}
+	}
+	for _, m := range []DistanceMetric{EuclideanDistance{}, ManhattanDistance{}, ChebyshevDistance{}, CosineDistance{}} {
+		t.Run(fmt.Sprintf("%T", m), func(t *testing.T) {
+			rt, _ := NewKDTree(pts, WithMetric(m), WithBackend(BackendRTree))
+			if rt.Backend() != BackendRTree {
+				t.Fatalf("backend %s", rt.Backend())
+			}
+			lin, _ := NewKDTree(pts, WithMetric(m), WithBackend(BackendLinear))
+			for q := 0; q < 30; q++ {
+				query := []float64{rng.Float64(), rng.Float64(), rng.Float64()}
+				_, dr, _ := rt.Nearest(query)
+				_, dl, _ := lin.Nearest(query)
+				if dr != dl {
+					t.Fatalf("nearest: %v vs %v", dr, dl)
+				}
+				_, kr := rt.KNearest(query, 7)
+				_, kl := lin.KNearest(query, 7)
+				if !reflect.DeepEqual(kr, kl) {
+					t.Fatalf("kNearest: %v vs %v", kr, kl)
+				}
+				rr, _ := rt.Radius(query, kl[4])
+				rl, _ := lin.Radius(query, kl[4])
+				if len(rr) != len(rl) {
+					t.Fatalf("radius: %d vs %d", len(rr), len(rl))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package poindexter
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// SampleUniform returns n distinct points chosen uniformly at random, in
+// random order: a fresh probe schedule or a spread of peers to share load
+// across. If the tree holds n or fewer points, all of them are returned,
+// shuffled. Returns nil if n <= 0 or the tree is empty. Sampling does not
+// record query analytics or peer selections.
+func (t *KDTree[T]) SampleUniform(n int) []KDPoint[T] {
+	if v := t.cowView(); v != nil {
+		return v.SampleUniform(n)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	if n <= 0 || len(t.points) == 0 {
+		return nil
+	}
+	n = min(n, len(t.points))
+	// partial Fisher-Yates over positions
+	idxs := make([]int, len(t.points))
+	for i := range idxs {
+		idxs[i] = i
+	}
+	out := make([]KDPoint[T], n)
+	for i := range out {
+		j := i + rand.IntN(len(idxs)-i)
+		idxs[i], idxs[j] = idxs[j], idxs[i]
+		out[i] = t.points[idxs[i]]
+	}
+	return out
+}
+
+// SampleWeighted returns n distinct points chosen at random with probability
+// proportional to weightOf, without replacement: each draw picks among the
+// points not yet drawn in proportion to their weights, and the result lists
+// points in draw order. Weight healthy or well-provisioned peers up to steer
+// more probes or load their way without starving the rest.
+//
+// Points whose weight is zero, negative or NaN are never drawn, so fewer than
+// n points come back when too few have a positive weight. Returns nil if
+// n <= 0 or weightOf is nil. weightOf is called once per point. Sampling does
+// not record query analytics or peer selections.
+func (t *KDTree[T]) SampleWeighted(n int, weightOf func(KDPoint[T]) float64) []KDPoint[T] {
+	if v := t.cowView(); v != nil {
+		return v.SampleWeighted(n, weightOf)
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	if n <= 0 || weightOf == nil {
+		return nil
+	}
+	// Efraimidis-Spirakis: the n largest keys u^(1/w) are a weighted sample
+	// without replacement, and sorting by key gives the draw order. The heap
+	// holds -log(key) so its top is the smallest key kept.
+	var h knnHeap
+	for i, p := range t.points {
+		w := weightOf(p)
+		if !(w > 0) {
+			continue
+		}
+		u := 1 - rand.Float64() // (0, 1]
+		k := -math.Log(u) / w
+		if h.Len() < n {
+			h.push(knnItem{idx: i, dist: k})
+		} else if k < h.peek().dist {
+			h[0] = knnItem{idx: i, dist: k}
+			h.down(0)
+		}
+	}
+	if h.Len() == 0 {
+		return nil
+	}
+	sort.Slice(h, func(i, j int) bool { return h[i].dist < h[j].dist })
+	out := make([]KDPoint[T], len(h))
+	for i, it := range h {
+		out[i] = t.points[it.idx]
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package poindexter
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func sampleTestTree(t *testing.T, n int) *KDTree[int] {
+	t.Helper()
+	pts := make([]KDPoint[int], n)
+	for i := range pts {
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{float64(i)}, Value: i}
+	}
+	tr, err := NewKDTree(pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+func distinctIDs(t *testing.T, pts []KDPoint[int]) {
+	t.Helper()
+	seen := make(map[string]bool)
+	for _, p := range pts {
+		if seen[p.ID] {
+			t.Fatalf("%s sampled twice", p.ID)
+		}
+		seen[p.ID] = true
+	}
+}
+
+func TestSampleUniform(t *testing.T) {
+	tr := sampleTestTree(t, 10)
+	if tr.SampleUniform(0) != nil {
+		t.Fatal("n=0 must return nil")
+	}
+	if got := tr.SampleUniform(20); len(got) != 10 {
+		t.Fatalf("want all 10 points, got %d", len(got))
+	}
+	counts := make([]int, 10)
+	for i := 0; i < 5000; i++ {
+		s := tr.SampleUniform(3)
+		if len(s) != 3 {
+			t.Fatalf("want 3 points, got %d", len(s))
+		}
+		distinctIDs(t, s)
+		for _, p := range s {
+			counts[p.Value]++
+		}
+	}
+	// each point is in a sample with probability 3/10: 1500 expected
+	for i, c := range counts {
+		if c < 1300 || c > 1700 {
+			t.Fatalf("point %d sampled %d times, want about 1500", i, c)
+		}
+	}
+	if got := tr.Snapshot().SampleUniform(4); len(got) != 4 {
+		t.Fatalf("snapshot: %d points", len(got))
+	}
+}
+
+func TestSampleWeighted(t *testing.T) {
+	tr := sampleTestTree(t, 4)
+	// point 3 is excluded; first draws follow 1:2:5
+	weight := func(p KDPoint[int]) float64 { return []float64{1, 2, 5, 0}[p.Value] }
+	if tr.SampleWeighted(0, weight) != nil || tr.SampleWeighted(2, nil) != nil {
+		t.Fatal("want nil")
+	}
+	if got := tr.SampleWeighted(10, weight); len(got) != 3 {
+		t.Fatalf("want the 3 positively weighted points, got %d", len(got))
+	}
+	first := make([]int, 4)
+	const trials = 8000
+	for i := 0; i < trials; i++ {
+		s := tr.SampleWeighted(2, weight)
+		if len(s) != 2 {
+			t.Fatalf("want 2 points, got %d", len(s))
+		}
+		distinctIDs(t, s)
+		first[s[0].Value]++
+	}
+	for i, w := range []float64{1, 2, 5, 0} {
+		want := trials * w / 8
+		if math.Abs(float64(first[i])-want) > 0.05*trials {
+			t.Fatalf("point %d drawn first %d times, want about %.0f", i, first[i], want)
+		}
+	}
+	neg := func(KDPoint[int]) float64 { return math.NaN() }
+	if tr.SampleWeighted(2, neg) != nil {
+		t.Fatal("NaN weights must never be drawn")
+	}
+}
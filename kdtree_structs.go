@@ -0,0 +1,173 @@
+package poindexter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrStructTags indicates BuildFromStructs could not derive features from the
+// item type's kd struct tags.
+var ErrStructTags = errors.New("kdtree: invalid kd struct tags")
+
+// BuildFromStructs builds points from structs (or pointers to structs) whose
+// fields carry kd tags, instead of one closure per axis:
+//
+//	type Peer struct {
+//		ID     string  `kd:"id"`
+//		PingMS float64 `kd:"axis=0"`
+//		Hops   int     `kd:"axis=1,weight=0.5"`
+//		Score  float64 `kd:"axis=2,weight=1.2,invert"`
+//	}
+//
+// "axis=N" makes a numeric or bool field (true is 1) feature axis N; axes
+// must be numbered 0..D-1 without gaps. "weight=W" sets the axis weight
+// (default 1) and "invert" inverts it, as in BuildND. "id" marks the string
+// field that supplies point IDs; without one IDs are empty. Fields of
+// promoted embedded structs count; one behind a nil embedded pointer reads
+// as NaN, which WithMissingValuePolicy can handle. opts are passed to BuildND.
+// Tags BuildFromStructs cannot use yield ErrStructTags.
+func BuildFromStructs[T any](items []T, opts ...BuildOption) ([]KDPoint[T], error) {
+	features, weights, invert, id, err := structFeatures[T]()
+	if err != nil {
+		return nil, err
+	}
+	return BuildND(items, id, features, weights, invert, opts...)
+}
+
+// structFeatures derives BuildND arguments from T's kd tags.
+func structFeatures[T any]() (features []func(T) float64, weights []float64, invert []bool, id func(T) string, err error) {
+	rt := reflect.TypeFor[T]()
+	ptr := rt.Kind() == reflect.Pointer
+	if ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return nil, nil, nil, nil, fmt.Errorf("%w: %v is not a struct", ErrStructTags, rt)
+	}
+	// value returns the struct an item points to, if any
+	value := func(item T) (reflect.Value, bool) {
+		v := reflect.ValueOf(item)
+		if ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		return v, true
+	}
+
+	type axisField struct {
+		index  []int
+		kind   reflect.Kind
+		weight float64
+		invert bool
+	}
+	axes := map[int]axisField{}
+	for _, f := range reflect.VisibleFields(rt) {
+		tag, ok := f.Tag.Lookup("kd")
+		if !ok || tag == "-" || f.Anonymous {
+			continue
+		}
+		if tag == "id" {
+			if f.Type.Kind() != reflect.String || id != nil {
+				return nil, nil, nil, nil, fmt.Errorf("%w: id field %s must be the only string id", ErrStructTags, f.Name)
+			}
+			index := f.Index
+			id = func(item T) string {
+				v, ok := value(item)
+				if !ok {
+					return ""
+				}
+				fv, err := v.FieldByIndexErr(index)
+				if err != nil {
+					return ""
+				}
+				return fv.String()
+			}
+			continue
+		}
+		af := axisField{index: f.Index, kind: f.Type.Kind(), weight: 1}
+		axis := -1
+		for _, part := range strings.Split(tag, ",") {
+			key, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch key {
+			case "axis":
+				if axis, err = strconv.Atoi(val); err != nil || axis < 0 {
+					return nil, nil, nil, nil, fmt.Errorf("%w: field %s: bad axis %q", ErrStructTags, f.Name, val)
+				}
+			case "weight":
+				if af.weight, err = strconv.ParseFloat(val, 64); err != nil {
+					return nil, nil, nil, nil, fmt.Errorf("%w: field %s: bad weight %q", ErrStructTags, f.Name, val)
+				}
+			case "invert":
+				af.invert = true
+			default:
+				return nil, nil, nil, nil, fmt.Errorf("%w: field %s: unknown key %q", ErrStructTags, f.Name, key)
+			}
+		}
+		if axis < 0 {
+			return nil, nil, nil, nil, fmt.Errorf("%w: field %s has no axis", ErrStructTags, f.Name)
+		}
+		if !numericKind(af.kind) {
+			return nil, nil, nil, nil, fmt.Errorf("%w: field %s is %v, not numeric", ErrStructTags, f.Name, f.Type)
+		}
+		if _, dup := axes[axis]; dup {
+			return nil, nil, nil, nil, fmt.Errorf("%w: axis %d used twice", ErrStructTags, axis)
+		}
+		axes[axis] = af
+	}
+	if len(axes) == 0 {
+		return nil, nil, nil, nil, fmt.Errorf("%w: %v has no axis fields", ErrStructTags, rt)
+	}
+	for axis := 0; axis < len(axes); axis++ {
+		af, ok := axes[axis]
+		if !ok {
+			return nil, nil, nil, nil, fmt.Errorf("%w: axis %d missing", ErrStructTags, axis)
+		}
+		features = append(features, func(item T) float64 {
+			v, ok := value(item)
+			if !ok {
+				return math.NaN()
+			}
+			fv, err := v.FieldByIndexErr(af.index)
+			if err != nil {
+				return math.NaN()
+			}
+			return numericValue(fv)
+		})
+		weights = append(weights, af.weight)
+		invert = append(invert, af.invert)
+	}
+	return features, weights, invert, id, nil
+}
+
+func numericKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Bool:
+		return true
+	}
+	return false
+}
+
+// numericValue reads a field of a numericKind as a float64.
+func numericValue(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Bool:
+		if v.Bool() {
+			return 1
+		}
+	}
+	return 0
+}
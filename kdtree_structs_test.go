@@ -0,0 +1,92 @@
+package poindexter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type taggedBase struct {
+	Loss float64 `kd:"axis=3"`
+}
+
+type taggedPeer struct {
+	*taggedBase
+	Name   string  `kd:"id"`
+	PingMS float64 `kd:"axis=0"`
+	Hops   uint8   `kd:"axis=1,weight=0.5"`
+	Score  float32 `kd:"axis=2, weight=2, invert"`
+	Relay  bool    `kd:"-"`
+	note   string
+}
+
+func TestBuildFromStructs(t *testing.T) {
+	peers := []taggedPeer{
+		{taggedBase: &taggedBase{Loss: 0}, Name: "a", PingMS: 10, Hops: 1, Score: 1},
+		{taggedBase: &taggedBase{Loss: 1}, Name: "b", PingMS: 30, Hops: 3, Score: 0},
+	}
+	got, err := BuildFromStructs(peers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := BuildND(peers, func(p taggedPeer) string { return p.Name }, []func(taggedPeer) float64{
+		func(p taggedPeer) float64 { return p.PingMS },
+		func(p taggedPeer) float64 { return float64(p.Hops) },
+		func(p taggedPeer) float64 { return float64(p.Score) },
+		func(p taggedPeer) float64 { return p.Loss },
+	}, []float64{1, 0.5, 2, 1}, []bool{false, false, true, false})
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("BuildFromStructs = %v, want %v", got, want)
+	}
+
+	// pointers work too, and a nil embedded struct reads as missing
+	ptrs := []*taggedPeer{&peers[0], &peers[1], {Name: "c", PingMS: 20, Hops: 2}}
+	pts, err := BuildFromStructs(ptrs, WithMissingValuePolicy(MissingDrop))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 2 || pts[0].ID != "a" || pts[1].Value != ptrs[1] {
+		t.Fatalf("pointer items = %v", pts)
+	}
+}
+
+func TestBuildFromStructsBadTags(t *testing.T) {
+	type gap struct {
+		A float64 `kd:"axis=0"`
+		B float64 `kd:"axis=2"`
+	}
+	type dup struct {
+		A float64 `kd:"axis=0"`
+		B float64 `kd:"axis=0"`
+	}
+	type text struct {
+		A string `kd:"axis=0"`
+	}
+	type unknown struct {
+		A float64 `kd:"axis=0,scale=2"`
+	}
+	type none struct{ A float64 }
+	type badID struct {
+		A  float64 `kd:"axis=0"`
+		ID int     `kd:"id"`
+	}
+	for name, err := range map[string]error{
+		"gap":     buildErr[gap](),
+		"dup":     buildErr[dup](),
+		"text":    buildErr[text](),
+		"unknown": buildErr[unknown](),
+		"none":    buildErr[none](),
+		"badID":   buildErr[badID](),
+		"int":     buildErr[int](),
+	} {
+		if !errors.Is(err, ErrStructTags) {
+			t.Errorf("%s: err = %v, want ErrStructTags", name, err)
+		}
+	}
+}
+
+func buildErr[T any]() error {
+	var zero T
+	_, err := BuildFromStructs([]T{zero})
+	return err
+}
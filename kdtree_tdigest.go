@@ -0,0 +1,230 @@
+package poindexter
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"sync"
+)
+
+// DefaultTDigestCompression is the t-digest compression used when none is
+// given. It keeps at most a few hundred centroids, with quantile errors well
+// under 1% and smallest near the tails.
+const DefaultTDigestCompression = 100
+
+// TDigest estimates quantiles of a stream of values in bounded memory, using
+// a merging t-digest: values are buffered and periodically folded into
+// weighted centroids that are kept small near the tails, so extreme
+// percentiles stay accurate. Unlike StreamingDistribution, every value
+// contributes to the estimates, not just a reservoir sample. Safe for
+// concurrent use.
+type TDigest struct {
+	mu          sync.Mutex
+	compression float64
+	centroids   []tdCentroid // sorted by mean
+	buf         []float64    // values not yet merged into centroids
+	count       int64
+	min, max    float64
+}
+
+type tdCentroid struct {
+	mean, weight float64
+}
+
+// NewTDigest creates an empty t-digest. Higher compression keeps more
+// centroids for more accurate quantiles (<= 0 uses DefaultTDigestCompression).
+func NewTDigest(compression float64) *TDigest {
+	if compression <= 0 {
+		compression = DefaultTDigestCompression
+	}
+	return &TDigest{compression: compression}
+}
+
+// Add records a single value. NaNs are ignored.
+func (d *TDigest) Add(v float64) {
+	d.mu.Lock()
+	d.add(v)
+	d.mu.Unlock()
+}
+
+// AddAll records every value in vs.
+func (d *TDigest) AddAll(vs []float64) {
+	d.mu.Lock()
+	for _, v := range vs {
+		d.add(v)
+	}
+	d.mu.Unlock()
+}
+
+func (d *TDigest) add(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	if d.count == 0 {
+		d.min, d.max = v, v
+	} else {
+		d.min, d.max = math.Min(d.min, v), math.Max(d.max, v)
+	}
+	d.count++
+	d.buf = append(d.buf, v)
+	if len(d.buf) >= int(5*d.compression) {
+		d.merge()
+	}
+}
+
+// merge folds the buffered values into the centroids. The caller holds mu.
+func (d *TDigest) merge() {
+	if len(d.buf) == 0 {
+		return
+	}
+	all := make([]tdCentroid, 0, len(d.centroids)+len(d.buf))
+	all = append(all, d.centroids...)
+	for _, v := range d.buf {
+		all = append(all, tdCentroid{v, 1})
+	}
+	d.buf = d.buf[:0]
+	slices.SortFunc(all, func(a, b tdCentroid) int { return cmp.Compare(a.mean, b.mean) })
+
+	total := float64(d.count)
+	out := d.centroids[:0]
+	cur := all[0]
+	before := 0.0 // weight of the centroids already emitted
+	for _, c := range all[1:] {
+		w := cur.weight + c.weight
+		// Merge while the combined centroid spans at most one unit of the
+		// scale function, which shrinks centroids towards q = 0 and q = 1.
+		if d.scale((before+w)/total)-d.scale(before/total) <= 1 {
+			cur.mean += (c.mean - cur.mean) * c.weight / w
+			cur.weight = w
+			continue
+		}
+		out = append(out, cur)
+		before += cur.weight
+		cur = c
+	}
+	d.centroids = append(out, cur)
+}
+
+// scale is the k1 t-digest scale function, δ/(2π)·asin(2q−1).
+func (d *TDigest) scale(q float64) float64 {
+	return d.compression / (2 * math.Pi) * math.Asin(2*math.Max(0, math.Min(1, q))-1)
+}
+
+// Count returns the number of values recorded.
+func (d *TDigest) Count() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.count
+}
+
+// Quantile estimates the q-th quantile (q in [0,1], clamped) of the recorded
+// values, interpolating between centroid means and the exact minimum and
+// maximum. Returns 0 if nothing has been recorded.
+func (d *TDigest) Quantile(q float64) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.count == 0 {
+		return 0
+	}
+	d.merge()
+	q = math.Max(0, math.Min(1, q))
+	total := float64(d.count)
+	target := q * total
+	// Each centroid's mean sits at the middle of the weight it covers; the
+	// minimum sits at weight 0 and the maximum at the total.
+	prevAt, prevMean := 0.0, d.min
+	cum := 0.0
+	for _, c := range d.centroids {
+		at := cum + c.weight/2
+		if target < at {
+			return tdInterpolate(target, prevAt, prevMean, at, c.mean)
+		}
+		prevAt, prevMean = at, c.mean
+		cum += c.weight
+	}
+	return tdInterpolate(target, prevAt, prevMean, total, d.max)
+}
+
+func tdInterpolate(x, x0, y0, x1, y1 float64) float64 {
+	if x1 <= x0 {
+		return y1
+	}
+	return y0 + (x-x0)/(x1-x0)*(y1-y0)
+}
+
+// Reset clears all recorded values.
+func (d *TDigest) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.centroids, d.buf = d.centroids[:0], d.buf[:0]
+	d.count, d.min, d.max = 0, 0, 0
+}
+
+// clone returns an independent copy of the digest. If empty is true only the
+// configuration (compression) is kept.
+func (d *TDigest) clone(empty bool) *TDigest {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c := NewTDigest(d.compression)
+	if empty {
+		return c
+	}
+	c.centroids = slices.Clone(d.centroids)
+	c.buf = slices.Clone(d.buf)
+	c.count, c.min, c.max = d.count, d.min, d.max
+	return c
+}
+
+// WithResultDistanceDigest feeds the distances returned by every query into a
+// t-digest on the tree, so ResultDistanceQuantile can report approximate
+// percentiles of match distance, such as the median and P95, over every
+// result rather than a sample. compression trades memory for accuracy (<= 0
+// uses DefaultTDigestCompression).
+func WithResultDistanceDigest(compression float64) KDOption {
+	return func(o *kdOptions) {
+		if compression <= 0 {
+			compression = DefaultTDigestCompression
+		}
+		o.resultDigest = compression
+	}
+}
+
+// newResultDigest returns the result-distance t-digest requested by cfg, if any.
+func newResultDigest(cfg kdOptions) *TDigest {
+	if cfg.resultDigest <= 0 {
+		return nil
+	}
+	return NewTDigest(cfg.resultDigest)
+}
+
+// ResultDistanceQuantile returns the approximate q-quantile (0 <= q <= 1) of
+// the distances returned by queries since construction (or the last
+// ResetAnalytics), e.g. 0.5 for the median match distance and 0.95 for the
+// P95. It returns 0 unless WithResultDistanceDigest was set.
+func (t *KDTree[T]) ResultDistanceQuantile(q float64) float64 {
+	if t.resultDigest == nil {
+		return 0
+	}
+	return t.resultDigest.Quantile(q)
+}
+
+// recordResultDist feeds one returned distance to the result-distance trackers.
+func (t *KDTree[T]) recordResultDist(d float64) {
+	if t.resultDist != nil {
+		t.resultDist.Add(d)
+	}
+	if t.resultDigest != nil {
+		t.resultDigest.Add(d)
+	}
+}
+
+// recordResultDists feeds a query's returned distances to the result-distance
+// trackers.
+func (t *KDTree[T]) recordResultDists(ds []float64) {
+	if t.resultDist != nil {
+		t.resultDist.AddAll(ds)
+	}
+	if t.resultDigest != nil {
+		t.resultDigest.AddAll(ds)
+	}
+}
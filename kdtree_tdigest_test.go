@@ -0,0 +1,96 @@
+package poindexter
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestTDigestQuantile(t *testing.T) {
+	d := NewTDigest(0)
+	if d.Quantile(0.5) != 0 || d.Count() != 0 {
+		t.Fatal("expected an empty digest")
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	vals := make([]float64, 100_000)
+	for i := range vals {
+		vals[i] = rng.ExpFloat64()
+	}
+	d.AddAll(vals)
+	d.Add(math.NaN())
+	if d.Count() != int64(len(vals)) {
+		t.Fatalf("count %d", d.Count())
+	}
+	if len(d.centroids) > 10*DefaultTDigestCompression {
+		t.Fatalf("%d centroids, memory not bounded", len(d.centroids))
+	}
+	slices.Sort(vals)
+	for _, q := range []float64{0.01, 0.1, 0.5, 0.9, 0.95, 0.99, 0.999} {
+		want := vals[int(q*float64(len(vals)))]
+		// compare ranks: the estimate should sit within 1% of the true rank
+		rank, _ := slices.BinarySearch(vals, d.Quantile(q))
+		if math.Abs(float64(rank)/float64(len(vals))-q) > 0.01 {
+			t.Errorf("q%v = %v (rank %d), want ~%v", q, d.Quantile(q), rank, want)
+		}
+	}
+	if d.Quantile(0) != vals[0] || d.Quantile(1) != vals[len(vals)-1] {
+		t.Fatalf("extremes %v, %v", d.Quantile(0), d.Quantile(1))
+	}
+
+	c := d.clone(false)
+	d.Reset()
+	if d.Count() != 0 || d.Quantile(0.5) != 0 {
+		t.Fatal("Reset should clear the digest")
+	}
+	if c.Count() != int64(len(vals)) || c.clone(true).Count() != 0 {
+		t.Fatal("clone should keep its own data, and an empty clone none")
+	}
+}
+
+func TestTDigest_SmallSets(t *testing.T) {
+	d := NewTDigest(100)
+	d.Add(7)
+	if d.Quantile(0) != 7 || d.Quantile(0.5) != 7 || d.Quantile(1) != 7 {
+		t.Fatal("single value should be every quantile")
+	}
+	d.AddAll([]float64{1, 2, 3, 4})
+	if got := d.Quantile(0.5); got < 2 || got > 4 {
+		t.Fatalf("median of 1..4,7 = %v", got)
+	}
+}
+
+func TestKDTreeResultDistanceDigest(t *testing.T) {
+	pts := make([]KDPoint[int], 100)
+	for i := range pts {
+		pts[i] = KDPoint[int]{ID: strconv.Itoa(i), Coords: []float64{float64(i)}}
+	}
+	tree, err := NewKDTree(pts, WithResultDistanceDigest(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		tree.Nearest([]float64{float64(i) + 0.25})
+	}
+	tree.KNearest([]float64{50}, 3)
+	if got := tree.ResultDistanceQuantile(0.5); math.Abs(got-0.25) > 1e-9 {
+		t.Fatalf("median match distance %v, want 0.25", got)
+	}
+	if got := tree.ResultDistanceQuantile(1); got != 1 {
+		t.Fatalf("max match distance %v, want 1", got)
+	}
+	if c := tree.Clone(false); c.ResultDistanceQuantile(0.5) == 0 {
+		t.Fatal("clone lost the digest")
+	}
+	tree.ResetAnalytics()
+	if tree.ResultDistanceQuantile(0.5) != 0 {
+		t.Fatal("ResetAnalytics should clear the digest")
+	}
+
+	plain, _ := NewKDTree(pts)
+	plain.Nearest([]float64{3})
+	if plain.ResultDistanceQuantile(0.5) != 0 {
+		t.Fatal("expected 0 without WithResultDistanceDigest")
+	}
+}
@@ -0,0 +1,93 @@
+package poindexter
+
+import "math"
+
+// WithStableTies makes queries break equal distances by point ID, so results
+// are reproducible across runs and backends: Nearest, KNearest, Radius,
+// RadiusAppend, their Into and batch variants, KNearestExcluding and
+// NearestToID order tied points by ascending ID, and when ties straddle the
+// k-th result, the points with the smallest IDs make the cut. Points without
+// an ID sort first among their ties, in no particular order. Settling a tie
+// at the cut costs an extra radius search. Approximate backends (HNSW, LSH)
+// only order the ties they found.
+func WithStableTies() KDOption {
+	return func(o *kdOptions) { o.stableTies = true }
+}
+
+// search fills h, in ascending order, with up to k points within distance r
+// of query, leaving out those for which skip, if not nil, is true. It is the
+// query path for trees that weight points or break ties, and for exclusions;
+// with weights, distances are effective distances. Either k or r is bounded.
+func (t *KDTree[T]) search(query []float64, k int, r float64, skip func(KDPoint[T]) bool, h *neighborHeap[T]) {
+	t.collect(query, k, r, skip, h)
+	if t.stableTies {
+		t.breakTies(query, k, skip, h)
+	}
+}
+
+// collect is search without tie breaking.
+func (t *KDTree[T]) collect(query []float64, k int, r float64, skip func(KDPoint[T]) bool, h *neighborHeap[T]) {
+	switch {
+	case t.weightOf != nil:
+		t.weightedSearch(query, k, r, skip, h)
+	case k < math.MaxInt:
+		t.knnSearch(query, k, skip, h)
+	default:
+		t.radiusSearch(query, r, skip, h)
+	}
+}
+
+// radiusSearch fills h, in ascending order, with the points within r of query
+// for which skip, if not nil, is false.
+func (t *KDTree[T]) radiusSearch(query []float64, r float64, skip func(KDPoint[T]) bool, h *neighborHeap[T]) {
+	visit := func(p KDPoint[T], d float64) {
+		if d <= r && (skip == nil || !skip(p)) {
+			h.offer(p, d, math.MaxInt)
+		}
+	}
+	ix := t.queryIndex()
+	if ix == nil || !indexRadiusEach[T](ix.data, query, r, func(idx int, d float64) { visit(ix.points[idx], d) }) {
+		scanDistances(t.metric, query, t.points, func(i int, d float64) { visit(t.points[i], d) })
+	}
+	h.sort()
+}
+
+// breakTies orders the ascending results in h by ID within equal distances.
+// If h holds k results, the points tied with the last one are gathered with a
+// radius search and the smallest IDs among them fill the tail.
+func (t *KDTree[T]) breakTies(query []float64, k int, skip func(KDPoint[T]) bool, h *neighborHeap[T]) {
+	h.sortTies()
+	n := len(h.dists)
+	if n == 0 || n < k {
+		return // every candidate is in h
+	}
+	if ix := t.queryIndex(); ix != nil && t.weightOf == nil {
+		switch ix.data.(type) {
+		case *hnswBackend, *lshBackend:
+			return // no exact radius search to find the other ties
+		}
+	}
+	last := h.dists[n-1]
+	first := n - 1
+	for first > 0 && h.dists[first-1] == last {
+		first--
+	}
+	var ties neighborHeap[T]
+	t.collect(query, math.MaxInt, last, skip, &ties)
+	ties.sortTies()
+	j := 0
+	for j < len(ties.dists) && ties.dists[j] < last {
+		j++
+	}
+	copy(h.pts[first:], ties.pts[j:])
+}
+
+// sortTies orders entries of equal distance by ID. h must already be sorted
+// by distance, so runs of ties are short and insertion sort suffices.
+func (h *neighborHeap[T]) sortTies() {
+	for i := 1; i < len(h.dists); i++ {
+		for j := i; j > 0 && h.dists[j] == h.dists[j-1] && h.pts[j].ID < h.pts[j-1].ID; j-- {
+			h.swap(j, j-1)
+		}
+	}
+}
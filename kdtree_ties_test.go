@@ -0,0 +1,68 @@
+package poindexter
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// gridPoints returns an n×n integer grid, where most distances tie, with IDs
+// in a shuffled order so insertion order does not coincide with ID order.
+func gridPoints(n int) []KDPoint[int] {
+	pts := make([]KDPoint[int], 0, n*n)
+	for i := 0; i < n*n; i++ {
+		j := (i * 7) % (n * n)
+		pts = append(pts, KDPoint[int]{ID: fmt.Sprintf("p%03d", i), Coords: []float64{float64(j % n), float64(j / n)}})
+	}
+	return pts
+}
+
+func ids(pts []KDPoint[int]) []string {
+	out := make([]string, len(pts))
+	for i, p := range pts {
+		out[i] = p.ID
+	}
+	return out
+}
+
+func TestStableTiesAcrossBackends(t *testing.T) {
+	pts := gridPoints(10)
+	ref, _ := NewKDTree(pts, WithBackend(BackendLinear), WithMetric(ManhattanDistance{}), WithStableTies())
+	for _, b := range []KDBackend{BackendGonum, BackendVPTree, BackendCoverTree, BackendRTree} {
+		tr, err := NewKDTree(pts, WithBackend(b), WithMetric(ManhattanDistance{}), WithStableTies())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, q := range [][]float64{{5, 5}, {0, 0}, {4.5, 2}} {
+			for _, k := range []int{1, 3, 8, 30} {
+				want, _ := ref.KNearest(q, k)
+				got, _ := tr.KNearest(q, k)
+				if !reflect.DeepEqual(ids(got), ids(want)) {
+					t.Fatalf("%s q=%v k=%d: %v, want %v", b, q, k, ids(got), ids(want))
+				}
+			}
+			want, _ := ref.Radius(q, 3)
+			got, _ := tr.Radius(q, 3)
+			if !reflect.DeepEqual(ids(got), ids(want)) {
+				t.Fatalf("%s q=%v: Radius %v, want %v", b, q, ids(got), ids(want))
+			}
+			wp, _, _ := ref.Nearest(q)
+			if p, _, _ := tr.Nearest(q); p.ID != wp.ID {
+				t.Fatalf("%s q=%v: Nearest %s, want %s", b, q, p.ID, wp.ID)
+			}
+		}
+	}
+}
+
+func TestStableTiesPicksSmallestIDs(t *testing.T) {
+	pts := []KDPoint[int]{pt1("d", 1), pt1("b", -1), pt1("c", 1), pt1("a", -1), pt1("e", 0)}
+	tr, _ := NewKDTree(pts, WithStableTies())
+	got, dists := tr.KNearest([]float64{0}, 3)
+	if want := []string{"e", "a", "b"}; !reflect.DeepEqual(ids(got), want) || dists[2] != 1 {
+		t.Fatalf("KNearest = %v %v, want %v", ids(got), dists, want)
+	}
+	got, _ = tr.KNearestExcluding([]float64{0}, 2, map[string]struct{}{"a": {}})
+	if want := []string{"e", "b"}; !reflect.DeepEqual(ids(got), want) {
+		t.Fatalf("KNearestExcluding = %v, want %v", ids(got), want)
+	}
+}
@@ -0,0 +1,78 @@
+package poindexter
+
+import (
+	"context"
+	"time"
+)
+
+// QueryTracer instruments individual queries, e.g. with OpenTelemetry spans,
+// so query latency can be correlated with the request that caused it.
+// StartQuery is called as Nearest, KNearest or Radius begins, with the
+// context passed via WithQueryContext (context.Background otherwise); the
+// function it returns, if not nil, is called with the number of results when
+// the query ends. Implementations must be safe for concurrent use. Poindexter
+// has no tracing dependency; see docs/api.md for an OpenTelemetry adapter.
+type QueryTracer interface {
+	StartQuery(ctx context.Context, q QueryInfo) (end func(results int))
+}
+
+// QueryInfo describes a traced query.
+type QueryInfo struct {
+	Op      string    // "Nearest", "KNearest" or "Radius"
+	Dim     int       // query dimensionality
+	K       int       // neighbours requested; 1 for Nearest, 0 for Radius
+	Radius  float64   // search radius for Radius, otherwise 0
+	Backend KDBackend // backend the tree queries with
+}
+
+// WithQueryTracer traces every Nearest, KNearest and Radius call (including
+// those on snapshots and copy-on-write views) through tr. Other query methods
+// are not traced. Queries that return early, such as ones with the wrong
+// dimensionality, are not traced either.
+func WithQueryTracer(tr QueryTracer) KDOption {
+	return func(o *kdOptions) { o.tracer = tr }
+}
+
+// WithQueryContext passes ctx to the tree's QueryTracer, so the query's span
+// becomes a child of the caller's:
+//
+//	tree.KNearest(q, 5, WithQueryContext(r.Context()))
+//
+// It has no effect on trees without a tracer; the query is not cancelled
+// when ctx is.
+func WithQueryContext(ctx context.Context) QueryOption {
+	return func(o *queryOptions) { o.ctx = ctx }
+}
+
+// startQuery starts tracing a query for the tree's QueryTracer and
+// Hooks.OnQuery and returns the function ending it, or nil if the tree has
+// neither.
+func (t *KDTree[T]) startQuery(op string, k int, r float64, opts []QueryOption) func(int) {
+	if t.tracer == nil && t.hooks.OnQuery == nil {
+		return nil
+	}
+	info := QueryInfo{Op: op, Dim: t.dim, K: k, Radius: r, Backend: t.backend}
+	var end func(int)
+	if t.tracer != nil {
+		var o queryOptions
+		for _, opt := range opts {
+			opt(&o)
+		}
+		ctx := o.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		end = t.tracer.StartQuery(ctx, info)
+	}
+	onQuery := t.hooks.OnQuery
+	if onQuery == nil {
+		return end
+	}
+	start := time.Now()
+	return func(results int) {
+		if end != nil {
+			end(results)
+		}
+		onQuery(QueryEvent{QueryInfo: info, Results: results, Duration: time.Since(start)})
+	}
+}
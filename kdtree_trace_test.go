@@ -0,0 +1,83 @@
+package poindexter
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+type traceKey struct{}
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []recordedSpan
+}
+
+type recordedSpan struct {
+	info    QueryInfo
+	ctxVal  any
+	results int
+	ended   bool
+}
+
+func (r *recordingTracer) StartQuery(ctx context.Context, q QueryInfo) func(int) {
+	r.mu.Lock()
+	i := len(r.spans)
+	r.spans = append(r.spans, recordedSpan{info: q, ctxVal: ctx.Value(traceKey{})})
+	r.mu.Unlock()
+	return func(n int) {
+		r.mu.Lock()
+		r.spans[i].results, r.spans[i].ended = n, true
+		r.mu.Unlock()
+	}
+}
+
+func TestWithQueryTracer(t *testing.T) {
+	pts := []KDPoint[int]{
+		{ID: "a", Coords: []float64{0, 0}},
+		{ID: "b", Coords: []float64{1, 0}},
+		{ID: "c", Coords: []float64{5, 5}, Labels: map[string]string{"region": "eu"}},
+	}
+	for _, cow := range []bool{false, true} {
+		tr := &recordingTracer{}
+		opts := []KDOption{WithQueryTracer(tr)}
+		if cow {
+			opts = append(opts, WithCopyOnWrite())
+		}
+		tree, err := NewKDTree(pts, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := context.WithValue(context.Background(), traceKey{}, "req-1")
+		tree.Nearest([]float64{0, 0}, WithQueryContext(ctx))
+		tree.KNearest([]float64{0, 0}, 2)
+		tree.Radius([]float64{0, 0}, 10, WithLabelSelector("region=eu"))
+		tree.Snapshot().KNearest([]float64{0, 0}, 5)
+		tree.Nearest([]float64{0}) // wrong dimension: not traced
+
+		want := []recordedSpan{
+			{info: QueryInfo{Op: "Nearest", Dim: 2, K: 1, Backend: tree.Backend()}, ctxVal: "req-1", results: 1, ended: true},
+			{info: QueryInfo{Op: "KNearest", Dim: 2, K: 2, Backend: tree.Backend()}, results: 2, ended: true},
+			{info: QueryInfo{Op: "Radius", Dim: 2, Radius: 10, Backend: tree.Backend()}, results: 1, ended: true},
+			{info: QueryInfo{Op: "KNearest", Dim: 2, K: 5, Backend: tree.Backend()}, results: 3, ended: true},
+		}
+		if len(tr.spans) != len(want) {
+			t.Fatalf("cow=%v: expected %d spans, got %+v", cow, len(want), tr.spans)
+		}
+		for i := range want {
+			if tr.spans[i] != want[i] {
+				t.Errorf("cow=%v span %d: got %+v, want %+v", cow, i, tr.spans[i], want[i])
+			}
+		}
+	}
+}
+
+func TestWithQueryContext_NoTracer(t *testing.T) {
+	tree, err := NewKDTree([]KDPoint[int]{{ID: "a", Coords: []float64{0}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := tree.Nearest([]float64{0}, WithQueryContext(context.Background())); !ok {
+		t.Fatal("WithQueryContext should not filter results")
+	}
+}
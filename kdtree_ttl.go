@@ -0,0 +1,124 @@
+package poindexter
+
+import "time"
+
+// ttlState holds the expiry deadlines of points inserted with InsertWithTTL.
+type ttlState struct {
+	deadlines map[string]time.Time // by point ID
+	next      time.Time            // earliest deadline; zero when none
+	timer     *time.Timer          // armed sweep, guarded by writeMu
+}
+
+// InsertWithTTL is Insert for a point that expires ttl from now. Expired
+// points are dropped by the next Insert or InsertWithTTL (so an expired ID
+// can be inserted again), by SweepExpired, and, in trees that serialize their
+// writers (WithCopyOnWrite or a RebuildAfter policy), by a background sweep
+// at the deadline; queries run before then still return them. The point must
+// have an ID. A ttl <= 0 inserts without expiry. Deleting or evicting the
+// point forgets its deadline.
+func (t *KDTree[T]) InsertWithTTL(p KDPoint[T], ttl time.Duration) bool {
+	if ttl <= 0 {
+		return t.Insert(p)
+	}
+	if p.ID == "" {
+		return false
+	}
+	return t.insert(p, time.Now().Add(ttl))
+}
+
+// SweepExpired deletes every point whose InsertWithTTL deadline has passed
+// and reports how many were removed, rebuilding the index at most once.
+func (t *KDTree[T]) SweepExpired() int {
+	if t.serialWrites() {
+		t.writeMu.Lock()
+		defer t.writeMu.Unlock()
+	}
+	if t.checked() {
+		t.beginWrite()
+		defer t.endWrite()
+	}
+	n := t.sweepExpired(time.Now())
+	if n > 0 && t.cow {
+		t.publish()
+	}
+	return n
+}
+
+// sweepExpired drops points expired at now. The caller holds the write locks
+// and publishes.
+func (t *KDTree[T]) sweepExpired(now time.Time) int {
+	s := t.ttl
+	if s == nil || s.next.IsZero() || now.Before(s.next) {
+		return 0
+	}
+	n := t.compact(func(_ int, p KDPoint[T]) bool {
+		d, ok := s.deadlines[p.ID]
+		return ok && !now.Before(d)
+	})
+	// compact forgot the swept deadlines; find the next one
+	s.next = time.Time{}
+	for _, d := range s.deadlines {
+		if s.next.IsZero() || d.Before(s.next) {
+			s.next = d
+		}
+	}
+	t.armTTLSweep()
+	return n
+}
+
+// setDeadline records p's expiry. The caller holds the write locks.
+func (t *KDTree[T]) setDeadline(id string, d time.Time) {
+	if t.ttl == nil {
+		t.ttl = &ttlState{deadlines: make(map[string]time.Time)}
+	}
+	t.ttl.deadlines[id] = d
+	if t.ttl.next.IsZero() || d.Before(t.ttl.next) {
+		t.ttl.next = d
+		if t.ttl.timer != nil {
+			t.ttl.timer.Stop()
+			t.ttl.timer = nil
+		}
+	}
+	t.armTTLSweep()
+}
+
+// forgetDeadline drops the deadline of a removed point, if any. The earliest
+// deadline is left as is; a sweep recomputes it.
+func (t *KDTree[T]) forgetDeadline(id string) {
+	if t.ttl != nil && id != "" {
+		delete(t.ttl.deadlines, id)
+	}
+}
+
+// armTTLSweep schedules a background sweep for the earliest deadline when the
+// tree serializes its writers; other trees cannot be mutated in the
+// background and rely on inserts and SweepExpired.
+func (t *KDTree[T]) armTTLSweep() {
+	s := t.ttl
+	if s == nil || s.next.IsZero() || s.timer != nil || !t.serialWrites() {
+		return
+	}
+	s.timer = time.AfterFunc(time.Until(s.next), func() {
+		t.writeMu.Lock()
+		defer t.writeMu.Unlock()
+		if t.ttl != s {
+			return // replaced by UnmarshalJSON
+		}
+		s.timer = nil
+		if t.sweepExpired(time.Now()) > 0 && t.cow {
+			t.publish()
+		}
+		t.armTTLSweep()
+	})
+}
+
+func (s *ttlState) clone() *ttlState {
+	if s == nil {
+		return nil
+	}
+	c := &ttlState{deadlines: make(map[string]time.Time, len(s.deadlines)), next: s.next}
+	for id, d := range s.deadlines {
+		c.deadlines[id] = d
+	}
+	return c
+}
@@ -0,0 +1,100 @@
+package poindexter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInsertWithTTLExpiresOnInsertAndSweep(t *testing.T) {
+	tr, _ := NewKDTreeFromDim[int](1)
+	if !tr.InsertWithTTL(pt1("old", 0), 20*time.Millisecond) || !tr.InsertWithTTL(pt1("keep", 1), 0) {
+		t.Fatal("insert failed")
+	}
+	if tr.InsertWithTTL(KDPoint[int]{Coords: []float64{2}}, time.Second) {
+		t.Fatal("InsertWithTTL accepted a point without an ID")
+	}
+	if tr.InsertWithTTL(pt1("old", 5), time.Second) {
+		t.Fatal("duplicate ID accepted before expiry")
+	}
+	time.Sleep(30 * time.Millisecond)
+	// expired but not yet swept: queries still see it
+	if p, _, _ := tr.Nearest([]float64{0}); p.ID != "old" {
+		t.Fatalf("Nearest = %s before sweep, want old", p.ID)
+	}
+	// the next insert sweeps first, so the expired ID is free again
+	if !tr.InsertWithTTL(pt1("old", 5), 20*time.Millisecond) {
+		t.Fatal("re-insert of expired ID failed")
+	}
+	if tr.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", tr.Len())
+	}
+	if n := tr.SweepExpired(); n != 0 {
+		t.Fatalf("SweepExpired removed %d unexpired points", n)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if n := tr.SweepExpired(); n != 1 || tr.Len() != 1 {
+		t.Fatalf("SweepExpired = %d, Len = %d; want 1, 1", n, tr.Len())
+	}
+	if p, _, _ := tr.Nearest([]float64{5}); p.ID != "keep" {
+		t.Fatalf("Nearest = %s, want keep", p.ID)
+	}
+}
+
+func TestInsertWithTTLDeleteForgetsDeadline(t *testing.T) {
+	tr, _ := NewKDTreeFromDim[int](1)
+	tr.InsertWithTTL(pt1("a", 0), 10*time.Millisecond)
+	tr.DeleteByID("a")
+	tr.Insert(pt1("a", 0)) // no TTL this time
+	c := tr.Clone(false)
+	tr.InsertWithTTL(pt1("b", 1), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if n := tr.SweepExpired(); n != 1 || tr.Len() != 1 {
+		t.Fatalf("SweepExpired = %d, Len = %d; want 1, 1", n, tr.Len())
+	}
+	if n := c.SweepExpired(); n != 0 || c.Len() != 1 {
+		t.Fatalf("clone SweepExpired = %d, Len = %d; want 0, 1", n, c.Len())
+	}
+}
+
+func TestInsertWithTTLBackgroundSweep(t *testing.T) {
+	tr, _ := NewKDTreeFromDim[int](1, WithCopyOnWrite())
+	tr.Insert(pt1("keep", 0))
+	tr.InsertWithTTL(pt1("late", 1), time.Hour)
+	tr.InsertWithTTL(pt1("soon", 2), 10*time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for tr.Len() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expired point never swept; Len = %d", tr.Len())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, ok := tr.idIndex["soon"]; ok {
+		t.Fatal("wrong point swept")
+	}
+	if n := tr.GetAnalyticsSnapshot().DeleteCount; n != 1 {
+		t.Fatalf("DeleteCount = %d, want 1", n)
+	}
+}
+
+func TestInsertDuplicatePublishesSweep(t *testing.T) {
+	tr, _ := NewKDTreeFromDim[int](1, WithCopyOnWrite())
+	if !tr.InsertWithTTL(pt1("old", 0), 10*time.Millisecond) || !tr.Insert(pt1("keep", 5)) {
+		t.Fatal("insert failed")
+	}
+	// Stop the background sweep so the rejected insert below is what sweeps.
+	tr.writeMu.Lock()
+	tr.ttl.timer.Stop()
+	tr.ttl.timer = nil
+	tr.writeMu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+
+	if tr.Insert(pt1("keep", 6)) {
+		t.Fatal("duplicate insert accepted")
+	}
+	if tr.Len() != 1 {
+		t.Fatalf("view still holds %d points after the sweep", tr.Len())
+	}
+	if p, _, _ := tr.Nearest([]float64{0}); p.ID != "keep" {
+		t.Fatalf("Nearest = %q, want keep", p.ID)
+	}
+}
@@ -0,0 +1,172 @@
+package poindexter
+
+// KDTreeView is a frozen, query-only view of a KDTree taken with Snapshot.
+// Its contents never change, so any number of goroutines may query it without
+// synchronization while the source tree keeps mutating. Queries on a view
+// still feed the source tree's analytics, which are safe for concurrent use.
+type KDTreeView[T any] struct {
+	t *KDTree[T]
+}
+
+// Snapshot returns a frozen view of the tree's current points. Taking a
+// snapshot copies the point slice (O(n), coordinates are shared since the
+// tree never modifies them in place) and reuses the current backend index
+// when it is up to date, so no rebuild is needed. Snapshot itself must not
+// run concurrently with mutations of t, unless the tree uses WithCopyOnWrite,
+// in which case the current published view is returned without copying.
+func (t *KDTree[T]) Snapshot() *KDTreeView[T] {
+	if v := t.cowView(); v != nil {
+		return v
+	}
+	if t.checked() {
+		defer t.endRead(t.beginRead())
+	}
+	return t.freeze()
+}
+
+// freeze copies the current state into a new view.
+func (t *KDTree[T]) freeze() *KDTreeView[T] {
+	f := &KDTree[T]{
+		points:        append([]KDPoint[T](nil), t.points...),
+		dim:           t.dim,
+		metric:        t.metric,
+		backend:       t.backend,
+		analytics:     t.analytics,
+		peerAnalytics: t.peerAnalytics,
+		resultDist:    t.resultDist,
+		resultDigest:  t.resultDigest,
+		queryDist:     t.queryDist,
+		history:       t.history,
+		peerIDFunc:    t.peerIDFunc,
+		weightOf:      t.weightOf,
+		maxWeight:     t.maxWeight,
+		stableTies:    t.stableTies,
+		tracer:        t.tracer,
+		hooks:         t.hooks,
+	}
+	f.version.Store(t.version.Load())
+	if ix := t.index.Load(); ix != nil && ix.version == t.version.Load() {
+		f.index.Store(ix)
+	}
+	return &KDTreeView[T]{t: f}
+}
+
+// cowView returns the published view in copy-on-write mode, or nil.
+func (t *KDTree[T]) cowView() *KDTreeView[T] {
+	if !t.cow {
+		return nil
+	}
+	return t.published.Load()
+}
+
+// publish freezes the current state as the view copy-on-write queries read.
+func (t *KDTree[T]) publish() {
+	t.published.Store(t.freeze())
+}
+
+// Len returns the number of points in the view.
+func (v *KDTreeView[T]) Len() int { return v.t.Len() }
+
+// Dim returns the dimensionality of the view's points.
+func (v *KDTreeView[T]) Dim() int { return v.t.Dim() }
+
+// Backend returns the backend the view queries with.
+func (v *KDTreeView[T]) Backend() KDBackend { return v.t.Backend() }
+
+// Points returns a copy of the view's points.
+func (v *KDTreeView[T]) Points() []KDPoint[T] { return v.t.Points() }
+
+// Nearest is KDTree.Nearest against the frozen point set.
+func (v *KDTreeView[T]) Nearest(query []float64, opts ...QueryOption) (KDPoint[T], float64, bool) {
+	return v.t.Nearest(query, opts...)
+}
+
+// KNearest is KDTree.KNearest against the frozen point set.
+func (v *KDTreeView[T]) KNearest(query []float64, k int, opts ...QueryOption) ([]KDPoint[T], []float64) {
+	return v.t.KNearest(query, k, opts...)
+}
+
+// KNearestBatch is KDTree.KNearestBatch against the frozen point set.
+func (v *KDTreeView[T]) KNearestBatch(queries [][]float64, k, workers int) ([][]KDPoint[T], [][]float64) {
+	return v.t.KNearestBatch(queries, k, workers)
+}
+
+// KNearestInto is KDTree.KNearestInto against the frozen point set.
+func (v *KDTreeView[T]) KNearestInto(query []float64, k int, ptsBuf []KDPoint[T], distBuf []float64) ([]KDPoint[T], []float64) {
+	return v.t.KNearestInto(query, k, ptsBuf, distBuf)
+}
+
+// KNearestExcluding is KDTree.KNearestExcluding against the frozen point set.
+func (v *KDTreeView[T]) KNearestExcluding(query []float64, k int, excludeIDs map[string]struct{}) ([]KDPoint[T], []float64) {
+	return v.t.KNearestExcluding(query, k, excludeIDs)
+}
+
+// NearestToID is KDTree.NearestToID against the frozen point set.
+func (v *KDTreeView[T]) NearestToID(id string, k int) ([]KDPoint[T], []float64) {
+	return v.t.NearestToID(id, k)
+}
+
+// NearestWeighted is KDTree.NearestWeighted against the frozen point set.
+func (v *KDTreeView[T]) NearestWeighted(query, axisWeights []float64) (KDPoint[T], float64, bool) {
+	return v.t.NearestWeighted(query, axisWeights)
+}
+
+// ReverseKNearest is KDTree.ReverseKNearest against the frozen point set.
+func (v *KDTreeView[T]) ReverseKNearest(query []float64, k int) ([]KDPoint[T], []float64) {
+	return v.t.ReverseKNearest(query, k)
+}
+
+// ComputeNormStats is KDTree.ComputeNormStats over the frozen point set.
+func (v *KDTreeView[T]) ComputeNormStats() NormStats {
+	return v.t.ComputeNormStats()
+}
+
+// InverseVarianceWeights is KDTree.InverseVarianceWeights over the frozen
+// point set.
+func (v *KDTreeView[T]) InverseVarianceWeights() []float64 {
+	return v.t.InverseVarianceWeights()
+}
+
+// PermutationImportance is KDTree.PermutationImportance over the frozen point
+// set.
+func (v *KDTreeView[T]) PermutationImportance(outcome func(KDPoint[T]) (float64, bool), k int, seed uint64) ([]FeatureImportance, error) {
+	return v.t.PermutationImportance(outcome, k, seed)
+}
+
+// QueryOutOfRange is KDTree.QueryOutOfRange against the frozen point set.
+func (v *KDTreeView[T]) QueryOutOfRange() []float64 { return v.t.QueryOutOfRange() }
+
+// Radius is KDTree.Radius against the frozen point set.
+func (v *KDTreeView[T]) Radius(query []float64, r float64, opts ...QueryOption) ([]KDPoint[T], []float64) {
+	return v.t.Radius(query, r, opts...)
+}
+
+// RadiusAppend is KDTree.RadiusAppend against the frozen point set.
+func (v *KDTreeView[T]) RadiusAppend(query []float64, r float64, dst []Neighbor[T]) []Neighbor[T] {
+	return v.t.RadiusAppend(query, r, dst)
+}
+
+// RadiusInto is KDTree.RadiusInto against the frozen point set.
+func (v *KDTreeView[T]) RadiusInto(query []float64, r float64, ptsBuf []KDPoint[T], distBuf []float64) ([]KDPoint[T], []float64) {
+	return v.t.RadiusInto(query, r, ptsBuf, distBuf)
+}
+
+// WithinBox is KDTree.WithinBox against the frozen point set.
+func (v *KDTreeView[T]) WithinBox(lo, hi []float64) []KDPoint[T] {
+	return v.t.WithinBox(lo, hi)
+}
+
+// SampleUniform is KDTree.SampleUniform against the frozen point set.
+func (v *KDTreeView[T]) SampleUniform(n int) []KDPoint[T] {
+	return v.t.SampleUniform(n)
+}
+
+// SampleWeighted is KDTree.SampleWeighted against the frozen point set.
+func (v *KDTreeView[T]) SampleWeighted(n int, weightOf func(KDPoint[T]) float64) []KDPoint[T] {
+	return v.t.SampleWeighted(n, weightOf)
+}
+
+// AxisImportance is KDTree.AxisImportance against the frozen point set.
+func (v *KDTreeView[T]) AxisImportance() []AxisImportanceStats {
+	return v.t.AxisImportance()
+}
@@ -0,0 +1,159 @@
+package poindexter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSnapshot_Frozen(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[string]{
+		{ID: "a", Coords: []float64{0, 0}},
+		{ID: "b", Coords: []float64{10, 10}},
+	})
+	v := tr.Snapshot()
+
+	tr.DeleteByID("a")
+	tr.Insert(KDPoint[string]{ID: "c", Coords: []float64{1, 1}})
+
+	if v.Len() != 2 || v.Dim() != 2 {
+		t.Fatalf("view changed: len=%d dim=%d", v.Len(), v.Dim())
+	}
+	if p, d, ok := v.Nearest([]float64{0.5, 0.5}); !ok || p.ID != "a" || d == 0 {
+		t.Fatalf("expected a from view, got %q ok=%v", p.ID, ok)
+	}
+	if p, _, _ := tr.Nearest([]float64{0.5, 0.5}); p.ID != "c" {
+		t.Fatalf("expected c from tree, got %q", p.ID)
+	}
+	if ns, _ := v.KNearest([]float64{0, 0}, 5); len(ns) != 2 {
+		t.Fatalf("expected 2 neighbours from view, got %d", len(ns))
+	}
+	if ns, _ := v.Radius([]float64{10, 10}, 0); len(ns) != 1 || ns[0].ID != "b" {
+		t.Fatalf("unexpected radius result %+v", ns)
+	}
+	if got := len(v.RadiusAppend([]float64{0, 0}, 100, nil)); got != 2 {
+		t.Fatalf("expected 2 from RadiusAppend, got %d", got)
+	}
+	// view queries feed the source tree's analytics
+	if q := tr.GetAnalyticsSnapshot().QueryCount; q != 5 {
+		t.Fatalf("expected 5 queries recorded, got %d", q)
+	}
+}
+
+func TestSnapshot_ConcurrentReadsDuringMutation(t *testing.T) {
+	tr, _ := NewKDTreeFromDim[int](2)
+	for i := 0; i < 200; i++ {
+		tr.Insert(KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{float64(i), 0}})
+	}
+	v := tr.Snapshot()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if p, _, ok := v.Nearest([]float64{float64(i), 0}); !ok || p.ID != fmt.Sprint(i) {
+					t.Errorf("view returned %q for %d", p.ID, i)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		tr.DeleteByID(fmt.Sprint(i))
+		tr.Insert(KDPoint[int]{ID: fmt.Sprint("n", i), Coords: []float64{float64(i), 1}})
+	}
+	wg.Wait()
+	if v.Len() != 200 {
+		t.Fatalf("view length changed to %d", v.Len())
+	}
+}
+
+func TestCopyOnWrite_ConcurrentReadersAndWriters(t *testing.T) {
+	pts := make([]KDPoint[int], 64)
+	for i := range pts {
+		pts[i] = KDPoint[int]{ID: fmt.Sprintf("p%d", i), Coords: []float64{float64(i), 0}, Value: i}
+	}
+	tr, err := NewKDTree(pts, WithCopyOnWrite())
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := tr.Snapshot()
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, _, ok := tr.Nearest([]float64{10, 0}); !ok {
+					t.Error("Nearest found nothing")
+					return
+				}
+				tr.KNearest([]float64{5, 0}, 3)
+				tr.Radius([]float64{5, 0}, 2)
+				_ = tr.Len()
+			}
+		}()
+	}
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				id := fmt.Sprintf("w%d-%d", w, i)
+				tr.Insert(KDPoint[int]{ID: id, Coords: []float64{float64(100 + i), float64(w)}})
+				tr.DeleteByID(id)
+			}
+			tr.DeleteWhere(func(p KDPoint[int]) bool { return p.Value == 63-w })
+		}(w)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	if tr.Len() != 62 {
+		t.Fatalf("expected 62 points, got %d", tr.Len())
+	}
+	if before.Len() != 64 {
+		t.Fatalf("earlier snapshot changed: %d points", before.Len())
+	}
+	if p, _, _ := tr.Nearest([]float64{63, 0}); p.ID != "p61" {
+		t.Fatalf("expected p61 after deletes, got %q", p.ID)
+	}
+	if c := tr.Clone(false); c.Len() != 62 || c.Snapshot().Len() != 62 {
+		t.Fatalf("clone lost copy-on-write state: %d", c.Len())
+	}
+}
+
+func TestCopyOnWrite_UnmarshalRepublishes(t *testing.T) {
+	tr, _ := NewKDTree([]KDPoint[string]{{ID: "a", Coords: []float64{0}}}, WithCopyOnWrite())
+	src, _ := NewKDTree([]KDPoint[string]{
+		{ID: "x", Coords: []float64{4}},
+		{ID: "y", Coords: []float64{9}},
+	})
+	b, err := src.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.UnmarshalJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	if tr.Len() != 2 {
+		t.Fatalf("Len after unmarshal = %d, want 2", tr.Len())
+	}
+	if p, _, _ := tr.Nearest([]float64{5}); p.ID != "x" {
+		t.Fatalf("Nearest after unmarshal = %q, want x", p.ID)
+	}
+	if !tr.Insert(KDPoint[string]{ID: "z", Coords: []float64{5}}) || tr.Len() != 3 {
+		t.Fatalf("insert after unmarshal: %d points", tr.Len())
+	}
+}
@@ -0,0 +1,254 @@
+package poindexter
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// The vantage-point tree backend (BackendVPTree) indexes points for any
+// metric that satisfies the triangle inequality, including custom
+// DistanceMetric implementations, where the kd backend only supports L1, L2
+// and L∞. Each node picks a vantage point and splits the remaining points at
+// the median distance from it; a query then skips any subtree whose distance
+// band around the vantage point cannot hold a closer result.
+//
+// CosineDistance and WeightedCosineDistance are not metrics (1 - cos violates
+// the triangle inequality), but sqrt(2·d) is: it is the chord length between
+// the normalized vectors. The tree prunes in that space and reports the
+// original distances, so results are identical to a linear scan.
+
+// vpNode is a vantage point with the median distance splitting its children:
+// inside holds points at distance <= mu, outside points at distance >= mu.
+type vpNode struct {
+	idx     int
+	mu      float64 // in the pruning space (see vpBackend.scale)
+	inside  *vpNode
+	outside *vpNode
+}
+
+// vpBackend is an immutable vantage-point tree over a point set.
+type vpBackend struct {
+	root   *vpNode
+	dim    int
+	metric DistanceMetric
+	coords func(i int) []float64
+	len    int
+	// scale maps metric distances into the metric space the tree prunes in.
+	scale func(d float64) float64
+}
+
+// vpSlack widens pruning bounds so rounding in scale cannot drop a result at
+// exactly the search radius.
+const vpSlack = 1e-9
+
+// vpScale returns the transform that makes metric a true metric.
+func vpScale(metric DistanceMetric) (func(float64) float64, bool) {
+	chord := func(d float64) float64 { return math.Sqrt(2 * d) }
+	switch m := metric.(type) {
+	case CosineDistance:
+		return chord, true
+	case WeightedCosineDistance:
+		// weighted cosine is cosine on sqrt(w)-scaled vectors: negative
+		// weights break that
+		for _, w := range m.Weights {
+			if w < 0 {
+				return nil, false
+			}
+		}
+		return chord, true
+	case CombinedDistance:
+		if isNormCombination(m) {
+			return func(d float64) float64 { return d }, true
+		}
+		return nil, false
+	case PeriodicMetric:
+		// wrapping keeps the triangle inequality only for norms
+		switch m.Base.(type) {
+		case EuclideanDistance, ManhattanDistance, ChebyshevDistance, nil:
+			return func(d float64) float64 { return d }, true
+		}
+		return nil, false
+	case nil:
+		return nil, false
+	default:
+		return func(d float64) float64 { return d }, true
+	}
+}
+
+// buildVPBackend builds a vantage-point tree over points. Vantage points are
+// chosen by a fixed-seed generator, so builds are deterministic. It returns
+// ErrBackendUnavailable for metrics it cannot prune under.
+func buildVPBackend[T any](points []KDPoint[T], metric DistanceMetric) (any, error) {
+	scale, ok := vpScale(metric)
+	if !ok {
+		return nil, ErrBackendUnavailable
+	}
+	b := &vpBackend{
+		metric: metric,
+		coords: func(i int) []float64 { return points[i].Coords },
+		len:    len(points),
+		scale:  scale,
+	}
+	if len(points) == 0 {
+		return b, nil
+	}
+	b.dim = len(points[0].Coords)
+	idxs := make([]int, len(points))
+	for i := range idxs {
+		idxs[i] = i
+	}
+	dists := make([]float64, len(points))
+	rng := rand.New(rand.NewPCG(uint64(len(points)), 0x5d))
+	b.root = b.build(idxs, dists, rng)
+	return b, nil
+}
+
+// build constructs the subtree over idxs; dists is scratch space of the same
+// length.
+func (b *vpBackend) build(idxs []int, dists []float64, rng *rand.Rand) *vpNode {
+	if len(idxs) == 0 {
+		return nil
+	}
+	v := rng.IntN(len(idxs))
+	idxs[0], idxs[v] = idxs[v], idxs[0]
+	n := &vpNode{idx: idxs[0]}
+	rest, rd := idxs[1:], dists[1:]
+	if len(rest) == 0 {
+		return n
+	}
+	vc := b.coords(n.idx)
+	for i, j := range rest {
+		rd[i] = b.scale(b.metric.Distance(vc, b.coords(j)))
+	}
+	sort.Sort(vpByDist{rest, rd})
+	mid := len(rest) / 2
+	n.mu = rd[mid]
+	n.inside = b.build(rest[:mid], rd[:mid], rng)
+	n.outside = b.build(rest[mid:], rd[mid:], rng)
+	return n
+}
+
+// vpByDist sorts point indices by their distance to a vantage point.
+type vpByDist struct {
+	idxs  []int
+	dists []float64
+}
+
+func (s vpByDist) Len() int           { return len(s.idxs) }
+func (s vpByDist) Less(i, j int) bool { return s.dists[i] < s.dists[j] }
+func (s vpByDist) Swap(i, j int) {
+	s.idxs[i], s.idxs[j] = s.idxs[j], s.idxs[i]
+	s.dists[i], s.dists[j] = s.dists[j], s.dists[i]
+}
+
+// vpSearchItem is a subtree pending visit; bound is a lower bound, in the
+// pruning space, on the distance from the query to any point in it.
+type vpSearchItem struct {
+	n     *vpNode
+	bound float64
+}
+
+// visit computes the query's distance to n's vantage point and pushes its
+// children, the one the query falls in last so it is explored first. It
+// returns the grown stack and the metric distance.
+func (b *vpBackend) visit(stack []vpSearchItem, n *vpNode, query []float64) ([]vpSearchItem, float64) {
+	d := b.metric.Distance(query, b.coords(n.idx))
+	s := b.scale(d)
+	near := vpSearchItem{n.outside, math.Max(n.mu-s, 0)}
+	far := vpSearchItem{n.inside, math.Max(s-n.mu, 0)}
+	if s < n.mu {
+		near, far = far, near
+	}
+	if far.n != nil {
+		stack = append(stack, far)
+	}
+	if near.n != nil {
+		stack = append(stack, near)
+	}
+	return stack, d
+}
+
+// pruned reports whether a subtree with the given bound cannot hold a point
+// within metric distance limit of the query.
+func (b *vpBackend) pruned(bound, limit float64) bool {
+	if math.IsInf(limit, 1) {
+		return false
+	}
+	return bound > b.scale(limit)*(1+vpSlack)+vpSlack
+}
+
+func (b *vpBackend) nearest(query []float64) (int, float64, bool) {
+	if b.root == nil || len(query) != b.dim {
+		return -1, 0, false
+	}
+	bestIdx, bestDist := -1, math.Inf(1)
+	stack := []vpSearchItem{{n: b.root}}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if b.pruned(it.bound, bestDist) {
+			continue
+		}
+		var d float64
+		stack, d = b.visit(stack, it.n, query)
+		if d < bestDist {
+			bestIdx, bestDist = it.n.idx, d
+		}
+	}
+	return bestIdx, bestDist, bestIdx >= 0
+}
+
+func (b *vpBackend) kNearest(query []float64, k int, skip func(idx int) bool) ([]int, []float64) {
+	if b.root == nil || len(query) != b.dim || k <= 0 {
+		return nil, nil
+	}
+	var h knnHeap
+	stack := []vpSearchItem{{n: b.root}}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if h.Len() == k && b.pruned(it.bound, h.peek().dist) {
+			continue
+		}
+		var d float64
+		stack, d = b.visit(stack, it.n, query)
+		if skip != nil && skip(it.n.idx) {
+			continue
+		}
+		if h.Len() < k {
+			h.push(knnItem{idx: it.n.idx, dist: d})
+		} else if d < h.peek().dist {
+			h[0] = knnItem{idx: it.n.idx, dist: d}
+			h.down(0)
+		}
+	}
+	sort.Slice(h, func(i, j int) bool { return h[i].dist < h[j].dist })
+	idxs := make([]int, len(h))
+	dists := make([]float64, len(h))
+	for i := range h {
+		idxs[i] = h[i].idx
+		dists[i] = h[i].dist
+	}
+	return idxs, dists
+}
+
+func (b *vpBackend) radiusEach(query []float64, r float64, visit func(idx int, dist float64)) bool {
+	if b.root == nil || len(query) != b.dim || r < 0 {
+		return false
+	}
+	stack := []vpSearchItem{{n: b.root}}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if b.pruned(it.bound, r) {
+			continue
+		}
+		var d float64
+		stack, d = b.visit(stack, it.n, query)
+		if d <= r {
+			visit(it.n.idx, d)
+		}
+	}
+	return true
+}
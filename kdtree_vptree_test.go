@@ -0,0 +1,113 @@
+package poindexter
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// countingMetric is a custom Euclidean metric that counts evaluations.
+type countingMetric struct{ n *int }
+
+func (m countingMetric) Distance(a, b []float64) float64 {
+	*m.n++
+	return EuclideanDistance{}.Distance(a, b)
+}
+
+func TestVPTree_MatchesLinear(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	pts := make([]KDPoint[int], 400)
+	for i := range pts {
+		pts[i] = KDPoint[int]{ID: fmt.Sprint(i), Coords: []float64{rng.Float64(), rng.Float64() * 2, rng.Float64() - 0.5, rng.Float64()}}
+	}
+	pts[7].Coords = []float64{0, 0, 0, 0} // cosine's zero-vector special case
+	metrics := []DistanceMetric{
+		CosineDistance{},
+		WeightedCosineDistance{Weights: []float64{1, 0.5, 2, 0}},
+		EuclideanDistance{},
+		ManhattanDistance{},
+		ChebyshevDistance{},
+		countingMetric{n: new(int)},
+	}
+	for _, m := range metrics {
+		t.Run(fmt.Sprintf("%T", m), func(t *testing.T) {
+			vp, err := NewKDTree(pts, WithMetric(m), WithBackend(BackendVPTree))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if vp.Backend() != BackendVPTree {
+				t.Fatalf("backend %s", vp.Backend())
+			}
+			vp.Insert(KDPoint[int]{ID: "extra", Coords: []float64{0.3, 0.3, 0.3, 0.3}})
+			vp.DeleteByID("11")
+			lin, _ := NewKDTree(vp.Points(), WithMetric(m), WithBackend(BackendLinear))
+			for q := 0; q < 40; q++ {
+				query := []float64{rng.Float64(), rng.Float64() * 2, rng.Float64() - 0.5, rng.Float64()}
+				_, dv, _ := vp.Nearest(query)
+				_, dl, _ := lin.Nearest(query)
+				if dv != dl {
+					t.Fatalf("nearest: %v vs %v", dv, dl)
+				}
+				_, kv := vp.KNearest(query, 9)
+				_, kl := lin.KNearest(query, 9)
+				for i := range kl {
+					if kv[i] != kl[i] {
+						t.Fatalf("kNearest at %d: %v vs %v", i, kv, kl)
+					}
+				}
+				r := kl[4]
+				rv, _ := vp.Radius(query, r)
+				rl, _ := lin.Radius(query, r)
+				if len(rv) != len(rl) {
+					t.Fatalf("radius %v: %d vs %d", r, len(rv), len(rl))
+				}
+			}
+		})
+	}
+}
+
+func TestVPTree_Prunes(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	pts := make([]KDPoint[int], 5000)
+	for i := range pts {
+		pts[i] = KDPoint[int]{Coords: []float64{rng.Float64() * 100, rng.Float64() * 100}}
+	}
+	evals := 0
+	tr, err := NewKDTree(pts, WithMetric(countingMetric{n: &evals}), WithBackend(BackendVPTree))
+	if err != nil {
+		t.Fatal(err)
+	}
+	evals = 0
+	for q := 0; q < 20; q++ {
+		tr.Nearest([]float64{rng.Float64() * 100, rng.Float64() * 100})
+	}
+	if avg := evals / 20; avg > len(pts)/10 {
+		t.Fatalf("expected sub-linear search, got %d distance evaluations per query", avg)
+	}
+}
+
+func TestVPTree_Fallback(t *testing.T) {
+	pts := []KDPoint[int]{{Coords: []float64{1, 1}}, {Coords: []float64{2, -1}}}
+	tr, err := NewKDTree(pts, WithMetric(WeightedCosineDistance{Weights: []float64{1, -1}}), WithBackend(BackendVPTree))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Backend() != BackendLinear {
+		t.Fatalf("expected linear fallback for negative weights, got %s", tr.Backend())
+	}
+
+	// an empty tree grows its index on insert
+	e, err := NewKDTreeFromDim[int](2, WithMetric(CosineDistance{}), WithBackend(BackendVPTree))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Insert(KDPoint[int]{ID: "a", Coords: []float64{1, 0}})
+	e.Insert(KDPoint[int]{ID: "b", Coords: []float64{0, 1}})
+	if p, d, ok := e.Nearest([]float64{1, 0.1}); !ok || p.ID != "a" || math.Abs(d-CosineDistance{}.Distance([]float64{1, 0.1}, []float64{1, 0})) > 0 {
+		t.Fatalf("nearest: %v %v %v", p, d, ok)
+	}
+	if e.Backend() != BackendVPTree || e.queryIndex() == nil {
+		t.Fatal("expected a vptree index")
+	}
+}
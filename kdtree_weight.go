@@ -0,0 +1,108 @@
+package poindexter
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrPointWeightType indicates WithPointWeight was given a function for a
+// different payload type than the tree.
+var ErrPointWeightType = errors.New("kdtree: point weight func payload type does not match tree")
+
+// WithPointWeight gives every point an importance weight that scales its
+// distance at query time: Nearest, KNearest, Radius, RadiusAppend and their
+// Into variants rank and filter by the effective distance dist/weight and
+// report it in place of the metric distance, as do peer and result-distance
+// analytics. A weight of 2 lets a trusted peer sit twice as far away and still
+// compete, without distorting its coordinates. Weights that are not positive
+// and finite count as 1. fn is called during queries, so it should be cheap,
+// and must return the same weight for a point for as long as it is in the
+// tree. The payload type must match the tree's, otherwise the constructor
+// returns ErrPointWeightType.
+//
+// Indexed backends stay exact: they gather candidates within the query
+// radius times the largest weight in the tree, so very uneven weights widen
+// the search.
+func WithPointWeight[T any](fn func(KDPoint[T]) float64) KDOption {
+	return func(o *kdOptions) { o.pointWeight = fn }
+}
+
+// resolvePointWeight asserts the WithPointWeight function against the tree's
+// payload type.
+func resolvePointWeight[T any](cfg kdOptions) (func(KDPoint[T]) float64, error) {
+	if cfg.pointWeight == nil {
+		return nil, nil
+	}
+	fn, ok := cfg.pointWeight.(func(KDPoint[T]) float64)
+	if !ok {
+		return nil, ErrPointWeightType
+	}
+	return fn, nil
+}
+
+// weight returns p's importance weight, 1 if unset or invalid.
+func (t *KDTree[T]) weight(p KDPoint[T]) float64 {
+	if t.weightOf == nil {
+		return 1
+	}
+	w := t.weightOf(p)
+	if !(w > 0) || math.IsInf(w, 1) {
+		return 1
+	}
+	return w
+}
+
+// updateMaxWeight recomputes the largest weight among the tree's points.
+// Inserts raise it and batch deletes recompute it; DeleteByID leaves it as is,
+// since a stale maximum only widens indexed searches.
+func (t *KDTree[T]) updateMaxWeight() {
+	if t.weightOf == nil {
+		return
+	}
+	t.maxWeight = 0
+	for _, p := range t.points {
+		t.maxWeight = max(t.maxWeight, t.weight(p))
+	}
+}
+
+// weightedSearch fills h, in ascending order, with up to k points whose
+// effective distance dist/weight from query is at most r, leaving out those
+// for which skip, if not nil, is true.
+func (t *KDTree[T]) weightedSearch(query []float64, k int, r float64, skip func(KDPoint[T]) bool, h *neighborHeap[T]) {
+	visit := func(p KDPoint[T], d float64) {
+		if skip != nil && skip(p) {
+			return
+		}
+		if e := d / t.weight(p); e <= r {
+			h.offer(p, e, k)
+		}
+	}
+	defer h.sort()
+	ix := t.queryIndex()
+	if ix == nil {
+		scanDistances(t.metric, query, t.points, func(i int, d float64) { visit(t.points[i], d) })
+		return
+	}
+	if k < math.MaxInt {
+		// any k points bound the k-th best effective distance from above
+		var skipIdx func(int) bool
+		if skip != nil {
+			skipIdx = func(idx int) bool { return skip(ix.points[idx]) }
+		}
+		if idxs, dists := indexKNearest[T](ix.data, query, k, skipIdx); len(idxs) == k {
+			bound := 0.0
+			for i, idx := range idxs {
+				bound = max(bound, dists[i]/t.weight(ix.points[idx]))
+			}
+			r = min(r, bound)
+		}
+	}
+	// a point within effective distance r lies within r*maxWeight; the slack
+	// absorbs rounding in the division
+	if !math.IsInf(r, 1) && indexRadiusEach[T](ix.data, query, r*t.maxWeight*(1+1e-9), func(idx int, d float64) {
+		visit(ix.points[idx], d)
+	}) {
+		return
+	}
+	scanDistances(t.metric, query, t.points, func(i int, d float64) { visit(t.points[i], d) })
+}
@@ -0,0 +1,90 @@
+package poindexter
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"testing"
+)
+
+// valueWeight weights points 0.5, 1, 2 or 4 by their value.
+func valueWeight(p KDPoint[int]) float64 { return []float64{0.5, 1, 2, 4}[p.Value%4] }
+
+func TestPointWeightBiasesRanking(t *testing.T) {
+	pts := []KDPoint[int]{
+		{ID: "near", Coords: []float64{1}, Value: 1},
+		{ID: "trusted", Coords: []float64{3}, Value: 4},
+	}
+	weight := func(p KDPoint[int]) float64 { return float64(p.Value) }
+	tr, err := NewKDTree(pts, WithPointWeight(weight))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, d, ok := tr.Nearest([]float64{0})
+	if !ok || p.ID != "trusted" || d != 0.75 {
+		t.Fatalf("Nearest = %q at %v, want trusted at 0.75", p.ID, d)
+	}
+	if got, _ := tr.Radius([]float64{0}, 0.9); len(got) != 1 || got[0].ID != "trusted" {
+		t.Fatalf("Radius(0.9) = %v, want only trusted", got)
+	}
+	if _, err := NewKDTree(pts, WithPointWeight(func(KDPoint[string]) float64 { return 1 })); !errors.Is(err, ErrPointWeightType) {
+		t.Fatalf("mismatched payload: err = %v, want ErrPointWeightType", err)
+	}
+}
+
+func TestPointWeightMatchesBruteForce(t *testing.T) {
+	pts := makePoints(600, 3)
+	for _, b := range []KDBackend{BackendLinear, BackendGonum, BackendVPTree, BackendCoverTree, BackendRTree} {
+		tr, err := NewKDTree(pts, WithBackend(b), WithPointWeight(valueWeight))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, q := range [][]float64{{0.5, 0.5, 0.5}, {0, 0, 0}, {1, 0.2, 0.9}} {
+			want := make([]float64, len(pts))
+			for i, p := range pts {
+				want[i] = EuclideanDistance{}.Distance(q, p.Coords) / valueWeight(p)
+			}
+			sort.Float64s(want)
+			for _, k := range []int{1, 9, 1000} {
+				got, dists := tr.KNearest(q, k)
+				n := min(k, len(want))
+				if len(got) != n {
+					t.Fatalf("%s k=%d: got %d neighbours", b, k, len(got))
+				}
+				for i := range dists {
+					if math.Abs(dists[i]-want[i]) > 1e-12 {
+						t.Fatalf("%s k=%d: dist %d = %v, want %v", b, k, i, dists[i], want[i])
+					}
+				}
+			}
+			_, dists := tr.Radius(q, 0.2)
+			n := sort.SearchFloat64s(want, math.Nextafter(0.2, 1))
+			if len(dists) != n {
+				t.Fatalf("%s: Radius found %d, want %d", b, len(dists), n)
+			}
+			if _, d, _ := tr.Nearest(q); d != want[0] {
+				t.Fatalf("%s: Nearest at %v, want %v", b, d, want[0])
+			}
+		}
+	}
+}
+
+func TestPointWeightTracksInserts(t *testing.T) {
+	pts := makePoints(300, 2)
+	for i := range pts {
+		pts[i].Value = 1 // weight 1
+	}
+	tr, err := NewKDTree(pts, WithBackend(BackendGonum), WithPointWeight(valueWeight))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// farther than the cloud, but weight 4 ranks it first
+	tr.Insert(KDPoint[int]{ID: "heavy", Coords: []float64{-4.5, 0.5}, Value: 3})
+	if p, d, _ := tr.Nearest([]float64{-1, 0.5}); p.ID != "heavy" || d != 0.875 {
+		t.Fatalf("Nearest = %q at %v, want heavy at 0.875", p.ID, d)
+	}
+	tr.DeleteWhere(func(p KDPoint[int]) bool { return p.ID == "heavy" })
+	if tr.maxWeight != 1 {
+		t.Fatalf("maxWeight after delete = %v, want 1", tr.maxWeight)
+	}
+}
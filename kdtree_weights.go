@@ -0,0 +1,71 @@
+package poindexter
+
+import "math"
+
+// InverseVarianceWeights returns a weight of 1/σ per feature, where σ is the
+// (population) standard deviation of the feature once BuildND has
+// transformed and normalised it under the same opts. Passed as BuildND's
+// weights, they give every axis unit spread, so an axis whose values
+// scatter widely over its range does not dominate distances over one whose
+// values bunch together, as MinMax leaves them when an outlier stretches the
+// range. Under ZScore every weight is 1. Multiply in any weights of your own
+// to express importance on top.
+//
+// Missing values are skipped. An axis with no spread gets weight 0, as it
+// cannot separate points. Errors are those of ComputeNormStatsND.
+func InverseVarianceWeights[T any](items []T, features []func(T) float64, opts ...BuildOption) ([]float64, error) {
+	stats, err := ComputeNormStatsND(items, features, opts...)
+	if err != nil {
+		return nil, err
+	}
+	ws := make([]float64, len(features))
+	vals := make([]float64, 0, len(items))
+	for d, f := range features {
+		vals = vals[:0]
+		for _, it := range items {
+			if v := transformAt(stats.Transforms, d, f(it)); !isMissing(v) {
+				vals = append(vals, stats.Method.scale(v, stats.Stats[d]))
+			}
+		}
+		ws[d] = inverseStdDev(vals)
+	}
+	return ws, nil
+}
+
+// InverseVarianceWeights returns 1/σ for each axis of the coordinates
+// currently in the tree, for NearestWeighted: the axis weights that give the
+// stored points unit spread on every axis, so a query is not ranked mostly
+// by whichever axis varies most. An axis with no spread gets weight 0; an
+// empty tree yields all zeros.
+func (t *KDTree[T]) InverseVarianceWeights() []float64 {
+	stats := t.ComputeNormStats()
+	ws := make([]float64, len(stats.Stats))
+	for d, s := range stats.Stats {
+		if s.StdDev > 0 {
+			ws[d] = 1 / s.StdDev
+		}
+	}
+	return ws
+}
+
+// inverseStdDev returns 1 over the population standard deviation of xs, or 0
+// if they do not spread.
+func inverseStdDev(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	var mean float64
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	var ss float64
+	for _, x := range xs {
+		ss += (x - mean) * (x - mean)
+	}
+	sd := math.Sqrt(ss / float64(len(xs)))
+	if !(sd > 0) || math.IsInf(sd, 1) {
+		return 0
+	}
+	return 1 / sd
+}
@@ -0,0 +1,70 @@
+package poindexter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInverseVarianceWeights(t *testing.T) {
+	recs := normRecs()
+	features := []func(normRec) float64{
+		func(r normRec) float64 { return r.ping },
+		func(r normRec) float64 { return r.hops },
+	}
+	ws, err := InverseVarianceWeights(recs, features)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// min-max squeezes the ordinary pings together under the outlier, so the
+	// ping axis spreads less than hops and is weighted up
+	if len(ws) != 2 || !(ws[0] > ws[1]) || ws[1] <= 0 {
+		t.Fatalf("weights = %v", ws)
+	}
+	// the weights give both axes unit spread
+	pts, err := BuildND(recs, func(r normRec) string { return r.id }, features, ws, []bool{false, false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, _ := NewKDTree(pts)
+	for d, s := range tree.ComputeNormStats().Stats {
+		if math.Abs(s.StdDev-1) > 1e-9 {
+			t.Fatalf("axis %d stddev = %v, want 1", d, s.StdDev)
+		}
+	}
+
+	ws, _ = InverseVarianceWeights(recs, features, WithNormalization(ZScore))
+	for d, w := range ws {
+		if math.Abs(w-1) > 1e-9 {
+			t.Fatalf("zscore weight %d = %v, want 1", d, w)
+		}
+	}
+
+	constant := []func(normRec) float64{func(normRec) float64 { return 7 }}
+	if ws, err := InverseVarianceWeights(recs, constant); err != nil || ws[0] != 0 {
+		t.Fatalf("constant axis: %v, %v", ws, err)
+	}
+	if _, err := InverseVarianceWeights(recs, nil); err != ErrInvalidFeatures {
+		t.Fatalf("no features: err = %v", err)
+	}
+}
+
+func TestKDTreeInverseVarianceWeights(t *testing.T) {
+	pts := []KDPoint[int]{
+		{ID: "a", Coords: []float64{0, 5}},
+		{ID: "b", Coords: []float64{100, 5}},
+		{ID: "c", Coords: []float64{200, 5}},
+		{ID: "d", Coords: []float64{300, 5}},
+	}
+	tree, _ := NewKDTree(pts)
+	ws := tree.InverseVarianceWeights()
+	if want := 1 / math.Sqrt(12500); math.Abs(ws[0]-want) > 1e-12 || ws[1] != 0 {
+		t.Fatalf("weights = %v", ws)
+	}
+	if got := tree.Snapshot().InverseVarianceWeights(); got[0] != ws[0] {
+		t.Fatalf("view weights = %v", got)
+	}
+	empty, _ := NewKDTreeFromDim[int](2)
+	if ws := empty.InverseVarianceWeights(); len(ws) != 2 || ws[0] != 0 {
+		t.Fatalf("empty tree weights = %v", ws)
+	}
+}
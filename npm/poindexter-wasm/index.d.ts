@@ -32,6 +32,14 @@ export interface TreeAnalytics {
   createdAt: number; // Unix milliseconds
   backendRebuildCount: number;
   lastRebuiltAt: number; // Unix milliseconds
+  p50QueryTimeNs: number;
+  p95QueryTimeNs: number;
+  p99QueryTimeNs: number;
+  p999QueryTimeNs: number;
+  bestDistanceMean: number; // best result distance over the last 1024 queries
+  bestDistanceP50: number;
+  bestDistanceP90: number;
+  anomalous: boolean; // query latency running above its EWMA + k·σ band
 }
 
 /** Per-peer selection statistics */
@@ -40,6 +48,27 @@ export interface PeerStats {
   selectionCount: number;
   avgDistance: number;
   lastSelectedAt: number; // Unix milliseconds
+  firstSelectedAt: number; // Unix milliseconds
+  decayedCount: number; // selectionCount with half-life decay applied
+}
+
+/** How evenly selections are spread across peers */
+export interface SelectionFairness {
+  peers: number;
+  entropy: number; // bits
+  normalizedEntropy: number; // 1 = perfectly even, 0 = one peer takes all
+  gini: number; // 0 = perfectly even, towards 1 = concentrated
+}
+
+/** Peer turnover; a peer is gone once unselected for a whole window */
+export interface PeerChurn {
+  windowMs: number;
+  peers: number;
+  active: number; // selected within the last window
+  joined: number; // first selected within the last window
+  departed: number; // last selected in the window before, not since
+  churnRate: number; // departed / peers present at the window start
+  medianLifetimeMs: number; // median first-to-last selection span
 }
 
 /** Statistical distribution analysis */
@@ -61,10 +90,17 @@ export interface DistributionStats {
 }
 
 /** Per-axis distribution in the KD-Tree */
+/** Equal-width histogram; bin i covers [edges[i], edges[i+1]) */
+export interface Histogram {
+  edges: number[];
+  counts: number[];
+}
+
 export interface AxisDistribution {
   axis: number;
   name: string;
   stats: DistributionStats;
+  histogram: Histogram;
 }
 
 // ============================================================================
@@ -170,11 +206,17 @@ export interface PxTree {
   kNearest(query: number[], k: number): Promise<KNearestResult>;
   radius(query: number[], r: number): Promise<KNearestResult>;
   exportJSON(): Promise<string>;
+  /** Points as a msgpack array of {id, coords, value} maps. */
+  exportMsgpack(): Promise<Uint8Array>;
 
   // Analytics operations
   getAnalytics(): Promise<TreeAnalytics>;
+  /** Analytics snapshot as a msgpack map keyed like TreeAnalytics. */
+  getAnalyticsMsgpack(): Promise<Uint8Array>;
   getPeerStats(): Promise<PeerStats[]>;
   getTopPeers(n: number): Promise<PeerStats[]>;
+  getSelectionFairness(): Promise<SelectionFairness>;
+  getPeerChurn(windowMs: number): Promise<PeerChurn>;
   getAxisDistributions(axisNames?: string[]): Promise<AxisDistribution[]>;
   resetAnalytics(): Promise<boolean>;
 }
@@ -306,10 +348,21 @@ export interface WebRedirectRecord {
   frame: boolean;        // Frame redirect vs HTTP redirect
 }
 
-/** External tool links for domain/IP/email analysis */
+/** Kind of lookup subject recognized by parseTarget */
+export type TargetKind = 'domain' | 'ipv4' | 'ipv6' | 'email' | 'asn' | 'url';
+
+/** A classified lookup subject (domain, IP, email, ASN or URL) */
+export interface Target {
+  raw: string;
+  kind: TargetKind;
+  value: string;  // normalized target
+  host?: string;  // domain or IP that DNS/RDAP queries are about
+}
+
+/** External tool links for domain/IP/email/ASN analysis */
 export interface ExternalToolLinks {
   target: string;
-  type: 'domain' | 'ip' | 'email';
+  type: 'domain' | 'ip' | 'email' | 'asn';
 
   // MXToolbox links
   mxtoolboxDns?: string;
@@ -352,6 +405,10 @@ export interface ExternalToolLinks {
   // Email-specific tools
   mailtester?: string;
   learndmarc?: string;
+
+  // ASN-specific tools
+  bgphe?: string;
+  peeringdb?: string;
 }
 
 /** RDAP server registry */
@@ -531,6 +588,8 @@ export interface PxAPI {
   weightedPeerFeatures(normalized: number[], weights: number[]): Promise<number[]>;
 
   // DNS tools
+  parseTarget(input: string): Promise<Target>;
+  getTargetLinks(input: string): Promise<ExternalToolLinks>;
   getExternalToolLinks(domain: string): Promise<ExternalToolLinks>;
   getExternalToolLinksIP(ip: string): Promise<ExternalToolLinks>;
   getExternalToolLinksEmail(emailOrDomain: string): Promise<ExternalToolLinks>;
@@ -49,10 +49,14 @@ class PxTree {
   async kNearest(query, k) { return call('pxKNearest', this.treeId, query, k); }
   async radius(query, r) { return call('pxRadius', this.treeId, query, r); }
   async exportJSON() { return call('pxExportJSON', this.treeId); }
+  async exportMsgpack() { return call('pxExportMsgpack', this.treeId); }
   // Analytics operations
   async getAnalytics() { return call('pxGetAnalytics', this.treeId); }
+  async getAnalyticsMsgpack() { return call('pxGetAnalyticsMsgpack', this.treeId); }
   async getPeerStats() { return call('pxGetPeerStats', this.treeId); }
   async getTopPeers(n) { return call('pxGetTopPeers', this.treeId, n); }
+  async getSelectionFairness() { return call('pxGetSelectionFairness', this.treeId); }
+  async getPeerChurn(windowMs) { return call('pxGetPeerChurn', this.treeId, windowMs); }
   async getAxisDistributions(axisNames) { return call('pxGetAxisDistributions', this.treeId, axisNames); }
   async resetAnalytics() { return call('pxResetAnalytics', this.treeId); }
 }
@@ -102,6 +106,8 @@ export async function init(options = {}) {
     normalizePeerFeatures: async (features, ranges) => call('pxNormalizePeerFeatures', features, ranges),
     weightedPeerFeatures: async (normalized, weights) => call('pxWeightedPeerFeatures', normalized, weights),
     // DNS tools
+    parseTarget: async (input) => call('pxParseTarget', input),
+    getTargetLinks: async (input) => call('pxGetTargetLinks', input),
     getExternalToolLinks: async (domain) => call('pxGetExternalToolLinks', domain),
     getExternalToolLinksIP: async (ip) => call('pxGetExternalToolLinksIP', ip),
     getExternalToolLinksEmail: async (emailOrDomain) => call('pxGetExternalToolLinksEmail', emailOrDomain),
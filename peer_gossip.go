@@ -0,0 +1,121 @@
+package poindexter
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	// ErrGossipSignature indicates a gossip envelope's signature does not verify.
+	ErrGossipSignature = errors.New("gossip: invalid envelope signature")
+	// ErrGossipPeerMismatch indicates the feature vector names a different peer
+	// than the envelope.
+	ErrGossipPeerMismatch = errors.New("gossip: feature vector peer ID does not match envelope")
+	// ErrUnknownPeer indicates no public key is registered for the envelope's peer.
+	ErrUnknownPeer = errors.New("gossip: unknown peer")
+)
+
+// GossipEnvelope carries one peer's scoring inputs between nodes. It is signed
+// by the peer it describes, so relays cannot alter the metrics in transit.
+//
+// The signature covers CanonicalBytes, a deterministic encoding independent of
+// how the envelope travels (JSON, msgpack, ...), including the feature
+// vector's Labels.
+type GossipEnvelope struct {
+	PeerID        string        `json:"peerId"`
+	FeatureVector FeatureVector `json:"featureVector"`
+	TrustMetrics  TrustMetrics  `json:"trustMetrics"`
+	Signature     []byte        `json:"signature,omitempty"`
+	Timestamp     time.Time     `json:"timestamp"`
+}
+
+// Canonical gossip encoding (all integers little-endian):
+//
+//	magic     [4]byte "PDXG"
+//	version   uint16  1 without labels, 2 with
+//	peerID    uvarint length + bytes
+//	timestamp int64 Unix nanoseconds (0 for the zero time)
+//	features  uvarint count + float64s
+//	labels    uvarint count + (uvarint length + bytes) each, in order (version 2 only)
+//	trust     ReputationScore float64, SuccessfulTransactions int64,
+//	          FailedTransactions int64, AgeSeconds int64,
+//	          LastSuccessAt int64, LastFailureAt int64 (Unix nanoseconds),
+//	          VouchCount int64, FlagCount int64, ProofOfWork float64
+//
+// Labels name the features positionally, so they are encoded in their given
+// order. Envelopes without labels keep the version 1 encoding, so their
+// signatures stay valid for peers that predate label signing; adding labels to
+// such an envelope changes the version and breaks the signature. The feature
+// vector's own PeerID is not encoded: Verify requires it to be empty or equal
+// to the envelope's.
+const (
+	gossipMagic       = "PDXG"
+	gossipVer         = 1
+	gossipVerLabelled = 2
+)
+
+// CanonicalBytes returns the deterministic encoding of the envelope that
+// Sign and Verify operate on. The Signature field is excluded.
+func (e *GossipEnvelope) CanonicalBytes() []byte {
+	fv := e.FeatureVector.Features
+	buf := make([]byte, 0, 4+2+binary.MaxVarintLen64+len(e.PeerID)+8+binary.MaxVarintLen64+8*len(fv)+9*8)
+	labels := e.FeatureVector.Labels
+	ver := uint16(gossipVer)
+	if len(labels) > 0 {
+		ver = gossipVerLabelled
+	}
+	buf = append(buf, gossipMagic...)
+	buf = binary.LittleEndian.AppendUint16(buf, ver)
+	buf = binary.AppendUvarint(buf, uint64(len(e.PeerID)))
+	buf = append(buf, e.PeerID...)
+	buf = appendGossipTime(buf, e.Timestamp)
+	buf = binary.AppendUvarint(buf, uint64(len(fv)))
+	for _, f := range fv {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(f))
+	}
+	if ver == gossipVerLabelled {
+		buf = binary.AppendUvarint(buf, uint64(len(labels)))
+		for _, l := range labels {
+			buf = appendBytes(buf, []byte(l))
+		}
+	}
+	tm := e.TrustMetrics
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(tm.ReputationScore))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(tm.SuccessfulTransactions))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(tm.FailedTransactions))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(tm.AgeSeconds))
+	buf = appendGossipTime(buf, tm.LastSuccessAt)
+	buf = appendGossipTime(buf, tm.LastFailureAt)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(int64(tm.VouchCount)))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(int64(tm.FlagCount)))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(tm.ProofOfWork))
+	return buf
+}
+
+func appendGossipTime(buf []byte, t time.Time) []byte {
+	var ns int64
+	if !t.IsZero() {
+		ns = t.UnixNano()
+	}
+	return binary.LittleEndian.AppendUint64(buf, uint64(ns))
+}
+
+// Sign sets the envelope's Signature using the peer's private key.
+func (e *GossipEnvelope) Sign(priv ed25519.PrivateKey) {
+	e.Signature = ed25519.Sign(priv, e.CanonicalBytes())
+}
+
+// Verify checks the envelope is consistent and signed by pubkey. It returns
+// ErrGossipPeerMismatch or ErrGossipSignature.
+func (e *GossipEnvelope) Verify(pubkey ed25519.PublicKey) error {
+	if id := e.FeatureVector.PeerID; id != "" && id != e.PeerID {
+		return ErrGossipPeerMismatch
+	}
+	if len(pubkey) != ed25519.PublicKeySize || !ed25519.Verify(pubkey, e.CanonicalBytes(), e.Signature) {
+		return ErrGossipSignature
+	}
+	return nil
+}
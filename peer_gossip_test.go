@@ -0,0 +1,112 @@
+package poindexter
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testEnvelope(t *testing.T) (GossipEnvelope, ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := GossipEnvelope{
+		PeerID:        "peer-a",
+		FeatureVector: FeatureVector{PeerID: "peer-a", Features: []float64{12, 3, 0.25}, Labels: []string{"ping", "hops", "loss"}},
+		TrustMetrics:  TrustMetrics{ReputationScore: 0.8, SuccessfulTransactions: 40, FailedTransactions: 2, LastSuccessAt: time.Unix(1700000000, 5)},
+		Timestamp:     time.Unix(1700000100, 0),
+	}
+	env.Sign(priv)
+	return env, pub, priv
+}
+
+func TestGossipEnvelope_SignVerify(t *testing.T) {
+	env, pub, _ := testEnvelope(t)
+	if err := env.Verify(pub); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// the signature survives a JSON round trip
+	b, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got GossipEnvelope
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := got.Verify(pub); err != nil {
+		t.Fatalf("verify after JSON: %v", err)
+	}
+
+	tampered := got
+	tampered.FeatureVector.Features = []float64{1, 3, 0.25}
+	if err := tampered.Verify(pub); !errors.Is(err, ErrGossipSignature) {
+		t.Fatalf("tampered features: got %v", err)
+	}
+	tampered = got
+	tampered.TrustMetrics.FlagCount = 1
+	if err := tampered.Verify(pub); !errors.Is(err, ErrGossipSignature) {
+		t.Fatalf("tampered trust: got %v", err)
+	}
+	for name, labels := range map[string][]string{
+		"relabelled": {"ping", "loss", "hops"},
+		"renamed":    {"ping", "hops", "jitter"},
+		"stripped":   nil,
+		"extra":      {"ping", "hops", "loss", "geo"},
+	} {
+		tampered = got
+		tampered.FeatureVector.Labels = labels
+		if err := tampered.Verify(pub); !errors.Is(err, ErrGossipSignature) {
+			t.Fatalf("tampered labels (%s): got %v", name, err)
+		}
+	}
+	tampered = got
+	tampered.Timestamp = tampered.Timestamp.Add(time.Second)
+	if err := tampered.Verify(pub); !errors.Is(err, ErrGossipSignature) {
+		t.Fatalf("tampered timestamp: got %v", err)
+	}
+
+	other, _, _ := ed25519.GenerateKey(nil)
+	if err := env.Verify(other); !errors.Is(err, ErrGossipSignature) {
+		t.Fatalf("wrong key: got %v", err)
+	}
+	if err := env.Verify(nil); !errors.Is(err, ErrGossipSignature) {
+		t.Fatalf("nil key: got %v", err)
+	}
+
+	env.FeatureVector.PeerID = "peer-b"
+	if err := env.Verify(pub); !errors.Is(err, ErrGossipPeerMismatch) {
+		t.Fatalf("peer mismatch: got %v", err)
+	}
+}
+
+func TestGossipEnvelope_CanonicalBytes(t *testing.T) {
+	env, _, _ := testEnvelope(t)
+	a := env.CanonicalBytes()
+	env.Signature = nil
+	if string(a) != string(env.CanonicalBytes()) {
+		t.Fatal("the signature must not affect the canonical encoding")
+	}
+	// labels are signed; without them the version 1 encoding is kept
+	env.FeatureVector.Labels = nil
+	if v1 := env.CanonicalBytes(); string(v1) == string(a) || v1[4] != gossipVer {
+		t.Fatal("labels must be part of the canonical encoding")
+	}
+	// labels cannot be shifted between each other
+	x := GossipEnvelope{FeatureVector: FeatureVector{Labels: []string{"ab", "c"}}}
+	y := GossipEnvelope{FeatureVector: FeatureVector{Labels: []string{"a", "bc"}}}
+	if string(x.CanonicalBytes()) == string(y.CanonicalBytes()) {
+		t.Fatal("distinct labels encode identically")
+	}
+	// length-prefixed fields cannot be shifted between each other
+	b := GossipEnvelope{PeerID: "ab", FeatureVector: FeatureVector{Features: []float64{1}}}
+	c := GossipEnvelope{PeerID: "a", FeatureVector: FeatureVector{Features: []float64{1}}}
+	if string(b.CanonicalBytes()) == string(c.CanonicalBytes()) {
+		t.Fatal("distinct envelopes encode identically")
+	}
+}
@@ -0,0 +1,203 @@
+package poindexter
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrStaleMetrics indicates metrics older than the registry's RejectOlderThan
+	// window, or without a timestamp.
+	ErrStaleMetrics = errors.New("gossip: metrics too old")
+	// ErrReplayedMetrics indicates metrics not newer than those last accepted for
+	// the peer, e.g. a replayed envelope.
+	ErrReplayedMetrics = errors.New("gossip: metrics not newer than last accepted")
+	// ErrFutureMetrics indicates metrics timestamped further ahead of the local
+	// clock than the registry's allowed clock skew.
+	ErrFutureMetrics = errors.New("gossip: metrics timestamped in the future")
+)
+
+// DefaultGossipClockSkew is how far ahead of the local clock a PeerRegistry
+// accepts metric timestamps unless AllowClockSkew says otherwise.
+const DefaultGossipClockSkew = time.Minute
+
+// PeerRecord is the latest accepted scoring input for a peer.
+type PeerRecord struct {
+	PeerID        string        `json:"peerId"`
+	FeatureVector FeatureVector `json:"featureVector"`
+	TrustMetrics  TrustMetrics  `json:"trustMetrics"`
+	// UpdatedAt is the Timestamp of the envelope the record came from.
+	UpdatedAt time.Time `json:"updatedAt"`
+	// RoutingMetrics are the latest imported NAT routing metrics, if any.
+	RoutingMetrics NATRoutingMetrics `json:"routingMetrics"`
+}
+
+// PeerRegistry tracks known peers' public keys and their latest gossiped
+// scoring inputs, from which callers build selection trees (e.g. with BuildND
+// over Records). It is safe for concurrent use.
+//
+// Metrics are only accepted if they are newer than the last ones accepted for
+// the same peer, so a replayed envelope or re-imported probe cannot roll a
+// peer back to earlier (better) scores. These per-peer high-water marks
+// survive RemovePeer: a dead peer cannot be resurrected by replaying its old
+// gossip after re-registration. Timestamps further in the future than the
+// allowed clock skew are rejected, so a validly signed but future-dated
+// envelope cannot push the watermark ahead and lock out the peer's genuine
+// updates. See also RejectOlderThan and AllowClockSkew.
+type PeerRegistry struct {
+	mu      sync.RWMutex
+	keys    map[string]ed25519.PublicKey
+	records map[string]PeerRecord
+	seen    map[string]peerWatermark
+	maxAge  time.Duration
+	skew    time.Duration
+	now     func() time.Time // time.Now; replaced in tests
+}
+
+// peerWatermark holds the newest timestamps accepted for a peer.
+type peerWatermark struct {
+	gossip, routing time.Time
+}
+
+// NewPeerRegistry creates an empty registry.
+func NewPeerRegistry() *PeerRegistry {
+	return &PeerRegistry{
+		keys:    make(map[string]ed25519.PublicKey),
+		records: make(map[string]PeerRecord),
+		seen:    make(map[string]peerWatermark),
+		skew:    DefaultGossipClockSkew,
+		now:     time.Now,
+	}
+}
+
+// RejectOlderThan makes the registry reject gossip and imported metrics whose
+// timestamp is more than maxAge in the past (ErrStaleMetrics). maxAge <= 0
+// disables the age check; untimestamped metrics are rejected and ordering per
+// peer is enforced either way.
+func (r *PeerRegistry) RejectOlderThan(maxAge time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxAge = maxAge
+}
+
+// AllowClockSkew sets how far ahead of the local clock gossip and imported
+// metrics may be timestamped before they are rejected with ErrFutureMetrics
+// (default DefaultGossipClockSkew). A negative skew disables the check.
+func (r *PeerRegistry) AllowClockSkew(skew time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skew = skew
+}
+
+// checkFresh validates ts against the age window, the allowed clock skew and
+// the previous watermark. A zero ts is always stale: it would leave the
+// watermark at zero, so the same metrics could be replayed forever.
+func (r *PeerRegistry) checkFresh(ts, last time.Time) error {
+	now := r.now()
+	if ts.IsZero() || (r.maxAge > 0 && now.Sub(ts) > r.maxAge) {
+		return ErrStaleMetrics
+	}
+	if r.skew >= 0 && ts.Sub(now) > r.skew {
+		return ErrFutureMetrics
+	}
+	if !last.IsZero() && !ts.After(last) {
+		return ErrReplayedMetrics
+	}
+	return nil
+}
+
+// RegisterPeer sets the public key gossip about peerID must be signed with,
+// replacing any previous key.
+func (r *PeerRegistry) RegisterPeer(peerID string, pubkey ed25519.PublicKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[peerID] = append(ed25519.PublicKey(nil), pubkey...)
+}
+
+// RemovePeer forgets a peer's key and record. Its freshness watermarks are
+// kept.
+func (r *PeerRegistry) RemovePeer(peerID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, peerID)
+	delete(r.records, peerID)
+}
+
+// ApplyGossip verifies env against its peer's registered key and, if valid and
+// fresh, stores its metrics as the peer's record. It returns ErrUnknownPeer for
+// unregistered peers, the error from GossipEnvelope.Verify, ErrStaleMetrics,
+// ErrFutureMetrics or ErrReplayedMetrics.
+func (r *PeerRegistry) ApplyGossip(env GossipEnvelope) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[env.PeerID]
+	if !ok {
+		return ErrUnknownPeer
+	}
+	if err := env.Verify(key); err != nil {
+		return err
+	}
+	wm := r.seen[env.PeerID]
+	if err := r.checkFresh(env.Timestamp, wm.gossip); err != nil {
+		return err
+	}
+	wm.gossip = env.Timestamp
+	r.seen[env.PeerID] = wm
+	fv := env.FeatureVector
+	fv.PeerID = env.PeerID
+	fv.Features = append([]float64(nil), fv.Features...)
+	fv.Labels = append([]string(nil), fv.Labels...)
+	rec := r.records[env.PeerID]
+	rec.PeerID = env.PeerID
+	rec.FeatureVector = fv
+	rec.TrustMetrics = env.TrustMetrics
+	rec.UpdatedAt = env.Timestamp
+	r.records[env.PeerID] = rec
+	return nil
+}
+
+// ImportRoutingMetrics stores locally probed or imported NAT routing metrics
+// for a registered peer, timestamped by m.LastProbeAt. It applies the same
+// freshness rules as ApplyGossip and returns ErrUnknownPeer, ErrStaleMetrics,
+// ErrFutureMetrics or ErrReplayedMetrics.
+func (r *PeerRegistry) ImportRoutingMetrics(peerID string, m NATRoutingMetrics) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.keys[peerID]; !ok {
+		return ErrUnknownPeer
+	}
+	wm := r.seen[peerID]
+	if err := r.checkFresh(m.LastProbeAt, wm.routing); err != nil {
+		return err
+	}
+	wm.routing = m.LastProbeAt
+	r.seen[peerID] = wm
+	rec := r.records[peerID]
+	rec.PeerID = peerID
+	rec.RoutingMetrics = m
+	r.records[peerID] = rec
+	return nil
+}
+
+// Record returns the latest record for peerID.
+func (r *PeerRegistry) Record(peerID string) (PeerRecord, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rec, ok := r.records[peerID]
+	return rec, ok
+}
+
+// Records returns all peer records sorted by peer ID.
+func (r *PeerRegistry) Records() []PeerRecord {
+	r.mu.RLock()
+	out := make([]PeerRecord, 0, len(r.records))
+	for _, rec := range r.records {
+		out = append(out, rec)
+	}
+	r.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].PeerID < out[j].PeerID })
+	return out
+}
@@ -0,0 +1,174 @@
+package poindexter
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPeerRegistry_ApplyGossip(t *testing.T) {
+	env, pub, _ := testEnvelope(t)
+	r := NewPeerRegistry()
+	if err := r.ApplyGossip(env); !errors.Is(err, ErrUnknownPeer) {
+		t.Fatalf("unregistered peer: got %v", err)
+	}
+	r.RegisterPeer("peer-a", pub)
+
+	forged := env
+	forged.TrustMetrics.ReputationScore = 1
+	if err := r.ApplyGossip(forged); !errors.Is(err, ErrGossipSignature) {
+		t.Fatalf("forged envelope: got %v", err)
+	}
+	if _, ok := r.Record("peer-a"); ok {
+		t.Fatal("forged envelope was stored")
+	}
+	relabelled := env
+	relabelled.FeatureVector.Labels = []string{"hops", "ping", "loss"}
+	if err := r.ApplyGossip(relabelled); !errors.Is(err, ErrGossipSignature) {
+		t.Fatalf("relabelled envelope: got %v", err)
+	}
+
+	if err := r.ApplyGossip(env); err != nil {
+		t.Fatal(err)
+	}
+	rec, ok := r.Record("peer-a")
+	if !ok || rec.TrustMetrics.ReputationScore != 0.8 || !rec.UpdatedAt.Equal(env.Timestamp) || rec.FeatureVector.Labels[0] != "ping" {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+	env.FeatureVector.Features[0] = 99 // the registry keeps its own copy
+	if rec, _ := r.Record("peer-a"); rec.FeatureVector.Features[0] != 12 {
+		t.Fatal("record aliases the envelope's features")
+	}
+	if recs := r.Records(); len(recs) != 1 || recs[0].PeerID != "peer-a" {
+		t.Fatalf("records: %+v", recs)
+	}
+
+	r.RemovePeer("peer-a")
+	if _, ok := r.Record("peer-a"); ok {
+		t.Fatal("record kept after RemovePeer")
+	}
+}
+
+func TestPeerRegistry_Freshness(t *testing.T) {
+	env, pub, priv := testEnvelope(t)
+	r := NewPeerRegistry()
+	r.RegisterPeer("peer-a", pub)
+	now := env.Timestamp.Add(time.Minute)
+	r.now = func() time.Time { return now }
+	r.RejectOlderThan(time.Hour)
+
+	if err := r.ApplyGossip(env); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ApplyGossip(env); !errors.Is(err, ErrReplayedMetrics) {
+		t.Fatalf("replayed envelope: got %v", err)
+	}
+
+	// a newer envelope with worse trust is accepted ...
+	worse := env
+	worse.TrustMetrics.ReputationScore = 0.1
+	worse.Timestamp = env.Timestamp.Add(time.Second)
+	worse.Sign(priv)
+	if err := r.ApplyGossip(worse); err != nil {
+		t.Fatal(err)
+	}
+	// ... and the original good one cannot roll it back, even after the peer
+	// was removed and registered again
+	r.RemovePeer("peer-a")
+	r.RegisterPeer("peer-a", pub)
+	if err := r.ApplyGossip(env); !errors.Is(err, ErrReplayedMetrics) {
+		t.Fatalf("resurrected envelope: got %v", err)
+	}
+
+	old := env
+	old.PeerID, old.FeatureVector.PeerID = "peer-b", "peer-b"
+	old.Timestamp = now.Add(-2 * time.Hour)
+	old.Sign(priv)
+	r.RegisterPeer("peer-b", pub)
+	if err := r.ApplyGossip(old); !errors.Is(err, ErrStaleMetrics) {
+		t.Fatalf("stale envelope: got %v", err)
+	}
+
+	m := NATRoutingMetrics{AvgRTTMs: 20, LastProbeAt: now.Add(-time.Second)}
+	if err := r.ImportRoutingMetrics("peer-a", m); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ImportRoutingMetrics("peer-a", m); !errors.Is(err, ErrReplayedMetrics) {
+		t.Fatalf("replayed import: got %v", err)
+	}
+	if err := r.ImportRoutingMetrics("peer-a", NATRoutingMetrics{}); !errors.Is(err, ErrStaleMetrics) {
+		t.Fatalf("untimestamped import: got %v", err)
+	}
+	if err := r.ImportRoutingMetrics("peer-z", m); !errors.Is(err, ErrUnknownPeer) {
+		t.Fatalf("unknown peer import: got %v", err)
+	}
+	rec, _ := r.Record("peer-a")
+	if rec.RoutingMetrics.AvgRTTMs != 20 || !rec.UpdatedAt.IsZero() {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+}
+
+func TestPeerRegistry_FutureTimestamps(t *testing.T) {
+	env, pub, priv := testEnvelope(t)
+	r := NewPeerRegistry()
+	r.RegisterPeer("peer-a", pub)
+	now := env.Timestamp
+	r.now = func() time.Time { return now }
+	r.RejectOlderThan(time.Hour)
+
+	future := env
+	future.Timestamp = now.Add(24 * time.Hour)
+	future.Sign(priv)
+	if err := r.ApplyGossip(future); !errors.Is(err, ErrFutureMetrics) {
+		t.Fatalf("future envelope: got %v", err)
+	}
+	// the rejected envelope must not have moved the watermark
+	if err := r.ApplyGossip(env); err != nil {
+		t.Fatalf("genuine update after a future-dated one: %v", err)
+	}
+
+	// within the allowed skew is fine
+	ahead := env
+	ahead.Timestamp = now.Add(DefaultGossipClockSkew / 2)
+	ahead.Sign(priv)
+	if err := r.ApplyGossip(ahead); err != nil {
+		t.Fatalf("envelope within skew: %v", err)
+	}
+
+	m := NATRoutingMetrics{LastProbeAt: now.Add(time.Hour)}
+	if err := r.ImportRoutingMetrics("peer-a", m); !errors.Is(err, ErrFutureMetrics) {
+		t.Fatalf("future import: got %v", err)
+	}
+	r.AllowClockSkew(2 * time.Hour)
+	if err := r.ImportRoutingMetrics("peer-a", m); err != nil {
+		t.Fatalf("import within configured skew: %v", err)
+	}
+	r.AllowClockSkew(-1)
+	if err := r.ApplyGossip(future); err != nil {
+		t.Fatalf("skew check disabled: %v", err)
+	}
+}
+
+func TestPeerRegistry_ZeroTimestamps(t *testing.T) {
+	env, pub, priv := testEnvelope(t)
+	r := NewPeerRegistry() // no RejectOlderThan window
+	r.RegisterPeer("peer-a", pub)
+
+	zero := env
+	zero.Timestamp = time.Time{}
+	zero.Sign(priv)
+	for i := 0; i < 2; i++ {
+		if err := r.ApplyGossip(zero); !errors.Is(err, ErrStaleMetrics) {
+			t.Fatalf("untimestamped envelope, attempt %d: got %v", i, err)
+		}
+	}
+	if err := r.ImportRoutingMetrics("peer-a", NATRoutingMetrics{AvgRTTMs: 5}); !errors.Is(err, ErrStaleMetrics) {
+		t.Fatalf("untimestamped import: got %v", err)
+	}
+	if _, ok := r.Record("peer-a"); ok {
+		t.Fatal("rejected metrics created a record")
+	}
+	if err := r.ApplyGossip(env); err != nil {
+		t.Fatalf("timestamped envelope after rejections: %v", err)
+	}
+}
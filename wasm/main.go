@@ -3,10 +3,10 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"syscall/js"
+	"time"
 
 	pd "github.com/Snider/Poindexter"
 )
@@ -53,7 +53,7 @@ func version(_ js.Value, _ []js.Value) (any, error) {
 
 func hello(_ js.Value, args []js.Value) (any, error) {
 	name := ""
-	if len(args) > 0 {
+	if len(args) > 0 && args[0].Type() == js.TypeString {
 		name = args[0].String()
 	}
 	return pd.Hello(name), nil
@@ -215,22 +215,49 @@ func exportJSON(_ js.Value, args []js.Value) (any, error) {
 	if !ok {
 		return nil, fmt.Errorf("unknown treeId %d", id)
 	}
-	// Export all points
-	points := t.Points()
-	jsPts := make([]any, len(points))
-	for i, p := range points {
-		jsPts[i] = map[string]any{"id": p.ID, "coords": p.Coords, "value": p.Value}
-	}
-	m := map[string]any{
-		"dim":     t.Dim(),
-		"len":     t.Len(),
-		"backend": string(t.Backend()),
-		"points":  jsPts,
+	b, err := t.MarshalJSON()
+	if err != nil {
+		return nil, err
 	}
-	b, _ := json.Marshal(m)
 	return string(b), nil
 }
 
+func exportMsgpack(_ js.Value, args []js.Value) (any, error) {
+	// exportMsgpack(treeId) -> Uint8Array (msgpack array of points)
+	if len(args) < 1 {
+		return nil, errors.New("exportMsgpack(treeId)")
+	}
+	id := args[0].Int()
+	t, ok := treeRegistry[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown treeId %d", id)
+	}
+	b, err := pd.KDPointsToMsgpack(t.Points(), pd.StringValueCodec())
+	if err != nil {
+		return nil, err
+	}
+	return bytesToJS(b), nil
+}
+
+func getAnalyticsMsgpack(_ js.Value, args []js.Value) (any, error) {
+	// getAnalyticsMsgpack(treeId) -> Uint8Array (msgpack analytics snapshot)
+	if len(args) < 1 {
+		return nil, errors.New("getAnalyticsMsgpack(treeId)")
+	}
+	id := args[0].Int()
+	t, ok := treeRegistry[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown treeId %d", id)
+	}
+	return bytesToJS(t.GetAnalyticsSnapshot().ToMsgpack()), nil
+}
+
+func bytesToJS(b []byte) js.Value {
+	arr := js.Global().Get("Uint8Array").New(len(b))
+	js.CopyBytesToJS(arr, b)
+	return arr
+}
+
 func getAnalytics(_ js.Value, args []js.Value) (any, error) {
 	// getAnalytics(treeId) -> analytics snapshot
 	if len(args) < 1 {
@@ -254,6 +281,14 @@ func getAnalytics(_ js.Value, args []js.Value) (any, error) {
 		"createdAt":           snap.CreatedAt.UnixMilli(),
 		"backendRebuildCount": snap.BackendRebuildCnt,
 		"lastRebuiltAt":       snap.LastRebuiltAt.UnixMilli(),
+		"p50QueryTimeNs":      snap.P50QueryTimeNs,
+		"p95QueryTimeNs":      snap.P95QueryTimeNs,
+		"p99QueryTimeNs":      snap.P99QueryTimeNs,
+		"p999QueryTimeNs":     snap.P999QueryTimeNs,
+		"bestDistanceMean":    snap.BestDistanceMean,
+		"bestDistanceP50":     snap.BestDistanceP50,
+		"bestDistanceP90":     snap.BestDistanceP90,
+		"anomalous":           snap.Anomalous,
 	}, nil
 }
 
@@ -271,10 +306,12 @@ func getPeerStats(_ js.Value, args []js.Value) (any, error) {
 	jsStats := make([]any, len(stats))
 	for i, s := range stats {
 		jsStats[i] = map[string]any{
-			"peerId":         s.PeerID,
-			"selectionCount": s.SelectionCount,
-			"avgDistance":    s.AvgDistance,
-			"lastSelectedAt": s.LastSelectedAt.UnixMilli(),
+			"peerId":          s.PeerID,
+			"selectionCount":  s.SelectionCount,
+			"avgDistance":     s.AvgDistance,
+			"lastSelectedAt":  s.LastSelectedAt.UnixMilli(),
+			"firstSelectedAt": s.FirstSelectedAt.UnixMilli(),
+			"decayedCount":    s.DecayedCount,
 		}
 	}
 	return jsStats, nil
@@ -295,15 +332,71 @@ func getTopPeers(_ js.Value, args []js.Value) (any, error) {
 	jsStats := make([]any, len(stats))
 	for i, s := range stats {
 		jsStats[i] = map[string]any{
-			"peerId":         s.PeerID,
-			"selectionCount": s.SelectionCount,
-			"avgDistance":    s.AvgDistance,
-			"lastSelectedAt": s.LastSelectedAt.UnixMilli(),
+			"peerId":          s.PeerID,
+			"selectionCount":  s.SelectionCount,
+			"avgDistance":     s.AvgDistance,
+			"lastSelectedAt":  s.LastSelectedAt.UnixMilli(),
+			"firstSelectedAt": s.FirstSelectedAt.UnixMilli(),
+			"decayedCount":    s.DecayedCount,
 		}
 	}
 	return jsStats, nil
 }
 
+func getSelectionFairness(_ js.Value, args []js.Value) (any, error) {
+	// getSelectionFairness(treeId) -> {peers, entropy, normalizedEntropy, gini}
+	if len(args) < 1 {
+		return nil, errors.New("getSelectionFairness(treeId)")
+	}
+	id := args[0].Int()
+	t, ok := treeRegistry[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown treeId %d", id)
+	}
+	f := t.GetSelectionFairness()
+	return map[string]any{
+		"peers":             f.Peers,
+		"entropy":           f.Entropy,
+		"normalizedEntropy": f.NormalizedEntropy,
+		"gini":              f.Gini,
+	}, nil
+}
+
+func getPeerChurn(_ js.Value, args []js.Value) (any, error) {
+	// getPeerChurn(treeId, windowMs) -> {active, joined, departed, churnRate, medianLifetimeMs, ...}
+	if len(args) < 2 {
+		return nil, errors.New("getPeerChurn(treeId, windowMs)")
+	}
+	id := args[0].Int()
+	window := time.Duration(args[1].Float() * float64(time.Millisecond))
+	t, ok := treeRegistry[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown treeId %d", id)
+	}
+	c := t.GetPeerChurn(window)
+	return map[string]any{
+		"windowMs":         c.Window.Milliseconds(),
+		"peers":            c.Peers,
+		"active":           c.Active,
+		"joined":           c.Joined,
+		"departed":         c.Departed,
+		"churnRate":        c.ChurnRate,
+		"medianLifetimeMs": c.MedianLifetime.Milliseconds(),
+	}, nil
+}
+
+func histogramToJS(h pd.Histogram) map[string]any {
+	edges := make([]any, len(h.Edges))
+	for i, e := range h.Edges {
+		edges[i] = e
+	}
+	counts := make([]any, len(h.Counts))
+	for i, c := range h.Counts {
+		counts[i] = c
+	}
+	return map[string]any{"edges": edges, "counts": counts}
+}
+
 func getAxisDistributions(_ js.Value, args []js.Value) (any, error) {
 	// getAxisDistributions(treeId, axisNames?: string[]) -> array of axis distribution stats
 	if len(args) < 1 {
@@ -344,6 +437,7 @@ func getAxisDistributions(_ js.Value, args []js.Value) (any, error) {
 				"variance": d.Stats.Variance,
 				"skewness": d.Stats.Skewness,
 			},
+			"histogram": histogramToJS(d.Histogram),
 		}
 	}
 	return jsDists, nil
@@ -531,6 +625,35 @@ func weightedPeerFeatures(_ js.Value, args []js.Value) (any, error) {
 // DNS Tools Functions
 // ============================================================================
 
+func parseTarget(_ js.Value, args []js.Value) (any, error) {
+	// parseTarget(input: string) -> Target
+	if len(args) < 1 {
+		return nil, errors.New("parseTarget(input)")
+	}
+	t, err := pd.ParseTarget(args[0].String())
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"raw":   t.Raw,
+		"kind":  string(t.Kind),
+		"value": t.Value,
+		"host":  t.Host,
+	}, nil
+}
+
+func getTargetLinks(_ js.Value, args []js.Value) (any, error) {
+	// getTargetLinks(input: string) -> ExternalToolLinks
+	if len(args) < 1 {
+		return nil, errors.New("getTargetLinks(input)")
+	}
+	t, err := pd.ParseTarget(args[0].String())
+	if err != nil {
+		return nil, err
+	}
+	return externalToolLinksToJS(t.Links()), nil
+}
+
 func getExternalToolLinks(_ js.Value, args []js.Value) (any, error) {
 	// getExternalToolLinks(domain: string) -> ExternalToolLinks
 	if len(args) < 1 {
@@ -602,6 +725,9 @@ func externalToolLinksToJS(links pd.ExternalToolLinks) map[string]any {
 		// Email-specific
 		"mailtester": links.MailTester,
 		"learndmarc": links.LearnDMARC,
+		// ASN-specific
+		"bgphe":     links.BGPHE,
+		"peeringdb": links.PeeringDB,
 	}
 }
 
@@ -699,47 +825,56 @@ func getCommonDNSRecordTypes(_ js.Value, _ []js.Value) (any, error) {
 	return result, nil
 }
 
+// Each export carries a //wasmgen directive describing its JS client method
+// and TypeScript signature; cmd/wasmgen generates the typed npm wrapper from
+// them, so keep the directive in sync when adding or changing an export.
 func main() {
 	// Export core API
-	export("pxVersion", version)
-	export("pxHello", hello)
-	export("pxNewTree", newTree)
-	export("pxTreeLen", treeLen)
-	export("pxTreeDim", treeDim)
-	export("pxInsert", insert)
-	export("pxDeleteByID", deleteByID)
-	export("pxNearest", nearest)
-	export("pxKNearest", kNearest)
-	export("pxRadius", radius)
-	export("pxExportJSON", exportJSON)
+	export("pxVersion", version)             //wasmgen:PoindexterClient.version(): string
+	export("pxHello", hello)                 //wasmgen:PoindexterClient.hello(name?: string): string
+	export("pxNewTree", newTree)             //wasmgen:PoindexterClient.newTree(dim: number): KDTreeClient(treeId)
+	export("pxTreeLen", treeLen)             //wasmgen:KDTreeClient.len(this): number
+	export("pxTreeDim", treeDim)             //wasmgen:KDTreeClient.dim(this): number
+	export("pxInsert", insert)               //wasmgen:KDTreeClient.insert(this, point: PxPoint): boolean
+	export("pxDeleteByID", deleteByID)       //wasmgen:KDTreeClient.deleteByID(this, id: string): boolean
+	export("pxNearest", nearest)             //wasmgen:KDTreeClient.nearest(this, query: number[]): NearestResult
+	export("pxKNearest", kNearest)           //wasmgen:KDTreeClient.kNearest(this, query: number[], k: number): KNearestResult
+	export("pxRadius", radius)               //wasmgen:KDTreeClient.radius(this, query: number[], r: number): KNearestResult
+	export("pxExportJSON", exportJSON)       //wasmgen:KDTreeClient.exportJSON(this): string
+	export("pxExportMsgpack", exportMsgpack) //wasmgen:KDTreeClient.exportMsgpack(this): Uint8Array
 
 	// Export analytics API
-	export("pxGetAnalytics", getAnalytics)
-	export("pxGetPeerStats", getPeerStats)
-	export("pxGetTopPeers", getTopPeers)
-	export("pxGetAxisDistributions", getAxisDistributions)
-	export("pxResetAnalytics", resetAnalytics)
-	export("pxComputeDistributionStats", computeDistributionStats)
+	export("pxGetAnalytics", getAnalytics)                         //wasmgen:KDTreeClient.getAnalytics(this): TreeAnalytics
+	export("pxGetAnalyticsMsgpack", getAnalyticsMsgpack)           //wasmgen:KDTreeClient.getAnalyticsMsgpack(this): Uint8Array
+	export("pxGetPeerStats", getPeerStats)                         //wasmgen:KDTreeClient.getPeerStats(this): PeerStats[]
+	export("pxGetTopPeers", getTopPeers)                           //wasmgen:KDTreeClient.getTopPeers(this, n: number): PeerStats[]
+	export("pxGetSelectionFairness", getSelectionFairness)         //wasmgen:KDTreeClient.getSelectionFairness(this): SelectionFairness
+	export("pxGetPeerChurn", getPeerChurn)                         //wasmgen:KDTreeClient.getPeerChurn(this, windowMs: number): PeerChurn
+	export("pxGetAxisDistributions", getAxisDistributions)         //wasmgen:KDTreeClient.getAxisDistributions(this, axisNames?: string[]): AxisDistribution[]
+	export("pxResetAnalytics", resetAnalytics)                     //wasmgen:KDTreeClient.resetAnalytics(this): boolean
+	export("pxComputeDistributionStats", computeDistributionStats) //wasmgen:PoindexterClient.computeDistributionStats(distances: number[]): DistributionStats
 
 	// Export NAT routing / peer quality API
-	export("pxComputePeerQualityScore", computePeerQualityScore)
-	export("pxComputeTrustScore", computeTrustScore)
-	export("pxGetDefaultQualityWeights", getDefaultQualityWeights)
-	export("pxGetDefaultPeerFeatureRanges", getDefaultPeerFeatureRanges)
-	export("pxNormalizePeerFeatures", normalizePeerFeatures)
-	export("pxWeightedPeerFeatures", weightedPeerFeatures)
+	export("pxComputePeerQualityScore", computePeerQualityScore)         //wasmgen:PoindexterClient.computePeerQualityScore(metrics: NATRoutingMetrics, weights?: QualityWeights): number
+	export("pxComputeTrustScore", computeTrustScore)                     //wasmgen:PoindexterClient.computeTrustScore(metrics: TrustMetrics): number
+	export("pxGetDefaultQualityWeights", getDefaultQualityWeights)       //wasmgen:PoindexterClient.getDefaultQualityWeights(): QualityWeights
+	export("pxGetDefaultPeerFeatureRanges", getDefaultPeerFeatureRanges) //wasmgen:PoindexterClient.getDefaultPeerFeatureRanges(): FeatureRanges
+	export("pxNormalizePeerFeatures", normalizePeerFeatures)             //wasmgen:PoindexterClient.normalizePeerFeatures(features: number[], ranges?: FeatureRanges): number[]
+	export("pxWeightedPeerFeatures", weightedPeerFeatures)               //wasmgen:PoindexterClient.weightedPeerFeatures(normalized: number[], weights: number[]): number[]
 
 	// Export DNS tools API
-	export("pxGetExternalToolLinks", getExternalToolLinks)
-	export("pxGetExternalToolLinksIP", getExternalToolLinksIP)
-	export("pxGetExternalToolLinksEmail", getExternalToolLinksEmail)
-	export("pxGetRDAPServers", getRDAPServers)
-	export("pxBuildRDAPDomainURL", buildRDAPDomainURL)
-	export("pxBuildRDAPIPURL", buildRDAPIPURL)
-	export("pxBuildRDAPASNURL", buildRDAPASNURL)
-	export("pxGetDNSRecordTypes", getDNSRecordTypes)
-	export("pxGetDNSRecordTypeInfo", getDNSRecordTypeInfo)
-	export("pxGetCommonDNSRecordTypes", getCommonDNSRecordTypes)
+	export("pxParseTarget", parseTarget)                             //wasmgen:DNSClient.parseTarget(input: string): Target
+	export("pxGetTargetLinks", getTargetLinks)                       //wasmgen:DNSClient.getTargetLinks(input: string): ExternalToolLinks
+	export("pxGetExternalToolLinks", getExternalToolLinks)           //wasmgen:DNSClient.getExternalToolLinks(domain: string): ExternalToolLinks
+	export("pxGetExternalToolLinksIP", getExternalToolLinksIP)       //wasmgen:DNSClient.getExternalToolLinksIP(ip: string): ExternalToolLinks
+	export("pxGetExternalToolLinksEmail", getExternalToolLinksEmail) //wasmgen:DNSClient.getExternalToolLinksEmail(emailOrDomain: string): ExternalToolLinks
+	export("pxGetRDAPServers", getRDAPServers)                       //wasmgen:DNSClient.getRDAPServers(): RDAPServers
+	export("pxBuildRDAPDomainURL", buildRDAPDomainURL)               //wasmgen:DNSClient.buildRDAPDomainURL(domain: string): string
+	export("pxBuildRDAPIPURL", buildRDAPIPURL)                       //wasmgen:DNSClient.buildRDAPIPURL(ip: string): string
+	export("pxBuildRDAPASNURL", buildRDAPASNURL)                     //wasmgen:DNSClient.buildRDAPASNURL(asn: string): string
+	export("pxGetDNSRecordTypes", getDNSRecordTypes)                 //wasmgen:DNSClient.getDNSRecordTypes(): DNSRecordType[]
+	export("pxGetDNSRecordTypeInfo", getDNSRecordTypeInfo)           //wasmgen:DNSClient.getDNSRecordTypeInfo(): DNSRecordTypeInfo[]
+	export("pxGetCommonDNSRecordTypes", getCommonDNSRecordTypes)     //wasmgen:DNSClient.getCommonDNSRecordTypes(): DNSRecordType[]
 
 	// Keep running
 	select {}